@@ -0,0 +1,237 @@
+// archive provides reading of archives produced by the pipeline (tar
+// archives with any registered compression, and zip archives), for
+// verification modules, and post-release tooling.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Format names of archives
+const (
+	FormatTar = "tar"
+	FormatZip = "zip"
+)
+
+// ErrNotFound is returned by ReadFile, if the archive has no such entry
+var ErrNotFound = errors.New("entry not found")
+
+// Entry is a file, directory, or link in an archive
+type Entry struct {
+	Name     string
+	Size     int64
+	Mode     os.FileMode
+	ModTime  time.Time
+	Linkname string
+}
+
+// IsDir reports whether the entry is a directory
+func (e *Entry) IsDir() bool {
+	return e.Mode.IsDir()
+}
+
+// WalkFunc is called for each archive entry. Contents of regular files can
+// be read from r, until the function returns.
+type WalkFunc func(entry *Entry, r io.Reader) error
+
+// Format detects an archive's format and compression
+func Format(filename string) (string, *Compression, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", nil, err
+	}
+
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+
+	head, err := reader.Peek(4)
+	if err == nil && bytes.Equal(head, []byte("PK\x03\x04")) {
+		none, _ := LookupCompression("none")
+
+		return FormatZip, none, nil
+	}
+
+	return FormatTar, DetectCompression(reader), nil
+}
+
+// Walk calls fn for each entry of an archive, in archive order
+func Walk(filename string, fn WalkFunc) error {
+	format, comp, err := Format(filename)
+	if err != nil {
+		return err
+	}
+
+	if format == FormatZip {
+		return walkZip(filename, fn)
+	}
+
+	return walkTar(filename, comp, fn)
+}
+
+// List returns entries of an archive
+func List(filename string) ([]*Entry, error) {
+	entries := []*Entry{}
+
+	err := Walk(filename, func(entry *Entry, _ io.Reader) error {
+		entries = append(entries, entry)
+		return nil
+	})
+
+	return entries, err
+}
+
+// ReadFile returns the contents of a single archive entry
+func ReadFile(filename, name string) ([]byte, error) {
+	var content []byte
+
+	errFound := errors.New("found")
+
+	err := Walk(filename, func(entry *Entry, r io.Reader) error {
+		if entry.Name != name {
+			return nil
+		}
+
+		var err error
+
+		if content, err = io.ReadAll(r); err != nil {
+			return err
+		}
+
+		return errFound
+	})
+
+	switch {
+	case errors.Is(err, errFound):
+		return content, nil
+	case err != nil:
+		return nil, err
+	default:
+		return nil, fmt.Errorf("%s in %s: %w", name, filename, ErrNotFound)
+	}
+}
+
+// Extract extracts all regular files and directories of an archive into
+// dir. Entries pointing outside of dir are refused; links are skipped.
+func Extract(filename, dir string) error {
+	return Walk(filename, func(entry *Entry, r io.Reader) error {
+		target := filepath.Join(dir, filepath.FromSlash(entry.Name))
+
+		if target != filepath.Clean(dir) && !strings.HasPrefix(target, filepath.Clean(dir)+string(filepath.Separator)) {
+			return fmt.Errorf("entry %s points outside of target directory", entry.Name)
+		}
+
+		switch {
+		case entry.IsDir():
+			return os.MkdirAll(target, 0o755)
+		case !entry.Mode.IsRegular():
+			return nil
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, entry.Mode.Perm())
+		if err != nil {
+			return err
+		}
+
+		if _, err := io.Copy(out, r); err != nil {
+			out.Close()
+			return fmt.Errorf("extracting %s: %w", entry.Name, err)
+		}
+
+		return out.Close()
+	})
+}
+
+func walkTar(filename string, comp *Compression, fn WalkFunc) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	decompressed, err := comp.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("decompressing %s (%s): %w", filename, comp.Name, err)
+	}
+
+	defer decompressed.Close()
+
+	tr := tar.NewReader(decompressed)
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", filename, err)
+		}
+
+		entry := &Entry{
+			Name:     strings.TrimPrefix(hdr.Name, "./"),
+			Size:     hdr.Size,
+			Mode:     hdr.FileInfo().Mode(),
+			ModTime:  hdr.ModTime,
+			Linkname: hdr.Linkname,
+		}
+
+		if err := fn(entry, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func walkZip(filename string, fn WalkFunc) error {
+	zr, err := zip.OpenReader(filename)
+	if err != nil {
+		return err
+	}
+
+	defer zr.Close()
+
+	for _, file := range zr.File {
+		entry := &Entry{
+			Name:    file.Name,
+			Size:    int64(file.UncompressedSize64),
+			Mode:    file.Mode(),
+			ModTime: file.Modified,
+		}
+
+		if err := walkZipFile(file, entry, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func walkZipFile(file *zip.File, entry *Entry, fn WalkFunc) error {
+	if entry.IsDir() {
+		return fn(entry, bytes.NewReader(nil))
+	}
+
+	reader, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", file.Name, err)
+	}
+
+	defer reader.Close()
+
+	return fn(entry, reader)
+}
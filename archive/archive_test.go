@@ -0,0 +1,182 @@
+package archive_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/julian7/goshipdone/archive"
+)
+
+func writeTar(t *testing.T, fn, compression string, files map[string]string) {
+	t.Helper()
+
+	comp, ok := archive.LookupCompression(compression)
+	if !ok {
+		t.Fatalf("compression %s not found", compression)
+	}
+
+	out, err := os.Create(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer out.Close()
+
+	cw := comp.NewWriter(out)
+	tw := tar.NewWriter(cw)
+
+	for _, name := range sortedKeys(files) {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(files[name]))}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := io.WriteString(tw, files[name]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeZip(t *testing.T, fn string, files map[string]string) {
+	t.Helper()
+
+	out, err := os.Create(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer out.Close()
+
+	zw := zip.NewWriter(out)
+
+	for _, name := range sortedKeys(files) {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := io.WriteString(w, files[name]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func sortedKeys(files map[string]string) []string {
+	keys := make([]string, 0, len(files))
+	for key := range files {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func TestArchive(t *testing.T) {
+	files := map[string]string{"app/README.md": "readme", "app/bin/app": "binary"}
+	dir := t.TempDir()
+
+	tests := []struct {
+		name       string
+		create     func(fn string)
+		wantFormat string
+		wantComp   string
+	}{
+		{name: "tar", create: func(fn string) { writeTar(t, fn, "none", files) }, wantFormat: archive.FormatTar, wantComp: "none"},
+		{name: "tar.gz", create: func(fn string) { writeTar(t, fn, "gzip", files) }, wantFormat: archive.FormatTar, wantComp: "gzip"},
+		{name: "zip", create: func(fn string) { writeZip(t, fn, files) }, wantFormat: archive.FormatZip, wantComp: "none"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			fn := filepath.Join(dir, "archive."+tt.name)
+			tt.create(fn)
+
+			format, comp, err := archive.Format(fn)
+			if err != nil {
+				t.Fatalf("Format() error = %v", err)
+			}
+
+			if format != tt.wantFormat || comp.Name != tt.wantComp {
+				t.Errorf("Format() = %s, %s; want %s, %s", format, comp.Name, tt.wantFormat, tt.wantComp)
+			}
+
+			entries, err := archive.List(fn)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+
+			names := []string{}
+			for _, entry := range entries {
+				names = append(names, entry.Name)
+			}
+
+			if diff := deep.Equal(names, []string{"app/README.md", "app/bin/app"}); diff != nil {
+				t.Errorf("List() %v", diff)
+			}
+
+			content, err := archive.ReadFile(fn, "app/bin/app")
+			if err != nil || string(content) != "binary" {
+				t.Errorf("ReadFile() = %q, %v; want \"binary\"", content, err)
+			}
+
+			if _, err := archive.ReadFile(fn, "missing"); !errors.Is(err, archive.ErrNotFound) {
+				t.Errorf("ReadFile(missing) error = %v, want %v", err, archive.ErrNotFound)
+			}
+
+			target := filepath.Join(dir, tt.name+"-extracted")
+			if err := archive.Extract(fn, target); err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+
+			extracted, err := os.ReadFile(filepath.Join(target, "app", "README.md"))
+			if err != nil || string(extracted) != "readme" {
+				t.Errorf("extracted README.md = %q, %v; want \"readme\"", extracted, err)
+			}
+		})
+	}
+}
+
+func TestExtract_traversal(t *testing.T) {
+	dir := t.TempDir()
+	fn := filepath.Join(dir, "evil.tar")
+
+	out, err := os.Create(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tw := tar.NewWriter(out)
+	_ = tw.WriteHeader(&tar.Header{Name: "../evil", Mode: 0o644, Size: 4})
+	_, _ = io.WriteString(tw, "evil")
+	_ = tw.Close()
+	_ = out.Close()
+
+	if err := archive.Extract(fn, filepath.Join(dir, "target")); err == nil {
+		t.Error("Extract() succeeded, want error")
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "evil")); !os.IsNotExist(err) {
+		t.Errorf("evil file written outside of target directory")
+	}
+}
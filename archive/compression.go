@@ -0,0 +1,129 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"sort"
+	"sync"
+)
+
+type (
+	// Compression is a compression format, registered by name
+	Compression struct {
+		// Name is the canonical name of the compression format
+		Name string
+		// Aliases are alternative names accepted in configuration
+		Aliases []string
+		// Extension is the file name extension of compressed files
+		Extension string
+		// Magic is the leading bytes of compressed streams, used for
+		// format detection. Empty for uncompressed streams.
+		Magic []byte
+		// NewWriter returns a compressing writer
+		NewWriter func(io.Writer) io.WriteCloser
+		// NewReader returns a decompressing reader
+		NewReader func(io.Reader) (io.ReadCloser, error)
+	}
+
+	nopWriteCloser struct {
+		io.Writer
+	}
+)
+
+// nolint: gochecknoglobals
+var (
+	compressionsMu sync.RWMutex
+	compressions   = map[string]*Compression{}
+)
+
+// nolint: gochecknoinits
+func init() {
+	RegisterCompression(&Compression{
+		Name:      "none",
+		Aliases:   []string{"", "NONE"},
+		NewWriter: func(w io.Writer) io.WriteCloser { return nopWriteCloser{w} },
+		NewReader: func(r io.Reader) (io.ReadCloser, error) { return io.NopCloser(r), nil },
+	})
+	RegisterCompression(&Compression{
+		Name:      "gzip",
+		Aliases:   []string{"gz", "GZip"},
+		Extension: ".gz",
+		Magic:     []byte{0x1f, 0x8b},
+		NewWriter: func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		NewReader: func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+	})
+}
+
+// RegisterCompression registers a compression format by its name and
+// aliases. It overrides already registered formats of the same names.
+func RegisterCompression(comp *Compression) {
+	compressionsMu.Lock()
+	defer compressionsMu.Unlock()
+
+	compressions[comp.Name] = comp
+
+	for _, alias := range comp.Aliases {
+		compressions[alias] = comp
+	}
+}
+
+// LookupCompression returns a registered compression format by name
+func LookupCompression(name string) (*Compression, bool) {
+	compressionsMu.RLock()
+	defer compressionsMu.RUnlock()
+
+	comp, ok := compressions[name]
+
+	return comp, ok
+}
+
+// Compressions returns canonical names of registered compression formats
+func Compressions() []string {
+	compressionsMu.RLock()
+	defer compressionsMu.RUnlock()
+
+	names := []string{}
+
+	for name, comp := range compressions {
+		if name == comp.Name {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// DetectCompression detects a stream's compression format by its magic
+// bytes, without consuming them. It returns the "none" format if no
+// registered format matches.
+func DetectCompression(reader *bufio.Reader) *Compression {
+	compressionsMu.RLock()
+	defer compressionsMu.RUnlock()
+
+	var found *Compression
+
+	for _, comp := range compressions {
+		if len(comp.Magic) == 0 || (found != nil && len(found.Magic) >= len(comp.Magic)) {
+			continue
+		}
+
+		head, err := reader.Peek(len(comp.Magic))
+		if err == nil && bytes.Equal(head, comp.Magic) {
+			found = comp
+		}
+	}
+
+	if found == nil {
+		found = compressions["none"]
+	}
+
+	return found
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
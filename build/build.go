@@ -3,6 +3,7 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"os"
 
@@ -11,12 +12,53 @@ import (
 
 func main() {
 	publish := flag.Bool("publish", false, "run publish phase (default: false)")
+	verbose := flag.Bool("v", false, "verbose output (default: false)")
+	profile := flag.String("profile", "", "configuration profile (default: detected)")
+	validate := flag.Bool("validate", false, "validate the pipeline instead of running it (default: false)")
+	from := flag.String("from", "", "resume the previous run from `stage` (default: run all stages)")
+	stages := flag.String("stages", "", "run only comma-separated `stages` of the previous run (default: run all stages)")
+	graph := flag.String("graph", "", "print the pipeline as a graph in `format` (dot, or mermaid) instead of running it")
 	flag.Parse()
 
 	if *publish {
 		os.Setenv("SKIP_PUBLISH", "false")
 	}
 
+	if *verbose {
+		os.Setenv("MAGEFILE_VERBOSE", "true")
+	}
+
+	if *profile != "" {
+		os.Setenv("GOSHIPDONE_PROFILE", *profile)
+	}
+
+	if *from != "" {
+		os.Setenv("GOSHIPDONE_FROM", *from)
+	}
+
+	if *stages != "" {
+		os.Setenv("GOSHIPDONE_STAGES", *stages)
+	}
+
+	if *validate {
+		if err := goshipdone.Validate(""); err != nil {
+			log.Fatalln(err)
+		}
+
+		return
+	}
+
+	if *graph != "" {
+		out, err := goshipdone.Graph("", *graph)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		fmt.Print(out)
+
+		return
+	}
+
 	if err := goshipdone.Run(""); err != nil {
 		log.Fatalln(err)
 	}
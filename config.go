@@ -0,0 +1,207 @@
+package goshipdone
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+// includeKey is the YAML key of included configuration files
+const includeKey = "include"
+
+// configLoader merges configuration files, and their includes
+type configLoader struct {
+	fs     afero.Fs
+	loaded map[string]bool
+	root   *yaml.Node
+}
+
+// loadConfig loads a configuration file, or all `*.yml`, and `*.yaml`
+// files of a directory in lexical order, merging them into a single YAML
+// document (see mergeNodes). Files listed in `include` are loaded before
+// the including file. Include paths are relative to the including file,
+// and they can be glob patterns. Each file is loaded once.
+func loadConfig(fs afero.Fs, filename string) (*yaml.Node, error) {
+	loader := &configLoader{
+		fs:     fs,
+		loaded: map[string]bool{},
+		root:   &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"},
+	}
+
+	st, err := fs.Stat(filename)
+	if err != nil {
+		return nil, fmt.Errorf("loading GoShipDone file: %w", err)
+	}
+
+	if st.IsDir() {
+		err = loader.loadDir(filename)
+	} else {
+		err = loader.load(filename)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{loader.root}}, nil
+}
+
+// loadDir loads configuration files of a directory in lexical order
+func (loader *configLoader) loadDir(dir string) error {
+	filenames := []string{}
+
+	for _, pattern := range []string{"*.yml", "*.yaml"} {
+		matches, err := afero.Glob(loader.fs, filepath.Join(dir, pattern))
+		if err != nil {
+			return fmt.Errorf("listing %s: %w", dir, err)
+		}
+
+		filenames = append(filenames, matches...)
+	}
+
+	if len(filenames) == 0 {
+		return fmt.Errorf("no GoShipDone files in %s", dir)
+	}
+
+	sort.Strings(filenames)
+
+	for _, filename := range filenames {
+		if err := loader.load(filename); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// load loads a configuration file after its includes
+func (loader *configLoader) load(filename string) error {
+	filename = filepath.Clean(filename)
+	if loader.loaded[filename] {
+		return nil
+	}
+
+	loader.loaded[filename] = true
+
+	content, err := afero.ReadFile(loader.fs, filename)
+	if err != nil {
+		return fmt.Errorf("loading GoShipDone file: %w", err)
+	}
+
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(content, doc); err != nil {
+		return fmt.Errorf("parsing %s: %w", filename, err)
+	}
+
+	if len(doc.Content) == 0 {
+		return nil
+	}
+
+	node := doc.Content[0]
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("parsing %s: configuration is not a map", filename)
+	}
+
+	includes, err := takeIncludes(node)
+	if err != nil {
+		return fmt.Errorf("parsing %s: %w", filename, err)
+	}
+
+	for _, include := range includes {
+		if err := loader.include(filepath.Dir(filename), include); err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+	}
+
+	mergeNodes(loader.root, node)
+
+	return nil
+}
+
+// include loads files matching an include pattern
+func (loader *configLoader) include(dir, pattern string) error {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(dir, pattern)
+	}
+
+	matches, err := afero.Glob(loader.fs, pattern)
+	if err != nil {
+		return fmt.Errorf("including %s: %w", pattern, err)
+	}
+
+	if len(matches) == 0 {
+		return fmt.Errorf("including %s: no such file", pattern)
+	}
+
+	sort.Strings(matches)
+
+	for _, match := range matches {
+		if err := loader.load(match); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// takeIncludes removes `include` from a configuration, returning its
+// paths
+func takeIncludes(node *yaml.Node) ([]string, error) {
+	value := takeKey(node, includeKey)
+	if value == nil {
+		return nil, nil
+	}
+
+	var includes []string
+
+	switch value.Kind {
+	case yaml.ScalarNode:
+		includes = []string{value.Value}
+	case yaml.SequenceNode:
+		if err := value.Decode(&includes); err != nil {
+			return nil, fmt.Errorf("decoding %s: %w", includeKey, err)
+		}
+	default:
+		return nil, errors.New(includeKey + " is not a path, or a list of paths")
+	}
+
+	return includes, nil
+}
+
+// mergeNodes merges a mapping node into another. Lists (eg. modules of a
+// stage) are appended, maps are merged, and other values are replaced.
+func mergeNodes(dst, src *yaml.Node) {
+	for idx := 0; idx+1 < len(src.Content); idx += 2 {
+		key, value := src.Content[idx], src.Content[idx+1]
+		merged := false
+
+		for pos := 0; pos+1 < len(dst.Content); pos += 2 {
+			if dst.Content[pos].Value != key.Value {
+				continue
+			}
+
+			existing := dst.Content[pos+1]
+
+			switch {
+			case existing.Kind == yaml.SequenceNode && value.Kind == yaml.SequenceNode:
+				existing.Content = append(existing.Content, value.Content...)
+			case existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+				mergeNodes(existing, value)
+			default:
+				dst.Content[pos+1] = value
+			}
+
+			merged = true
+
+			break
+		}
+
+		if !merged {
+			dst.Content = append(dst.Content, key, value)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package goshipdone
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+func Test_loadConfig(t *testing.T) {
+	files := map[string]string{
+		".goshipdone.yml":    "include: shared/*.yml\nsetups:\n- type: project\n  name: app\nbuilds:\n- type: go\n",
+		"shared/a.yml":       "include: [b.yml]\nbuilds:\n- type: tar\n",
+		"shared/b.yml":       "include: ../.goshipdone.yml\npublishes:\n- type: show\n",
+		"dir/20-publish.yml": "publishes:\n- type: artifact\n",
+		"dir/10-build.yaml":  "builds:\n- type: go\n",
+		"dir/notes.txt":      "not a config",
+		"broken.yml":         "include: missing.yml\n",
+	}
+
+	fs := afero.NewMemMapFs()
+	for filename, content := range files {
+		if err := afero.WriteFile(fs, filename, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		filename string
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "includes",
+			filename: ".goshipdone.yml",
+			want:     "publishes:\n    - type: show\nbuilds:\n    - type: tar\n    - type: go\nsetups:\n    - type: project\n      name: app\n",
+		},
+		{
+			name:     "directory",
+			filename: "dir",
+			want:     "builds:\n    - type: go\npublishes:\n    - type: artifact\n",
+		},
+		{
+			name:     "missing include",
+			filename: "broken.yml",
+			wantErr:  "broken.yml: including missing.yml: no such file",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := loadConfig(fs, tt.filename)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("loadConfig() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("loadConfig() error = %v", err)
+			}
+
+			data, err := yaml.Marshal(node)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(data) != tt.want {
+				t.Errorf("loadConfig() = %q, want %q", data, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package ctx
+
+import (
+	"log"
+	"sync"
+)
+
+type handler struct {
+	name string
+	fn   func() error
+}
+
+// handlers is a list of rollback, or cleanup handlers. It is safe for
+// concurrent use.
+type handlers struct {
+	mu    sync.Mutex
+	items []handler
+}
+
+func (h *handlers) add(name string, fn func() error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.items = append(h.items, handler{name: name, fn: fn})
+}
+
+// run runs handlers in reverse order of registration, and forgets them.
+// Errors are logged, and they don't stop other handlers.
+func (h *handlers) run(action string) {
+	h.mu.Lock()
+	items := h.items
+	h.items = nil
+	h.mu.Unlock()
+
+	for i := len(items) - 1; i >= 0; i-- {
+		log.Printf("      %s %s", action, items[i].name)
+
+		if err := items[i].fn(); err != nil {
+			log.Printf("      %s %s failed: %v", action, items[i].name, err)
+		}
+	}
+}
+
+// OnAbort registers a rollback handler, which runs if the pipeline is
+// interrupted (eg. deleting a half-created release). Handlers must not use
+// the pipeline's context, as it is already canceled when they run.
+func (c *Context) OnAbort(name string, fn func() error) {
+	c.abort.add(name, fn)
+}
+
+// Abort runs registered rollback handlers in reverse order of
+// registration. Errors are logged, and they don't stop other handlers.
+func (c *Context) Abort() {
+	c.abort.run("rolling back")
+}
+
+// OnFailure registers a cleanup handler of partial results, which runs if
+// the pipeline fails, or it is interrupted (eg. deleting a draft release
+// left orphaned by failed uploads). Handlers must not use the pipeline's
+// context, as it may be canceled when they run.
+func (c *Context) OnFailure(name string, fn func() error) {
+	c.failure.add(name, fn)
+}
+
+// Fail runs registered failure handlers in reverse order of registration.
+// Errors are logged, and they don't stop other handlers.
+func (c *Context) Fail() {
+	c.failure.run("cleaning up")
+}
+
+// OnFinish registers a cleanup handler, which runs at the end of the
+// pipeline, regardless of its result (eg. logging out of registries set
+// up by a setup module). Handlers must not use the pipeline's context, as
+// it may be canceled when they run.
+func (c *Context) OnFinish(name string, fn func() error) {
+	c.finish.add(name, fn)
+}
+
+// Finish runs registered cleanup handlers in reverse order of
+// registration. Errors are logged, and they don't stop other handlers.
+func (c *Context) Finish() {
+	c.finish.run("cleaning up")
+}
@@ -0,0 +1,38 @@
+package ctx
+
+import "sync"
+
+type (
+	// Action is an operation a module intended to do, without actually
+	// doing it. Fake modules record their actions for inspection.
+	Action struct {
+		Module   string `json:"module"`
+		Action   string `json:"action"`
+		Artifact string `json:"artifact"`
+		Target   string `json:"target,omitempty"`
+		Size     int64  `json:"size"`
+	}
+
+	// Actions collects recorded actions in order. It is safe for
+	// concurrent use.
+	Actions struct {
+		mu    sync.Mutex
+		items []Action
+	}
+)
+
+// Record records an action
+func (a *Actions) Record(action Action) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.items = append(a.items, action)
+}
+
+// List returns recorded actions in order of recording
+func (a *Actions) List() []Action {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return append([]Action(nil), a.items...)
+}
@@ -2,6 +2,10 @@ package ctx
 
 import (
 	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
 )
 
 type (
@@ -13,18 +17,158 @@ type (
 	// an archive)
 	Artifact struct {
 		*OsArch
-		Filename string
-		ID       string
-		Location string
+		// Checksums maps hash algorithms to hex digests of the artifact's
+		// file, recorded by modules producing, or hashing it (see Digest)
+		Checksums map[string]string `json:"checksums,omitempty"`
+		// FileFormat is the artifact's file format, if it can't be
+		// detected from its file name (see Format)
+		FileFormat Format `json:"format,omitempty"`
+		Filename   string `json:"filename"`
+		ID         string `json:"id"`
+		Location   string `json:"location"`
+		// ModTime is the modification time of the artifact's file,
+		// recorded at registration (see Stat)
+		ModTime time.Time `json:"mod_time"`
+		// Meta contains annotations of the artifact (eg. "channel" =>
+		// "stable", "signed" => "true"), set by modules producing, or
+		// processing it (see SetMeta), for later modules to filter on
+		Meta map[string]string `json:"meta,omitempty"`
+		// Parents lists file names of artifacts the artifact was made of
+		// (eg. binaries of an archive, or the signed file of a signature),
+		// see Artifacts.Materials
+		Parents []string `json:"parents,omitempty"`
+		// Size is the size of the artifact's file in bytes, recorded at
+		// registration (see Stat)
+		Size int64 `json:"size,omitempty"`
 	}
 )
 
-// Add registers a new artifact in Artifacts
+// artifactLock guards Checksums, and Meta of artifacts, which are shared
+// by concurrently running modules
+// nolint: gochecknoglobals
+var artifactLock sync.RWMutex
+
+// Digest returns the recorded hex digest of the artifact's file by hash
+// algorithm name (eg. "sha256"), or an empty string
+func (art *Artifact) Digest(algo string) string {
+	artifactLock.RLock()
+	defer artifactLock.RUnlock()
+
+	return art.Checksums[algo]
+}
+
+// SetDigest records a hex digest of the artifact's file
+func (art *Artifact) SetDigest(algo, sum string) {
+	artifactLock.Lock()
+	defer artifactLock.Unlock()
+
+	if art.Checksums == nil {
+		art.Checksums = map[string]string{}
+	}
+
+	art.Checksums[algo] = sum
+}
+
+// ResetDigests forgets recorded digests, after the artifact's file has
+// been changed (eg. signed in place)
+func (art *Artifact) ResetDigests() {
+	artifactLock.Lock()
+	defer artifactLock.Unlock()
+
+	art.Checksums = nil
+}
+
+// Stat records the size, and modification time of the artifact's file.
+// Nothing is recorded for directories.
+func (art *Artifact) Stat() error {
+	info, err := os.Stat(art.Location)
+	if err != nil {
+		return err
+	}
+
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+
+	artifactLock.Lock()
+	defer artifactLock.Unlock()
+
+	art.Size = info.Size()
+	art.ModTime = info.ModTime()
+
+	return nil
+}
+
+// Recorded returns true if the size, and modification time of the
+// artifact's file have been recorded (see Stat)
+func (art *Artifact) Recorded() bool {
+	artifactLock.RLock()
+	defer artifactLock.RUnlock()
+
+	return !art.ModTime.IsZero()
+}
+
+// Changed returns true if the artifact's file has been changed (or
+// removed) since its size, and modification time have been recorded
+func (art *Artifact) Changed() bool {
+	if !art.Recorded() {
+		return false
+	}
+
+	info, err := os.Stat(art.Location)
+	if err != nil {
+		return true
+	}
+
+	artifactLock.RLock()
+	defer artifactLock.RUnlock()
+
+	return info.Size() != art.Size || !info.ModTime().Equal(art.ModTime)
+}
+
+// Add registers a new artifact in Artifacts, recording the size, and
+// modification time of its file (see Stat)
 func (arts *Artifacts) Add(artifact *Artifact) {
 	log.Printf("      storing artifact %s as %s (%s)", artifact.Filename, artifact.ID, artifact.OsArch.String())
+	_ = artifact.Stat()
 	*arts = append(*arts, artifact)
 }
 
+// SetMeta annotates the artifact
+func (art *Artifact) SetMeta(key, value string) {
+	artifactLock.Lock()
+	defer artifactLock.Unlock()
+
+	if art.Meta == nil {
+		art.Meta = map[string]string{}
+	}
+
+	art.Meta[key] = value
+}
+
+// HasMeta returns true if the artifact is annotated with a key, and value
+func (art *Artifact) HasMeta(key, value string) bool {
+	artifactLock.RLock()
+	defer artifactLock.RUnlock()
+
+	actual, ok := art.Meta[key]
+
+	return ok && actual == value
+}
+
+// ByMeta searches artifacts by their annotations
+func (arts *Artifacts) ByMeta(key, value string) *Artifacts {
+	results := &Artifacts{}
+
+	for _, art := range *arts {
+		if art.HasMeta(key, value) {
+			*results = append(*results, art)
+		}
+	}
+
+	return results
+}
+
 // ByID searches artifacts by their build IDs
 func (arts *Artifacts) ByID(id string) *Artifacts {
 	results := &Artifacts{}
@@ -38,30 +182,103 @@ func (arts *Artifacts) ByID(id string) *Artifacts {
 	return results
 }
 
-// OsArchByIDs maps artifacts by OS-Arch, filtering by IDs
-func (arts *Artifacts) OsArchByIDs(ids []string, skips []string) map[string]*Artifacts {
-	skipIndex := make(map[string]bool, len(skips))
+// ByFilename returns an artifact by its file name, or nil
+func (arts *Artifacts) ByFilename(filename string) *Artifact {
+	for _, art := range *arts {
+		if art.Filename == filename {
+			return art
+		}
+	}
 
-	for _, skip := range skips {
-		skipIndex[skip] = true
+	return nil
+}
+
+// Filenames returns file names of artifacts, for recording them as
+// Parents of an artifact made of them
+func (arts *Artifacts) Filenames() []string {
+	filenames := make([]string, 0, len(*arts))
+
+	for _, art := range *arts {
+		filenames = append(filenames, art.Filename)
 	}
 
-	builds := map[string]*Artifacts{}
+	return filenames
+}
 
-	for _, id := range ids {
-		for _, art := range *arts.ByID(id) {
-			osarch := art.OsArch.String()
-			if _, ok := skipIndex[osarch]; ok {
-				continue
-			}
+// Parents returns registered artifacts an artifact was made of
+func (arts *Artifacts) Parents(art *Artifact) Artifacts {
+	parents := Artifacts{}
+
+	for _, filename := range art.Parents {
+		if parent := arts.ByFilename(filename); parent != nil {
+			parents = append(parents, parent)
+		}
+	}
+
+	return parents
+}
+
+// Children returns registered artifacts made of an artifact
+func (arts *Artifacts) Children(art *Artifact) Artifacts {
+	children := Artifacts{}
 
-			if _, ok := builds[osarch]; !ok {
-				builds[osarch] = &Artifacts{}
+	for _, child := range *arts {
+		for _, filename := range child.Parents {
+			if filename == art.Filename {
+				children = append(children, child)
+
+				break
 			}
+		}
+	}
+
+	return children
+}
+
+// Materials returns all registered artifacts an artifact was made of,
+// directly, or indirectly (eg. binaries of an archive, whose signature is
+// the artifact), parents first
+func (arts *Artifacts) Materials(art *Artifact) Artifacts {
+	materials := Artifacts{}
+	seen := map[string]bool{art.Filename: true}
+	queue := arts.Parents(art)
+
+	for len(queue) > 0 {
+		parent := queue[0]
+		queue = queue[1:]
+
+		if seen[parent.Filename] {
+			continue
+		}
+
+		seen[parent.Filename] = true
+		materials = append(materials, parent)
+		queue = append(queue, arts.Parents(parent)...)
+	}
+
+	return materials
+}
 
-			*builds[osarch] = append(*builds[osarch], art)
+// OSes returns operating systems of artifacts, in alphabetical order
+func (arts *Artifacts) OSes() []string {
+	seen := map[string]bool{}
+	oses := []string{}
+
+	for _, art := range *arts {
+		if art.OsArch == nil || art.OS == "" || seen[art.OS] {
+			continue
 		}
+
+		seen[art.OS] = true
+		oses = append(oses, art.OS)
 	}
 
-	return builds
+	sort.Strings(oses)
+
+	return oses
+}
+
+// OsArchByIDs maps artifacts by OS-Arch, filtering by IDs
+func (arts *Artifacts) OsArchByIDs(ids []string, skips []string) map[string]*Artifacts {
+	return arts.Select().Name(ids...).Skip(skips...).ByOsArch()
 }
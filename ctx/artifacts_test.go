@@ -1,7 +1,10 @@
 package ctx
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestArtifacts_Add(t *testing.T) {
@@ -59,6 +62,50 @@ func TestArtifacts_Add(t *testing.T) {
 	}
 }
 
+func TestArtifact_Changed(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app")
+	if err := os.WriteFile(filename, []byte("app"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	art := &Artifact{Filename: "app", Location: filename}
+	if art.Changed() {
+		t.Error("Artifact.Changed() = true without recorded size, and modification time")
+	}
+
+	arts := Artifacts{}
+	arts.Add(art)
+
+	if art.Size != 3 || art.ModTime.IsZero() {
+		t.Fatalf("Artifacts.Add() recorded size %d, modification time %v", art.Size, art.ModTime)
+	}
+
+	if art.Changed() {
+		t.Error("Artifact.Changed() = true of an unchanged file")
+	}
+
+	later := art.ModTime.Add(time.Second)
+	if err := os.Chtimes(filename, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	if !art.Changed() {
+		t.Error("Artifact.Changed() = false of a touched file")
+	}
+
+	if err := art.Stat(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Remove(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	if !art.Changed() {
+		t.Error("Artifact.Changed() = false of a removed file")
+	}
+}
+
 func TestArtifacts_ByID(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -85,3 +132,59 @@ func TestArtifacts_ByID(t *testing.T) {
 		})
 	}
 }
+
+func TestArtifacts_ByMeta(t *testing.T) {
+	stable := &Artifact{Filename: "a", ID: "a"}
+	stable.SetMeta("channel", "stable")
+
+	beta := &Artifact{Filename: "b", ID: "a"}
+	beta.SetMeta("channel", "beta")
+
+	arts := Artifacts{stable, beta, &Artifact{Filename: "c", ID: "a"}}
+
+	if got := arts.ByMeta("channel", "stable"); len(*got) != 1 || (*got)[0] != stable {
+		t.Errorf("Artifacts.ByMeta() = %v, want stable artifact", got)
+	}
+
+	if got := arts.ByMeta("signed", ""); len(*got) != 0 {
+		t.Errorf("Artifacts.ByMeta() of missing key = %v, want none", got)
+	}
+}
+
+func TestArtifacts_Materials(t *testing.T) {
+	binary := &Artifact{Filename: "linux/app", ID: "default"}
+	readme := &Artifact{Filename: "README.md", ID: "docs"}
+	archive := &Artifact{Filename: "app.tar.gz", ID: "archive", Parents: []string{"linux/app", "README.md"}}
+	signature := &Artifact{Filename: "app.tar.gz.minisig", ID: "signature", Parents: []string{"app.tar.gz"}}
+	checksums := &Artifact{Filename: "checksums.txt", ID: "checksum", Parents: []string{"app.tar.gz", "linux/app"}}
+	arts := Artifacts{binary, readme, archive, signature, checksums}
+
+	tests := []struct {
+		name string
+		got  Artifacts
+		want []string
+	}{
+		{"parents", arts.Parents(signature), []string{"app.tar.gz"}},
+		{"materials", arts.Materials(signature), []string{"app.tar.gz", "linux/app", "README.md"}},
+		{"shared materials", arts.Materials(checksums), []string{"app.tar.gz", "linux/app", "README.md"}},
+		{"no materials", arts.Materials(binary), []string{}},
+		{"children", arts.Children(archive), []string{"app.tar.gz.minisig", "checksums.txt"}},
+	}
+
+	for _, tt := range tests {
+		got := tt.got.Filenames()
+		if len(got) != len(tt.want) {
+			t.Errorf("%s = %v, want %v", tt.name, got, tt.want)
+
+			continue
+		}
+
+		for idx := range got {
+			if got[idx] != tt.want[idx] {
+				t.Errorf("%s = %v, want %v", tt.name, got, tt.want)
+
+				break
+			}
+		}
+	}
+}
@@ -5,10 +5,21 @@ package ctx
 import (
 	"context"
 	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
 
 	"github.com/julian7/withenv"
 )
 
+const (
+	// DefaultHeartbeat is the default interval of progress reports
+	DefaultHeartbeat = 30 * time.Second
+	// DefaultHistory is the default number of runs kept in run history
+	DefaultHistory = 10
+)
+
 type info struct{}
 
 var Info = &info{}
@@ -16,34 +27,158 @@ var Info = &info{}
 // Context are a cumulative structure carried over to each module,
 // to contain data later steps might require
 type Context struct {
+	// Context is the pipeline's context, canceled when the run is
+	// interrupted. Modules should use the context they run with instead,
+	// which is canceled after their timeouts too.
 	context.Context
-	Artifacts   Artifacts
-	Env         *withenv.Env
-	Git         *GitData
+	// Actions collects intended actions of fake modules
+	Actions   *Actions
+	Artifacts Artifacts
+	// CI contains information on the CI environment (see setup:ci)
+	CI *CIData
+	// Dir is the project's absolute directory (see setup:project), for
+	// monorepos. Relative paths of the configuration are resolved, and
+	// commands are run in it (see Path). Empty is the current directory.
+	Dir string
+	Env *withenv.Env
+	// Events is the event bus of the pipeline: stages, modules, produced
+	// artifacts, and transfers are reported to its subscribers
+	Events *Events
+	// ExeExtensions override file name extensions of executables in
+	// templates by OS, architecture, or "os-arch" (see setup:project)
+	ExeExtensions map[string]string
+	Git           *GitData
+	// GPGFingerprint is the fingerprint of the private key imported by
+	// setup:gpg_import
+	GPGFingerprint string
+	// Heartbeat is the interval of progress reports of long-running
+	// modules. Zero turns heartbeat logging off.
+	Heartbeat time.Duration
+	// History is the number of runs kept in run history under
+	// TargetDir. Zero turns run history off.
+	History int
+	// LenientTemplates renders missing map keys of templates as "<no
+	// value>", instead of failing (see setup:project)
+	LenientTemplates bool
+	// Parallelism is the maximum number of modules running concurrently
+	// in a stage (see Module.Needs)
+	Parallelism int
+	// Progress is the progress report of the currently running module
+	Progress    *Progress
 	ProjectName string
 	Publish     bool
-	TargetDir   string
-	Version     string
+	// Published collects release links, download links, and install
+	// instructions of publishers for the end-of-run summary
+	Published *Published
+	// Random is a seedable source of identifiers. It is seeded by
+	// GOSHIPDONE_SEED environment variable, or by the current time.
+	Random *Random
+	// Resumed marks runs resumed from a previous run's state, loading its
+	// artifacts from TargetDir
+	Resumed bool
+	// Snapshot marks builds of untagged commits (see setup:snapshot)
+	Snapshot bool
+	// StartedAt is the time the pipeline has been started
+	StartedAt time.Time
+	TargetDir string
+	// Transfers collects upload / download statistics of publishers
+	Transfers *Transfers
+	// Verbose turns on detailed logging of module operations
+	Verbose bool
+	Version string
+	abort   *handlers
+	failure *handlers
+	finish  *handlers
+	// forked is the number of artifacts at the time of Fork
+	forked   int
+	lockFile *os.File
+	tempDirs []string
+}
+
+// CIData contains information on the CI environment
+type CIData struct {
+	// Branch is the branch being built, if it's not a tag build
+	Branch string
+	// Commit is the full SHA1 checksum of the commit being built
+	Commit string
+	// Name is the CI service's name: github, gitlab, circleci, or
+	// jenkins. It is empty outside of CI environments.
+	Name string
+	// RunURL is the URL of the CI run (workflow run, pipeline, or build)
+	RunURL string
+	// Tag is the tag being built
+	Tag string
 }
 
 // GitData contains git-specific information on the repository
 type GitData struct {
+	// Branch is the current branch. It is empty on detached HEADs (eg.
+	// tag builds), unless detected by setup:ci.
+	Branch string
+	// CommitDate is the committer date of the current commit
+	CommitDate time.Time
+	// Dirty is true if the working tree has uncommitted changes (see
+	// setup:git_state)
+	Dirty bool
+	// PreviousTag contains the previous release tag (see
+	// setup:previous_tag)
+	PreviousTag string
+	// Range contains the commit range since the previous release tag, like
+	// "v1.2.3..HEAD" (see setup:previous_tag)
+	Range string
 	// Tag contains git tag information, if the repo is on a specific tag
 	Tag string
 	// Ref contains the full SHA1 checksum of the current commit
 	Ref string
+	// Paths are pathspecs limiting commits considered by changelogs, and
+	// versioning, for monorepos (see setup:project). Empty means all
+	// commits.
+	Paths []string
+	// TagPrefix is the prefix of the project's tags in monorepos, like
+	// "svc-a/" (see setup:project)
+	TagPrefix string
+	// Semver contains the tag's semantic version components. It is left
+	// empty, if the repo is not on a tag, or the tag is not a semantic
+	// version.
+	Semver Semver
 	// URL contains git repo's URL, collected from current branch's upstream
 	URL string
 }
 
+// Pathspec returns git arguments limiting commits to Paths
+func (git *GitData) Pathspec() []string {
+	if len(git.Paths) == 0 {
+		return nil
+	}
+
+	return append([]string{"--"}, git.Paths...)
+}
+
 func New(ctx context.Context) context.Context {
+	now := time.Now()
+	events := new(Events)
+
 	return context.WithValue(
 		ctx,
 		Info,
 		&Context{
-			Context: ctx,
-			Env:     withenv.New(),
-			Git:     new(GitData),
+			Context:     ctx,
+			Actions:     new(Actions),
+			CI:          new(CIData),
+			Env:         withenv.New(),
+			Events:      events,
+			Git:         new(GitData),
+			Heartbeat:   DefaultHeartbeat,
+			History:     DefaultHistory,
+			Parallelism: runtime.NumCPU(),
+			Progress:    new(Progress),
+			Published:   new(Published),
+			Random:      NewRandom(now.UnixNano()),
+			StartedAt:   now,
+			Transfers:   &Transfers{events: events},
+			abort:       new(handlers),
+			failure:     new(handlers),
+			finish:      new(handlers),
 		},
 	)
 }
@@ -57,3 +192,14 @@ func GetShipContext(cx context.Context) (*Context, error) {
 
 	return context, nil
 }
+
+// Path resolves a path of the configuration (like a file to be read)
+// against the project's directory (see Dir). Absolute paths are returned
+// as is.
+func (c *Context) Path(name string) string {
+	if c.Dir == "" || name == "" || filepath.IsAbs(name) {
+		return name
+	}
+
+	return filepath.Join(c.Dir, name)
+}
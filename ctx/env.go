@@ -0,0 +1,54 @@
+package ctx
+
+import (
+	"fmt"
+
+	"github.com/julian7/withenv"
+)
+
+// Getenv returns an environment variable of the run (see Env), or
+// fallback, if it's not set
+func (c *Context) Getenv(name, fallback string) string {
+	if c.Env == nil {
+		return fallback
+	}
+
+	return c.Env.GetOrDefault(name, fallback)
+}
+
+// LookupEnv returns an environment variable of the run (see Env), and
+// whether it's set
+func (c *Context) LookupEnv(name string) (string, bool) {
+	if c.Env == nil {
+		return "", false
+	}
+
+	return c.Env.Get(name)
+}
+
+// RequireEnv returns an environment variable of the run (see Env), or an
+// error naming the variable, and what it should contain (like "access
+// key"), if it's not set
+func (c *Context) RequireEnv(name, description string) (string, error) {
+	val, ok := c.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("%s not found in $%s", description, name)
+	}
+
+	return val, nil
+}
+
+// Setenv sets an environment variable for later modules, and for child
+// processes they run (see RunCommand). The value is stored as given:
+// unlike withenv.Env.Set, variable references (like "$VAR") in it are not
+// expanded.
+func (c *Context) Setenv(name, value string) {
+	if c.Env == nil {
+		c.Env = withenv.New()
+	}
+
+	c.Env.Lock()
+	defer c.Env.Unlock()
+
+	c.Env.Vars[name] = value
+}
@@ -0,0 +1,48 @@
+package ctx
+
+import (
+	"context"
+	"testing"
+)
+
+func TestContext_env(t *testing.T) {
+	shipContext := &Context{Context: context.Background()}
+
+	if got := shipContext.Getenv("NAME", "fallback"); got != "fallback" {
+		t.Errorf("Getenv() = %q without Env, want fallback", got)
+	}
+
+	if _, err := shipContext.RequireEnv("NAME", "project name"); err == nil || err.Error() != "project name not found in $NAME" {
+		t.Errorf("RequireEnv() error = %v, want missing variable", err)
+	}
+
+	shipContext.Setenv("NAME", "app")
+
+	if got := shipContext.Getenv("NAME", "fallback"); got != "app" {
+		t.Errorf("Getenv() = %q, want app", got)
+	}
+
+	if got, err := shipContext.RequireEnv("NAME", "project name"); err != nil || got != "app" {
+		t.Errorf("RequireEnv() = %q, %v, want app", got, err)
+	}
+
+	found := false
+
+	for _, env := range shipContext.Env.Environ() {
+		found = found || env == "NAME=app"
+	}
+
+	if !found {
+		t.Error("Setenv() didn't set variable for child processes")
+	}
+}
+
+func TestContext_SetenvLiteral(t *testing.T) {
+	shipContext := &Context{Context: context.Background()}
+	shipContext.Setenv("word", "expanded")
+	shipContext.Setenv("PASSWORD", "pa$word${word}")
+
+	if got := shipContext.Getenv("PASSWORD", ""); got != "pa$word${word}" {
+		t.Errorf("Getenv() = %q, want value as set", got)
+	}
+}
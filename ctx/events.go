@@ -0,0 +1,92 @@
+package ctx
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType is the type of a pipeline event
+type EventType string
+
+const (
+	// EventStageStarted is emitted before running a stage's modules
+	EventStageStarted EventType = "stage_started"
+	// EventStageFinished is emitted after a stage finished, failed, or
+	// has been skipped (see Event.Status)
+	EventStageFinished EventType = "stage_finished"
+	// EventModuleStarted is emitted before running a module
+	EventModuleStarted EventType = "module_started"
+	// EventModuleFinished is emitted after a module finished, failed, or
+	// has been skipped (see Event.Status)
+	EventModuleFinished EventType = "module_finished"
+	// EventArtifact is emitted for each artifact produced by a module
+	EventArtifact EventType = "artifact"
+	// EventUpload is emitted when bytes are uploaded (see Transfers)
+	EventUpload EventType = "upload"
+	// EventDownload is emitted when bytes are downloaded (see Transfers)
+	EventDownload EventType = "download"
+)
+
+type (
+	// Event is a structured pipeline event. Fields not related to its
+	// type are left empty.
+	Event struct {
+		Type EventType `json:"type"`
+		Time time.Time `json:"time"`
+		// Stage is the stage's name in stage, module, and artifact events
+		Stage string `json:"stage,omitempty"`
+		// Module is the module's type in module, and artifact events
+		Module string `json:"module,omitempty"`
+		// Status is the outcome of finished stages, and modules, like
+		// "completed", "failed", or "skipped"
+		Status string `json:"status,omitempty"`
+		// Duration is the run time of finished stages, and modules
+		Duration time.Duration `json:"duration,omitempty"`
+		// Error is the error message of failed stages, and modules
+		Error string `json:"error,omitempty"`
+		// Artifact is the produced artifact of artifact events
+		Artifact *Artifact `json:"artifact,omitempty"`
+		// Destination is the remote end of upload, and download events
+		Destination string `json:"destination,omitempty"`
+		// Bytes is the number of bytes transferred in upload, and
+		// download events
+		Bytes int64 `json:"bytes,omitempty"`
+	}
+
+	// Events is an event bus of the pipeline. Subscribers are called
+	// synchronously, in order of subscription, possibly from multiple
+	// goroutines (eg. concurrently running modules), therefore they have
+	// to be safe for concurrent use, and quick.
+	Events struct {
+		mu          sync.RWMutex
+		subscribers []func(Event)
+	}
+)
+
+// Subscribe registers a function called with every event emitted later
+func (e *Events) Subscribe(fn func(Event)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.subscribers = append(e.subscribers, fn)
+}
+
+// Emit sends an event to all subscribers, setting its time, if not set.
+// It is a no-op on a nil Events.
+func (e *Events) Emit(event Event) {
+	if e == nil {
+		return
+	}
+
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	e.mu.RLock()
+	subscribers := e.subscribers
+	e.mu.RUnlock()
+
+	for _, fn := range subscribers {
+		fn(event)
+	}
+}
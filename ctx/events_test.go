@@ -0,0 +1,45 @@
+package ctx_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+	"github.com/julian7/goshipdone/ctx"
+)
+
+func TestEvents(t *testing.T) {
+	var nilEvents *ctx.Events
+
+	nilEvents.Emit(ctx.Event{Type: ctx.EventStageStarted})
+
+	shipContext, err := ctx.GetShipContext(ctx.New(context.Background()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []ctx.Event{}
+	shipContext.Events.Subscribe(func(event ctx.Event) {
+		if event.Time.IsZero() {
+			t.Errorf("Events.Emit() didn't set time of %s event", event.Type)
+		}
+
+		event.Time = time.Time{}
+		got = append(got, event)
+	})
+
+	shipContext.Events.Emit(ctx.Event{Type: ctx.EventStageStarted, Stage: "build"})
+	shipContext.Transfers.Upload("scp:b", 10)
+	shipContext.Transfers.Download("github:a", 7)
+
+	want := []ctx.Event{
+		{Type: ctx.EventStageStarted, Stage: "build"},
+		{Type: ctx.EventUpload, Destination: "scp:b", Bytes: 10},
+		{Type: ctx.EventDownload, Destination: "github:a", Bytes: 7},
+	}
+
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Errorf("Events %v", diff)
+	}
+}
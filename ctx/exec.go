@@ -0,0 +1,84 @@
+package ctx
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/julian7/withenv"
+)
+
+// RunCommand runs a command with the variables of env, expanding them in
+// its arguments, like withenv.Env.Run. The command is looked up in PATH
+// of env (see LookPath), and it runs in the project's directory (see
+// Context.Dir). The command is killed when cx is
+// canceled, therefore modules should pass the context they run with,
+// which is canceled when the pipeline is interrupted, or the module timed
+// out.
+func RunCommand(cx context.Context, env *withenv.Env, cmd string, args ...string) error {
+	expanded := make([]string, 0, len(args))
+	for _, arg := range args {
+		expanded = append(expanded, env.Expand(arg))
+	}
+
+	command := exec.CommandContext(cx, LookPath(env, cmd), expanded...)
+	command.Env = env.Environ()
+
+	if shipContext, err := GetShipContext(cx); err == nil {
+		command.Dir = shipContext.Dir
+	}
+
+	command.Stdin = os.Stdin
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+
+	return command.Run()
+}
+
+// LookPath returns the path of an executable in PATH of env (like
+// directories of tools installed by setup:tools), or the name as is, if
+// it's not found there, or it contains a path separator. exec.Command
+// looks up names in the process's PATH.
+func LookPath(env *withenv.Env, name string) string {
+	if strings.ContainsAny(name, `/\`) {
+		return name
+	}
+
+	dirs, ok := env.Get("PATH")
+	if !ok {
+		return name
+	}
+
+	exts := []string{""}
+	if runtime.GOOS == "windows" {
+		exts = append(exts, ".exe", ".bat", ".cmd")
+	}
+
+	for _, dir := range filepath.SplitList(dirs) {
+		if dir == "" {
+			continue
+		}
+
+		for _, ext := range exts {
+			if fn := filepath.Join(dir, name+ext); isExecutable(fn) {
+				return fn
+			}
+		}
+	}
+
+	return name
+}
+
+// isExecutable returns true for regular files, which are executable on
+// unix-like systems
+func isExecutable(fn string) bool {
+	st, err := os.Stat(fn)
+	if err != nil || !st.Mode().IsRegular() {
+		return false
+	}
+
+	return runtime.GOOS == "windows" || st.Mode().Perm()&0o111 != 0
+}
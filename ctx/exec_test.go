@@ -0,0 +1,68 @@
+package ctx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/julian7/withenv"
+)
+
+func TestRunCommand(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "out")
+	env := withenv.New()
+	env.Set("OUT", fn)
+
+	if err := RunCommand(context.Background(), env, "touch", "$OUT"); err != nil {
+		t.Fatalf("RunCommand() error = %v", err)
+	}
+
+	if _, err := os.Stat(fn); err != nil {
+		t.Errorf("RunCommand() didn't expand arguments: %v", err)
+	}
+
+	cx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := RunCommand(cx, env, "sleep", "10"); err == nil {
+		t.Error("RunCommand() succeeded with a canceled context")
+	}
+}
+
+func TestLookPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("executable bits are not available on windows")
+	}
+
+	dir := t.TempDir()
+	tool := filepath.Join(dir, "tool")
+
+	if err := os.WriteFile(tool, []byte("#!/bin/sh\n"), 0o755); err != nil { // nolint: gosec
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "data"), []byte("data"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	env := withenv.New()
+	env.Set("PATH", string(os.PathListSeparator)+dir)
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "tool", want: tool},
+		{name: "data", want: "data"},
+		{name: "missing", want: "missing"},
+		{name: "./tool", want: "./tool"},
+	}
+
+	for _, tt := range tests {
+		if got := LookPath(env, tt.name); got != tt.want {
+			t.Errorf("LookPath(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
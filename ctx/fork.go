@@ -0,0 +1,29 @@
+package ctx
+
+import "context"
+
+// Fork returns a copy of the ship context for a module running
+// concurrently with others, and cx carrying the copy. The copy shares
+// everything with c, except its artifact list, progress report, and
+// temporary directories. Join merges artifacts registered in the copy
+// back into c.
+//
+// Fork, and Join are not safe for concurrent use: callers must serialize
+// them.
+func (c *Context) Fork(cx context.Context) (context.Context, *Context) {
+	child := *c
+	child.Artifacts = append(Artifacts{}, c.Artifacts...)
+	child.Progress = new(Progress)
+	child.forked = len(c.Artifacts)
+	child.tempDirs = nil
+
+	return context.WithValue(cx, Info, &child), &child
+}
+
+// Join registers artifacts, which have been added to a forked ship
+// context (see Fork).
+func (c *Context) Join(child *Context) {
+	if len(child.Artifacts) > child.forked {
+		c.Artifacts = append(c.Artifacts, child.Artifacts[child.forked:]...)
+	}
+}
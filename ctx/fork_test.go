@@ -0,0 +1,47 @@
+package ctx
+
+import (
+	"context"
+	"testing"
+)
+
+func TestContext_Fork(t *testing.T) {
+	parent, err := GetShipContext(New(context.Background()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parent.Artifacts.Add(&Artifact{ID: "default", Filename: "app"})
+
+	cx, first := parent.Fork(context.Background())
+	_, second := parent.Fork(context.Background())
+
+	if got, _ := GetShipContext(cx); got != first {
+		t.Errorf("Fork() context doesn't carry the fork")
+	}
+
+	first.Artifacts.Add(&Artifact{ID: "archive", Filename: "app.tar.gz"})
+	second.Artifacts.Add(&Artifact{ID: "checksum", Filename: "checksums.txt"})
+
+	if first.Progress == parent.Progress {
+		t.Error("Fork() shares progress report")
+	}
+
+	if len(parent.Artifacts) != 1 {
+		t.Errorf("Fork() changed parent's artifacts: %d", len(parent.Artifacts))
+	}
+
+	parent.Join(second)
+	parent.Join(first)
+
+	want := []string{"app", "checksums.txt", "app.tar.gz"}
+	if len(parent.Artifacts) != len(want) {
+		t.Fatalf("Join() resulted %d artifacts, want %d", len(parent.Artifacts), len(want))
+	}
+
+	for idx, art := range parent.Artifacts {
+		if art.Filename != want[idx] {
+			t.Errorf("Join() artifact #%d = %s, want %s", idx, art.Filename, want[idx])
+		}
+	}
+}
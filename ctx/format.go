@@ -0,0 +1,168 @@
+package ctx
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Format is the file format of an artifact (see Artifact.Format). Core
+// formats are listed as constants; custom formats can be registered with
+// RegisterFormat.
+type Format string
+
+const (
+	// FormatBinary is an executable (without extension, or ".exe")
+	FormatBinary Format = "binary"
+	// FormatTar is a tar archive, compressed, or not
+	FormatTar Format = "tar"
+	// FormatZip is a zip archive
+	FormatZip Format = "zip"
+	// FormatDeb is a Debian package
+	FormatDeb Format = "deb"
+	// FormatRPM is an RPM package
+	FormatRPM Format = "rpm"
+	// FormatMSI is a Windows installer
+	FormatMSI Format = "msi"
+	// FormatDMG is a macOS disk image
+	FormatDMG Format = "dmg"
+	// FormatDockerImage is a container image (eg. saved by `docker save`)
+	FormatDockerImage Format = "docker-image"
+	// FormatSBOM is a software bill of materials (SPDX, or CycloneDX)
+	FormatSBOM Format = "sbom"
+	// FormatSignature is a detached signature (minisign, cosign, or GPG)
+	FormatSignature Format = "signature"
+	// FormatChecksum is a checksum file
+	FormatChecksum Format = "checksum"
+	// FormatOther is any other file
+	FormatOther Format = "other"
+)
+
+// formatSuffix maps a file name suffix to a format
+type formatSuffix struct {
+	suffix string
+	format Format
+}
+
+// nolint: gochecknoglobals
+var (
+	// formats contains registered formats
+	formats = map[Format]bool{
+		FormatBinary: true, FormatTar: true, FormatZip: true, FormatDeb: true,
+		FormatRPM: true, FormatMSI: true, FormatDMG: true, FormatDockerImage: true,
+		FormatSBOM: true, FormatSignature: true, FormatChecksum: true, FormatOther: true,
+	}
+	// formatSuffixes lists file name suffixes of formats, longest first
+	formatSuffixes = sortSuffixes([]formatSuffix{
+		{".tar.bz2", FormatTar},
+		{".tar.zst", FormatTar},
+		{".tar.gz", FormatTar},
+		{".tar.xz", FormatTar},
+		{".tbz2", FormatTar},
+		{".tar", FormatTar},
+		{".tgz", FormatTar},
+		{".txz", FormatTar},
+		{".zip", FormatZip},
+		{".deb", FormatDeb},
+		{".rpm", FormatRPM},
+		{".msi", FormatMSI},
+		{".dmg", FormatDMG},
+		{".docker.tar", FormatDockerImage},
+		{".oci.tar", FormatDockerImage},
+		{".spdx.json", FormatSBOM},
+		{".cdx.json", FormatSBOM},
+		{".spdx", FormatSBOM},
+		{".minisig", FormatSignature},
+		{".sig", FormatSignature},
+		{".asc", FormatSignature},
+		{"checksums.txt", FormatChecksum},
+		{".sha256", FormatChecksum},
+		{".sha512", FormatChecksum},
+		{".exe", FormatBinary},
+	})
+	formatsLock sync.RWMutex
+)
+
+// RegisterFormat registers a custom format, detected by file name
+// suffixes (eg. ".uf2"). Custom formats should be namespaced (eg.
+// "acme/firmware"). Registering a format, or a suffix already registered
+// (including core formats) is an error, so custom formats never collide
+// with core ones.
+func RegisterFormat(format Format, suffixes ...string) error {
+	formatsLock.Lock()
+	defer formatsLock.Unlock()
+
+	if formats[format] {
+		return fmt.Errorf("format %s already registered", format)
+	}
+
+	for _, suffix := range suffixes {
+		for _, item := range formatSuffixes {
+			if item.suffix == strings.ToLower(suffix) {
+				return fmt.Errorf("suffix %s already registered for format %s", suffix, item.format)
+			}
+		}
+	}
+
+	formats[format] = true
+
+	for _, suffix := range suffixes {
+		formatSuffixes = append(formatSuffixes, formatSuffix{suffix: strings.ToLower(suffix), format: format})
+	}
+
+	formatSuffixes = sortSuffixes(formatSuffixes)
+
+	return nil
+}
+
+// Formats returns all registered formats in alphabetical order
+func Formats() []Format {
+	formatsLock.RLock()
+	defer formatsLock.RUnlock()
+
+	list := make([]Format, 0, len(formats))
+	for format := range formats {
+		list = append(list, format)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+
+	return list
+}
+
+// sortSuffixes sorts suffixes longest first, for matching the most
+// specific one (eg. ".docker.tar" before ".tar")
+func sortSuffixes(suffixes []formatSuffix) []formatSuffix {
+	sort.SliceStable(suffixes, func(i, j int) bool {
+		return len(suffixes[i].suffix) > len(suffixes[j].suffix)
+	})
+
+	return suffixes
+}
+
+// Format returns the file format of the artifact: FileFormat, if set by
+// the module producing it, or detected from its file name
+func (art *Artifact) Format() Format {
+	if art.FileFormat != "" {
+		return art.FileFormat
+	}
+
+	name := strings.ToLower(path.Base(art.Filename))
+
+	formatsLock.RLock()
+	defer formatsLock.RUnlock()
+
+	for _, item := range formatSuffixes {
+		if strings.HasSuffix(name, item.suffix) {
+			return item.format
+		}
+	}
+
+	if path.Ext(name) == "" {
+		return FormatBinary
+	}
+
+	return FormatOther
+}
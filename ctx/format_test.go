@@ -0,0 +1,61 @@
+package ctx_test
+
+import (
+	"testing"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+func TestArtifact_Format(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     ctx.Format
+	}{
+		{"app", ctx.FormatBinary},
+		{"app.exe", ctx.FormatBinary},
+		{"app-linux-amd64.tar.gz", ctx.FormatTar},
+		{"app-linux-amd64.TGZ", ctx.FormatTar},
+		{"app-windows-amd64.zip", ctx.FormatZip},
+		{"app_1.0.0_amd64.deb", ctx.FormatDeb},
+		{"app.tar.gz.minisig", ctx.FormatSignature},
+		{"app-checksums.txt", ctx.FormatChecksum},
+		{"app.docker.tar", ctx.FormatDockerImage},
+		{"app.spdx.json", ctx.FormatSBOM},
+		{"app.json", ctx.FormatOther},
+		{"app.uf2", "test/firmware"},
+	}
+
+	if err := ctx.RegisterFormat("test/firmware", ".UF2"); err != nil {
+		t.Fatalf("RegisterFormat() error = %v", err)
+	}
+
+	for _, tt := range tests {
+		if got := (&ctx.Artifact{Filename: tt.filename}).Format(); got != tt.want {
+			t.Errorf("Artifact{%s}.Format() = %s, want %s", tt.filename, got, tt.want)
+		}
+	}
+}
+
+func TestRegisterFormat(t *testing.T) {
+	if err := ctx.RegisterFormat(ctx.FormatZip, ".zip2"); err == nil {
+		t.Error("RegisterFormat() accepted a core format")
+	}
+
+	if err := ctx.RegisterFormat("test/zip", ".zip"); err == nil {
+		t.Error("RegisterFormat() accepted a core format's suffix")
+	}
+
+	if err := ctx.RegisterFormat("test/archive", ".tar.lz"); err != nil {
+		t.Fatalf("RegisterFormat() error = %v", err)
+	}
+
+	art := &ctx.Artifact{Filename: "app.tar.lz"}
+	if got := art.Format(); got != "test/archive" {
+		t.Errorf("Artifact.Format() = %s, want test/archive", got)
+	}
+
+	art.FileFormat = ctx.FormatDockerImage
+	if got := art.Format(); got != ctx.FormatDockerImage {
+		t.Errorf("Artifact.Format() = %s, want explicit %s", got, ctx.FormatDockerImage)
+	}
+}
@@ -0,0 +1,155 @@
+package ctx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// LockFilename is the lock file's name in TargetDir, preventing
+	// concurrent runs
+	LockFilename = ".lock"
+	// lockPollInterval is the interval of checking a held lock
+	lockPollInterval = 500 * time.Millisecond
+)
+
+// errLocked is returned by lockFile, if the lock is held by another run
+var errLocked = errors.New("locked")
+
+// lockInfo is the content of a lock file
+type lockInfo struct {
+	PID      int       `json:"pid"`
+	Hostname string    `json:"hostname"`
+	Created  time.Time `json:"created"`
+}
+
+// LockTargetDir acquires an exclusive lock on TargetDir, so concurrent
+// runs don't interleave their writes. The lock is an OS-level lock of the
+// lock file (see lockFile), therefore it is released by the OS if the run
+// dies, and lock files left behind are taken over. If the lock is held by
+// another run, it waits for at most wait, or fails immediately if wait is
+// zero. Release the lock with UnlockTargetDir.
+func (c *Context) LockTargetDir(wait time.Duration) error {
+	if c.lockFile != nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(c.TargetDir, 0o755); err != nil {
+		return fmt.Errorf("creating target directory: %w", err)
+	}
+
+	fn := filepath.Join(c.TargetDir, LockFilename)
+	deadline := time.Now().Add(wait)
+	hostname, _ := os.Hostname()
+
+	data, err := json.Marshal(&lockInfo{PID: os.Getpid(), Hostname: hostname, Created: time.Now()})
+	if err != nil {
+		return err
+	}
+
+	for {
+		file, err := lockFile(fn)
+		if err == nil {
+			if err := writeLock(file, data); err != nil {
+				_ = unlockFile(file)
+				return fmt.Errorf("writing lock file: %w", err)
+			}
+
+			c.lockFile = file
+
+			return nil
+		}
+
+		if !errors.Is(err, errLocked) {
+			return fmt.Errorf("creating lock file: %w", err)
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s is locked by %s; another run is in progress", c.TargetDir, readLock(fn))
+		}
+
+		if err := c.Err(); err != nil {
+			return err
+		}
+
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// MoveTargetDir changes TargetDir to dir. If TargetDir is locked (see
+// LockTargetDir), dir is locked too, waiting for at most wait, and the old
+// lock is released only afterwards.
+func (c *Context) MoveTargetDir(dir string, wait time.Duration) error {
+	oldDir, oldLock := c.TargetDir, c.lockFile
+
+	if filepath.Clean(dir) == filepath.Clean(oldDir) {
+		return nil
+	}
+
+	c.TargetDir = dir
+
+	if oldLock == nil {
+		return nil
+	}
+
+	c.lockFile = nil
+
+	if err := c.LockTargetDir(wait); err != nil {
+		c.TargetDir, c.lockFile = oldDir, oldLock
+
+		return err
+	}
+
+	if err := unlockFile(oldLock); err != nil {
+		return fmt.Errorf("removing lock file: %w", err)
+	}
+
+	return nil
+}
+
+// UnlockTargetDir releases the lock acquired by LockTargetDir
+func (c *Context) UnlockTargetDir() error {
+	if c.lockFile == nil {
+		return nil
+	}
+
+	file := c.lockFile
+	c.lockFile = nil
+
+	if err := unlockFile(file); err != nil {
+		return fmt.Errorf("removing lock file: %w", err)
+	}
+
+	return nil
+}
+
+// writeLock replaces the content of a locked lock file
+func writeLock(file *os.File, data []byte) error {
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+
+	_, err := file.WriteAt(data, 0)
+
+	return err
+}
+
+// readLock returns the description of a lock file's owner
+func readLock(fn string) string {
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		return "unknown process"
+	}
+
+	info := &lockInfo{}
+	if err := json.Unmarshal(data, info); err != nil {
+		// lock file is being written
+		return "unknown process"
+	}
+
+	return fmt.Sprintf("pid %d on %s since %s", info.PID, info.Hostname, info.Created.Format(time.RFC3339))
+}
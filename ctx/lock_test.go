@@ -0,0 +1,137 @@
+package ctx
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestContext_LockTargetDir(t *testing.T) {
+	dir := t.TempDir()
+	first := &Context{Context: context.Background(), TargetDir: dir}
+	second := &Context{Context: context.Background(), TargetDir: dir}
+
+	if err := first.LockTargetDir(0); err != nil {
+		t.Fatalf("first LockTargetDir() error = %v", err)
+	}
+
+	err := second.LockTargetDir(0)
+	if err == nil || !strings.Contains(err.Error(), "another run is in progress") {
+		t.Errorf("second LockTargetDir() error = %v, want lock error", err)
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+
+		_ = first.UnlockTargetDir()
+	}()
+
+	if err := second.LockTargetDir(5 * time.Second); err != nil {
+		t.Errorf("waiting LockTargetDir() error = %v", err)
+	}
+
+	if err := second.UnlockTargetDir(); err != nil {
+		t.Errorf("UnlockTargetDir() error = %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, LockFilename)); !os.IsNotExist(err) {
+		t.Errorf("lock file exists after unlock: %v", err)
+	}
+}
+
+func TestContext_LockTargetDirLeftover(t *testing.T) {
+	dir := t.TempDir()
+	data, _ := json.Marshal(&lockInfo{PID: 1 << 30, Hostname: "elsewhere", Created: time.Now()})
+
+	if err := os.WriteFile(filepath.Join(dir, LockFilename), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	context := &Context{Context: context.Background(), TargetDir: dir}
+
+	if err := context.LockTargetDir(0); err != nil {
+		t.Errorf("LockTargetDir() error = %v", err)
+	}
+
+	if err := context.UnlockTargetDir(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestContext_LockTargetDirConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	contexts := make([]*Context, 20)
+	errs := make([]error, len(contexts))
+
+	var wg sync.WaitGroup
+
+	for idx := range contexts {
+		contexts[idx] = &Context{Context: context.Background(), TargetDir: dir}
+
+		wg.Add(1)
+
+		go func(idx int) {
+			defer wg.Done()
+
+			errs[idx] = contexts[idx].LockTargetDir(0)
+		}(idx)
+	}
+
+	wg.Wait()
+
+	locked := 0
+
+	for idx, err := range errs {
+		if err == nil {
+			locked++
+
+			defer contexts[idx].UnlockTargetDir() // nolint: errcheck
+
+			continue
+		}
+
+		if !strings.Contains(err.Error(), "locked by pid") && !strings.Contains(err.Error(), "locked by unknown process") {
+			t.Errorf("LockTargetDir() error = %v", err)
+		}
+	}
+
+	if locked != 1 {
+		t.Errorf("LockTargetDir() locked %d times, want once", locked)
+	}
+}
+
+func TestContext_MoveTargetDir(t *testing.T) {
+	base := t.TempDir()
+	old := filepath.Join(base, "dist")
+	next := filepath.Join(old, "v1.2.3")
+	context := &Context{Context: context.Background(), TargetDir: old}
+
+	if err := context.LockTargetDir(0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := context.MoveTargetDir(next, 0); err != nil {
+		t.Fatalf("MoveTargetDir() error = %v", err)
+	}
+
+	if context.TargetDir != next {
+		t.Errorf("MoveTargetDir() set TargetDir = %s, want %s", context.TargetDir, next)
+	}
+
+	if _, err := os.Stat(filepath.Join(old, LockFilename)); !os.IsNotExist(err) {
+		t.Errorf("old lock file exists after move: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(next, LockFilename)); err != nil {
+		t.Errorf("new lock file missing: %v", err)
+	}
+
+	if err := context.UnlockTargetDir(); err != nil {
+		t.Error(err)
+	}
+}
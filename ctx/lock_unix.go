@@ -0,0 +1,65 @@
+//go:build !windows
+// +build !windows
+
+package ctx
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// lockFile opens, and locks a lock file with flock(2), or returns
+// errLocked, if it's locked by another process. Lock files removed by
+// their owner, while waiting for the lock, are not accepted.
+func lockFile(fn string) (*os.File, error) {
+	for {
+		file, err := os.OpenFile(fn, os.O_CREATE|os.O_RDWR, 0o644)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+			file.Close()
+
+			if errors.Is(err, syscall.EWOULDBLOCK) {
+				return nil, errLocked
+			}
+
+			return nil, err
+		}
+
+		// the previous owner might have removed the file after we opened it
+		opened, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+
+		current, err := os.Stat(fn)
+		if err == nil && os.SameFile(opened, current) {
+			return file, nil
+		}
+
+		file.Close()
+
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+}
+
+// unlockFile removes a lock file, and releases its lock. The file is
+// removed while it's still locked, so waiting processes notice it.
+func unlockFile(file *os.File) error {
+	err := os.Remove(file.Name())
+	if errors.Is(err, os.ErrNotExist) {
+		err = nil
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
@@ -0,0 +1,57 @@
+//go:build windows
+// +build windows
+
+package ctx
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// errorSharingViolation is returned by CreateFile for files opened by
+// another process without sharing
+const errorSharingViolation syscall.Errno = 32
+
+// lockFile opens a lock file without sharing write access, or returns
+// errLocked, if it's opened by another process. The lock is released by
+// closing the file.
+func lockFile(fn string) (*os.File, error) {
+	name, err := syscall.UTF16PtrFromString(fn)
+	if err != nil {
+		return nil, err
+	}
+
+	handle, err := syscall.CreateFile(
+		name,
+		syscall.GENERIC_READ|syscall.GENERIC_WRITE,
+		syscall.FILE_SHARE_READ,
+		nil,
+		syscall.OPEN_ALWAYS,
+		syscall.FILE_ATTRIBUTE_NORMAL,
+		0,
+	)
+	if err != nil {
+		if errors.Is(err, errorSharingViolation) {
+			return nil, errLocked
+		}
+
+		return nil, &os.PathError{Op: "open", Path: fn, Err: err}
+	}
+
+	return os.NewFile(uintptr(handle), fn), nil
+}
+
+// unlockFile releases the lock of a lock file, and removes it, unless
+// another process locked it in the meantime
+func unlockFile(file *os.File) error {
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Remove(file.Name()); err != nil && !errors.Is(err, os.ErrNotExist) && !errors.Is(err, errorSharingViolation) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package ctx
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ManifestFilename is the name of the artifact manifest in the target
+// directory
+const ManifestFilename = "artifacts.json"
+
+// Manifest is a portable list of artifacts, to hand them over to another
+// pipeline run (eg. a publishing CI job). Artifact locations are relative
+// to the manifest's directory.
+type Manifest struct {
+	Artifacts   Artifacts `json:"artifacts"`
+	ProjectName string    `json:"project_name"`
+	Version     string    `json:"version"`
+}
+
+// WriteManifest writes the manifest of registered artifacts into the target
+// directory
+func (c *Context) WriteManifest() error {
+	if c.TargetDir == "" {
+		return nil
+	}
+
+	manifest := &Manifest{
+		Artifacts:   Artifacts{},
+		ProjectName: c.ProjectName,
+		Version:     c.Version,
+	}
+
+	for _, artifact := range c.Artifacts {
+		location, err := filepath.Rel(c.TargetDir, artifact.Location)
+		if err != nil {
+			location = artifact.Location
+		}
+
+		item := *artifact
+		item.Location = filepath.ToSlash(location)
+		manifest.Artifacts = append(manifest.Artifacts, &item)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding artifact manifest: %w", err)
+	}
+
+	if err := os.MkdirAll(c.TargetDir, 0o755); err != nil {
+		return fmt.Errorf("creating target directory: %w", err)
+	}
+
+	fn := filepath.Join(c.TargetDir, ManifestFilename)
+	if err := os.WriteFile(fn, append(data, '\n'), 0o644); err != nil { // nolint: gosec
+		return fmt.Errorf("writing artifact manifest %s: %w", fn, err)
+	}
+
+	return nil
+}
+
+// ReadManifest reads an artifact manifest, resolving artifact locations
+func ReadManifest(fn string) (*Manifest, error) {
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, fmt.Errorf("reading artifact manifest: %w", err)
+	}
+
+	manifest := &Manifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("decoding artifact manifest %s: %w", fn, err)
+	}
+
+	dir := filepath.Dir(fn)
+
+	for _, artifact := range manifest.Artifacts {
+		location := filepath.FromSlash(artifact.Location)
+		if !filepath.IsAbs(location) {
+			location = filepath.Join(dir, location)
+		}
+
+		artifact.Location = location
+	}
+
+	return manifest, nil
+}
@@ -1,13 +1,37 @@
 package ctx
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
 
+// knownOS and knownArch are GOOS and GOARCH values recognized in file names
+// nolint: gochecknoglobals
+var (
+	knownOS = map[string]bool{
+		"aix": true, "android": true, "darwin": true, "dragonfly": true,
+		"freebsd": true, "illumos": true, "ios": true, "js": true,
+		"linux": true, "netbsd": true, "openbsd": true, "plan9": true,
+		"solaris": true, "windows": true,
+	}
+	knownArch = map[string]bool{
+		"386": true, "amd64": true, "arm": true, "arm64": true,
+		"mips": true, "mips64": true, "mips64le": true, "mipsle": true,
+		"ppc64": true, "ppc64le": true, "riscv64": true, "s390x": true,
+		"wasm": true,
+	}
+)
+
+// OsArch is a target platform of an artifact
 type OsArch struct {
-	OS         string
-	Arch       string
-	ArmVersion int32
+	OS         string `json:"os"`
+	Arch       string `json:"arch"`
+	ArmVersion int32  `json:"arm_version,omitempty"`
 }
 
+// ArchName returns the architecture's name, including ARM versions (eg.
+// "armv7")
 func (oa *OsArch) ArchName() string {
 	if oa.Arch == "arm" && oa.ArmVersion > 0 {
 		return fmt.Sprintf("%sv%d", oa.Arch, oa.ArmVersion)
@@ -16,6 +40,7 @@ func (oa *OsArch) ArchName() string {
 	return oa.Arch
 }
 
+// String returns the platform in "os-arch" format, or "noarch"
 func (oa *OsArch) String() string {
 	if oa == nil {
 		return "noarch"
@@ -23,3 +48,41 @@ func (oa *OsArch) String() string {
 
 	return fmt.Sprintf("%s-%s", oa.OS, oa.ArchName())
 }
+
+// ParseOsArch parses GOOS and GOARCH values, including ARM versions (eg.
+// "armv7"). It returns nil for unknown values.
+func ParseOsArch(goos, arch string) *OsArch {
+	if !knownOS[goos] {
+		return nil
+	}
+
+	if knownArch[arch] {
+		return &OsArch{OS: goos, Arch: arch}
+	}
+
+	if strings.HasPrefix(arch, "armv") {
+		version, err := strconv.ParseInt(arch[4:], 10, 32)
+		if err == nil && version >= 5 && version <= 7 {
+			return &OsArch{OS: goos, Arch: "arm", ArmVersion: int32(version)}
+		}
+	}
+
+	return nil
+}
+
+// OsArchFromFilename finds GOOS and GOARCH values in a file name's `-`,
+// `_`, or `.` separated parts (eg. "app_1.0.0_linux_amd64.tar.gz"). It
+// returns nil if there are none.
+func OsArchFromFilename(filename string) *OsArch {
+	parts := strings.FieldsFunc(filename, func(r rune) bool {
+		return r == '-' || r == '_' || r == '.'
+	})
+
+	for i := 0; i+1 < len(parts); i++ {
+		if osarch := ParseOsArch(parts[i], parts[i+1]); osarch != nil {
+			return osarch
+		}
+	}
+
+	return nil
+}
@@ -80,3 +80,26 @@ func TestOsarch_String(t *testing.T) {
 		})
 	}
 }
+
+func TestOsArchFromFilename(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		want     string
+	}{
+		{name: "archive", filename: "app_1.0.0_linux_amd64.tar.gz", want: "linux-amd64"},
+		{name: "arm version", filename: "app-linux-armv7", want: "linux-armv7"},
+		{name: "invalid arm version", filename: "app-linux-armv9", want: "noarch"},
+		{name: "unknown", filename: "app-1.0.0.zip", want: "noarch"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ctx.OsArchFromFilename(tt.filename).String(); got != tt.want {
+				t.Errorf("OsArchFromFilename() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package ctx
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Progress is a status report of the currently running module. Modules
+// can update it from any goroutine, and the pipeline reports it in
+// heartbeat log lines for long-running modules.
+type Progress struct {
+	bytes int64
+	mu    sync.Mutex
+	state string
+}
+
+// SetState sets a short description of what the module is doing
+func (p *Progress) SetState(state string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.state = state
+}
+
+// AddBytes adds n to the number of bytes transferred
+func (p *Progress) AddBytes(n int64) {
+	atomic.AddInt64(&p.bytes, n)
+}
+
+// Bytes returns the number of bytes transferred so far
+func (p *Progress) Bytes() int64 {
+	return atomic.LoadInt64(&p.bytes)
+}
+
+// Reader wraps an io.Reader, counting bytes read into Progress
+func (p *Progress) Reader(reader io.Reader) io.Reader {
+	return &progressReader{Reader: reader, progress: p}
+}
+
+// String returns a human-readable report of the progress, or an empty
+// string if there is nothing to report
+func (p *Progress) String() string {
+	if p == nil {
+		return ""
+	}
+
+	p.mu.Lock()
+	state := p.state
+	p.mu.Unlock()
+
+	items := []string{}
+
+	if state != "" {
+		items = append(items, state)
+	}
+
+	if bytes := p.Bytes(); bytes > 0 {
+		items = append(items, fmt.Sprintf("%s transferred", FormatBytes(bytes)))
+	}
+
+	return strings.Join(items, ", ")
+}
+
+// FormatBytes returns byte size in a human-readable, binary prefixed format
+func FormatBytes(bytes int64) string {
+	const unit = 1024
+
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+type progressReader struct {
+	io.Reader
+	progress *Progress
+}
+
+func (r *progressReader) Read(buf []byte) (int, error) {
+	n, err := r.Reader.Read(buf)
+	r.progress.AddBytes(int64(n))
+
+	return n, err
+}
@@ -0,0 +1,47 @@
+package ctx_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		bytes int64
+		want  string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{5 * 1024 * 1024, "5.0 MiB"},
+		{3 * 1024 * 1024 * 1024, "3.0 GiB"},
+	}
+
+	for _, tt := range tests {
+		if got := ctx.FormatBytes(tt.bytes); got != tt.want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", tt.bytes, got, tt.want)
+		}
+	}
+}
+
+func TestProgress_String(t *testing.T) {
+	progress := &ctx.Progress{}
+
+	if got := progress.String(); got != "" {
+		t.Errorf("empty Progress.String() = %q", got)
+	}
+
+	progress.SetState("uploading")
+
+	if _, err := io.Copy(io.Discard, progress.Reader(bytes.NewReader(make([]byte, 2048)))); err != nil {
+		t.Errorf("reading through progress: %v", err)
+	}
+
+	if got, want := progress.String(), "uploading, 2.0 KiB transferred"; got != want {
+		t.Errorf("Progress.String() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,105 @@
+package ctx
+
+import "sync"
+
+type (
+	// Link is a named URL, like a release page
+	Link struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	}
+
+	// Download is a download link of a published artifact
+	Download struct {
+		Filename string `json:"filename"`
+		OsArch   string `json:"osarch"`
+		URL      string `json:"url"`
+	}
+
+	// Release contains user-facing information of a published release:
+	// where it can be found, and how it can be installed.
+	Release struct {
+		Downloads []Download `json:"downloads,omitempty"`
+		Images    []string   `json:"images,omitempty"`
+		Install   []string   `json:"install,omitempty"`
+		Links     []Link     `json:"links,omitempty"`
+	}
+
+	// Published collects release information from publishers. It is safe
+	// for concurrent use.
+	Published struct {
+		mu      sync.Mutex
+		release Release
+	}
+)
+
+// AddLink records a release page, or other link of interest
+func (p *Published) AddLink(name, url string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.release.Links = append(p.release.Links, Link{Name: name, URL: url})
+}
+
+// AddDownload records the download link of an artifact
+func (p *Published) AddDownload(artifact *Artifact, url string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.release.Downloads = append(p.release.Downloads, Download{
+		Filename: artifact.Filename,
+		OsArch:   artifact.OsArch.String(),
+		URL:      url,
+	})
+}
+
+// DownloadURL returns the recorded download link of an artifact by its
+// file name
+func (p *Published) DownloadURL(filename string) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, download := range p.release.Downloads {
+		if download.Filename == filename {
+			return download.URL, true
+		}
+	}
+
+	return "", false
+}
+
+// AddImage records a pushed container image reference, preferably with
+// its digest
+func (p *Published) AddImage(image string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.release.Images = append(p.release.Images, image)
+}
+
+// AddInstall records an install command, like `brew install owner/tap/app`
+func (p *Published) AddInstall(command string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.release.Install = append(p.release.Install, command)
+}
+
+// Release returns a copy of recorded release information, or nil if
+// nothing has been recorded
+func (p *Published) Release() *Release {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	rel := p.release
+	if len(rel.Downloads)+len(rel.Images)+len(rel.Install)+len(rel.Links) == 0 {
+		return nil
+	}
+
+	return &Release{
+		Downloads: append([]Download(nil), rel.Downloads...),
+		Images:    append([]string(nil), rel.Images...),
+		Install:   append([]string(nil), rel.Install...),
+		Links:     append([]Link(nil), rel.Links...),
+	}
+}
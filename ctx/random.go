@@ -0,0 +1,71 @@
+package ctx
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"sync"
+)
+
+// SeedEnv is the environment variable seeding Random of a pipeline run,
+// for reproducible outputs
+const SeedEnv = "GOSHIPDONE_SEED"
+
+// Random is a seedable source of identifiers (upload session IDs,
+// multipart boundaries, and similar), which are not security sensitive.
+// Runs with the same seed produce the same outputs. It is safe for
+// concurrent use.
+type Random struct {
+	mu   sync.Mutex
+	rnd  *rand.Rand
+	seed int64
+}
+
+// NewRandom returns a new Random, seeded by seed
+func NewRandom(seed int64) *Random {
+	return &Random{
+		// nolint: gosec
+		rnd:  rand.New(rand.NewSource(seed)),
+		seed: seed,
+	}
+}
+
+// Seed returns the seed Random was created with
+func (r *Random) Seed() int64 {
+	return r.seed
+}
+
+// Read fills p with random bytes. It implements io.Reader, and never fails.
+func (r *Random) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.rnd.Read(p)
+}
+
+// Int63n returns a non-negative random number in [0,n)
+func (r *Random) Int63n(n int64) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.rnd.Int63n(n)
+}
+
+// Hex returns n random bytes in hexadecimal format
+func (r *Random) Hex(n int) string {
+	buf := make([]byte, n)
+	_, _ = r.Read(buf)
+
+	return hex.EncodeToString(buf)
+}
+
+// UUID returns a random (version 4) UUID
+func (r *Random) UUID() string {
+	buf := make([]byte, 16)
+	_, _ = r.Read(buf)
+
+	buf[6] = (buf[6] & 0x0f) | 0x40
+	buf[8] = (buf[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", buf[0:4], buf[4:6], buf[6:8], buf[8:10], buf[10:])
+}
@@ -0,0 +1,34 @@
+package ctx_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+func TestRandom(t *testing.T) {
+	first := ctx.NewRandom(42)
+	second := ctx.NewRandom(42)
+
+	if a, b := first.UUID(), second.UUID(); a != b {
+		t.Errorf("UUIDs with the same seed differ: %s != %s", a, b)
+	}
+
+	if a, b := first.Hex(8), second.Hex(8); a != b {
+		t.Errorf("Hex with the same seed differ: %s != %s", a, b)
+	}
+
+	if other := ctx.NewRandom(43).UUID(); other == ctx.NewRandom(42).UUID() {
+		t.Errorf("UUIDs with different seeds are the same: %s", other)
+	}
+
+	reUUID := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if uuid := first.UUID(); !reUUID.MatchString(uuid) {
+		t.Errorf("UUID() = %s, not a version 4 UUID", uuid)
+	}
+
+	if hex := first.Hex(3); len(hex) != 6 {
+		t.Errorf("Hex(3) = %s, want 6 characters", hex)
+	}
+}
@@ -0,0 +1,140 @@
+package ctx
+
+// Selector filters artifacts by chaining conditions (see
+// Artifacts.Select). Artifacts are selected if they match all
+// conditions.
+type Selector struct {
+	arts    Artifacts
+	byName  bool
+	names   []string
+	filters []func(*Artifact) bool
+}
+
+// Select returns a Selector of the artifacts
+//
+// Example: `arts.Select().Name("archive").OS("linux").Format(ctx.FormatTar).List()`
+func (arts *Artifacts) Select() *Selector {
+	return &Selector{arts: *arts}
+}
+
+// Name selects artifacts by their build names (IDs). Artifacts are listed
+// in the order of names.
+func (sel *Selector) Name(names ...string) *Selector {
+	sel.byName = true
+	sel.names = append(sel.names, names...)
+
+	return sel
+}
+
+// OS selects artifacts built for any of the operating systems
+func (sel *Selector) OS(oses ...string) *Selector {
+	return sel.Filter(func(art *Artifact) bool {
+		return art.OsArch != nil && contains(oses, art.OS)
+	})
+}
+
+// Arch selects artifacts built for any of the architectures
+func (sel *Selector) Arch(archs ...string) *Selector {
+	return sel.Filter(func(art *Artifact) bool {
+		return art.OsArch != nil && contains(archs, art.Arch)
+	})
+}
+
+// Format selects artifacts of any of the file formats
+func (sel *Selector) Format(list ...Format) *Selector {
+	return sel.Filter(func(art *Artifact) bool {
+		format := art.Format()
+
+		for _, item := range list {
+			if item == format {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// Meta selects artifacts annotated with a key, and value (see
+// Artifact.SetMeta)
+func (sel *Selector) Meta(key, value string) *Selector {
+	return sel.Filter(func(art *Artifact) bool {
+		return art.HasMeta(key, value)
+	})
+}
+
+// Skip drops artifacts of OS-Arch combinations (eg. "linux-armv6")
+func (sel *Selector) Skip(osarchs ...string) *Selector {
+	return sel.Filter(func(art *Artifact) bool {
+		return !contains(osarchs, art.OsArch.String())
+	})
+}
+
+// Filter selects artifacts a function returns true for
+func (sel *Selector) Filter(fn func(*Artifact) bool) *Selector {
+	sel.filters = append(sel.filters, fn)
+
+	return sel
+}
+
+// List returns selected artifacts
+func (sel *Selector) List() Artifacts {
+	candidates := sel.arts
+
+	if sel.byName {
+		candidates = Artifacts{}
+
+		for _, name := range sel.names {
+			candidates = append(candidates, *sel.arts.ByID(name)...)
+		}
+	}
+
+	results := Artifacts{}
+
+	for _, art := range candidates {
+		if sel.matches(art) {
+			results = append(results, art)
+		}
+	}
+
+	return results
+}
+
+// ByOsArch returns selected artifacts mapped by OS-Arch
+func (sel *Selector) ByOsArch() map[string]*Artifacts {
+	results := map[string]*Artifacts{}
+
+	for _, art := range sel.List() {
+		osarch := art.OsArch.String()
+
+		if _, ok := results[osarch]; !ok {
+			results[osarch] = &Artifacts{}
+		}
+
+		*results[osarch] = append(*results[osarch], art)
+	}
+
+	return results
+}
+
+// matches returns true if an artifact matches all filters
+func (sel *Selector) matches(art *Artifact) bool {
+	for _, fn := range sel.filters {
+		if !fn(art) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// contains returns true if a list contains an item
+func contains(list []string, item string) bool {
+	for _, elem := range list {
+		if elem == item {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,59 @@
+package ctx_test
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/julian7/goshipdone/ctx"
+)
+
+func TestSelector(t *testing.T) {
+	linux := &ctx.OsArch{OS: "linux", Arch: "amd64"}
+	arm := &ctx.OsArch{OS: "linux", Arch: "arm", ArmVersion: 6}
+	windows := &ctx.OsArch{OS: "windows", Arch: "amd64"}
+	arts := ctx.Artifacts{
+		{Filename: "app", ID: "default", OsArch: linux},
+		{Filename: "app.exe", ID: "default", OsArch: windows},
+		{Filename: "app-linux-amd64.tar.gz", ID: "archive", OsArch: linux},
+		{Filename: "app-linux-armv6.tar.gz", ID: "archive", OsArch: arm},
+		{Filename: "app-windows-amd64.zip", ID: "archive", OsArch: windows, Meta: map[string]string{"channel": "stable"}},
+		{Filename: "app-checksums.txt", ID: "checksum"},
+	}
+
+	tests := []struct {
+		name string
+		sel  *ctx.Selector
+		want []string
+	}{
+		{name: "all", sel: arts.Select(), want: []string{
+			"app", "app.exe", "app-linux-amd64.tar.gz", "app-linux-armv6.tar.gz", "app-windows-amd64.zip", "app-checksums.txt",
+		}},
+		{name: "names in order", sel: arts.Select().Name("checksum", "default"), want: []string{"app-checksums.txt", "app", "app.exe"}},
+		{name: "no names", sel: arts.Select().Name(), want: []string{}},
+		{name: "os", sel: arts.Select().Name("archive").OS("linux"), want: []string{"app-linux-amd64.tar.gz", "app-linux-armv6.tar.gz"}},
+		{name: "arch", sel: arts.Select().Arch("arm"), want: []string{"app-linux-armv6.tar.gz"}},
+		{name: "format", sel: arts.Select().Format(ctx.FormatTar, ctx.FormatZip).Skip("linux-armv6"), want: []string{
+			"app-linux-amd64.tar.gz", "app-windows-amd64.zip",
+		}},
+		{name: "meta", sel: arts.Select().Meta("channel", "stable"), want: []string{"app-windows-amd64.zip"}},
+		{name: "filter", sel: arts.Select().Filter(func(art *ctx.Artifact) bool { return art.OsArch == nil }), want: []string{
+			"app-checksums.txt",
+		}},
+	}
+
+	for _, tt := range tests {
+		got := []string{}
+		for _, art := range tt.sel.List() {
+			got = append(got, art.Filename)
+		}
+
+		if diff := deep.Equal(got, tt.want); diff != nil {
+			t.Errorf("Selector.List() of %s %v", tt.name, diff)
+		}
+	}
+
+	byOsArch := arts.Select().Name("archive").ByOsArch()
+	if len(byOsArch) != 3 || len(*byOsArch["linux-amd64"]) != 1 {
+		t.Errorf("Selector.ByOsArch() = %v", byOsArch)
+	}
+}
@@ -0,0 +1,155 @@
+package ctx
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// nolint: gochecknoglobals
+var reSemver = regexp.MustCompile(
+	`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+		`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+		`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`,
+)
+
+// Semver is a semantic version (https://semver.org/)
+type Semver struct {
+	Major uint64
+	Minor uint64
+	Patch uint64
+	// Prerelease is the pre-release part after "-", like "rc.1"
+	Prerelease string
+	// Metadata is the build metadata after "+"
+	Metadata string
+}
+
+// ParseSemver parses a semantic version, with an optional "v" prefix
+func ParseSemver(version string) (*Semver, error) {
+	match := reSemver.FindStringSubmatch(version)
+	if match == nil {
+		return nil, fmt.Errorf("%q is not a semantic version", version)
+	}
+
+	semver := &Semver{Prerelease: match[4], Metadata: match[5]}
+
+	for i, target := range []*uint64{&semver.Major, &semver.Minor, &semver.Patch} {
+		val, err := strconv.ParseUint(match[i+1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a semantic version: %w", version, err)
+		}
+
+		*target = val
+	}
+
+	return semver, nil
+}
+
+// Compare compares precedence of versions, returning -1, 0, or 1, if the
+// version is lower, equal, or higher than other. Build metadata is
+// ignored.
+func (semver *Semver) Compare(other *Semver) int {
+	for _, pair := range [][2]uint64{
+		{semver.Major, other.Major},
+		{semver.Minor, other.Minor},
+		{semver.Patch, other.Patch},
+	} {
+		if cmp := compareUint(pair[0], pair[1]); cmp != 0 {
+			return cmp
+		}
+	}
+
+	switch {
+	case semver.Prerelease == other.Prerelease:
+		return 0
+	case semver.Prerelease == "":
+		return 1
+	case other.Prerelease == "":
+		return -1
+	}
+
+	left := strings.Split(semver.Prerelease, ".")
+	right := strings.Split(other.Prerelease, ".")
+
+	for i := 0; i < len(left) && i < len(right); i++ {
+		if cmp := comparePrerelease(left[i], right[i]); cmp != 0 {
+			return cmp
+		}
+	}
+
+	return compareUint(uint64(len(left)), uint64(len(right)))
+}
+
+// comparePrerelease compares pre-release identifiers: numeric identifiers
+// are compared numerically, and they have lower precedence than
+// alphanumeric ones
+func comparePrerelease(left, right string) int {
+	leftNum, leftErr := strconv.ParseUint(left, 10, 64)
+	rightNum, rightErr := strconv.ParseUint(right, 10, 64)
+
+	switch {
+	case leftErr == nil && rightErr == nil:
+		return compareUint(leftNum, rightNum)
+	case leftErr == nil:
+		return -1
+	case rightErr == nil:
+		return 1
+	}
+
+	return strings.Compare(left, right)
+}
+
+func compareUint(left, right uint64) int {
+	switch {
+	case left < right:
+		return -1
+	case left > right:
+		return 1
+	}
+
+	return 0
+}
+
+// IsPrerelease tells whether the version is a pre-release
+func (semver *Semver) IsPrerelease() bool {
+	return semver.Prerelease != ""
+}
+
+// IncMajor returns the next major version, like "2.0.0" for "1.2.3"
+func (semver *Semver) IncMajor() *Semver {
+	return &Semver{Major: semver.Major + 1}
+}
+
+// IncMinor returns the next minor version, like "1.3.0" for "1.2.3"
+func (semver *Semver) IncMinor() *Semver {
+	return &Semver{Major: semver.Major, Minor: semver.Minor + 1}
+}
+
+// IncPatch returns the next patch version, like "1.2.4" for "1.2.3". The
+// next version of a pre-release is its release, like "1.2.3" for
+// "1.2.3-rc.1".
+func (semver *Semver) IncPatch() *Semver {
+	if semver.IsPrerelease() {
+		return &Semver{Major: semver.Major, Minor: semver.Minor, Patch: semver.Patch}
+	}
+
+	return &Semver{Major: semver.Major, Minor: semver.Minor, Patch: semver.Patch + 1}
+}
+
+// String returns the version without "v" prefix
+func (semver *Semver) String() string {
+	out := &strings.Builder{}
+
+	fmt.Fprintf(out, "%d.%d.%d", semver.Major, semver.Minor, semver.Patch)
+
+	if semver.Prerelease != "" {
+		out.WriteString("-" + semver.Prerelease)
+	}
+
+	if semver.Metadata != "" {
+		out.WriteString("+" + semver.Metadata)
+	}
+
+	return out.String()
+}
@@ -0,0 +1,93 @@
+package ctx_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+func TestParseSemver(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		want    *ctx.Semver
+		wantErr bool
+	}{
+		{name: "plain", version: "1.2.3", want: &ctx.Semver{Major: 1, Minor: 2, Patch: 3}},
+		{name: "v prefix", version: "v10.0.1", want: &ctx.Semver{Major: 10, Patch: 1}},
+		{
+			name:    "prerelease and metadata",
+			version: "v1.0.0-rc.1+build.5",
+			want:    &ctx.Semver{Major: 1, Prerelease: "rc.1", Metadata: "build.5"},
+		},
+		{name: "metadata", version: "1.0.0+20210102", want: &ctx.Semver{Major: 1, Metadata: "20210102"}},
+		{name: "missing patch", version: "v1.2", wantErr: true},
+		{name: "leading zero", version: "v01.2.3", wantErr: true},
+		{name: "leading zero in prerelease", version: "v1.2.3-01", wantErr: true},
+		{name: "git describe", version: "v1.2.3-4-gabcdef0-dirty", want: &ctx.Semver{Major: 1, Minor: 2, Patch: 3, Prerelease: "4-gabcdef0-dirty"}},
+		{name: "not a version", version: "release", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ctx.ParseSemver(tt.version)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseSemver() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSemver() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSemver_String(t *testing.T) {
+	semver := &ctx.Semver{Major: 1, Minor: 2, Patch: 3, Prerelease: "beta.2", Metadata: "sha.abc"}
+	if got := semver.String(); got != "1.2.3-beta.2+sha.abc" {
+		t.Errorf("String() = %q", got)
+	}
+
+	if !semver.IsPrerelease() {
+		t.Error("IsPrerelease() = false, want true")
+	}
+}
+
+func TestSemver_Compare(t *testing.T) {
+	// in ascending order, from semver.org
+	versions := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.1.0",
+		"2.0.0",
+	}
+
+	for i := range versions {
+		for j := range versions {
+			left, _ := ctx.ParseSemver(versions[i])
+			right, _ := ctx.ParseSemver(versions[j])
+
+			want := 0
+			if i < j {
+				want = -1
+			} else if i > j {
+				want = 1
+			}
+
+			if got := left.Compare(right); got != want {
+				t.Errorf("%s.Compare(%s) = %d, want %d", versions[i], versions[j], got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package ctx
+
+const (
+	// HistoryDir is the directory under TargetDir, where per-run logs,
+	// resolved configuration, and reports are kept
+	HistoryDir = ".runs"
+	// ReportFilename is the name of the machine-readable report written
+	// into TargetDir at the end of the pipeline run
+	ReportFilename = "report.json"
+	// StateFilename is the name of the pipeline's state file in
+	// TargetDir, which resumed runs load artifacts from
+	StateFilename = ".state.json"
+)
+
+// IsReservedFile returns true if a file, or directory name in TargetDir
+// is used by goshipdone itself across runs: the lock file, the state
+// file, the artifact manifest, the report, run history, and temporary
+// directories
+func IsReservedFile(name string) bool {
+	switch name {
+	case LockFilename, StateFilename, ManifestFilename, ReportFilename, HistoryDir, tempDirName:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,73 @@
+package ctx
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+const tempDirName = ".tmp"
+
+// TempDir creates a new temporary directory for a module under
+// TargetDir/.tmp/<run timestamp>/, prefixed by namespace (usually the
+// module's type). The directory is removed by ReleaseTempDirs after the
+// module finished successfully, and retained if it failed, for debugging.
+func (c *Context) TempDir(namespace string) (string, error) {
+	base := filepath.Join(c.TargetDir, tempDirName, c.StartedAt.Format("20060102-150405"))
+
+	if err := os.MkdirAll(base, 0o755); err != nil {
+		return "", fmt.Errorf("creating temp directory base %s: %w", base, err)
+	}
+
+	dir, err := os.MkdirTemp(base, namespace+"-")
+	if err != nil {
+		return "", fmt.Errorf("creating temp directory for %s: %w", namespace, err)
+	}
+
+	c.tempDirs = append(c.tempDirs, dir)
+
+	return dir, nil
+}
+
+// SecretDir creates a private temporary directory for key material, and
+// credentials, prefixed by namespace (usually the module's type). Unlike
+// TempDir, it is outside TargetDir, and it is removed at the end of the
+// run (see OnFinish), even if the run failed.
+func (c *Context) SecretDir(namespace string) (string, error) {
+	dir, err := os.MkdirTemp("", "goshipdone-"+namespace+"-")
+	if err != nil {
+		return "", fmt.Errorf("creating secret directory for %s: %w", namespace, err)
+	}
+
+	c.OnFinish(namespace+" secrets", func() error {
+		return os.RemoveAll(dir)
+	})
+
+	return dir, nil
+}
+
+// ReleaseTempDirs cleans up all temporary directories created by TempDir
+// since the last call. If failed is true, directories are kept on the disk,
+// and their locations are logged instead.
+func (c *Context) ReleaseTempDirs(failed bool) error {
+	dirs := c.tempDirs
+	c.tempDirs = nil
+
+	for _, dir := range dirs {
+		if failed {
+			log.Printf("      keeping temp directory %s", dir)
+			continue
+		}
+
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("removing temp directory %s: %w", dir, err)
+		}
+
+		// remove parent directories only if they became empty
+		_ = os.Remove(filepath.Dir(dir))
+		_ = os.Remove(filepath.Dir(filepath.Dir(dir)))
+	}
+
+	return nil
+}
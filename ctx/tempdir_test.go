@@ -0,0 +1,81 @@
+package ctx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContext_TempDir(t *testing.T) {
+	tests := []struct {
+		name       string
+		failed     bool
+		wantExists bool
+	}{
+		{name: "success cleans up", failed: false, wantExists: false},
+		{name: "failure keeps directory", failed: true, wantExists: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			context := &Context{TargetDir: t.TempDir(), StartedAt: time.Now()}
+
+			dir, err := context.TempDir("test")
+			if err != nil {
+				t.Errorf("Context.TempDir() error = %v", err)
+				return
+			}
+
+			if rel, _ := filepath.Rel(context.TargetDir, dir); filepath.Dir(filepath.Dir(rel)) != tempDirName {
+				t.Errorf("Context.TempDir() = %s, not under %s", dir, tempDirName)
+			}
+
+			if err := context.ReleaseTempDirs(tt.failed); err != nil {
+				t.Errorf("Context.ReleaseTempDirs() error = %v", err)
+			}
+
+			if _, err := os.Stat(dir); (err == nil) != tt.wantExists {
+				t.Errorf("temp dir exists: %v, want %v", err == nil, tt.wantExists)
+			}
+
+			if _, err := os.Stat(filepath.Join(context.TargetDir, tempDirName)); (err == nil) != tt.wantExists {
+				t.Errorf("temp base dir exists: %v, want %v", err == nil, tt.wantExists)
+			}
+		})
+	}
+}
+
+func TestContext_SecretDir(t *testing.T) {
+	shipContext, err := GetShipContext(New(context.Background()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shipContext.TargetDir = t.TempDir()
+
+	dir, err := shipContext.SecretDir("test")
+	if err != nil {
+		t.Fatalf("Context.SecretDir() error = %v", err)
+	}
+
+	if rel, err := filepath.Rel(shipContext.TargetDir, dir); err == nil && !strings.HasPrefix(rel, "..") {
+		t.Errorf("Context.SecretDir() = %s, under target directory", dir)
+	}
+
+	if st, err := os.Stat(dir); err != nil || (runtime.GOOS != "windows" && st.Mode().Perm() != 0o700) {
+		t.Errorf("Context.SecretDir() created %s: %v", dir, err)
+	}
+
+	shipContext.Fail()
+	shipContext.Finish()
+
+	if _, err := os.Stat(dir); err == nil {
+		t.Errorf("Context.Finish() kept secret dir %s", dir)
+	}
+}
@@ -0,0 +1,80 @@
+package ctx
+
+import (
+	"sort"
+	"sync"
+)
+
+type (
+	// Transfer contains transfer statistics of a single destination
+	Transfer struct {
+		Destination string `json:"destination"`
+		Uploaded    int64  `json:"uploaded"`
+		Downloaded  int64  `json:"downloaded"`
+	}
+
+	// Transfers collects transfer statistics of publishers by destination,
+	// emitting EventUpload, and EventDownload events. It is safe for
+	// concurrent use.
+	Transfers struct {
+		events *Events
+		mu     sync.Mutex
+		items  map[string]*Transfer
+	}
+)
+
+// Upload records n bytes uploaded to destination
+func (t *Transfers) Upload(destination string, n int64) {
+	t.get(destination, func(item *Transfer) { item.Uploaded += n })
+	t.events.Emit(Event{Type: EventUpload, Destination: destination, Bytes: n})
+}
+
+// Download records n bytes downloaded from destination
+func (t *Transfers) Download(destination string, n int64) {
+	t.get(destination, func(item *Transfer) { item.Downloaded += n })
+	t.events.Emit(Event{Type: EventDownload, Destination: destination, Bytes: n})
+}
+
+// List returns transfer statistics ordered by destination
+func (t *Transfers) List() []Transfer {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	list := make([]Transfer, 0, len(t.items))
+	for _, item := range t.items {
+		list = append(list, *item)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Destination < list[j].Destination })
+
+	return list
+}
+
+// Total returns summarized transfer statistics of all destinations
+func (t *Transfers) Total() Transfer {
+	total := Transfer{Destination: "total"}
+
+	for _, item := range t.List() {
+		total.Uploaded += item.Uploaded
+		total.Downloaded += item.Downloaded
+	}
+
+	return total
+}
+
+func (t *Transfers) get(destination string, update func(*Transfer)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.items == nil {
+		t.items = map[string]*Transfer{}
+	}
+
+	item, ok := t.items[destination]
+	if !ok {
+		item = &Transfer{Destination: destination}
+		t.items[destination] = item
+	}
+
+	update(item)
+}
@@ -0,0 +1,32 @@
+package ctx_test
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/julian7/goshipdone/ctx"
+)
+
+func TestTransfers(t *testing.T) {
+	transfers := &ctx.Transfers{}
+
+	transfers.Upload("scp:b", 10)
+	transfers.Upload("github:a", 5)
+	transfers.Upload("scp:b", 20)
+	transfers.Download("github:a", 7)
+
+	want := []ctx.Transfer{
+		{Destination: "github:a", Uploaded: 5, Downloaded: 7},
+		{Destination: "scp:b", Uploaded: 30},
+	}
+
+	if diff := deep.Equal(transfers.List(), want); diff != nil {
+		t.Errorf("Transfers.List() %v", diff)
+	}
+
+	wantTotal := ctx.Transfer{Destination: "total", Uploaded: 35, Downloaded: 7}
+
+	if diff := deep.Equal(transfers.Total(), wantTotal); diff != nil {
+		t.Errorf("Transfers.Total() %v", diff)
+	}
+}
@@ -3,9 +3,11 @@
 package goshipdone
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 
+	"github.com/julian7/goshipdone/modules"
 	"github.com/julian7/goshipdone/pipeline"
 	"github.com/spf13/afero"
 )
@@ -14,6 +16,7 @@ const (
 	filenameEnv          = "GOSHIPDONE_CONFIG"
 	defaultFilename      = ".goshipdone.yml"
 	defaultLocalFilename = ".goshipdone.local.yml"
+	defaultDirname       = ".goshipdone"
 )
 
 // nolint: gochecknoglobals
@@ -24,29 +27,103 @@ var defaultFS = afero.NewOsFs()
 // - provided filename
 // - GOSHIPDONE_CONFIG environment variable
 // - .goshipdone.local.yml (if exists)
-// - .goshipdone.yml
+// - .goshipdone.yml (if exists)
+// - .goshipdone directory
+//
+// Directories are loaded by merging their `*.yml`, and `*.yaml` files in
+// lexical order. Files can include other files with `include`. The
+// profile selected by GOSHIPDONE_PROFILE environment variable, or detected
+// ("release" for tagged commits, "snapshot" otherwise) is applied from
+// `profiles`.
 //
 // It returns an error if any of the subsequent processing has an error.
 func Run(filename string) error {
-	filename = detectFilename(filename)
+	pipe, err := load(filename)
+	if err != nil {
+		return err
+	}
+
+	if err := pipe.Run(); err != nil {
+		return fmt.Errorf("running GoShipDone: %w", err)
+	}
+
+	return nil
+}
 
-	content, err := afero.ReadFile(defaultFS, filename)
+// Graph loads the configuration file like Run, and renders the resolved
+// pipeline (stages, modules, their dependencies, and artifacts flowing
+// between them) in Graphviz DOT ("dot"), or Mermaid ("mermaid") format,
+// without running it.
+func Graph(filename, format string) (string, error) {
+	pipe, err := load(filename)
 	if err != nil {
-		return fmt.Errorf("loading GoShipDone file: %w", err)
+		return "", err
 	}
 
-	pipe, err := pipeline.LoadBuildPipeline(content)
+	return pipe.Graph(format)
+}
+
+// Validate loads the configuration file like Run, and checks the resolved
+// pipeline without running it (see pipeline.Pipeline.Validate), for
+// catching configuration errors before building, like in CI.
+func Validate(filename string) error {
+	pipe, err := load(filename)
 	if err != nil {
-		return fmt.Errorf("processing GoShipDone file: %w", err)
+		return err
 	}
 
-	if err := pipe.Run(); err != nil {
-		return fmt.Errorf("running GoShipDone: %w", err)
+	if err := pipe.Validate(); err != nil {
+		return fmt.Errorf("validating GoShipDone file: %w", err)
 	}
 
 	return nil
 }
 
+// load loads the pipeline from the configuration file (see Run)
+func load(filename string) (*pipeline.Pipeline, error) {
+	node, err := loadConfig(defaultFS, detectFilename(filename))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyProfile(node); err != nil {
+		return nil, fmt.Errorf("processing GoShipDone file: %w", err)
+	}
+
+	pipe, err := pipeline.LoadBuildPipelineNode(node)
+	if err != nil {
+		return nil, fmt.Errorf("processing GoShipDone file: %w", err)
+	}
+
+	return pipe, nil
+}
+
+// Schema returns a JSON Schema of the configuration file, with all
+// registered modules. Editors can validate configuration files with it.
+// Custom stages declared in the configuration are not included.
+func Schema() ([]byte, error) {
+	pipe, err := pipeline.LoadBuildPipeline(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := pipe.Schema()
+	overlay := &modules.Schema{Type: "object", Properties: map[string]*modules.Schema{}, AdditionalProperties: false}
+
+	for _, stg := range pipe.Stages {
+		// modules of profiles are matched by `id`, or `type`
+		overlay.Properties[stg.Plural] = &modules.Schema{Type: "array", Items: &modules.Schema{Type: "object"}}
+	}
+
+	schema.Properties[includeKey] = &modules.Schema{OneOf: []*modules.Schema{
+		{Type: "string"},
+		{Type: "array", Items: &modules.Schema{Type: "string"}},
+	}}
+	schema.Properties[profilesKey] = &modules.Schema{Type: "object", AdditionalProperties: overlay}
+
+	return json.MarshalIndent(schema, "", "  ")
+}
+
 func detectFilename(filename string) string {
 	if filename != "" {
 		return filename
@@ -60,5 +137,11 @@ func detectFilename(filename string) string {
 		return defaultLocalFilename
 	}
 
+	if _, err := defaultFS.Stat(defaultFilename); err != nil {
+		if st, err := defaultFS.Stat(defaultDirname); err == nil && st.IsDir() {
+			return defaultDirname
+		}
+	}
+
 	return defaultFilename
 }
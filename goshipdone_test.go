@@ -34,6 +34,13 @@ func Test_detectFilename(t *testing.T) {
 			files: []string{},
 			want:  ".goshipdone.yml",
 		},
+		{
+			name:  "config directory exists",
+			env:   "",
+			input: "",
+			files: []string{".goshipdone/build.yml"},
+			want:  ".goshipdone",
+		},
 	}
 	for _, tt := range tests {
 		tt := tt
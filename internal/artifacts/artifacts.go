@@ -3,32 +3,68 @@ package artifacts
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/blang/semver"
 	"github.com/julian7/goshipdone/ctx"
 	"gopkg.in/yaml.v3"
 )
 
+// rollbackTimeout limits rollback, and cleanup operations of interrupted,
+// or failed runs
+const rollbackTimeout = 30 * time.Second
+
+const (
+	// IfExistsFail fails uploading assets already in the release
+	IfExistsFail IfExists = "fail"
+	// IfExistsReplace replaces assets already in the release
+	IfExistsReplace IfExists = "replace"
+	// IfExistsSkip keeps assets already in the release
+	IfExistsSkip IfExists = "skip"
+)
+
+// ErrSkipped is returned by Releaser.Upload, if the asset already exists,
+// and it has been skipped
+var ErrSkipped = errors.New("asset already exists")
+
 type (
 	Service interface {
+		fmt.Stringer
 		DefaultTokenEnv() string
 		DefaultTokenFile() string
 		New(ctx context.Context, url, token, owner, name string, opts *tls.Config) (Connection, error)
 	}
 
 	Connection interface {
+		// LatestVersion returns the highest semantic version tag of
+		// published releases (skipping drafts, and pre-releases), or an
+		// empty string, if there are no releases yet
+		LatestVersion() (string, error)
 		NewReleaser(tag, ref, version string) (Releaser, error)
+		// Promote publishes the draft release of tag, returning its URL
+		Promote(tag string) (string, error)
 	}
 
 	Releaser interface {
-		Release(name, notes string) error
-		Upload(*ctx.Artifact) error
+		// Release creates, or updates the release. New releases are
+		// created as drafts, if draft is set. Published releases are not
+		// turned back into drafts.
+		Release(name, notes string, draft bool) error
+		// Upload uploads an artifact as a release asset. Assets already
+		// in the release are handled by ifExists. It returns ErrSkipped,
+		// if the upload has been skipped.
+		Upload(art *ctx.Artifact, ifExists IfExists) error
 	}
 
+	// IfExists is the policy of uploading assets already in a release
+	IfExists string
+
 	Storage struct {
 		Service
 	}
@@ -45,7 +81,7 @@ func (s *Storage) GetToken(cx context.Context, tokenEnv, tokenFile string) strin
 			continue
 		}
 
-		if token, ok := context.Env.Get(envName); ok {
+		if token, ok := context.LookupEnv(envName); ok {
 			return token
 		}
 	}
@@ -55,7 +91,7 @@ func (s *Storage) GetToken(cx context.Context, tokenEnv, tokenFile string) strin
 			continue
 		}
 
-		fileName := context.Env.Expand(fileName)
+		fileName := context.Path(context.Env.Expand(fileName))
 		if _, err := os.Stat(fileName); err != nil {
 			log.Printf("cannot stat tokenfile `%s`: %v", fileName, err)
 			break
@@ -73,6 +109,45 @@ func (s *Storage) GetToken(cx context.Context, tokenEnv, tokenFile string) strin
 	return ""
 }
 
+// UnmarshalYAML validates the policy
+func (policy *IfExists) UnmarshalYAML(node *yaml.Node) error {
+	var value string
+	if err := node.Decode(&value); err != nil {
+		return fmt.Errorf("if_exists cannot be decoded: %w", err)
+	}
+
+	switch IfExists(value) {
+	case IfExistsFail, IfExistsReplace, IfExistsSkip:
+		*policy = IfExists(value)
+	default:
+		return fmt.Errorf("invalid if_exists policy `%s` (fail, replace, or skip)", value)
+	}
+
+	return nil
+}
+
+// existingAsset returns the policy's decision on an asset already in the
+// release: whether it must be replaced, or an error (ErrSkipped, if it
+// should be kept)
+func existingAsset(ifExists IfExists, name string, releaser fmt.Stringer) (bool, error) {
+	switch ifExists {
+	case IfExistsReplace:
+		return true, nil
+	case IfExistsSkip:
+		return false, ErrSkipped
+	default:
+		return false, fmt.Errorf("asset %s already exists in %v (set if_exists to skip, or replace it)", name, releaser)
+	}
+}
+
+func (s Storage) MarshalYAML() (interface{}, error) {
+	if s.Service == nil {
+		return "", nil
+	}
+
+	return s.Service.String(), nil
+}
+
 func (s *Storage) UnmarshalYAML(node *yaml.Node) error {
 	if node.Kind != yaml.ScalarNode {
 		return fmt.Errorf("storage is `%v`, not scalar", node.Kind)
@@ -116,3 +191,101 @@ func New(name string) (*Storage, error) {
 
 	return s, nil
 }
+
+// progress returns the running module's progress report from a context
+func progress(cx context.Context) *ctx.Progress {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil || context.Progress == nil {
+		return new(ctx.Progress)
+	}
+
+	return context.Progress
+}
+
+// boundary returns a multipart boundary from the ship context's random
+// source, for reproducible request bodies
+func boundary(cx context.Context) string {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil || context.Random == nil {
+		return ctx.NewRandom(time.Now().UnixNano()).Hex(30)
+	}
+
+	return context.Random.Hex(30)
+}
+
+// latestTag returns the highest semantic version tag, ignoring
+// pre-releases, and tags not in semver format
+func latestTag(tags []string) string {
+	var (
+		latest    string
+		latestVer semver.Version
+	)
+
+	for _, tag := range tags {
+		ver, err := semver.ParseTolerant(tag)
+		if err != nil || len(ver.Pre) > 0 {
+			continue
+		}
+
+		if latest == "" || ver.GT(latestVer) {
+			latest, latestVer = tag, ver
+		}
+	}
+
+	return latest
+}
+
+// onAbort registers a rollback handler in the ship context. The handler
+// receives a new context, as the pipeline's context is already canceled
+// when it runs.
+func onAbort(cx context.Context, name string, fn func(context.Context) error) {
+	shipContext, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return
+	}
+
+	shipContext.OnAbort(name, withRollbackTimeout(fn))
+}
+
+// onFailure registers a cleanup handler in the ship context, which runs
+// if the pipeline fails, or it is interrupted. The handler receives a new
+// context, as the pipeline's context may be canceled when it runs.
+func onFailure(cx context.Context, name string, fn func(context.Context) error) {
+	shipContext, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return
+	}
+
+	shipContext.OnFailure(name, withRollbackTimeout(fn))
+}
+
+// withRollbackTimeout calls a handler with a new context, limited by
+// rollbackTimeout
+func withRollbackTimeout(fn func(context.Context) error) func() error {
+	return func() error {
+		cx, cancel := context.WithTimeout(context.Background(), rollbackTimeout)
+		defer cancel()
+
+		return fn(cx)
+	}
+}
+
+// recordUpload records a successful upload in transfer statistics
+func recordUpload(cx context.Context, destination string, size int64) {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil || context.Transfers == nil {
+		return
+	}
+
+	context.Transfers.Upload(destination, size)
+}
+
+// published returns ship context's collection of release information
+func published(cx context.Context) *ctx.Published {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil || context.Published == nil {
+		return new(ctx.Published)
+	}
+
+	return context.Published
+}
@@ -0,0 +1,25 @@
+package artifacts
+
+import "testing"
+
+func Test_latestTag(t *testing.T) {
+	tests := []struct {
+		name string
+		tags []string
+		want string
+	}{
+		{name: "empty", tags: nil, want: ""},
+		{name: "semver order", tags: []string{"v1.10.0", "v1.9.0", "v1.2.3"}, want: "v1.10.0"},
+		{name: "prerelease", tags: []string{"v2.0.0-rc.1", "v1.9.0"}, want: "v1.9.0"},
+		{name: "non-semver ignored", tags: []string{"nightly", "1.0.0"}, want: "1.0.0"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := latestTag(tt.tags); got != tt.want {
+				t.Errorf("latestTag() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"sync"
 
 	"github.com/blang/semver"
 	"github.com/google/go-github/v28/github"
@@ -19,11 +20,17 @@ import (
 type GitHubService struct{}
 
 type GitHubRelease struct {
-	Conn *GitHubClient
-	ID   int64
-	Tag  string
-	Ref  string
-	Ver  string
+	Conn   *GitHubClient
+	ID     int64
+	Tag    string
+	Ref    string
+	Ver    string
+	assets map[string]*github.ReleaseAsset
+	mu     sync.Mutex
+}
+
+func (*GitHubService) String() string {
+	return "github"
 }
 
 func (*GitHubService) DefaultTokenEnv() string {
@@ -73,6 +80,101 @@ func (c *GitHubClient) NewReleaser(tag, ref, version string) (Releaser, error) {
 	}, nil
 }
 
+// LatestVersion returns the highest semantic version tag of releases,
+// skipping drafts, and pre-releases
+func (c *GitHubClient) LatestVersion() (string, error) {
+	tags := []string{}
+	opts := &github.ListOptions{PerPage: 100}
+
+	for {
+		releases, resp, err := c.Client.Repositories.ListReleases(c.Context, c.Owner, c.Name, opts)
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return "", nil
+			}
+
+			return "", fmt.Errorf("listing releases: %w", err)
+		}
+
+		for _, release := range releases {
+			if !release.GetDraft() && !release.GetPrerelease() {
+				tags = append(tags, release.GetTagName())
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		opts.Page = resp.NextPage
+	}
+
+	return latestTag(tags), nil
+}
+
+func (c *GitHubClient) Promote(tag string) (string, error) {
+	release, err := c.releaseByTag(tag)
+	if err != nil {
+		return "", err
+	}
+
+	if release == nil {
+		return "", fmt.Errorf("release %s not found", tag)
+	}
+
+	if release.GetDraft() {
+		release, _, err = c.Client.Repositories.EditRelease(
+			c.Context,
+			c.Owner,
+			c.Name,
+			release.GetID(),
+			&github.RepositoryRelease{Draft: github.Bool(false)},
+		)
+		if err != nil {
+			return "", fmt.Errorf("promoting release %s: %w", tag, err)
+		}
+	}
+
+	published(c.Context).AddLink("github release", release.GetHTMLURL())
+
+	return release.GetHTMLURL(), nil
+}
+
+// releaseByTag returns the release of a tag, or nil if it doesn't exist.
+// Draft releases are not returned by tag lookups, therefore they are
+// searched in the release list.
+func (c *GitHubClient) releaseByTag(tag string) (*github.RepositoryRelease, error) {
+	release, resp, err := c.Client.Repositories.GetReleaseByTag(c.Context, c.Owner, c.Name, tag)
+	if err == nil {
+		return release, nil
+	}
+
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		return nil, fmt.Errorf("searching release %s: %w", tag, err)
+	}
+
+	opts := &github.ListOptions{PerPage: 100}
+
+	for {
+		releases, resp, err := c.Client.Repositories.ListReleases(c.Context, c.Owner, c.Name, opts)
+		if err != nil {
+			return nil, fmt.Errorf("listing releases: %w", err)
+		}
+
+		for _, release := range releases {
+			if release.GetDraft() && release.GetTagName() == tag {
+				return release, nil
+			}
+		}
+
+		if resp.NextPage == 0 {
+			return nil, nil
+		}
+
+		opts.Page = resp.NextPage
+	}
+}
+
 func (c *GitHubClient) setURLs(baseURL string) error {
 	if baseURL == "" {
 		return nil
@@ -99,16 +201,15 @@ func (c *GitHubClient) setURLs(baseURL string) error {
 	return nil
 }
 
-func (rel *GitHubRelease) Release(name, notes string) error {
-	data := rel.getReleaseData(name, notes)
+func (rel *GitHubRelease) Release(name, notes string, draft bool) error {
+	data := rel.getReleaseData(name, notes, draft)
 
-	release, _, err := rel.Conn.Client.Repositories.GetReleaseByTag(
-		rel.Conn.Context,
-		rel.Conn.Owner,
-		rel.Conn.Name,
-		data.GetTagName(),
-	)
+	release, err := rel.Conn.releaseByTag(data.GetTagName())
 	if err != nil {
+		return err
+	}
+
+	if release == nil {
 		var resp *github.Response
 
 		release, resp, err = rel.Conn.Client.Repositories.CreateRelease(
@@ -121,12 +222,29 @@ func (rel *GitHubRelease) Release(name, notes string) error {
 			returned, _ := ioutil.ReadAll(resp.Response.Body)
 			return fmt.Errorf("creating release %s: %w (%s)", rel.Ver, err, string(returned))
 		}
+
+		relID := release.GetID()
+		rollback := func(cx context.Context) error {
+			_, err := rel.Conn.Client.Repositories.DeleteRelease(cx, rel.Conn.Owner, rel.Conn.Name, relID)
+			return err
+		}
+
+		// draft releases are left orphaned by failed uploads too
+		if data.GetDraft() {
+			onFailure(rel.Conn.Context, fmt.Sprintf("draft github release %s", data.GetTagName()), rollback)
+		} else {
+			onAbort(rel.Conn.Context, fmt.Sprintf("github release %s", data.GetTagName()), rollback)
+		}
 	} else {
 		relID := release.GetID()
 		if release.GetBody() != "" {
 			data.Body = release.Body
 		}
 
+		if draft && !release.GetDraft() {
+			data.Draft = github.Bool(false)
+		}
+
 		release, _, err = rel.Conn.Client.Repositories.EditRelease(
 			rel.Conn.Context,
 			rel.Conn.Owner,
@@ -141,10 +259,19 @@ func (rel *GitHubRelease) Release(name, notes string) error {
 
 	rel.ID = release.GetID()
 
+	if htmlURL := release.GetHTMLURL(); htmlURL != "" {
+		linkName := "github release"
+		if release.GetDraft() {
+			linkName = "github release (draft)"
+		}
+
+		published(rel.Conn.Context).AddLink(linkName, htmlURL)
+	}
+
 	return nil
 }
 
-func (rel *GitHubRelease) getReleaseData(name, notes string) *github.RepositoryRelease {
+func (rel *GitHubRelease) getReleaseData(name, notes string, draft bool) *github.RepositoryRelease {
 	var prerelease bool
 
 	tag := rel.Tag
@@ -160,22 +287,49 @@ func (rel *GitHubRelease) getReleaseData(name, notes string) *github.RepositoryR
 		Name:       github.String(name),
 		TagName:    github.String(tag),
 		Body:       github.String(notes),
-		Draft:      github.Bool(rel.Tag == ""),
+		Draft:      github.Bool(rel.Tag == "" || draft),
 		Prerelease: github.Bool(prerelease),
 	}
 }
 
-func (rel *GitHubRelease) Upload(art *ctx.Artifact) error {
+func (rel *GitHubRelease) Upload(art *ctx.Artifact, ifExists IfExists) error {
 	if rel.ID == 0 {
 		return errors.New("no release selected")
 	}
 
+	asset, err := rel.asset(art.Filename)
+	if err != nil {
+		return err
+	}
+
+	if asset != nil {
+		replace, err := existingAsset(ifExists, art.Filename, rel)
+		if errors.Is(err, ErrSkipped) {
+			published(rel.Conn.Context).AddDownload(art, asset.GetBrowserDownloadURL())
+		}
+
+		if !replace {
+			return err
+		}
+
+		if _, err := rel.Conn.Client.Repositories.DeleteReleaseAsset(
+			rel.Conn.Context,
+			rel.Conn.Owner,
+			rel.Conn.Name,
+			asset.GetID(),
+		); err != nil {
+			return fmt.Errorf("removing existing asset %s from %v: %w", art.Filename, rel, err)
+		}
+	}
+
 	file, err := os.Open(art.Location)
 	if err != nil {
 		return fmt.Errorf("opening file %s for uploading: %w", art.Location, err)
 	}
 
-	if _, _, err = rel.Conn.Client.Repositories.UploadReleaseAsset(
+	defer file.Close()
+
+	asset, _, err = rel.Conn.Client.Repositories.UploadReleaseAsset(
 		rel.Conn.Context,
 		rel.Conn.Owner,
 		rel.Conn.Name,
@@ -184,13 +338,66 @@ func (rel *GitHubRelease) Upload(art *ctx.Artifact) error {
 			Name: art.Filename,
 		},
 		file,
-	); err != nil {
+	)
+	if err != nil {
 		return fmt.Errorf("uploading file %s into %v: %w", art.Location, rel, err)
 	}
 
+	if downloadURL := asset.GetBrowserDownloadURL(); downloadURL != "" {
+		published(rel.Conn.Context).AddDownload(art, downloadURL)
+	}
+
+	if st, err := file.Stat(); err == nil {
+		progress(rel.Conn.Context).AddBytes(st.Size())
+		recordUpload(
+			rel.Conn.Context,
+			fmt.Sprintf("github:%s/%s", rel.Conn.Owner, rel.Conn.Name),
+			st.Size(),
+		)
+	}
+
 	return nil
 }
 
+// asset returns the release's asset by name, or nil if it doesn't exist.
+// Assets are listed once, on first call.
+func (rel *GitHubRelease) asset(name string) (*github.ReleaseAsset, error) {
+	rel.mu.Lock()
+	defer rel.mu.Unlock()
+
+	if rel.assets == nil {
+		assets := map[string]*github.ReleaseAsset{}
+		opts := &github.ListOptions{PerPage: 100}
+
+		for {
+			page, resp, err := rel.Conn.Client.Repositories.ListReleaseAssets(
+				rel.Conn.Context,
+				rel.Conn.Owner,
+				rel.Conn.Name,
+				rel.ID,
+				opts,
+			)
+			if err != nil {
+				return nil, fmt.Errorf("listing assets of %v: %w", rel, err)
+			}
+
+			for _, asset := range page {
+				assets[asset.GetName()] = asset
+			}
+
+			if resp.NextPage == 0 {
+				break
+			}
+
+			opts.Page = resp.NextPage
+		}
+
+		rel.assets = assets
+	}
+
+	return rel.assets[name], nil
+}
+
 func (rel *GitHubRelease) String() string {
 	return fmt.Sprintf("%s/%s #%d", rel.Conn.Owner, rel.Conn.Name, rel.ID)
 }
@@ -0,0 +1,156 @@
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/google/go-github/v28/github"
+	"github.com/julian7/goshipdone/ctx"
+)
+
+func TestGitHubRelease_Upload(t *testing.T) {
+	tests := []struct {
+		name     string
+		ifExists IfExists
+		wantErr  error
+		wantReqs []string
+	}{
+		{
+			name:     "fail",
+			ifExists: IfExistsFail,
+			wantErr:  errors.New("asset app.tar.gz already exists in o/r #1 (set if_exists to skip, or replace it)"),
+			wantReqs: []string{"GET /repos/o/r/releases/1/assets"},
+		},
+		{
+			name:     "skip",
+			ifExists: IfExistsSkip,
+			wantErr:  ErrSkipped,
+			wantReqs: []string{"GET /repos/o/r/releases/1/assets"},
+		},
+		{
+			name:     "replace",
+			ifExists: IfExistsReplace,
+			wantReqs: []string{
+				"GET /repos/o/r/releases/1/assets",
+				"DELETE /repos/o/r/releases/assets/5",
+				"POST /repos/o/r/releases/1/assets",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu   sync.Mutex
+				reqs []string
+			)
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				reqs = append(reqs, r.Method+" "+r.URL.Path)
+				mu.Unlock()
+
+				switch r.Method {
+				case http.MethodGet:
+					_, _ = w.Write([]byte(`[{"id":5,"name":"app.tar.gz","browser_download_url":"https://example.com/old/app.tar.gz"}]`))
+				case http.MethodDelete:
+					w.WriteHeader(http.StatusNoContent)
+				case http.MethodPost:
+					w.WriteHeader(http.StatusCreated)
+					_, _ = w.Write([]byte(`{"id":6,"name":"app.tar.gz","browser_download_url":"https://example.com/new/app.tar.gz"}`))
+				}
+			}))
+			defer srv.Close()
+
+			cx := ctx.New(context.Background())
+
+			base, _ := url.Parse(srv.URL + "/")
+			client := github.NewClient(nil)
+			client.BaseURL = base
+			client.UploadURL = base
+
+			art := &ctx.Artifact{Filename: "app.tar.gz", Location: filepath.Join(t.TempDir(), "app.tar.gz")}
+			if err := os.WriteFile(art.Location, []byte("content"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			rel := &GitHubRelease{
+				Conn: &GitHubClient{Client: client, Context: cx, Owner: "o", Name: "r"},
+				ID:   1,
+			}
+
+			err := rel.Upload(art, tt.ifExists)
+			if (err == nil) != (tt.wantErr == nil) || (err != nil && err.Error() != tt.wantErr.Error()) {
+				t.Fatalf("Upload() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(reqs, tt.wantReqs) {
+				t.Errorf("requests = %v, want %v", reqs, tt.wantReqs)
+			}
+
+			if tt.ifExists == IfExistsSkip {
+				if url, _ := published(cx).DownloadURL("app.tar.gz"); url != "https://example.com/old/app.tar.gz" {
+					t.Errorf("download URL = %q", url)
+				}
+			}
+		})
+	}
+}
+
+func TestGitHubClient_LatestVersion(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		want   string
+	}{
+		{name: "releases", status: http.StatusOK, want: "v2.0.0"},
+		{name: "no repository", status: http.StatusNotFound, want: ""},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var srv *httptest.Server
+
+			srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.status != http.StatusOK {
+					w.WriteHeader(tt.status)
+					return
+				}
+
+				if r.URL.Query().Get("page") == "2" {
+					_, _ = w.Write([]byte(`[{"tag_name":"v2.0.0"},{"tag_name":"v1.10.0"}]`))
+					return
+				}
+
+				w.Header().Set("Link", `<`+srv.URL+`/repos/o/r/releases?page=2>; rel="next"`)
+				_, _ = w.Write([]byte(`[{"tag_name":"v3.0.0","draft":true},{"tag_name":"v2.1.0-rc.1","prerelease":true},{"tag_name":"v1.2.0"}]`))
+			}))
+			defer srv.Close()
+
+			base, _ := url.Parse(srv.URL + "/")
+			client := github.NewClient(nil)
+			client.BaseURL = base
+
+			conn := &GitHubClient{Client: client, Context: context.Background(), Owner: "o", Name: "r"}
+
+			got, err := conn.LatestVersion()
+			if err != nil {
+				t.Fatalf("LatestVersion() error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("LatestVersion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
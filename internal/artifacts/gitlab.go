@@ -13,6 +13,8 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/julian7/goshipdone/ctx"
 	"github.com/xanzy/go-gitlab"
@@ -28,12 +30,18 @@ type GitLabClient struct {
 }
 
 type GitLabRelease struct {
-	Base string
-	Conn *GitLabClient
-	ID   string
-	Ref  string
-	Tag  string
-	Ver  string
+	Base  string
+	Conn  *GitLabClient
+	ID    string
+	Ref   string
+	Tag   string
+	Ver   string
+	links map[string]*gitlab.ReleaseLink
+	mu    sync.Mutex
+}
+
+func (*GitLabService) String() string {
+	return "gitlab"
 }
 
 func (*GitLabService) DefaultTokenEnv() string {
@@ -98,9 +106,46 @@ func (c *GitLabClient) ProjectID() string {
 	return strings.Replace(url.PathEscape(c.ProjectPath()), ".", "%2E", -1) // nolint:gocritic
 }
 
-func (rel *GitLabRelease) Release(name, notes string) error {
+// LatestVersion returns the highest semantic version tag of releases,
+// skipping upcoming releases (released in the future), and pre-releases
+func (c *GitLabClient) LatestVersion() (string, error) {
+	tags := []string{}
+	opts := &gitlab.ListReleasesOptions{PerPage: 100}
+	now := time.Now()
+
+	for {
+		releases, resp, err := c.Client.Releases.ListReleases(c.ProjectID(), opts, gitlab.WithContext(c.Context))
+		if err != nil {
+			return "", fmt.Errorf("listing releases: %w", err)
+		}
+
+		for _, release := range releases {
+			if release.ReleasedAt == nil || !release.ReleasedAt.After(now) {
+				tags = append(tags, release.TagName)
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		opts.Page = resp.NextPage
+	}
+
+	return latestTag(tags), nil
+}
+
+func (c *GitLabClient) Promote(tag string) (string, error) {
+	return "", errors.New("gitlab doesn't support draft releases")
+}
+
+func (rel *GitLabRelease) Release(name, notes string, draft bool) error {
 	var release *gitlab.Release
 
+	if draft {
+		return errors.New("gitlab doesn't support draft releases")
+	}
+
 	tag := rel.Tag
 	if tag == "" {
 		tag = rel.Ver
@@ -127,6 +172,11 @@ func (rel *GitLabRelease) Release(name, notes string) error {
 			returned, _ := ioutil.ReadAll(resp.Response.Body)
 			return fmt.Errorf("creating release %s: %w (%s)", rel.Ver, err, string(returned))
 		}
+
+		onAbort(rel.Conn.Context, fmt.Sprintf("gitlab release %s", tag), func(context.Context) error {
+			_, _, err := rel.Conn.Client.Releases.DeleteRelease(projectPath, tag)
+			return err
+		})
 	} else {
 		release, _, err = rel.Conn.Client.Releases.UpdateRelease(
 			projectPath,
@@ -143,6 +193,8 @@ func (rel *GitLabRelease) Release(name, notes string) error {
 
 	rel.ID = release.Name
 
+	published(rel.Conn.Context).AddLink("gitlab release", rel.Base+"/-/releases/"+url.PathEscape(tag))
+
 	return nil
 }
 
@@ -158,12 +210,16 @@ func (rel *GitLabRelease) uploadFile(filename, location string) (*gitlab.Project
 	b := &bytes.Buffer{}
 	w := multipart.NewWriter(b)
 
+	if err := w.SetBoundary(boundary(rel.Conn.Context)); err != nil {
+		return nil, fmt.Errorf("setting multipart boundary: %w", err)
+	}
+
 	fw, err := w.CreateFormFile("file", filename)
 	if err != nil {
 		return nil, fmt.Errorf("building file upload form for %s: %w", filename, err)
 	}
 
-	_, err = io.Copy(fw, file)
+	size, err := io.Copy(fw, progress(rel.Conn.Context).Reader(file))
 	if err != nil {
 		return nil, fmt.Errorf("loading file %s to upload form: %w", filename, err)
 	}
@@ -186,14 +242,36 @@ func (rel *GitLabRelease) uploadFile(filename, location string) (*gitlab.Project
 		return nil, fmt.Errorf("uploading file %s: %w", filename, err)
 	}
 
+	recordUpload(rel.Conn.Context, "gitlab:"+rel.Conn.ProjectPath(), size)
+
 	return projFile, nil
 }
 
-func (rel *GitLabRelease) Upload(art *ctx.Artifact) error {
+func (rel *GitLabRelease) Upload(art *ctx.Artifact, ifExists IfExists) error {
 	if rel.ID == "" {
 		return errors.New("no release selected")
 	}
 
+	link, err := rel.link(art.Filename)
+	if err != nil {
+		return err
+	}
+
+	if link != nil {
+		replace, err := existingAsset(ifExists, art.Filename, rel)
+		if errors.Is(err, ErrSkipped) {
+			published(rel.Conn.Context).AddDownload(art, link.URL)
+		}
+
+		if !replace {
+			return err
+		}
+
+		if _, _, err := rel.Conn.ReleaseLinks.DeleteReleaseLink(rel.Conn.ProjectPath(), rel.ID, link.ID); err != nil {
+			return fmt.Errorf("removing existing asset %s from %v: %w", art.Filename, rel, err)
+		}
+	}
+
 	projectFile, err := rel.uploadFile(
 		art.Filename,
 		art.Location,
@@ -204,7 +282,7 @@ func (rel *GitLabRelease) Upload(art *ctx.Artifact) error {
 
 	fileURL := rel.Base + projectFile.URL
 
-	relLink, _, err := rel.Conn.ReleaseLinks.CreateReleaseLink(
+	_, _, err = rel.Conn.ReleaseLinks.CreateReleaseLink(
 		rel.Conn.ProjectPath(),
 		rel.ID,
 		&gitlab.CreateReleaseLinkOptions{
@@ -216,11 +294,44 @@ func (rel *GitLabRelease) Upload(art *ctx.Artifact) error {
 		return fmt.Errorf("uploading file %s into %v: %w", art.Location, rel, err)
 	}
 
-	_ = relLink
+	published(rel.Conn.Context).AddDownload(art, fileURL)
 
 	return nil
 }
 
+// link returns the release's asset link by name, or nil if it doesn't
+// exist. Links are listed once, on first call.
+func (rel *GitLabRelease) link(name string) (*gitlab.ReleaseLink, error) {
+	rel.mu.Lock()
+	defer rel.mu.Unlock()
+
+	if rel.links == nil {
+		links := map[string]*gitlab.ReleaseLink{}
+		opts := &gitlab.ListReleaseLinksOptions{PerPage: 100}
+
+		for {
+			page, resp, err := rel.Conn.ReleaseLinks.ListReleaseLinks(rel.Conn.ProjectPath(), rel.ID, opts)
+			if err != nil {
+				return nil, fmt.Errorf("listing assets of %v: %w", rel, err)
+			}
+
+			for _, link := range page {
+				links[link.Name] = link
+			}
+
+			if resp == nil || resp.NextPage == 0 {
+				break
+			}
+
+			opts.Page = resp.NextPage
+		}
+
+		rel.links = links
+	}
+
+	return rel.links[name], nil
+}
+
 func (rel *GitLabRelease) String() string {
 	return fmt.Sprintf("%s/%s release %s", rel.Conn.Namespace, rel.Conn.Name, rel.ID)
 }
@@ -0,0 +1,76 @@
+package artifacts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/go-github/v28/github"
+)
+
+const (
+	// forkRetries and forkWait limit waiting for asynchronously created
+	// forks to become available
+	forkRetries = 10
+	forkWait    = 3 * time.Second
+)
+
+// Fork creates the authenticated user's fork of the repository, or finds
+// an existing one. It returns the fork's owner, and clone URL.
+func (c *GitHubClient) Fork() (string, string, error) {
+	repo, _, err := c.Client.Repositories.CreateFork(c.Context, c.Owner, c.Name, nil)
+	if err != nil {
+		var accepted *github.AcceptedError
+		if !errors.As(err, &accepted) {
+			return "", "", fmt.Errorf("forking %s/%s: %w", c.Owner, c.Name, err)
+		}
+
+		// fork is being created asynchronously
+		repo = &github.Repository{}
+		if err := json.Unmarshal(accepted.Raw, repo); err != nil {
+			return "", "", fmt.Errorf("decoding fork of %s/%s: %w", c.Owner, c.Name, err)
+		}
+
+		if err := c.waitForRepo(repo.GetOwner().GetLogin(), repo.GetName()); err != nil {
+			return "", "", err
+		}
+	}
+
+	return repo.GetOwner().GetLogin(), repo.GetCloneURL(), nil
+}
+
+func (c *GitHubClient) waitForRepo(owner, name string) error {
+	var err error
+
+	for i := 0; i < forkRetries; i++ {
+		if _, _, err = c.Client.Repositories.Get(c.Context, owner, name); err == nil {
+			return nil
+		}
+
+		select {
+		case <-c.Context.Done():
+			return c.Context.Err()
+		case <-time.After(forkWait):
+		}
+	}
+
+	return fmt.Errorf("waiting for fork %s/%s: %w", owner, name, err)
+}
+
+// OpenPullRequest opens a pull request from head (in `owner:branch`
+// format) against the repository's base branch, returning its URL
+func (c *GitHubClient) OpenPullRequest(head, base, title, body string, draft bool) (string, error) {
+	pr, _, err := c.Client.PullRequests.Create(c.Context, c.Owner, c.Name, &github.NewPullRequest{
+		Title: github.String(title),
+		Head:  github.String(head),
+		Base:  github.String(base),
+		Body:  github.String(body),
+		Draft: github.Bool(draft),
+	})
+	if err != nil {
+		return "", fmt.Errorf("opening pull request against %s/%s: %w", c.Owner, c.Name, err)
+	}
+
+	return pr.GetHTMLURL(), nil
+}
@@ -0,0 +1,292 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultBlockSize is the default block size of Azure block uploads
+	DefaultBlockSize = 64 << 20
+
+	azureVersion = "2020-10-02"
+)
+
+// Azure is a minimal Azure Blob Storage client, authenticating with
+// Shared Key, or a SAS token.
+type Azure struct {
+	// Account is the storage account's name
+	Account string
+	// BlockSize sets the size of blocks. Files larger than this are
+	// uploaded in multiple blocks. Default: DefaultBlockSize.
+	BlockSize int64
+	// Client is the HTTP client used for requests. Default:
+	// http.DefaultClient.
+	Client *http.Client
+	// Container is the target container's name
+	Container string
+	// Endpoint is the service's base URL. Default:
+	// https://<account>.blob.core.windows.net
+	Endpoint *url.URL
+	// Key is the base64-encoded shared key of the account
+	Key string
+	// Progress wraps request bodies for progress reporting, if set
+	Progress func(io.Reader) io.Reader
+	// SASToken is a shared access signature token (query string). It is
+	// used instead of Key if set.
+	SASToken string
+
+	now func() time.Time
+}
+
+func (az *Azure) String() string {
+	return fmt.Sprintf("azblob:%s/%s", az.Account, az.Container)
+}
+
+// URL returns a blob's URL, without SAS token
+func (az *Azure) URL(key string) string {
+	return az.blobURL(key).String()
+}
+
+// UploadFile uploads a local file into the container as a block blob. ACL
+// is not supported (access is set on containers), and StorageClass sets
+// access tier (eg. "Cool").
+func (az *Azure) UploadFile(cx context.Context, key, filename string, opts *UploadOptions) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	st, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if opts == nil {
+		opts = &UploadOptions{}
+	}
+
+	header := http.Header{}
+	if opts.ContentType != "" {
+		header.Set("x-ms-blob-content-type", opts.ContentType)
+	}
+
+	if opts.StorageClass != "" {
+		header.Set("x-ms-access-tier", opts.StorageClass)
+	}
+
+	if st.Size() <= az.blockSize() {
+		header.Set("x-ms-blob-type", "BlockBlob")
+
+		return az.do(cx, http.MethodPut, key, nil, header, io.NewSectionReader(file, 0, st.Size()), st.Size())
+	}
+
+	blockSize := az.blockSize()
+	blocks := []string{}
+
+	for offset, num := int64(0), 0; offset < st.Size(); offset, num = offset+blockSize, num+1 {
+		length := blockSize
+		if offset+length > st.Size() {
+			length = st.Size() - offset
+		}
+
+		// block IDs must have the same length within a blob
+		blockID := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("block-%06d", num)))
+		query := url.Values{"comp": {"block"}, "blockid": {blockID}}
+
+		if err := az.do(cx, http.MethodPut, key, query, nil, io.NewSectionReader(file, offset, length), length); err != nil {
+			return fmt.Errorf("uploading block %d: %w", num, err)
+		}
+
+		blocks = append(blocks, blockID)
+	}
+
+	payload, err := xml.Marshal(struct {
+		XMLName xml.Name `xml:"BlockList"`
+		Latest  []string
+	}{Latest: blocks})
+	if err != nil {
+		return err
+	}
+
+	err = az.do(cx, http.MethodPut, key, url.Values{"comp": {"blocklist"}}, header, bytes.NewReader(payload), int64(len(payload)))
+	if err != nil {
+		return fmt.Errorf("committing block list: %w", err)
+	}
+
+	return nil
+}
+
+func (az *Azure) do(cx context.Context, method, key string, query url.Values, header http.Header, body io.Reader, size int64) error {
+	reqURL := az.blobURL(key)
+	reqURL.RawQuery = query.Encode()
+
+	if az.SASToken != "" {
+		sas := strings.TrimPrefix(az.SASToken, "?")
+		if reqURL.RawQuery != "" {
+			sas = "&" + sas
+		}
+
+		reqURL.RawQuery += sas
+	}
+
+	req, err := http.NewRequestWithContext(cx, method, reqURL.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	if size > 0 {
+		if az.Progress != nil {
+			body = az.Progress(body)
+		}
+
+		req.Body = ioutil.NopCloser(body)
+		req.ContentLength = size
+	}
+
+	for name, values := range header {
+		req.Header[http.CanonicalHeaderKey(name)] = values
+	}
+
+	now := time.Now
+	if az.now != nil {
+		now = az.now
+	}
+
+	req.Header.Set("x-ms-date", now().UTC().Format(http.TimeFormat))
+	req.Header.Set("x-ms-version", azureVersion)
+
+	if az.SASToken == "" {
+		if err := az.sign(req, query); err != nil {
+			return err
+		}
+	}
+
+	client := az.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return responseError(resp)
+	}
+
+	return nil
+}
+
+func (az *Azure) blobURL(key string) *url.URL {
+	var u url.URL
+
+	if az.Endpoint != nil {
+		u = *az.Endpoint
+	} else {
+		u = url.URL{Scheme: "https", Host: az.Account + ".blob.core.windows.net"}
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + az.Container + "/" + strings.TrimPrefix(key, "/")
+	u.RawPath = uriEncode(u.Path, false)
+
+	return &u
+}
+
+// sign adds a Shared Key authorization header to a request
+func (az *Azure) sign(req *http.Request, query url.Values) error {
+	key, err := base64.StdEncoding.DecodeString(az.Key)
+	if err != nil {
+		return fmt.Errorf("decoding shared key: %w", err)
+	}
+
+	contentLength := ""
+	if req.ContentLength > 0 {
+		contentLength = strconv.FormatInt(req.ContentLength, 10)
+	}
+
+	msHeaders := []string{}
+
+	for name := range req.Header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "x-ms-") {
+			msHeaders = append(msHeaders, name)
+		}
+	}
+
+	sort.Strings(msHeaders)
+
+	canonical := &strings.Builder{}
+
+	for _, value := range []string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		contentLength,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		"", // Date (x-ms-date is used instead)
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+	} {
+		canonical.WriteString(value + "\n")
+	}
+
+	for _, name := range msHeaders {
+		fmt.Fprintf(canonical, "%s:%s\n", name, strings.TrimSpace(req.Header.Get(name)))
+	}
+
+	fmt.Fprintf(canonical, "/%s%s", az.Account, req.URL.EscapedPath())
+
+	params := make([]string, 0, len(query))
+	for name := range query {
+		params = append(params, name)
+	}
+
+	sort.Strings(params)
+
+	for _, name := range params {
+		values := append([]string{}, query[name]...)
+		sort.Strings(values)
+		fmt.Fprintf(canonical, "\n%s:%s", strings.ToLower(name), strings.Join(values, ","))
+	}
+
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(canonical.String()))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"SharedKey %s:%s",
+		az.Account,
+		base64.StdEncoding.EncodeToString(mac.Sum(nil)),
+	))
+
+	return nil
+}
+
+func (az *Azure) blockSize() int64 {
+	if az.BlockSize <= 0 {
+		return DefaultBlockSize
+	}
+
+	return az.BlockSize
+}
@@ -0,0 +1,128 @@
+package blob_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/julian7/goshipdone/internal/blob"
+)
+
+// fakeAzure is a minimal Azure Blob Storage server, storing block blobs
+type fakeAzure struct {
+	mu      sync.Mutex
+	auth    []string
+	blobs   map[string][]byte
+	blocks  map[string][]byte
+	headers map[string]http.Header
+}
+
+func (srv *fakeAzure) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	srv.auth = append(srv.auth, req.Header.Get("Authorization")+req.URL.Query().Get("sig"))
+	data, _ := io.ReadAll(req.Body)
+	query := req.URL.Query()
+
+	switch query.Get("comp") {
+	case "block":
+		srv.blocks[query.Get("blockid")] = data
+	case "blocklist":
+		var list struct{ Latest []string }
+		if err := xml.Unmarshal(data, &list); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		content := &bytes.Buffer{}
+		for _, id := range list.Latest {
+			content.Write(srv.blocks[id])
+		}
+
+		srv.blobs[req.URL.Path] = content.Bytes()
+		srv.headers[req.URL.Path] = req.Header.Clone()
+	default:
+		if req.Header.Get("x-ms-blob-type") != "BlockBlob" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		srv.blobs[req.URL.Path] = data
+		srv.headers[req.URL.Path] = req.Header.Clone()
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+func TestAzure_UploadFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		size     int
+		sasToken string
+		wantAuth string
+	}{
+		{"simple", 1024, "", "SharedKey account:"},
+		{"blocks", 2500, "", "SharedKey account:"},
+		{"sas", 1024, "?sv=2020-10-02&sig=signature", "signature"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeAzure{
+				blobs:   map[string][]byte{},
+				blocks:  map[string][]byte{},
+				headers: map[string]http.Header{},
+			}
+			srv := httptest.NewServer(fake)
+			t.Cleanup(srv.Close)
+
+			endpoint, _ := url.Parse(srv.URL + "/account")
+			content := bytes.Repeat([]byte{'x'}, tt.size)
+			filename := filepath.Join(t.TempDir(), "app.zip")
+
+			if err := os.WriteFile(filename, content, 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			az := &blob.Azure{
+				Account:   "account",
+				BlockSize: 1024,
+				Container: "releases",
+				Endpoint:  endpoint,
+				Key:       base64.StdEncoding.EncodeToString([]byte("secret")),
+				SASToken:  tt.sasToken,
+			}
+
+			opts := &blob.UploadOptions{ContentType: "application/zip", StorageClass: "Cool"}
+			if err := az.UploadFile(context.Background(), "app/v1.0.0/app.zip", filename, opts); err != nil {
+				t.Fatalf("UploadFile() error = %v", err)
+			}
+
+			key := "/account/releases/app/v1.0.0/app.zip"
+			if got := fake.blobs[key]; !bytes.Equal(got, content) {
+				t.Errorf("blob size = %d, want %d", len(got), len(content))
+			}
+
+			if tier := fake.headers[key].Get("x-ms-access-tier"); tier != "Cool" {
+				t.Errorf("access tier = %q, want Cool", tier)
+			}
+
+			for _, auth := range fake.auth {
+				if !strings.HasPrefix(auth, tt.wantAuth) {
+					t.Errorf("authorization = %q, want %q prefix", auth, tt.wantAuth)
+				}
+			}
+		})
+	}
+}
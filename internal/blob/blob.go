@@ -0,0 +1,30 @@
+// Package blob implements minimal object storage clients for publishing
+// artifacts: S3-compatible storages (including Google Cloud Storage), and
+// Azure Blob Storage.
+package blob
+
+import (
+	"context"
+	"fmt"
+)
+
+// Bucket is an object storage container files can be uploaded into
+type Bucket interface {
+	fmt.Stringer
+	// UploadFile uploads a local file with a specified key (object name)
+	UploadFile(cx context.Context, key, filename string, opts *UploadOptions) error
+	// URL returns an object's URL (without credentials)
+	URL(key string) string
+}
+
+// UploadOptions are optional settings of an upload. Backends may not
+// support all of them.
+type UploadOptions struct {
+	// ACL is a canned ACL, like "public-read"
+	ACL string
+	// ContentType is the object's media type
+	ContentType string
+	// StorageClass is the object's storage class, like "STANDARD_IA", or
+	// access tier, like "Cool"
+	StorageClass string
+}
@@ -0,0 +1,498 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultPartSize is the default part size of multipart uploads
+	DefaultPartSize = 64 << 20
+	// MinPartSize is the smallest part size S3 accepts (except for the
+	// last part of an upload)
+	MinPartSize = 5 << 20
+
+	s3Algorithm  = "AWS4-HMAC-SHA256"
+	s3DateFormat = "20060102T150405Z"
+	s3Service    = "s3"
+)
+
+// S3 is a minimal S3-compatible object storage client, signing requests
+// with AWS Signature Version 4. It works with AWS S3, MinIO, DigitalOcean
+// Spaces, and other S3-compatible services.
+type S3 struct {
+	// AccessKey is the access key ID
+	AccessKey string
+	// Bucket is the name of the target bucket
+	Bucket string
+	// Client is the HTTP client used for requests. Default:
+	// http.DefaultClient.
+	Client *http.Client
+	// Endpoint is the service's base URL, like https://s3.amazonaws.com
+	Endpoint *url.URL
+	// HeaderPrefix is the prefix of vendor-specific headers, like ACL, and
+	// storage class. Default: "x-amz-".
+	HeaderPrefix string
+	// PartSize sets the size of multipart upload parts. Files larger than
+	// this are uploaded in parts. Default: DefaultPartSize.
+	PartSize int64
+	// PathStyle addresses the bucket as the first path element instead
+	// of a subdomain of the endpoint. MinIO requires this.
+	PathStyle bool
+	// Progress wraps request bodies for progress reporting, if set
+	Progress func(io.Reader) io.Reader
+	// Region is the bucket's region. Default: us-east-1.
+	Region string
+	// Scheme is the storage's name in String(). Default: "s3".
+	Scheme string
+	// SecretKey is the secret access key
+	SecretKey string
+	// SessionToken is the temporary security credentials' session token
+	SessionToken string
+	// Token is an OAuth 2.0 bearer token. If set, requests are not signed.
+	Token string
+
+	now func() time.Time
+}
+
+// NewGCS returns a client of Google Cloud Storage's S3-compatible XML
+// API. It authenticates with HMAC keys, or an OAuth 2.0 access token.
+func NewGCS(bucket, accessKey, secretKey, token string) *S3 {
+	return &S3{
+		AccessKey:    accessKey,
+		Bucket:       bucket,
+		Endpoint:     &url.URL{Scheme: "https", Host: "storage.googleapis.com"},
+		HeaderPrefix: "x-goog-",
+		Region:       "auto",
+		Scheme:       "gs",
+		SecretKey:    secretKey,
+		Token:        token,
+	}
+}
+
+func (s *S3) String() string {
+	scheme := s.Scheme
+	if scheme == "" {
+		scheme = "s3"
+	}
+
+	return fmt.Sprintf("%s:%s", scheme, s.Bucket)
+}
+
+// URL returns an object's URL
+func (s *S3) URL(key string) string {
+	return s.objectURL(key).String()
+}
+
+// UploadFile uploads a local file to the bucket with a specified key.
+// Files larger than PartSize are uploaded in multiple parts.
+func (s *S3) UploadFile(cx context.Context, key, filename string, opts *UploadOptions) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	st, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if opts == nil {
+		opts = &UploadOptions{}
+	}
+
+	if st.Size() > s.partSize() {
+		return s.uploadMultipart(cx, key, file, st.Size(), opts)
+	}
+
+	resp, err := s.do(cx, http.MethodPut, key, nil, s.header(opts), io.NewSectionReader(file, 0, st.Size()))
+	if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
+
+func (s *S3) uploadMultipart(cx context.Context, key string, file io.ReaderAt, size int64, opts *UploadOptions) error {
+	resp, err := s.do(cx, http.MethodPost, key, url.Values{"uploads": {""}}, s.header(opts), nil)
+	if err != nil {
+		return fmt.Errorf("initiating multipart upload: %w", err)
+	}
+
+	var initiated struct {
+		UploadID string `xml:"UploadId"`
+	}
+
+	err = decodeXML(resp, &initiated)
+	if err != nil {
+		return fmt.Errorf("initiating multipart upload: %w", err)
+	}
+
+	if initiated.UploadID == "" {
+		return errors.New("initiating multipart upload: no upload ID received")
+	}
+
+	if err := s.uploadParts(cx, key, initiated.UploadID, file, size); err != nil {
+		query := url.Values{"uploadId": {initiated.UploadID}}
+		if resp, abortErr := s.do(context.Background(), http.MethodDelete, key, query, nil, nil); abortErr == nil {
+			resp.Body.Close()
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+type completedPart struct {
+	PartNumber int
+	ETag       string
+}
+
+func (s *S3) uploadParts(cx context.Context, key, uploadID string, file io.ReaderAt, size int64) error {
+	partSize := s.partSize()
+	parts := []completedPart{}
+
+	for offset, num := int64(0), 1; offset < size; offset, num = offset+partSize, num+1 {
+		length := partSize
+		if offset+length > size {
+			length = size - offset
+		}
+
+		query := url.Values{
+			"partNumber": {strconv.Itoa(num)},
+			"uploadId":   {uploadID},
+		}
+
+		resp, err := s.do(cx, http.MethodPut, key, query, nil, io.NewSectionReader(file, offset, length))
+		if err != nil {
+			return fmt.Errorf("uploading part %d: %w", num, err)
+		}
+
+		resp.Body.Close()
+
+		parts = append(parts, completedPart{PartNumber: num, ETag: resp.Header.Get("ETag")})
+	}
+
+	payload, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(cx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, nil, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("completing multipart upload: %w", err)
+	}
+
+	// S3 may report errors in a 200 OK response of a complete request
+	var result struct {
+		XMLName xml.Name
+		Code    string
+		Message string
+	}
+
+	if err := decodeXML(resp, &result); err != nil {
+		return fmt.Errorf("completing multipart upload: %w", err)
+	}
+
+	if result.XMLName.Local == "Error" {
+		return fmt.Errorf("completing multipart upload: %s: %s", result.Code, result.Message)
+	}
+
+	return nil
+}
+
+// do sends a signed request, returning an error for non-2xx responses.
+// Body is read twice: first for calculating its hash, then for sending.
+func (s *S3) do(cx context.Context, method, key string, query url.Values, header http.Header, body io.ReadSeeker) (*http.Response, error) {
+	req, err := s.newRequest(cx, method, key, query, header, body)
+	if err != nil {
+		return nil, err
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+
+		return nil, responseError(resp)
+	}
+
+	return resp, nil
+}
+
+func (s *S3) newRequest(cx context.Context, method, key string, query url.Values, header http.Header, body io.ReadSeeker) (*http.Request, error) {
+	payloadHash := sha256.New()
+
+	var size int64
+
+	if body != nil {
+		var err error
+
+		size, err = io.Copy(payloadHash, body)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := body.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+
+	reqURL := s.objectURL(key)
+	reqURL.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(cx, method, reqURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if size > 0 {
+		var reader io.Reader = body
+		if s.Progress != nil {
+			reader = s.Progress(reader)
+		}
+
+		req.Body = ioutil.NopCloser(reader)
+		req.ContentLength = size
+	}
+
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	} else {
+		s.sign(req, hex.EncodeToString(payloadHash.Sum(nil)))
+	}
+
+	return req, nil
+}
+
+func (s *S3) objectURL(key string) *url.URL {
+	u := *s.Endpoint
+	key = strings.TrimPrefix(key, "/")
+
+	if s.PathStyle {
+		u.Path = "/" + s.Bucket + "/" + key
+	} else {
+		u.Host = s.Bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+
+	u.RawPath = uriEncode(u.Path, false)
+
+	return &u
+}
+
+// sign adds AWS Signature Version 4 headers to a request. All headers set
+// at the time of signing are signed, in addition to Host.
+func (s *S3) sign(req *http.Request, payloadHash string) {
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+
+	timestamp := now().UTC().Format(s3DateFormat)
+	scope := strings.Join([]string{timestamp[:8], s.region(), s3Service, "aws4_request"}, "/")
+
+	req.Header.Set("X-Amz-Date", timestamp)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	canonicalHeaders := &strings.Builder{}
+	for _, name := range names {
+		fmt.Fprintf(canonicalHeaders, "%s:%s\n", name, headers[name])
+	}
+
+	signedHeaders := strings.Join(names, ";")
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		timestamp,
+		scope,
+		hexSHA256([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), timestamp[:8])
+	for _, part := range []string{s.region(), s3Service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm,
+		s.AccessKey,
+		scope,
+		signedHeaders,
+		hex.EncodeToString(hmacSHA256(key, stringToSign)),
+	))
+}
+
+func (s *S3) partSize() int64 {
+	if s.PartSize <= 0 {
+		return DefaultPartSize
+	}
+
+	if s.PartSize < MinPartSize {
+		return MinPartSize
+	}
+
+	return s.PartSize
+}
+
+func (s *S3) region() string {
+	if s.Region == "" {
+		return "us-east-1"
+	}
+
+	return s.Region
+}
+
+func (s *S3) header(opts *UploadOptions) http.Header {
+	header := http.Header{}
+
+	prefix := s.HeaderPrefix
+	if prefix == "" {
+		prefix = "x-amz-"
+	}
+
+	if opts.ACL != "" {
+		header.Set(prefix+"acl", opts.ACL)
+	}
+
+	if opts.ContentType != "" {
+		header.Set("Content-Type", opts.ContentType)
+	}
+
+	if opts.StorageClass != "" {
+		header.Set(prefix+"storage-class", opts.StorageClass)
+	}
+
+	return header
+}
+
+// canonicalQuery encodes query parameters in the form signatures require
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	pairs := []string{}
+
+	for _, key := range keys {
+		values := append([]string{}, query[key]...)
+		sort.Strings(values)
+
+		for _, value := range values {
+			pairs = append(pairs, uriEncode(key, true)+"="+uriEncode(value, true))
+		}
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode percent-encodes everything except unreserved characters, and
+// optionally slashes
+func uriEncode(text string, encodeSlash bool) string {
+	out := &strings.Builder{}
+
+	for _, b := range []byte(text) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9',
+			b == '-', b == '.', b == '_', b == '~', b == '/' && !encodeSlash:
+			out.WriteByte(b)
+		default:
+			fmt.Fprintf(out, "%%%02X", b)
+		}
+	}
+
+	return out.String()
+}
+
+func decodeXML(resp *http.Response, v interface{}) error {
+	defer resp.Body.Close()
+
+	return xml.NewDecoder(resp.Body).Decode(v)
+}
+
+func responseError(resp *http.Response) error {
+	var apiErr struct {
+		Code    string
+		Message string
+	}
+
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err := xml.Unmarshal(body, &apiErr); err == nil && apiErr.Code != "" {
+		return fmt.Errorf("%s: %s: %s", resp.Status, apiErr.Code, apiErr.Message)
+	}
+
+	return fmt.Errorf("%s", resp.Status)
+}
+
+func hexSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(data))
+
+	return mac.Sum(nil)
+}
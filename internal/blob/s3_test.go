@@ -0,0 +1,108 @@
+package blob_test
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/julian7/goshipdone/internal/blob"
+	"github.com/julian7/goshipdone/pipelinetest"
+)
+
+func TestS3_UploadFile(t *testing.T) {
+	tests := []struct {
+		name      string
+		size      int
+		wantParts int
+	}{
+		{"simple", 1024, 0},
+		{"empty", 0, 0},
+		{"multipart", 2*blob.MinPartSize + 1024, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := pipelinetest.NewFakeStorage(t)
+			endpoint, _ := url.Parse(storage.URL)
+
+			content := bytes.Repeat([]byte{'x'}, tt.size)
+			filename := filepath.Join(t.TempDir(), "app.tar.gz")
+
+			if err := os.WriteFile(filename, content, 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			s3 := &blob.S3{
+				AccessKey: "access",
+				Bucket:    "releases",
+				Endpoint:  endpoint,
+				PartSize:  blob.MinPartSize,
+				PathStyle: true,
+				SecretKey: "secret",
+			}
+
+			opts := &blob.UploadOptions{ACL: "public-read", StorageClass: "STANDARD_IA"}
+			if err := s3.UploadFile(context.Background(), "app/v1.0.0/app.tar.gz", filename, opts); err != nil {
+				t.Fatalf("UploadFile() error = %v", err)
+			}
+
+			key := "releases/app/v1.0.0/app.tar.gz"
+
+			got, ok := storage.Object(key)
+			if !ok {
+				t.Fatalf("object %s not found in %v", key, storage.Objects())
+			}
+
+			if !bytes.Equal(got, content) {
+				t.Errorf("object size = %d, want %d", len(got), len(content))
+			}
+
+			if parts := storage.Parts(key); parts != tt.wantParts {
+				t.Errorf("parts = %d, want %d", parts, tt.wantParts)
+			}
+
+			header := storage.Header(key)
+			if acl := header.Get("X-Amz-Acl"); acl != "public-read" {
+				t.Errorf("ACL = %q, want public-read", acl)
+			}
+
+			if class := header.Get("X-Amz-Storage-Class"); class != "STANDARD_IA" {
+				t.Errorf("storage class = %q, want STANDARD_IA", class)
+			}
+		})
+	}
+}
+
+func TestNewGCS(t *testing.T) {
+	storage := pipelinetest.NewFakeStorage(t)
+	filename := filepath.Join(t.TempDir(), "app.tar.gz")
+
+	if err := os.WriteFile(filename, []byte("content"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	gcs := blob.NewGCS("releases", "", "", "oauth-token")
+	gcs.Endpoint, _ = url.Parse(storage.URL)
+	gcs.PathStyle = true
+
+	if gcs.String() != "gs:releases" {
+		t.Errorf("String() = %s, want gs:releases", gcs)
+	}
+
+	opts := &blob.UploadOptions{ACL: "public-read"}
+	if err := gcs.UploadFile(context.Background(), "app.tar.gz", filename, opts); err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+
+	header := storage.Header("releases/app.tar.gz")
+	if auth := header.Get("Authorization"); auth != "Bearer oauth-token" {
+		t.Errorf("Authorization = %q, want bearer token", auth)
+	}
+
+	if acl := header.Get("X-Goog-Acl"); acl != "public-read" {
+		t.Errorf("ACL = %q, want public-read", acl)
+	}
+}
@@ -0,0 +1,242 @@
+// Package deb reads metadata of Debian binary packages, and renders APT
+// repository indexes.
+package deb
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/julian7/goshipdone/archive"
+)
+
+const arMagic = "!<arch>\n"
+
+// externalDecompressors are commands decompressing control archives
+// compressed with formats not registered in the archive package
+// nolint: gochecknoglobals
+var externalDecompressors = map[string]string{
+	".xz":  "xz",
+	".zst": "zstd",
+}
+
+type (
+	// Field is a field of a control paragraph
+	Field struct {
+		Name  string
+		Value string
+	}
+
+	// Control is a control paragraph, keeping the order of its fields
+	Control []Field
+)
+
+// Get returns a field's value, matching its name case-insensitively
+func (c Control) Get(name string) string {
+	for _, field := range c {
+		if strings.EqualFold(field.Name, name) {
+			return field.Value
+		}
+	}
+
+	return ""
+}
+
+// Set overwrites a field's value, or appends a new field
+func (c *Control) Set(name, value string) {
+	for i, field := range *c {
+		if strings.EqualFold(field.Name, name) {
+			(*c)[i].Value = value
+			return
+		}
+	}
+
+	*c = append(*c, Field{Name: name, Value: value})
+}
+
+// String renders the paragraph, without trailing empty line
+func (c Control) String() string {
+	out := &strings.Builder{}
+
+	for _, field := range c {
+		separator := ": "
+		if strings.HasPrefix(field.Value, "\n") {
+			separator = ":"
+		}
+
+		fmt.Fprintf(out, "%s%s%s\n", field.Name, separator, field.Value)
+	}
+
+	return out.String()
+}
+
+// ParseControl parses a single control paragraph. Continuation lines are
+// kept verbatim in values, prefixed with a newline.
+func ParseControl(reader io.Reader) (Control, error) {
+	control := Control{}
+	scanner := bufio.NewScanner(reader)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.TrimSpace(line) == "" {
+			if len(control) > 0 {
+				break
+			}
+
+			continue
+		}
+
+		if line[0] == ' ' || line[0] == '\t' {
+			if len(control) == 0 {
+				return nil, errors.New("continuation line without field")
+			}
+
+			control[len(control)-1].Value += "\n" + line
+
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid control line %q", line)
+		}
+
+		control = append(control, Field{Name: parts[0], Value: strings.TrimSpace(parts[1])})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return control, nil
+}
+
+// ReadControl reads the control paragraph of a binary package
+func ReadControl(cx context.Context, filename string) (Control, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+
+	magic := make([]byte, len(arMagic))
+	if _, err := io.ReadFull(reader, magic); err != nil || string(magic) != arMagic {
+		return nil, fmt.Errorf("%s: not a debian package", filename)
+	}
+
+	for {
+		name, size, err := readArHeader(reader)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("%s: control archive not found", filename)
+			}
+
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+
+		if strings.HasPrefix(name, "control.tar") {
+			control, err := readControlArchive(cx, io.LimitReader(reader, size), path.Ext(name))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", filename, err)
+			}
+
+			return control, nil
+		}
+
+		// members are padded to even size
+		if _, err := reader.Discard(int(size + size%2)); err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+	}
+}
+
+func readArHeader(reader io.Reader) (string, int64, error) {
+	header := make([]byte, 60)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return "", 0, errors.New("truncated ar header")
+		}
+
+		return "", 0, err
+	}
+
+	if string(header[58:60]) != "`\n" {
+		return "", 0, errors.New("invalid ar header")
+	}
+
+	size, err := strconv.ParseInt(strings.TrimSpace(string(header[48:58])), 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid ar member size: %w", err)
+	}
+
+	return strings.TrimSuffix(strings.TrimSpace(string(header[0:16])), "/"), size, nil
+}
+
+func readControlArchive(cx context.Context, reader io.Reader, ext string) (Control, error) {
+	decompressed, err := decompress(cx, reader, ext)
+	if err != nil {
+		return nil, err
+	}
+	defer decompressed.Close()
+
+	tr := tar.NewReader(decompressed)
+
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, errors.New("control file not found")
+			}
+
+			return nil, fmt.Errorf("reading control archive: %w", err)
+		}
+
+		if path.Clean(header.Name) == "control" {
+			return ParseControl(tr)
+		}
+	}
+}
+
+// decompress returns a decompressing reader of a control archive by its
+// extension. Formats not registered in the archive package are
+// decompressed with external commands, which are killed when cx is
+// canceled.
+func decompress(cx context.Context, reader io.Reader, ext string) (io.ReadCloser, error) {
+	if ext == ".tar" {
+		return io.NopCloser(reader), nil
+	}
+
+	for _, name := range archive.Compressions() {
+		if comp, _ := archive.LookupCompression(name); comp.Extension == ext {
+			return comp.NewReader(reader)
+		}
+	}
+
+	command, ok := externalDecompressors[ext]
+	if !ok {
+		return nil, fmt.Errorf("control archive compression %q is not supported", ext)
+	}
+
+	out := &bytes.Buffer{}
+	cmd := exec.CommandContext(cx, command, "-dc")
+	cmd.Stdin = reader
+	cmd.Stdout = out
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("decompressing control archive with %s: %w", command, err)
+	}
+
+	return io.NopCloser(out), nil
+}
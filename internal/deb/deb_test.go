@@ -0,0 +1,73 @@
+package deb_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julian7/goshipdone/internal/deb"
+)
+
+func TestParseControl(t *testing.T) {
+	control, err := deb.ParseControl(strings.NewReader(`Package: hello
+Version: 1.0.0
+Architecture: amd64
+Description: says hello
+ Long description
+ .
+ with paragraphs
+
+Package: ignored
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := control.Get("package"); got != "hello" {
+		t.Errorf("Get(package) = %q, want hello", got)
+	}
+
+	control.Set("Size", "42")
+
+	want := `Package: hello
+Version: 1.0.0
+Architecture: amd64
+Description: says hello
+ Long description
+ .
+ with paragraphs
+Size: 42
+`
+	if got := control.String(); got != want {
+		t.Errorf("String():\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRelease_Bytes(t *testing.T) {
+	release := &deb.Release{
+		Architectures: []string{"amd64", "arm64"},
+		Codename:      "stable",
+		Components:    []string{"main"},
+		Date:          time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Files:         []*deb.IndexFile{deb.NewIndexFile("main/binary-amd64/Packages", []byte("content"))},
+		Origin:        "hello",
+		Suite:         "stable",
+	}
+
+	want := `Origin: hello
+Suite: stable
+Codename: stable
+Date: Fri, 02 Jan 2026 03:04:05 UTC
+Architectures: amd64 arm64
+Components: main
+MD5Sum:
+ 9a0364b9e99bb480dd25e1f0284c8555 7 main/binary-amd64/Packages
+SHA1:
+ 040f06fd774092478d450774f5ba30c5da78acc8 7 main/binary-amd64/Packages
+SHA256:
+ ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73 7 main/binary-amd64/Packages
+`
+	if got := string(release.Bytes()); got != want {
+		t.Errorf("Bytes():\n%s\nwant:\n%s", got, want)
+	}
+}
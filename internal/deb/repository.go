@@ -0,0 +1,213 @@
+package deb
+
+import (
+	"context"
+	"crypto/md5"  // nolint: gosec
+	"crypto/sha1" // nolint: gosec
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+type (
+	// Package is a binary package in an APT repository
+	Package struct {
+		// Control is the package's control paragraph
+		Control Control
+		// Filename is the package's path relative to the repository root
+		Filename string
+		// Size is the package's size in bytes
+		Size int64
+		// MD5 is the package's hex-encoded MD5 checksum
+		MD5 string
+		// SHA1 is the package's hex-encoded SHA1 checksum
+		SHA1 string
+		// SHA256 is the package's hex-encoded SHA256 checksum
+		SHA256 string
+	}
+
+	// IndexFile is a file listed in a Release file with its checksums
+	IndexFile struct {
+		// Name is the file's path relative to the Release file
+		Name   string
+		Size   int64
+		MD5    string
+		SHA1   string
+		SHA256 string
+	}
+
+	// Release is a Release file of an APT repository
+	Release struct {
+		Architectures []string
+		Codename      string
+		Components    []string
+		Date          time.Time
+		Description   string
+		Files         []*IndexFile
+		Label         string
+		Origin        string
+		Suite         string
+	}
+)
+
+// ReadPackage reads a binary package's control paragraph and checksums.
+// Filename is the package's path in the repository.
+func ReadPackage(cx context.Context, location, filename string) (*Package, error) {
+	control, err := ReadControl(cx, location)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, field := range []string{"Package", "Version", "Architecture"} {
+		if control.Get(field) == "" {
+			return nil, fmt.Errorf("%s: %s field is missing", location, field)
+		}
+	}
+
+	file, err := os.Open(location)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	md5sum, sha1sum, sha256sum := md5.New(), sha1.New(), sha256.New() // nolint: gosec
+
+	size, err := io.Copy(io.MultiWriter(md5sum, sha1sum, sha256sum), file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", location, err)
+	}
+
+	return &Package{
+		Control:  control,
+		Filename: filename,
+		Size:     size,
+		MD5:      hexSum(md5sum),
+		SHA1:     hexSum(sha1sum),
+		SHA256:   hexSum(sha256sum),
+	}, nil
+}
+
+// Name returns the package's name
+func (pkg *Package) Name() string {
+	return pkg.Control.Get("Package")
+}
+
+// Version returns the package's version
+func (pkg *Package) Version() string {
+	return pkg.Control.Get("Version")
+}
+
+// Architecture returns the package's architecture, like "amd64", or "all"
+func (pkg *Package) Architecture() string {
+	return pkg.Control.Get("Architecture")
+}
+
+// Source returns the package's source package name, without version
+func (pkg *Package) Source() string {
+	source := strings.Fields(pkg.Control.Get("Source"))
+	if len(source) == 0 {
+		return pkg.Name()
+	}
+
+	return source[0]
+}
+
+// Stanza renders the package's paragraph in a Packages index
+func (pkg *Package) Stanza() string {
+	control := append(Control{}, pkg.Control...)
+	control.Set("Filename", pkg.Filename)
+	control.Set("Size", fmt.Sprint(pkg.Size))
+	control.Set("MD5sum", pkg.MD5)
+	control.Set("SHA1", pkg.SHA1)
+	control.Set("SHA256", pkg.SHA256)
+
+	return control.String()
+}
+
+// Packages renders a Packages index, ordered by package name, version,
+// and architecture
+func Packages(pkgs []*Package) []byte {
+	sorted := append([]*Package{}, pkgs...)
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Name() != b.Name() {
+			return a.Name() < b.Name()
+		}
+
+		if a.Version() != b.Version() {
+			return a.Version() < b.Version()
+		}
+
+		return a.Architecture() < b.Architecture()
+	})
+
+	stanzas := make([]string, 0, len(sorted))
+	for _, pkg := range sorted {
+		stanzas = append(stanzas, pkg.Stanza())
+	}
+
+	return []byte(strings.Join(stanzas, "\n"))
+}
+
+// NewIndexFile calculates checksums of an index file's content
+func NewIndexFile(name string, content []byte) *IndexFile {
+	md5sum := md5.Sum(content)   // nolint: gosec
+	sha1sum := sha1.Sum(content) // nolint: gosec
+	sha256sum := sha256.Sum256(content)
+
+	return &IndexFile{
+		Name:   name,
+		Size:   int64(len(content)),
+		MD5:    hex.EncodeToString(md5sum[:]),
+		SHA1:   hex.EncodeToString(sha1sum[:]),
+		SHA256: hex.EncodeToString(sha256sum[:]),
+	}
+}
+
+// Bytes renders the Release file
+func (rel *Release) Bytes() []byte {
+	control := Control{}
+
+	for _, field := range []Field{
+		{"Origin", rel.Origin},
+		{"Label", rel.Label},
+		{"Suite", rel.Suite},
+		{"Codename", rel.Codename},
+		{"Date", rel.Date.UTC().Format(time.RFC1123)},
+		{"Architectures", strings.Join(rel.Architectures, " ")},
+		{"Components", strings.Join(rel.Components, " ")},
+		{"Description", rel.Description},
+	} {
+		if field.Value != "" {
+			control = append(control, field)
+		}
+	}
+
+	for _, sum := range []struct {
+		name  string
+		value func(*IndexFile) string
+	}{
+		{"MD5Sum", func(file *IndexFile) string { return file.MD5 }},
+		{"SHA1", func(file *IndexFile) string { return file.SHA1 }},
+		{"SHA256", func(file *IndexFile) string { return file.SHA256 }},
+	} {
+		lines := &strings.Builder{}
+		for _, file := range rel.Files {
+			fmt.Fprintf(lines, "\n %s %d %s", sum.value(file), file.Size, file.Name)
+		}
+
+		control = append(control, Field{Name: sum.name, Value: lines.String()})
+	}
+
+	return []byte(control.String())
+}
+
+func hexSum(h hash.Hash) string {
+	return hex.EncodeToString(h.Sum(nil))
+}
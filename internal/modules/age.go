@@ -0,0 +1,226 @@
+package modules
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/agessh"
+	"filippo.io/age/armor"
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+// Age is a module for encrypting artifacts to a set of age recipients, for
+// private distribution channels. See https://age-encryption.org/.
+type Age struct {
+	// Armor turns on ASCII armored (PEM-like) output. Default: false.
+	Armor bool
+	// Builds specifies which build names should be encrypted.
+	Builds []string
+	// ID contains the encrypted artifacts' name used by later stages of
+	// the pipeline. Default: "age".
+	ID string
+	// Output is the encrypted file name template. Default:
+	// `{{.Filename}}.age`.
+	Output string
+	// Recipients is a list of age recipients (`age1...`), or SSH public
+	// keys (`ssh-ed25519 ...`, `ssh-rsa ...`). Variable expansion is
+	// available.
+	Recipients []string
+	// RecipientsFile specifies a file with one recipient per line. Empty
+	// lines, and lines starting with `#` are ignored. Variable expansion is
+	// available.
+	RecipientsFile string `yaml:"recipients_file"`
+	// Skip specifies GOOS-GOArch combinations to be skipped.
+	Skip []string
+}
+
+// NewAge is a factory function for Age module
+func NewAge() modules.Pluggable {
+	return &Age{
+		ID:     "age",
+		Output: "{{.Filename}}.age",
+	}
+}
+
+// Run encrypts artifacts
+func (mod *Age) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	recipients, err := mod.recipients(context)
+	if err != nil {
+		return err
+	}
+
+	for _, build := range context.Artifacts.OsArchByIDs(mod.Builds, mod.Skip) {
+		for _, art := range *build {
+			if err := mod.encrypt(cx, recipients, art); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (mod *Age) recipients(context *ctx.Context) ([]age.Recipient, error) {
+	lines := make([]string, 0, len(mod.Recipients))
+
+	for _, recipient := range mod.Recipients {
+		lines = append(lines, context.Env.Expand(recipient))
+	}
+
+	if mod.RecipientsFile != "" {
+		fn := context.Path(context.Env.Expand(mod.RecipientsFile))
+
+		file, err := os.Open(fn)
+		if err != nil {
+			return nil, fmt.Errorf("opening recipients file: %w", err)
+		}
+
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("reading recipients file %s: %w", fn, err)
+		}
+	}
+
+	recipients := []age.Recipient{}
+
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		recipient, err := parseAgeRecipient(line)
+		if err != nil {
+			return nil, err
+		}
+
+		recipients = append(recipients, recipient)
+	}
+
+	if len(recipients) == 0 {
+		return nil, errors.New("no age recipients provided")
+	}
+
+	return recipients, nil
+}
+
+func parseAgeRecipient(line string) (age.Recipient, error) {
+	if strings.HasPrefix(line, "ssh-") {
+		recipient, err := agessh.ParseRecipient(line)
+		if err != nil {
+			return nil, fmt.Errorf("parsing SSH recipient: %w", err)
+		}
+
+		return recipient, nil
+	}
+
+	recipient, err := age.ParseX25519Recipient(line)
+	if err != nil {
+		return nil, fmt.Errorf("parsing age recipient: %w", err)
+	}
+
+	return recipient, nil
+}
+
+func (mod *Age) encrypt(cx context.Context, recipients []age.Recipient, art *ctx.Artifact) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	td, err := modules.NewTemplate(cx)
+	if err != nil {
+		return err
+	}
+
+	td.OSArch = art.OsArch
+	td.Filename = art.Filename
+
+	output, err := td.Parse("age-output", mod.Output)
+	if err != nil {
+		return fmt.Errorf("rendering %q: %w", mod.Output, err)
+	}
+
+	location := path.Join(context.TargetDir, output)
+
+	context.Progress.SetState(fmt.Sprintf("encrypting %s", art.Filename))
+
+	if err := encryptFile(art.Location, location, mod.Armor, recipients, context.Progress); err != nil {
+		return fmt.Errorf("encrypting %s: %w", art.Location, err)
+	}
+
+	context.Artifacts.Add(&ctx.Artifact{
+		Filename: output,
+		ID:       mod.ID,
+		Location: location,
+		OsArch:   art.OsArch,
+		Parents:  []string{art.Filename},
+	})
+
+	return nil
+}
+
+func encryptFile(src, dst string, useArmor bool, recipients []age.Recipient, progress *ctx.Progress) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	defer out.Close()
+
+	var dest io.Writer = out
+
+	var armorWriter io.WriteCloser
+
+	if useArmor {
+		armorWriter = armor.NewWriter(out)
+		dest = armorWriter
+	}
+
+	writer, err := age.Encrypt(dest, recipients...)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(writer, progress.Reader(in)); err != nil {
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	if armorWriter != nil {
+		if err := armorWriter.Close(); err != nil {
+			return err
+		}
+	}
+
+	return out.Close()
+}
@@ -0,0 +1,65 @@
+package modules
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/julian7/goshipdone/ctx"
+)
+
+func Test_encryptFile(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recipient, err := parseAgeRecipient(identity.Recipient().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "plain")
+	content := []byte("secret artifact\n")
+
+	if err := os.WriteFile(src, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, useArmor := range []bool{false, true} {
+		dst := filepath.Join(dir, "plain.age")
+
+		if err := encryptFile(src, dst, useArmor, []age.Recipient{recipient}, new(ctx.Progress)); err != nil {
+			t.Fatalf("encryptFile(armor: %v) error = %v", useArmor, err)
+		}
+
+		encrypted, err := os.ReadFile(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var reader io.Reader = bytes.NewReader(encrypted)
+		if useArmor {
+			reader = armor.NewReader(reader)
+		}
+
+		decrypted, err := age.Decrypt(reader, identity)
+		if err != nil {
+			t.Fatalf("decrypting (armor: %v): %v", useArmor, err)
+		}
+
+		got, err := io.ReadAll(decrypted)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(got, content) {
+			t.Errorf("decrypted (armor: %v) = %q, want %q", useArmor, got, content)
+		}
+	}
+}
@@ -0,0 +1,376 @@
+package modules
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/internal/deb"
+	"github.com/julian7/goshipdone/modules"
+)
+
+// APT is a publish module maintaining an APT repository of debian packages
+// in a local directory. It copies packages into the repository, and
+// regenerates Packages and Release indexes of all packages found there,
+// optionally signing them with GnuPG. Repository files changed are
+// registered as artifacts, to be uploaded by other publishers (like
+// publish:s3), or the directory can be synced elsewhere.
+type APT struct {
+	// Architectures are always listed in the repository, in addition to
+	// architectures of packages. Default: [].
+	Architectures []string
+	// Artifacts is a template expression selecting artifacts of Builds
+	// (or all artifacts, if Builds is empty), evaluated for each artifact.
+	// See modules.SelectArtifacts.
+	Artifacts string
+	// Builds specifies debian packages to be published. Default: ["deb"].
+	Builds []string
+	// Codename is the distribution's codename. Default: "stable".
+	Codename string
+	// Component is the repository component. Default: "main".
+	Component string
+	// Description is the Release file's description. Default: empty.
+	Description string
+	// Directory is the repository's local directory. Keep it (or sync it
+	// back) between releases to retain packages of previous releases.
+	// Default: "<target>/apt".
+	Directory string
+	// Flat creates a flat repository (packages and indexes in the
+	// repository root) instead of a pool-based one. Default: false.
+	Flat bool
+	// ID is the artifact ID of repository files. Default: "apt".
+	ID string
+	// Label is the Release file's label. Default: empty.
+	Label string
+	// Origin is the Release file's origin (template).
+	// Default: "{{.ProjectName}}".
+	Origin string
+	// PassphraseEnv specifies the environment variable of the signing
+	// key's passphrase. Default: empty (no passphrase).
+	PassphraseEnv string `yaml:"passphrase_env"`
+	// SigningKey is the GnuPG key ID signing Release files. Default: the
+	// key imported by setup:gpg_import. Release files are not signed if
+	// empty.
+	SigningKey string `yaml:"signing_key"`
+	// Skip specifies GOOS-GOArch combinations to be skipped.
+	// They are in `{{.Os}}-{{.Arch}}` format.
+	Skip []string
+	// Suite is the distribution's suite. Default: Codename.
+	Suite string
+}
+
+// NewAPT is a factory function for APT module
+func NewAPT() modules.Pluggable {
+	return &APT{
+		Architectures: []string{},
+		Builds:        []string{"deb"},
+		Codename:      "stable",
+		Component:     "main",
+		ID:            "apt",
+		Origin:        "{{.ProjectName}}",
+		Skip:          []string{},
+	}
+}
+
+// Run adds packages into the repository, and regenerates its indexes
+func (mod *APT) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	dir := context.Path(mod.Directory)
+	if dir == "" {
+		dir = filepath.Join(context.TargetDir, "apt")
+	}
+
+	added, err := mod.addPackages(cx, dir)
+	if err != nil {
+		return err
+	}
+
+	pkgs, err := mod.scan(cx, dir)
+	if err != nil {
+		return err
+	}
+
+	indexes, err := mod.indexes(cx, context, pkgs)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range repoFileNames(indexes) {
+		if err := writeRepoFile(dir, name, indexes[name]); err != nil {
+			return err
+		}
+	}
+
+	files := append(added, repoFileNames(indexes)...)
+
+	if key := defaultString(mod.SigningKey, context.GPGFingerprint); key != "" {
+		signatures, err := mod.sign(cx, context, dir, key)
+		if err != nil {
+			return err
+		}
+
+		files = append(files, signatures...)
+	}
+
+	for _, name := range files {
+		context.Artifacts.Add(&ctx.Artifact{
+			Filename: name,
+			ID:       mod.ID,
+			Location: filepath.Join(dir, filepath.FromSlash(name)),
+		})
+	}
+
+	return nil
+}
+
+// releaseDir returns the directory of the Release file, relative to the
+// repository root
+func (mod *APT) releaseDir() string {
+	if mod.Flat {
+		return ""
+	}
+
+	return path.Join("dists", mod.Codename)
+}
+
+// addPackages copies selected packages into the repository, returning
+// their paths relative to the repository root
+func (mod *APT) addPackages(cx context.Context, dir string) ([]string, error) {
+	builds, err := modules.SelectArtifacts(cx, mod.Builds, mod.Skip, mod.Artifacts)
+	if err != nil {
+		return nil, err
+	}
+
+	added := []string{}
+
+	for _, build := range builds {
+		for _, artifact := range *build {
+			if !strings.HasSuffix(artifact.Filename, ".deb") {
+				continue
+			}
+
+			name := artifact.Filename
+
+			if !mod.Flat {
+				control, err := deb.ReadControl(cx, artifact.Location)
+				if err != nil {
+					return nil, err
+				}
+
+				name = path.Join(mod.poolDir((&deb.Package{Control: control}).Source()), name)
+			}
+
+			if err := copyRepoFile(artifact.Location, filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+				return nil, err
+			}
+
+			added = append(added, name)
+		}
+	}
+
+	if len(added) == 0 {
+		return nil, errors.New("no debian packages found")
+	}
+
+	return added, nil
+}
+
+// poolDir returns a source package's pool directory, like
+// "pool/main/libf/libfoo"
+func (mod *APT) poolDir(source string) string {
+	prefix := source[:1]
+	if strings.HasPrefix(source, "lib") && len(source) > 3 {
+		prefix = source[:4]
+	}
+
+	return path.Join("pool", mod.Component, prefix, source)
+}
+
+// scan reads all packages of the repository
+func (mod *APT) scan(cx context.Context, dir string) ([]*deb.Package, error) {
+	root := dir
+	if !mod.Flat {
+		root = filepath.Join(dir, "pool", mod.Component)
+	}
+
+	pkgs := []*deb.Package{}
+
+	err := filepath.Walk(root, func(location string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if mod.Flat && location != root {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if !strings.HasSuffix(info.Name(), ".deb") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, location)
+		if err != nil {
+			return err
+		}
+
+		pkg, err := deb.ReadPackage(cx, location, filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+
+		pkgs = append(pkgs, pkg)
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning repository: %w", err)
+	}
+
+	return pkgs, nil
+}
+
+// indexes renders Packages and Release files, keyed by their paths
+// relative to the repository root
+func (mod *APT) indexes(cx context.Context, context *ctx.Context, pkgs []*deb.Package) (map[string][]byte, error) {
+	td, err := modules.NewTemplate(cx)
+	if err != nil {
+		return nil, err
+	}
+
+	origin, err := td.Parse("apt-origin", mod.Origin)
+	if err != nil {
+		return nil, fmt.Errorf("rendering origin: %w", err)
+	}
+
+	archIndex := map[string]bool{}
+	for _, arch := range mod.Architectures {
+		archIndex[arch] = true
+	}
+
+	for _, pkg := range pkgs {
+		if pkg.Architecture() != "all" {
+			archIndex[pkg.Architecture()] = true
+		}
+	}
+
+	if len(archIndex) == 0 {
+		archIndex["all"] = true
+	}
+
+	archs := make([]string, 0, len(archIndex))
+	for arch := range archIndex {
+		archs = append(archs, arch)
+	}
+
+	sort.Strings(archs)
+
+	release := &deb.Release{
+		Architectures: archs,
+		Date:          context.StartedAt,
+		Description:   mod.Description,
+		Label:         mod.Label,
+		Origin:        origin,
+	}
+
+	// flat repositories have a single index of all architectures
+	binaryDirs := []string{""}
+	binaryPkgs := map[string][]*deb.Package{"": pkgs}
+
+	if !mod.Flat {
+		release.Codename = mod.Codename
+		release.Components = []string{mod.Component}
+		release.Suite = mod.Suite
+
+		if release.Suite == "" {
+			release.Suite = mod.Codename
+		}
+
+		binaryDirs = []string{}
+
+		for _, arch := range archs {
+			binaryDir := path.Join(mod.Component, "binary-"+arch)
+			binaryDirs = append(binaryDirs, binaryDir)
+
+			for _, pkg := range pkgs {
+				if pkg.Architecture() == arch || pkg.Architecture() == "all" {
+					binaryPkgs[binaryDir] = append(binaryPkgs[binaryDir], pkg)
+				}
+			}
+		}
+	}
+
+	files := map[string][]byte{}
+
+	for _, binaryDir := range binaryDirs {
+		content := deb.Packages(binaryPkgs[binaryDir])
+
+		compressed := &bytes.Buffer{}
+		gz := gzip.NewWriter(compressed)
+
+		if _, err := gz.Write(content); err != nil {
+			return nil, err
+		}
+
+		if err := gz.Close(); err != nil {
+			return nil, err
+		}
+
+		for _, index := range []struct {
+			name    string
+			content []byte
+		}{
+			{path.Join(binaryDir, "Packages"), content},
+			{path.Join(binaryDir, "Packages.gz"), compressed.Bytes()},
+		} {
+			release.Files = append(release.Files, deb.NewIndexFile(index.name, index.content))
+			files[path.Join(mod.releaseDir(), index.name)] = index.content
+		}
+	}
+
+	files[path.Join(mod.releaseDir(), "Release")] = release.Bytes()
+
+	return files, nil
+}
+
+// sign creates InRelease and Release.gpg signatures of the Release file
+// with key, returning their paths relative to the repository root
+func (mod *APT) sign(cx context.Context, context *ctx.Context, dir, key string) ([]string, error) {
+	releaseDir := mod.releaseDir()
+	release := filepath.Join(dir, filepath.FromSlash(path.Join(releaseDir, "Release")))
+	signatures := []string{}
+
+	for _, signature := range []struct {
+		name string
+		args []string
+	}{
+		{"InRelease", []string{"--clearsign"}},
+		{"Release.gpg", []string{"--armor", "--detach-sign"}},
+	} {
+		name := path.Join(releaseDir, signature.name)
+		output := filepath.Join(dir, filepath.FromSlash(name))
+
+		if err := gpgSign(cx, context, key, mod.PassphraseEnv, release, output, signature.args...); err != nil {
+			return nil, err
+		}
+
+		signatures = append(signatures, name)
+	}
+
+	return signatures, nil
+}
@@ -0,0 +1,131 @@
+package modules
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+// writeDeb writes a minimal debian package with a control file only
+func writeDeb(t *testing.T, location, control string) {
+	t.Helper()
+
+	controlTar := &bytes.Buffer{}
+	gz := gzip.NewWriter(controlTar)
+	tw := tar.NewWriter(gz)
+
+	if err := tw.WriteHeader(&tar.Header{Name: "./control", Mode: 0o644, Size: int64(len(control))}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tw.Write([]byte(control)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	out := bytes.NewBufferString("!<arch>\n")
+
+	for _, member := range []struct {
+		name    string
+		content []byte
+	}{
+		{"debian-binary", []byte("2.0\n")},
+		{"control.tar.gz", controlTar.Bytes()},
+	} {
+		fmt.Fprintf(out, "%-16s%-12d%-6d%-6d%-8s%-10d`\n", member.name, 0, 0, 0, "100644", len(member.content))
+		out.Write(member.content)
+
+		if len(member.content)%2 == 1 {
+			out.WriteByte('\n')
+		}
+	}
+
+	if err := os.WriteFile(location, out.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAPT_Run(t *testing.T) {
+	dir := t.TempDir()
+	cx := ctx.New(context.Background())
+
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	context.ProjectName = "hello"
+	context.TargetDir = dir
+
+	for _, item := range []struct {
+		filename string
+		arch     string
+	}{
+		{"hello_1.0.0_amd64.deb", "amd64"},
+		{"hello-doc_1.0.0_all.deb", "all"},
+	} {
+		location := filepath.Join(dir, item.filename)
+		name := strings.SplitN(item.filename, "_", 2)[0]
+
+		writeDeb(t, location, fmt.Sprintf("Package: %s\nSource: hello (1.0.0)\nVersion: 1.0.0\nArchitecture: %s\n", name, item.arch))
+		context.Artifacts.Add(&ctx.Artifact{Filename: item.filename, ID: "deb", Location: location})
+	}
+
+	mod := NewAPT().(*APT)
+	mod.Architectures = []string{"arm64"}
+
+	if err := mod.Run(cx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	packages, err := os.ReadFile(filepath.Join(dir, "apt", "dists", "stable", "main", "binary-amd64", "Packages"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"Package: hello\n",
+		"Filename: pool/main/h/hello/hello_1.0.0_amd64.deb\n",
+		"\nPackage: hello-doc\n",
+		"Filename: pool/main/h/hello/hello-doc_1.0.0_all.deb\n",
+	} {
+		if !strings.Contains(string(packages), want) {
+			t.Errorf("Packages does not contain %q:\n%s", want, packages)
+		}
+	}
+
+	release, err := os.ReadFile(filepath.Join(dir, "apt", "dists", "stable", "Release"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"Origin: hello\n",
+		"Architectures: amd64 arm64\n",
+		" main/binary-arm64/Packages.gz\n",
+	} {
+		if !strings.Contains(string(release), want) {
+			t.Errorf("Release does not contain %q:\n%s", want, release)
+		}
+	}
+
+	// 2 packages, and 2 Packages indexes with gzipped versions, and Release
+	if got := len(*context.Artifacts.ByID("apt")); got != 7 {
+		t.Errorf("registered %d repository files, want 7", got)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io/ioutil"
 
+	"github.com/blang/semver"
 	"github.com/julian7/goshipdone/ctx"
 	"github.com/julian7/goshipdone/internal/artifacts"
 	"github.com/julian7/goshipdone/modules"
@@ -14,15 +15,31 @@ import (
 
 // Artifact is a publish module for artifact storage servers like GitHub, or GitLab.
 type Artifact struct {
+	// AllowDowngrade allows publishing a version older than the latest
+	// release of the artifact storage. Default: false.
+	AllowDowngrade bool `yaml:"allow_downgrade"`
+	// Artifacts is a template expression selecting artifacts of Builds
+	// (or all artifacts, if Builds is empty), evaluated for each artifact.
+	// See modules.SelectArtifacts.
+	Artifacts string
 	// Builds specifies which build names should be uploaded to the
 	// github release.
 	Builds []string
+	// Draft creates the release as a draft, to be published by
+	// publish:promote after verification. Default: false.
+	Draft bool
+	// IfExists specifies what to do with assets already in the release,
+	// like after re-running a partially failed pipeline: fail, skip
+	// (keeping the existing asset), or replace. Default: "fail".
+	IfExists artifacts.IfExists `yaml:"if_exists"`
 	// Name specifies the repository's name. No default, no detection (yet).
 	// Required.
 	Name string
 	// Owner specifies the repository's owning organization. No default,
 	// no detection (yet). Required.
 	Owner string
+	// Parallelism is the maximum number of concurrent uploads. Default: 4.
+	Parallelism int
 	// ReleaseName specifies the release's name, using modules.TemplateData.
 	// Default: "{{.Version}}"
 	ReleaseName string `yaml:"release_name,omitempty"`
@@ -52,6 +69,8 @@ func NewArtifact() modules.Pluggable {
 	storage, _ := artifacts.New("github")
 
 	return &Artifact{
+		IfExists:      artifacts.IfExistsFail,
+		Parallelism:   defaultParallelism,
 		ReleaseName:   "{{.Version}}",
 		SkipTLSVerify: false,
 		Storage:       storage,
@@ -61,14 +80,14 @@ func NewArtifact() modules.Pluggable {
 // Run uploads previously created artifact into artifact storage provided
 // by artifacts.Storage.
 func (mod *Artifact) Run(cx context.Context) error {
-	context, err := ctx.GetShipContext(cx)
+	shipContext, err := ctx.GetShipContext(cx)
 	if err != nil {
 		return err
 	}
 
 	var notes string
 
-	relNotes := []*ctx.Artifact(*context.Artifacts.ByID(mod.ReleaseNotes))
+	relNotes := []*ctx.Artifact(*shipContext.Artifacts.ByID(mod.ReleaseNotes))
 
 	switch len(relNotes) {
 	case 0:
@@ -99,24 +118,38 @@ func (mod *Artifact) Run(cx context.Context) error {
 		return fmt.Errorf("parsing release name: %w", err)
 	}
 
-	releaser, err := client.NewReleaser(context.Git.Tag, context.Git.Ref, context.Version)
+	if !mod.AllowDowngrade {
+		if err := checkDowngrade(client, releaseTag(shipContext)); err != nil {
+			return err
+		}
+	}
+
+	releaser, err := client.NewReleaser(shipContext.Git.Tag, shipContext.Git.Ref, shipContext.Version)
 	if err != nil {
 		return fmt.Errorf("setting up releaser: %w", err)
 	}
 
-	if err := releaser.Release(name, notes); err != nil {
+	if err := releaser.Release(name, notes, mod.Draft); err != nil {
 		return fmt.Errorf("releasing: %w", err)
 	}
 
-	for _, build := range context.Artifacts.OsArchByIDs(mod.Builds, nil) {
-		for _, item := range *build {
-			if err := releaser.Upload(item); err != nil {
-				return fmt.Errorf("uploading file %s to release %v: %w", item.Location, releaser, err)
-			}
-		}
+	builds, err := modules.SelectArtifacts(cx, mod.Builds, nil, mod.Artifacts)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return uploadAll(cx, builds, mod.Parallelism, func(_ context.Context, item *ctx.Artifact) error {
+		err := releaser.Upload(item, mod.IfExists)
+		if errors.Is(err, artifacts.ErrSkipped) {
+			return errUploadSkipped
+		}
+
+		if err != nil {
+			return fmt.Errorf("uploading file %s to release %v: %w", item.Location, releaser, err)
+		}
+
+		return nil
+	})
 }
 
 // NewClient returns a new Storage connection
@@ -138,3 +171,40 @@ func (mod *Artifact) getTLSConfig() *tls.Config {
 		MinVersion:         tls.VersionTLS12,
 	}
 }
+
+// checkDowngrade makes sure version is not older than the latest release
+func checkDowngrade(client artifacts.Connection, version string) error {
+	latest, err := client.LatestVersion()
+	if err != nil {
+		return fmt.Errorf("checking latest release: %w", err)
+	}
+
+	if latest == "" {
+		return nil
+	}
+
+	latestVer, err := semver.ParseTolerant(latest)
+	if err != nil {
+		return nil
+	}
+
+	ver, err := semver.ParseTolerant(version)
+	if err != nil {
+		return fmt.Errorf("comparing version %q with latest release %s: %w", version, latest, err)
+	}
+
+	if ver.LT(latestVer) {
+		return fmt.Errorf("version %s is older than latest release %s (set allow_downgrade to publish anyway)", version, latest)
+	}
+
+	return nil
+}
+
+// releaseTag returns the tag of the release, falling back to version
+func releaseTag(context *ctx.Context) string {
+	if context.Git.Tag != "" {
+		return context.Git.Tag
+	}
+
+	return context.Version
+}
@@ -0,0 +1,346 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+// aurArchs maps GOARCH values to Arch Linux architectures
+// nolint: gochecknoglobals
+var aurArchs = map[string]string{
+	"386":   "i686",
+	"amd64": "x86_64",
+	"arm64": "aarch64",
+	"armv6": "armv6h",
+	"armv7": "armv7h",
+}
+
+// AUR is a publish module rendering PKGBUILD and .SRCINFO files of a
+// binary package for linux artifacts, and pushing them into an AUR git
+// repository over SSH.
+type AUR struct {
+	// Artifacts is a template expression selecting artifacts of Builds
+	// (or all artifacts, if Builds is empty), evaluated for each artifact.
+	// See modules.SelectArtifacts.
+	Artifacts string
+	// Builds specifies linux artifacts to be packaged. Default:
+	// ["archive"].
+	Builds []string
+	// CommitAuthor is the commit's author in `Name <email>` format. Git
+	// config is used if empty.
+	CommitAuthor string `yaml:"commit_author"`
+	// Conflicts lists conflicting packages.
+	// Default: ["{{.ProjectName}}"].
+	Conflicts []string
+	// Depends lists package dependencies. Default: [].
+	Depends []string
+	// Description is the package's description. Required.
+	Description string
+	// GitURL is the AUR git repository. Default:
+	// "ssh://aur@aur.archlinux.org/<name>.git".
+	GitURL string `yaml:"git_url"`
+	// ID is the artifact ID of generated files. Default: "aur".
+	ID string
+	// License lists the package's licenses. Required.
+	License []string
+	// Maintainers are added to PKGBUILD as comments, in
+	// `Name <email>` format.
+	Maintainers []string
+	// Name is the package's name (template).
+	// Default: "{{.ProjectName}}-bin".
+	Name string
+	// Package is the body of PKGBUILD's package() function. It is not a
+	// template, as PKGBUILD variables would be expanded as environment
+	// variables. Default: installs the project's binary into /usr/bin.
+	Package string
+	// PrivateKeyEnv specifies the environment variable of the SSH
+	// private key for AUR. SSH config (or agent) is used if it is not
+	// set. Default: AUR_KEY.
+	PrivateKeyEnv string `yaml:"private_key_env"`
+	// Provides lists provided packages. Default: ["{{.ProjectName}}"].
+	Provides []string
+	// Rel is the package release number. Default: 1.
+	Rel int
+	// Skip specifies GOOS-GOArch combinations to be skipped.
+	// They are in `{{.Os}}-{{.Arch}}` format.
+	Skip []string
+	// URL is the project's home page. Default: empty.
+	URL string
+	// URLTemplate renders download URLs of artifacts. Download links
+	// of previous publishers are used if empty.
+	URLTemplate string `yaml:"url_template"`
+}
+
+// NewAUR is a factory function for AUR module
+func NewAUR() modules.Pluggable {
+	return &AUR{
+		Builds:        []string{"archive"},
+		Conflicts:     []string{"{{.ProjectName}}"},
+		Depends:       []string{},
+		ID:            "aur",
+		Name:          "{{.ProjectName}}-bin",
+		PrivateKeyEnv: "AUR_KEY",
+		Provides:      []string{"{{.ProjectName}}"},
+		Rel:           1,
+		Skip:          []string{},
+	}
+}
+
+// aurPackage is a rendered AUR package description
+type aurPackage struct {
+	arches      []string
+	conflicts   []string
+	depends     []string
+	description string
+	licenses    []string
+	maintainers []string
+	name        string
+	pkgBody     string
+	provides    []string
+	rel         int
+	sources     []*aurSource
+	url         string
+	version     string
+}
+
+type aurSource struct {
+	arch   string
+	name   string
+	sha256 string
+	url    string
+}
+
+// Run renders package files, registers them as artifacts, and pushes them
+// into the AUR repository
+func (mod *AUR) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	pkg, err := mod.render(cx, context)
+	if err != nil {
+		return err
+	}
+
+	files := map[string][]byte{
+		"PKGBUILD": []byte(pkg.pkgbuild()),
+		".SRCINFO": []byte(pkg.srcinfo()),
+	}
+
+	dir := filepath.Join(context.TargetDir, "aur")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating aur directory: %w", err)
+	}
+
+	for _, name := range []string{"PKGBUILD", ".SRCINFO"} {
+		location := filepath.Join(dir, name)
+		if err := os.WriteFile(location, files[name], 0o644); err != nil { // nolint: gosec
+			return fmt.Errorf("writing %s: %w", name, err)
+		}
+
+		context.Artifacts.Add(&ctx.Artifact{Filename: name, ID: mod.ID, Location: location})
+	}
+
+	gitURL := mod.GitURL
+	if gitURL == "" {
+		gitURL = fmt.Sprintf("ssh://aur@aur.archlinux.org/%s.git", pkg.name)
+	}
+
+	commit := &gitCommit{
+		author:   mod.CommitAuthor,
+		cloneURL: gitURL,
+		files:    files,
+		message:  fmt.Sprintf("Update to %s-%d", pkg.version, pkg.rel),
+	}
+
+	if key, ok := context.LookupEnv(mod.PrivateKeyEnv); ok {
+		commit.sshKey, err = writeTempSecret(context, "aur", "aur_key", strings.TrimSpace(key)+"\n")
+		if err != nil {
+			return err
+		}
+	}
+
+	return commit.push(cx, context)
+}
+
+func (mod *AUR) render(cx context.Context, context *ctx.Context) (*aurPackage, error) {
+	if mod.Description == "" || len(mod.License) == 0 {
+		return nil, errors.New("description and license are required")
+	}
+
+	td, err := modules.NewTemplate(cx)
+	if err != nil {
+		return nil, err
+	}
+
+	pkg := &aurPackage{
+		depends:     mod.Depends,
+		description: mod.Description,
+		licenses:    mod.License,
+		maintainers: mod.Maintainers,
+		pkgBody:     mod.Package,
+		rel:         mod.Rel,
+		url:         mod.URL,
+		version:     aurVersion(context.Version),
+	}
+
+	if pkg.name, err = td.Parse("aur-name", mod.Name); err != nil {
+		return nil, fmt.Errorf("rendering name: %w", err)
+	}
+
+	if pkg.pkgBody == "" {
+		pkg.pkgBody = fmt.Sprintf(
+			`install -Dm755 "$(find "${srcdir}" -type f -name '%[1]s' | head -n1)" "${pkgdir}/usr/bin/%[1]s"`,
+			context.ProjectName,
+		)
+	}
+
+	for _, item := range []struct {
+		name   string
+		list   []string
+		target *[]string
+	}{
+		{"conflicts", mod.Conflicts, &pkg.conflicts},
+		{"provides", mod.Provides, &pkg.provides},
+	} {
+		for _, text := range item.list {
+			value, err := td.Parse("aur-"+item.name, text)
+			if err != nil {
+				return nil, fmt.Errorf("rendering %s: %w", item.name, err)
+			}
+
+			*item.target = append(*item.target, value)
+		}
+	}
+
+	artifacts, err := packageArtifacts(cx, mod.Builds, mod.Skip, mod.Artifacts, mod.URLTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, artifact := range artifacts {
+		if artifact.OsArch == nil || artifact.OS != "linux" {
+			continue
+		}
+
+		arch, ok := aurArchs[artifact.ArchName()]
+		if !ok {
+			return nil, fmt.Errorf("%s: architecture %s is not supported by AUR", artifact.Filename, artifact.ArchName())
+		}
+
+		pkg.arches = append(pkg.arches, arch)
+		pkg.sources = append(pkg.sources, &aurSource{
+			arch:   arch,
+			name:   artifact.Filename,
+			sha256: artifact.SHA256,
+			url:    artifact.URL,
+		})
+	}
+
+	if len(pkg.sources) == 0 {
+		return nil, errors.New("no linux artifacts found")
+	}
+
+	return pkg, nil
+}
+
+func (pkg *aurPackage) pkgbuild() string {
+	out := &strings.Builder{}
+
+	for _, maintainer := range pkg.maintainers {
+		fmt.Fprintf(out, "# Maintainer: %s\n", maintainer)
+	}
+
+	fmt.Fprintf(out, "# Created with goshipdone\n\n")
+	fmt.Fprintf(out, "pkgname=%s\n", shellQuote(pkg.name))
+	fmt.Fprintf(out, "pkgver=%s\n", shellQuote(pkg.version))
+	fmt.Fprintf(out, "pkgrel=%d\n", pkg.rel)
+	fmt.Fprintf(out, "pkgdesc=%s\n", shellQuote(pkg.description))
+	fmt.Fprintf(out, "arch=(%s)\n", shellQuoteAll(pkg.arches))
+
+	if pkg.url != "" {
+		fmt.Fprintf(out, "url=%s\n", shellQuote(pkg.url))
+	}
+
+	fmt.Fprintf(out, "license=(%s)\n", shellQuoteAll(pkg.licenses))
+	fmt.Fprintf(out, "depends=(%s)\n", shellQuoteAll(pkg.depends))
+	fmt.Fprintf(out, "provides=(%s)\n", shellQuoteAll(pkg.provides))
+	fmt.Fprintf(out, "conflicts=(%s)\n", shellQuoteAll(pkg.conflicts))
+
+	for _, src := range pkg.sources {
+		fmt.Fprintf(out, "\nsource_%s=(%s)\n", src.arch, shellQuote(src.name+"::"+src.url))
+		fmt.Fprintf(out, "sha256sums_%s=(%s)\n", src.arch, shellQuote(src.sha256))
+	}
+
+	fmt.Fprintf(out, "\npackage() {\n")
+
+	for _, line := range strings.Split(strings.TrimSpace(pkg.pkgBody), "\n") {
+		fmt.Fprintf(out, "  %s\n", strings.TrimSpace(line))
+	}
+
+	fmt.Fprintf(out, "}\n")
+
+	return out.String()
+}
+
+func (pkg *aurPackage) srcinfo() string {
+	out := &strings.Builder{}
+
+	field := func(name string, values ...string) {
+		for _, value := range values {
+			fmt.Fprintf(out, "\t%s = %s\n", name, value)
+		}
+	}
+
+	fmt.Fprintf(out, "pkgbase = %s\n", pkg.name)
+	field("pkgdesc", pkg.description)
+	field("pkgver", pkg.version)
+	field("pkgrel", fmt.Sprint(pkg.rel))
+
+	if pkg.url != "" {
+		field("url", pkg.url)
+	}
+
+	field("arch", pkg.arches...)
+	field("license", pkg.licenses...)
+	field("depends", pkg.depends...)
+	field("provides", pkg.provides...)
+	field("conflicts", pkg.conflicts...)
+
+	for _, src := range pkg.sources {
+		field("source_"+src.arch, src.name+"::"+src.url)
+		field("sha256sums_"+src.arch, src.sha256)
+	}
+
+	fmt.Fprintf(out, "\npkgname = %s\n", pkg.name)
+
+	return out.String()
+}
+
+// aurVersion converts a version into pkgver format: without "v" prefix,
+// and without hyphens
+func aurVersion(version string) string {
+	return strings.ReplaceAll(strings.TrimPrefix(version, "v"), "-", "_")
+}
+
+// shellQuote quotes a string for bash in single quotes
+func shellQuote(text string) string {
+	return "'" + strings.ReplaceAll(text, "'", `'\''`) + "'"
+}
+
+func shellQuoteAll(items []string) string {
+	quoted := make([]string, 0, len(items))
+	for _, item := range items {
+		quoted = append(quoted, shellQuote(item))
+	}
+
+	return strings.Join(quoted, " ")
+}
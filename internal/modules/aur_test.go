@@ -0,0 +1,98 @@
+package modules
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+func TestAUR_render(t *testing.T) {
+	dir := t.TempDir()
+	cx := ctx.New(context.Background())
+
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	context.ProjectName = "app"
+	context.TargetDir = dir
+	context.Version = "v1.2.3-rc.1"
+
+	for _, art := range []*ctx.Artifact{
+		{Filename: "app-linux-amd64.tar.gz", OsArch: &ctx.OsArch{OS: "linux", Arch: "amd64"}},
+		{Filename: "app-linux-armv7.tar.gz", OsArch: &ctx.OsArch{OS: "linux", Arch: "arm", ArmVersion: 7}},
+		{Filename: "app-windows-amd64.zip", OsArch: &ctx.OsArch{OS: "windows", Arch: "amd64"}},
+	} {
+		art.ID = "archive"
+		art.Location = filepath.Join(dir, art.Filename)
+
+		if err := os.WriteFile(art.Location, []byte("content"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		context.Artifacts.Add(art)
+	}
+
+	mod := NewAUR().(*AUR)
+	mod.Description = "It's an example app"
+	mod.License = []string{"MIT"}
+	mod.Maintainers = []string{"Jane Doe <jane@example.com>"}
+	mod.URLTemplate = "https://example.com/{{.Version}}/{{.Filename}}"
+
+	pkg, err := mod.render(cx, context)
+	if err != nil {
+		t.Fatalf("render() error = %v", err)
+	}
+
+	sum := "ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73"
+	wantPKGBUILD := `# Maintainer: Jane Doe <jane@example.com>
+# Created with goshipdone
+
+pkgname='app-bin'
+pkgver='1.2.3_rc.1'
+pkgrel=1
+pkgdesc='It'\''s an example app'
+arch=('x86_64' 'armv7h')
+license=('MIT')
+depends=()
+provides=('app')
+conflicts=('app')
+
+source_x86_64=('app-linux-amd64.tar.gz::https://example.com/v1.2.3-rc.1/app-linux-amd64.tar.gz')
+sha256sums_x86_64=('` + sum + `')
+
+source_armv7h=('app-linux-armv7.tar.gz::https://example.com/v1.2.3-rc.1/app-linux-armv7.tar.gz')
+sha256sums_armv7h=('` + sum + `')
+
+package() {
+  install -Dm755 "$(find "${srcdir}" -type f -name 'app' | head -n1)" "${pkgdir}/usr/bin/app"
+}
+`
+	if got := pkg.pkgbuild(); got != wantPKGBUILD {
+		t.Errorf("PKGBUILD:\n%s\nwant:\n%s", got, wantPKGBUILD)
+	}
+
+	wantSRCINFO := `pkgbase = app-bin
+	pkgdesc = It's an example app
+	pkgver = 1.2.3_rc.1
+	pkgrel = 1
+	arch = x86_64
+	arch = armv7h
+	license = MIT
+	provides = app
+	conflicts = app
+	source_x86_64 = app-linux-amd64.tar.gz::https://example.com/v1.2.3-rc.1/app-linux-amd64.tar.gz
+	sha256sums_x86_64 = ` + sum + `
+	source_armv7h = app-linux-armv7.tar.gz::https://example.com/v1.2.3-rc.1/app-linux-armv7.tar.gz
+	sha256sums_armv7h = ` + sum + `
+
+pkgname = app-bin
+`
+	if got := pkg.srcinfo(); got != wantSRCINFO {
+		t.Errorf(".SRCINFO:\n%s\nwant:\n%s", got, wantSRCINFO)
+	}
+}
@@ -0,0 +1,337 @@
+package modules
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+type (
+	// Authenticode is a module for signing windows executables with
+	// Authenticode signatures, in place. Put it between build:go and
+	// archiving modules, to sign executables before they're archived.
+	//
+	// It uses a PFX (PKCS#12) certificate with `osslsigncode` or
+	// `signtool`, or an Azure Key Vault-backed certificate with
+	// `azuresigntool`. Secrets are never passed in command line
+	// arguments, and key material is written outside the target
+	// directory, removed at the end of the run.
+	Authenticode struct {
+		// AzureKeyVault sets up Azure Key Vault-backed signing. Certificate
+		// settings are ignored if set.
+		AzureKeyVault *AuthenticodeAzure `yaml:"azure_key_vault,omitempty"`
+		// Builds specifies which build names should be signed. Only
+		// windows artifacts are signed.
+		Builds []string
+		// CertificateEnv specifies an environment variable containing the
+		// base64-encoded PFX certificate. It takes precedence over
+		// CertificateFile. Default: "AUTHENTICODE_CERTIFICATE".
+		CertificateEnv string `yaml:"certificate_env"`
+		// CertificateFile specifies the PFX certificate file. Variable
+		// expansion is available.
+		CertificateFile string `yaml:"certificate_file"`
+		// Description is the signed content's description.
+		// Default: "{{.ProjectName}}".
+		Description string
+		// Digest is the file digest algorithm. Default: "sha256".
+		Digest string
+		// PasswordEnv specifies an environment variable containing the
+		// certificate's password. signtool doesn't support passwords, as
+		// it takes them on its command line only. Default:
+		// "AUTHENTICODE_PASSWORD".
+		PasswordEnv string `yaml:"password_env"`
+		// Skip specifies GOOS-GOArch combinations to be skipped.
+		Skip []string
+		// TimestampURL is the RFC 3161 timestamp server's URL.
+		// Default: "http://timestamp.digicert.com".
+		TimestampURL string `yaml:"timestamp_url"`
+		// Tool selects the signing tool for PFX certificates: "osslsigncode",
+		// or "signtool". Default: "signtool" on windows, "osslsigncode"
+		// elsewhere.
+		Tool string
+		// URL is the signed content's URL. Optional.
+		URL string
+	}
+
+	// AuthenticodeAzure contains Azure Key Vault settings
+	AuthenticodeAzure struct {
+		// Certificate is the certificate's name in the key vault.
+		Certificate string
+		// ClientID is the application ID of the service principal.
+		ClientID string `yaml:"client_id"`
+		// ClientSecretEnv specifies an environment variable containing
+		// the client secret. It is passed to azuresigntool in
+		// AZURE_CLIENT_SECRET environment variable. Default:
+		// "AZURE_CLIENT_SECRET".
+		ClientSecretEnv string `yaml:"client_secret_env"`
+		// TenantID is the Azure AD tenant ID.
+		TenantID string `yaml:"tenant_id"`
+		// URL is the key vault's URL, like https://myvault.vault.azure.net.
+		URL string
+	}
+
+	authenticodeSigner struct {
+		tool string
+		args []string
+		// env contains variables passed to the tool besides the run's
+		// environment, like secrets
+		env     []string
+		inPlace bool
+	}
+)
+
+// NewAuthenticode is a factory function for Authenticode module
+func NewAuthenticode() modules.Pluggable {
+	tool := "osslsigncode"
+	if runtime.GOOS == "windows" {
+		tool = "signtool"
+	}
+
+	return &Authenticode{
+		Builds:         []string{"default"},
+		CertificateEnv: "AUTHENTICODE_CERTIFICATE",
+		Description:    "{{.ProjectName}}",
+		Digest:         "sha256",
+		PasswordEnv:    "AUTHENTICODE_PASSWORD",
+		TimestampURL:   "http://timestamp.digicert.com",
+		Tool:           tool,
+	}
+}
+
+// Run signs windows artifacts in place
+func (mod *Authenticode) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	arts := context.Artifacts.Select().Name(mod.Builds...).Skip(mod.Skip...).OS("windows").List()
+
+	if len(arts) == 0 {
+		return nil
+	}
+
+	td, err := modules.NewTemplate(cx)
+	if err != nil {
+		return err
+	}
+
+	description, err := td.Parse("authenticode-description", mod.Description)
+	if err != nil {
+		return fmt.Errorf("rendering %q: %w", mod.Description, err)
+	}
+
+	signer, err := mod.signer(context, description)
+	if err != nil {
+		return err
+	}
+
+	for _, art := range arts {
+		context.Progress.SetState(fmt.Sprintf("signing %s", art.Filename))
+
+		if err := signer.sign(cx, context, art); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (mod *Authenticode) signer(context *ctx.Context, description string) (*authenticodeSigner, error) {
+	if mod.AzureKeyVault != nil {
+		return mod.azureSigner(context, description)
+	}
+
+	cert, err := mod.certificate(context)
+	if err != nil {
+		return nil, err
+	}
+
+	password := context.Getenv(mod.PasswordEnv, "")
+
+	switch mod.Tool {
+	case "osslsigncode":
+		args := []string{"sign", "-pkcs12", cert, "-h", mod.Digest, "-n", description}
+
+		if password != "" {
+			passFile, err := writeTempSecret(context, "authenticode", "password", password)
+			if err != nil {
+				return nil, err
+			}
+
+			args = append(args, "-readpass", passFile)
+		}
+
+		if mod.URL != "" {
+			args = append(args, "-i", mod.URL)
+		}
+
+		if mod.TimestampURL != "" {
+			args = append(args, "-ts", mod.TimestampURL)
+		}
+
+		return &authenticodeSigner{tool: mod.Tool, args: args}, nil
+	case "signtool":
+		if password != "" {
+			return nil, errors.New("signtool takes certificate passwords on its command line only; use osslsigncode for password-protected certificates")
+		}
+
+		args := []string{"sign", "/f", cert, "/fd", mod.Digest, "/d", description}
+
+		if mod.URL != "" {
+			args = append(args, "/du", mod.URL)
+		}
+
+		if mod.TimestampURL != "" {
+			args = append(args, "/tr", mod.TimestampURL, "/td", mod.Digest)
+		}
+
+		return &authenticodeSigner{tool: mod.Tool, args: args, inPlace: true}, nil
+	default:
+		return nil, fmt.Errorf("unknown authenticode tool %q", mod.Tool)
+	}
+}
+
+func (mod *Authenticode) azureSigner(context *ctx.Context, description string) (*authenticodeSigner, error) {
+	kv := mod.AzureKeyVault
+
+	secretEnv := kv.ClientSecretEnv
+	if secretEnv == "" {
+		secretEnv = "AZURE_CLIENT_SECRET"
+	}
+
+	secret, ok := context.LookupEnv(secretEnv)
+	if !ok || secret == "" {
+		return nil, fmt.Errorf("azure client secret not found in %s", secretEnv)
+	}
+
+	if kv.URL == "" || kv.Certificate == "" {
+		return nil, errors.New("azure key vault url and certificate are required")
+	}
+
+	// azuresigntool authenticates with credentials of the environment
+	// (see DefaultAzureCredential) in managed identity mode, therefore
+	// the secret is not visible in the process list
+	args := []string{
+		"sign",
+		"-kvu", kv.URL,
+		"-kvc", kv.Certificate,
+		"-kvm",
+		"-fd", mod.Digest,
+		"-d", description,
+	}
+
+	if mod.URL != "" {
+		args = append(args, "-du", mod.URL)
+	}
+
+	if mod.TimestampURL != "" {
+		args = append(args, "-tr", mod.TimestampURL, "-td", mod.Digest)
+	}
+
+	return &authenticodeSigner{
+		tool:    "azuresigntool",
+		args:    args,
+		env:     []string{"AZURE_CLIENT_ID=" + kv.ClientID, "AZURE_CLIENT_SECRET=" + secret, "AZURE_TENANT_ID=" + kv.TenantID},
+		inPlace: true,
+	}, nil
+}
+
+func (mod *Authenticode) certificate(context *ctx.Context) (string, error) {
+	encoded, ok := context.LookupEnv(mod.CertificateEnv)
+	if !ok || encoded == "" {
+		if mod.CertificateFile == "" {
+			return "", errors.New("no authenticode certificate provided")
+		}
+
+		return context.Path(context.Env.Expand(mod.CertificateFile)), nil
+	}
+
+	cert, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return "", fmt.Errorf("decoding %s: %w", mod.CertificateEnv, err)
+	}
+
+	return writeTempSecret(context, "authenticode", "certificate.pfx", string(cert))
+}
+
+func (signer *authenticodeSigner) sign(cx context.Context, context *ctx.Context, art *ctx.Artifact) error {
+	args := signer.args
+	output := art.Location
+
+	if signer.inPlace {
+		args = append(args, art.Location)
+	} else {
+		dir, err := context.TempDir("authenticode")
+		if err != nil {
+			return err
+		}
+
+		output = filepath.Join(dir, art.Filename)
+		args = append(args, "-in", art.Location, "-out", output)
+	}
+
+	cmd := exec.CommandContext(cx, ctx.LookPath(context.Env, signer.tool), args...)
+	cmd.Dir = context.Dir
+	cmd.Env = append(context.Env.Environ(), signer.env...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("signing %s with %s: %w", art.Location, signer.tool, err)
+	}
+
+	// the signature changes the file
+	art.ResetDigests()
+	art.SetMeta("signed", "true")
+
+	if output != art.Location {
+		if err := replaceFile(output, art.Location); err != nil {
+			return fmt.Errorf("replacing %s with signed file: %w", art.Location, err)
+		}
+	}
+
+	return art.Stat()
+}
+
+// writeTempSecret writes a secret into a file in a private directory
+// outside the target directory, which is removed at the end of the run
+// (see ctx.Context.SecretDir)
+func writeTempSecret(context *ctx.Context, namespace, name, content string) (string, error) {
+	dir, err := context.SecretDir(namespace)
+	if err != nil {
+		return "", err
+	}
+
+	fn := filepath.Join(dir, name)
+
+	if err := os.WriteFile(fn, []byte(content), 0o600); err != nil {
+		return "", fmt.Errorf("writing %s: %w", fn, err)
+	}
+
+	return fn, nil
+}
+
+// replaceFile moves src over dst, keeping dst's permissions
+func replaceFile(src, dst string) error {
+	stat, err := os.Stat(dst)
+	if err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(dst, content, stat.Mode().Perm())
+}
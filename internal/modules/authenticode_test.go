@@ -0,0 +1,202 @@
+package modules
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+// fakeSigner copies the certificate, the password file, and the azure
+// client secret into $COPIES, and signs the file in place, or into -out
+const fakeSigner = `echo "$AZURE_CLIENT_SECRET" > "$COPIES/secret"
+in=; out=
+while [ $# -gt 0 ]; do
+	case "$1" in
+	-in) in=$2 ;;
+	-out) out=$2 ;;
+	-pkcs12|/f) cp "$2" "$COPIES/cert" ;;
+	-readpass) cp "$2" "$COPIES/password" ;;
+	esac
+	last=$1
+	shift
+done
+if [ -n "$out" ]; then { cat "$in"; echo signed; } > "$out"; else echo signed >> "$last"; fi`
+
+// nolint: funlen
+func TestAuthenticode_Run(t *testing.T) {
+	windows := &ctx.OsArch{OS: "windows", Arch: "amd64"}
+	linux := &ctx.OsArch{OS: "linux", Arch: "amd64"}
+	certificate := base64.StdEncoding.EncodeToString([]byte("pfx"))
+
+	tests := []struct {
+		name     string
+		mod      *Authenticode
+		env      map[string]string
+		wantTool string
+		wantArgs string
+		// wantCopies are files the fake signer copied into $COPIES
+		wantCopies map[string]string
+		wantErr    string
+	}{
+		{
+			name:     "osslsigncode with password",
+			mod:      &Authenticode{Tool: "osslsigncode"},
+			env:      map[string]string{"AUTHENTICODE_CERTIFICATE": certificate, "AUTHENTICODE_PASSWORD": "pfx-password"},
+			wantTool: "osslsigncode",
+			wantArgs: "sign -pkcs12 SECRET -h sha256 -n app -readpass SECRET -ts http://ts.example -in TARGET/app.exe -out TEMP",
+			wantCopies: map[string]string{
+				"cert":     "pfx",
+				"password": "pfx-password",
+			},
+		},
+		{
+			name:       "signtool with certificate file",
+			mod:        &Authenticode{Tool: "signtool", CertificateFile: "CERTDIR/cert.pfx", URL: "https://example.com"},
+			wantTool:   "signtool",
+			wantArgs:   "sign /f CERTDIR/cert.pfx /fd sha256 /d app /du https://example.com /tr http://ts.example /td sha256 TARGET/app.exe",
+			wantCopies: map[string]string{"cert": "pfx file"},
+		},
+		{
+			name: "azure key vault",
+			mod: &Authenticode{AzureKeyVault: &AuthenticodeAzure{
+				Certificate: "codesign",
+				ClientID:    "client",
+				TenantID:    "tenant",
+				URL:         "https://vault.example",
+			}},
+			env:        map[string]string{"AZURE_CLIENT_SECRET": "azure-secret"},
+			wantTool:   "azuresigntool",
+			wantArgs:   "sign -kvu https://vault.example -kvc codesign -kvm -fd sha256 -d app -tr http://ts.example -td sha256 TARGET/app.exe",
+			wantCopies: map[string]string{"secret": "azure-secret\n"},
+		},
+		{
+			name:    "signtool with password",
+			mod:     &Authenticode{Tool: "signtool"},
+			env:     map[string]string{"AUTHENTICODE_CERTIFICATE": certificate, "AUTHENTICODE_PASSWORD": "pfx-password"},
+			wantErr: "use osslsigncode",
+		},
+		{
+			name:    "azure without secret",
+			mod:     &Authenticode{AzureKeyVault: &AuthenticodeAzure{Certificate: "codesign", URL: "https://vault.example"}},
+			wantErr: "azure client secret not found",
+		},
+		{name: "no certificate", mod: &Authenticode{Tool: "osslsigncode"}, wantErr: "no authenticode certificate"},
+		{name: "unknown tool", mod: &Authenticode{Tool: "jsign", CertificateFile: "cert.pfx"}, wantErr: "unknown authenticode tool"},
+		{
+			name:     "failing tool",
+			mod:      &Authenticode{Tool: "signtool", CertificateFile: "cert.pfx"},
+			wantTool: "signtool",
+			wantErr:  "signing",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			calls := ""
+			if tt.wantTool != "" {
+				script := fakeSigner
+				if tt.wantErr != "" {
+					script = "exit 1"
+				}
+
+				calls = fakeCommand(t, tt.wantTool, script)
+			}
+
+			cx, shipContext := testShipContext(t)
+			exe := addTestArtifact(t, shipContext, "default", "app.exe", windows)
+			elf := addTestArtifact(t, shipContext, "default", "app", linux)
+
+			copies := t.TempDir()
+			shipContext.Env.Set("COPIES", copies)
+
+			for key, value := range tt.env {
+				shipContext.Env.Set(key, value)
+			}
+
+			certDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(certDir, "cert.pfx"), []byte("pfx file"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			mod := NewAuthenticode().(*Authenticode)
+			mod.AzureKeyVault = tt.mod.AzureKeyVault
+			mod.CertificateFile = strings.ReplaceAll(tt.mod.CertificateFile, "CERTDIR", certDir)
+			mod.TimestampURL = "http://ts.example"
+			mod.Tool = tt.mod.Tool
+			mod.URL = tt.mod.URL
+
+			err := mod.Run(cx)
+			shipContext.Finish()
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Run() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			got := fakeCalls(t, calls)
+			if len(got) != 1 {
+				t.Fatalf("%s called %d times, want once", tt.wantTool, len(got))
+			}
+
+			pattern := strings.NewReplacer(
+				"TARGET", regexp.QuoteMeta(shipContext.TargetDir),
+				"CERTDIR", regexp.QuoteMeta(certDir),
+				"SECRET", `(\S+)`,
+				"TEMP", `\S+`,
+			).Replace(regexp.QuoteMeta(tt.wantArgs))
+
+			match := regexp.MustCompile("^" + pattern + "$").FindStringSubmatch(got[0])
+			if match == nil {
+				t.Fatalf("%s args = %q, want %q", tt.wantTool, got[0], tt.wantArgs)
+			}
+
+			for _, secret := range match[1:] {
+				if rel, err := filepath.Rel(shipContext.TargetDir, secret); err == nil && !strings.HasPrefix(rel, "..") {
+					t.Errorf("secret written into target directory: %s", secret)
+				}
+
+				if _, err := os.Stat(secret); err == nil {
+					t.Errorf("secret %s kept after the run", secret)
+				}
+			}
+
+			for _, value := range tt.env {
+				if strings.Contains(got[0], value) {
+					t.Errorf("%s args contain a secret: %q", tt.wantTool, got[0])
+				}
+			}
+
+			for fn, want := range tt.wantCopies {
+				if content, _ := os.ReadFile(filepath.Join(copies, fn)); string(content) != want {
+					t.Errorf("%s got %s %q, want %q", tt.wantTool, fn, content, want)
+				}
+			}
+
+			if content, _ := os.ReadFile(exe.Location); string(content) != "app.exesigned\n" {
+				t.Errorf("signed file = %q", content)
+			}
+
+			if content, _ := os.ReadFile(elf.Location); string(content) != "app" {
+				t.Errorf("linux artifact was signed: %q", content)
+			}
+
+			if !exe.HasMeta("signed", "true") || exe.Size != int64(len("app.exesigned\n")) {
+				t.Errorf("signed artifact not updated: %+v", exe)
+			}
+		})
+	}
+}
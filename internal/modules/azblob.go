@@ -0,0 +1,91 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/internal/blob"
+	"github.com/julian7/goshipdone/modules"
+)
+
+// AzBlob is a publish module uploading artifacts to Azure Blob Storage,
+// authenticating with the storage account's shared key, or a SAS token.
+type AzBlob struct {
+	BlobPublisher `yaml:",inline"`
+	// AccessTier sets uploaded blobs' access tier, like "Cool".
+	// Default: empty (account default).
+	AccessTier string `yaml:"access_tier"`
+	// Account is the storage account's name. Required.
+	Account string
+	// BlockSize sets the block size in bytes. Larger files are uploaded
+	// in multiple blocks. Default: 64 MiB.
+	BlockSize int64 `yaml:"block_size"`
+	// Container is the target container's name. Required.
+	Container string
+	// Endpoint is the blob service's URL. Scheme is https if not
+	// specified. Default: <account>.blob.core.windows.net.
+	Endpoint string
+	// KeyEnv specifies the environment variable of the account's shared
+	// key. Default: AZURE_STORAGE_KEY.
+	KeyEnv string `yaml:"key_env"`
+	// SASTokenEnv specifies the environment variable of a SAS token,
+	// which is used instead of the shared key if set.
+	// Default: AZURE_STORAGE_SAS_TOKEN.
+	SASTokenEnv string `yaml:"sas_token_env"`
+}
+
+// NewAzBlob is a factory function for AzBlob module
+func NewAzBlob() modules.Pluggable {
+	return &AzBlob{
+		BlobPublisher: newBlobPublisher(),
+		BlockSize:     blob.DefaultBlockSize,
+		KeyEnv:        "AZURE_STORAGE_KEY",
+		SASTokenEnv:   "AZURE_STORAGE_SAS_TOKEN",
+	}
+}
+
+// Run uploads selected artifacts into an Azure Blob Storage container
+func (mod *AzBlob) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	client, err := mod.client(context)
+	if err != nil {
+		return err
+	}
+
+	return mod.publish(cx, client, blob.UploadOptions{StorageClass: mod.AccessTier})
+}
+
+func (mod *AzBlob) client(context *ctx.Context) (*blob.Azure, error) {
+	if mod.Account == "" || mod.Container == "" {
+		return nil, fmt.Errorf("account and container are required")
+	}
+
+	client := &blob.Azure{
+		Account:   mod.Account,
+		BlockSize: mod.BlockSize,
+		Container: mod.Container,
+		Key:       context.Getenv(mod.KeyEnv, ""),
+		Progress:  context.Progress.Reader,
+		SASToken:  context.Getenv(mod.SASTokenEnv, ""),
+	}
+
+	if client.Key == "" && client.SASToken == "" {
+		return nil, fmt.Errorf("credentials not found: set $%s, or $%s", mod.KeyEnv, mod.SASTokenEnv)
+	}
+
+	if mod.Endpoint != "" {
+		endpointURL, err := parseEndpoint(mod.Endpoint)
+		if err != nil {
+			return nil, err
+		}
+
+		client.Endpoint = endpointURL
+	}
+
+	return client, nil
+}
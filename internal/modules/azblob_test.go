@@ -0,0 +1,128 @@
+package modules
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/julian7/goshipdone/ctx"
+	"gopkg.in/yaml.v3"
+)
+
+// nolint: funlen
+func TestAzBlob_Run(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString([]byte("shared key"))
+
+	tests := []struct {
+		name string
+		// config is the module's configuration, ENDPOINT standing for
+		// the test storage's URL, and FORBIDDEN for a storage rejecting
+		// uploads
+		config     string
+		env        map[string]string
+		wantObject string
+		wantAuth   string
+		// wantHeaders are request headers of the upload
+		wantHeaders map[string]string
+		wantErr     string
+	}{
+		{
+			name:       "shared key",
+			config:     "account: acme\ncontainer: releases\nendpoint: ENDPOINT\naccess_tier: Cool\n",
+			env:        map[string]string{"AZURE_STORAGE_KEY": key},
+			wantObject: "releases/app/v1.0.0/app-linux-amd64.tar.gz",
+			wantAuth:   "SharedKey acme:",
+			wantHeaders: map[string]string{
+				"X-Ms-Access-Tier": "Cool",
+				"X-Ms-Blob-Type":   "BlockBlob",
+			},
+		},
+		{
+			name:        "sas token",
+			config:      "account: acme\ncontainer: releases\nendpoint: ENDPOINT\nprefix: \"{{.ProjectName}}/\"\nsas_token_env: SAS\n",
+			env:         map[string]string{"SAS": "?sv=2020-10-02&sig=signature"},
+			wantObject:  "releases/app/app-linux-amd64.tar.gz",
+			wantHeaders: map[string]string{"X-Ms-Access-Tier": "", "X-Ms-Blob-Type": "BlockBlob"},
+		},
+		{
+			name:    "no container",
+			config:  "account: acme\nendpoint: ENDPOINT\n",
+			env:     map[string]string{"AZURE_STORAGE_KEY": key},
+			wantErr: "account and container are required",
+		},
+		{
+			name:    "no credentials",
+			config:  "account: acme\ncontainer: releases\nendpoint: ENDPOINT\n",
+			wantErr: "credentials not found: set $AZURE_STORAGE_KEY, or $AZURE_STORAGE_SAS_TOKEN",
+		},
+		{
+			name:    "invalid shared key",
+			config:  "account: acme\ncontainer: releases\nendpoint: ENDPOINT\n",
+			env:     map[string]string{"AZURE_STORAGE_KEY": "not base64!"},
+			wantErr: "decoding shared key",
+		},
+		{
+			name:    "rejected upload",
+			config:  "account: acme\ncontainer: releases\nendpoint: FORBIDDEN\n",
+			env:     map[string]string{"AZURE_STORAGE_KEY": key},
+			wantErr: "uploading app-linux-amd64.tar.gz to azblob:acme/releases",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			storage, objects := testStorage(t, http.StatusCreated)
+			forbidden, _ := testStorage(t, http.StatusForbidden)
+
+			cx, shipContext := testShipContext(t)
+			addTestArtifact(t, shipContext, "archive", "app-linux-amd64.tar.gz", &ctx.OsArch{OS: "linux", Arch: "amd64"})
+
+			for key, value := range tt.env {
+				shipContext.Env.Set(key, value)
+			}
+
+			mod := NewAzBlob()
+			config := strings.NewReplacer("ENDPOINT", storage.URL, "FORBIDDEN", forbidden.URL).Replace(tt.config)
+
+			if err := yaml.Unmarshal([]byte(config), mod); err != nil {
+				t.Fatal(err)
+			}
+
+			err := mod.Run(cx)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Run() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			header, ok := objects()[tt.wantObject]
+			if !ok {
+				t.Fatalf("object %s not uploaded", tt.wantObject)
+			}
+
+			if auth := header.Get("Authorization"); !strings.HasPrefix(auth, tt.wantAuth) || (tt.wantAuth == "" && auth != "") {
+				t.Errorf("Authorization = %q, want %q", auth, tt.wantAuth)
+			}
+
+			for name, want := range tt.wantHeaders {
+				if got := header.Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+
+			if got, _ := shipContext.Published.DownloadURL("app-linux-amd64.tar.gz"); got != storage.URL+"/"+tt.wantObject {
+				t.Errorf("download URL = %q, want %q", got, storage.URL+"/"+tt.wantObject)
+			}
+		})
+	}
+}
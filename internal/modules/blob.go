@@ -0,0 +1,107 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/internal/blob"
+	"github.com/julian7/goshipdone/modules"
+)
+
+// BlobPublisher contains common settings of object storage publishers,
+// like S3, GCS, and AzBlob.
+type BlobPublisher struct {
+	// Artifacts is a template expression selecting artifacts of Builds
+	// (or all artifacts, if Builds is empty), evaluated for each artifact.
+	// See modules.SelectArtifacts.
+	Artifacts string
+	// Builds specifies which build names should be uploaded.
+	// Default: ["archive"].
+	Builds []string
+	// Parallelism is the maximum number of concurrent uploads. Default: 4.
+	Parallelism int
+	// Prefix is prepended to file names to make object keys, using
+	// modules.TemplateData. Default: "{{.ProjectName}}/{{.Version}}/".
+	Prefix string
+	// Skip specifies GOOS-GOArch combinations to be skipped.
+	// They are in `{{.Os}}-{{.Arch}}` format.
+	Skip []string
+}
+
+func newBlobPublisher() BlobPublisher {
+	return BlobPublisher{
+		Builds:      []string{"archive"},
+		Parallelism: defaultParallelism,
+		Prefix:      "{{.ProjectName}}/{{.Version}}/",
+		Skip:        []string{},
+	}
+}
+
+// publish uploads selected artifacts into a bucket
+func (pub *BlobPublisher) publish(cx context.Context, bucket blob.Bucket, opts blob.UploadOptions) error {
+	shipContext, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	td, err := modules.NewTemplate(cx)
+	if err != nil {
+		return err
+	}
+
+	prefix, err := td.Parse("blob-prefix", pub.Prefix)
+	if err != nil {
+		return fmt.Errorf("parsing prefix: %w", err)
+	}
+
+	builds, err := modules.SelectArtifacts(cx, pub.Builds, pub.Skip, pub.Artifacts)
+	if err != nil {
+		return err
+	}
+
+	return uploadAll(cx, builds, pub.Parallelism, func(cx context.Context, artifact *ctx.Artifact) error {
+		key := path.Join(prefix, artifact.Filename)
+
+		fileOpts := opts
+		fileOpts.ContentType = contentType(artifact.Filename)
+
+		if err := bucket.UploadFile(cx, key, artifact.Location, &fileOpts); err != nil {
+			return fmt.Errorf("uploading %s to %s: %w", artifact.Filename, bucket, err)
+		}
+
+		shipContext.Transfers.Upload(bucket.String(), artifact.Size)
+
+		shipContext.Published.AddDownload(artifact, bucket.URL(key))
+
+		return nil
+	})
+}
+
+// contentType guesses a file's media type from its extension
+func contentType(filename string) string {
+	if ctype := mime.TypeByExtension(filepath.Ext(filename)); ctype != "" {
+		return ctype
+	}
+
+	return "application/octet-stream"
+}
+
+// parseEndpoint parses a storage service's URL, defaulting to https scheme
+func parseEndpoint(endpoint string) (*url.URL, error) {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parsing endpoint: %w", err)
+	}
+
+	return endpointURL, nil
+}
@@ -0,0 +1,141 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+// nolint: gochecknoglobals
+var reCalVerToken = regexp.MustCompile(`YYYY|YY|0Y|MM|0M|WW|0W|DD|0D|MICRO`)
+
+// CalVer is a setup module calculating the version from the build date
+// (https://calver.org/), for projects not following semantic versioning
+type CalVer struct {
+	VersionTag `yaml:",inline"`
+	// Format is the version's format. Tokens: YYYY (2021), YY (21), 0Y
+	// (21, or 06), MM (1), 0M (01), WW (ISO week, 1), 0W (01), DD (2), 0D
+	// (02), and MICRO (0, 1, ..., incremented for each release with the
+	// same date parts). Default: "YYYY.0M.MICRO".
+	Format string
+	// Prefix is the prefix of tags. Default: empty.
+	Prefix string
+}
+
+// NewCalVer is a factory function for CalVer module
+func NewCalVer() modules.Pluggable {
+	return &CalVer{
+		VersionTag: newVersionTag(),
+		Format:     "YYYY.0M.MICRO",
+	}
+}
+
+// Run calculates the version, and records it into ctx.Context as Version,
+// and as the current tag
+func (mod *CalVer) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	if mod.Format == "" {
+		return errors.New("format is not specified")
+	}
+
+	if err := mod.validate(); err != nil {
+		return err
+	}
+
+	if context.Git.Tag != "" {
+		log.Printf("      current commit is already tagged as %s", context.Git.Tag)
+		return nil
+	}
+
+	version := formatCalVer(mod.Format, context.StartedAt.UTC(), 0)
+
+	if strings.Contains(mod.Format, "MICRO") {
+		out, err := gitOutput(cx, context, "tag", "--list")
+		if err != nil {
+			return fmt.Errorf("listing tags: %w", err)
+		}
+
+		version = formatCalVer(
+			mod.Format,
+			context.StartedAt.UTC(),
+			nextCalVerMicro(context.Git.TagPrefix+mod.Prefix, mod.Format, context.StartedAt.UTC(), strings.Fields(out)),
+		)
+	}
+
+	context.Version = mod.Prefix + version
+	context.Git.Tag = context.Git.TagPrefix + context.Version
+	context.Git.Semver = ctx.Semver{}
+
+	log.Printf("      version is %s", context.Version)
+
+	return mod.create(cx, context, context.Git.Tag)
+}
+
+// formatCalVer renders a calendar version of date
+func formatCalVer(format string, date time.Time, micro int) string {
+	_, week := date.ISOWeek()
+
+	return reCalVerToken.ReplaceAllStringFunc(format, func(token string) string {
+		switch token {
+		case "YYYY":
+			return strconv.Itoa(date.Year())
+		case "YY":
+			return strconv.Itoa(date.Year() % 100)
+		case "0Y":
+			return fmt.Sprintf("%02d", date.Year()%100)
+		case "MM":
+			return strconv.Itoa(int(date.Month()))
+		case "0M":
+			return fmt.Sprintf("%02d", date.Month())
+		case "WW":
+			return strconv.Itoa(week)
+		case "0W":
+			return fmt.Sprintf("%02d", week)
+		case "DD":
+			return strconv.Itoa(date.Day())
+		case "0D":
+			return fmt.Sprintf("%02d", date.Day())
+		default:
+			return strconv.Itoa(micro)
+		}
+	})
+}
+
+// nextCalVerMicro returns the next MICRO value, after the ones in tags
+// having the same date parts
+func nextCalVerMicro(prefix, format string, date time.Time, tags []string) int {
+	parts := strings.Split(regexp.QuoteMeta(prefix+format), "MICRO")
+	for i := range parts {
+		parts[i] = formatCalVer(parts[i], date, 0)
+	}
+
+	re := regexp.MustCompile("^" + strings.Join(parts, `(0|[1-9]\d*)`) + "$")
+	next := 0
+
+	for _, tag := range tags {
+		match := re.FindStringSubmatch(tag)
+		if match == nil {
+			continue
+		}
+
+		// all MICRO occurrences are the same
+		micro, err := strconv.Atoi(match[1])
+		if err == nil && micro >= next {
+			next = micro + 1
+		}
+	}
+
+	return next
+}
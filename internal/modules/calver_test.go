@@ -0,0 +1,44 @@
+package modules
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatCalVer(t *testing.T) {
+	date := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{format: "YYYY.0M.MICRO", want: "2006.01.3"},
+		{format: "YY.MM.DD", want: "6.1.2"},
+		{format: "0Y.0W", want: "06.01"},
+		{format: "YYYY0M0D", want: "20060102"},
+		{format: "v0Y.WW.MICRO", want: "v06.1.3"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.format, func(t *testing.T) {
+			if got := formatCalVer(tt.format, date, 3); got != tt.want {
+				t.Errorf("formatCalVer() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextCalVerMicro(t *testing.T) {
+	date := time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC)
+	tags := []string{"v2021.03.0", "v2021.03.2", "v2021.02.7", "2021.03.9", "v2021.03.x", "v2021.03.1"}
+
+	if got := nextCalVerMicro("v", "YYYY.0M.MICRO", date, tags); got != 3 {
+		t.Errorf("nextCalVerMicro() = %d, want 3", got)
+	}
+
+	if got := nextCalVerMicro("v", "YYYY.0M.MICRO", date, nil); got != 0 {
+		t.Errorf("nextCalVerMicro() without tags = %d, want 0", got)
+	}
+}
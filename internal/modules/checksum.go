@@ -3,11 +3,10 @@ package modules
 import (
 	"context"
 	"fmt"
-	"hash"
-	"io"
 	"log"
 	"os"
 	"path"
+	"sort"
 
 	"github.com/julian7/goshipdone/ctx"
 	"github.com/julian7/goshipdone/modules"
@@ -15,8 +14,9 @@ import (
 
 // Checksum calculates checksums of artifacts, and stores them in a checksum file
 type Checksum struct {
-	// Algorithm specifies checksum algorithm
-	Algorithm HashAlgorithm
+	// Algorithm specifies checksum algorithm. See
+	// modules.RegisterHashAlgorithm for registering new ones.
+	Algorithm modules.HashAlgorithm
 	// Builds specifies a build names to find related artifacts to
 	// calculate checksums of.
 	Builds []string
@@ -31,7 +31,7 @@ type Checksum struct {
 }
 
 func NewChecksum() modules.Pluggable {
-	algo, _ := NewHashAlgorithm("sha256")
+	algo, _ := modules.NewHashAlgorithm("sha256")
 
 	return &Checksum{
 		Algorithm: *algo,
@@ -54,24 +54,24 @@ func (checksum *Checksum) Run(cx context.Context) error {
 
 	checksumFilename := path.Join(context.TargetDir, output)
 
-	artifactMap := context.Artifacts.OsArchByIDs(checksum.Builds, checksum.Skip)
-	if len(artifactMap) == 0 {
+	artifacts := context.Artifacts.Select().Name(checksum.Builds...).Skip(checksum.Skip...).List()
+	if len(artifacts) == 0 {
 		return nil
 	}
 
-	checksums := []string{}
-
-	hasher := checksum.Algorithm.Factory()
+	sort.Slice(artifacts, func(i, j int) bool {
+		return artifacts[i].Filename < artifacts[j].Filename
+	})
 
-	for osarch := range artifactMap {
-		for _, artifact := range *artifactMap[osarch] {
-			sum, err := checksumArtifact(hasher, artifact)
-			if err != nil {
-				return err
-			}
+	checksums := make([]string, 0, len(artifacts))
 
-			checksums = append(checksums, sum)
+	for _, artifact := range artifacts {
+		sum, err := checksum.Algorithm.SumArtifact(artifact)
+		if err != nil {
+			return err
 		}
+
+		checksums = append(checksums, fmt.Sprintf("%s  %s", sum, artifact.Filename))
 	}
 
 	writer, err := os.Create(checksumFilename)
@@ -88,9 +88,11 @@ func (checksum *Checksum) Run(cx context.Context) error {
 	}
 
 	context.Artifacts.Add(&ctx.Artifact{
-		Filename: output,
-		Location: checksumFilename,
-		ID:       checksum.ID,
+		FileFormat: ctx.FormatChecksum,
+		Filename:   output,
+		Location:   checksumFilename,
+		ID:         checksum.ID,
+		Parents:    artifacts.Filenames(),
 	})
 
 	log.Printf("checksum file %s written", checksumFilename)
@@ -98,29 +100,6 @@ func (checksum *Checksum) Run(cx context.Context) error {
 	return nil
 }
 
-func checksumArtifact(hasher hash.Hash, artifact *ctx.Artifact) (string, error) {
-	hasher.Reset()
-
-	f, err := os.Open(artifact.Location)
-	if err != nil {
-		return "", fmt.Errorf("checksumming %s: %w", artifact.Location, err)
-	}
-
-	if _, err := io.Copy(hasher, f); err != nil {
-		return "", fmt.Errorf("reading %s for checksumming: %w", artifact.Location, err)
-	}
-
-	if err := f.Close(); err != nil {
-		return "", fmt.Errorf("closing %s after checksumming: %w", artifact.Location, err)
-	}
-
-	return fmt.Sprintf(
-		"%x  %s",
-		hasher.Sum(nil),
-		artifact.Filename,
-	), nil
-}
-
 func (checksum *Checksum) parseOutput(cx context.Context) (string, error) {
 	td, err := modules.NewTemplate(cx)
 	if err != nil {
@@ -129,7 +108,7 @@ func (checksum *Checksum) parseOutput(cx context.Context) (string, error) {
 
 	td.Algo = checksum.Algorithm.String()
 
-	output, err := td.Parse("checksum", checksum.Output)
+	output, err := td.Parse("checksum-output", checksum.Output)
 	if err != nil {
 		return "", fmt.Errorf("rendering %q: %w", checksum.Output, err)
 	}
@@ -0,0 +1,126 @@
+package modules
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+	"github.com/julian7/withenv"
+)
+
+// CI is a setup module detecting CI environments (GitHub Actions, GitLab
+// CI, CircleCI, and Jenkins), and recording the tag, branch, commit, and
+// run URL from their environment variables into ctx.Context, therefore
+// pipelines work without local git access (like shallow clones without
+// tags). It has to be configured before setup:git, which fills in only
+// what is not detected.
+type CI struct{}
+
+// NewCI is a factory function for CI module
+func NewCI() modules.Pluggable {
+	return &CI{}
+}
+
+// Run records CI environment information into ctx.Context
+func (*CI) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	ci, url := detectCI(context.Env)
+	if ci == nil {
+		log.Printf("      no CI environment detected")
+		return nil
+	}
+
+	*context.CI = *ci
+
+	log.Printf("      detected %s: %s", ci.Name, ci.RunURL)
+
+	if ci.Tag != "" {
+		context.Git.Tag = ci.Tag
+		context.Version = strings.TrimPrefix(ci.Tag, context.Git.TagPrefix)
+	}
+
+	if ci.Commit != "" {
+		context.Git.Ref = ci.Commit
+	}
+
+	if ci.Branch != "" {
+		context.Git.Branch = ci.Branch
+	}
+
+	if url != "" {
+		context.Git.URL = url
+	}
+
+	return nil
+}
+
+// detectCI returns CI information, and the repository URL from
+// environment variables, or nil if it's not a known CI environment
+func detectCI(env *withenv.Env) (*ctx.CIData, string) {
+	get := func(name string) string { return env.GetOrDefault(name, "") }
+
+	switch {
+	case get("GITHUB_ACTIONS") == "true":
+		ci := &ctx.CIData{Commit: get("GITHUB_SHA"), Name: "github"}
+		url := ""
+
+		if repository := get("GITHUB_REPOSITORY"); repository != "" {
+			url = get("GITHUB_SERVER_URL") + "/" + repository + ".git"
+			ci.RunURL = get("GITHUB_SERVER_URL") + "/" + repository + "/actions/runs/" + get("GITHUB_RUN_ID")
+		}
+
+		switch {
+		case get("GITHUB_REF_TYPE") == "tag", strings.HasPrefix(get("GITHUB_REF"), "refs/tags/"):
+			ci.Tag = strings.TrimPrefix(get("GITHUB_REF"), "refs/tags/")
+		case get("GITHUB_HEAD_REF") != "":
+			// pull requests
+			ci.Branch = get("GITHUB_HEAD_REF")
+		default:
+			ci.Branch = strings.TrimPrefix(get("GITHUB_REF"), "refs/heads/")
+		}
+
+		return ci, url
+	case get("GITLAB_CI") == "true":
+		branch := get("CI_COMMIT_BRANCH")
+		if branch == "" {
+			branch = get("CI_MERGE_REQUEST_SOURCE_BRANCH_NAME")
+		}
+
+		return &ctx.CIData{
+			Branch: branch,
+			Commit: get("CI_COMMIT_SHA"),
+			Name:   "gitlab",
+			RunURL: get("CI_PIPELINE_URL"),
+			Tag:    get("CI_COMMIT_TAG"),
+		}, get("CI_REPOSITORY_URL")
+	case get("CIRCLECI") == "true":
+		return &ctx.CIData{
+			Branch: get("CIRCLE_BRANCH"),
+			Commit: get("CIRCLE_SHA1"),
+			Name:   "circleci",
+			RunURL: get("CIRCLE_BUILD_URL"),
+			Tag:    get("CIRCLE_TAG"),
+		}, get("CIRCLE_REPOSITORY_URL")
+	case get("JENKINS_URL") != "":
+		branch := get("BRANCH_NAME")
+		if branch == "" {
+			branch = strings.TrimPrefix(get("GIT_BRANCH"), "origin/")
+		}
+
+		return &ctx.CIData{
+			Branch: branch,
+			Commit: get("GIT_COMMIT"),
+			Name:   "jenkins",
+			RunURL: get("BUILD_URL"),
+			Tag:    get("TAG_NAME"),
+		}, get("GIT_URL")
+	}
+
+	return nil, ""
+}
@@ -0,0 +1,94 @@
+package modules
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/withenv"
+)
+
+func TestDetectCI(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    *ctx.CIData
+		wantURL string
+	}{
+		{name: "local", env: map[string]string{"HOME": "/home/user"}},
+		{
+			name: "github tag",
+			env: map[string]string{
+				"GITHUB_ACTIONS":    "true",
+				"GITHUB_REF":        "refs/tags/v1.2.3",
+				"GITHUB_REF_TYPE":   "tag",
+				"GITHUB_REPOSITORY": "owner/repo",
+				"GITHUB_RUN_ID":     "42",
+				"GITHUB_SERVER_URL": "https://github.com",
+				"GITHUB_SHA":        "abcdef0123",
+			},
+			want: &ctx.CIData{
+				Commit: "abcdef0123",
+				Name:   "github",
+				RunURL: "https://github.com/owner/repo/actions/runs/42",
+				Tag:    "v1.2.3",
+			},
+			wantURL: "https://github.com/owner/repo.git",
+		},
+		{
+			name: "github pull request",
+			env: map[string]string{
+				"GITHUB_ACTIONS":  "true",
+				"GITHUB_HEAD_REF": "feature",
+				"GITHUB_REF":      "refs/pull/3/merge",
+			},
+			want: &ctx.CIData{Branch: "feature", Name: "github"},
+		},
+		{
+			name: "gitlab branch",
+			env: map[string]string{
+				"GITLAB_CI":         "true",
+				"CI_COMMIT_BRANCH":  "main",
+				"CI_COMMIT_SHA":     "abc",
+				"CI_PIPELINE_URL":   "https://gitlab.com/g/p/-/pipelines/1",
+				"CI_REPOSITORY_URL": "https://gitlab.com/g/p.git",
+			},
+			want: &ctx.CIData{
+				Branch: "main",
+				Commit: "abc",
+				Name:   "gitlab",
+				RunURL: "https://gitlab.com/g/p/-/pipelines/1",
+			},
+			wantURL: "https://gitlab.com/g/p.git",
+		},
+		{
+			name: "circleci tag",
+			env:  map[string]string{"CIRCLECI": "true", "CIRCLE_TAG": "v2.0.0", "CIRCLE_SHA1": "abc"},
+			want: &ctx.CIData{Commit: "abc", Name: "circleci", Tag: "v2.0.0"},
+		},
+		{
+			name:    "jenkins",
+			env:     map[string]string{"JENKINS_URL": "https://ci", "GIT_BRANCH": "origin/dev", "GIT_URL": "git@host:repo.git"},
+			want:    &ctx.CIData{Branch: "dev", Name: "jenkins"},
+			wantURL: "git@host:repo.git",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			env := withenv.New()
+			env.LoadMap(tt.env)
+
+			got, url := detectCI(env)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("detectCI() = %+v, want %+v", got, tt.want)
+			}
+
+			if url != tt.wantURL {
+				t.Errorf("detectCI() url = %q, want %q", url, tt.wantURL)
+			}
+		})
+	}
+}
@@ -0,0 +1,292 @@
+package modules
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+const (
+	// commitSeparator separates commits in git log output
+	commitSeparator = "\x1e"
+	// commitFieldSeparator separates fields of commits in git log output
+	commitFieldSeparator = "\x1f"
+)
+
+// nolint: gochecknoglobals
+var (
+	reConventionalCommit = regexp.MustCompile(`^(\w+)(?:\(([^)]*)\))?(!)?: *(.+)$`)
+	reBreakingChange     = regexp.MustCompile(`(?m)^BREAKING[ -]CHANGE: *(.+)$`)
+)
+
+type (
+	// ConventionalChangelog is a build module generating release notes
+	// from conventional commits (https://www.conventionalcommits.org/)
+	// since the previous tag, grouped by commit type.
+	ConventionalChangelog struct {
+		// Breaking is the title of the breaking changes' group, listing
+		// commits marked with "!", or "BREAKING CHANGE:" footer,
+		// regardless of their types. Empty turns it off. Default:
+		// "Breaking Changes".
+		Breaking string
+		// From is the git revision the changelog starts from (exclusive).
+		// Default: the previous tag.
+		From string
+		// Groups specify titles, and commit types of groups, in order.
+		// Commits of other types are left out. Default: Features (feat),
+		// Bug Fixes (fix), and Performance Improvements (perf).
+		Groups []*ConventionalGroup
+		// ID is the artifact ID of the changelog. Default: "changelog".
+		ID string
+		// Output is the changelog's filename under the target directory.
+		// Default: "CHANGELOG.md".
+		Output string
+	}
+
+	// ConventionalGroup is a section of the conventional changelog
+	ConventionalGroup struct {
+		// Title is the section's title. Required.
+		Title string
+		// Types are commit types listed in the section. Required.
+		Types []string
+	}
+
+	// conventionalCommit is a parsed conventional commit
+	conventionalCommit struct {
+		Breaking    string
+		Description string
+		Hash        string
+		Scope       string
+		Type        string
+	}
+)
+
+// NewConventionalChangelog is a factory function for ConventionalChangelog
+// module
+func NewConventionalChangelog() modules.Pluggable {
+	return &ConventionalChangelog{
+		Breaking: "Breaking Changes",
+		Groups: []*ConventionalGroup{
+			{Title: "Features", Types: []string{"feat"}},
+			{Title: "Bug Fixes", Types: []string{"fix"}},
+			{Title: "Performance Improvements", Types: []string{"perf"}},
+		},
+		ID:     "changelog",
+		Output: "CHANGELOG.md",
+	}
+}
+
+// Run generates the changelog
+func (mod *ConventionalChangelog) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	from := mod.From
+	if from == "" {
+		from, err = previousTag(cx, context)
+		if err != nil {
+			return err
+		}
+	}
+
+	revisions := "HEAD"
+	if from != "" {
+		revisions = from + "..HEAD"
+	}
+
+	out, err := gitLog(cx, context, revisions)
+	if err != nil {
+		return err
+	}
+
+	title := context.Git.Tag
+	if title == "" {
+		title = context.Version
+	}
+
+	content := mod.render(title, context.StartedAt.Format("2006-01-02"), parseConventionalCommits(out))
+	outfile := filepath.Join(context.TargetDir, mod.Output)
+
+	if err := os.WriteFile(outfile, []byte(content), 0o644); err != nil { // nolint: gosec
+		return fmt.Errorf("writing changelog %s: %w", outfile, err)
+	}
+
+	context.Artifacts.Add(&ctx.Artifact{
+		ID:       mod.ID,
+		Filename: mod.Output,
+		Location: outfile,
+	})
+
+	return nil
+}
+
+// render renders commits into markdown
+func (mod *ConventionalChangelog) render(title, date string, commits []*conventionalCommit) string {
+	out := &strings.Builder{}
+
+	fmt.Fprintf(out, "## [%s] - %s\n", title, date)
+
+	if mod.Breaking != "" {
+		items := []string{}
+
+		for _, commit := range commits {
+			if commit.Breaking != "" {
+				items = append(items, commit.item(commit.Breaking))
+			}
+		}
+
+		writeChangelogSection(out, mod.Breaking, items)
+	}
+
+	for _, group := range mod.Groups {
+		items := []string{}
+
+		for _, commit := range commits {
+			for _, typ := range group.Types {
+				if commit.Type == typ {
+					items = append(items, commit.item(commit.Description))
+					break
+				}
+			}
+		}
+
+		writeChangelogSection(out, group.Title, items)
+	}
+
+	return out.String()
+}
+
+// writeChangelogSection writes a changelog section, if it has items
+func writeChangelogSection(out *strings.Builder, title string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+
+	fmt.Fprintf(out, "\n### %s\n\n", title)
+
+	for _, item := range items {
+		fmt.Fprintf(out, "- %s\n", item)
+	}
+}
+
+// item returns a changelog item of the commit
+func (commit *conventionalCommit) item(text string) string {
+	hash := commit.Hash
+	if len(hash) > 7 {
+		hash = hash[:7]
+	}
+
+	if commit.Scope != "" {
+		return fmt.Sprintf("**%s:** %s (%s)", commit.Scope, text, hash)
+	}
+
+	return fmt.Sprintf("%s (%s)", text, hash)
+}
+
+// parseConventionalCommits parses git log output, in oldest first order.
+// Commits not following conventional commits format are left out.
+func parseConventionalCommits(log string) []*conventionalCommit {
+	commits := []*conventionalCommit{}
+
+	for _, entry := range strings.Split(log, commitSeparator) {
+		fields := strings.SplitN(strings.TrimSpace(entry), commitFieldSeparator, 2)
+		if len(fields) != 2 {
+			continue
+		}
+
+		message := strings.TrimSpace(fields[1])
+		subject := strings.SplitN(message, "\n", 2)[0]
+
+		match := reConventionalCommit.FindStringSubmatch(subject)
+		if match == nil {
+			continue
+		}
+
+		commit := &conventionalCommit{
+			Description: strings.TrimSpace(match[4]),
+			Hash:        fields[0],
+			Scope:       match[2],
+			Type:        strings.ToLower(match[1]),
+		}
+
+		if breaking := reBreakingChange.FindStringSubmatch(message); breaking != nil {
+			commit.Breaking = strings.TrimSpace(breaking[1])
+		} else if match[3] != "" {
+			commit.Breaking = commit.Description
+		}
+
+		commits = append([]*conventionalCommit{commit}, commits...)
+	}
+
+	return commits
+}
+
+// previousTag returns the latest tag before the current release, or an
+// empty string if there is none. It prefers the tag found by
+// setup:previous_tag.
+func previousTag(cx context.Context, context *ctx.Context) (string, error) {
+	if context.Git.Range != "" {
+		return context.Git.PreviousTag, nil
+	}
+
+	args := []string{"describe", "--tags", "--abbrev=0"}
+	if context.Git.TagPrefix != "" {
+		args = append(args, "--match", context.Git.TagPrefix+"*")
+	}
+
+	if context.Git.Tag != "" {
+		args = append(args, "--exclude", context.Git.Tag)
+	}
+
+	tag, err := gitOutput(cx, context, append(args, "HEAD")...)
+	if err != nil {
+		// no previous tags
+		return "", nil
+	}
+
+	return strings.TrimSpace(tag), nil
+}
+
+// gitLog returns git log output of commits in revisions, limited to the
+// project's paths, for parseConventionalCommits
+func gitLog(cx context.Context, context *ctx.Context, revisions string) (string, error) {
+	args := append(
+		[]string{"log", "--format=%H" + commitFieldSeparator + "%B" + commitSeparator, revisions},
+		context.Git.Pathspec()...,
+	)
+
+	out, err := gitOutput(cx, context, args...)
+	if err != nil {
+		return "", fmt.Errorf("listing commits of %s: %w", revisions, err)
+	}
+
+	return out, nil
+}
+
+// gitOutput runs git, returning its output
+func gitOutput(cx context.Context, context *ctx.Context, args ...string) (string, error) {
+	out := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	cmd := exec.CommandContext(cx, "git", args...)
+	cmd.Dir = context.Dir
+	cmd.Env = context.Env.Environ()
+	cmd.Stdout = out
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	return out.String(), nil
+}
@@ -0,0 +1,45 @@
+package modules
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConventionalChangelog_render(t *testing.T) {
+	log := strings.Join([]string{
+		"1111111111\x1fchore: update dependencies\n",
+		"2222222222\x1ffeat(api)!: drop v1 endpoints\n",
+		"3333333333\x1fFix: handle empty input\n\nCloses #12\n",
+		"4444444444\x1fUpdate README\n",
+		"5555555555\x1ffeat: add --json flag\n\nBREAKING CHANGE: output format changed\n",
+		"6666666666\x1fperf(tar): buffer writes\n",
+		"",
+	}, "\x1e\n")
+
+	mod := NewConventionalChangelog().(*ConventionalChangelog)
+	got := mod.render("v2.0.0", "2021-01-02", parseConventionalCommits(log))
+
+	want := `## [v2.0.0] - 2021-01-02
+
+### Breaking Changes
+
+- output format changed (5555555)
+- **api:** drop v1 endpoints (2222222)
+
+### Features
+
+- add --json flag (5555555)
+- **api:** drop v1 endpoints (2222222)
+
+### Bug Fixes
+
+- handle empty input (3333333)
+
+### Performance Improvements
+
+- **tar:** buffer writes (6666666)
+`
+	if got != want {
+		t.Errorf("render():\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,213 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+type (
+	// Cosign is a module for signing artifacts, and container images with
+	// sigstore's `cosign` tool. It supports both key-based, and keyless
+	// (OIDC) signing.
+	Cosign struct {
+		// Artifacts is a template expression selecting artifacts of Builds
+		// (or all artifacts, if Builds is empty), evaluated for each artifact.
+		// See modules.SelectArtifacts.
+		Artifacts string
+		// Attestations are in-toto attestations to be attached to each
+		// image in Images.
+		Attestations []CosignAttestation
+		// Builds specifies which build names should be signed as blobs.
+		Builds []string
+		// CertificateID contains the artifact name of the signing
+		// certificates, written in keyless mode. Default: "certificate".
+		CertificateID string `yaml:"certificate_id"`
+		// CertificateOutput is the certificate file name template, in
+		// keyless mode. Default: `{{.Filename}}.pem`.
+		CertificateOutput string `yaml:"certificate_output"`
+		// ID contains the signature artifacts' name used by later stages of
+		// the pipeline. Default: "signature".
+		ID string
+		// Images are container image references (templates) to be signed.
+		// They have to be pushed to the registry before signing.
+		Images []string
+		// Key is a cosign key reference (file name, KMS URI, or `env://`
+		// reference). Keyless signing is used if empty. Key password is
+		// read from `COSIGN_PASSWORD` environment variable by cosign.
+		Key string
+		// Output is the signature file name template. Default:
+		// `{{.Filename}}.sig`.
+		Output string
+		// Skip specifies GOOS-GOArch combinations to be skipped.
+		Skip []string
+		// TlogUpload specifies whether signatures should be uploaded to
+		// the Rekor transparency log. Default: true.
+		TlogUpload bool `yaml:"tlog_upload"`
+	}
+
+	// CosignAttestation is an attestation attached to container images.
+	CosignAttestation struct {
+		// Predicate is a file name template of the predicate.
+		Predicate string
+		// Type is the predicate type (eg. "slsaprovenance", "spdxjson",
+		// or an URI). Default: "custom".
+		Type string
+	}
+)
+
+// NewCosign is a factory function for Cosign module
+func NewCosign() modules.Pluggable {
+	return &Cosign{
+		CertificateID:     "certificate",
+		CertificateOutput: "{{.Filename}}.pem",
+		ID:                "signature",
+		Output:            "{{.Filename}}.sig",
+		TlogUpload:        true,
+	}
+}
+
+// Run signs artifacts, and container images
+func (mod *Cosign) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	builds, err := modules.SelectArtifacts(cx, mod.Builds, mod.Skip, mod.Artifacts)
+	if err != nil {
+		return err
+	}
+
+	for _, build := range builds {
+		for _, art := range *build {
+			if err := mod.signBlob(cx, art); err != nil {
+				return err
+			}
+		}
+	}
+
+	td, err := modules.NewTemplate(cx)
+	if err != nil {
+		return err
+	}
+
+	for _, imageTemplate := range mod.Images {
+		image, err := td.Parse("cosign-images", imageTemplate)
+		if err != nil {
+			return fmt.Errorf("rendering image name %q: %w", imageTemplate, err)
+		}
+
+		if err := mod.signImage(cx, context, td, image); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (mod *Cosign) commonArgs(cmd string) []string {
+	args := []string{cmd, "--yes"}
+
+	if mod.Key != "" {
+		args = append(args, "--key", mod.Key)
+	}
+
+	if !mod.TlogUpload {
+		args = append(args, "--tlog-upload=false")
+	}
+
+	return args
+}
+
+func (mod *Cosign) signBlob(cx context.Context, art *ctx.Artifact) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	td, err := modules.NewTemplate(cx)
+	if err != nil {
+		return err
+	}
+
+	td.OSArch = art.OsArch
+	td.Filename = art.Filename
+
+	sigName, err := td.Parse("cosign-output", mod.Output)
+	if err != nil {
+		return fmt.Errorf("rendering %q: %w", mod.Output, err)
+	}
+
+	sigLocation := path.Join(context.TargetDir, sigName)
+	args := append(mod.commonArgs("sign-blob"), "--output-signature", sigLocation)
+
+	var certName, certLocation string
+
+	if mod.Key == "" {
+		certName, err = td.Parse("cosign-certificate-output", mod.CertificateOutput)
+		if err != nil {
+			return fmt.Errorf("rendering %q: %w", mod.CertificateOutput, err)
+		}
+
+		certLocation = path.Join(context.TargetDir, certName)
+		args = append(args, "--output-certificate", certLocation)
+	}
+
+	context.Progress.SetState(fmt.Sprintf("signing %s", art.Filename))
+
+	if err := ctx.RunCommand(cx, context.Env, "cosign", append(args, art.Location)...); err != nil {
+		return fmt.Errorf("signing %s: %w", art.Location, err)
+	}
+
+	context.Artifacts.Add(&ctx.Artifact{
+		Filename: sigName,
+		ID:       mod.ID,
+		Location: sigLocation,
+		OsArch:   art.OsArch,
+		Parents:  []string{art.Filename},
+	})
+
+	if certName != "" {
+		context.Artifacts.Add(&ctx.Artifact{
+			Filename: certName,
+			ID:       mod.CertificateID,
+			Location: certLocation,
+			OsArch:   art.OsArch,
+			Parents:  []string{art.Filename},
+		})
+	}
+
+	return nil
+}
+
+func (mod *Cosign) signImage(cx context.Context, context *ctx.Context, td *modules.TemplateData, image string) error {
+	context.Progress.SetState(fmt.Sprintf("signing %s", image))
+
+	if err := ctx.RunCommand(cx, context.Env, "cosign", append(mod.commonArgs("sign"), image)...); err != nil {
+		return fmt.Errorf("signing image %s: %w", image, err)
+	}
+
+	for _, attestation := range mod.Attestations {
+		predicate, err := td.Parse("cosign-attestations", attestation.Predicate)
+		if err != nil {
+			return fmt.Errorf("rendering predicate %q: %w", attestation.Predicate, err)
+		}
+
+		predicateType := attestation.Type
+		if predicateType == "" {
+			predicateType = "custom"
+		}
+
+		args := append(mod.commonArgs("attest"), "--predicate", predicate, "--type", predicateType, image)
+
+		if err := ctx.RunCommand(cx, context.Env, "cosign", args...); err != nil {
+			return fmt.Errorf("attesting image %s with %s: %w", image, predicate, err)
+		}
+	}
+
+	return nil
+}
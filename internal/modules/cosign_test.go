@@ -0,0 +1,145 @@
+package modules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/julian7/goshipdone/ctx"
+)
+
+// nolint: funlen
+func TestCosign_Run(t *testing.T) {
+	linux := &ctx.OsArch{OS: "linux", Arch: "amd64"}
+
+	tests := []struct {
+		name   string
+		mod    *Cosign
+		script string
+		// wantCalls are cosign invocations, TARGET standing for the
+		// target directory
+		wantCalls []string
+		// wantArtifacts are registered artifacts besides app.tar.gz, as
+		// ID:Filename
+		wantArtifacts []string
+		wantErr       string
+	}{
+		{
+			name:          "key-based blob",
+			mod:           &Cosign{Builds: []string{"archive"}, Key: "cosign.key", TlogUpload: true},
+			wantCalls:     []string{"sign-blob --yes --key cosign.key --output-signature TARGET/app.tar.gz.sig TARGET/app.tar.gz"},
+			wantArtifacts: []string{"signature:app.tar.gz.sig"},
+		},
+		{
+			name: "keyless blob without tlog",
+			mod:  &Cosign{Builds: []string{"archive"}},
+			wantCalls: []string{
+				"sign-blob --yes --tlog-upload=false --output-signature TARGET/app.tar.gz.sig " +
+					"--output-certificate TARGET/app.tar.gz.pem TARGET/app.tar.gz",
+			},
+			wantArtifacts: []string{"signature:app.tar.gz.sig", "certificate:app.tar.gz.pem"},
+		},
+		{
+			name: "image with attestations",
+			mod: &Cosign{
+				Attestations: []CosignAttestation{
+					{Predicate: "{{.ProjectName}}.spdx.json", Type: "spdxjson"},
+					{Predicate: "provenance.json"},
+				},
+				Images:     []string{"registry.example/app:{{.Version}}"},
+				Key:        "env://COSIGN_KEY",
+				TlogUpload: true,
+			},
+			wantCalls: []string{
+				"sign --yes --key env://COSIGN_KEY registry.example/app:v1.0.0",
+				"attest --yes --key env://COSIGN_KEY --predicate app.spdx.json --type spdxjson registry.example/app:v1.0.0",
+				"attest --yes --key env://COSIGN_KEY --predicate provenance.json --type custom registry.example/app:v1.0.0",
+			},
+		},
+		{
+			name:    "failing blob signing",
+			mod:     &Cosign{Builds: []string{"archive"}, Key: "cosign.key"},
+			script:  "exit 1",
+			wantErr: "signing",
+		},
+		{
+			name:    "failing image signing",
+			mod:     &Cosign{Images: []string{"registry.example/app"}, Key: "cosign.key"},
+			script:  "exit 1",
+			wantErr: "signing image registry.example/app",
+		},
+		{
+			name:    "failing attestation",
+			mod:     &Cosign{Attestations: []CosignAttestation{{Predicate: "sbom.json"}}, Images: []string{"registry.example/app"}},
+			script:  `[ "$1" != attest ]`,
+			wantErr: "attesting image registry.example/app with sbom.json",
+		},
+		{
+			name:    "bad output template",
+			mod:     &Cosign{Builds: []string{"archive"}, Key: "cosign.key", Output: "{{.Filename"},
+			wantErr: "rendering",
+		},
+		{
+			name:    "bad image template",
+			mod:     &Cosign{Images: []string{"{{.Version"}},
+			wantErr: "rendering image name",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			calls := fakeCommand(t, "cosign", tt.script)
+			cx, shipContext := testShipContext(t)
+			addTestArtifact(t, shipContext, "archive", "app.tar.gz", linux)
+
+			mod := NewCosign().(*Cosign)
+			mod.Attestations = tt.mod.Attestations
+			mod.Builds = tt.mod.Builds
+			mod.Images = tt.mod.Images
+			mod.Key = tt.mod.Key
+			mod.TlogUpload = tt.mod.TlogUpload
+
+			if tt.mod.Output != "" {
+				mod.Output = tt.mod.Output
+			}
+
+			err := mod.Run(cx)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Run() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			want := make([]string, 0, len(tt.wantCalls))
+			for _, call := range tt.wantCalls {
+				want = append(want, strings.ReplaceAll(call, "TARGET", shipContext.TargetDir))
+			}
+
+			if diff := deep.Equal(fakeCalls(t, calls), want); diff != nil {
+				t.Errorf("cosign calls %v", diff)
+			}
+
+			var got []string
+			for _, art := range shipContext.Artifacts[1:] {
+				got = append(got, art.ID+":"+art.Filename)
+
+				if len(art.Parents) != 1 || art.Parents[0] != "app.tar.gz" {
+					t.Errorf("%s parents = %v, want [app.tar.gz]", art.Filename, art.Parents)
+				}
+			}
+
+			if diff := deep.Equal(got, tt.wantArtifacts); diff != nil {
+				t.Errorf("Run() artifacts %v", diff)
+			}
+		})
+	}
+}
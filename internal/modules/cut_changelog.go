@@ -48,7 +48,7 @@ func (mod *CutChangelog) Run(cx context.Context) error {
 		return err
 	}
 
-	contents, err := ioutil.ReadFile(mod.Input)
+	contents, err := ioutil.ReadFile(context.Path(mod.Input))
 	if err != nil {
 		return fmt.Errorf("reading original CHANGELOG %s: %w", mod.Input, err)
 	}
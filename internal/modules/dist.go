@@ -0,0 +1,190 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+// Dist is a setup module preparing the target directory of the run. It
+// can move the target directory to a per-run location (like
+// "dist/{{.Version}}"), and it refuses to run with unexpected files in
+// it, which could be published accidentally, or removes them. Files of
+// goshipdone (lock, state, artifact manifest, report, run history, and
+// temp directories, see ctx.IsReservedFile) are always kept.
+// Resumed runs keep the target directory intact.
+type Dist struct {
+	// Allow lists glob patterns of file names allowed in the target
+	// directory. Default: empty.
+	Allow []string
+	// Clean removes unexpected files from the target directory, instead
+	// of failing. Default: false.
+	Clean bool
+	// LockWait is the maximum time to wait for another run using the new
+	// target directory. Default: 0.
+	LockWait time.Duration `yaml:"lock_wait"`
+	// Target is the target directory's template. Default: empty (the
+	// project's target directory, see setup:project).
+	Target string
+}
+
+// NewDist is the factory function for Dist
+func NewDist() modules.Pluggable {
+	return &Dist{}
+}
+
+// Run changes the target directory, and checks, or cleans its contents
+func (mod *Dist) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	if mod.Target != "" {
+		td, err := modules.NewTemplate(cx)
+		if err != nil {
+			return err
+		}
+
+		target, err := td.Parse("dist-target", mod.Target)
+		if err != nil {
+			return err
+		}
+
+		if target == "" {
+			return errors.New("target directory is empty")
+		}
+
+		target = context.Path(target)
+
+		if err := context.MoveTargetDir(target, mod.LockWait); err != nil {
+			return err
+		}
+
+		log.Printf("      target directory is %s", target)
+	}
+
+	if context.Resumed {
+		return nil
+	}
+
+	stale, err := mod.staleFiles(context.TargetDir)
+	if err != nil || len(stale) == 0 {
+		return err
+	}
+
+	if !mod.Clean {
+		return fmt.Errorf(
+			"unexpected files in target directory %s: %s (set `clean` to remove them)",
+			context.TargetDir,
+			strings.Join(stale, ", "),
+		)
+	}
+
+	if err := cleanable(context); err != nil {
+		return err
+	}
+
+	for _, name := range stale {
+		fn := filepath.Join(context.TargetDir, name)
+
+		log.Printf("      removing %s", fn)
+
+		if err := os.RemoveAll(fn); err != nil {
+			return fmt.Errorf("removing %s: %w", fn, err)
+		}
+	}
+
+	return nil
+}
+
+// cleanable returns an error if the target directory is unsafe to clean:
+// it is the current, or the project's directory, or any of their parents,
+// or it looks like a source tree, having go.mod, or .git
+func cleanable(context *ctx.Context) error {
+	dir, err := filepath.Abs(context.TargetDir)
+	if err != nil {
+		return fmt.Errorf("target directory: %w", err)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting working directory: %w", err)
+	}
+
+	for _, protected := range []string{cwd, context.Dir} {
+		if protected == "" {
+			continue
+		}
+
+		rel, err := filepath.Rel(dir, protected)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("refusing to clean target directory %s: it is, or contains %s", dir, protected)
+		}
+	}
+
+	for _, name := range []string{"go.mod", ".git"} {
+		if _, err := os.Lstat(filepath.Join(dir, name)); err == nil {
+			return fmt.Errorf("refusing to clean target directory %s: it has %s", dir, name)
+		}
+	}
+
+	return nil
+}
+
+// staleFiles returns names of files in a directory, which are neither
+// reserved, nor allowed
+func (mod *Dist) staleFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("reading target directory: %w", err)
+	}
+
+	stale := []string{}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if ctx.IsReservedFile(name) {
+			continue
+		}
+
+		allowed, err := mod.allowed(name)
+		if err != nil {
+			return nil, err
+		}
+
+		if !allowed {
+			stale = append(stale, name)
+		}
+	}
+
+	return stale, nil
+}
+
+// allowed returns true if a file name matches any of the Allow patterns
+func (mod *Dist) allowed(name string) (bool, error) {
+	for _, pattern := range mod.Allow {
+		ok, err := filepath.Match(pattern, name)
+		if err != nil {
+			return false, fmt.Errorf("allow pattern %q: %w", pattern, err)
+		}
+
+		if ok {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,200 @@
+package modules
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+func TestDist_Run(t *testing.T) {
+	base := t.TempDir()
+	project := filepath.Join(base, "dist")
+	target := filepath.Join(project, "v1.2.3")
+
+	for _, name := range []string{"leftover.tar.gz", "notes.txt", ctx.LockFilename} {
+		if err := os.MkdirAll(target, 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(filepath.Join(target, name), []byte(name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Join(target, ctx.HistoryDir), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	cx := ctx.New(context.Background())
+
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	context.TargetDir = project
+	context.Version = "v1.2.3"
+
+	mod := NewDist().(*Dist)
+	mod.Allow = []string{"*.txt"}
+	mod.Target = filepath.Join(project, "{{.Version}}")
+
+	if err := mod.Run(cx); err == nil {
+		t.Error("Run() succeeded with unexpected files")
+	}
+
+	if context.TargetDir != target {
+		t.Errorf("Run() set target directory %s, want %s", context.TargetDir, target)
+	}
+
+	context.Resumed = true
+
+	if err := mod.Run(cx); err != nil {
+		t.Errorf("Run() of a resumed run error = %v", err)
+	}
+
+	context.Resumed = false
+	mod.Clean = true
+
+	if err := mod.Run(cx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	entries, err := os.ReadDir(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, entry := range entries {
+		got = append(got, entry.Name())
+	}
+
+	want := []string{ctx.LockFilename, ctx.HistoryDir, "notes.txt"}
+	if len(got) != len(want) {
+		t.Fatalf("Run() left %v, want %v", got, want)
+	}
+
+	for idx := range want {
+		if got[idx] != want[idx] {
+			t.Errorf("Run() left %v, want %v", got, want)
+		}
+	}
+}
+
+func TestDist_RunUnsafeClean(t *testing.T) {
+	base := t.TempDir()
+	project := filepath.Join(base, "project")
+	work := filepath.Join(base, "work")
+
+	for _, dir := range []string{
+		filepath.Join(project, "dist"),
+		filepath.Join(work, "sub"),
+		filepath.Join(base, "module"),
+		filepath.Join(base, "repo", ".git"),
+	} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(base, "module", "go.mod"), []byte("module test\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(filepath.Join(work, "sub")); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		if err := os.Chdir(wd); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	tests := []struct {
+		name   string
+		target string
+	}{
+		{"project directory", project},
+		{"parent of project directory", base},
+		{"working directory", filepath.Join(work, "sub")},
+		{"parent of working directory", work},
+		{"go module", filepath.Join(base, "module")},
+		{"git repository", filepath.Join(base, "repo")},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			stale := filepath.Join(tt.target, "stale.txt")
+			if err := os.WriteFile(stale, nil, 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			cx := ctx.New(context.Background())
+
+			context, err := ctx.GetShipContext(cx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			context.Dir = project
+			context.TargetDir = tt.target
+
+			mod := NewDist().(*Dist)
+			mod.Clean = true
+
+			if err := mod.Run(cx); err == nil || !strings.Contains(err.Error(), "refusing") {
+				t.Errorf("Run() error = %v, want refusal", err)
+			}
+
+			if _, err := os.Stat(stale); err != nil {
+				t.Errorf("Run() removed files: %v", err)
+			}
+		})
+	}
+}
+
+func TestDist_RunRerun(t *testing.T) {
+	target := t.TempDir()
+
+	reserved := []string{ctx.LockFilename, ctx.StateFilename, ctx.ManifestFilename, ctx.ReportFilename}
+	for _, name := range reserved {
+		if err := os.WriteFile(filepath.Join(target, name), []byte(name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Join(target, ctx.HistoryDir), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	cx := ctx.New(context.Background())
+
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	context.TargetDir = target
+
+	if err := NewDist().Run(cx); err != nil {
+		t.Errorf("Run() over files of a previous run error = %v", err)
+	}
+
+	for _, name := range append(reserved, ctx.HistoryDir) {
+		if _, err := os.Stat(filepath.Join(target, name)); err != nil {
+			t.Errorf("Run() removed %s: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,356 @@
+package modules
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+type (
+	// Docker is a publish module pushing container images into one or more
+	// registries with multiple tags. It uses the `docker` CLI.
+	Docker struct {
+		// Artifacts is a template expression selecting artifacts of
+		// Builds (or all artifacts, if Builds is empty), evaluated for
+		// each artifact. See modules.SelectArtifacts.
+		Artifacts string
+		// Builds specifies image artifacts to be pushed. Image artifacts'
+		// file names are local image references. Default: ["image"].
+		Builds []string
+		// Image is a local image reference (template), which is pushed if
+		// no image artifacts are found. Default: empty.
+		Image string
+		// Registries lists target repositories
+		Registries []*DockerRegistry
+		// SemverAliases adds major, and major.minor version tags (eg.
+		// "v1", and "v1.2" for "v1.2.3"), if version is a semantic version
+		// without prerelease info. Default: false.
+		SemverAliases bool `yaml:"semver_aliases"`
+		// Tags are image tag templates. Default: ["{{.Version}}"].
+		Tags []string
+	}
+
+	// DockerRegistry is a target repository of Docker module
+	DockerRegistry struct {
+		// PasswordEnv specifies the environment variable of the registry
+		// password or token. If it is not set, credentials of the
+		// docker config are used.
+		PasswordEnv string `yaml:"password_env"`
+		// Repository is the target repository, like "ghcr.io/owner/app".
+		// Required.
+		Repository string
+		// UsernameEnv specifies the environment variable of the registry
+		// user name.
+		UsernameEnv string `yaml:"username_env"`
+	}
+)
+
+// NewDocker is a factory function for Docker module
+func NewDocker() modules.Pluggable {
+	return &Docker{
+		Builds: []string{"image"},
+		Tags:   []string{"{{.Version}}"},
+	}
+}
+
+// Run tags and pushes the image into all registries
+func (mod *Docker) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	if len(mod.Registries) == 0 {
+		return errors.New("no registries specified")
+	}
+
+	image, err := mod.sourceImage(cx)
+	if err != nil {
+		return err
+	}
+
+	tags, err := mod.tags(cx, context.Version)
+	if err != nil {
+		return err
+	}
+
+	env, err := mod.login(cx, context)
+	if err != nil {
+		return err
+	}
+
+	for _, registry := range mod.Registries {
+		for _, tag := range tags {
+			target := registry.Repository + ":" + tag
+
+			context.Progress.SetState(fmt.Sprintf("pushing %s", target))
+
+			if err := mod.docker(cx, env, nil, "tag", image, target); err != nil {
+				return fmt.Errorf("tagging %s as %s: %w", image, target, err)
+			}
+
+			if err := mod.docker(cx, env, nil, "push", target); err != nil {
+				return fmt.Errorf("pushing %s: %w", target, err)
+			}
+		}
+
+		image := registry.Repository + ":" + tags[0]
+		if digest := mod.digest(cx, env, image); digest != "" {
+			image = digest
+		}
+
+		log.Printf("      pushed %s", image)
+		context.Published.AddImage(image)
+	}
+
+	return nil
+}
+
+// sourceImage returns the local image reference to be pushed
+func (mod *Docker) sourceImage(cx context.Context) (string, error) {
+	builds, err := modules.SelectArtifacts(cx, mod.Builds, nil, mod.Artifacts)
+	if err != nil {
+		return "", err
+	}
+
+	images := []string{}
+
+	for _, build := range builds {
+		for _, artifact := range *build {
+			images = append(images, artifact.Filename)
+		}
+	}
+
+	switch len(images) {
+	case 0:
+	case 1:
+		return images[0], nil
+	default:
+		return "", fmt.Errorf("multiple images selected (%s), use one module for each", strings.Join(images, ", "))
+	}
+
+	if mod.Image == "" {
+		return "", errors.New("no image artifacts found, and no image specified")
+	}
+
+	td, err := modules.NewTemplate(cx)
+	if err != nil {
+		return "", err
+	}
+
+	image, err := td.Parse("docker-image", mod.Image)
+	if err != nil {
+		return "", fmt.Errorf("rendering image %q: %w", mod.Image, err)
+	}
+
+	return image, nil
+}
+
+// tags renders tag templates, and adds semver aliases
+func (mod *Docker) tags(cx context.Context, version string) ([]string, error) {
+	td, err := modules.NewTemplate(cx)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := []string{}
+
+	for _, tagTemplate := range mod.Tags {
+		tag, err := td.Parse("docker-tag", tagTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("rendering tag %q: %w", tagTemplate, err)
+		}
+
+		tags = append(tags, tag)
+	}
+
+	if mod.SemverAliases {
+		tags = append(tags, semverAliases(version)...)
+	}
+
+	if len(tags) == 0 {
+		return nil, errors.New("no tags specified")
+	}
+
+	return tags, nil
+}
+
+// semverAliases returns major, and major.minor aliases of a release
+// version, keeping its "v" prefix
+func semverAliases(version string) []string {
+	prefix := ""
+	if strings.HasPrefix(version, "v") {
+		prefix = "v"
+	}
+
+	ver, err := semver.Parse(strings.TrimPrefix(version, "v"))
+	if err != nil || len(ver.Pre) > 0 {
+		return nil
+	}
+
+	return []string{
+		fmt.Sprintf("%s%d", prefix, ver.Major),
+		fmt.Sprintf("%s%d.%d", prefix, ver.Major, ver.Minor),
+	}
+}
+
+// login logs into registries with credentials in environment variables.
+// It uses a temporary docker config directory outside the target
+// directory, initialized with the user's config, which is returned in
+// environment variables for further docker commands. Registries are
+// logged out of, and the directory is removed at the end of the run.
+func (mod *Docker) login(cx context.Context, context *ctx.Context) ([]string, error) {
+	env := context.Env.Environ()
+	configured := false
+
+	for _, registry := range mod.Registries {
+		if registry.Repository == "" {
+			return nil, errors.New("registry without repository")
+		}
+
+		if registry.PasswordEnv == "" {
+			continue
+		}
+
+		password, err := context.RequireEnv(registry.PasswordEnv, "registry password")
+		if err != nil {
+			return nil, err
+		}
+
+		if !configured {
+			configDir, err := dockerConfig(context)
+			if err != nil {
+				return nil, err
+			}
+
+			env = append(env, "DOCKER_CONFIG="+configDir)
+			configured = true
+		}
+
+		args := []string{"login", "--password-stdin"}
+		if registry.UsernameEnv != "" {
+			args = append(args, "--username", context.Getenv(registry.UsernameEnv, ""))
+		}
+
+		host := registryHost(registry.Repository)
+		context.Progress.SetState(fmt.Sprintf("logging into %s", host))
+
+		if err := mod.docker(cx, env, strings.NewReader(password), append(args, host)...); err != nil {
+			return nil, fmt.Errorf("logging into %s: %w", host, err)
+		}
+
+		// credential helpers of the user's config may keep credentials
+		// outside the config directory
+		context.OnFinish("docker logout "+host, dockerLogout(env, host))
+	}
+
+	return env, nil
+}
+
+// digest returns the repository digest of a pushed image, or empty string
+func (mod *Docker) digest(cx context.Context, env []string, image string) string {
+	cmd := exec.CommandContext(cx, "docker", "image", "inspect", "--format", "{{json .RepoDigests}}", image)
+	cmd.Env = env
+
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+
+	var digests []string
+	if err := json.Unmarshal(out, &digests); err != nil {
+		return ""
+	}
+
+	for _, digest := range digests {
+		if strings.HasPrefix(digest, repositoryOf(image)+"@") {
+			return digest
+		}
+	}
+
+	return ""
+}
+
+func (mod *Docker) docker(cx context.Context, env []string, stdin io.Reader, args ...string) error {
+	return dockerCommand(cx, env, stdin, args...)
+}
+
+// dockerLogout returns a cleanup handler logging out of a registry
+func dockerLogout(env []string, host string) func() error {
+	return func() error {
+		// the pipeline's context may be canceled already
+		return dockerCommand(context.Background(), env, nil, "logout", host)
+	}
+}
+
+// dockerConfig creates a temporary docker config directory for
+// credentials (see ctx.Context.SecretDir), copying the user's config.json
+// into it, keeping configured credential helpers
+func dockerConfig(context *ctx.Context) (string, error) {
+	dir, err := context.SecretDir("docker")
+	if err != nil {
+		return "", err
+	}
+
+	if err := copyDockerConfig(context, dir); err != nil {
+		return "", err
+	}
+
+	return dir, nil
+}
+
+// copyDockerConfig copies the user's config.json (or the one of
+// DOCKER_CONFIG) into dir, if it exists
+func copyDockerConfig(context *ctx.Context, dir string) error {
+	userDir, ok := context.LookupEnv("DOCKER_CONFIG")
+	if !ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+
+		userDir = filepath.Join(home, ".docker")
+	}
+
+	config, err := os.ReadFile(filepath.Join(userDir, "config.json"))
+	if err != nil {
+		return nil
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), config, 0o600); err != nil {
+		return fmt.Errorf("copying docker config: %w", err)
+	}
+
+	return nil
+}
+
+// repositoryOf returns an image reference without its tag
+func repositoryOf(reference string) string {
+	if idx := strings.LastIndex(reference, ":"); idx > strings.LastIndex(reference, "/") {
+		return reference[:idx]
+	}
+
+	return reference
+}
+
+// registryHost returns the registry part of a repository reference, as
+// docker interprets it
+func registryHost(repository string) string {
+	parts := strings.SplitN(repository, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return parts[0]
+	}
+
+	return "docker.io"
+}
@@ -0,0 +1,148 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+type (
+	// DockerLogin is a setup module logging into container registries,
+	// therefore later modules (like publish:docker, or docker builds in
+	// hooks) can push images without credentials of their own. It uses the
+	// `docker` CLI with a docker config directory dedicated to the run,
+	// which is removed at the end of the pipeline, after logging out.
+	DockerLogin struct {
+		// Registries are the registries to log into. Required.
+		Registries []*DockerLoginRegistry
+	}
+
+	// DockerLoginRegistry is a registry of DockerLogin module
+	DockerLoginRegistry struct {
+		// PasswordEnv specifies the environment variable of the password,
+		// or token (like an OIDC token, or GITHUB_TOKEN for ghcr.io).
+		// Required.
+		PasswordEnv string `yaml:"password_env"`
+		// Registry is the registry host, like "ghcr.io". Default:
+		// "docker.io".
+		Registry string
+		// Username is the user name. Default: empty.
+		Username string
+		// UsernameEnv specifies the environment variable of the user
+		// name, if Username is empty.
+		UsernameEnv string `yaml:"username_env"`
+	}
+)
+
+// NewDockerLogin is a factory function for DockerLogin module
+func NewDockerLogin() modules.Pluggable {
+	return &DockerLogin{}
+}
+
+// Run logs into registries, and sets DOCKER_CONFIG for later modules
+func (mod *DockerLogin) Run(cx context.Context) error {
+	shipContext, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	if len(mod.Registries) == 0 {
+		return errors.New("no registries specified")
+	}
+
+	configDir, err := os.MkdirTemp("", "goshipdone-docker-")
+	if err != nil {
+		return fmt.Errorf("creating docker config directory: %w", err)
+	}
+
+	if err := copyDockerConfig(shipContext, configDir); err != nil {
+		_ = os.RemoveAll(configDir)
+		return err
+	}
+
+	env := append(shipContext.Env.Environ(), "DOCKER_CONFIG="+configDir)
+	hosts := []string{}
+
+	shipContext.OnFinish("docker login", func() error {
+		for _, host := range hosts {
+			// the pipeline's context may be canceled already
+			if err := dockerCommand(context.Background(), env, nil, "logout", host); err != nil {
+				log.Printf("      logging out of %s failed: %v", host, err)
+			}
+		}
+
+		return os.RemoveAll(configDir)
+	})
+
+	for _, registry := range mod.Registries {
+		host, err := registry.login(cx, shipContext, env)
+		if err != nil {
+			return err
+		}
+
+		hosts = append(hosts, host)
+	}
+
+	shipContext.Setenv("DOCKER_CONFIG", configDir)
+
+	return nil
+}
+
+// login logs into the registry, returning its host
+func (registry *DockerLoginRegistry) login(cx context.Context, context *ctx.Context, env []string) (string, error) {
+	host := registry.Registry
+	if host == "" {
+		host = "docker.io"
+	}
+
+	if registry.PasswordEnv == "" {
+		return "", fmt.Errorf("password_env of %s is not specified", host)
+	}
+
+	password, err := context.RequireEnv(registry.PasswordEnv, "registry password")
+	if err != nil {
+		return "", err
+	}
+
+	username := registry.Username
+	if username == "" && registry.UsernameEnv != "" {
+		username, err = context.RequireEnv(registry.UsernameEnv, "registry user name")
+		if err != nil {
+			return "", err
+		}
+	}
+
+	args := []string{"login", "--password-stdin"}
+	if username != "" {
+		args = append(args, "--username", username)
+	}
+
+	context.Progress.SetState(fmt.Sprintf("logging into %s", host))
+
+	if err := dockerCommand(cx, env, strings.NewReader(password), append(args, host)...); err != nil {
+		return "", fmt.Errorf("logging into %s: %w", host, err)
+	}
+
+	log.Printf("      logged into %s", host)
+
+	return host, nil
+}
+
+// dockerCommand runs a docker command
+func dockerCommand(cx context.Context, env []string, stdin io.Reader, args ...string) error {
+	cmd := exec.CommandContext(cx, "docker", args...)
+	cmd.Env = env
+	cmd.Stdin = stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	return cmd.Run()
+}
@@ -0,0 +1,78 @@
+package modules
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+func TestDockerLogin_Run(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake docker CLI is a shell script")
+	}
+
+	bin := t.TempDir()
+	calls := filepath.Join(bin, "calls")
+	script := "#!/bin/sh\necho \"$DOCKER_CONFIG $* $(cat)\" >> " + calls + "\n"
+
+	if err := os.WriteFile(filepath.Join(bin, "docker"), []byte(script), 0o755); err != nil { // nolint: gosec
+		t.Fatal(err)
+	}
+
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cx := ctx.New(context.Background())
+
+	shipContext, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shipContext.Env.Set("GHCR_TOKEN", "gh-token")
+	shipContext.Env.Set("HUB_USER", "hubuser")
+	shipContext.Env.Set("HUB_PASSWORD", "hub-password")
+
+	mod := NewDockerLogin().(*DockerLogin)
+	mod.Registries = []*DockerLoginRegistry{
+		{Registry: "ghcr.io", Username: "owner", PasswordEnv: "GHCR_TOKEN"},
+		{UsernameEnv: "HUB_USER", PasswordEnv: "HUB_PASSWORD"},
+	}
+
+	if err := mod.Run(cx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	configDir, ok := shipContext.Env.Get("DOCKER_CONFIG")
+	if !ok {
+		t.Fatal("DOCKER_CONFIG is not set")
+	}
+
+	shipContext.Finish()
+
+	if _, err := os.Stat(configDir); !os.IsNotExist(err) {
+		t.Errorf("docker config directory is not removed: %v", err)
+	}
+
+	out, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Join([]string{
+		configDir + " login --password-stdin --username owner ghcr.io gh-token",
+		configDir + " login --password-stdin --username hubuser docker.io hub-password",
+		configDir + " logout ghcr.io ",
+		configDir + " logout docker.io ",
+		"",
+	}, "\n")
+
+	if string(out) != want {
+		t.Errorf("docker calls:\n%s\nwant:\n%s", out, want)
+	}
+}
@@ -0,0 +1,116 @@
+package modules
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSemverAliases(t *testing.T) {
+	tests := []struct {
+		version string
+		want    []string
+	}{
+		{"v1.2.3", []string{"v1", "v1.2"}},
+		{"1.2.3", []string{"1", "1.2"}},
+		{"v1.2.3-rc.1", nil},
+		{"v1.2.3-1-gdeadbee-dirty", nil},
+		{"deadbee", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			if got := semverAliases(tt.version); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("semverAliases() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistryHost(t *testing.T) {
+	tests := map[string]string{
+		"ghcr.io/owner/app":   "ghcr.io",
+		"localhost/app":       "localhost",
+		"registry:5000/app":   "registry:5000",
+		"owner/app":           "docker.io",
+		"app":                 "docker.io",
+		"quay.io/owner/app/x": "quay.io",
+	}
+
+	for repository, want := range tests {
+		if got := registryHost(repository); got != want {
+			t.Errorf("registryHost(%q) = %q, want %q", repository, got, want)
+		}
+	}
+}
+
+func TestRepositoryOf(t *testing.T) {
+	tests := map[string]string{
+		"ghcr.io/owner/app:v1.0.0":    "ghcr.io/owner/app",
+		"registry:5000/app":           "registry:5000/app",
+		"registry:5000/app:v1,latest": "registry:5000/app",
+		"app":                         "app",
+	}
+
+	for reference, want := range tests {
+		if got := repositoryOf(reference); got != want {
+			t.Errorf("repositoryOf(%q) = %q, want %q", reference, got, want)
+		}
+	}
+}
+
+func TestDocker_login(t *testing.T) {
+	calls := fakeCommand(t, "docker", `echo "$DOCKER_CONFIG" > "$CONFIG_COPY"
+[ "$1" = login ] && cat > "$DOCKER_CONFIG/credentials"
+exit 0`)
+	cx, shipContext := testShipContext(t)
+
+	configCopy := filepath.Join(t.TempDir(), "config")
+	shipContext.Env.Set("CONFIG_COPY", configCopy)
+	shipContext.Env.Set("DOCKER_CONFIG", t.TempDir())
+	shipContext.Env.Set("GHCR_TOKEN", "gh-token")
+	shipContext.Env.Set("HUB_USER", "hubuser")
+
+	mod := NewDocker().(*Docker)
+	mod.Registries = []*DockerRegistry{
+		{Repository: "ghcr.io/owner/app", PasswordEnv: "GHCR_TOKEN", UsernameEnv: "HUB_USER"},
+		{Repository: "docker.io/owner/app"},
+	}
+
+	env, err := mod.login(cx, shipContext)
+	if err != nil {
+		t.Fatalf("login() error = %v", err)
+	}
+
+	content, err := os.ReadFile(configCopy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	configDir := strings.TrimSpace(string(content))
+	if !reflect.DeepEqual(env[len(env)-1:], []string{"DOCKER_CONFIG=" + configDir}) {
+		t.Errorf("login() env ends with %v, want DOCKER_CONFIG=%s", env[len(env)-1:], configDir)
+	}
+
+	if rel, err := filepath.Rel(shipContext.TargetDir, configDir); err == nil && !strings.HasPrefix(rel, "..") {
+		t.Errorf("credentials written into target directory: %s", configDir)
+	}
+
+	if credentials, _ := os.ReadFile(filepath.Join(configDir, "credentials")); string(credentials) != "gh-token" {
+		t.Errorf("docker login got password %q", credentials)
+	}
+
+	shipContext.Fail()
+	shipContext.Finish()
+
+	if _, err := os.Stat(configDir); err == nil {
+		t.Errorf("docker config %s kept after the run", configDir)
+	}
+
+	want := []string{"login --password-stdin --username hubuser ghcr.io", "logout ghcr.io"}
+	if got := fakeCalls(t, calls); !reflect.DeepEqual(got, want) {
+		t.Errorf("docker calls = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,356 @@
+package modules
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+const (
+	emailSubject = `{{.ProjectName}} {{.Version}} released`
+
+	emailText = `{{.ProjectName}} {{.Version}} has been released.
+{{with highlights 10 .Changelog}}
+Highlights:
+{{range .}}
+- {{.}}{{end}}
+{{end}}{{with .Links}}{{range .}}
+{{.Name}}: {{.URL}}{{end}}
+{{end}}{{range .Artifacts}}{{if .URL}}
+{{.Filename}}: {{.URL}}
+  sha256: {{.SHA256}}{{end}}{{end}}`
+
+	emailHTML = `<html>
+<body>
+<p><strong>{{.ProjectName}} {{.Version}}</strong> has been released.</p>
+{{with highlights 10 .Changelog}}<p>Highlights:</p>
+<ul>{{range .}}
+<li>{{.}}</li>{{end}}
+</ul>
+{{end}}{{with .Links}}<p>{{range .}}<a href="{{.URL}}">{{.Name}}</a><br>
+{{end}}</p>
+{{end}}<ul>{{range .Artifacts}}{{if .URL}}
+<li><a href="{{.URL}}">{{.Filename}}</a> (sha256: <code>{{.SHA256}}</code>)</li>{{end}}{{end}}
+</ul>
+</body>
+</html>`
+)
+
+// Email is a notify module sending release announcements in email, like
+// to an announce mailing list
+type Email struct {
+	// Artifacts is a template expression selecting artifacts of
+	// Builds (or all artifacts, if Builds is empty), evaluated for
+	// each artifact. See modules.SelectArtifacts.
+	Artifacts string
+	// Builds specifies which build names are linked in the message,
+	// if their download links are known. Default: ["archive"].
+	Builds []string
+	// Changelog is the artifact ID of the changelog excerpt. It is
+	// optional. Default: "changelog".
+	Changelog string
+	// From is the sender's address, like "Releases <releases@example.com>".
+	// Required.
+	From string
+	// HTML is the HTML body (Go HTML template of WebhookPayload, with
+	// `highlights` function). Default: version, changelog highlights,
+	// release links, and download links.
+	HTML string
+	// Host is the SMTP server's host name. Required.
+	Host string
+	// PasswordEnv specifies the environment variable of the SMTP
+	// password. Default: "SMTP_PASSWORD".
+	PasswordEnv string `yaml:"password_env"`
+	// Port is the SMTP server's port. Port 465 uses implicit TLS, other
+	// ports use STARTTLS, if the server supports it. Default: 587.
+	Port int
+	// Skip specifies GOOS-GOArch combinations to be skipped.
+	// They are in `{{.Os}}-{{.Arch}}` format.
+	Skip []string
+	// Subject is the subject (Go template of WebhookPayload). Default:
+	// "{{.ProjectName}} {{.Version}} released".
+	Subject string
+	// Text is the plain text body (Go template of WebhookPayload, with
+	// `json`, and `highlights` functions). Default: version, changelog
+	// highlights, release links, and download links.
+	Text string
+	// To lists recipients' addresses. Required.
+	To []string
+	// Username is the SMTP user name. Authentication is turned off if
+	// empty.
+	Username string
+}
+
+// NewEmail is a factory function for Email module
+func NewEmail() modules.Pluggable {
+	return &Email{
+		Builds:      []string{"archive"},
+		Changelog:   "changelog",
+		PasswordEnv: "SMTP_PASSWORD",
+		Port:        587,
+		Skip:        []string{},
+		To:          []string{},
+	}
+}
+
+// Run sends the announcement
+func (mod *Email) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	if mod.Host == "" {
+		return errors.New("SMTP host is not specified")
+	}
+
+	if mod.From == "" {
+		return errors.New("sender is not specified")
+	}
+
+	if len(mod.To) == 0 {
+		return errors.New("no recipients specified")
+	}
+
+	payload, err := releasePayload(cx, context, mod.Builds, mod.Skip, mod.Artifacts, mod.Changelog)
+	if err != nil {
+		return err
+	}
+
+	msg, err := mod.message(context, payload, time.Now())
+	if err != nil {
+		return err
+	}
+
+	context.Progress.SetState(fmt.Sprintf("sending announcement to %s", strings.Join(mod.To, ", ")))
+
+	if err := mod.send(cx, context, msg); err != nil {
+		return fmt.Errorf("sending announcement: %w", err)
+	}
+
+	return nil
+}
+
+// message renders the announcement into a multipart/alternative email
+func (mod *Email) message(context *ctx.Context, payload *WebhookPayload, date time.Time) ([]byte, error) {
+	subject, err := renderPayload("email-subject", mod.template(mod.Subject, emailSubject), payload)
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := renderPayload("email-text", mod.template(mod.Text, emailText), payload)
+	if err != nil {
+		return nil, err
+	}
+
+	html, err := renderHTMLPayload("email-html", mod.template(mod.HTML, emailHTML), payload)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	parts := multipart.NewWriter(buf)
+
+	if err := parts.SetBoundary(context.Random.Hex(16)); err != nil {
+		return nil, err
+	}
+
+	from, err := mail.ParseAddress(mod.From)
+	if err != nil {
+		return nil, fmt.Errorf("parsing sender: %w", err)
+	}
+
+	domain := from.Address[strings.LastIndex(from.Address, "@")+1:]
+
+	for _, header := range [][2]string{
+		{"From", mod.From},
+		{"To", strings.Join(mod.To, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", strings.TrimSpace(subject))},
+		{"Date", date.Format(time.RFC1123Z)},
+		{"Message-ID", fmt.Sprintf("<%s@%s>", context.Random.Hex(16), domain)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + parts.Boundary()},
+	} {
+		fmt.Fprintf(buf, "%s: %s\r\n", header[0], header[1])
+	}
+
+	buf.WriteString("\r\n")
+
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", strings.TrimSpace(text) + "\n"},
+		{"text/html; charset=utf-8", html},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// ValidateTemplates implements modules.TemplateValidator
+func (mod *Email) ValidateTemplates(context.Context) ([]string, error) {
+	payload := sampleWebhookPayload()
+
+	if _, err := renderPayload("email-subject", mod.template(mod.Subject, emailSubject), payload); err != nil {
+		return nil, err
+	}
+
+	if _, err := renderPayload("email-text", mod.template(mod.Text, emailText), payload); err != nil {
+		return nil, err
+	}
+
+	if _, err := renderHTMLPayload("email-html", mod.template(mod.HTML, emailHTML), payload); err != nil {
+		return nil, err
+	}
+
+	return []string{"HTML", "Subject", "Text"}, nil
+}
+
+// template returns the configured template, or its default
+func (mod *Email) template(configured, fallback string) string {
+	if configured == "" {
+		return fallback
+	}
+
+	return configured
+}
+
+// send delivers the message through the SMTP server
+func (mod *Email) send(cx context.Context, context *ctx.Context, msg []byte) error {
+	addr := net.JoinHostPort(mod.Host, strconv.Itoa(mod.Port))
+	tlsConfig := &tls.Config{ServerName: mod.Host, MinVersion: tls.VersionTLS12}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+
+	if mod.Port == 465 {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.DialContext(cx, "tcp", addr)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, mod.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && mod.Port != 465 {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+
+	if mod.Username != "" {
+		password, err := context.RequireEnv(mod.PasswordEnv, "SMTP password")
+		if err != nil {
+			return err
+		}
+
+		if err := client.Auth(smtp.PlainAuth("", mod.Username, password, mod.Host)); err != nil {
+			return err
+		}
+	}
+
+	from, err := mail.ParseAddress(mod.From)
+	if err != nil {
+		return fmt.Errorf("parsing sender: %w", err)
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+
+	for _, to := range mod.To {
+		rcpt, err := mail.ParseAddress(to)
+		if err != nil {
+			return fmt.Errorf("parsing recipient: %w", err)
+		}
+
+		if err := client.Rcpt(rcpt.Address); err != nil {
+			return fmt.Errorf("recipient %s: %w", rcpt.Address, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// renderHTMLPayload renders a Go HTML template of release information,
+// with `highlights` function returning the first n list items of a
+// changelog, and registered template functions (see
+// modules.RegisterTemplateFunc)
+func renderHTMLPayload(name, text string, payload *WebhookPayload) (string, error) {
+	funcs := htmltemplate.FuncMap(modules.TemplateFuncs())
+	funcs["highlights"] = changelogHighlights
+
+	tmpl, err := htmltemplate.New(name).Funcs(funcs).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("parsing %s: %w", name, err)
+	}
+
+	out := &bytes.Buffer{}
+	if err := tmpl.Execute(out, payload); err != nil {
+		return "", fmt.Errorf("rendering %s: %w", name, err)
+	}
+
+	return out.String(), nil
+}
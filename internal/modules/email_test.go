@@ -0,0 +1,117 @@
+package modules
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+func TestEmail_message(t *testing.T) {
+	cx := ctx.New(context.Background())
+
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := &WebhookPayload{
+		Artifacts: []*WebhookArtifact{{
+			Filename: "app-linux-amd64.tar.gz",
+			SHA256:   "abc",
+			URL:      "https://example.com/app-linux-amd64.tar.gz",
+		}},
+		Changelog:   "### Fixed\n\n- <script> handling\n",
+		Links:       []ctx.Link{{Name: "Release", URL: "https://example.com/v1.2.3"}},
+		ProjectName: "app",
+		Version:     "v1.2.3",
+	}
+
+	mod := NewEmail().(*Email)
+	mod.From = "Releases <releases@example.com>"
+	mod.To = []string{"announce@example.com"}
+	mod.Subject = "{{.ProjectName}} {{.Version}} – out now"
+
+	out, err := mod.message(context, payload, time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("message() error = %v", err)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(out)))
+	if err != nil {
+		t.Fatalf("parsing message: %v", err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "app v1.2.3 – out now" {
+		t.Errorf("Subject = %q (%v)", subject, err)
+	}
+
+	if date := msg.Header.Get("Date"); date != "Sat, 02 Jan 2021 03:04:05 +0000" {
+		t.Errorf("Date = %q", date)
+	}
+
+	if id := msg.Header.Get("Message-ID"); !strings.HasSuffix(id, "@example.com>") {
+		t.Errorf("Message-ID = %q", id)
+	}
+
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	bodies := map[string]string{}
+
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		content, err := io.ReadAll(quotedprintable.NewReader(part))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// quoted-printable text has CRLF line endings
+		bodies[part.Header.Get("Content-Type")] = strings.ReplaceAll(string(content), "\r\n", "\n")
+	}
+
+	wantText := `app v1.2.3 has been released.
+
+Highlights:
+
+- <script> handling
+
+Release: https://example.com/v1.2.3
+
+app-linux-amd64.tar.gz: https://example.com/app-linux-amd64.tar.gz
+  sha256: abc
+`
+	if text := bodies["text/plain; charset=utf-8"]; text != wantText {
+		t.Errorf("text body:\n%s\nwant:\n%s", text, wantText)
+	}
+
+	html := bodies["text/html; charset=utf-8"]
+	for _, want := range []string{
+		"<li>&lt;script&gt; handling</li>",
+		`<a href="https://example.com/v1.2.3">Release</a>`,
+		`<a href="https://example.com/app-linux-amd64.tar.gz">app-linux-amd64.tar.gz</a>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("html body doesn't contain %q:\n%s", want, html)
+		}
+	}
+}
@@ -2,8 +2,12 @@ package modules
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path"
+	"regexp"
+	"strings"
 
 	"github.com/julian7/goshipdone/ctx"
 	"github.com/julian7/goshipdone/modules"
@@ -15,14 +19,28 @@ const (
 	EnvHomePath   = "HOMEPATH"
 )
 
+// nolint: gochecknoglobals
+var reDotenvKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
 // Env module sets up context's Env hash
-type Env struct{}
+type Env struct {
+	// Files are dotenv files loaded in order, later files overriding
+	// earlier ones. File names ending with "?" are optional. Default:
+	// empty.
+	Files []string
+	// Override lets variables of dotenv files override the process's
+	// environment. Default: false.
+	Override bool
+	// Required lists variables, which must be set (either in the
+	// environment, or in dotenv files). Default: empty.
+	Required []string
+}
 
 func NewEnv() modules.Pluggable {
 	return &Env{}
 }
 
-func (*Env) Run(cx context.Context) error {
+func (mod *Env) Run(cx context.Context) error {
 	context, err := ctx.GetShipContext(cx)
 	if err != nil {
 		return err
@@ -32,11 +50,15 @@ func (*Env) Run(cx context.Context) error {
 		return err
 	}
 
-	if _, ok := context.Env.Get(EnvConfigHome); !ok {
+	if err := mod.loadFiles(context); err != nil {
+		return err
+	}
+
+	if _, ok := context.LookupEnv(EnvConfigHome); !ok {
 		for _, homeEnv := range []string{EnvHome, EnvHomePath} {
-			home, ok := context.Env.Get(homeEnv)
+			home, ok := context.LookupEnv(homeEnv)
 			if ok {
-				context.Env.Set(EnvConfigHome, path.Join(
+				context.Setenv(EnvConfigHome, path.Join(
 					home,
 					".config",
 				))
@@ -46,5 +68,147 @@ func (*Env) Run(cx context.Context) error {
 		}
 	}
 
+	missing := []string{}
+
+	for _, name := range mod.Required {
+		if _, ok := context.LookupEnv(name); !ok {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("required environment variables are not set: %s", strings.Join(missing, ", "))
+	}
+
 	return nil
 }
+
+// loadFiles loads dotenv files into context's Env
+func (mod *Env) loadFiles(context *ctx.Context) error {
+	vars := map[string]string{}
+	lookup := func(name string) string {
+		if val, ok := vars[name]; ok && (mod.Override || !isSet(context, name)) {
+			return val
+		}
+
+		return context.Getenv(name, "")
+	}
+
+	for _, name := range mod.Files {
+		optional := strings.HasSuffix(name, "?")
+		name = strings.TrimSuffix(name, "?")
+
+		content, err := os.ReadFile(context.Path(name))
+		if optional && errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("reading dotenv file: %w", err)
+		}
+
+		if err := parseDotenv(string(content), lookup, vars); err != nil {
+			return fmt.Errorf("parsing dotenv file %s: %w", name, err)
+		}
+	}
+
+	// values are already expanded by parseDotenv, and Setenv stores them
+	// as they are
+	for name, val := range vars {
+		if mod.Override || !isSet(context, name) {
+			context.Setenv(name, val)
+		}
+	}
+
+	return nil
+}
+
+// isSet tells whether a variable is set in context's Env
+func isSet(context *ctx.Context, name string) bool {
+	_, ok := context.LookupEnv(name)
+	return ok
+}
+
+// parseDotenv parses dotenv content into vars. Lines are in `KEY=value`
+// format, with an optional `export ` prefix. Values can be single-quoted
+// (literal), or double-quoted (with `\n`, `\t`, `\"`, and `\\` escapes).
+// Variables (`$VAR`, `${VAR}`) are expanded by lookup in unquoted, and
+// double-quoted values. Empty lines, and lines starting with `#` are
+// ignored, as well as ` #` comments after unquoted values.
+func parseDotenv(content string, lookup func(string) string, vars map[string]string) error {
+	for lineno, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line = strings.TrimPrefix(line, "export ")
+
+		items := strings.SplitN(line, "=", 2)
+		key := strings.TrimSpace(items[0])
+
+		if len(items) != 2 || !reDotenvKey.MatchString(key) {
+			return fmt.Errorf("line %d: invalid declaration", lineno+1)
+		}
+
+		val, err := parseDotenvValue(strings.TrimSpace(items[1]), lookup)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineno+1, err)
+		}
+
+		vars[key] = val
+	}
+
+	return nil
+}
+
+// parseDotenvValue parses a value of a dotenv declaration
+func parseDotenvValue(val string, lookup func(string) string) (string, error) {
+	switch {
+	case strings.HasPrefix(val, "'"):
+		end := strings.Index(val[1:], "'")
+		if end < 0 {
+			return "", errors.New("unterminated single quote")
+		}
+
+		return val[1 : end+1], nil
+	case strings.HasPrefix(val, `"`):
+		out := &strings.Builder{}
+
+		for i := 1; i < len(val); i++ {
+			switch val[i] {
+			case '"':
+				return os.Expand(out.String(), lookup), nil
+			case '\\':
+				if i+1 < len(val) {
+					i++
+					out.WriteString(unescapeDotenv(val[i]))
+
+					continue
+				}
+			}
+
+			out.WriteByte(val[i])
+		}
+
+		return "", errors.New("unterminated double quote")
+	}
+
+	if idx := strings.Index(val, " #"); idx >= 0 {
+		val = strings.TrimSpace(val[:idx])
+	}
+
+	return os.Expand(val, lookup), nil
+}
+
+// unescapeDotenv returns the character of a backslash escape sequence
+func unescapeDotenv(char byte) string {
+	switch char {
+	case 'n':
+		return "\n"
+	case 't':
+		return "\t"
+	default:
+		return string(char)
+	}
+}
@@ -0,0 +1,67 @@
+package modules
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseDotenv(t *testing.T) {
+	content := `# comment
+export NAME=app
+EMPTY=
+GREETING="hello\n\"$NAME\""
+LITERAL='$NAME # not a comment'
+URL=https://example.com/${NAME} # comment
+NESTED=${GREETING_MISSING}x
+`
+
+	vars := map[string]string{}
+	lookup := func(name string) string { return vars[name] }
+
+	if err := parseDotenv(content, lookup, vars); err != nil {
+		t.Fatalf("parseDotenv() error = %v", err)
+	}
+
+	want := map[string]string{
+		"EMPTY":    "",
+		"GREETING": "hello\n\"app\"",
+		"LITERAL":  "$NAME # not a comment",
+		"NAME":     "app",
+		"NESTED":   "x",
+		"URL":      "https://example.com/app",
+	}
+
+	if !reflect.DeepEqual(vars, want) {
+		t.Errorf("parseDotenv() = %q, want %q", vars, want)
+	}
+
+	for _, invalid := range []string{"NO_VALUE", "1X=y", `QUOTE="unterminated`, "SINGLE='x"} {
+		if err := parseDotenv(invalid, lookup, map[string]string{}); err == nil {
+			t.Errorf("parseDotenv(%q) succeeded", invalid)
+		}
+	}
+}
+
+func TestEnv_loadFiles(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(fn, []byte("KEY='a$b'\nQUOTED=\"${KEY}c\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, shipContext := testShipContext(t)
+	shipContext.Setenv("b", "expanded")
+
+	mod := &Env{Files: []string{fn}}
+	if err := mod.loadFiles(shipContext); err != nil {
+		t.Fatalf("loadFiles() error = %v", err)
+	}
+
+	// parsed values are stored without expanding them again
+	for name, want := range map[string]string{"KEY": "a$b", "QUOTED": "a$bc"} {
+		if got := shipContext.Getenv(name, ""); got != want {
+			t.Errorf("$%s = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+// Fake is a stand-in for signing and publishing modules. It doesn't
+// contact any external services, and doesn't need credentials: it records
+// intended actions into ctx.Context (and therefore into the run report).
+// It allows testing full pipelines in CI.
+//
+// As a signing module (sign:fake), it creates placeholder signature
+// artifacts, so later modules can refer to them. As a publishing module
+// (publish:fake), it only records what would have been uploaded.
+type Fake struct {
+	// Artifacts is a template expression selecting artifacts of Builds
+	// (or all artifacts, if Builds is empty), evaluated for each artifact.
+	// See modules.SelectArtifacts.
+	Artifacts string
+	// Builds specifies which build names should be processed.
+	Builds []string
+	// ID contains the placeholder signature artifacts' name used by later
+	// stages of the pipeline. Signing only. Default: "fakesig".
+	ID string `yaml:",omitempty"`
+	// Output is the placeholder signature file name template. Signing only.
+	// Default: `{{.Filename}}.sig`.
+	Output string `yaml:",omitempty"`
+	// Skip specifies GOOS-GOArch combinations to be skipped.
+	Skip []string
+	// Target is a free-form description of the destination, recorded with
+	// each action. Default: "fake".
+	Target string
+	action string
+}
+
+// NewFakeSign is a factory function for Fake module, signing artifacts
+func NewFakeSign() modules.Pluggable {
+	return &Fake{
+		ID:     "fakesig",
+		Output: "{{.Filename}}.sig",
+		Target: "fake",
+		action: "sign",
+	}
+}
+
+// NewFakePublish is a factory function for Fake module, publishing artifacts
+func NewFakePublish() modules.Pluggable {
+	return &Fake{
+		Target: "fake",
+		action: "publish",
+	}
+}
+
+// Run records intended actions of selected artifacts
+func (mod *Fake) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	builds, err := modules.SelectArtifacts(cx, mod.Builds, mod.Skip, mod.Artifacts)
+	if err != nil {
+		return err
+	}
+
+	for _, build := range builds {
+		for _, art := range *build {
+			if err := mod.record(context, art); err != nil {
+				return err
+			}
+
+			if mod.action == "sign" {
+				if err := mod.sign(cx, art); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (mod *Fake) record(context *ctx.Context, art *ctx.Artifact) error {
+	stat, err := os.Stat(art.Location)
+	if err != nil {
+		return fmt.Errorf("checking %s: %w", art.Location, err)
+	}
+
+	log.Printf("      would %s %s to %s", mod.action, art.Filename, mod.Target)
+
+	context.Actions.Record(ctx.Action{
+		Module:   "fake",
+		Action:   mod.action,
+		Artifact: art.Filename,
+		Target:   mod.Target,
+		Size:     stat.Size(),
+	})
+
+	return nil
+}
+
+func (mod *Fake) sign(cx context.Context, art *ctx.Artifact) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	td, err := modules.NewTemplate(cx)
+	if err != nil {
+		return err
+	}
+
+	td.OSArch = art.OsArch
+	td.Filename = art.Filename
+
+	sigName, err := td.Parse("fake-output", mod.Output)
+	if err != nil {
+		return fmt.Errorf("rendering %q: %w", mod.Output, err)
+	}
+
+	sigLocation := path.Join(context.TargetDir, sigName)
+	content := fmt.Sprintf("fake signature of %s\n", art.Filename)
+
+	if err := os.WriteFile(sigLocation, []byte(content), 0o644); err != nil { // nolint: gosec
+		return fmt.Errorf("writing fake signature %s: %w", sigLocation, err)
+	}
+
+	context.Artifacts.Add(&ctx.Artifact{
+		Filename: sigName,
+		ID:       mod.ID,
+		Location: sigLocation,
+		OsArch:   art.OsArch,
+		Parents:  []string{art.Filename},
+	})
+
+	return nil
+}
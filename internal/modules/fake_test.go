@@ -0,0 +1,136 @@
+package modules
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+// nolint: funlen
+func TestFake_Run(t *testing.T) {
+	linux := &ctx.OsArch{OS: "linux", Arch: "amd64"}
+
+	tests := []struct {
+		name    string
+		factory func() modules.Pluggable
+		builds  []string
+		output  string
+		target  string
+		// missing removes the artifact's file before the run
+		missing       bool
+		wantActions   []ctx.Action
+		wantArtifacts []string
+		wantErr       string
+	}{
+		{
+			name:    "sign",
+			factory: NewFakeSign,
+			builds:  []string{"archive"},
+			wantActions: []ctx.Action{
+				{Module: "fake", Action: "sign", Artifact: "app.tar.gz", Target: "fake", Size: 10},
+			},
+			wantArtifacts: []string{"fakesig:app.tar.gz.sig"},
+		},
+		{
+			name:    "sign with output template",
+			factory: NewFakeSign,
+			builds:  []string{"archive"},
+			output:  "{{.Filename}}.{{OS}}.asc",
+			target:  "cosign",
+			wantActions: []ctx.Action{
+				{Module: "fake", Action: "sign", Artifact: "app.tar.gz", Target: "cosign", Size: 10},
+			},
+			wantArtifacts: []string{"fakesig:app.tar.gz.linux.asc"},
+		},
+		{
+			name:    "publish",
+			factory: NewFakePublish,
+			builds:  []string{"archive", "default"},
+			target:  "s3://releases",
+			wantActions: []ctx.Action{
+				{Module: "fake", Action: "publish", Artifact: "app.tar.gz", Target: "s3://releases", Size: 10},
+				{Module: "fake", Action: "publish", Artifact: "app", Target: "s3://releases", Size: 3},
+			},
+		},
+		{
+			name:    "missing file",
+			factory: NewFakePublish,
+			builds:  []string{"archive"},
+			missing: true,
+			wantErr: "checking",
+		},
+		{
+			name:    "bad output template",
+			factory: NewFakeSign,
+			builds:  []string{"archive"},
+			output:  "{{.Filename",
+			wantErr: "rendering",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			cx, shipContext := testShipContext(t)
+			archive := addTestArtifact(t, shipContext, "archive", "app.tar.gz", linux)
+			addTestArtifact(t, shipContext, "default", "app", linux)
+
+			if tt.missing {
+				if err := os.Remove(archive.Location); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			mod := tt.factory().(*Fake)
+			mod.Builds = tt.builds
+
+			if tt.output != "" {
+				mod.Output = tt.output
+			}
+
+			if tt.target != "" {
+				mod.Target = tt.target
+			}
+
+			err := mod.Run(cx)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Run() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			if diff := deep.Equal(shipContext.Actions.List(), tt.wantActions); diff != nil {
+				t.Errorf("recorded actions %v", diff)
+			}
+
+			var got []string
+			for _, art := range shipContext.Artifacts[2:] {
+				got = append(got, art.ID+":"+art.Filename)
+
+				if _, err := os.Stat(art.Location); err != nil {
+					t.Errorf("placeholder signature %s: %v", art.Filename, err)
+				}
+
+				if len(art.Parents) != 1 || art.Parents[0] != "app.tar.gz" {
+					t.Errorf("%s parents = %v, want [app.tar.gz]", art.Filename, art.Parents)
+				}
+			}
+
+			if diff := deep.Equal(got, tt.wantArtifacts); diff != nil {
+				t.Errorf("Run() artifacts %v", diff)
+			}
+		})
+	}
+}
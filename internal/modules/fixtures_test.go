@@ -0,0 +1,122 @@
+package modules
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+// fakeCommand installs a fake CLI into PATH for the duration of the test.
+// The fake logs its arguments (one call per line) into the returned file,
+// and then runs script.
+func fakeCommand(t *testing.T, name, script string) string {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skipf("fake %s CLI is a shell script", name)
+	}
+
+	bin := t.TempDir()
+	calls := filepath.Join(bin, "calls")
+	content := "#!/bin/sh\necho \"$*\" >> " + calls + "\n" + script + "\n"
+
+	if err := os.WriteFile(filepath.Join(bin, name), []byte(content), 0o755); err != nil { // nolint: gosec
+		t.Fatal(err)
+	}
+
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	return calls
+}
+
+// fakeCalls returns calls logged by a fake CLI (see fakeCommand)
+func fakeCalls(t *testing.T, calls string) []string {
+	t.Helper()
+
+	content, err := os.ReadFile(calls)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		t.Fatal(err)
+	}
+
+	return strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+}
+
+// testShipContext returns a ship context with a temporary target
+// directory, and the project "app" at version v1.0.0
+func testShipContext(t *testing.T) (context.Context, *ctx.Context) {
+	t.Helper()
+
+	cx := ctx.New(context.Background())
+
+	shipContext, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shipContext.ProjectName = "app"
+	shipContext.TargetDir = t.TempDir()
+	shipContext.Version = "v1.0.0"
+
+	return cx, shipContext
+}
+
+// addTestArtifact writes an artifact into the target directory, and
+// registers it
+func addTestArtifact(t *testing.T, shipContext *ctx.Context, id, filename string, osarch *ctx.OsArch) *ctx.Artifact {
+	t.Helper()
+
+	art := &ctx.Artifact{
+		ID:       id,
+		Filename: filename,
+		Location: filepath.Join(shipContext.TargetDir, filename),
+		OsArch:   osarch,
+	}
+
+	if err := os.WriteFile(art.Location, []byte(filename), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	shipContext.Artifacts.Add(art)
+
+	return art
+}
+
+// testStorage starts an object storage server answering requests with
+// status. Headers of successful PUT requests are recorded by object path.
+func testStorage(t *testing.T, status int) (*httptest.Server, func() map[string]http.Header) {
+	t.Helper()
+
+	var mu sync.Mutex
+
+	objects := map[string]http.Header{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if req.Method == http.MethodPut && status < http.StatusMultipleChoices {
+			objects[strings.TrimPrefix(req.URL.Path, "/")] = req.Header.Clone()
+		}
+
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, func() map[string]http.Header {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return objects
+	}
+}
@@ -0,0 +1,95 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/internal/blob"
+	"github.com/julian7/goshipdone/modules"
+)
+
+// GCS is a publish module uploading artifacts to Google Cloud Storage. It
+// uses Cloud Storage's XML API, authenticating with an OAuth 2.0 access
+// token, or HMAC keys.
+type GCS struct {
+	BlobPublisher `yaml:",inline"`
+	// AccessKeyEnv specifies the environment variable of the HMAC access
+	// ID. Default: GOOGLE_HMAC_ACCESS_ID.
+	AccessKeyEnv string `yaml:"access_key_env"`
+	// ACL sets a predefined ACL on uploaded objects, like "public-read".
+	// Default: empty (bucket default).
+	ACL string
+	// Bucket is the target bucket's name. Required.
+	Bucket string
+	// Endpoint is the storage service's URL. Scheme is https if not
+	// specified. Default: storage.googleapis.com.
+	Endpoint string
+	// SecretKeyEnv specifies the environment variable of the HMAC secret.
+	// Default: GOOGLE_HMAC_SECRET.
+	SecretKeyEnv string `yaml:"secret_key_env"`
+	// StorageClass sets uploaded objects' storage class, like
+	// "NEARLINE". Default: empty (bucket default).
+	StorageClass string `yaml:"storage_class"`
+	// TokenEnv specifies the environment variable of an OAuth 2.0 access
+	// token (eg. output of `gcloud auth print-access-token`). HMAC keys
+	// are used if it is not set. Default: GOOGLE_OAUTH_ACCESS_TOKEN.
+	TokenEnv string `yaml:"token_env"`
+}
+
+// NewGCS is a factory function for GCS module
+func NewGCS() modules.Pluggable {
+	return &GCS{
+		BlobPublisher: newBlobPublisher(),
+		AccessKeyEnv:  "GOOGLE_HMAC_ACCESS_ID",
+		Endpoint:      "storage.googleapis.com",
+		SecretKeyEnv:  "GOOGLE_HMAC_SECRET",
+		TokenEnv:      "GOOGLE_OAUTH_ACCESS_TOKEN",
+	}
+}
+
+// Run uploads selected artifacts into a GCS bucket
+func (mod *GCS) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	client, err := mod.client(context)
+	if err != nil {
+		return err
+	}
+
+	return mod.publish(cx, client, blob.UploadOptions{ACL: mod.ACL, StorageClass: mod.StorageClass})
+}
+
+func (mod *GCS) client(context *ctx.Context) (*blob.S3, error) {
+	if mod.Bucket == "" {
+		return nil, fmt.Errorf("bucket is not specified")
+	}
+
+	token := context.Getenv(mod.TokenEnv, "")
+	accessKey := context.Getenv(mod.AccessKeyEnv, "")
+	secretKey := context.Getenv(mod.SecretKeyEnv, "")
+
+	if token == "" && (accessKey == "" || secretKey == "") {
+		return nil, fmt.Errorf(
+			"credentials not found: set $%s, or $%s and $%s",
+			mod.TokenEnv,
+			mod.AccessKeyEnv,
+			mod.SecretKeyEnv,
+		)
+	}
+
+	client := blob.NewGCS(mod.Bucket, accessKey, secretKey, token)
+	client.Progress = context.Progress.Reader
+
+	endpointURL, err := parseEndpoint(mod.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	client.Endpoint = endpointURL
+
+	return client, nil
+}
@@ -0,0 +1,141 @@
+package modules
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/internal/blob"
+	"gopkg.in/yaml.v3"
+)
+
+// nolint: funlen
+func TestGCS_Run(t *testing.T) {
+	tests := []struct {
+		name string
+		// config is the module's configuration, ENDPOINT standing for
+		// the test storage's URL, and FORBIDDEN for a storage rejecting
+		// uploads
+		config     string
+		env        map[string]string
+		wantObject string
+		wantAuth   string
+		// wantHeaders are request headers of the upload
+		wantHeaders map[string]string
+		wantErr     string
+	}{
+		{
+			name:       "oauth token",
+			config:     "bucket: releases\nendpoint: ENDPOINT\nacl: publicRead\nstorage_class: NEARLINE\n",
+			env:        map[string]string{"GOOGLE_OAUTH_ACCESS_TOKEN": "token", "GOOGLE_HMAC_ACCESS_ID": "access", "GOOGLE_HMAC_SECRET": "secret"},
+			wantObject: "releases/app/v1.0.0/app-linux-amd64.tar.gz",
+			wantAuth:   "Bearer token",
+			wantHeaders: map[string]string{
+				"X-Goog-Acl":           "publicRead",
+				"X-Goog-Storage-Class": "NEARLINE",
+			},
+		},
+		{
+			name:        "hmac keys",
+			config:      "bucket: releases\nendpoint: ENDPOINT\nprefix: \"{{.ProjectName}}/\"\n",
+			env:         map[string]string{"GOOGLE_HMAC_ACCESS_ID": "access", "GOOGLE_HMAC_SECRET": "secret"},
+			wantObject:  "releases/app/app-linux-amd64.tar.gz",
+			wantAuth:    "AWS4-HMAC-SHA256 Credential=access/",
+			wantHeaders: map[string]string{"X-Goog-Acl": "", "X-Goog-Storage-Class": ""},
+		},
+		{
+			name:       "custom token variable",
+			config:     "bucket: releases\nendpoint: ENDPOINT\ntoken_env: GCS_TOKEN\n",
+			env:        map[string]string{"GCS_TOKEN": "token"},
+			wantObject: "releases/app/v1.0.0/app-linux-amd64.tar.gz",
+			wantAuth:   "Bearer token",
+		},
+		{
+			name:    "no bucket",
+			config:  "endpoint: ENDPOINT\n",
+			env:     map[string]string{"GOOGLE_OAUTH_ACCESS_TOKEN": "token"},
+			wantErr: "bucket is not specified",
+		},
+		{
+			name:    "incomplete hmac keys",
+			config:  "bucket: releases\n",
+			env:     map[string]string{"GOOGLE_HMAC_ACCESS_ID": "access"},
+			wantErr: "credentials not found: set $GOOGLE_OAUTH_ACCESS_TOKEN, or $GOOGLE_HMAC_ACCESS_ID and $GOOGLE_HMAC_SECRET",
+		},
+		{
+			name:    "bad endpoint",
+			config:  "bucket: releases\nendpoint: \"://storage\"\n",
+			env:     map[string]string{"GOOGLE_OAUTH_ACCESS_TOKEN": "token"},
+			wantErr: "parsing endpoint",
+		},
+		{
+			name:    "rejected upload",
+			config:  "bucket: releases\nendpoint: FORBIDDEN\n",
+			env:     map[string]string{"GOOGLE_OAUTH_ACCESS_TOKEN": "token"},
+			wantErr: "uploading app-linux-amd64.tar.gz to gs:releases",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			storage, objects := testStorage(t, http.StatusOK)
+			forbidden, _ := testStorage(t, http.StatusForbidden)
+
+			cx, shipContext := testShipContext(t)
+			addTestArtifact(t, shipContext, "archive", "app-linux-amd64.tar.gz", &ctx.OsArch{OS: "linux", Arch: "amd64"})
+
+			for key, value := range tt.env {
+				shipContext.Env.Set(key, value)
+			}
+
+			mod := NewGCS().(*GCS)
+			config := strings.NewReplacer("ENDPOINT", storage.URL, "FORBIDDEN", forbidden.URL).Replace(tt.config)
+
+			if err := yaml.Unmarshal([]byte(config), mod); err != nil {
+				t.Fatal(err)
+			}
+
+			// GCS addresses buckets by host name, while test storages are
+			// addressed by path
+			client, err := mod.client(shipContext)
+			if err == nil {
+				client.PathStyle = true
+				err = mod.publish(cx, client, blob.UploadOptions{ACL: mod.ACL, StorageClass: mod.StorageClass})
+			}
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("publishing error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("publishing error = %v", err)
+			}
+
+			header, ok := objects()[tt.wantObject]
+			if !ok {
+				t.Fatalf("object %s not uploaded", tt.wantObject)
+			}
+
+			if auth := header.Get("Authorization"); !strings.HasPrefix(auth, tt.wantAuth) {
+				t.Errorf("Authorization = %q, want %q", auth, tt.wantAuth)
+			}
+
+			for name, want := range tt.wantHeaders {
+				if got := header.Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+
+			if got := shipContext.Transfers.Total().Uploaded; got != int64(len("app-linux-amd64.tar.gz")) {
+				t.Errorf("uploaded %d bytes, want %d", got, len("app-linux-amd64.tar.gz"))
+			}
+		})
+	}
+}
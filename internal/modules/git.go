@@ -3,10 +3,12 @@ package modules
 import (
 	"context"
 	"fmt"
+	"log"
+	"strings"
+	"time"
 
 	"github.com/julian7/goshipdone/ctx"
 	"github.com/julian7/goshipdone/modules"
-	"github.com/magefile/mage/sh"
 )
 
 // Git is a module, which takes a git repo, and filling in
@@ -18,33 +20,81 @@ func NewGit() modules.Pluggable {
 	return &Git{}
 }
 
-// Run records git tag information into ctx.Context
+// Run records git tag information into ctx.Context, including the
+// tag's semantic version components, the current branch, and the commit
+// date. In monorepos, only tags with the
+// project's tag prefix are considered, and the prefix is removed from
+// Version. Values already detected by setup:ci are kept.
 func (*Git) Run(cx context.Context) error {
 	context, err := ctx.GetShipContext(cx)
 	if err != nil {
 		return err
 	}
 
+	versionArgs := []string{"describe", "--tags", "--always", "--dirty"}
+	tagArgs := []string{"describe", "--exact-match", "--tags"}
+
+	if context.Git.TagPrefix != "" {
+		versionArgs = append(versionArgs, "--match", context.Git.TagPrefix+"*")
+		tagArgs = append(tagArgs, "--match", context.Git.TagPrefix+"*")
+	}
+
 	items := []struct {
 		name     string
 		required bool
 		target   *string
 		args     []string
 	}{
-		{"version info", true, &context.Version, []string{"describe", "--tags", "--always", "--dirty"}},
-		{"current tag", false, &context.Git.Tag, []string{"describe", "--exact-match", "--tags"}},
+		{"version info", true, &context.Version, versionArgs},
+		{"current tag", false, &context.Git.Tag, tagArgs},
 		{"current ref", true, &context.Git.Ref, []string{"-P", "show", "--format=%H", "-s"}},
 		{"url", false, &context.Git.URL, []string{"ls-remote", "--get-url"}},
+		{"current branch", false, &context.Git.Branch, []string{"rev-parse", "--abbrev-ref", "HEAD"}},
 	}
 
 	for _, item := range items {
-		val, err := sh.Output("git", item.args...)
+		// already detected by setup:ci
+		if *item.target != "" {
+			continue
+		}
+
+		val, err := gitOutput(cx, context, item.args...)
+		val = strings.TrimSpace(val)
 		if item.required && err != nil {
+			if item.target == &context.Version && len(context.CI.Commit) >= 7 {
+				context.Version = context.CI.Commit[:7]
+				continue
+			}
+
 			return fmt.Errorf("cannot detect %s from git: %w", item.name, err)
 		}
 
 		*item.target = val
 	}
 
+	// detached HEAD
+	if context.Git.Branch == "HEAD" {
+		context.Git.Branch = ""
+	}
+
+	if date, err := gitOutput(cx, context, "-P", "show", "--format=%cI", "-s"); err == nil {
+		if commitDate, err := time.Parse(time.RFC3339, strings.TrimSpace(date)); err == nil {
+			context.Git.CommitDate = commitDate
+		}
+	}
+
+	context.Version = strings.TrimPrefix(context.Version, context.Git.TagPrefix)
+	context.Git.Semver = ctx.Semver{}
+
+	if context.Git.Tag != "" {
+		semver, err := ctx.ParseSemver(strings.TrimPrefix(context.Git.Tag, context.Git.TagPrefix))
+		if err != nil {
+			log.Printf("      tag is not a semantic version: %v", err)
+			return nil
+		}
+
+		context.Git.Semver = *semver
+	}
+
 	return nil
 }
@@ -0,0 +1,74 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+// GitState is a setup module recording the current commit, and whether the
+// working tree has uncommitted changes, failing for dirty working trees
+// unless they are allowed
+type GitState struct {
+	// AllowDirty allows building from a working tree with uncommitted
+	// changes (including untracked files not ignored by git). Default:
+	// false.
+	AllowDirty bool `yaml:"allow_dirty"`
+}
+
+// NewGitState is a factory function for GitState module
+func NewGitState() modules.Pluggable {
+	return &GitState{}
+}
+
+// Run records git working tree state into ctx.Context
+func (mod *GitState) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	ref, err := gitOutput(cx, context, "rev-parse", "HEAD")
+	if err != nil {
+		return fmt.Errorf("cannot detect current ref from git: %w", err)
+	}
+
+	status, err := gitOutput(cx, context, append([]string{"status", "--porcelain"}, context.Git.Pathspec()...)...)
+	if err != nil {
+		return fmt.Errorf("cannot detect working tree state from git: %w", err)
+	}
+
+	files := dirtyFiles(status)
+
+	context.Git.Ref = strings.TrimSpace(ref)
+	context.Git.Dirty = len(files) > 0
+
+	if !context.Git.Dirty {
+		return nil
+	}
+
+	if !mod.AllowDirty {
+		return fmt.Errorf("working tree has uncommitted changes: %s", strings.Join(files, ", "))
+	}
+
+	log.Printf("      working tree has uncommitted changes: %s", strings.Join(files, ", "))
+
+	return nil
+}
+
+// dirtyFiles returns changed files of `git status --porcelain` output
+func dirtyFiles(status string) []string {
+	files := []string{}
+
+	for _, line := range strings.Split(status, "\n") {
+		if len(line) > 3 {
+			files = append(files, line[3:])
+		}
+	}
+
+	return files
+}
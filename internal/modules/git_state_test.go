@@ -0,0 +1,19 @@
+package modules
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDirtyFiles(t *testing.T) {
+	status := " M README.md\n?? notes.txt\nR  old.go -> new.go\n"
+	want := []string{"README.md", "notes.txt", "old.go -> new.go"}
+
+	if got := dirtyFiles(status); !reflect.DeepEqual(got, want) {
+		t.Errorf("dirtyFiles() = %q, want %q", got, want)
+	}
+
+	if got := dirtyFiles(""); len(got) != 0 {
+		t.Errorf("dirtyFiles() of clean tree = %q", got)
+	}
+}
@@ -0,0 +1,284 @@
+package modules
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/internal/artifacts"
+)
+
+type (
+	// GitHubPullRequest configures submitting package manifests as a pull
+	// request against an upstream GitHub repository, through a fork of
+	// the authenticated user
+	GitHubPullRequest struct {
+		// Base is the upstream branch the pull request is opened
+		// against. Default: "master".
+		Base string
+		// Draft opens a draft pull request. Default: false.
+		Draft bool
+		// Repository is the upstream repository in `owner/name` format.
+		// Default depends on the module.
+		Repository string
+		// TokenEnv specifies the environment variable of the GitHub
+		// token. Default: GITHUB_TOKEN.
+		TokenEnv string `yaml:"token_env"`
+		// URL is the base URL of GitHub Enterprise servers. Default:
+		// empty (github.com).
+		URL string
+	}
+
+	// gitCommit describes files to be committed, and pushed into a git
+	// repository
+	gitCommit struct {
+		// author is the commit author in `Name <email>` format. Git
+		// config is used if empty.
+		author string
+		// branch is the branch to be pushed
+		branch string
+		// cloneURL is the repository to be cloned
+		cloneURL string
+		// files maps slash-separated relative paths to contents
+		files map[string][]byte
+		// message is the commit message
+		message string
+		// pushURL is the repository to be pushed into. Default: cloneURL.
+		pushURL string
+		// sshKey is a private key file for SSH authentication. SSH config
+		// (or agent) is used if empty.
+		sshKey string
+		// token is a GitHub token for HTTPS authentication
+		token string
+	}
+)
+
+// defaults fills in default values of the pull request configuration
+func (pr *GitHubPullRequest) defaults(repository string) {
+	if pr.Base == "" {
+		pr.Base = "master"
+	}
+
+	if pr.Repository == "" {
+		pr.Repository = repository
+	}
+
+	if pr.TokenEnv == "" {
+		pr.TokenEnv = "GITHUB_TOKEN"
+	}
+}
+
+// submit commits files into a new branch of a fork of the upstream
+// repository, and opens a pull request, returning its URL
+func (pr *GitHubPullRequest) submit(cx context.Context, context *ctx.Context, branch, title, author string, files map[string][]byte) (string, error) {
+	token, err := context.RequireEnv(pr.TokenEnv, "github token")
+	if err != nil {
+		return "", err
+	}
+
+	parts := strings.SplitN(pr.Repository, "/", 2)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("invalid repository %q, expected owner/name", pr.Repository)
+	}
+
+	conn, err := (&artifacts.GitHubService{}).New(cx, pr.URL, token, parts[0], parts[1], nil)
+	if err != nil {
+		return "", err
+	}
+
+	client, ok := conn.(*artifacts.GitHubClient)
+	if !ok {
+		return "", errors.New("unexpected github connection")
+	}
+
+	context.Progress.SetState(fmt.Sprintf("forking %s", pr.Repository))
+
+	forkOwner, forkURL, err := client.Fork()
+	if err != nil {
+		return "", err
+	}
+
+	server := strings.TrimSuffix(pr.URL, "/")
+	if server == "" {
+		server = "https://github.com"
+	}
+
+	commit := &gitCommit{
+		author:   author,
+		branch:   branch,
+		cloneURL: fmt.Sprintf("%s/%s.git", server, pr.Repository),
+		files:    files,
+		message:  title,
+		pushURL:  forkURL,
+		token:    token,
+	}
+
+	if err := commit.push(cx, context); err != nil {
+		return "", err
+	}
+
+	context.Progress.SetState(fmt.Sprintf("opening pull request against %s", pr.Repository))
+
+	prURL, err := client.OpenPullRequest(forkOwner+":"+branch, pr.Base, title, "Created by goshipdone.", pr.Draft)
+	if err != nil {
+		return "", err
+	}
+
+	context.Published.AddLink("pull request", prURL)
+
+	return prURL, nil
+}
+
+// push clones the repository (sparsely, as package indexes can be huge),
+// writes files, commits them, and pushes the commit. It does nothing if
+// files are already up to date.
+func (commit *gitCommit) push(cx context.Context, context *ctx.Context) error {
+	dir, err := context.TempDir("git")
+	if err != nil {
+		return err
+	}
+
+	env, err := commit.env(context)
+	if err != nil {
+		return err
+	}
+
+	git := func(args ...string) error {
+		cmd := exec.CommandContext(cx, "git", args...)
+		cmd.Dir = dir
+		cmd.Env = env
+
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("git %s: %w\n%s", args[0], err, out)
+		}
+
+		return nil
+	}
+
+	context.Progress.SetState(fmt.Sprintf("cloning %s", commit.cloneURL))
+
+	if err := git("clone", "--quiet", "--depth", "1", "--filter=blob:none", "--sparse", commit.cloneURL, "."); err != nil {
+		return err
+	}
+
+	if dirs := commit.dirs(); len(dirs) > 0 {
+		if err := git(append([]string{"sparse-checkout", "set"}, dirs...)...); err != nil {
+			return err
+		}
+	}
+
+	if commit.branch != "" {
+		if err := git("checkout", "--quiet", "-B", commit.branch); err != nil {
+			return err
+		}
+	}
+
+	for name, content := range commit.files {
+		fn := filepath.Join(dir, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(fn), 0o755); err != nil {
+			return fmt.Errorf("creating directory for %s: %w", name, err)
+		}
+
+		if err := os.WriteFile(fn, content, 0o644); err != nil { // nolint: gosec
+			return fmt.Errorf("writing %s: %w", name, err)
+		}
+	}
+
+	if err := git("add", "--all"); err != nil {
+		return err
+	}
+
+	if err := git("diff", "--cached", "--quiet"); err == nil {
+		log.Printf("      %s is up to date", commit.cloneURL)
+		return nil
+	}
+
+	if err := git("commit", "--quiet", "--message", commit.message); err != nil {
+		return err
+	}
+
+	pushURL := commit.pushURL
+	if pushURL == "" {
+		pushURL = "origin"
+	}
+
+	context.Progress.SetState(fmt.Sprintf("pushing to %s", pushURL))
+
+	refspec := "HEAD"
+	if commit.branch != "" {
+		refspec = "HEAD:refs/heads/" + commit.branch
+	}
+
+	return git("push", "--quiet", pushURL, refspec)
+}
+
+// env returns git's environment with author, and HTTPS authentication
+// settings. Token is passed in environment, not to leak it in messages.
+func (commit *gitCommit) env(context *ctx.Context) ([]string, error) {
+	env := append(context.Env.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	if commit.author != "" {
+		addr, err := mail.ParseAddress(commit.author)
+		if err != nil {
+			return nil, fmt.Errorf("parsing commit author %q: %w", commit.author, err)
+		}
+
+		env = append(
+			env,
+			"GIT_AUTHOR_NAME="+addr.Name,
+			"GIT_AUTHOR_EMAIL="+addr.Address,
+			"GIT_COMMITTER_NAME="+addr.Name,
+			"GIT_COMMITTER_EMAIL="+addr.Address,
+		)
+	}
+
+	if commit.sshKey != "" {
+		env = append(env, fmt.Sprintf(
+			"GIT_SSH_COMMAND=ssh -i '%s' -o IdentitiesOnly=yes -o StrictHostKeyChecking=accept-new",
+			commit.sshKey,
+		))
+	}
+
+	if commit.token != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + commit.token))
+		env = append(
+			env,
+			"GIT_CONFIG_COUNT=1",
+			"GIT_CONFIG_KEY_0=http.extraheader",
+			"GIT_CONFIG_VALUE_0=Authorization: Basic "+auth,
+		)
+	}
+
+	return env, nil
+}
+
+// dirs returns sorted directories of files for sparse checkout
+func (commit *gitCommit) dirs() []string {
+	index := map[string]bool{}
+
+	for name := range commit.files {
+		if dir := path.Dir(name); dir != "." {
+			index[dir] = true
+		}
+	}
+
+	dirs := make([]string, 0, len(index))
+	for dir := range index {
+		dirs = append(dirs, dir)
+	}
+
+	sort.Strings(dirs)
+
+	return dirs
+}
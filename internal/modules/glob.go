@@ -0,0 +1,102 @@
+package modules
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// globFiles returns regular files matching a list of glob patterns, in
+// order of their first match (sorted by name for each pattern). Patterns support `**` for any number of
+// directories, and `{a,b}` alternation. Patterns starting with "!"
+// exclude files matched by previous patterns, like `!**/*_test.go`, and
+// later patterns can add them again. Relative patterns are matched in dir
+// (the current directory, if it's empty), and their matches are relative
+// to it.
+func globFiles(dir string, patterns []string) ([]string, error) {
+	matches := []string{}
+	seen := map[string]bool{}
+
+	for _, pattern := range patterns {
+		if negated := strings.TrimPrefix(pattern, "!"); negated != pattern {
+			kept, err := excludeFiles(matches, negated)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, filename := range matches {
+				delete(seen, filename)
+			}
+
+			for _, filename := range kept {
+				seen[filename] = true
+			}
+
+			matches = kept
+
+			continue
+		}
+
+		found, err := globPattern(dir, pattern)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %q: %w", pattern, err)
+		}
+
+		sort.Strings(found)
+
+		for _, filename := range found {
+			if !seen[filename] {
+				seen[filename] = true
+				matches = append(matches, filename)
+			}
+		}
+	}
+
+	return matches, nil
+}
+
+// globPattern returns regular files matching a glob pattern. Relative
+// patterns are matched in dir, without interpreting dir as a pattern.
+func globPattern(dir, pattern string) ([]string, error) {
+	if filepath.IsAbs(pattern) {
+		return doublestar.FilepathGlob(pattern, doublestar.WithFilesOnly())
+	}
+
+	if dir == "" {
+		dir = "."
+	}
+
+	found, err := doublestar.Glob(os.DirFS(dir), path.Clean(filepath.ToSlash(pattern)), doublestar.WithFilesOnly())
+	if err != nil {
+		return nil, err
+	}
+
+	for idx := range found {
+		found[idx] = filepath.FromSlash(found[idx])
+	}
+
+	return found, nil
+}
+
+// excludeFiles returns files not matching a glob pattern
+func excludeFiles(files []string, pattern string) ([]string, error) {
+	pattern = filepath.Clean(pattern)
+	if !doublestar.ValidatePathPattern(pattern) {
+		return nil, fmt.Errorf("pattern %q: %w", "!"+pattern, doublestar.ErrBadPattern)
+	}
+
+	kept := []string{}
+
+	for _, filename := range files {
+		if ok, _ := doublestar.PathMatch(pattern, filename); !ok {
+			kept = append(kept, filename)
+		}
+	}
+
+	return kept, nil
+}
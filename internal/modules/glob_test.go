@@ -0,0 +1,68 @@
+package modules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_globFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, name := range []string{"README.md", "LICENSE", "docs/guide.md", "docs/api/index.md", "docs/api/index_test.md"} {
+		fn := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(fn), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(fn, []byte(name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		patterns []string
+		want     []string
+		wantErr  bool
+	}{
+		{name: "plain", patterns: []string{"./README*"}, want: []string{"README.md"}},
+		{name: "doublestar", patterns: []string{"docs/**"}, want: []string{"docs/api/index.md", "docs/api/index_test.md", "docs/guide.md"}},
+		{name: "alternation", patterns: []string{"{LICENSE,README.md}"}, want: []string{"LICENSE", "README.md"}},
+		{name: "duplicates", patterns: []string{"**/*.md", "README.md"}, want: []string{"README.md", "docs/api/index.md", "docs/api/index_test.md", "docs/guide.md"}},
+		{name: "negation", patterns: []string{"**/*.md", "!**/*_test.md", "!README.md"}, want: []string{"docs/api/index.md", "docs/guide.md"}},
+		{name: "re-added", patterns: []string{"docs/**", "!docs/api/**", "docs/api/index.md"}, want: []string{"docs/guide.md", "docs/api/index.md"}},
+		{name: "bad pattern", patterns: []string{"docs/[a"}, wantErr: true},
+		{name: "bad negation", patterns: []string{"!docs/[a"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := globFiles(dir, tt.patterns)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("globFiles() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("globFiles() = %v, want %v", got, tt.want)
+			}
+
+			for idx := range tt.want {
+				if got[idx] != filepath.FromSlash(tt.want[idx]) {
+					t.Errorf("globFiles() = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+
+	abs := filepath.Join(dir, "docs", "*.md")
+	if got, err := globFiles("", []string{abs}); err != nil || len(got) != 1 || got[0] != filepath.Join(dir, "docs", "guide.md") {
+		t.Errorf("globFiles(%q) = %v, %v", abs, got, err)
+	}
+}
@@ -74,6 +74,15 @@ func (tar *goSingleTarget) Setup(cx context.Context) error {
 
 	td.OSArch = tar.osarch
 
+	skip, err := td.ParseBool("build:go", tar.mod.SkipIf)
+	if err != nil {
+		return fmt.Errorf("cannot render skip_if: %w", err)
+	}
+
+	if skip {
+		return ErrSkippedTarget
+	}
+
 	for _, item := range tasks {
 		(*item.target), err = td.Parse("build:go", item.source)
 		if err != nil {
@@ -109,17 +118,25 @@ func (tar *goSingleTarget) Run(cx context.Context) error {
 
 	output := path.Join(tar.OutDir, tar.Output)
 
-	if err := tar.Env.Run("go", "build", "-o", output, "-ldflags", tar.LDFlags, tar.Main); err != nil {
+	context.Progress.SetState(fmt.Sprintf("building %s", tar.OSArch()))
+
+	if err := ctx.RunCommand(cx, tar.Env, "go", "build", "-o", output, "-ldflags", tar.LDFlags, tar.Main); err != nil {
 		_ = os.Remove(output)
 		return err
 	}
 
-	context.Artifacts.Add(&ctx.Artifact{
+	artifact := &ctx.Artifact{
 		Filename: tar.Output,
 		Location: output,
 		ID:       tar.ID,
 		OsArch:   tar.osarch,
-	})
+	}
+
+	if err := modules.RecordDigest(artifact); err != nil {
+		return err
+	}
+
+	context.Artifacts.Add(artifact)
 
 	return nil
 }
@@ -41,7 +41,7 @@ type Go struct {
 	// (as well as `main` function) is defined.
 	Main string
 	// Output is where the build writes its output. Default:
-	// `{{.ProjectName}}{{.Ext}}`
+	// `{{.ProjectName}}{{.Exe}}`
 	Output string
 	// Skip specifies GOOS-GOArch combinations to be skipped.
 	// They are in `{{.Os}}-{{.Arch}}` format.
@@ -58,6 +58,11 @@ type Go struct {
 	//
 	// will run builds for linux-amd64, windows-amd64, and windows-386 only.
 	Skip []string
+	// SkipIf is a `modules.TemplateData` template, evaluated for each
+	// target. The target is skipped if it renders to a truthy value.
+	//
+	// Eg. `{{ and (eq OS "windows") (eq Arch "arm") }}`
+	SkipIf string `yaml:"skip_if"`
 }
 
 // nolint: gochecknoinits
@@ -78,7 +83,7 @@ func NewGo() modules.Pluggable {
 		GOArm:   []int32{6},
 		Main:    ".",
 		ID:      "default",
-		Output:  "{{.ProjectName}}{{OSExt}}",
+		Output:  "{{.ProjectName}}{{.Exe}}",
 	}
 }
 
@@ -119,7 +124,7 @@ func (mod *Go) runHooks(cx context.Context, hooks []string) error {
 
 	for _, hook := range hooks {
 		args := strings.Fields(hook)
-		if err := context.Env.Run(args[0], args[1:]...); err != nil {
+		if err := ctx.RunCommand(cx, context.Env, args[0], args[1:]...); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,171 @@
+package modules
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+// GoMod is a setup module, which makes sure go module dependencies are in
+// a clean state before building release artifacts.
+type GoMod struct {
+	// Dir is the directory where go.mod can be found. Default: ".".
+	Dir string
+	// Tidy checks whether `go mod tidy` would change go.mod or go.sum,
+	// by tidying copies of them, and fails with a diff if they are not
+	// tidy. The files are not modified. Default: true.
+	Tidy bool
+	// Verify runs `go mod verify`, checking downloaded module hashes.
+	// Default: true.
+	Verify bool
+}
+
+// NewGoMod is a factory method for GoMod module
+func NewGoMod() modules.Pluggable {
+	return &GoMod{
+		Dir:    ".",
+		Tidy:   true,
+		Verify: true,
+	}
+}
+
+// Run checks go.mod / go.sum state
+func (mod *GoMod) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	if mod.Tidy {
+		if err := mod.checkTidy(cx, context); err != nil {
+			return err
+		}
+	}
+
+	if mod.Verify {
+		if out, err := mod.goCmd(cx, context, "mod", "verify"); err != nil {
+			return fmt.Errorf("go mod verify: %w\n%s", err, out)
+		}
+	}
+
+	return nil
+}
+
+// checkTidy runs `go mod tidy` on copies of go.mod, and go.sum (see
+// -modfile), and compares them with the originals, which are left intact
+func (mod *GoMod) checkTidy(cx context.Context, context *ctx.Context) error {
+	tmp, err := context.TempDir("gomod")
+	if err != nil {
+		return err
+	}
+
+	files := []string{"go.mod", "go.sum"}
+	originals := make(map[string][]byte, len(files))
+
+	for _, name := range files {
+		fn := filepath.Join(mod.dir(context), name)
+
+		content, err := os.ReadFile(fn)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", fn, err)
+		}
+
+		originals[name] = content
+
+		if err := os.WriteFile(filepath.Join(tmp, name), content, 0o600); err != nil {
+			return fmt.Errorf("copying %s: %w", fn, err)
+		}
+	}
+
+	if out, err := mod.goCmd(cx, context, "mod", "tidy", "-modfile="+filepath.Join(tmp, "go.mod")); err != nil {
+		return fmt.Errorf("go mod tidy: %w\n%s", err, out)
+	}
+
+	var diffs []string
+
+	for _, name := range files {
+		fn := filepath.Join(tmp, name)
+
+		content, err := os.ReadFile(fn)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("reading %s: %w", fn, err)
+		}
+
+		if !bytes.Equal(content, originals[name]) {
+			diffs = append(diffs, lineDiff(name, originals[name], content))
+		}
+	}
+
+	if len(diffs) > 0 {
+		return fmt.Errorf("go modules are not tidy:\n%s", strings.Join(diffs, "\n"))
+	}
+
+	log.Printf("go modules are tidy")
+
+	return nil
+}
+
+func (mod *GoMod) goCmd(cx context.Context, context *ctx.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(cx, "go", args...)
+	cmd.Dir = mod.dir(context)
+	cmd.Env = context.Env.Environ()
+
+	out, err := cmd.CombinedOutput()
+
+	return string(out), err
+}
+
+// dir returns the module's directory, relative to the project's
+func (mod *GoMod) dir(context *ctx.Context) string {
+	if mod.Dir == "" {
+		return context.Path(".")
+	}
+
+	return context.Path(mod.Dir)
+}
+
+// lineDiff provides a simple, line-based difference of two file versions
+func lineDiff(name string, before, after []byte) string {
+	beforeLines := strings.Split(string(before), "\n")
+	afterLines := strings.Split(string(after), "\n")
+	seen := make(map[string]bool, len(afterLines))
+
+	for _, line := range afterLines {
+		seen[line] = true
+	}
+
+	out := []string{fmt.Sprintf("--- %s\n+++ %s (tidy)", name, name)}
+
+	for _, line := range beforeLines {
+		if !seen[line] {
+			out = append(out, "-"+line)
+		}
+	}
+
+	seen = make(map[string]bool, len(beforeLines))
+
+	for _, line := range beforeLines {
+		seen[line] = true
+	}
+
+	for _, line := range afterLines {
+		if !seen[line] {
+			out = append(out, "+"+line)
+		}
+	}
+
+	return strings.Join(out, "\n")
+}
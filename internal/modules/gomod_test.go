@@ -0,0 +1,81 @@
+package modules
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_lineDiff(t *testing.T) {
+	before := []byte("module a\n\nrequire (\n\tb v1.0.0\n\tc v1.0.0\n)\n")
+	after := []byte("module a\n\nrequire (\n\tb v1.0.0\n\td v1.2.0\n)\n")
+	want := "--- go.mod\n+++ go.mod (tidy)\n-\tc v1.0.0\n+\td v1.2.0"
+
+	if got := lineDiff("go.mod", before, after); got != want {
+		t.Errorf("lineDiff() = %q, want %q", got, want)
+	}
+}
+
+func TestGoMod_Run(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is not available")
+	}
+
+	tidy := "module example.com/app\n\ngo 1.17\n"
+	untidy := tidy + "\nrequire example.com/unused v0.0.0\n\nreplace example.com/unused => ./unused\n"
+
+	tests := []struct {
+		name    string
+		gomod   string
+		wantErr string
+	}{
+		{name: "tidy", gomod: tidy},
+		{name: "untidy", gomod: untidy, wantErr: "-require example.com/unused v0.0.0"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			cx, shipContext := testShipContext(t)
+			dir := t.TempDir()
+
+			files := map[string]string{
+				"go.mod":        tt.gomod,
+				"main.go":       "package main\n\nfunc main() {}\n",
+				"unused/go.mod": "module example.com/unused\n\ngo 1.17\n",
+			}
+
+			for name, content := range files {
+				fn := filepath.Join(dir, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(fn), 0o755); err != nil {
+					t.Fatal(err)
+				}
+
+				if err := os.WriteFile(fn, []byte(content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			shipContext.Dir = dir
+			mod := NewGoMod().(*GoMod)
+			mod.Verify = false
+
+			err := mod.Run(cx)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Run() error = %v, want %q", err, tt.wantErr)
+			}
+
+			content, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+			if err != nil || string(content) != tt.gomod {
+				t.Errorf("Run() changed go.mod: %q (%v)", content, err)
+			}
+
+			if _, err := os.Stat(filepath.Join(dir, "go.sum")); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("Run() left go.sum behind: %v", err)
+			}
+		})
+	}
+}
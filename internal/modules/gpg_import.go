@@ -0,0 +1,156 @@
+package modules
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+// GPGImport is a setup module importing an armored private key into a
+// GnuPG home directory dedicated to the run, therefore later modules (like
+// publish:apt, or publish:yum) can sign with it. The directory is set as
+// GNUPGHOME for later modules, and it is removed at the end of the
+// pipeline.
+type GPGImport struct {
+	// KeyEnv specifies the environment variable of the armored private
+	// key. Default: "GPG_PRIVATE_KEY".
+	KeyEnv string `yaml:"key_env"`
+	// KeyFile is the armored private key's file name, if it's not in
+	// the environment. Environment variables are expanded.
+	KeyFile string `yaml:"key_file"`
+}
+
+// NewGPGImport is a factory function for GPGImport module
+func NewGPGImport() modules.Pluggable {
+	return &GPGImport{KeyEnv: "GPG_PRIVATE_KEY"}
+}
+
+// Run imports the private key, and records its fingerprint
+func (mod *GPGImport) Run(cx context.Context) error {
+	shipContext, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	key, err := mod.key(shipContext)
+	if err != nil {
+		return err
+	}
+
+	home, err := os.MkdirTemp("", "goshipdone-gnupg-")
+	if err != nil {
+		return fmt.Errorf("creating gnupg home: %w", err)
+	}
+
+	// gpg refuses to use world-readable home directories
+	if err := os.Chmod(home, 0o700); err != nil {
+		_ = os.RemoveAll(home)
+		return fmt.Errorf("creating gnupg home: %w", err)
+	}
+
+	env := append(shipContext.Env.Environ(), "GNUPGHOME="+home)
+
+	shipContext.OnFinish("gpg import", func() error {
+		// the agent keeps running otherwise; the pipeline's context may be
+		// canceled already
+		cmd := exec.CommandContext(context.Background(), "gpgconf", "--kill", "gpg-agent")
+		cmd.Env = env
+		_ = cmd.Run()
+
+		return os.RemoveAll(home)
+	})
+
+	shipContext.Progress.SetState("importing private key")
+
+	if _, err := gpgCommand(cx, env, strings.NewReader(key), "--batch", "--import"); err != nil {
+		return fmt.Errorf("importing private key: %w", err)
+	}
+
+	out, err := gpgCommand(cx, env, nil, "--batch", "--with-colons", "--list-secret-keys")
+	if err != nil {
+		return fmt.Errorf("listing private keys: %w", err)
+	}
+
+	fingerprint := gpgFingerprint(out)
+	if fingerprint == "" {
+		return errors.New("no private key imported")
+	}
+
+	shipContext.Setenv("GNUPGHOME", home)
+	shipContext.GPGFingerprint = fingerprint
+
+	log.Printf("      imported private key %s", fingerprint)
+
+	return nil
+}
+
+// key returns the armored private key
+func (mod *GPGImport) key(context *ctx.Context) (string, error) {
+	if key, ok := context.LookupEnv(mod.KeyEnv); ok && key != "" {
+		return key, nil
+	}
+
+	if mod.KeyFile == "" {
+		return "", fmt.Errorf("private key not found in $%s", mod.KeyEnv)
+	}
+
+	filename := context.Path(context.Env.Expand(mod.KeyFile))
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("reading private key: %w", err)
+	}
+
+	return string(content), nil
+}
+
+// gpgFingerprint returns the fingerprint of the first secret key in
+// `gpg --with-colons --list-secret-keys` output
+func gpgFingerprint(listing string) string {
+	inKey := false
+
+	for _, line := range strings.Split(listing, "\n") {
+		fields := strings.Split(strings.TrimSpace(line), ":")
+
+		switch fields[0] {
+		case "sec":
+			inKey = true
+		case "ssb":
+			// fingerprints of subkeys follow
+			inKey = false
+		case "fpr":
+			if inKey && len(fields) > 9 {
+				return fields[9]
+			}
+		}
+	}
+
+	return ""
+}
+
+// gpgCommand runs a gpg command, returning its output
+func gpgCommand(cx context.Context, env []string, stdin io.Reader, args ...string) (string, error) {
+	out := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	cmd := exec.CommandContext(cx, "gpg", args...)
+	cmd.Env = env
+	cmd.Stdin = stdin
+	cmd.Stdout = out
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	return out.String(), nil
+}
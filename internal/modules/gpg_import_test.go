@@ -0,0 +1,21 @@
+package modules
+
+import "testing"
+
+func TestGPGFingerprint(t *testing.T) {
+	listing := `sec:u:255:22:1234567890ABCDEF:1700000000:::u:::scESC:::+:::ed25519:::0:
+fpr:::::::::0123456789ABCDEF0123456789ABCDEF01234567:
+grp:::::::::AAAA:
+uid:u::::1700000000::HASH::Release Signing <release@example.com>::::::::::0:
+ssb:u:255:18:FEDCBA0987654321:1700000000::::::e:::+:::cv25519::
+fpr:::::::::FEDCBA9876543210FEDCBA9876543210FEDCBA98:
+`
+
+	if got, want := gpgFingerprint(listing), "0123456789ABCDEF0123456789ABCDEF01234567"; got != want {
+		t.Errorf("gpgFingerprint() = %q, want %q", got, want)
+	}
+
+	if got := gpgFingerprint(""); got != "" {
+		t.Errorf("gpgFingerprint() of empty listing = %q", got)
+	}
+}
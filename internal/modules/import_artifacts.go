@@ -0,0 +1,143 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+// ImportArtifacts is a setup module registering pre-existing files (eg.
+// built by another system) as artifacts, so the pipeline can handle
+// archiving, signing, and publishing only. It can also import artifacts
+// of another pipeline run from its artifact manifest (see
+// ctx.ManifestFilename).
+type ImportArtifacts struct {
+	// Files specifies file name patterns of files to be imported.
+	// Required, unless Manifest is set.
+	Files []string
+	// ID is the artifact ID of imported files. Default: "default".
+	ID string
+	// Manifest is the file name of another run's artifact manifest (eg.
+	// "dist/artifacts.json"), whose artifacts are imported with their
+	// IDs, and platforms. Default: empty.
+	Manifest string
+	// Mapping maps file names to OS-arch combinations in
+	// `{{.OS}}-{{.ArchName}}` format (eg. "linux-armv7"), or "noarch".
+	// Files not listed here have their OS and architecture parsed from
+	// their names (eg. "app_linux_amd64").
+	Mapping map[string]string
+}
+
+// NewImportArtifacts is a factory method for ImportArtifacts module
+func NewImportArtifacts() modules.Pluggable {
+	return &ImportArtifacts{
+		ID:      "default",
+		Mapping: map[string]string{},
+	}
+}
+
+// DynamicArtifacts implements modules.DynamicArtifacts. Artifacts imported
+// from a manifest keep their IDs.
+func (mod *ImportArtifacts) DynamicArtifacts() bool {
+	return mod.Manifest != ""
+}
+
+// Run registers matching files as artifacts
+func (mod *ImportArtifacts) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	if len(mod.Files) == 0 && mod.Manifest == "" {
+		return fmt.Errorf("no files specified")
+	}
+
+	if mod.Manifest != "" {
+		if err := mod.importManifest(context); err != nil {
+			return err
+		}
+	}
+
+	for _, pattern := range mod.Files {
+		matches, err := filepath.Glob(context.Path(pattern))
+		if err != nil {
+			return fmt.Errorf("matching %s: %w", pattern, err)
+		}
+
+		if len(matches) == 0 {
+			return fmt.Errorf("no files match %s", pattern)
+		}
+
+		sort.Strings(matches)
+
+		for _, location := range matches {
+			filename := filepath.Base(location)
+
+			osarch, err := mod.osArch(filename)
+			if err != nil {
+				return err
+			}
+
+			context.Artifacts.Add(&ctx.Artifact{
+				Filename: filename,
+				ID:       mod.ID,
+				Location: location,
+				OsArch:   osarch,
+			})
+		}
+	}
+
+	return nil
+}
+
+// importManifest registers artifacts of an artifact manifest
+func (mod *ImportArtifacts) importManifest(context *ctx.Context) error {
+	manifest, err := ctx.ReadManifest(context.Path(mod.Manifest))
+	if err != nil {
+		return err
+	}
+
+	if manifest.Version != context.Version {
+		return fmt.Errorf("manifest %s is of version %s, not %s", mod.Manifest, manifest.Version, context.Version)
+	}
+
+	for _, artifact := range manifest.Artifacts {
+		if _, err := os.Stat(artifact.Location); err != nil {
+			return fmt.Errorf("importing %s: %w", artifact.Filename, err)
+		}
+
+		context.Artifacts.Add(artifact)
+	}
+
+	return nil
+}
+
+func (mod *ImportArtifacts) osArch(filename string) (*ctx.OsArch, error) {
+	if mapped, ok := mod.Mapping[filename]; ok {
+		if mapped == "noarch" {
+			return nil, nil
+		}
+
+		parts := strings.SplitN(mapped, "-", 2)
+		if len(parts) == 2 {
+			if osarch := ctx.ParseOsArch(parts[0], parts[1]); osarch != nil {
+				return osarch, nil
+			}
+		}
+
+		return nil, fmt.Errorf("invalid OS-arch mapping for %s: %q", filename, mapped)
+	}
+
+	if osarch := ctx.OsArchFromFilename(filename); osarch != nil {
+		return osarch, nil
+	}
+
+	return nil, fmt.Errorf("cannot detect OS and architecture of %s, please add it to mapping", filename)
+}
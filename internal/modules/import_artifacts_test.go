@@ -0,0 +1,121 @@
+package modules
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/julian7/goshipdone/ctx"
+)
+
+func TestImportArtifacts_Run(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, name := range []string{"app_linux_amd64", "app-darwin-arm64", "app_linux_armv7", "app.exe", "README.md"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cx := ctx.New(context.Background())
+
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mod := NewImportArtifacts().(*ImportArtifacts)
+	mod.Files = []string{filepath.Join(dir, "app*")}
+	mod.Mapping["app.exe"] = "windows-386"
+
+	if err := mod.Run(cx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	want := ctx.Artifacts{
+		{Filename: "app-darwin-arm64", ID: "default", Location: filepath.Join(dir, "app-darwin-arm64"), OsArch: &ctx.OsArch{OS: "darwin", Arch: "arm64"}},
+		{Filename: "app.exe", ID: "default", Location: filepath.Join(dir, "app.exe"), OsArch: &ctx.OsArch{OS: "windows", Arch: "386"}},
+		{Filename: "app_linux_amd64", ID: "default", Location: filepath.Join(dir, "app_linux_amd64"), OsArch: &ctx.OsArch{OS: "linux", Arch: "amd64"}},
+		{Filename: "app_linux_armv7", ID: "default", Location: filepath.Join(dir, "app_linux_armv7"), OsArch: &ctx.OsArch{OS: "linux", Arch: "arm", ArmVersion: 7}},
+	}
+
+	// sizes, and modification times are recorded at registration
+	for _, art := range want {
+		if err := art.Stat(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if diff := deep.Equal(context.Artifacts, want); diff != nil {
+		t.Error(diff)
+	}
+
+	mod.Files = []string{filepath.Join(dir, "README.md")}
+	if err := mod.Run(cx); err == nil {
+		t.Error("Run() succeeded on a file without OS and architecture")
+	}
+}
+
+func TestImportArtifacts_Run_manifest(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := os.MkdirAll(filepath.Join(dir, "linux"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "linux", "app"), []byte("app"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	built := &ctx.Context{TargetDir: dir, Version: "1.2.3"}
+	built.Artifacts = ctx.Artifacts{
+		{Filename: "app", ID: "default", Location: filepath.Join(dir, "linux", "app"), OsArch: &ctx.OsArch{OS: "linux", Arch: "amd64"}},
+	}
+
+	if err := built.WriteManifest(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the target directory is handed over to another job
+	moved := filepath.Join(t.TempDir(), "dist")
+	if err := os.Rename(dir, moved); err != nil {
+		t.Fatal(err)
+	}
+
+	cx := ctx.New(context.Background())
+
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mod := NewImportArtifacts().(*ImportArtifacts)
+	mod.Manifest = filepath.Join(moved, ctx.ManifestFilename)
+
+	if err := mod.Run(cx); err == nil {
+		t.Error("Run() succeeded importing a manifest of another version")
+	}
+
+	context.Version = "1.2.3"
+
+	if err := mod.Run(cx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	want := ctx.Artifacts{
+		{Filename: "app", ID: "default", Location: filepath.Join(moved, "linux", "app"), OsArch: &ctx.OsArch{OS: "linux", Arch: "amd64"}},
+	}
+
+	// sizes, and modification times are recorded at registration
+	for _, art := range want {
+		if err := art.Stat(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if diff := deep.Equal(context.Artifacts, want); diff != nil {
+		t.Error(diff)
+	}
+}
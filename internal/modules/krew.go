@@ -0,0 +1,246 @@
+package modules
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// Krew is a publish module generating a krew plugin manifest for
+	// kubectl plugins, optionally submitting it as a pull request against
+	// kubernetes-sigs/krew-index.
+	Krew struct {
+		// Artifacts is a template expression selecting artifacts of
+		// Builds (or all artifacts, if Builds is empty), evaluated for
+		// each artifact. See modules.SelectArtifacts.
+		Artifacts string
+		// Bin is the plugin executable's path in archives (template,
+		// rendered for each artifact). Default: "{{.ProjectName}}{{.Exe}}".
+		Bin string
+		// Builds specifies archives to be installed. Default: ["archive"].
+		Builds []string
+		// Caveats are shown after installation. Default: empty.
+		Caveats string
+		// CommitAuthor is the pull request commit's author in
+		// `Name <email>` format. Git config is used if empty.
+		CommitAuthor string `yaml:"commit_author"`
+		// Description is the plugin's long description. Default:
+		// ShortDescription.
+		Description string
+		// Homepage is the plugin's home page. Default: empty.
+		Homepage string
+		// ID is the artifact ID of the generated manifest. Default: "krew".
+		ID string
+		// Name is the plugin's name (template), without "kubectl-"
+		// prefix. Default: "{{.ProjectName}}" without "kubectl-" prefix.
+		Name string
+		// PullRequest submits the manifest to krew-index, if set.
+		// Default repository: kubernetes-sigs/krew-index.
+		PullRequest *GitHubPullRequest `yaml:"pull_request"`
+		// ShortDescription is the plugin's description. Required.
+		ShortDescription string `yaml:"short_description"`
+		// Skip specifies GOOS-GOArch combinations to be skipped.
+		// They are in `{{.Os}}-{{.Arch}}` format.
+		Skip []string
+		// URLTemplate renders download URLs of artifacts. Download links
+		// of previous publishers are used if empty.
+		URLTemplate string `yaml:"url_template"`
+	}
+
+	krewPlugin struct {
+		APIVersion string       `yaml:"apiVersion"`
+		Kind       string       `yaml:"kind"`
+		Metadata   krewMetadata `yaml:"metadata"`
+		Spec       krewSpec     `yaml:"spec"`
+	}
+
+	krewMetadata struct {
+		Name string `yaml:"name"`
+	}
+
+	krewSpec struct {
+		Version          string          `yaml:"version"`
+		Homepage         string          `yaml:"homepage,omitempty"`
+		ShortDescription string          `yaml:"shortDescription"`
+		Description      string          `yaml:"description,omitempty"`
+		Caveats          string          `yaml:"caveats,omitempty"`
+		Platforms        []*krewPlatform `yaml:"platforms"`
+	}
+
+	krewPlatform struct {
+		Selector krewSelector `yaml:"selector"`
+		URI      string       `yaml:"uri"`
+		SHA256   string       `yaml:"sha256"`
+		Bin      string       `yaml:"bin"`
+	}
+
+	krewSelector struct {
+		MatchLabels map[string]string `yaml:"matchLabels"`
+	}
+)
+
+// NewKrew is a factory function for Krew module
+func NewKrew() modules.Pluggable {
+	return &Krew{
+		Bin:    "{{.ProjectName}}{{.Exe}}",
+		Builds: []string{"archive"},
+		ID:     "krew",
+		Skip:   []string{},
+	}
+}
+
+// Run generates the plugin manifest, registers it as an artifact, and
+// submits it as a pull request if configured
+func (mod *Krew) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	name, content, err := mod.manifest(cx, context)
+	if err != nil {
+		return err
+	}
+
+	filename := name + ".yaml"
+	location := filepath.Join(context.TargetDir, "krew", filename)
+
+	if err := os.MkdirAll(filepath.Dir(location), 0o755); err != nil {
+		return fmt.Errorf("creating krew directory: %w", err)
+	}
+
+	if err := os.WriteFile(location, content, 0o644); err != nil { // nolint: gosec
+		return fmt.Errorf("writing %s: %w", filename, err)
+	}
+
+	context.Artifacts.Add(&ctx.Artifact{Filename: filename, ID: mod.ID, Location: location})
+
+	if mod.PullRequest == nil {
+		return nil
+	}
+
+	mod.PullRequest.defaults("kubernetes-sigs/krew-index")
+
+	branch := fmt.Sprintf("krew-%s-%s", name, context.Version)
+	title := fmt.Sprintf("Update %s to %s", name, context.Version)
+	files := map[string][]byte{"plugins/" + filename: content}
+
+	_, err = mod.PullRequest.submit(cx, context, branch, title, mod.CommitAuthor, files)
+
+	return err
+}
+
+// manifest renders the plugin manifest, returning the plugin's name too
+func (mod *Krew) manifest(cx context.Context, context *ctx.Context) (string, []byte, error) {
+	if mod.ShortDescription == "" {
+		return "", nil, errors.New("short_description is required")
+	}
+
+	td, err := modules.NewTemplate(cx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	name := strings.TrimPrefix(context.ProjectName, "kubectl-")
+
+	if mod.Name != "" {
+		if name, err = td.Parse("krew-name", mod.Name); err != nil {
+			return "", nil, fmt.Errorf("rendering name: %w", err)
+		}
+	}
+
+	platforms, err := mod.platforms(cx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	description := mod.Description
+	if description == "" {
+		description = mod.ShortDescription
+	}
+
+	// krew-index requires semver versions with "v" prefix
+	version := "v" + strings.TrimPrefix(context.Version, "v")
+
+	plugin := &krewPlugin{
+		APIVersion: "krew.googlecontainertools.github.com/v1alpha2",
+		Kind:       "Plugin",
+		Metadata:   krewMetadata{Name: name},
+		Spec: krewSpec{
+			Version:          version,
+			Homepage:         mod.Homepage,
+			ShortDescription: mod.ShortDescription,
+			Description:      description,
+			Caveats:          mod.Caveats,
+			Platforms:        platforms,
+		},
+	}
+
+	content := &bytes.Buffer{}
+
+	enc := yaml.NewEncoder(content)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(plugin); err != nil {
+		return "", nil, fmt.Errorf("encoding plugin manifest: %w", err)
+	}
+
+	return name, content.Bytes(), nil
+}
+
+func (mod *Krew) platforms(cx context.Context) ([]*krewPlatform, error) {
+	artifacts, err := packageArtifacts(cx, mod.Builds, mod.Skip, mod.Artifacts, mod.URLTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	td, err := modules.NewTemplate(cx)
+	if err != nil {
+		return nil, err
+	}
+
+	platforms := []*krewPlatform{}
+
+	for _, artifact := range artifacts {
+		if artifact.OsArch == nil {
+			continue
+		}
+
+		td.OSArch = artifact.OsArch
+		td.Ext = ""
+
+		if artifact.OS == "windows" {
+			td.Ext = ".exe"
+		}
+
+		bin, err := td.Parse("krew-bin", mod.Bin)
+		if err != nil {
+			return nil, fmt.Errorf("rendering bin of %s: %w", artifact.Filename, err)
+		}
+
+		platforms = append(platforms, &krewPlatform{
+			Selector: krewSelector{MatchLabels: map[string]string{
+				"os":   artifact.OS,
+				"arch": artifact.Arch,
+			}},
+			URI:    artifact.URL,
+			SHA256: artifact.SHA256,
+			Bin:    bin,
+		})
+	}
+
+	if len(platforms) == 0 {
+		return nil, errors.New("no platform-specific artifacts found")
+	}
+
+	return platforms, nil
+}
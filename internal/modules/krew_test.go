@@ -0,0 +1,85 @@
+package modules
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+func TestKrew_Run(t *testing.T) {
+	dir := t.TempDir()
+	cx := ctx.New(context.Background())
+
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	context.ProjectName = "kubectl-hello"
+	context.TargetDir = dir
+	context.Version = "1.2.3"
+
+	for _, art := range []*ctx.Artifact{
+		{Filename: "hello-linux-amd64.tar.gz", OsArch: &ctx.OsArch{OS: "linux", Arch: "amd64"}},
+		{Filename: "hello-windows-amd64.zip", OsArch: &ctx.OsArch{OS: "windows", Arch: "amd64"}},
+	} {
+		art.ID = "archive"
+		art.Location = filepath.Join(dir, art.Filename)
+
+		if err := os.WriteFile(art.Location, []byte("content"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		context.Artifacts.Add(art)
+	}
+
+	mod := NewKrew().(*Krew)
+	mod.Homepage = "https://example.com"
+	mod.ShortDescription = "Says hello"
+	mod.URLTemplate = "https://example.com/{{.Version}}/{{.Filename}}"
+
+	if err := mod.Run(cx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	manifest, err := os.ReadFile(filepath.Join(dir, "krew", "hello.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `apiVersion: krew.googlecontainertools.github.com/v1alpha2
+kind: Plugin
+metadata:
+  name: hello
+spec:
+  version: v1.2.3
+  homepage: https://example.com
+  shortDescription: Says hello
+  description: Says hello
+  platforms:
+    - selector:
+        matchLabels:
+          arch: amd64
+          os: linux
+      uri: https://example.com/1.2.3/hello-linux-amd64.tar.gz
+      sha256: ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73
+      bin: kubectl-hello
+    - selector:
+        matchLabels:
+          arch: amd64
+          os: windows
+      uri: https://example.com/1.2.3/hello-windows-amd64.zip
+      sha256: ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73
+      bin: kubectl-hello.exe
+`
+	if string(manifest) != want {
+		t.Errorf("manifest:\n%s\nwant:\n%s", manifest, want)
+	}
+
+	if got := len(*context.Artifacts.ByID("krew")); got != 1 {
+		t.Errorf("registered %d manifests, want 1", got)
+	}
+}
@@ -0,0 +1,154 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+const (
+	matrixMessage = `{{.ProjectName}} {{.Version}} has been released.{{with highlights 5 .Changelog}}
+{{range .}}
+- {{.}}{{end}}{{end}}{{with .Links}}
+{{range .}}
+{{.Name}}: {{.URL}}{{end}}{{end}}{{range .Artifacts}}{{if .URL}}
+{{.Filename}}: {{.URL}}{{end}}{{end}}`
+
+	matrixHTML = `<p><strong>{{.ProjectName}} {{.Version}}</strong> has been released.</p>
+{{with highlights 5 .Changelog}}<ul>{{range .}}
+<li>{{.}}</li>{{end}}
+</ul>
+{{end}}<p>{{range .Links}}<a href="{{.URL}}">{{.Name}}</a><br>
+{{end}}{{range .Artifacts}}{{if .URL}}<a href="{{.URL}}">{{.Filename}}</a><br>
+{{end}}{{end}}</p>`
+)
+
+// Matrix is a notify module posting release announcements into a Matrix
+// room
+type Matrix struct {
+	Announcement `yaml:",inline"`
+	// Homeserver is the URL of the homeserver, like "https://matrix.org".
+	// Required.
+	Homeserver string
+	// HTML is the formatted message (Go HTML template of WebhookPayload,
+	// with `highlights` function). Default: version, changelog highlights,
+	// release links, and download links.
+	HTML string
+	// MsgType is the message type: m.notice (usually rendered as bot
+	// messages), or m.text. Default: "m.notice".
+	MsgType string `yaml:"msg_type"`
+	// Room is the room's ID (like "!abc:matrix.org"), or alias (like
+	// "#announce:matrix.org"). The account must be a member of the room.
+	// Required.
+	Room string
+	// TokenEnv specifies the environment variable of the account's access
+	// token. Default: "MATRIX_ACCESS_TOKEN".
+	TokenEnv string `yaml:"token_env"`
+}
+
+// NewMatrix is a factory function for Matrix module
+func NewMatrix() modules.Pluggable {
+	return &Matrix{
+		Announcement: newAnnouncement(),
+		MsgType:      "m.notice",
+		TokenEnv:     "MATRIX_ACCESS_TOKEN",
+	}
+}
+
+// ValidateTemplates implements modules.TemplateValidator
+func (mod *Matrix) ValidateTemplates(cx context.Context) ([]string, error) {
+	skips, err := mod.Announcement.ValidateTemplates(cx)
+	if err != nil || mod.HTML == "" {
+		return append(skips, "HTML"), err
+	}
+
+	_, err = renderHTMLPayload("matrix-html", mod.HTML, sampleWebhookPayload())
+
+	return append(skips, "HTML"), err
+}
+
+// Run posts the announcement into the room
+func (mod *Matrix) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	if mod.Homeserver == "" {
+		return errors.New("homeserver is not specified")
+	}
+
+	if mod.Room == "" {
+		return errors.New("room is not specified")
+	}
+
+	token, err := context.RequireEnv(mod.TokenEnv, "matrix access token")
+	if err != nil {
+		return err
+	}
+
+	payload, err := mod.payload(cx, context)
+	if err != nil {
+		return err
+	}
+
+	message, err := mod.message(payload, "matrix", matrixMessage)
+	if err != nil {
+		return err
+	}
+
+	html := mod.HTML
+	if html == "" {
+		html = matrixHTML
+	}
+
+	formatted, err := renderHTMLPayload("matrix-html", html, payload)
+	if err != nil {
+		return err
+	}
+
+	api := strings.TrimSuffix(mod.Homeserver, "/") + "/_matrix/client/v3"
+	headers := map[string]string{"Authorization": "Bearer " + token}
+
+	context.Progress.SetState(fmt.Sprintf("posting announcement to %s", mod.Room))
+
+	room := mod.Room
+	if strings.HasPrefix(room, "#") {
+		var alias struct {
+			RoomID string `json:"room_id"`
+		}
+
+		err := requestJSON(cx, http.MethodGet, api+"/directory/room/"+neturl.PathEscape(room), headers, nil, &alias)
+		if err != nil {
+			return fmt.Errorf("resolving room alias %s: %w", room, err)
+		}
+
+		room = alias.RoomID
+	}
+
+	// transaction IDs must be unique for the access token
+	url := fmt.Sprintf(
+		"%s/rooms/%s/send/m.room.message/%s",
+		api,
+		neturl.PathEscape(room),
+		neturl.PathEscape(context.ProjectName+"-"+context.Version+"-"+context.Random.Hex(8)),
+	)
+
+	err = requestJSON(cx, http.MethodPut, url, headers, map[string]string{
+		"body":           message,
+		"format":         "org.matrix.custom.html",
+		"formatted_body": strings.TrimSpace(formatted),
+		"msgtype":        mod.MsgType,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("posting announcement to %s: %w", mod.Room, err)
+	}
+
+	return nil
+}
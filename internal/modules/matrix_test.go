@@ -0,0 +1,75 @@
+package modules
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+func TestMatrix_Run(t *testing.T) {
+	var (
+		body   map[string]string
+		method string
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, `{"errcode":"M_UNKNOWN_TOKEN"}`, http.StatusUnauthorized)
+			return
+		}
+
+		switch {
+		case r.URL.Path == "/_matrix/client/v3/directory/room/#announce:example.com":
+			_, _ = w.Write([]byte(`{"room_id":"!room:example.com"}`))
+		case strings.HasPrefix(r.URL.Path, "/_matrix/client/v3/rooms/!room:example.com/send/m.room.message/app-v1.2.3-"):
+			method = r.Method
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			_, _ = w.Write([]byte(`{"event_id":"$event"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cx := ctx.New(context.Background())
+
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	context.ProjectName = "app"
+	context.Version = "v1.2.3"
+	context.Env.Set("MATRIX_ACCESS_TOKEN", "token")
+	context.Published.AddLink("Release", "https://example.com/releases/v1.2.3?a=1&b=2")
+
+	mod := NewMatrix().(*Matrix)
+	mod.Homeserver = srv.URL
+	mod.Room = "#announce:example.com"
+
+	if err := mod.Run(cx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if method != http.MethodPut {
+		t.Errorf("method = %q", method)
+	}
+
+	want := map[string]string{
+		"body":   "app v1.2.3 has been released.\n\nRelease: https://example.com/releases/v1.2.3?a=1&b=2",
+		"format": "org.matrix.custom.html",
+		"formatted_body": "<p><strong>app v1.2.3</strong> has been released.</p>\n" +
+			`<p><a href="https://example.com/releases/v1.2.3?a=1&amp;b=2">Release</a><br>` + "\n</p>",
+		"msgtype": "m.notice",
+	}
+	for key, value := range want {
+		if body[key] != value {
+			t.Errorf("%s = %q, want %q", key, body[key], value)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+// Minisign is a module for signing artifacts with `minisign` tool.
+type Minisign struct {
+	// Artifacts is a template expression selecting artifacts of Builds
+	// (or all artifacts, if Builds is empty), evaluated for each artifact.
+	// See modules.SelectArtifacts.
+	Artifacts string
+	// Builds specifies which build names should be signed.
+	Builds []string
+	// ID contains the signature artifacts' name used by later stages of
+	// the pipeline. Default: "minisig".
+	ID string
+	// KeyEnv specifies an environment variable containing the secret key.
+	// It takes precedence over KeyFile. Default: "MINISIGN_KEY".
+	KeyEnv string `yaml:"key_env"`
+	// KeyFile specifies the secret key file. Variable expansion is
+	// available. Default: "$HOME/.minisign/minisign.key".
+	KeyFile string `yaml:"key_file"`
+	// Output is the signature file name template. Default:
+	// `{{.Filename}}.minisig`.
+	Output string
+	// PasswordEnv specifies an environment variable containing the secret
+	// key's password. The key is considered unencrypted if the variable is
+	// not set. Default: "MINISIGN_PASSWORD".
+	PasswordEnv string `yaml:"password_env"`
+	// Skip specifies GOOS-GOArch combinations to be skipped.
+	Skip []string
+	// TrustedComment is a template of the signed comment. Default:
+	// `{{.ProjectName}} {{.Version}} {{.Filename}} {{.BuildDate.Format "2006-01-02"}}`.
+	TrustedComment string `yaml:"trusted_comment"`
+}
+
+// NewMinisign is a factory function for Minisign module
+func NewMinisign() modules.Pluggable {
+	return &Minisign{
+		ID:             "minisig",
+		KeyEnv:         "MINISIGN_KEY",
+		KeyFile:        "$HOME/.minisign/minisign.key",
+		Output:         "{{.Filename}}.minisig",
+		PasswordEnv:    "MINISIGN_PASSWORD",
+		TrustedComment: `{{.ProjectName}} {{.Version}} {{.Filename}} {{.BuildDate.Format "2006-01-02"}}`,
+	}
+}
+
+// Run signs artifacts
+func (mod *Minisign) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	keyFile, err := mod.keyFile(context)
+	if err != nil {
+		return err
+	}
+
+	builds, err := modules.SelectArtifacts(cx, mod.Builds, mod.Skip, mod.Artifacts)
+	if err != nil {
+		return err
+	}
+
+	for _, build := range builds {
+		for _, art := range *build {
+			if err := mod.sign(cx, keyFile, art); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (mod *Minisign) keyFile(context *ctx.Context) (string, error) {
+	key, ok := context.LookupEnv(mod.KeyEnv)
+	if !ok || key == "" {
+		if mod.KeyFile == "" {
+			return "", errors.New("no minisign key provided")
+		}
+
+		return context.Path(context.Env.Expand(mod.KeyFile)), nil
+	}
+
+	// the key must not be left in the target directory
+	dir, err := context.SecretDir("minisign")
+	if err != nil {
+		return "", err
+	}
+
+	keyFile := filepath.Join(dir, "minisign.key")
+
+	if err := os.WriteFile(keyFile, []byte(strings.TrimSpace(key)+"\n"), 0o600); err != nil {
+		return "", fmt.Errorf("writing minisign key: %w", err)
+	}
+
+	return keyFile, nil
+}
+
+func (mod *Minisign) sign(cx context.Context, keyFile string, art *ctx.Artifact) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	td, err := modules.NewTemplate(cx)
+	if err != nil {
+		return err
+	}
+
+	td.OSArch = art.OsArch
+	td.Filename = art.Filename
+
+	sigName, err := td.Parse("minisign-output", mod.Output)
+	if err != nil {
+		return fmt.Errorf("rendering %q: %w", mod.Output, err)
+	}
+
+	comment, err := td.Parse("minisign-trusted-comment", mod.TrustedComment)
+	if err != nil {
+		return fmt.Errorf("rendering %q: %w", mod.TrustedComment, err)
+	}
+
+	sigLocation := path.Join(context.TargetDir, sigName)
+	args := []string{"-S", "-s", keyFile, "-m", art.Location, "-x", sigLocation, "-t", comment}
+
+	password, hasPassword := context.LookupEnv(mod.PasswordEnv)
+	if !hasPassword {
+		args = append(args, "-W")
+	}
+
+	cmd := exec.CommandContext(cx, ctx.LookPath(context.Env, "minisign"), args...)
+	cmd.Dir = context.Dir
+	cmd.Env = context.Env.Environ()
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if hasPassword {
+		cmd.Stdin = strings.NewReader(password + "\n")
+	}
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("signing %s: %w", art.Location, err)
+	}
+
+	context.Artifacts.Add(&ctx.Artifact{
+		Filename: sigName,
+		ID:       mod.ID,
+		Location: sigLocation,
+		OsArch:   art.OsArch,
+		Parents:  []string{art.Filename},
+	})
+
+	return nil
+}
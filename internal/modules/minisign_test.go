@@ -0,0 +1,136 @@
+package modules
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+// fakeMinisign copies the secret key to $KEY_COPY, and its stdin to
+// $STDIN_COPY, and writes the signature
+const fakeMinisign = `cat > "$STDIN_COPY"
+while [ $# -gt 0 ]; do
+	case "$1" in
+	-s) shift; cp "$1" "$KEY_COPY" ;;
+	-x) shift; echo signature > "$1" ;;
+	esac
+	shift
+done`
+
+func TestMinisign_Run(t *testing.T) {
+	linux := &ctx.OsArch{OS: "linux", Arch: "amd64"}
+
+	tests := []struct {
+		name      string
+		env       map[string]string
+		keyFile   string
+		script    string
+		wantArgs  string
+		wantKey   string
+		wantStdin string
+		wantErr   string
+	}{
+		{
+			name:      "key from env",
+			env:       map[string]string{"MINISIGN_KEY": "secret key\n", "MINISIGN_PASSWORD": "pass"},
+			wantArgs:  "-S -s KEY -m TARGET/app.tar.gz -x TARGET/app.tar.gz.minisig -t app v1.0.0 app.tar.gz",
+			wantKey:   "secret key\n",
+			wantStdin: "pass\n",
+		},
+		{
+			name:     "unencrypted key file",
+			keyFile:  "KEYDIR/minisign.key",
+			wantArgs: "-S -s KEYDIR/minisign.key -m TARGET/app.tar.gz -x TARGET/app.tar.gz.minisig -t app v1.0.0 app.tar.gz -W",
+			wantKey:  "key file\n",
+		},
+		{name: "no key", wantErr: "no minisign key provided"},
+		{name: "failing minisign", keyFile: "KEYDIR/minisign.key", script: "exit 1", wantErr: "signing"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			script := fakeMinisign
+			if tt.script != "" {
+				script = tt.script
+			}
+
+			calls := fakeCommand(t, "minisign", script)
+			cx, shipContext := testShipContext(t)
+			addTestArtifact(t, shipContext, "archive", "app.tar.gz", linux)
+
+			copies := t.TempDir()
+			shipContext.Env.Set("KEY_COPY", filepath.Join(copies, "key"))
+			shipContext.Env.Set("STDIN_COPY", filepath.Join(copies, "stdin"))
+
+			for key, value := range tt.env {
+				shipContext.Env.Set(key, value)
+			}
+
+			keyDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(keyDir, "minisign.key"), []byte("key file\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			mod := NewMinisign().(*Minisign)
+			mod.Builds = []string{"archive"}
+			mod.KeyFile = strings.ReplaceAll(tt.keyFile, "KEYDIR", keyDir)
+			mod.TrustedComment = "{{.ProjectName}} {{.Version}} {{.Filename}}"
+
+			err := mod.Run(cx)
+			shipContext.Finish()
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Run() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			got := fakeCalls(t, calls)
+			if len(got) != 1 {
+				t.Fatalf("minisign called %d times, want once", len(got))
+			}
+
+			keyFile := strings.Fields(got[0])[2]
+
+			want := strings.NewReplacer(
+				"TARGET", shipContext.TargetDir,
+				"KEYDIR", keyDir,
+				"KEY", keyFile,
+			).Replace(tt.wantArgs)
+			if got[0] != want {
+				t.Errorf("minisign args = %q, want %q", got[0], want)
+			}
+
+			if rel, err := filepath.Rel(shipContext.TargetDir, keyFile); err == nil && !strings.HasPrefix(rel, "..") {
+				t.Errorf("secret key written into target directory: %s", keyFile)
+			}
+
+			if tt.keyFile == "" {
+				if _, err := os.Stat(keyFile); err == nil {
+					t.Errorf("secret key %s kept after the run", keyFile)
+				}
+			}
+
+			for fn, want := range map[string]string{"key": tt.wantKey, "stdin": tt.wantStdin} {
+				if content, _ := os.ReadFile(filepath.Join(copies, fn)); string(content) != want {
+					t.Errorf("minisign got %s %q, want %q", fn, content, want)
+				}
+			}
+
+			if sig := shipContext.Artifacts.ByFilename("app.tar.gz.minisig"); sig == nil || sig.ID != "minisig" {
+				t.Errorf("Run() registered signature %v", sig)
+			}
+		})
+	}
+}
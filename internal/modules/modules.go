@@ -4,18 +4,69 @@ import "github.com/julian7/goshipdone/modules"
 
 func Register() {
 	for _, mod := range []*modules.ModuleRegistration{
+		{Stage: "*", Type: "plugin", Factory: modules.V2Factory(NewPlugin)},
 		{Stage: "*", Type: "show", Factory: NewShow},
+		{Stage: "setup", Type: "calver", Factory: NewCalVer},
+		{Stage: "setup", Type: "ci", Factory: NewCI},
+		{Stage: "setup", Type: "dist", Factory: NewDist},
+		{Stage: "setup", Type: "docker_login", Factory: NewDockerLogin},
 		{Stage: "setup", Type: "env", Factory: NewEnv},
 		{Stage: "setup", Type: "git", Factory: NewGit},
+		{Stage: "setup", Type: "git_state", Factory: NewGitState},
+		{Stage: "setup", Type: "gomod", Factory: NewGoMod},
+		{Stage: "setup", Type: "gpg_import", Factory: NewGPGImport},
+		{Stage: "setup", Type: "import_artifacts", Factory: NewImportArtifacts},
+		{Stage: "setup", Type: "next_version", Factory: NewNextVersion},
+		{Stage: "setup", Type: "previous_tag", Factory: NewPreviousTag},
 		{Stage: "setup", Type: "project", Factory: NewProject},
+		{Stage: "setup", Type: "secrets", Factory: NewSecrets},
 		{Stage: "setup", Type: "skip_publish", Factory: NewSkipPublish},
+		{Stage: "setup", Type: "snapshot", Factory: NewSnapshot},
+		{Stage: "setup", Type: "tools", Factory: NewTools},
+		{Stage: "setup", Type: "vault", Factory: NewVault},
+		{Stage: "build", Type: "age", Factory: NewAge},
+		{Stage: "build", Type: "authenticode", Factory: NewAuthenticode},
 		{Stage: "build", Type: "changelog", Factory: NewCutChangelog},
 		{Stage: "build", Type: "checksum", Factory: NewChecksum},
+		{Stage: "build", Type: "conventional_changelog", Factory: NewConventionalChangelog},
+		{Stage: "build", Type: "cosign", Factory: NewCosign},
+		{Stage: "build", Type: "fake", Factory: NewFakeSign},
 		{Stage: "build", Type: "go", Factory: NewGo},
+		{Stage: "build", Type: "minisign", Factory: NewMinisign},
+		{Stage: "build", Type: "release_notes", Factory: NewReleaseNotes},
 		{Stage: "build", Type: "tar", Factory: NewTar},
 		{Stage: "build", Type: "upx", Factory: NewUPX},
+		{Stage: "build", Type: "verify_signatures", Factory: NewVerifySignatures},
+		{Stage: "notify", Type: "bluesky", Factory: NewBluesky},
+		{Stage: "notify", Type: "discord", Factory: NewDiscord},
+		{Stage: "notify", Type: "email", Factory: NewEmail},
+		{Stage: "notify", Type: "mastodon", Factory: NewMastodon},
+		{Stage: "notify", Type: "matrix", Factory: NewMatrix},
+		{Stage: "notify", Type: "slack", Factory: NewSlack},
+		{Stage: "notify", Type: "teams", Factory: NewTeams},
+		{Stage: "publish", Type: "apt", Factory: NewAPT},
 		{Stage: "publish", Type: "artifact", Factory: NewArtifact},
+		{Stage: "publish", Type: "aur", Factory: NewAUR},
+		{Stage: "publish", Type: "azblob", Factory: NewAzBlob},
+		{Stage: "publish", Type: "cosign", Factory: NewCosign},
+		{Stage: "publish", Type: "docker", Factory: NewDocker},
+		{Stage: "publish", Type: "fake", Factory: NewFakePublish},
+		{Stage: "publish", Type: "gcs", Factory: NewGCS},
+		{Stage: "publish", Type: "krew", Factory: NewKrew},
+		{Stage: "publish", Type: "oras", Factory: NewORAS},
+		{Stage: "publish", Type: "preflight", Factory: NewPreflight},
+		{Stage: "publish", Type: "promote", Factory: NewPromote},
+		{Stage: "publish", Type: "rsync", Factory: NewRsync},
+		{Stage: "publish", Type: "s3", Factory: NewS3},
 		{Stage: "publish", Type: "scp", Factory: NewSCP},
+		{Stage: "publish", Type: "verify_signatures", Factory: NewVerifySignatures},
+		{Stage: "publish", Type: "webhook", Factory: NewWebhook},
+		{Stage: "publish", Type: "winget", Factory: NewWinget},
+		{Stage: "publish", Type: "yum", Factory: NewYUM},
+		{Stage: "sign", Type: "cosign", Factory: NewCosign},
+		{Stage: "sign", Type: "fake", Factory: NewFakeSign},
+		{Stage: "sign", Type: "minisign", Factory: NewMinisign},
+		{Stage: "verify", Type: "signatures", Factory: NewVerifySignatures},
 	} {
 		modules.RegisterModule(mod)
 	}
@@ -0,0 +1,225 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+type (
+	// NextVersion is a setup module calculating the next version from
+	// conventional commits (https://www.conventionalcommits.org/) since the
+	// previous tag: breaking changes bump the major version, features bump
+	// the minor version, and any other commits bump the patch version. A
+	// pre-release tag is followed by its release version.
+	NextVersion struct {
+		VersionTag `yaml:",inline"`
+		// Initial is the version used if there are no previous tags.
+		// Default: "0.1.0".
+		Initial string
+		// Prefix is the prefix of tags. Default: "v".
+		Prefix string
+	}
+
+	// VersionTag configures tagging of calculated versions
+	VersionTag struct {
+		// Message is the tag's message (template of modules.TemplateData).
+		// Default: "Release {{.Version}}".
+		Message string
+		// Push pushes the new tag to Remote. It requires Tag. Default:
+		// false.
+		Push bool
+		// Remote is the git remote the tag is pushed to. Default: "origin".
+		Remote string
+		// Tag creates an annotated git tag of the calculated version on
+		// the current commit. Default: false.
+		Tag bool
+	}
+)
+
+// newVersionTag returns VersionTag defaults
+func newVersionTag() VersionTag {
+	return VersionTag{
+		Message: "Release {{.Version}}",
+		Remote:  "origin",
+	}
+}
+
+// NewNextVersion is a factory function for NextVersion module
+func NewNextVersion() modules.Pluggable {
+	return &NextVersion{
+		VersionTag: newVersionTag(),
+		Initial:    "0.1.0",
+		Prefix:     "v",
+	}
+}
+
+// Run calculates the next version, and records it into ctx.Context as
+// Version, and as the current tag
+func (mod *NextVersion) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	if err := mod.validate(); err != nil {
+		return err
+	}
+
+	if context.Git.Tag != "" {
+		log.Printf("      current commit is already tagged as %s", context.Git.Tag)
+		return nil
+	}
+
+	next, err := mod.next(cx, context)
+	if err != nil {
+		return err
+	}
+
+	context.Version = mod.Prefix + next.String()
+	context.Git.Tag = context.Git.TagPrefix + context.Version
+	context.Git.Semver = *next
+
+	log.Printf("      next version is %s", context.Version)
+
+	return mod.create(cx, context, context.Git.Tag)
+}
+
+// validate checks tagging options
+func (mod *VersionTag) validate() error {
+	if mod.Push && !mod.Tag {
+		return errors.New("push requires tag")
+	}
+
+	return nil
+}
+
+// create creates, and pushes the version's tag, if configured. It refuses
+// to tag a working tree with uncommitted changes, or to push a tag, which
+// already exists on the remote.
+func (mod *VersionTag) create(cx context.Context, context *ctx.Context, version string) error {
+	if !mod.Tag {
+		return nil
+	}
+
+	status, err := gitOutput(cx, context, "status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return fmt.Errorf("checking working tree: %w", err)
+	}
+
+	if strings.TrimSpace(status) != "" {
+		return fmt.Errorf("not tagging %s: working tree has uncommitted changes:\n%s", version, strings.TrimRight(status, "\n"))
+	}
+
+	if mod.Push {
+		remote, err := gitOutput(cx, context, "ls-remote", "--tags", mod.Remote, "refs/tags/"+version)
+		if err != nil {
+			return fmt.Errorf("checking tags of %s: %w", mod.Remote, err)
+		}
+
+		if strings.TrimSpace(remote) != "" {
+			return fmt.Errorf("not tagging %s: tag already exists on %s", version, mod.Remote)
+		}
+	}
+
+	td, err := modules.NewTemplate(cx)
+	if err != nil {
+		return err
+	}
+
+	message, err := td.Parse("version-tag-message", mod.Message)
+	if err != nil {
+		return fmt.Errorf("rendering tag message: %w", err)
+	}
+
+	if _, err := gitOutput(cx, context, "tag", "-a", version, "-m", message); err != nil {
+		return fmt.Errorf("tagging %s: %w", version, err)
+	}
+
+	if !mod.Push {
+		return nil
+	}
+
+	context.Progress.SetState(fmt.Sprintf("pushing %s to %s", version, mod.Remote))
+
+	if _, err := gitOutput(cx, context, "push", mod.Remote, "refs/tags/"+version); err != nil {
+		return fmt.Errorf("pushing %s to %s: %w", version, mod.Remote, err)
+	}
+
+	return nil
+}
+
+// next returns the next version, based on commits since the previous tag
+func (mod *NextVersion) next(cx context.Context, context *ctx.Context) (*ctx.Semver, error) {
+	tag, err := previousTag(cx, context)
+	if err != nil {
+		return nil, err
+	}
+
+	if tag == "" {
+		initial, err := ctx.ParseSemver(mod.Initial)
+		if err != nil {
+			return nil, fmt.Errorf("initial version: %w", err)
+		}
+
+		return initial, nil
+	}
+
+	last, err := ctx.ParseSemver(strings.TrimPrefix(tag, context.Git.TagPrefix+mod.Prefix))
+	if err != nil {
+		return nil, fmt.Errorf("previous tag: %w", err)
+	}
+
+	revisions := tag + "..HEAD"
+
+	count, err := gitOutput(cx, context, append([]string{"rev-list", "--count", revisions}, context.Git.Pathspec()...)...)
+	if err != nil {
+		return nil, fmt.Errorf("counting commits of %s: %w", revisions, err)
+	}
+
+	if n, err := strconv.Atoi(strings.TrimSpace(count)); err != nil || n == 0 {
+		return nil, fmt.Errorf("no commits since %s", tag)
+	}
+
+	out, err := gitLog(cx, context, revisions)
+	if err != nil {
+		return nil, err
+	}
+
+	return bumpSemver(last, parseConventionalCommits(out)), nil
+}
+
+// bumpSemver returns the release version following last, based on
+// conventional commits since last
+func bumpSemver(last *ctx.Semver, commits []*conventionalCommit) *ctx.Semver {
+	breaking := false
+	feature := false
+
+	for _, commit := range commits {
+		if commit.Breaking != "" {
+			breaking = true
+		}
+
+		if commit.Type == "feat" {
+			feature = true
+		}
+	}
+
+	switch {
+	case last.IsPrerelease():
+		// the release of a pre-release version
+		return last.IncPatch()
+	case breaking:
+		return last.IncMajor()
+	case feature:
+		return last.IncMinor()
+	default:
+		return last.IncPatch()
+	}
+}
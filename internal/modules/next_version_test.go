@@ -0,0 +1,174 @@
+package modules
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+func TestBumpSemver(t *testing.T) {
+	last := &ctx.Semver{Major: 1, Minor: 2, Patch: 3}
+
+	tests := []struct {
+		name    string
+		last    *ctx.Semver
+		commits []*conventionalCommit
+		want    string
+	}{
+		{name: "no conventional commits", last: last, want: "1.2.4"},
+		{name: "fix", last: last, commits: []*conventionalCommit{{Type: "fix"}}, want: "1.2.4"},
+		{name: "feature", last: last, commits: []*conventionalCommit{{Type: "fix"}, {Type: "feat"}}, want: "1.3.0"},
+		{
+			name:    "breaking change",
+			last:    last,
+			commits: []*conventionalCommit{{Type: "feat"}, {Type: "fix", Breaking: "removed flag"}},
+			want:    "2.0.0",
+		},
+		{name: "prerelease", last: &ctx.Semver{Major: 2, Prerelease: "rc.1"}, commits: []*conventionalCommit{{Type: "feat"}}, want: "2.0.0"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bumpSemver(tt.last, tt.commits).String(); got != tt.want {
+				t.Errorf("bumpSemver() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// testGitRepo creates a git repository with a commit, and a bare remote
+// as "origin"
+func testGitRepo(t *testing.T) (string, func(dir string, args ...string) string) {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+
+	for _, name := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(name, "Test")
+	}
+
+	for _, name := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(name, "test@example.com")
+	}
+
+	git := func(dir string, args ...string) string {
+		t.Helper()
+
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+
+		return strings.TrimSpace(string(out))
+	}
+
+	base := t.TempDir()
+	repo := filepath.Join(base, "repo")
+	remote := filepath.Join(base, "remote.git")
+
+	git(base, "init", "--quiet", "--bare", remote)
+	git(base, "init", "--quiet", repo)
+
+	if err := os.WriteFile(filepath.Join(repo, "README"), []byte("hello\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	git(repo, "add", "README")
+	git(repo, "commit", "--quiet", "-m", "feat: hello")
+	git(repo, "remote", "add", "origin", remote)
+
+	return repo, git
+}
+
+func TestNextVersion_Run(t *testing.T) {
+	tests := []struct {
+		name    string
+		tag     bool
+		push    bool
+		prepare func(t *testing.T, repo string, git func(string, ...string) string)
+		wantErr string
+		wantTag bool
+	}{
+		{name: "push without tag", push: true, wantErr: "push requires tag"},
+		{name: "no tagging", wantTag: false},
+		{name: "tag, and push", tag: true, push: true, wantTag: true},
+		{
+			name: "dirty working tree",
+			tag:  true,
+			prepare: func(t *testing.T, repo string, git func(string, ...string) string) {
+				if err := os.WriteFile(filepath.Join(repo, "README"), []byte("changed\n"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "uncommitted changes",
+		},
+		{
+			name: "tag exists on remote",
+			tag:  true,
+			push: true,
+			prepare: func(t *testing.T, repo string, git func(string, ...string) string) {
+				git(repo, "tag", "v0.1.0")
+				git(repo, "push", "--quiet", "origin", "refs/tags/v0.1.0")
+				git(repo, "tag", "-d", "v0.1.0")
+			},
+			wantErr: "tag already exists on origin",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			repo, git := testGitRepo(t)
+			if tt.prepare != nil {
+				tt.prepare(t, repo, git)
+			}
+
+			cx, shipContext := testShipContext(t)
+			shipContext.Dir = repo
+
+			if err := shipContext.Env.Load(os.Environ()); err != nil {
+				t.Fatal(err)
+			}
+
+			mod := NewNextVersion().(*NextVersion)
+			mod.Tag = tt.tag
+			mod.Push = tt.push
+
+			err := mod.Run(cx)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Run() error = %v, want %q", err, tt.wantErr)
+			}
+
+			if tt.wantErr != "" {
+				if tags := git(repo, "tag", "--list"); tags != "" {
+					t.Errorf("Run() created tags %q", tags)
+				}
+
+				return
+			}
+
+			if shipContext.Version != "v0.1.0" {
+				t.Errorf("Run() set version %s, want v0.1.0", shipContext.Version)
+			}
+
+			if tags := git(repo, "tag", "--list"); (tags == "v0.1.0") != tt.wantTag {
+				t.Errorf("Run() created tags %q, want tag: %v", tags, tt.wantTag)
+			}
+
+			if tags := git(repo, "ls-remote", "--tags", "origin"); strings.Contains(tags, "v0.1.0") != (tt.wantTag && tt.push) {
+				t.Errorf("Run() pushed tags %q", tags)
+			}
+		})
+	}
+}
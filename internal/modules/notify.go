@@ -0,0 +1,204 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+const (
+	// discordMaxLength is the maximum length of Discord messages
+	discordMaxLength = 2000
+
+	slackMessage = `*{{.ProjectName}} {{.Version}}* has been released.
+{{range highlights 5 .Changelog}}
+• {{.}}{{end}}
+{{range .Links}}
+<{{.URL}}|{{.Name}}>{{end}}{{range .Artifacts}}{{if .URL}}
+<{{.URL}}|{{.Filename}}>{{end}}{{end}}`
+
+	markdownMessage = `**{{.ProjectName}} {{.Version}}** has been released.
+{{range highlights 5 .Changelog}}
+- {{.}}{{end}}
+{{range .Links}}
+[{{.Name}}]({{.URL}}){{end}}{{range .Artifacts}}{{if .URL}}
+[{{.Filename}}]({{.URL}}){{end}}{{end}}`
+)
+
+type (
+	// Announcement contains common settings of notify modules, rendering
+	// release announcements
+	Announcement struct {
+		// Artifacts is a template expression selecting artifacts of
+		// Builds (or all artifacts, if Builds is empty), evaluated for
+		// each artifact. See modules.SelectArtifacts.
+		Artifacts string
+		// Builds specifies which build names are linked in the message,
+		// if their download links are known. Default: ["archive"].
+		Builds []string
+		// Changelog is the artifact ID of the changelog excerpt. It is
+		// optional. Default: "changelog".
+		Changelog string
+		// Message is the announcement (Go template of WebhookPayload,
+		// with `json`, and `highlights` functions). Default depends on
+		// the module.
+		Message string
+		// Skip specifies GOOS-GOArch combinations to be skipped.
+		// They are in `{{.Os}}-{{.Arch}}` format.
+		Skip []string
+	}
+
+	// ChatNotifier contains common settings of chat notification modules,
+	// posting release announcements into incoming webhooks
+	ChatNotifier struct {
+		Announcement `yaml:",inline"`
+		// WebhookEnv specifies the environment variable of the incoming
+		// webhook's URL. Default depends on the module.
+		WebhookEnv string `yaml:"webhook_env"`
+	}
+
+	// Slack is a notify module posting release announcements to Slack
+	Slack struct {
+		ChatNotifier `yaml:",inline"`
+	}
+
+	// Discord is a notify module posting release announcements to Discord
+	Discord struct {
+		ChatNotifier `yaml:",inline"`
+	}
+
+	// Teams is a notify module posting release announcements to Microsoft
+	// Teams
+	Teams struct {
+		ChatNotifier `yaml:",inline"`
+	}
+)
+
+func newAnnouncement() Announcement {
+	return Announcement{
+		Builds:    []string{"archive"},
+		Changelog: "changelog",
+		Skip:      []string{},
+	}
+}
+
+func newChatNotifier(webhookEnv string) ChatNotifier {
+	return ChatNotifier{
+		Announcement: newAnnouncement(),
+		WebhookEnv:   webhookEnv,
+	}
+}
+
+// NewSlack is a factory function for Slack module
+func NewSlack() modules.Pluggable {
+	return &Slack{ChatNotifier: newChatNotifier("SLACK_WEBHOOK_URL")}
+}
+
+// NewDiscord is a factory function for Discord module
+func NewDiscord() modules.Pluggable {
+	return &Discord{ChatNotifier: newChatNotifier("DISCORD_WEBHOOK_URL")}
+}
+
+// NewTeams is a factory function for Teams module
+func NewTeams() modules.Pluggable {
+	return &Teams{ChatNotifier: newChatNotifier("TEAMS_WEBHOOK_URL")}
+}
+
+// Run posts the announcement into Slack
+func (mod *Slack) Run(cx context.Context) error {
+	return mod.notify(cx, "slack", slackMessage, func(message string) interface{} {
+		return map[string]string{"text": message}
+	})
+}
+
+// Run posts the announcement into Discord
+func (mod *Discord) Run(cx context.Context) error {
+	return mod.notify(cx, "discord", markdownMessage, func(message string) interface{} {
+		return map[string]string{"content": truncateRunes(message, discordMaxLength)}
+	})
+}
+
+// Run posts the announcement into Microsoft Teams
+func (mod *Teams) Run(cx context.Context) error {
+	return mod.notify(cx, "teams", markdownMessage, func(message string) interface{} {
+		// Teams needs empty lines for line breaks
+		return map[string]string{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  strings.SplitN(message, "\n", 2)[0],
+			"text":     strings.ReplaceAll(message, "\n", "\n\n"),
+		}
+	})
+}
+
+// notify renders the announcement, and posts it in the service's format
+func (n *ChatNotifier) notify(cx context.Context, service, defaultMessage string, body func(string) interface{}) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	url, err := context.RequireEnv(n.WebhookEnv, service+" webhook URL")
+	if err != nil {
+		return err
+	}
+
+	message, err := n.render(cx, context, service, defaultMessage)
+	if err != nil {
+		return err
+	}
+
+	// webhook URLs are secrets: they are not reported
+	context.Progress.SetState(fmt.Sprintf("posting announcement to %s", service))
+
+	if err := requestJSON(cx, http.MethodPost, url, nil, body(message), nil); err != nil {
+		return fmt.Errorf("posting announcement to %s: %w", service, err)
+	}
+
+	return nil
+}
+
+// ValidateTemplates implements modules.TemplateValidator
+func (a *Announcement) ValidateTemplates(context.Context) ([]string, error) {
+	if a.Message == "" {
+		return []string{"Message"}, nil
+	}
+
+	_, err := renderPayload("message", a.Message, sampleWebhookPayload())
+
+	return []string{"Message"}, err
+}
+
+// render renders the announcement of the release
+func (a *Announcement) render(cx context.Context, context *ctx.Context, service, defaultMessage string) (string, error) {
+	payload, err := a.payload(cx, context)
+	if err != nil {
+		return "", err
+	}
+
+	return a.message(payload, service, defaultMessage)
+}
+
+// payload collects release information for the announcement
+func (a *Announcement) payload(cx context.Context, context *ctx.Context) (*WebhookPayload, error) {
+	return releasePayload(cx, context, a.Builds, a.Skip, a.Artifacts, a.Changelog)
+}
+
+// message renders the announcement from release information
+func (a *Announcement) message(payload *WebhookPayload, service, defaultMessage string) (string, error) {
+	text := a.Message
+	if text == "" {
+		text = defaultMessage
+	}
+
+	message, err := renderPayload(service+"-message", text, payload)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(message), nil
+}
@@ -0,0 +1,121 @@
+package modules
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+func TestChatNotifier_notify(t *testing.T) {
+	var body map[string]string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body = map[string]string{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	cx := ctx.New(context.Background())
+
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	context.ProjectName = "app"
+	context.Version = "v1.2.3"
+
+	changelog := &ctx.Artifact{
+		Filename: "changelog.md",
+		ID:       "changelog",
+		Location: filepath.Join(dir, "changelog.md"),
+	}
+
+	if err := os.WriteFile(changelog.Location, []byte("### Added\n\n- Feature\n\n### Fixed\n\n* Bug\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	art := &ctx.Artifact{
+		Filename: "app-linux-amd64.tar.gz",
+		ID:       "archive",
+		Location: filepath.Join(dir, "app-linux-amd64.tar.gz"),
+		OsArch:   &ctx.OsArch{OS: "linux", Arch: "amd64"},
+	}
+
+	if err := os.WriteFile(art.Location, []byte("content"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	context.Artifacts.Add(changelog)
+	context.Artifacts.Add(art)
+	context.Published.AddDownload(art, "https://example.com/app-linux-amd64.tar.gz")
+
+	for _, env := range []string{"SLACK_WEBHOOK_URL", "DISCORD_WEBHOOK_URL", "TEAMS_WEBHOOK_URL"} {
+		context.Env.Set(env, srv.URL)
+	}
+
+	tests := []struct {
+		name string
+		mod  modules.Pluggable
+		want map[string]string
+	}{
+		{
+			name: "slack",
+			mod:  NewSlack(),
+			want: map[string]string{
+				"text": "*app v1.2.3* has been released.\n\n• Feature\n• Bug\n\n" +
+					"<https://example.com/app-linux-amd64.tar.gz|app-linux-amd64.tar.gz>",
+			},
+		},
+		{
+			name: "discord",
+			mod:  NewDiscord(),
+			want: map[string]string{
+				"content": "**app v1.2.3** has been released.\n\n- Feature\n- Bug\n\n" +
+					"[app-linux-amd64.tar.gz](https://example.com/app-linux-amd64.tar.gz)",
+			},
+		},
+		{
+			name: "teams",
+			mod:  NewTeams(),
+			want: map[string]string{
+				"@type":    "MessageCard",
+				"@context": "https://schema.org/extensions",
+				"summary":  "**app v1.2.3** has been released.",
+				"text": "**app v1.2.3** has been released.\n\n\n\n- Feature\n\n- Bug\n\n\n\n" +
+					"[app-linux-amd64.tar.gz](https://example.com/app-linux-amd64.tar.gz)",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.mod.Run(cx); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(body, tt.want) {
+				t.Errorf("body:\n%q\nwant:\n%q", body, tt.want)
+			}
+		})
+	}
+}
+
+func TestChatNotifier_missingWebhook(t *testing.T) {
+	cx := ctx.New(context.Background())
+
+	err := NewSlack().Run(cx)
+	if err == nil || err.Error() != "slack webhook URL not found in $SLACK_WEBHOOK_URL" {
+		t.Errorf("Run() error = %v", err)
+	}
+}
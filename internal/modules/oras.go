@@ -0,0 +1,255 @@
+package modules
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+// orasMediaTypes maps file name suffixes to media types. Longer suffixes
+// take precedence.
+// nolint: gochecknoglobals
+var (
+	orasMediaTypes = map[string]string{
+		".cdx.json":  "application/vnd.cyclonedx+json",
+		".json":      "application/json",
+		".md":        "text/markdown",
+		".minisig":   "application/vnd.goshipdone.minisign.signature",
+		".pem":       "application/x-pem-file",
+		".sig":       "application/vnd.goshipdone.signature",
+		".spdx.json": "application/spdx+json",
+		".tar":       "application/vnd.oci.image.layer.v1.tar",
+		".tar.gz":    "application/vnd.oci.image.layer.v1.tar+gzip",
+		".tgz":       "application/vnd.oci.image.layer.v1.tar+gzip",
+		".txt":       "text/plain",
+		".zip":       "application/zip",
+	}
+	reORASDigest = regexp.MustCompile(`Digest: (sha256:[0-9a-f]{64})`)
+)
+
+// ORAS is a publish module pushing artifacts (archives, SBOMs, signatures)
+// into an OCI registry as a single OCI artifact, using the `oras` CLI.
+type ORAS struct {
+	// Annotations are manifest annotations (templates).
+	Annotations map[string]string
+	// Artifacts is a template expression selecting artifacts of Builds
+	// (or all artifacts, if Builds is empty), evaluated for each artifact.
+	// See modules.SelectArtifacts.
+	Artifacts string
+	// ArtifactType is the OCI artifact type of the manifest.
+	// Default: "application/vnd.goshipdone.release.v1".
+	ArtifactType string `yaml:"artifact_type"`
+	// Builds specifies which build names should be pushed.
+	// Default: ["archive"].
+	Builds []string
+	// MediaTypes maps file name suffixes to media types, extending and
+	// overriding built-in media types. Files with unknown media types are
+	// pushed as "application/octet-stream".
+	MediaTypes map[string]string `yaml:"media_types"`
+	// PasswordEnv specifies the environment variable of the registry
+	// password or token. If it is not set, credentials of the docker
+	// config are used.
+	PasswordEnv string `yaml:"password_env"`
+	// Repository is the target repository (template), like
+	// "ghcr.io/owner/app". Required.
+	Repository string
+	// Skip specifies GOOS-GOArch combinations to be skipped.
+	// They are in `{{.Os}}-{{.Arch}}` format.
+	Skip []string
+	// Tags are tag templates. Default: ["{{.Version}}"].
+	Tags []string
+	// UsernameEnv specifies the environment variable of the registry
+	// user name.
+	UsernameEnv string `yaml:"username_env"`
+}
+
+// NewORAS is a factory function for ORAS module
+func NewORAS() modules.Pluggable {
+	return &ORAS{
+		Annotations:  map[string]string{},
+		ArtifactType: "application/vnd.goshipdone.release.v1",
+		Builds:       []string{"archive"},
+		MediaTypes:   map[string]string{},
+		Skip:         []string{},
+		Tags:         []string{"{{.Version}}"},
+	}
+}
+
+// Run pushes selected artifacts into the repository
+func (mod *ORAS) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	td, err := modules.NewTemplate(cx)
+	if err != nil {
+		return err
+	}
+
+	reference, err := mod.reference(td)
+	if err != nil {
+		return err
+	}
+
+	builds, err := modules.SelectArtifacts(cx, mod.Builds, mod.Skip, mod.Artifacts)
+	if err != nil {
+		return err
+	}
+
+	dir, err := context.TempDir("oras")
+	if err != nil {
+		return err
+	}
+
+	args := []string{"push", reference, "--artifact-type", mod.ArtifactType}
+
+	annotations, err := mod.annotations(td)
+	if err != nil {
+		return err
+	}
+
+	args = append(args, annotations...)
+
+	files := []string{}
+
+	for _, build := range builds {
+		for _, artifact := range *build {
+			// oras takes annotation titles from relative paths
+			location, err := filepath.Abs(artifact.Location)
+			if err != nil {
+				return err
+			}
+
+			if err := os.Symlink(location, filepath.Join(dir, artifact.Filename)); err != nil {
+				return fmt.Errorf("linking %s: %w", artifact.Filename, err)
+			}
+
+			files = append(files, artifact.Filename+":"+mod.mediaType(artifact.Filename))
+		}
+	}
+
+	if len(files) == 0 {
+		return errors.New("no artifacts to push")
+	}
+
+	sort.Strings(files)
+
+	var stdin io.Reader
+
+	if mod.PasswordEnv != "" {
+		password, err := context.RequireEnv(mod.PasswordEnv, "registry password")
+		if err != nil {
+			return err
+		}
+
+		stdin = strings.NewReader(password)
+		args = append(args, "--password-stdin")
+
+		if mod.UsernameEnv != "" {
+			args = append(args, "--username", context.Getenv(mod.UsernameEnv, ""))
+		}
+	}
+
+	context.Progress.SetState(fmt.Sprintf("pushing %d file(s) to %s", len(files), reference))
+
+	out := &bytes.Buffer{}
+	cmd := exec.CommandContext(cx, "oras", append(args, files...)...)
+	cmd.Dir = dir
+	cmd.Env = context.Env.Environ()
+	cmd.Stdin = stdin
+	cmd.Stdout = io.MultiWriter(os.Stdout, out)
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("pushing to %s: %w", reference, err)
+	}
+
+	if match := reORASDigest.FindStringSubmatch(out.String()); match != nil {
+		context.Published.AddLink("oci artifact", repositoryOf(reference)+"@"+match[1])
+	}
+
+	return nil
+}
+
+// reference renders the target reference with all tags, in
+// `repository:tag1,tag2` format
+func (mod *ORAS) reference(td *modules.TemplateData) (string, error) {
+	if mod.Repository == "" {
+		return "", errors.New("repository is not specified")
+	}
+
+	repository, err := td.Parse("oras-repository", mod.Repository)
+	if err != nil {
+		return "", fmt.Errorf("rendering repository: %w", err)
+	}
+
+	tags := make([]string, 0, len(mod.Tags))
+
+	for _, tagTemplate := range mod.Tags {
+		tag, err := td.Parse("oras-tag", tagTemplate)
+		if err != nil {
+			return "", fmt.Errorf("rendering tag %q: %w", tagTemplate, err)
+		}
+
+		tags = append(tags, tag)
+	}
+
+	if len(tags) == 0 {
+		return "", errors.New("no tags specified")
+	}
+
+	return repository + ":" + strings.Join(tags, ","), nil
+}
+
+// annotations renders annotations into `--annotation` arguments
+func (mod *ORAS) annotations(td *modules.TemplateData) ([]string, error) {
+	keys := make([]string, 0, len(mod.Annotations))
+	for key := range mod.Annotations {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	args := make([]string, 0, 2*len(keys))
+
+	for _, key := range keys {
+		value, err := td.Parse("oras-annotation", mod.Annotations[key])
+		if err != nil {
+			return nil, fmt.Errorf("rendering annotation %s: %w", key, err)
+		}
+
+		args = append(args, "--annotation", key+"="+value)
+	}
+
+	return args, nil
+}
+
+// mediaType finds the media type of a file by the longest matching
+// suffix of configured and built-in media types
+func (mod *ORAS) mediaType(filename string) string {
+	mediaType := "application/octet-stream"
+	matched := 0
+
+	for _, types := range []map[string]string{orasMediaTypes, mod.MediaTypes} {
+		for suffix, value := range types {
+			if strings.HasSuffix(filename, suffix) && len(suffix) >= matched {
+				mediaType = value
+				matched = len(suffix)
+			}
+		}
+	}
+
+	return mediaType
+}
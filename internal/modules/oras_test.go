@@ -0,0 +1,23 @@
+package modules
+
+import "testing"
+
+func TestORAS_mediaType(t *testing.T) {
+	mod := NewORAS().(*ORAS)
+	mod.MediaTypes[".sbom.json"] = "application/vnd.example.sbom+json"
+
+	tests := map[string]string{
+		"app-linux-amd64.tar.gz":   "application/vnd.oci.image.layer.v1.tar+gzip",
+		"app-windows-amd64.zip":    "application/zip",
+		"app.spdx.json":            "application/spdx+json",
+		"app.sbom.json":            "application/vnd.example.sbom+json",
+		"checksums.txt":            "text/plain",
+		"app-linux-amd64.tar.gz.X": "application/octet-stream",
+	}
+
+	for filename, want := range tests {
+		if got := mod.mediaType(filename); got != want {
+			t.Errorf("mediaType(%q) = %q, want %q", filename, got, want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+// packageArtifact is an artifact referenced by a package manifest, with
+// its download URL, and SHA256 checksum
+type packageArtifact struct {
+	*ctx.Artifact
+	SHA256 string
+	URL    string
+}
+
+// packageArtifacts collects selected artifacts with their download URLs
+// and checksums for package manager modules. URLs are rendered from
+// urlTemplate (with Artifact, Filename, and OSArch template fields), or
+// taken from download links recorded by previous publishers, if
+// urlTemplate is empty. Results are ordered by OS-arch and file name.
+func packageArtifacts(cx context.Context, builds, skips []string, expr, urlTemplate string) ([]*packageArtifact, error) {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return nil, err
+	}
+
+	selected, err := modules.SelectArtifacts(cx, builds, skips, expr)
+	if err != nil {
+		return nil, err
+	}
+
+	algo, err := modules.NewHashAlgorithm(modules.DefaultDigest)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []*packageArtifact{}
+
+	for _, build := range selected {
+		for _, artifact := range *build {
+			url, err := packageURL(cx, context, artifact, urlTemplate)
+			if err != nil {
+				return nil, err
+			}
+
+			sum, err := algo.SumArtifact(artifact)
+			if err != nil {
+				return nil, fmt.Errorf("calculating checksum of %s: %w", artifact.Filename, err)
+			}
+
+			results = append(results, &packageArtifact{Artifact: artifact, SHA256: sum, URL: url})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if a, b := results[i].OsArch.String(), results[j].OsArch.String(); a != b {
+			return a < b
+		}
+
+		return results[i].Filename < results[j].Filename
+	})
+
+	return results, nil
+}
+
+func packageURL(cx context.Context, context *ctx.Context, artifact *ctx.Artifact, urlTemplate string) (string, error) {
+	if urlTemplate == "" {
+		url, ok := context.Published.DownloadURL(artifact.Filename)
+		if !ok {
+			return "", fmt.Errorf("download URL of %s is unknown: publish it first, or set url_template", artifact.Filename)
+		}
+
+		return url, nil
+	}
+
+	td, err := modules.NewTemplate(cx)
+	if err != nil {
+		return "", err
+	}
+
+	td.Artifact = artifact
+	td.Filename = artifact.Filename
+	td.OSArch = artifact.OsArch
+
+	url, err := td.Parse("package-url", urlTemplate)
+	if err != nil {
+		return "", fmt.Errorf("rendering URL of %s: %w", artifact.Filename, err)
+	}
+
+	return url, nil
+}
@@ -0,0 +1,108 @@
+package modules
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+	"github.com/julian7/goshipdone/plugin"
+)
+
+// Plugin is a module running an external program, which can be loaded in
+// every stage. The program receives a plugin.Request as JSON on its standard
+// input, and it writes a plugin.Response as JSON to its standard output.
+// Logs can be written to standard error.
+type Plugin struct {
+	// Args are the program's arguments. Environment variables are
+	// expanded.
+	Args []string
+	// Command is the program to run. Environment variables are
+	// expanded. Required.
+	Command string
+	// Config is passed to the program as is.
+	Config map[string]interface{}
+}
+
+// NewPlugin is a factory function for Plugin module
+func NewPlugin() modules.PluggableV2 {
+	return &Plugin{Config: map[string]interface{}{}}
+}
+
+// DynamicArtifacts implements modules.DynamicArtifacts
+func (mod *Plugin) DynamicArtifacts() bool {
+	return true
+}
+
+// ValidateTemplates implements modules.TemplateValidator. Config is not a
+// template.
+func (mod *Plugin) ValidateTemplates(context.Context) ([]string, error) {
+	return []string{"Config"}, nil
+}
+
+// RunResult runs the plugin
+func (mod *Plugin) RunResult(cx context.Context) (*modules.Result, error) {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return nil, err
+	}
+
+	if mod.Command == "" {
+		return nil, errors.New("no command specified")
+	}
+
+	request, err := json.Marshal(plugin.NewRequest(context, mod.Config))
+	if err != nil {
+		return nil, fmt.Errorf("encoding plugin request: %w", err)
+	}
+
+	args := make([]string, 0, len(mod.Args))
+	for _, arg := range mod.Args {
+		args = append(args, context.Env.Expand(arg))
+	}
+
+	command := context.Env.Expand(mod.Command)
+	out := &bytes.Buffer{}
+
+	cmd := exec.CommandContext(cx, command, args...)
+	cmd.Dir = context.Dir
+	cmd.Env = context.Env.Environ()
+	cmd.Stdin = bytes.NewReader(request)
+	cmd.Stdout = out
+	cmd.Stderr = os.Stderr
+
+	context.Progress.SetState(fmt.Sprintf("running plugin %s", filepath.Base(command)))
+
+	runErr := cmd.Run()
+
+	response := &plugin.Response{}
+	if err := json.Unmarshal(out.Bytes(), response); err != nil {
+		if runErr != nil {
+			return nil, fmt.Errorf("running plugin %s: %w", command, runErr)
+		}
+
+		return nil, fmt.Errorf("decoding response of plugin %s: %w", command, err)
+	}
+
+	if response.Error != "" {
+		return nil, fmt.Errorf("plugin %s: %s", command, response.Error)
+	}
+
+	if runErr != nil {
+		return nil, fmt.Errorf("running plugin %s: %w", command, runErr)
+	}
+
+	response.Fill()
+
+	return &modules.Result{
+		Artifacts: response.Artifacts,
+		Metrics:   response.Metrics,
+		Warnings:  response.Warnings,
+	}, nil
+}
@@ -0,0 +1,89 @@
+package modules
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/plugin"
+)
+
+func TestPlugin_RunResult(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake plugin is a shell script")
+	}
+
+	dir := t.TempDir()
+	requestFile := filepath.Join(dir, "request.json")
+	program := filepath.Join(dir, "plugin")
+	script := "#!/bin/sh\ncat > " + requestFile + "\necho 'signing' >&2\n" +
+		`echo '{"artifacts": [{"id": "sig", "location": "` + dir + `/app.sig", "os": "linux", "arch": "amd64"}], "warnings": ["key expires soon"]}'` + "\n"
+
+	if err := os.WriteFile(program, []byte(script), 0o755); err != nil { // nolint: gosec
+		t.Fatal(err)
+	}
+
+	cx := ctx.New(context.Background())
+
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	context.ProjectName = "app"
+	context.Version = "1.2.3"
+	context.Artifacts.Add(&ctx.Artifact{ID: "default", Filename: "app", Location: "dist/app"})
+
+	mod := NewPlugin().(*Plugin)
+	mod.Command = program
+	mod.Config["key"] = "release"
+
+	result, err := mod.RunResult(cx)
+	if err != nil {
+		t.Fatalf("RunResult() error = %v", err)
+	}
+
+	want := []*ctx.Artifact{{
+		ID:       "sig",
+		Filename: "app.sig",
+		Location: filepath.Join(dir, "app.sig"),
+		OsArch:   &ctx.OsArch{OS: "linux", Arch: "amd64"},
+	}}
+
+	if diff := deep.Equal(result.Artifacts, want); diff != nil {
+		t.Errorf("RunResult() artifacts %v", diff)
+	}
+
+	if diff := deep.Equal(result.Warnings, []string{"key expires soon"}); diff != nil {
+		t.Errorf("RunResult() warnings %v", diff)
+	}
+
+	data, err := os.ReadFile(requestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := &plugin.Request{}
+	if err := json.Unmarshal(data, request); err != nil {
+		t.Fatalf("decoding request: %v", err)
+	}
+
+	if request.Protocol != 1 || request.Config["key"] != "release" || request.Context.Version != "1.2.3" ||
+		len(request.Context.Artifacts) != 1 || request.Context.Artifacts[0].Location != "dist/app" {
+		t.Errorf("RunResult() sent request %s", data)
+	}
+
+	if err := os.WriteFile(program, []byte("#!/bin/sh\necho '{\"error\": \"no key\"}'\nexit 1\n"), 0o755); err != nil { // nolint: gosec
+		t.Fatal(err)
+	}
+
+	if _, err := mod.RunResult(cx); err == nil || !strings.HasSuffix(err.Error(), ": no key") {
+		t.Errorf("RunResult() error = %v, want plugin's error", err)
+	}
+}
@@ -0,0 +1,180 @@
+package modules
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+type (
+	// Preflight is a module for re-validating artifacts before
+	// publishing. It checks that checksums recorded in checksum files
+	// still match files on the disk, that artifacts checksum files were
+	// made of are all listed, and that required signatures exist.
+	// It reports all inconsistencies at once, aborting publication.
+	Preflight struct {
+		// Algorithm specifies the checksum files' algorithm.
+		// Default: "sha256".
+		Algorithm modules.HashAlgorithm
+		// Checksums specifies which checksum files (created by
+		// build:checksum) should be validated. Default: ["checksum"].
+		Checksums []string
+		// Signatures specifies required signatures.
+		Signatures []*PreflightSignature
+	}
+
+	// PreflightSignature specifies a signature requirement
+	PreflightSignature struct {
+		// Builds specifies which build names must be signed.
+		Builds []string
+		// ID is the artifact name of signatures.
+		ID string
+		// Skip specifies GOOS-GOArch combinations to be skipped.
+		Skip []string
+	}
+)
+
+// NewPreflight is a factory function for Preflight module
+func NewPreflight() modules.Pluggable {
+	algo, _ := modules.NewHashAlgorithm("sha256")
+
+	return &Preflight{
+		Algorithm: *algo,
+		Checksums: []string{"checksum"},
+	}
+}
+
+// Run validates checksums and signatures
+func (mod *Preflight) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	problems := []string{}
+
+	for _, id := range mod.Checksums {
+		for _, checksumFile := range *context.Artifacts.ByID(id) {
+			found, err := mod.checkChecksums(context, checksumFile)
+			if err != nil {
+				return err
+			}
+
+			problems = append(problems, found...)
+		}
+	}
+
+	for _, req := range mod.Signatures {
+		signatures := context.Artifacts.ByID(req.ID)
+
+		for _, build := range context.Artifacts.OsArchByIDs(req.Builds, req.Skip) {
+			for _, art := range *build {
+				if findSignature(signatures, art) == nil {
+					problems = append(problems, fmt.Sprintf("%s: %s signature not found", art.Filename, req.ID))
+				}
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("preflight failed:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	return nil
+}
+
+// checkChecksums validates a checksum file's lines against the artifacts
+// it was made of, returning problems. Every artifact having the file name
+// of a line is checked by its location, as they can't be told apart by
+// their checksums. Artifacts missing from the checksum file are problems
+// too.
+func (mod *Preflight) checkChecksums(context *ctx.Context, checksumFile *ctx.Artifact) ([]string, error) {
+	file, err := os.Open(checksumFile.Location)
+	if err != nil {
+		return nil, fmt.Errorf("opening checksum file: %w", err)
+	}
+
+	defer file.Close()
+
+	parents := map[string]bool{}
+	for _, filename := range checksumFile.Parents {
+		parents[filename] = true
+	}
+
+	scope := []*ctx.Artifact{}
+	byName := map[string][]*ctx.Artifact{}
+
+	for _, art := range context.Artifacts {
+		if parents[art.Filename] {
+			scope = append(scope, art)
+			byName[art.Filename] = append(byName[art.Filename], art)
+		}
+	}
+
+	problems := []string{}
+	checked := map[string]bool{}
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		fields := strings.SplitN(line, "  ", 2)
+		if len(fields) != 2 {
+			problems = append(problems, fmt.Sprintf("%s: invalid line %q", checksumFile.Filename, line))
+			continue
+		}
+
+		want, filename := fields[0], fields[1]
+
+		for _, location := range checksumLocations(checksumFile, byName[filename], filename) {
+			checked[location] = true
+
+			got, err := mod.Algorithm.SumFile(location)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", filename, err))
+				continue
+			}
+
+			if got != want {
+				problems = append(problems, fmt.Sprintf("%s: %s checksum mismatch (recorded in %s)", location, mod.Algorithm.Algo, checksumFile.Filename))
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading checksum file %s: %w", checksumFile.Location, err)
+	}
+
+	for _, art := range scope {
+		if !checked[art.Location] {
+			problems = append(problems, fmt.Sprintf("%s: missing from %s", art.Location, checksumFile.Filename))
+		}
+	}
+
+	return problems, nil
+}
+
+// checksumLocations returns locations of a checksum file's line: the
+// artifacts the checksum file was made of, having the line's file name,
+// or the file next to the checksum file
+func checksumLocations(checksumFile *ctx.Artifact, artifacts []*ctx.Artifact, filename string) []string {
+	if len(artifacts) == 0 {
+		return []string{filepath.Join(filepath.Dir(checksumFile.Location), filepath.FromSlash(filename))}
+	}
+
+	locations := make([]string, 0, len(artifacts))
+	for _, art := range artifacts {
+		locations = append(locations, art.Location)
+	}
+
+	return locations
+}
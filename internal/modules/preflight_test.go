@@ -0,0 +1,82 @@
+package modules
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+func TestPreflight_Run(t *testing.T) {
+	dir := t.TempDir()
+	cx := ctx.New(context.Background())
+
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	context.TargetDir = dir
+	linux := &ctx.OsArch{OS: "linux", Arch: "amd64"}
+
+	for name, content := range map[string]string{
+		"good.tar":       "good",
+		"other/good.tar": "unrelated",
+		"unlisted.tar":   "unlisted",
+		"bad.tar":        "changed",
+		"good.tar.sig":   "signature",
+		// sha256 of "good", and a stale checksum of bad.tar
+		"checksums.txt": "770e607624d689265ca6c44884d0807d9b054d23c473c106c72be9de08b7376c  good.tar\n" +
+			"fd7fdb6f8dd2ed6f2a4ee29fa0c8ee74ec8fc76e2c0ee0dd7e4fe5a6d3d1d52f  bad.tar\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	context.Artifacts = ctx.Artifacts{
+		// a different file with the same name
+		{Filename: "good.tar", ID: "import", Location: filepath.Join(dir, "other", "good.tar"), OsArch: linux},
+		{Filename: "good.tar", ID: "archive", Location: filepath.Join(dir, "good.tar"), OsArch: linux},
+		{Filename: "bad.tar", ID: "archive", Location: filepath.Join(dir, "bad.tar"), OsArch: linux},
+		{Filename: "unlisted.tar", ID: "extra", Location: filepath.Join(dir, "unlisted.tar"), OsArch: linux},
+		{Filename: "good.tar.sig", ID: "sig", Location: filepath.Join(dir, "good.tar.sig"), OsArch: linux},
+		{
+			Filename: "checksums.txt",
+			ID:       "checksum",
+			Location: filepath.Join(dir, "checksums.txt"),
+			OsArch:   &ctx.OsArch{},
+			Parents:  []string{"good.tar", "bad.tar", "unlisted.tar"},
+		},
+	}
+
+	mod := NewPreflight().(*Preflight)
+	mod.Signatures = []*PreflightSignature{{Builds: []string{"archive"}, ID: "sig"}}
+
+	err = mod.Run(cx)
+	if err == nil {
+		t.Fatal("Run() succeeded, want error")
+	}
+
+	for _, want := range []string{
+		"bad.tar: sha256 checksum mismatch",
+		"bad.tar: sig signature not found",
+		"unlisted.tar: missing from checksums.txt",
+		filepath.Join("other", "good.tar") + ": sha256 checksum mismatch",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Run() error = %v, missing %q", err, want)
+		}
+	}
+
+	if strings.Contains(err.Error(), filepath.Join(dir, "good.tar")+":") {
+		t.Errorf("Run() error = %v, reports good.tar", err)
+	}
+}
@@ -0,0 +1,81 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+// PreviousTag is a setup module determining the previous release tag: the
+// highest semantic version tag reachable from the current commit, other
+// than the current tag. It records the tag, and the commit range since
+// the tag into ctx.Context, for changelogs, and compare links.
+type PreviousTag struct {
+	// Prefix is the prefix of tags. Default: "v".
+	Prefix string
+	// Prereleases allows pre-release tags to be previous tags. Otherwise,
+	// changes since the previous release are collected in pre-releases,
+	// and releases alike. Default: false.
+	Prereleases bool
+}
+
+// NewPreviousTag is a factory function for PreviousTag module
+func NewPreviousTag() modules.Pluggable {
+	return &PreviousTag{
+		Prefix: "v",
+	}
+}
+
+// Run records the previous tag, and the commit range into ctx.Context
+func (mod *PreviousTag) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	out, err := gitOutput(cx, context, "tag", "--merged", "HEAD")
+	if err != nil {
+		return fmt.Errorf("listing tags: %w", err)
+	}
+
+	context.Git.PreviousTag = mod.find(strings.Fields(out), context.Git.TagPrefix+mod.Prefix, context.Git.Tag)
+	context.Git.Range = "HEAD"
+
+	if context.Git.PreviousTag != "" {
+		context.Git.Range = context.Git.PreviousTag + "..HEAD"
+	}
+
+	log.Printf("      previous tag is %q, commit range is %s", context.Git.PreviousTag, context.Git.Range)
+
+	return nil
+}
+
+// find returns the highest version of tags with prefix, other than current
+func (mod *PreviousTag) find(tags []string, prefix, current string) string {
+	var (
+		found   string
+		highest *ctx.Semver
+	)
+
+	for _, tag := range tags {
+		if tag == current || !strings.HasPrefix(tag, prefix) {
+			continue
+		}
+
+		semver, err := ctx.ParseSemver(strings.TrimPrefix(tag, prefix))
+		if err != nil || (semver.IsPrerelease() && !mod.Prereleases) {
+			continue
+		}
+
+		if highest == nil || semver.Compare(highest) > 0 {
+			found = tag
+			highest = semver
+		}
+	}
+
+	return found
+}
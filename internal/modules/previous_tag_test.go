@@ -0,0 +1,31 @@
+package modules
+
+import "testing"
+
+func TestPreviousTag_find(t *testing.T) {
+	tags := []string{"v1.9.0", "v1.10.0", "v1.10.1-rc.1", "v2.0.0", "latest", "x1.11.0"}
+
+	tests := []struct {
+		name        string
+		current     string
+		prereleases bool
+		want        string
+	}{
+		{name: "untagged", want: "v2.0.0"},
+		{name: "current tag", current: "v2.0.0", want: "v1.10.0"},
+		{name: "prereleases", current: "v2.0.0", prereleases: true, want: "v1.10.1-rc.1"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			mod := NewPreviousTag().(*PreviousTag)
+			mod.Prereleases = tt.prereleases
+
+			if got := mod.find(tags, "v", tt.current); got != tt.want {
+				t.Errorf("find() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
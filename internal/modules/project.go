@@ -2,8 +2,11 @@ package modules
 
 import (
 	"context"
+	"fmt"
 	"os"
-	"path"
+	"path/filepath"
+	"runtime"
+	"time"
 
 	"github.com/julian7/goshipdone/ctx"
 	"github.com/julian7/goshipdone/modules"
@@ -11,34 +14,108 @@ import (
 
 // Project is a module for setting basic project-specific data
 type Project struct {
-	Name      string
+	// ChangePaths are git pathspecs (relative to Path) limiting commits
+	// considered by changelogs, and versioning, like [".", "../shared"].
+	// Default: all commits.
+	ChangePaths []string `yaml:"change_paths"`
+	// ExeExtensions override file name extensions of executables (the
+	// `{{.Exe}}` template field) by OS, architecture, or "os-arch", like
+	// {"js-wasm": ".js"}. Default: ".exe" on windows, ".wasm" for wasm.
+	ExeExtensions map[string]string `yaml:"exe_extensions"`
+	// History is the number of runs kept under TargetDir/.runs, with
+	// their logs, resolved configuration, and report. Zero turns run
+	// history off. Default: 10.
+	History int
+	// LenientTemplates renders missing map keys of templates as "<no
+	// value>", instead of failing. Default: false.
+	LenientTemplates bool `yaml:"lenient_templates"`
+	// LockWait is the maximum time to wait for another run to finish
+	// using TargetDir. Zero fails immediately. Default: 0.
+	LockWait time.Duration `yaml:"lock_wait"`
+	// Name is the project's name. Default: base name of Path, or the
+	// current directory.
+	Name string
+	// Parallelism is the maximum number of modules running concurrently
+	// in a stage (see `needs`). Default: number of CPUs.
+	Parallelism int
+	// Path is the project's directory, for monorepos. Other paths
+	// (including TargetDir) are relative to it, and commands run in it.
+	// The process's working directory is not changed. Default: empty
+	// (current directory).
+	Path string
+	// TagPrefix is the prefix of the project's tags, for monorepos, like
+	// "svc-a/" for "svc-a/v1.2.3" tags. Default: empty.
+	TagPrefix string `yaml:"tag_prefix"`
 	TargetDir string `yaml:"target"`
 }
 
 // NewProject is the factory function for Project
 func NewProject() modules.Pluggable {
-	pwd, err := os.Getwd()
-	if err != nil {
-		pwd = "."
-	} else {
-		pwd = path.Base(pwd)
-	}
-
 	return &Project{
-		Name:      pwd,
-		TargetDir: "dist",
+		History:     ctx.DefaultHistory,
+		Parallelism: runtime.NumCPU(),
+		TargetDir:   "dist",
 	}
 }
 
-// Run records project's basic information into ctx.Context
+// Run records project's basic information into ctx.Context, and locks
+// TargetDir against concurrent runs
 func (mod *Project) Run(cx context.Context) error {
 	context, err := ctx.GetShipContext(cx)
 	if err != nil {
 		return err
 	}
 
-	context.ProjectName = mod.Name
-	context.TargetDir = mod.TargetDir
+	dir, err := projectDir(mod.Path)
+	if err != nil {
+		return err
+	}
+
+	if mod.Path != "" {
+		context.Dir = dir
+	}
+
+	name := mod.Name
+	if name == "" {
+		name = filepath.Base(dir)
+	}
+
+	context.ExeExtensions = mod.ExeExtensions
+	context.Git.Paths = mod.ChangePaths
+	context.Git.TagPrefix = mod.TagPrefix
+	context.History = mod.History
+	context.LenientTemplates = mod.LenientTemplates
+	context.Parallelism = mod.Parallelism
+	context.ProjectName = name
+	context.TargetDir = context.Path(mod.TargetDir)
+
+	if err := context.LockTargetDir(mod.LockWait); err != nil {
+		return err
+	}
 
 	return nil
 }
+
+// projectDir returns the absolute path of the project's directory, or
+// the current directory, if path is empty
+func projectDir(path string) (string, error) {
+	if path == "" {
+		return os.Getwd()
+	}
+
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("project path: %w", err)
+	}
+
+	st, err := os.Stat(dir)
+	if err != nil {
+		return "", fmt.Errorf("project path: %w", err)
+	}
+
+	if !st.IsDir() {
+		return "", fmt.Errorf("project path %s is not a directory", dir)
+	}
+
+	return dir, nil
+}
@@ -0,0 +1,121 @@
+package modules
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+func TestProject_Run(t *testing.T) {
+	root := t.TempDir()
+	svc := filepath.Join(root, "svc")
+
+	if err := os.Mkdir(svc, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "file"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		projName   string
+		targetDir  string
+		wantDir    string
+		wantName   string
+		wantTarget string
+		wantErr    bool
+	}{
+		{
+			name:       "path",
+			path:       svc,
+			targetDir:  "dist",
+			wantDir:    svc,
+			wantName:   "svc",
+			wantTarget: filepath.Join(svc, "dist"),
+		},
+		{
+			name:       "named",
+			path:       svc,
+			projName:   "app",
+			targetDir:  filepath.Join(root, "out"),
+			wantDir:    svc,
+			wantName:   "app",
+			wantTarget: filepath.Join(root, "out"),
+		},
+		{
+			name:       "current directory",
+			targetDir:  filepath.Join(root, "cwd"),
+			wantName:   filepath.Base(wd),
+			wantTarget: filepath.Join(root, "cwd"),
+		},
+		{
+			name:    "missing path",
+			path:    filepath.Join(root, "missing"),
+			wantErr: true,
+		},
+		{
+			name:    "not a directory",
+			path:    filepath.Join(root, "file"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			cx := ctx.New(context.Background())
+
+			shipContext, err := ctx.GetShipContext(cx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mod := NewProject().(*Project)
+			mod.Path = tt.path
+			mod.Name = tt.projName
+			mod.TargetDir = tt.targetDir
+
+			err = mod.Run(cx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Project.Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			defer func() {
+				if err := shipContext.UnlockTargetDir(); err != nil {
+					t.Error(err)
+				}
+			}()
+
+			if shipContext.Dir != tt.wantDir {
+				t.Errorf("Dir = %q, want %q", shipContext.Dir, tt.wantDir)
+			}
+
+			if shipContext.ProjectName != tt.wantName {
+				t.Errorf("ProjectName = %q, want %q", shipContext.ProjectName, tt.wantName)
+			}
+
+			if shipContext.TargetDir != tt.wantTarget {
+				t.Errorf("TargetDir = %q, want %q", shipContext.TargetDir, tt.wantTarget)
+			}
+
+			if cwd, err := os.Getwd(); err != nil || cwd != wd {
+				t.Errorf("working directory changed to %q (%v)", cwd, err)
+			}
+		})
+	}
+}
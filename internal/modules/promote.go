@@ -0,0 +1,91 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/internal/artifacts"
+	"github.com/julian7/goshipdone/modules"
+)
+
+// Promote is a publish module publishing a draft release created by
+// publish:artifact with `draft: true`. It is meant to be run by a separate
+// pipeline, after the draft's artifacts have been verified.
+type Promote struct {
+	// Name specifies the repository's name. Required.
+	Name string
+	// Owner specifies the repository's owning organization. Required.
+	Owner string
+	// SkipTLSVerify allows connecting to servers with invalid TLS certs.
+	// default: false
+	SkipTLSVerify bool `yaml:"skip_tls_verify"`
+	// Storage specifies which artifact service we are using. Default: "github".
+	Storage *artifacts.Storage
+	// Tag is the release's tag (template). Default: the current git tag,
+	// or the version.
+	Tag string
+	// TokenEnv specifies which environment variable the module should look
+	// for for server token. It is discovered from artifacts.Storage if not set.
+	TokenEnv string `yaml:"token_env"`
+	// TokenFile specifies which file the module should look for artifact storage
+	// token. It is discovered from artifacts.Storage if not set.
+	TokenFile string `yaml:"token_file"`
+	// URL base URL for the artifact storage. Provide this only for on-premises services.
+	URL string
+}
+
+// NewPromote is a factory method for Promote module
+func NewPromote() modules.Pluggable {
+	storage, _ := artifacts.New("github")
+
+	return &Promote{Storage: storage}
+}
+
+// Run publishes the draft release
+func (mod *Promote) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	tag := releaseTag(context)
+
+	if mod.Tag != "" {
+		td, err := modules.NewTemplate(cx)
+		if err != nil {
+			return err
+		}
+
+		tag, err = td.Parse("promote-tag", mod.Tag)
+		if err != nil {
+			return fmt.Errorf("parsing tag: %w", err)
+		}
+	}
+
+	artifact := &Artifact{
+		Name:          mod.Name,
+		Owner:         mod.Owner,
+		SkipTLSVerify: mod.SkipTLSVerify,
+		Storage:       mod.Storage,
+		TokenEnv:      mod.TokenEnv,
+		TokenFile:     mod.TokenFile,
+		URL:           mod.URL,
+	}
+
+	client, err := artifact.NewClient(cx)
+	if err != nil {
+		return err
+	}
+
+	context.Progress.SetState(fmt.Sprintf("promoting release %s", tag))
+
+	url, err := client.Promote(tag)
+	if err != nil {
+		return err
+	}
+
+	context.Progress.SetState(fmt.Sprintf("release %s is published at %s", tag, url))
+
+	return nil
+}
@@ -0,0 +1,152 @@
+package modules
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/julian7/goshipdone/ctx"
+)
+
+// nolint: funlen
+func TestPromote_Run(t *testing.T) {
+	tests := []struct {
+		name    string
+		tag     string
+		gitTag  string
+		version string
+		// status is the status of all API responses, if set
+		status int
+		// editStatus is the status of editing releases, if set
+		editStatus int
+		wantEdits  []string
+		wantLink   string
+		wantErr    string
+	}{
+		{
+			name:      "draft by version",
+			version:   "v1.0.0",
+			wantEdits: []string{`/repos/o/app/releases/1 {"draft":false}`},
+			wantLink:  "https://github.example/o/app/releases/tag/v1.0.0",
+		},
+		{
+			name:     "published by git tag",
+			gitTag:   "v0.9.0",
+			version:  "v1.0.0",
+			wantLink: "https://github.example/o/app/releases/tag/v0.9.0",
+		},
+		{
+			name:      "tag template",
+			tag:       "{{.Version}}",
+			gitTag:    "v0.9.0",
+			version:   "v1.0.0",
+			wantEdits: []string{`/repos/o/app/releases/1 {"draft":false}`},
+			wantLink:  "https://github.example/o/app/releases/tag/v1.0.0",
+		},
+		{
+			name:    "missing release",
+			version: "v2.0.0",
+			wantErr: "release v2.0.0 not found",
+		},
+		{
+			name:    "bad tag template",
+			tag:     "{{.Version",
+			version: "v1.0.0",
+			wantErr: "parsing tag",
+		},
+		{
+			name:    "server error",
+			version: "v1.0.0",
+			status:  http.StatusInternalServerError,
+			wantErr: "searching release v1.0.0",
+		},
+		{
+			name:       "rejected promotion",
+			version:    "v1.0.0",
+			editStatus: http.StatusUnprocessableEntity,
+			wantErr:    "promoting release v1.0.0",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu    sync.Mutex
+				edits []string
+			)
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if auth := r.Header.Get("Authorization"); auth != "Bearer token" {
+					t.Errorf("Authorization = %q, want token", auth)
+				}
+
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+					return
+				}
+
+				path := strings.TrimPrefix(r.URL.Path, "/api/v3")
+
+				switch {
+				case r.Method == http.MethodGet && path == "/repos/o/app/releases/tags/v0.9.0":
+					_, _ = io.WriteString(w, `{"id":2,"tag_name":"v0.9.0","html_url":"https://github.example/o/app/releases/tag/v0.9.0"}`)
+				case r.Method == http.MethodGet && path == "/repos/o/app/releases":
+					_, _ = io.WriteString(w, `[{"id":1,"tag_name":"v1.0.0","draft":true},{"id":2,"tag_name":"v0.9.0"}]`)
+				case r.Method == http.MethodPatch && tt.editStatus != 0:
+					w.WriteHeader(tt.editStatus)
+				case r.Method == http.MethodPatch:
+					body, _ := io.ReadAll(r.Body)
+
+					mu.Lock()
+					edits = append(edits, path+" "+strings.TrimSpace(string(body)))
+					mu.Unlock()
+
+					_, _ = io.WriteString(w, `{"id":1,"tag_name":"v1.0.0","html_url":"https://github.example/o/app/releases/tag/v1.0.0"}`)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer srv.Close()
+
+			cx, shipContext := testShipContext(t)
+			shipContext.Git.Tag = tt.gitTag
+			shipContext.Version = tt.version
+			shipContext.Env.Set("GITHUB_TOKEN", "token")
+
+			mod := NewPromote().(*Promote)
+			mod.Name = "app"
+			mod.Owner = "o"
+			mod.Tag = tt.tag
+			mod.URL = srv.URL + "/"
+
+			err := mod.Run(cx)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Run() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			if diff := deep.Equal(edits, tt.wantEdits); diff != nil {
+				t.Errorf("release edits %v", diff)
+			}
+
+			want := []ctx.Link{{Name: "github release", URL: tt.wantLink}}
+			if diff := deep.Equal(shipContext.Published.Release().Links, want); diff != nil {
+				t.Errorf("published links %v", diff)
+			}
+		})
+	}
+}
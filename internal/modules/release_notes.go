@@ -0,0 +1,266 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+const releaseNotesTemplate = `{{with .Header}}{{.}}
+
+{{end}}{{with .Changelog}}{{.}}
+
+{{end}}{{with .Artifacts}}## Checksums
+
+| File | Size | SHA256 |
+| :--- | ---: | :----- |
+{{range .}}| {{.Filename}} | {{.Size}} | ` + "`{{.SHA256}}`" + ` |
+{{end}}
+{{end}}{{with .Install}}## Installation
+{{range .}}
+### {{.Platform}}
+
+` + "```" + `
+{{.Command}}
+` + "```" + `
+{{end}}{{end}}`
+
+type (
+	// ReleaseNotes is a build module composing release notes from a
+	// header, the changelog, a checksum table of artifacts, and
+	// installation instructions per platform.
+	ReleaseNotes struct {
+		// Builds specifies artifacts listed in the checksum table, and
+		// installation instructions. Default: ["archive"].
+		Builds []string
+		// Changelog is the artifact ID of the changelog. It is optional.
+		// Default: "changelog".
+		Changelog string
+		// Header is the first paragraph of release notes (template of
+		// modules.TemplateData). Default: empty.
+		Header string
+		// ID is the artifact ID of release notes. Default: "release_notes".
+		ID string
+		// Install maps operating systems to installation instructions
+		// (templates of modules.TemplateData, with the artifact's
+		// `.Filename`, `.OSArch`, and `.Artifact`), like
+		// {"linux": "tar -xzf {{.Filename}}"}. Instructions are listed for
+		// each platform of selected artifacts.
+		Install map[string]string
+		// Output is the file name of release notes under the target
+		// directory. Default: "RELEASE_NOTES.md".
+		Output string
+		// Skip specifies GOOS-GOArch combinations to be skipped.
+		// They are in `{{.Os}}-{{.Arch}}` format.
+		Skip []string
+		// Template is the template of release notes (ReleaseNotesData,
+		// with `json`, and `highlights` functions). Default: header,
+		// changelog, checksums, and installation.
+		Template string
+	}
+
+	// ReleaseNotesData is the data of release notes templates
+	ReleaseNotesData struct {
+		*modules.TemplateData
+		// Artifacts are the selected artifacts, ordered by file name
+		Artifacts []*ReleaseNotesArtifact
+		// Changelog is the contents of the changelog artifact
+		Changelog string
+		// Header is the rendered header
+		Header string
+		// Install are installation instructions, ordered by platform
+		Install []*ReleaseNotesInstall
+	}
+
+	// ReleaseNotesArtifact is an artifact in release notes
+	ReleaseNotesArtifact struct {
+		Filename string
+		OsArch   string
+		SHA256   string
+		Size     string
+	}
+
+	// ReleaseNotesInstall is the installation instruction of a platform
+	ReleaseNotesInstall struct {
+		Command  string
+		Platform string
+	}
+)
+
+// NewReleaseNotes is a factory function for ReleaseNotes module
+func NewReleaseNotes() modules.Pluggable {
+	return &ReleaseNotes{
+		Builds:    []string{"archive"},
+		Changelog: "changelog",
+		ID:        "release_notes",
+		Install:   map[string]string{},
+		Output:    "RELEASE_NOTES.md",
+		Skip:      []string{},
+	}
+}
+
+// ValidateTemplates implements modules.TemplateValidator
+func (mod *ReleaseNotes) ValidateTemplates(cx context.Context) ([]string, error) {
+	if mod.Template == "" {
+		return []string{"Template"}, nil
+	}
+
+	td, err := modules.SampleTemplate(cx)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &ReleaseNotesData{
+		TemplateData: td,
+		Artifacts: []*ReleaseNotesArtifact{{
+			Filename: td.ArchiveName,
+			OsArch:   td.OSArch.String(),
+			SHA256:   strings.Repeat("0", 64),
+			Size:     "1.0 MiB",
+		}},
+		Changelog: "- change",
+		Install:   []*ReleaseNotesInstall{{Command: "tar -xzf " + td.ArchiveName, Platform: td.OSArch.String()}},
+	}
+
+	_, err = renderPayload("release-notes", mod.Template, data)
+
+	return []string{"Template"}, err
+}
+
+// Run renders release notes
+func (mod *ReleaseNotes) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	td, err := modules.NewTemplate(cx)
+	if err != nil {
+		return err
+	}
+
+	data := &ReleaseNotesData{TemplateData: td}
+
+	if mod.Header != "" {
+		if data.Header, err = td.Parse("release-notes-header", mod.Header); err != nil {
+			return fmt.Errorf("rendering header: %w", err)
+		}
+	}
+
+	if mod.Changelog != "" {
+		if changelogs := *context.Artifacts.ByID(mod.Changelog); len(changelogs) > 0 {
+			content, err := ioutil.ReadFile(changelogs[0].Location)
+			if err != nil {
+				return fmt.Errorf("reading changelog: %w", err)
+			}
+
+			data.Changelog = strings.TrimSpace(string(content))
+		}
+	}
+
+	if err := mod.collect(cx, data); err != nil {
+		return err
+	}
+
+	text := mod.Template
+	if text == "" {
+		text = releaseNotesTemplate
+	}
+
+	notes, err := renderPayload("release-notes", text, data)
+	if err != nil {
+		return err
+	}
+
+	outfile := filepath.Join(context.TargetDir, mod.Output)
+
+	if err := os.WriteFile(outfile, []byte(strings.TrimSpace(notes)+"\n"), 0o644); err != nil { // nolint: gosec
+		return fmt.Errorf("writing release notes %s: %w", outfile, err)
+	}
+
+	context.Artifacts.Add(&ctx.Artifact{
+		ID:       mod.ID,
+		Filename: mod.Output,
+		Location: outfile,
+	})
+
+	return nil
+}
+
+// collect collects selected artifacts' checksums, and installation
+// instructions
+func (mod *ReleaseNotes) collect(cx context.Context, data *ReleaseNotesData) error {
+	builds, err := modules.SelectArtifacts(cx, mod.Builds, mod.Skip, "")
+	if err != nil {
+		return err
+	}
+
+	algo, err := modules.NewHashAlgorithm(modules.DefaultDigest)
+	if err != nil {
+		return err
+	}
+
+	install := map[string]string{}
+
+	for _, build := range builds {
+		for _, artifact := range *build {
+			sum, err := algo.SumArtifact(artifact)
+			if err != nil {
+				return fmt.Errorf("calculating checksum of %s: %w", artifact.Filename, err)
+			}
+
+			data.Artifacts = append(data.Artifacts, &ReleaseNotesArtifact{
+				Filename: artifact.Filename,
+				OsArch:   artifact.OsArch.String(),
+				SHA256:   sum,
+				Size:     fileSize(artifact),
+			})
+
+			if artifact.OsArch == nil || mod.Install[artifact.OsArch.OS] == "" {
+				continue
+			}
+
+			td, err := modules.NewTemplate(cx)
+			if err != nil {
+				return err
+			}
+
+			td.Artifact = artifact
+			td.Filename = artifact.Filename
+			td.OSArch = artifact.OsArch
+
+			command, err := td.Parse("release-notes-install", mod.Install[artifact.OsArch.OS])
+			if err != nil {
+				return fmt.Errorf("rendering installation of %s: %w", artifact.OsArch, err)
+			}
+
+			platform := artifact.OsArch.String()
+			if previous, ok := install[platform]; ok {
+				command = previous + "\n" + command
+			}
+
+			install[platform] = strings.TrimSpace(command)
+		}
+	}
+
+	sort.Slice(data.Artifacts, func(i, j int) bool {
+		return data.Artifacts[i].Filename < data.Artifacts[j].Filename
+	})
+
+	for platform, command := range install {
+		data.Install = append(data.Install, &ReleaseNotesInstall{Command: command, Platform: platform})
+	}
+
+	sort.Slice(data.Install, func(i, j int) bool {
+		return data.Install[i].Platform < data.Install[j].Platform
+	})
+
+	return nil
+}
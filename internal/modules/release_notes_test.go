@@ -0,0 +1,104 @@
+package modules
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+func TestReleaseNotes_Run(t *testing.T) {
+	cx := ctx.New(context.Background())
+
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	context.ProjectName = "app"
+	context.Version = "v1.2.3"
+	context.TargetDir = t.TempDir()
+
+	files := map[string]string{
+		"CHANGELOG.md":            "### Fixed\n\n- cost is $5 now\n",
+		"app-linux-amd64.tar.gz":  "hello",
+		"app-darwin-arm64.tar.gz": "hello",
+		"app-windows-amd64.zip":   "hello",
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(context.TargetDir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	context.Artifacts.Add(&ctx.Artifact{ID: "changelog", Filename: "CHANGELOG.md", Location: filepath.Join(context.TargetDir, "CHANGELOG.md")})
+
+	for _, osarch := range []*ctx.OsArch{{OS: "linux", Arch: "amd64"}, {OS: "darwin", Arch: "arm64"}, {OS: "windows", Arch: "amd64"}} {
+		name := "app-" + osarch.String() + ".tar.gz"
+		if osarch.OS == "windows" {
+			name = "app-" + osarch.String() + ".zip"
+		}
+
+		context.Artifacts.Add(&ctx.Artifact{
+			ID:       "archive",
+			Filename: name,
+			Location: filepath.Join(context.TargetDir, name),
+			OsArch:   osarch,
+		})
+	}
+
+	mod := NewReleaseNotes().(*ReleaseNotes)
+	mod.Header = "{{.ProjectName}} {{.Version}} is out!"
+	mod.Install = map[string]string{
+		"linux":  "tar -xzf {{.Filename}}",
+		"darwin": "brew install {{.ProjectName}}",
+	}
+	mod.Skip = []string{"windows-amd64"}
+
+	if err := mod.Run(cx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(context.TargetDir, "RELEASE_NOTES.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "app v1.2.3 is out!\n" +
+		"\n" +
+		"### Fixed\n" +
+		"\n" +
+		"- cost is $5 now\n" +
+		"\n" +
+		"## Checksums\n" +
+		"\n" +
+		"| File | Size | SHA256 |\n" +
+		"| :--- | ---: | :----- |\n" +
+		"| app-darwin-arm64.tar.gz | 5 B | `2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824` |\n" +
+		"| app-linux-amd64.tar.gz | 5 B | `2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824` |\n" +
+		"\n" +
+		"## Installation\n" +
+		"\n" +
+		"### darwin-arm64\n" +
+		"\n" +
+		"```\n" +
+		"brew install app\n" +
+		"```\n" +
+		"\n" +
+		"### linux-amd64\n" +
+		"\n" +
+		"```\n" +
+		"tar -xzf app-linux-amd64.tar.gz\n" +
+		"```\n"
+
+	if string(got) != want {
+		t.Errorf("Run() wrote:\n%s\nwant:\n%s", got, want)
+	}
+
+	if notes := *context.Artifacts.ByID("release_notes"); len(notes) != 1 {
+		t.Errorf("Run() stored %d release notes artifacts, want 1", len(notes))
+	}
+}
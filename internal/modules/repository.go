@@ -0,0 +1,103 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+// gpgSign signs a file with a GnuPG key, using the key's passphrase from
+// an environment variable if set. Args select the signature format, like
+// "--clearsign", or "--armor", "--detach-sign".
+func gpgSign(cx context.Context, context *ctx.Context, key, passphraseEnv, input, output string, args ...string) error {
+	gpgArgs := []string{"--batch", "--yes", "--local-user", key}
+
+	var stdin io.Reader
+
+	if passphraseEnv != "" {
+		passphrase, err := context.RequireEnv(passphraseEnv, "signing key passphrase")
+		if err != nil {
+			return err
+		}
+
+		gpgArgs = append(gpgArgs, "--pinentry-mode", "loopback", "--passphrase-fd", "0")
+		stdin = strings.NewReader(passphrase)
+	}
+
+	gpgArgs = append(gpgArgs, args...)
+	gpgArgs = append(gpgArgs, "--output", output, input)
+
+	cmd := exec.CommandContext(cx, "gpg", gpgArgs...)
+	cmd.Dir = context.Dir
+	cmd.Env = context.Env.Environ()
+	cmd.Stdin = stdin
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("gpg: signing %s: %w\n%s", filepath.Base(input), err, out)
+	}
+
+	return nil
+}
+
+// copyRepoFile copies a file into the repository, unless it is already
+// there
+func copyRepoFile(src, dst string) error {
+	if src == dst {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return fmt.Errorf("creating directory for %s: %w", dst, err)
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("copying %s: %w", src, err)
+	}
+
+	return out.Close()
+}
+
+func writeRepoFile(dir, name string, content []byte) error {
+	location := filepath.Join(dir, filepath.FromSlash(name))
+
+	if err := os.MkdirAll(filepath.Dir(location), 0o755); err != nil {
+		return fmt.Errorf("creating directory for %s: %w", name, err)
+	}
+
+	if err := os.WriteFile(location, content, 0o644); err != nil { // nolint: gosec
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// repoFileNames returns names of repository files in order
+func repoFileNames(files map[string][]byte) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,134 @@
+package modules
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+// nolint: gochecknoglobals
+var reRsyncTransferred = regexp.MustCompile(`(?m)^Total transferred file size: ([\d,.]+)`)
+
+// Rsync is a publish module mirroring a local directory (the target
+// directory by default) to a remote rsync destination
+type Rsync struct {
+	// Args are additional rsync arguments, like ["--rsh", "ssh -p 2222"].
+	Args []string
+	// Delete removes files from the destination which don't exist in
+	// the source. Default: false.
+	Delete bool
+	// Exclude lists rsync patterns of files not to be mirrored.
+	Exclude []string
+	// Include lists rsync patterns of files to be mirrored, even if they
+	// match Exclude patterns. Use it with Exclude: ["*"] for mirroring
+	// selected files only.
+	Include []string
+	// Source is the local directory to be mirrored. Default: target
+	// directory.
+	Source string
+	// Target is the rsync destination (template), like
+	// "mirror.example.com:/srv/{{.ProjectName}}/{{.Version}}/", or
+	// "rsync://mirror.example.com/module/". Required.
+	Target string
+}
+
+// NewRsync is a factory function for Rsync module
+func NewRsync() modules.Pluggable {
+	return &Rsync{
+		Args:    []string{},
+		Exclude: []string{},
+		Include: []string{},
+	}
+}
+
+// Run mirrors the source directory to the destination
+func (mod *Rsync) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	if mod.Target == "" {
+		return errors.New("target is not specified")
+	}
+
+	td, err := modules.NewTemplate(cx)
+	if err != nil {
+		return err
+	}
+
+	target, err := td.Parse("rsync-target", mod.Target)
+	if err != nil {
+		return fmt.Errorf("rendering target: %w", err)
+	}
+
+	source := mod.Source
+	if source == "" {
+		source = context.TargetDir
+	}
+
+	context.Progress.SetState(fmt.Sprintf("mirroring %s to %s", source, target))
+
+	out := &bytes.Buffer{}
+	cmd := exec.CommandContext(cx, "rsync", mod.args(source, target)...)
+	cmd.Dir = context.Dir
+	cmd.Env = context.Env.Environ()
+	cmd.Stdout = io.MultiWriter(os.Stdout, out)
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("mirroring to %s: %w", target, err)
+	}
+
+	size := rsyncTransferred(out.String())
+
+	context.Progress.AddBytes(size)
+	context.Transfers.Upload("rsync:"+target, size)
+
+	return nil
+}
+
+// args returns rsync's arguments. Source gets a trailing slash to mirror
+// its contents, not the directory itself.
+func (mod *Rsync) args(source, target string) []string {
+	args := []string{"--archive", "--stats"}
+
+	if mod.Delete {
+		args = append(args, "--delete")
+	}
+
+	// rsync applies the first matching filter rule
+	for _, pattern := range mod.Include {
+		args = append(args, "--include", pattern)
+	}
+
+	for _, pattern := range mod.Exclude {
+		args = append(args, "--exclude", pattern)
+	}
+
+	args = append(args, mod.Args...)
+
+	return append(args, strings.TrimSuffix(source, "/")+"/", target)
+}
+
+// rsyncTransferred parses the transferred size from rsync's statistics
+func rsyncTransferred(stats string) int64 {
+	match := reRsyncTransferred.FindStringSubmatch(stats)
+	if match == nil {
+		return 0
+	}
+
+	size, _ := strconv.ParseInt(strings.NewReplacer(",", "", ".", "").Replace(match[1]), 10, 64)
+
+	return size
+}
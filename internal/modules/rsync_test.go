@@ -0,0 +1,38 @@
+package modules
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRsync_args(t *testing.T) {
+	mod := NewRsync().(*Rsync)
+	mod.Delete = true
+	mod.Exclude = []string{"*"}
+	mod.Include = []string{"*.tar.gz"}
+	mod.Args = []string{"--rsh", "ssh -p 2222"}
+
+	want := []string{
+		"--archive", "--stats", "--delete",
+		"--include", "*.tar.gz",
+		"--exclude", "*",
+		"--rsh", "ssh -p 2222",
+		"dist/", "mirror:/srv/app/",
+	}
+
+	if got := mod.args("dist", "mirror:/srv/app/"); !reflect.DeepEqual(got, want) {
+		t.Errorf("args() = %q, want %q", got, want)
+	}
+}
+
+func TestRsyncTransferred(t *testing.T) {
+	stats := "Number of files: 3 (reg: 2, dir: 1)\nTotal file size: 12,345 bytes\nTotal transferred file size: 1,234,567 bytes\n"
+
+	if got := rsyncTransferred(stats); got != 1234567 {
+		t.Errorf("rsyncTransferred() = %d, want 1234567", got)
+	}
+
+	if got := rsyncTransferred("no stats"); got != 0 {
+		t.Errorf("rsyncTransferred() = %d, want 0", got)
+	}
+}
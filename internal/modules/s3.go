@@ -0,0 +1,105 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/internal/blob"
+	"github.com/julian7/goshipdone/modules"
+)
+
+// S3 is a publish module uploading artifacts to S3-compatible object
+// storages, like AWS S3, MinIO, or DigitalOcean Spaces.
+type S3 struct {
+	BlobPublisher `yaml:",inline"`
+	// AccessKeyEnv specifies the environment variable of the access key
+	// ID. Default: AWS_ACCESS_KEY_ID.
+	AccessKeyEnv string `yaml:"access_key_env"`
+	// ACL sets a canned ACL on uploaded objects, like "public-read".
+	// Default: empty (bucket default).
+	ACL string
+	// Bucket is the target bucket's name. Required.
+	Bucket string
+	// Endpoint is the storage service's URL. Scheme is https if not
+	// specified. Default: s3.amazonaws.com.
+	Endpoint string
+	// PartSize sets the part size of multipart uploads in bytes. Larger
+	// files are uploaded in multiple parts. Default: 64 MiB.
+	PartSize int64 `yaml:"part_size"`
+	// PathStyle puts bucket name into the URL path instead of the host
+	// name. Required by MinIO. Default: false.
+	PathStyle bool `yaml:"path_style"`
+	// Region is the bucket's region. Default: us-east-1.
+	Region string
+	// SecretKeyEnv specifies the environment variable of the secret
+	// access key. Default: AWS_SECRET_ACCESS_KEY.
+	SecretKeyEnv string `yaml:"secret_key_env"`
+	// SessionTokenEnv specifies the environment variable of the optional
+	// session token. Default: AWS_SESSION_TOKEN.
+	SessionTokenEnv string `yaml:"session_token_env"`
+	// StorageClass sets uploaded objects' storage class, like
+	// "STANDARD_IA". Default: empty (bucket default).
+	StorageClass string `yaml:"storage_class"`
+}
+
+// NewS3 is a factory function for S3 module
+func NewS3() modules.Pluggable {
+	return &S3{
+		BlobPublisher:   newBlobPublisher(),
+		AccessKeyEnv:    "AWS_ACCESS_KEY_ID",
+		Endpoint:        "s3.amazonaws.com",
+		PartSize:        blob.DefaultPartSize,
+		Region:          "us-east-1",
+		SecretKeyEnv:    "AWS_SECRET_ACCESS_KEY",
+		SessionTokenEnv: "AWS_SESSION_TOKEN",
+	}
+}
+
+// Run uploads selected artifacts into an S3 bucket
+func (mod *S3) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	client, err := mod.client(context)
+	if err != nil {
+		return err
+	}
+
+	return mod.publish(cx, client, blob.UploadOptions{ACL: mod.ACL, StorageClass: mod.StorageClass})
+}
+
+func (mod *S3) client(context *ctx.Context) (*blob.S3, error) {
+	if mod.Bucket == "" {
+		return nil, fmt.Errorf("bucket is not specified")
+	}
+
+	endpointURL, err := parseEndpoint(mod.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	accessKey, err := context.RequireEnv(mod.AccessKeyEnv, "access key")
+	if err != nil {
+		return nil, err
+	}
+
+	secretKey, err := context.RequireEnv(mod.SecretKeyEnv, "secret key")
+	if err != nil {
+		return nil, err
+	}
+
+	return &blob.S3{
+		AccessKey:    accessKey,
+		Bucket:       mod.Bucket,
+		Endpoint:     endpointURL,
+		PartSize:     mod.PartSize,
+		PathStyle:    mod.PathStyle,
+		Progress:     context.Progress.Reader,
+		Region:       mod.Region,
+		SecretKey:    secretKey,
+		SessionToken: context.Getenv(mod.SessionTokenEnv, ""),
+	}, nil
+}
@@ -0,0 +1,156 @@
+package modules
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/julian7/goshipdone/ctx"
+	"gopkg.in/yaml.v3"
+)
+
+// nolint: funlen
+func TestS3_Run(t *testing.T) {
+	credentials := map[string]string{"AWS_ACCESS_KEY_ID": "access", "AWS_SECRET_ACCESS_KEY": "secret"}
+
+	tests := []struct {
+		name string
+		// config is the module's configuration, ENDPOINT standing for
+		// the test storage's URL, and FORBIDDEN for a storage rejecting
+		// uploads
+		config      string
+		env         map[string]string
+		wantObjects []string
+		// wantHeaders are request headers of the linux archive's upload
+		wantHeaders map[string]string
+		wantErr     string
+	}{
+		{
+			name:        "default prefix",
+			config:      "bucket: releases\nendpoint: ENDPOINT\npath_style: true\n",
+			env:         credentials,
+			wantObjects: []string{"releases/app/v1.0.0/app-linux-amd64.tar.gz", "releases/app/v1.0.0/app-windows-amd64.zip"},
+			wantHeaders: map[string]string{"X-Amz-Acl": "", "X-Amz-Security-Token": "", "X-Amz-Storage-Class": ""},
+		},
+		{
+			name: "object settings",
+			config: "bucket: releases\nendpoint: ENDPOINT\npath_style: true\nprefix: \"{{.ProjectName}}/\"\n" +
+				"acl: public-read\nstorage_class: STANDARD_IA\n",
+			env:         map[string]string{"AWS_ACCESS_KEY_ID": "access", "AWS_SECRET_ACCESS_KEY": "secret", "AWS_SESSION_TOKEN": "session"},
+			wantObjects: []string{"releases/app/app-linux-amd64.tar.gz", "releases/app/app-windows-amd64.zip"},
+			wantHeaders: map[string]string{
+				"X-Amz-Acl":            "public-read",
+				"X-Amz-Security-Token": "session",
+				"X-Amz-Storage-Class":  "STANDARD_IA",
+			},
+		},
+		{
+			name:        "custom credential variables",
+			config:      "bucket: releases\nendpoint: ENDPOINT\npath_style: true\naccess_key_env: KEY\nsecret_key_env: SECRET\n",
+			env:         map[string]string{"KEY": "access", "SECRET": "secret"},
+			wantObjects: []string{"releases/app/v1.0.0/app-linux-amd64.tar.gz", "releases/app/v1.0.0/app-windows-amd64.zip"},
+		},
+		{
+			name:    "no bucket",
+			config:  "endpoint: ENDPOINT\n",
+			env:     credentials,
+			wantErr: "bucket is not specified",
+		},
+		{
+			name:    "no access key",
+			config:  "bucket: releases\nendpoint: ENDPOINT\n",
+			env:     map[string]string{"AWS_SECRET_ACCESS_KEY": "secret"},
+			wantErr: "access key not found in $AWS_ACCESS_KEY_ID",
+		},
+		{
+			name:    "no secret key",
+			config:  "bucket: releases\nendpoint: ENDPOINT\n",
+			env:     map[string]string{"AWS_ACCESS_KEY_ID": "access"},
+			wantErr: "secret key not found in $AWS_SECRET_ACCESS_KEY",
+		},
+		{
+			name:    "bad prefix",
+			config:  "bucket: releases\nendpoint: ENDPOINT\npath_style: true\nprefix: \"{{.Version\"\n",
+			env:     credentials,
+			wantErr: "parsing prefix",
+		},
+		{
+			name:    "rejected upload",
+			config:  "bucket: releases\nendpoint: FORBIDDEN\npath_style: true\nparallelism: 1\n",
+			env:     credentials,
+			wantErr: "uploading app-linux-amd64.tar.gz to s3:releases",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			storage, objects := testStorage(t, http.StatusOK)
+			forbidden, _ := testStorage(t, http.StatusForbidden)
+
+			cx, shipContext := testShipContext(t)
+			linux := addTestArtifact(t, shipContext, "archive", "app-linux-amd64.tar.gz", &ctx.OsArch{OS: "linux", Arch: "amd64"})
+			addTestArtifact(t, shipContext, "archive", "app-windows-amd64.zip", &ctx.OsArch{OS: "windows", Arch: "amd64"})
+			addTestArtifact(t, shipContext, "default", "app", &ctx.OsArch{OS: "linux", Arch: "amd64"})
+
+			for key, value := range tt.env {
+				shipContext.Env.Set(key, value)
+			}
+
+			mod := NewS3()
+			config := strings.NewReplacer("ENDPOINT", storage.URL, "FORBIDDEN", forbidden.URL).Replace(tt.config)
+
+			if err := yaml.Unmarshal([]byte(config), mod); err != nil {
+				t.Fatal(err)
+			}
+
+			err := mod.Run(cx)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Run() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			got := []string{}
+			for key := range objects() {
+				got = append(got, key)
+			}
+
+			sort.Strings(got)
+
+			if diff := deep.Equal(got, tt.wantObjects); diff != nil {
+				t.Errorf("objects %v", diff)
+			}
+
+			header := objects()[tt.wantObjects[0]]
+			if auth := header.Get("Authorization"); !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=access/") {
+				t.Errorf("Authorization = %q, want signature of access key", auth)
+			}
+
+			for name, want := range tt.wantHeaders {
+				if got := header.Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+
+			if got, _ := shipContext.Published.DownloadURL(linux.Filename); got != storage.URL+"/"+tt.wantObjects[0] {
+				t.Errorf("download URL = %q, want %q", got, storage.URL+"/"+tt.wantObjects[0])
+			}
+
+			want := []ctx.Transfer{{Destination: "s3:releases", Uploaded: 43}}
+			if diff := deep.Equal(shipContext.Transfers.List(), want); diff != nil {
+				t.Errorf("transfers %v", diff)
+			}
+		})
+	}
+}
@@ -2,14 +2,20 @@ package modules
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"os/exec"
 
 	"github.com/julian7/goshipdone/ctx"
 	"github.com/julian7/goshipdone/modules"
-	"github.com/magefile/mage/sh"
 )
 
 // SCP is a module for uploading artifacts to a remote server via scp
 type SCP struct {
+	// Artifacts is a template expression selecting artifacts of Builds
+	// (or all artifacts, if Builds is empty), evaluated for each artifact.
+	// See modules.SelectArtifacts.
+	Artifacts string
 	// Builds specifies which build names should be added to the archive.
 	Builds []string
 	// Skip specifies GOOS-GOArch combinations to be skipped.
@@ -37,17 +43,39 @@ func (mod *SCP) Run(cx context.Context) error {
 		return err
 	}
 
-	builds := context.Artifacts.OsArchByIDs(mod.Builds, mod.Skip)
+	builds, err := modules.SelectArtifacts(cx, mod.Builds, mod.Skip, mod.Artifacts)
+	if err != nil {
+		return err
+	}
 
 	cmdArgs := []string{}
 
+	var size int64
+
 	for osarch := range builds {
 		for _, artifact := range *builds[osarch] {
 			cmdArgs = append(cmdArgs, artifact.Location)
+
+			size += artifact.Size
 		}
 	}
 
 	cmdArgs = append(cmdArgs, mod.Target)
 
-	return sh.RunV("scp", cmdArgs...)
+	context.Progress.SetState(fmt.Sprintf("uploading %d file(s) to %s", len(cmdArgs)-1, mod.Target))
+
+	cmd := exec.CommandContext(cx, ctx.LookPath(context.Env, "scp"), cmdArgs...)
+	cmd.Dir = context.Dir
+	cmd.Env = context.Env.Environ()
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+
+	context.Progress.AddBytes(size)
+	context.Transfers.Upload("scp:"+mod.Target, size)
+
+	return nil
 }
@@ -0,0 +1,491 @@
+package modules
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ageHeader is the first line of binary age files
+	ageHeader = "age-encryption.org/v1"
+	// sopsUnencryptedSuffix is the default suffix of SOPS keys, which are
+	// not encrypted
+	sopsUnencryptedSuffix = "_unencrypted"
+)
+
+// nolint: gochecknoglobals
+var (
+	reSopsValue   = regexp.MustCompile(`^ENC\[AES256_GCM,data:([^,]*),iv:([^,]+),tag:([^,]+),type:([a-z]+)\]$`)
+	reEnvNameChar = regexp.MustCompile(`[^A-Z0-9_]`)
+)
+
+type (
+	// Secrets is a setup module loading secrets from SOPS
+	// (https://github.com/mozilla/sops) files encrypted with age keys, or
+	// from age encrypted YAML, JSON, or dotenv files into the context's
+	// environment, for later modules.
+	Secrets struct {
+		// File is the secrets file. Variable expansion is available.
+		// Required.
+		File string
+		// IdentityFile is a file of age identities (one per line). Default:
+		// identities in $SOPS_AGE_KEY, or $SOPS_AGE_KEY_FILE, or
+		// "$XDG_CONFIG_HOME/sops/age/keys.txt". Variable expansion is
+		// available.
+		IdentityFile string `yaml:"identity_file"`
+		// Prefix is prepended to variable names. Default: empty.
+		Prefix string
+	}
+
+	// sopsMetadata is the `sops` section of SOPS files
+	sopsMetadata struct {
+		Age []struct {
+			Enc string `yaml:"enc"`
+		} `yaml:"age"`
+		LastModified      string `yaml:"lastmodified"`
+		MAC               string `yaml:"mac"`
+		MACOnlyEncrypted  bool   `yaml:"mac_only_encrypted"`
+		UnencryptedSuffix string `yaml:"unencrypted_suffix"`
+	}
+)
+
+// NewSecrets is a factory function for Secrets module
+func NewSecrets() modules.Pluggable {
+	return &Secrets{}
+}
+
+// Run decrypts secrets, and sets them as environment variables. Nested
+// keys are joined with "_", and names are upper-cased, like
+// `github: {token: x}` sets GITHUB_TOKEN.
+func (mod *Secrets) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	if mod.File == "" {
+		return errors.New("secrets file is not specified")
+	}
+
+	fn := context.Path(context.Env.Expand(mod.File))
+
+	content, err := os.ReadFile(fn)
+	if err != nil {
+		return fmt.Errorf("reading secrets: %w", err)
+	}
+
+	identities, err := mod.identities(context)
+	if err != nil {
+		return err
+	}
+
+	secrets, err := decryptSecrets(fn, content, identities)
+	if err != nil {
+		return fmt.Errorf("decrypting %s: %w", fn, err)
+	}
+
+	names := make([]string, 0, len(secrets))
+
+	for name, val := range secrets {
+		name = mod.Prefix + name
+		names = append(names, name)
+
+		// Setenv stores secrets as decrypted, without expanding "$"
+		context.Setenv(name, val)
+	}
+
+	sort.Strings(names)
+	log.Printf("      loaded secrets: %s", strings.Join(names, ", "))
+
+	return nil
+}
+
+// identities loads age identities
+func (mod *Secrets) identities(context *ctx.Context) ([]age.Identity, error) {
+	if mod.IdentityFile == "" {
+		if keys, ok := context.LookupEnv("SOPS_AGE_KEY"); ok {
+			return parseAgeIdentities(strings.NewReader(keys))
+		}
+	}
+
+	fn := context.Path(context.Env.Expand(mod.IdentityFile))
+	if fn == "" {
+		fn = context.Getenv("SOPS_AGE_KEY_FILE", path.Join(context.Getenv(EnvConfigHome, ""), "sops", "age", "keys.txt"))
+	}
+
+	file, err := os.Open(fn)
+	if err != nil {
+		return nil, fmt.Errorf("opening age identities: %w", err)
+	}
+
+	defer file.Close()
+
+	identities, err := parseAgeIdentities(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading age identities %s: %w", fn, err)
+	}
+
+	return identities, nil
+}
+
+// parseAgeIdentities parses age identities, skipping empty lines, and
+// comments
+func parseAgeIdentities(r io.Reader) ([]age.Identity, error) {
+	identities := []age.Identity{}
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		identity, err := age.ParseX25519Identity(line)
+		if err != nil {
+			return nil, err
+		}
+
+		identities = append(identities, identity)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(identities) == 0 {
+		return nil, errors.New("no age identities found")
+	}
+
+	return identities, nil
+}
+
+// decryptSecrets decrypts an age encrypted file, or a SOPS file, returning
+// environment variables
+func decryptSecrets(fn string, content []byte, identities []age.Identity) (map[string]string, error) {
+	trimmed := bytes.TrimSpace(content)
+	isAge := bytes.HasPrefix(trimmed, []byte(ageHeader)) || bytes.HasPrefix(trimmed, []byte(armor.Header))
+
+	if !isAge {
+		return decryptSops(content, identities)
+	}
+
+	plain, err := decryptAge(content, identities)
+	if err != nil {
+		return nil, err
+	}
+
+	vars := map[string]string{}
+
+	if strings.Contains(path.Base(fn), ".env") {
+		lookup := func(name string) string { return vars[name] }
+		if err := parseDotenv(string(plain), lookup, vars); err != nil {
+			return nil, err
+		}
+
+		return vars, nil
+	}
+
+	var tree map[string]interface{}
+	if err := yaml.Unmarshal(plain, &tree); err != nil {
+		return nil, fmt.Errorf("parsing secrets: %w", err)
+	}
+
+	return vars, flattenSecrets(tree, nil, func(keys []string, val interface{}) error {
+		vars[secretEnvName(keys)] = fmt.Sprint(val)
+		return nil
+	})
+}
+
+// decryptAge decrypts (optionally armored) age content
+func decryptAge(content []byte, identities []age.Identity) ([]byte, error) {
+	var in io.Reader = bytes.NewReader(content)
+	if bytes.HasPrefix(bytes.TrimSpace(content), []byte(armor.Header)) {
+		in = armor.NewReader(bytes.NewReader(bytes.TrimSpace(content)))
+	}
+
+	out, err := age.Decrypt(in, identities...)
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(out)
+}
+
+// decryptSops decrypts values of a SOPS file, encrypted for age
+// recipients. Values are authenticated, and the file's MAC is verified,
+// therefore values can't be added, removed, or changed, including
+// unencrypted ones.
+func decryptSops(content []byte, identities []age.Identity) (map[string]string, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return nil, fmt.Errorf("parsing secrets: %w", err)
+	}
+
+	var file struct {
+		Sops *sopsMetadata `yaml:"sops"`
+	}
+
+	if err := root.Decode(&file); err != nil || file.Sops == nil {
+		return nil, errors.New("neither an age encrypted file, nor a SOPS file")
+	}
+
+	key, err := file.Sops.dataKey(identities)
+	if err != nil {
+		return nil, err
+	}
+
+	suffix := file.Sops.UnencryptedSuffix
+	if suffix == "" {
+		suffix = sopsUnencryptedSuffix
+	}
+
+	vars := map[string]string{}
+	hash := sha512.New()
+
+	err = walkSopsTree(&root, nil, func(keys []string, node *yaml.Node) error {
+		name := secretEnvName(keys)
+
+		if !sopsEncrypted(keys, suffix) {
+			text, macBytes, err := sopsPlainValue(node)
+			if err != nil {
+				return fmt.Errorf("%s: %w", strings.Join(keys, "."), err)
+			}
+
+			vars[name] = text
+
+			if !file.Sops.MACOnlyEncrypted {
+				hash.Write(macBytes)
+			}
+
+			return nil
+		}
+
+		plain, err := decryptSopsValue(node.Value, key, strings.Join(keys, ":")+":")
+		if err != nil {
+			return fmt.Errorf("decrypting %s: %w", strings.Join(keys, "."), err)
+		}
+
+		vars[name] = plain
+		hash.Write([]byte(plain))
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := file.Sops.verifyMAC(key, fmt.Sprintf("%X", hash.Sum(nil))); err != nil {
+		return nil, err
+	}
+
+	return vars, nil
+}
+
+// walkSopsTree walks nested mappings of a SOPS file in document order
+// (which the MAC depends on), except the `sops` section, calling fn with
+// keys of scalar values
+func walkSopsTree(node *yaml.Node, keys []string, fn func([]string, *yaml.Node) error) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			if err := walkSopsTree(child, keys, fn); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case yaml.MappingNode:
+		for idx := 0; idx+1 < len(node.Content); idx += 2 {
+			key := node.Content[idx].Value
+			if len(keys) == 0 && key == "sops" {
+				continue
+			}
+
+			if err := walkSopsTree(node.Content[idx+1], append(append([]string{}, keys...), key), fn); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case yaml.ScalarNode:
+		if len(keys) == 0 {
+			return errors.New("SOPS file is not a mapping")
+		}
+
+		return fn(keys, node)
+	case yaml.SequenceNode:
+		return fmt.Errorf("%s: lists are not supported", strings.Join(keys, "."))
+	default:
+		return fmt.Errorf("%s: unsupported value", strings.Join(keys, "."))
+	}
+}
+
+// sopsEncrypted returns true if a value is encrypted: none of its keys
+// have the unencrypted suffix
+func sopsEncrypted(keys []string, suffix string) bool {
+	for _, key := range keys {
+		if strings.HasSuffix(key, suffix) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sopsPlainValue returns an unencrypted value as text, and in the form
+// SOPS includes it in the MAC (eg. booleans are "True", and "False")
+func sopsPlainValue(node *yaml.Node) (string, []byte, error) {
+	var val interface{}
+	if err := node.Decode(&val); err != nil {
+		return "", nil, err
+	}
+
+	switch typed := val.(type) {
+	case nil:
+		return "", nil, nil
+	case string:
+		return typed, []byte(typed), nil
+	case int:
+		return node.Value, []byte(strconv.Itoa(typed)), nil
+	case float64:
+		return node.Value, []byte(strconv.FormatFloat(typed, 'f', -1, 64)), nil
+	case bool:
+		return fmt.Sprint(typed), []byte(map[bool]string{true: "True", false: "False"}[typed]), nil
+	default:
+		return "", nil, fmt.Errorf("unsupported value %q", node.Value)
+	}
+}
+
+// verifyMAC checks the file's MAC, which is encrypted with the data key,
+// and authenticated with the modification time
+func (meta *sopsMetadata) verifyMAC(key []byte, mac string) error {
+	if meta.MAC == "" {
+		return errors.New("SOPS file has no MAC")
+	}
+
+	modified, err := time.Parse(time.RFC3339, meta.LastModified)
+	if err != nil {
+		return fmt.Errorf("SOPS file modification time: %w", err)
+	}
+
+	want, err := decryptSopsValue(meta.MAC, key, modified.Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("decrypting SOPS MAC: %w", err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(want), []byte(mac)) != 1 {
+		return errors.New("SOPS MAC mismatch: the file has been tampered with")
+	}
+
+	return nil
+}
+
+// dataKey decrypts SOPS data key with age identities
+func (meta *sopsMetadata) dataKey(identities []age.Identity) ([]byte, error) {
+	if len(meta.Age) == 0 {
+		return nil, errors.New("SOPS file is not encrypted for age recipients")
+	}
+
+	var lastErr error
+
+	for _, recipient := range meta.Age {
+		key, err := decryptAge([]byte(recipient.Enc), identities)
+		if err == nil {
+			return key, nil
+		}
+
+		lastErr = err
+	}
+
+	return nil, fmt.Errorf("decrypting SOPS data key: %w", lastErr)
+}
+
+// decryptSopsValue decrypts an `ENC[AES256_GCM,...]` value
+func decryptSopsValue(value string, key []byte, additionalData string) (string, error) {
+	match := reSopsValue.FindStringSubmatch(value)
+	if match == nil {
+		return "", errors.New("invalid encrypted value")
+	}
+
+	parts := make([][]byte, 3)
+
+	for i := range parts {
+		part, err := base64.StdEncoding.DecodeString(match[i+1])
+		if err != nil {
+			return "", fmt.Errorf("invalid encrypted value: %w", err)
+		}
+
+		parts[i] = part
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(parts[1]))
+	if err != nil {
+		return "", err
+	}
+
+	plain, err := gcm.Open(nil, parts[1], append(parts[0], parts[2]...), []byte(additionalData))
+	if err != nil {
+		return "", err
+	}
+
+	return string(plain), nil
+}
+
+// flattenSecrets walks nested maps, calling fn with keys of leaf values
+func flattenSecrets(tree map[string]interface{}, keys []string, fn func([]string, interface{}) error) error {
+	for key, val := range tree {
+		nested := append(append([]string{}, keys...), key)
+
+		switch typed := val.(type) {
+		case map[string]interface{}:
+			if err := flattenSecrets(typed, nested, fn); err != nil {
+				return err
+			}
+
+			continue
+		case []interface{}:
+			return fmt.Errorf("%s: lists are not supported", strings.Join(nested, "."))
+		case nil:
+			val = ""
+		}
+
+		if err := fn(nested, val); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// secretEnvName returns the environment variable name of nested keys
+func secretEnvName(keys []string) string {
+	return reEnvNameChar.ReplaceAllString(strings.ToUpper(strings.Join(keys, "_")), "_")
+}
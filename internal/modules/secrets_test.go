@@ -0,0 +1,247 @@
+package modules
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+func ageEncrypt(t *testing.T, recipient age.Recipient, plain []byte) []byte {
+	t.Helper()
+
+	out := &bytes.Buffer{}
+	armored := armor.NewWriter(out)
+
+	w, err := age.Encrypt(armored, recipient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.Write(plain); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := armored.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return out.Bytes()
+}
+
+func sopsEncrypt(t *testing.T, key []byte, plain, additionalData string) string {
+	t.Helper()
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	iv := bytes.Repeat([]byte{7}, 32)
+
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed := gcm.Seal(nil, iv, []byte(plain), []byte(additionalData))
+	data, tag := sealed[:len(plain)], sealed[len(plain):]
+	enc := base64.StdEncoding.EncodeToString
+
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:str]", enc(data), enc(iv), enc(tag))
+}
+
+// sopsMAC returns the encrypted MAC of a SOPS file's values
+func sopsMAC(t *testing.T, key []byte, lastModified string, values ...string) string {
+	t.Helper()
+
+	hash := sha512.New()
+	for _, value := range values {
+		hash.Write([]byte(value))
+	}
+
+	return sopsEncrypt(t, key, fmt.Sprintf("%X", hash.Sum(nil)), lastModified)
+}
+
+func TestDecryptSecrets(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := bytes.Repeat([]byte{42}, 32)
+	encKey := ageEncrypt(t, identity.Recipient(), key)
+
+	sops := fmt.Sprintf(`github:
+    token: %s
+registry_password: %s
+comment_unencrypted: visible
+debug_unencrypted: true
+sops:
+    age:
+        - recipient: %s
+          enc: |
+%s
+    lastmodified: "2021-01-02T03:04:05Z"
+    mac: %s
+    version: 3.7.1
+`,
+		sopsEncrypt(t, key, "gh-secret", "github:token:"),
+		sopsEncrypt(t, key, "p4ss", "registry_password:"),
+		identity.Recipient(),
+		indent(string(encKey), "            "),
+		sopsMAC(t, key, "2021-01-02T03:04:05Z", "gh-secret", "p4ss", "visible", "True"),
+	)
+
+	tests := []struct {
+		name       string
+		fn         string
+		content    []byte
+		identities []age.Identity
+		want       map[string]string
+		wantErr    bool
+	}{
+		{
+			name:       "sops",
+			fn:         "secrets.yaml",
+			content:    []byte(sops),
+			identities: []age.Identity{identity},
+			want: map[string]string{
+				"COMMENT_UNENCRYPTED": "visible",
+				"DEBUG_UNENCRYPTED":   "true",
+				"GITHUB_TOKEN":        "gh-secret",
+				"REGISTRY_PASSWORD":   "p4ss",
+			},
+		},
+		{
+			name:       "sops with wrong identity",
+			fn:         "secrets.yaml",
+			content:    []byte(sops),
+			identities: []age.Identity{other},
+			wantErr:    true,
+		},
+		{
+			name:       "sops with tampered unencrypted value",
+			fn:         "secrets.yaml",
+			content:    []byte(strings.Replace(sops, "visible", "tampered", 1)),
+			identities: []age.Identity{identity},
+			wantErr:    true,
+		},
+		{
+			name:       "sops with added unencrypted value",
+			fn:         "secrets.yaml",
+			content:    []byte("url_unencrypted: https://attacker.example.com\n" + sops),
+			identities: []age.Identity{identity},
+			wantErr:    true,
+		},
+		{
+			name:       "sops with removed value",
+			fn:         "secrets.yaml",
+			content:    []byte(strings.Replace(sops, "debug_unencrypted: true\n", "", 1)),
+			identities: []age.Identity{identity},
+			wantErr:    true,
+		},
+		{
+			name:       "sops without mac",
+			fn:         "secrets.yaml",
+			content:    []byte(regexp.MustCompile(`(?m)^    mac: .*\n`).ReplaceAllString(sops, "")),
+			identities: []age.Identity{identity},
+			wantErr:    true,
+		},
+		{
+			name:       "age encrypted json",
+			fn:         "secrets.json.age",
+			content:    ageEncrypt(t, identity.Recipient(), []byte(`{"npm": {"token": "abc"}, "retries": 3}`)),
+			identities: []age.Identity{identity},
+			want:       map[string]string{"NPM_TOKEN": "abc", "RETRIES": "3"},
+		},
+		{
+			name:       "age encrypted dotenv",
+			fn:         "secrets.env.age",
+			content:    ageEncrypt(t, identity.Recipient(), []byte("TOKEN=abc\nURL=https://$TOKEN@example.com\n")),
+			identities: []age.Identity{identity},
+			want:       map[string]string{"TOKEN": "abc", "URL": "https://abc@example.com"},
+		},
+		{
+			name:       "plain file",
+			fn:         "secrets.yaml",
+			content:    []byte("token: abc\n"),
+			identities: []age.Identity{identity},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decryptSecrets(tt.fn, tt.content, tt.identities)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decryptSecrets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decryptSecrets() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func indent(text, prefix string) string {
+	out := &bytes.Buffer{}
+
+	for _, line := range bytes.Split(bytes.TrimSpace([]byte(text)), []byte("\n")) {
+		fmt.Fprintf(out, "%s%s\n", prefix, line)
+	}
+
+	return out.String()
+}
+
+func TestSecrets_Run(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fn := filepath.Join(t.TempDir(), "secrets.json.age")
+	content := ageEncrypt(t, identity.Recipient(), []byte(`{"password": "pa$word${HOME}"}`))
+
+	if err := os.WriteFile(fn, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cx, shipContext := testShipContext(t)
+	shipContext.Setenv("SOPS_AGE_KEY", identity.String())
+
+	mod := NewSecrets().(*Secrets)
+	mod.File = fn
+
+	if err := mod.Run(cx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// secrets are stored as decrypted, without expanding variables
+	if got := shipContext.Getenv("PASSWORD", ""); got != "pa$word${HOME}" {
+		t.Errorf("PASSWORD = %q, want %q", got, "pa$word${HOME}")
+	}
+}
@@ -34,7 +34,7 @@ func (Show) Run(cx context.Context) error {
 	log.Printf("Environment:")
 
 	for _, env := range envKeys {
-		log.Printf("- %s = %q", env, context.Env.GetOrDefault(env, "-unset-"))
+		log.Printf("- %s = %q", env, context.Getenv(env, "-unset-"))
 	}
 
 	log.Printf("Artifacts:")
@@ -34,7 +34,7 @@ func (mod *SkipPublish) Run(cx context.Context) error {
 		return err
 	}
 
-	if variable, ok := context.Env.Get(mod.EnvName); ok {
+	if variable, ok := context.LookupEnv(mod.EnvName); ok {
 		skip, err := strconv.ParseBool(variable)
 		if err != nil {
 			return fmt.Errorf("parsing %s as bool: %w", mod.EnvName, err)
@@ -0,0 +1,100 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+// Snapshot is a setup module deriving a snapshot version, if the current
+// commit is not tagged, like "v1.2.4-next.20210102.abcdef0": the patch
+// version following the previous tag, the build date, and the short
+// commit ref. Snapshot builds are marked in ctx.Context, and in
+// modules.TemplateData, therefore templates can target a different
+// channel (like "nightly").
+type Snapshot struct {
+	// Prefix is the prefix of tags. Default: "v".
+	Prefix string
+	// Publish allows publishing snapshot builds. Otherwise, the publish
+	// stage is skipped. Default: false.
+	Publish bool
+	// Suffix is the pre-release identifier of snapshot versions. Default:
+	// "next".
+	Suffix string
+}
+
+// NewSnapshot is a factory function for Snapshot module
+func NewSnapshot() modules.Pluggable {
+	return &Snapshot{
+		Prefix: "v",
+		Suffix: "next",
+	}
+}
+
+// Run records snapshot version into ctx.Context, if the current commit is
+// not tagged
+func (mod *Snapshot) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	if context.Git.Tag != "" {
+		return nil
+	}
+
+	base := &ctx.Semver{}
+
+	tag, err := previousTag(cx, context)
+	if err != nil {
+		return err
+	}
+
+	if tag != "" {
+		last, err := ctx.ParseSemver(strings.TrimPrefix(tag, context.Git.TagPrefix+mod.Prefix))
+		if err != nil {
+			return fmt.Errorf("previous tag: %w", err)
+		}
+
+		base = bumpSemver(last, nil)
+	}
+
+	context.Snapshot = true
+	context.Version = mod.Prefix + snapshotVersion(base, mod.Suffix, context.StartedAt, context.Git.Ref)
+
+	log.Printf("      snapshot version is %s", context.Version)
+
+	if !mod.Publish && context.Publish {
+		context.Publish = false
+
+		log.Printf("publishing is set to %v", context.Publish)
+	}
+
+	return nil
+}
+
+// snapshotVersion returns the snapshot version following base
+func snapshotVersion(base *ctx.Semver, suffix string, date time.Time, ref string) string {
+	if len(ref) > 7 {
+		ref = ref[:7]
+	}
+
+	identifiers := []string{}
+
+	for _, identifier := range []string{suffix, date.UTC().Format("20060102"), ref} {
+		if identifier != "" {
+			identifiers = append(identifiers, identifier)
+		}
+	}
+
+	version := *base
+	version.Prerelease = strings.Join(identifiers, ".")
+	version.Metadata = ""
+
+	return version.String()
+}
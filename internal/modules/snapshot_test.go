@@ -0,0 +1,34 @@
+package modules
+
+import (
+	"testing"
+	"time"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+func TestSnapshotVersion(t *testing.T) {
+	date := time.Date(2021, 1, 2, 23, 0, 0, 0, time.FixedZone("UTC-2", -2*3600))
+	base := &ctx.Semver{Major: 1, Minor: 2, Patch: 4}
+
+	tests := []struct {
+		name   string
+		suffix string
+		ref    string
+		want   string
+	}{
+		{name: "default", suffix: "next", ref: "abcdef0123456789", want: "1.2.4-next.20210103.abcdef0"},
+		{name: "no suffix", ref: "abcdef0123456789", want: "1.2.4-20210103.abcdef0"},
+		{name: "no ref", suffix: "nightly", want: "1.2.4-nightly.20210103"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			if got := snapshotVersion(base, tt.suffix, date, tt.ref); got != tt.want {
+				t.Errorf("snapshotVersion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package modules
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+const (
+	// mastodonMaxLength is the default maximum length of Mastodon statuses
+	mastodonMaxLength = 500
+	// blueskyMaxLength is the maximum length of Bluesky posts
+	blueskyMaxLength = 300
+
+	socialMessage = `{{.ProjectName}} {{.Version}} has been released!{{with .Links}}
+
+{{(index . 0).URL}}{{end}}`
+)
+
+// nolint: gochecknoglobals
+var reSocialLink = regexp.MustCompile(`https?://[^\s<>"]+[^\s<>".,;:!?)\]]`)
+
+type (
+	// Mastodon is a notify module posting release announcements to
+	// Mastodon
+	Mastodon struct {
+		Announcement `yaml:",inline"`
+		// Server is the URL of the Mastodon instance, like
+		// "https://fosstodon.org". Required.
+		Server string
+		// TokenEnv specifies the environment variable of the access token
+		// (with write:statuses scope). Default: "MASTODON_ACCESS_TOKEN".
+		TokenEnv string `yaml:"token_env"`
+		// Visibility is the status's visibility: public, unlisted,
+		// private, or direct. Default: "public".
+		Visibility string
+	}
+
+	// Bluesky is a notify module posting release announcements to Bluesky
+	Bluesky struct {
+		Announcement `yaml:",inline"`
+		// Handle is the account's handle, like "example.bsky.social".
+		// Required.
+		Handle string
+		// PasswordEnv specifies the environment variable of the account's
+		// app password. Default: "BLUESKY_APP_PASSWORD".
+		PasswordEnv string `yaml:"password_env"`
+		// Server is the URL of the account's PDS. Default:
+		// "https://bsky.social".
+		Server string
+	}
+
+	blueskyFacet struct {
+		Index    blueskyFacetIndex     `json:"index"`
+		Features []blueskyFacetFeature `json:"features"`
+	}
+
+	blueskyFacetIndex struct {
+		ByteStart int `json:"byteStart"`
+		ByteEnd   int `json:"byteEnd"`
+	}
+
+	blueskyFacetFeature struct {
+		Type string `json:"$type"`
+		URI  string `json:"uri"`
+	}
+)
+
+// NewMastodon is a factory function for Mastodon module
+func NewMastodon() modules.Pluggable {
+	return &Mastodon{
+		Announcement: newAnnouncement(),
+		TokenEnv:     "MASTODON_ACCESS_TOKEN",
+		Visibility:   "public",
+	}
+}
+
+// NewBluesky is a factory function for Bluesky module
+func NewBluesky() modules.Pluggable {
+	return &Bluesky{
+		Announcement: newAnnouncement(),
+		PasswordEnv:  "BLUESKY_APP_PASSWORD",
+		Server:       "https://bsky.social",
+	}
+}
+
+// Run posts the announcement as a status
+func (mod *Mastodon) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	if mod.Server == "" {
+		return errors.New("server is not specified")
+	}
+
+	token, err := context.RequireEnv(mod.TokenEnv, "mastodon access token")
+	if err != nil {
+		return err
+	}
+
+	message, err := mod.render(cx, context, "mastodon", socialMessage)
+	if err != nil {
+		return err
+	}
+
+	context.Progress.SetState(fmt.Sprintf("posting announcement to %s", mod.Server))
+
+	var status struct {
+		URL string `json:"url"`
+	}
+
+	err = requestJSON(cx, http.MethodPost, strings.TrimSuffix(mod.Server, "/")+"/api/v1/statuses", map[string]string{
+		"Authorization": "Bearer " + token,
+		// retried requests don't post duplicates
+		"Idempotency-Key": context.ProjectName + "-" + context.Version,
+	}, map[string]string{
+		"status":     truncateRunes(message, mastodonMaxLength),
+		"visibility": mod.Visibility,
+	}, &status)
+	if err != nil {
+		return fmt.Errorf("posting announcement to mastodon: %w", err)
+	}
+
+	if status.URL != "" {
+		context.Progress.SetState(fmt.Sprintf("announcement posted to %s", status.URL))
+	}
+
+	return nil
+}
+
+// Run posts the announcement
+func (mod *Bluesky) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	if mod.Handle == "" {
+		return errors.New("handle is not specified")
+	}
+
+	password, err := context.RequireEnv(mod.PasswordEnv, "bluesky app password")
+	if err != nil {
+		return err
+	}
+
+	message, err := mod.render(cx, context, "bluesky", socialMessage)
+	if err != nil {
+		return err
+	}
+
+	message = truncateRunes(message, blueskyMaxLength)
+	server := strings.TrimSuffix(mod.Server, "/")
+
+	context.Progress.SetState(fmt.Sprintf("posting announcement to %s as %s", server, mod.Handle))
+
+	var session struct {
+		AccessJwt string `json:"accessJwt"`
+		DID       string `json:"did"`
+	}
+
+	err = requestJSON(cx, http.MethodPost, server+"/xrpc/com.atproto.server.createSession", nil, map[string]string{
+		"identifier": mod.Handle,
+		"password":   password,
+	}, &session)
+	if err != nil {
+		return fmt.Errorf("logging in to bluesky: %w", err)
+	}
+
+	record := map[string]interface{}{
+		"$type":     "app.bsky.feed.post",
+		"createdAt": time.Now().UTC().Format(time.RFC3339),
+		"text":      message,
+	}
+
+	if facets := blueskyLinkFacets(message); len(facets) > 0 {
+		record["facets"] = facets
+	}
+
+	err = requestJSON(cx, http.MethodPost, server+"/xrpc/com.atproto.repo.createRecord", map[string]string{
+		"Authorization": "Bearer " + session.AccessJwt,
+	}, map[string]interface{}{
+		"collection": "app.bsky.feed.post",
+		"record":     record,
+		"repo":       session.DID,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("posting announcement to bluesky: %w", err)
+	}
+
+	return nil
+}
+
+// blueskyLinkFacets returns link facets of URLs in the text, as Bluesky
+// doesn't detect links in posts
+func blueskyLinkFacets(text string) []blueskyFacet {
+	facets := []blueskyFacet{}
+
+	for _, loc := range reSocialLink.FindAllStringIndex(text, -1) {
+		facets = append(facets, blueskyFacet{
+			Index: blueskyFacetIndex{ByteStart: loc[0], ByteEnd: loc[1]},
+			Features: []blueskyFacetFeature{{
+				Type: "app.bsky.richtext.facet#link",
+				URI:  text[loc[0]:loc[1]],
+			}},
+		})
+	}
+
+	return facets
+}
+
+// truncateRunes truncates text to max runes, marking truncation with an
+// ellipsis
+func truncateRunes(text string, max int) string {
+	if runes := []rune(text); len(runes) > max {
+		return string(runes[:max-1]) + "…"
+	}
+
+	return text
+}
+
+// requestJSON sends body (if it's not nil) as JSON, and decodes the
+// response into out (if it's not nil). Request URLs are not reported, as
+// they may contain secrets.
+func requestJSON(cx context.Context, method, url string, headers map[string]string, body, out interface{}) error {
+	var reader io.Reader
+
+	if body != nil {
+		content, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		reader = bytes.NewReader(content)
+	}
+
+	req, err := http.NewRequestWithContext(cx, method, url, reader)
+	if err != nil {
+		return err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	req.Header.Set("User-Agent", "goshipdone")
+
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		var urlErr *neturl.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package modules
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+func TestMastodon_Run(t *testing.T) {
+	var (
+		auth string
+		body map[string]string
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/statuses" {
+			http.NotFound(w, r)
+			return
+		}
+
+		auth = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		_, _ = w.Write([]byte(`{"url":"https://example.com/@app/1"}`))
+	}))
+	defer srv.Close()
+
+	cx := ctx.New(context.Background())
+
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	context.ProjectName = "app"
+	context.Version = "v1.2.3"
+	context.Env.Set("MASTODON_ACCESS_TOKEN", "token")
+	context.Published.AddLink("Release", "https://example.com/releases/v1.2.3")
+
+	mod := NewMastodon().(*Mastodon)
+	mod.Server = srv.URL + "/"
+
+	if err := mod.Run(cx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if auth != "Bearer token" {
+		t.Errorf("Authorization = %q", auth)
+	}
+
+	want := map[string]string{
+		"status":     "app v1.2.3 has been released!\n\nhttps://example.com/releases/v1.2.3",
+		"visibility": "public",
+	}
+	if !reflect.DeepEqual(body, want) {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+}
+
+func TestBluesky_Run(t *testing.T) {
+	var post struct {
+		Collection string
+		Record     struct {
+			Text   string
+			Facets []blueskyFacet
+		}
+		Repo string
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/xrpc/com.atproto.server.createSession":
+			var login map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&login)
+
+			if login["identifier"] != "app.bsky.social" || login["password"] != "secret" {
+				http.Error(w, "invalid login", http.StatusUnauthorized)
+				return
+			}
+
+			_, _ = w.Write([]byte(`{"accessJwt":"jwt","did":"did:plc:app"}`))
+		case "/xrpc/com.atproto.repo.createRecord":
+			if r.Header.Get("Authorization") != "Bearer jwt" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			_ = json.NewDecoder(r.Body).Decode(&post)
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cx := ctx.New(context.Background())
+
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	context.ProjectName = "app"
+	context.Version = "v1.2.3"
+	context.Env.Set("BLUESKY_APP_PASSWORD", "secret")
+
+	mod := NewBluesky().(*Bluesky)
+	mod.Handle = "app.bsky.social"
+	mod.Message = "{{.ProjectName}} {{.Version}} ✨ https://example.com/v1.2.3."
+	mod.Server = srv.URL
+
+	if err := mod.Run(cx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if post.Repo != "did:plc:app" || post.Collection != "app.bsky.feed.post" {
+		t.Errorf("repo = %q, collection = %q", post.Repo, post.Collection)
+	}
+
+	if post.Record.Text != "app v1.2.3 ✨ https://example.com/v1.2.3." {
+		t.Errorf("text = %q", post.Record.Text)
+	}
+
+	want := []blueskyFacet{{
+		Index: blueskyFacetIndex{ByteStart: 15, ByteEnd: 41},
+		Features: []blueskyFacetFeature{{
+			Type: "app.bsky.richtext.facet#link",
+			URI:  "https://example.com/v1.2.3",
+		}},
+	}}
+	if !reflect.DeepEqual(post.Record.Facets, want) {
+		t.Errorf("facets = %+v, want %+v", post.Record.Facets, want)
+	}
+}
@@ -15,10 +15,11 @@ import (
 
 type tarSingleTarget struct {
 	CommonDir   string
-	Compression Compression
+	Compression modules.Compression
 	DirsWritten map[string]bool
 	Files       []string
 	ID          string
+	Included    ctx.Artifacts
 	osarch      *ctx.OsArch
 	Output      string
 	Targets     *ctx.Artifacts
@@ -39,6 +40,13 @@ func (mod *Tar) singleTarget(cx context.Context, artifacts *ctx.Artifacts) (*tar
 		ret.Files[i] = mod.Files[i]
 	}
 
+	included, err := mod.included(cx, ret.osarch)
+	if err != nil {
+		return nil, err
+	}
+
+	ret.Included = included
+
 	td, err := modules.NewTemplate(cx)
 	if err != nil {
 		return nil, err
@@ -46,7 +54,16 @@ func (mod *Tar) singleTarget(cx context.Context, artifacts *ctx.Artifacts) (*tar
 
 	td.OSArch = ret.osarch
 
-	td.Ext = mod.Compression.Extension()
+	td.Ext = mod.Compression.Extension
+
+	skip, err := td.ParseBool("archive:tar", mod.SkipIf)
+	if err != nil {
+		return nil, fmt.Errorf("rendering skip_if: %w", err)
+	}
+
+	if skip {
+		return nil, ErrSkippedTarget
+	}
 
 	for _, task := range []struct {
 		name   string
@@ -69,6 +86,36 @@ func (mod *Tar) singleTarget(cx context.Context, artifacts *ctx.Artifacts) (*tar
 	return ret, nil
 }
 
+// included returns artifacts of Include for an OS-arch combination,
+// including artifacts without OS-arch. Each name has to select at least
+// one artifact.
+func (mod *Tar) included(cx context.Context, osarch *ctx.OsArch) (ctx.Artifacts, error) {
+	if len(mod.Include) == 0 {
+		return nil, nil
+	}
+
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return nil, err
+	}
+
+	included := ctx.Artifacts{}
+
+	for _, name := range mod.Include {
+		artifacts := context.Artifacts.Select().Name(name).Filter(func(art *ctx.Artifact) bool {
+			return art.OsArch == nil || art.OsArch.String() == osarch.String()
+		}).List()
+
+		if len(artifacts) == 0 {
+			return nil, fmt.Errorf("no %s artifacts found to include for %s", name, osarch)
+		}
+
+		included = append(included, artifacts...)
+	}
+
+	return included, nil
+}
+
 func (target *tarSingleTarget) Run(cx context.Context) error {
 	context, err := ctx.GetShipContext(cx)
 	if err != nil {
@@ -77,6 +124,33 @@ func (target *tarSingleTarget) Run(cx context.Context) error {
 
 	archiveFile := path.Join(context.TargetDir, target.Output)
 
+	if err := target.writeArchive(cx, archiveFile); err != nil {
+		// don't leave half-written archives around
+		_ = os.Remove(archiveFile)
+
+		return err
+	}
+
+	artifact := &ctx.Artifact{
+		Filename: target.Output,
+		Location: archiveFile,
+		ID:       target.ID,
+		OsArch:   target.osarch,
+		Parents:  append(target.Targets.Filenames(), target.Included.Filenames()...),
+	}
+
+	if err := modules.RecordDigest(artifact); err != nil {
+		return err
+	}
+
+	context.Artifacts.Add(artifact)
+
+	return nil
+}
+
+// writeArchive writes artifacts, and files into the archive. It stops if
+// cx is canceled.
+func (target *tarSingleTarget) writeArchive(cx context.Context, archiveFile string) error {
 	archive, err := os.Create(archiveFile)
 	if err != nil {
 		return fmt.Errorf("cannot create archive file %s: %w", archiveFile, err)
@@ -84,30 +158,51 @@ func (target *tarSingleTarget) Run(cx context.Context) error {
 
 	defer archive.Close()
 
-	compressedArchive := target.Compression.Writer(archive)
+	compressedArchive := target.Compression.NewWriter(archive)
 	defer compressedArchive.Close()
 
 	tw := tar.NewWriter(compressedArchive)
 	defer tw.Close()
 
 	for _, artifact := range *target.Targets {
+		if err := cx.Err(); err != nil {
+			return err
+		}
+
 		if err := target.writeArtifact(tw, artifact); err != nil {
 			return fmt.Errorf("writing %s: %w", archiveFile, err)
 		}
 	}
 
-	for _, file := range target.Files {
-		if err := target.writeFileGlob(tw, file); err != nil {
+	for _, artifact := range target.Included {
+		if err := cx.Err(); err != nil {
+			return err
+		}
+
+		if err := target.writeArtifact(tw, artifact); err != nil {
 			return fmt.Errorf("writing %s: %w", archiveFile, err)
 		}
 	}
 
-	context.Artifacts.Add(&ctx.Artifact{
-		Filename: target.Output,
-		Location: archiveFile,
-		ID:       target.ID,
-		OsArch:   target.osarch,
-	})
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	files, err := globFiles(context.Dir, target.Files)
+	if err != nil {
+		return fmt.Errorf("finding files: %w", err)
+	}
+
+	for _, filename := range files {
+		if err := cx.Err(); err != nil {
+			return err
+		}
+
+		if err := target.writeStaticFile(tw, filename, context.Path(filename)); err != nil {
+			return fmt.Errorf("writing %s: %w", archiveFile, err)
+		}
+	}
 
 	return nil
 }
@@ -121,24 +216,15 @@ func (target *tarSingleTarget) writeArtifact(tw *tar.Writer, artifact *ctx.Artif
 	return target.writeFile(tw, filename, artifact.Location)
 }
 
-func (target *tarSingleTarget) writeFileGlob(tw *tar.Writer, source string) error {
-	matches, err := filepath.Glob(source)
-	if err != nil {
+// writeStaticFile writes a file of the project (at source) into the
+// archive, as filename under CommonDir
+func (target *tarSingleTarget) writeStaticFile(tw *tar.Writer, filename, source string) error {
+	fullfn := path.Join(target.CommonDir, filepath.ToSlash(filename))
+	if err := target.writeDirs(tw, path.Dir(fullfn)); err != nil {
 		return err
 	}
 
-	for _, filename := range matches {
-		fullfn := path.Join(target.CommonDir, filename)
-		if err := target.writeDirs(tw, path.Dir(fullfn)); err != nil {
-			return err
-		}
-
-		if err := target.writeFile(tw, fullfn, filename); err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return target.writeFile(tw, fullfn, source)
 }
 
 func (target *tarSingleTarget) writeFile(tw *tar.Writer, destpath, source string) error {
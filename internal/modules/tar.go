@@ -2,6 +2,7 @@ package modules
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
@@ -21,10 +22,17 @@ type (
 		CommonDir string
 		// Compression specifies which compression should be applied to the
 		// archive.
-		Compression Compression
+		Compression modules.Compression
 		// Files contains a list of static files should be added to the
-		// archive file. They are interpretered as glob.
+		// archive file. They are glob patterns, supporting `**`, and
+		// `{a,b}` alternation. Patterns starting with "!" exclude files
+		// matched by previous patterns, like `!**/*_test.go`.
 		Files []string
+		// Include lists names (IDs) of further artifacts to be added to
+		// each archive, like generated license bundles, SBOMs, or shell
+		// completions. Artifacts of the archive's OS-arch combination, and
+		// artifacts without OS-arch are added. Default: empty.
+		Include []string
 		// ID contains the artifact's name used by later stages of the build
 		// pipeline. Archives, and Publishes may refer to this name for
 		// referencing build results.
@@ -38,16 +46,23 @@ type (
 		// They are in `{{.Os}}-{{.Arch}}` format.
 		// It filters builds to be included.
 		Skip []string
+		// SkipIf is a `modules.TemplateData` template, evaluated for each
+		// os-arch combination. The archive is not created if it renders
+		// to a truthy value.
+		SkipIf string `yaml:"skip_if"`
 	}
 )
 
 func NewTar() modules.Pluggable {
+	none, _ := modules.NewCompression("none")
+
 	return &Tar{
 		Builds:      []string{"default"},
 		CommonDir:   "{{.ProjectName}}-{{.Version}}-{{OS}}-{{ArchName}}",
-		Compression: Compression{&CompressNONE{}},
+		Compression: none,
 		Files:       []string{"README*"},
 		ID:          "archive",
+		Include:     []string{},
 		Output:      "{{.ProjectName}}-{{.Version}}-{{OS}}-{{ArchName}}.tar{{.Ext}}",
 		Skip:        []string{},
 	}
@@ -68,6 +83,10 @@ func (mod *Tar) Run(cx context.Context) error {
 	for osarch := range builds {
 		target, err := mod.singleTarget(cx, builds[osarch])
 		if err != nil {
+			if errors.Is(err, ErrSkippedTarget) {
+				continue
+			}
+
 			return err
 		}
 
@@ -1,8 +1,12 @@
 package modules
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/julian7/goshipdone/archive"
 	"github.com/julian7/goshipdone/ctx"
 )
 
@@ -103,3 +107,82 @@ func Test_errNumTargets(t *testing.T) {
 		})
 	}
 }
+
+func TestTar_Run_include(t *testing.T) {
+	dir := t.TempDir()
+	cx := ctx.New(context.Background())
+
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	context.ProjectName = "app"
+	context.TargetDir = dir
+	context.Version = "v1.0.0"
+
+	linux := &ctx.OsArch{OS: "linux", Arch: "amd64"}
+	windows := &ctx.OsArch{OS: "windows", Arch: "amd64"}
+
+	for _, art := range []*ctx.Artifact{
+		{ID: "default", Filename: "app", OsArch: linux},
+		{ID: "completions", Filename: "completions/app.bash"},
+		{ID: "sbom", Filename: "app.linux.spdx.json", OsArch: linux},
+		{ID: "sbom", Filename: "app.windows.spdx.json", OsArch: windows},
+	} {
+		art.Location = filepath.Join(dir, filepath.FromSlash(art.Filename))
+		if err := os.MkdirAll(filepath.Dir(art.Location), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(art.Location, []byte(art.Filename), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		context.Artifacts.Add(art)
+	}
+
+	mod := NewTar().(*Tar)
+	mod.Builds = []string{"default"}
+	mod.CommonDir = "app"
+	mod.Files = []string{}
+	mod.Include = []string{"completions", "sbom"}
+	mod.Output = "app.tar"
+
+	if err := mod.Run(cx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	entries, err := archive.List(filepath.Join(dir, "app.tar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			got = append(got, entry.Name)
+		}
+	}
+
+	want := []string{"app/app", "app/completions/app.bash", "app/app.linux.spdx.json"}
+	if len(got) != len(want) {
+		t.Fatalf("Run() archived %v, want %v", got, want)
+	}
+
+	for idx := range want {
+		if got[idx] != want[idx] {
+			t.Errorf("Run() archived %v, want %v", got, want)
+		}
+	}
+
+	if parents := context.Artifacts.ByFilename("app.tar").Parents; len(parents) != 3 {
+		t.Errorf("Run() recorded parents %v, want 3", parents)
+	}
+
+	mod.Include = []string{"missing"}
+
+	if err := mod.Run(cx); err == nil {
+		t.Error("Run() succeeded including missing artifacts")
+	}
+}
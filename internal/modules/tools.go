@@ -0,0 +1,391 @@
+package modules
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+type (
+	// Tools is a setup module ensuring external binaries the pipeline
+	// needs (like upx, cosign, or syft) are present at pinned versions.
+	// Tools are downloaded into a cache directory, which is added to PATH
+	// of the run's environment for later modules.
+	Tools struct {
+		// CacheDir is the directory tools are downloaded into. Variable
+		// expansion is available. Default: "goshipdone/tools" in the
+		// user's cache directory (see os.UserCacheDir).
+		CacheDir string `yaml:"cache_dir"`
+		// Tools are the tools to be installed. Required.
+		Tools []*Tool
+	}
+
+	// Tool is an external binary at a pinned version
+	Tool struct {
+		// Binary is the binary's path in the archive, if URL points to an
+		// archive (.tar.gz, .tgz, or .zip). Default: the tool's name (in
+		// any directory).
+		Binary string
+		// Name is the tool's name, and the installed binary's name
+		// (with .exe extension on Windows). Required.
+		Name string
+		// SHA256 maps `{{.OS}}-{{.Arch}}` platforms (or "*" for all
+		// platforms) to SHA256 checksums of downloads. Downloads are
+		// verified, and cached downloads are verified again on each run.
+		// Required for the current platform.
+		SHA256 map[string]string `yaml:"sha256"`
+		// URL is the download URL (template with `.Name`, `.Version`,
+		// `.OS`, `.Arch`, and `.Ext`, being ".exe" on Windows). Required.
+		URL string
+		// Version is the tool's pinned version. Required.
+		Version string
+	}
+
+	// toolData is the template data of tool URLs
+	toolData struct {
+		Arch    string
+		Ext     string
+		Name    string
+		OS      string
+		Version string
+	}
+)
+
+// NewTools is a factory function for Tools module
+func NewTools() modules.Pluggable {
+	return &Tools{}
+}
+
+// ValidateTemplates implements modules.TemplateValidator
+func (mod *Tools) ValidateTemplates(context.Context) ([]string, error) {
+	for _, tool := range mod.Tools {
+		data := &toolData{Arch: "amd64", Name: tool.Name, OS: "linux", Version: tool.Version}
+		if _, err := renderPayload("tool-url", tool.URL, data); err != nil {
+			return nil, fmt.Errorf("tool %s: %w", tool.Name, err)
+		}
+	}
+
+	return []string{"URL"}, nil
+}
+
+// Run installs missing tools, and adds their directories to PATH
+func (mod *Tools) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	cacheDir := context.Env.Expand(mod.CacheDir)
+	if cacheDir == "" {
+		userCache, err := os.UserCacheDir()
+		if err != nil {
+			return fmt.Errorf("finding cache directory: %w", err)
+		}
+
+		cacheDir = filepath.Join(userCache, "goshipdone", "tools")
+	}
+
+	dirs := []string{}
+
+	for _, tool := range mod.Tools {
+		dir, err := tool.install(cx, context, cacheDir)
+		if err != nil {
+			return fmt.Errorf("installing %s %s: %w", tool.Name, tool.Version, err)
+		}
+
+		dirs = append(dirs, dir)
+	}
+
+	if current := context.Getenv("PATH", os.Getenv("PATH")); current != "" {
+		dirs = append(dirs, current)
+	}
+
+	// commands of later modules are looked up in PATH of the run's
+	// environment (see ctx.RunCommand)
+	context.Setenv("PATH", strings.Join(dirs, string(os.PathListSeparator)))
+
+	return nil
+}
+
+// install downloads the tool into its directory under cacheDir, if its
+// verified download is not there yet, and extracts the binary from the
+// download, returning the directory
+func (tool *Tool) install(cx context.Context, context *ctx.Context, cacheDir string) (string, error) {
+	if tool.Name == "" || tool.Version == "" || tool.URL == "" {
+		return "", errors.New("name, version, and url are required")
+	}
+
+	data := &toolData{
+		Arch:    runtime.GOARCH,
+		Name:    tool.Name,
+		OS:      runtime.GOOS,
+		Version: tool.Version,
+	}
+
+	if runtime.GOOS == "windows" {
+		data.Ext = ".exe"
+	}
+
+	want := tool.SHA256[data.OS+"-"+data.Arch]
+	if want == "" {
+		want = tool.SHA256["*"]
+	}
+
+	if want == "" {
+		return "", fmt.Errorf("sha256 of %s-%s is required", data.OS, data.Arch)
+	}
+
+	url, err := renderPayload("tool-url", tool.URL, data)
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(cacheDir, tool.Name, tool.Version)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	cached := filepath.Join(dir, ".download")
+
+	download, err := openVerified(cached, want)
+	if err != nil {
+		return "", err
+	}
+
+	if download == nil {
+		context.Progress.SetState(fmt.Sprintf("downloading %s %s", tool.Name, tool.Version))
+
+		download, err = fetchVerified(cx, context, "tools:"+tool.Name, url, cached, want)
+		if err != nil {
+			return "", err
+		}
+
+		log.Printf("      downloaded %s %s into %s", tool.Name, tool.Version, dir)
+	}
+
+	defer download.Close()
+
+	binary := tool.Binary
+	if binary == "" {
+		binary = tool.Name + data.Ext
+	}
+
+	// the binary is extracted on each run, therefore it's always the
+	// verified download's content
+	if err := extractTool(download, url, binary, filepath.Join(dir, tool.Name+data.Ext)); err != nil {
+		return "", err
+	}
+
+	return dir, nil
+}
+
+// openVerified opens a cached download, if its SHA256 checksum matches
+// want. It returns nil without errors if the download is missing, or its
+// checksum doesn't match.
+func openVerified(fn, want string) (*os.File, error) {
+	download, err := os.Open(fn)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, download); err != nil {
+		download.Close()
+		return nil, err
+	}
+
+	if sum := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(want, sum) {
+		download.Close()
+		log.Printf("      checksum mismatch of cached %s: got %s, want %s", fn, sum, want)
+
+		return nil, nil
+	}
+
+	return download, nil
+}
+
+// fetchVerified downloads url into fn, if its SHA256 checksum matches
+// want, and returns the open download. Downloaded bytes are recorded for
+// destination.
+func fetchVerified(cx context.Context, context *ctx.Context, destination, url, fn, want string) (*os.File, error) {
+	download, err := os.CreateTemp(filepath.Dir(fn), ".download-*")
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.Remove(download.Name())
+
+	sum, size, err := downloadFile(cx, url, download)
+	if closeErr := download.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	context.Transfers.Download(destination, size)
+
+	if !strings.EqualFold(want, sum) {
+		return nil, fmt.Errorf("checksum mismatch of %s: got %s, want %s", url, sum, want)
+	}
+
+	if err := os.Rename(download.Name(), fn); err != nil {
+		return nil, err
+	}
+
+	return os.Open(fn)
+}
+
+// downloadFile downloads url into out, returning its SHA256 checksum, and
+// the number of bytes downloaded
+func downloadFile(cx context.Context, url string, out io.Writer) (string, int64, error) {
+	req, err := http.NewRequestWithContext(cx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", 0, err
+	}
+
+	req.Header.Set("User-Agent", "goshipdone")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("downloading %s: %s", url, resp.Status)
+	}
+
+	hash := sha256.New()
+
+	size, err := io.Copy(io.MultiWriter(out, hash), resp.Body)
+	if err != nil {
+		return "", size, fmt.Errorf("downloading %s: %w", url, err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
+// extractTool writes the binary into target, extracting it from the
+// download, if the URL points to an archive
+func extractTool(download *os.File, url, binary, target string) error {
+	if _, err := download.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	var (
+		in  io.Reader
+		err error
+	)
+
+	switch {
+	case strings.HasSuffix(url, ".tar.gz"), strings.HasSuffix(url, ".tgz"):
+		in, err = findInTarGz(download, binary)
+	case strings.HasSuffix(url, ".zip"):
+		in, err = findInZip(download, binary)
+	default:
+		in = download
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if closer, ok := in.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	tmp := target + ".tmp"
+
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o755) // nolint: gosec
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil { // nolint: gosec
+		out.Close()
+		return err
+	}
+
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, target)
+}
+
+// matchesBinary tells whether an archive entry is the binary
+func matchesBinary(name, binary string) bool {
+	name = strings.TrimPrefix(name, "./")
+
+	if strings.Contains(binary, "/") {
+		return name == binary
+	}
+
+	return path.Base(name) == binary
+}
+
+func findInTarGz(in io.Reader, binary string) (io.Reader, error) {
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := tar.NewReader(gz)
+
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%s not found in archive", binary)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if header.Typeflag == tar.TypeReg && matchesBinary(header.Name, binary) {
+			return archive, nil
+		}
+	}
+}
+
+func findInZip(file *os.File, binary string) (io.Reader, error) {
+	st, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	archive, err := zip.NewReader(file, st.Size())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range archive.File {
+		if !entry.FileInfo().IsDir() && matchesBinary(entry.Name, binary) {
+			return entry.Open()
+		}
+	}
+
+	return nil, fmt.Errorf("%s not found in archive", binary)
+}
@@ -0,0 +1,172 @@
+package modules
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/julian7/goshipdone/ctx"
+)
+
+func TestTools_Run(t *testing.T) {
+	archive := &bytes.Buffer{}
+	gz := gzip.NewWriter(archive)
+	tw := tar.NewWriter(gz)
+
+	for name, content := range map[string]string{"syft/README.md": "readme", "syft/syft": "syft binary"} {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var requests int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		switch r.URL.Path {
+		case "/cosign/v2.0.0/cosign-" + runtime.GOOS + "-" + runtime.GOARCH:
+			_, _ = w.Write([]byte("cosign binary"))
+		case "/syft_1.0.0.tar.gz":
+			_, _ = w.Write(archive.Bytes())
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	sum := sha256.Sum256([]byte("cosign binary"))
+	archiveSum := sha256.Sum256(archive.Bytes())
+
+	cx := ctx.New(context.Background())
+
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	context.Env.Set("PATH", "/usr/bin")
+	processPath := os.Getenv("PATH")
+
+	mod := NewTools().(*Tools)
+	mod.CacheDir = t.TempDir()
+	mod.Tools = []*Tool{
+		{
+			Name:    "cosign",
+			SHA256:  map[string]string{runtime.GOOS + "-" + runtime.GOARCH: hex.EncodeToString(sum[:])},
+			URL:     srv.URL + "/{{.Name}}/{{.Version}}/cosign-{{.OS}}-{{.Arch}}",
+			Version: "v2.0.0",
+		},
+		{
+			Binary:  "syft/syft",
+			Name:    "syft",
+			SHA256:  map[string]string{"*": hex.EncodeToString(archiveSum[:])},
+			URL:     srv.URL + "/syft_{{.Version}}.tar.gz",
+			Version: "1.0.0",
+		},
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := mod.Run(cx); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+	}
+
+	if requests != 2 {
+		t.Errorf("tools downloaded %d times, want 2", requests)
+	}
+
+	wantTransfers := []ctx.Transfer{
+		{Destination: "tools:cosign", Downloaded: int64(len("cosign binary"))},
+		{Destination: "tools:syft", Downloaded: int64(archive.Len())},
+	}
+	if diff := deep.Equal(context.Transfers.List(), wantTransfers); diff != nil {
+		t.Errorf("transfers %v", diff)
+	}
+
+	ext := ""
+	if runtime.GOOS == "windows" {
+		ext = ".exe"
+	}
+
+	for _, tool := range []struct{ name, version, content string }{{"cosign", "v2.0.0", "cosign binary"}, {"syft", "1.0.0", "syft binary"}} {
+		content, err := os.ReadFile(filepath.Join(mod.CacheDir, tool.name, tool.version, tool.name+ext))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(content) != tool.content {
+			t.Errorf("%s = %q, want %q", tool.name, content, tool.content)
+		}
+	}
+
+	path, _ := context.Env.Get("PATH")
+	if want := filepath.Join(mod.CacheDir, "cosign", "v2.0.0"); !strings.HasPrefix(path, want+string(os.PathListSeparator)) {
+		t.Errorf("PATH = %q, want %q first", path, want)
+	}
+
+	if os.Getenv("PATH") != processPath {
+		t.Errorf("Run() changed the process's PATH to %q", os.Getenv("PATH"))
+	}
+
+	// tampered binaries are restored from the verified download, and
+	// tampered downloads are downloaded again
+	cosign := filepath.Join(mod.CacheDir, "cosign", "v2.0.0", "cosign"+ext)
+	if err := os.WriteFile(cosign, []byte("evil"), 0o755); err != nil { // nolint: gosec
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(mod.CacheDir, "syft", "1.0.0", ".download"), []byte("evil"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mod.Run(cx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if content, _ := os.ReadFile(cosign); string(content) != "cosign binary" {
+		t.Errorf("tampered cosign = %q after Run()", content)
+	}
+
+	if requests != 3 {
+		t.Errorf("tools downloaded %d times, want 3", requests)
+	}
+
+	mod.Tools[1].SHA256 = nil
+
+	if err := mod.Run(cx); err == nil || !strings.Contains(err.Error(), "sha256") {
+		t.Errorf("Run() without checksum error = %v", err)
+	}
+
+	mod.Tools[1].SHA256 = map[string]string{"*": hex.EncodeToString(archiveSum[:])}
+	mod.CacheDir = t.TempDir()
+	mod.Tools[0].SHA256 = map[string]string{"*": "0000"}
+
+	if err := mod.Run(cx); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Run() with wrong checksum error = %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+// defaultParallelism is the default number of concurrent uploads of
+// publishers
+const defaultParallelism = 4
+
+// errUploadSkipped is returned by upload functions of uploadAll, if the
+// artifact has not been uploaded, as it already exists
+var errUploadSkipped = errors.New("upload skipped")
+
+// uploadAll uploads artifacts of builds concurrently, running at most
+// parallelism uploads at a time, and logging each finished upload. It
+// stops starting new uploads after the first failure, and returns the
+// first error.
+func uploadAll(
+	cx context.Context,
+	builds map[string]*ctx.Artifacts,
+	parallelism int,
+	upload func(context.Context, *ctx.Artifact) error,
+) error {
+	shipContext, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(builds))
+	for name := range builds {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	items := []*ctx.Artifact{}
+	for _, name := range names {
+		items = append(items, *builds[name]...)
+	}
+
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	cx, cancel := context.WithCancel(cx)
+	defer cancel()
+
+	var (
+		done     int
+		firstErr error
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+	)
+
+	slots := make(chan struct{}, parallelism)
+
+	shipContext.Progress.SetState(fmt.Sprintf("uploading %d file(s)", len(items)))
+
+	for _, item := range items {
+		select {
+		case slots <- struct{}{}:
+		case <-cx.Done():
+		}
+
+		if cx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+
+		go func(item *ctx.Artifact) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			start := time.Now()
+			err := upload(cx, item)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if errors.Is(err, errUploadSkipped) {
+				done++
+
+				log.Printf("      skipped %s: already exists", item.Filename)
+				shipContext.Progress.SetState(fmt.Sprintf("uploaded %d of %d file(s)", done, len(items)))
+
+				return
+			}
+
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+
+				return
+			}
+
+			done++
+
+			log.Printf("      uploaded %s (%s) in %s", item.Filename, fileSize(item), time.Since(start).Round(time.Millisecond))
+			shipContext.Progress.SetState(fmt.Sprintf("uploaded %d of %d file(s)", done, len(items)))
+		}(item)
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	// the pipeline has been interrupted
+	return cx.Err()
+}
+
+// fileSize returns an artifact's human-readable size, or "unknown size"
+func fileSize(art *ctx.Artifact) string {
+	if !art.Recorded() {
+		if err := art.Stat(); err != nil || !art.Recorded() {
+			return "unknown size"
+		}
+	}
+
+	return ctx.FormatBytes(art.Size)
+}
@@ -0,0 +1,96 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+func TestUploadAll(t *testing.T) {
+	builds := map[string]*ctx.Artifacts{
+		"archive": {
+			{Filename: "app-linux-amd64.tar.gz"},
+			{Filename: "app-linux-arm64.tar.gz"},
+			{Filename: "app-darwin-amd64.tar.gz"},
+		},
+		"checksum": {
+			{Filename: "SHA256SUMS"},
+			{Filename: "SHA256SUMS.sig"},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		parallelism int
+		fail        string
+		wantErr     bool
+		maxRunning  int32
+	}{
+		{name: "serial", parallelism: 1, maxRunning: 1},
+		{name: "parallel", parallelism: 2, maxRunning: 2},
+		{name: "invalid parallelism", parallelism: 0, maxRunning: 1},
+		{name: "failure", parallelism: 1, fail: "app-linux-arm64.tar.gz", wantErr: true, maxRunning: 1},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				maxRunning int32
+				mu         sync.Mutex
+				running    int32
+				uploaded   = map[string]bool{}
+			)
+
+			err := uploadAll(ctx.New(context.Background()), builds, tt.parallelism, func(cx context.Context, artifact *ctx.Artifact) error {
+				n := atomic.AddInt32(&running, 1)
+				defer atomic.AddInt32(&running, -1)
+
+				for {
+					max := atomic.LoadInt32(&maxRunning)
+					if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+						break
+					}
+				}
+
+				time.Sleep(10 * time.Millisecond)
+
+				if artifact.Filename == tt.fail {
+					return errors.New("upload failed")
+				}
+
+				mu.Lock()
+				uploaded[artifact.Filename] = true
+				mu.Unlock()
+
+				return nil
+			})
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("uploadAll() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if maxRunning > tt.maxRunning {
+				t.Errorf("%d concurrent uploads, want at most %d", maxRunning, tt.maxRunning)
+			}
+
+			if tt.wantErr {
+				// artifacts are uploaded in build name order
+				if len(uploaded) != 1 || !uploaded["app-linux-amd64.tar.gz"] {
+					t.Errorf("uploaded %v after failure", uploaded)
+				}
+
+				return
+			}
+
+			if len(uploaded) != 5 {
+				t.Errorf("uploaded %d files, want 5", len(uploaded))
+			}
+		})
+	}
+}
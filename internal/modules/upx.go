@@ -2,11 +2,11 @@ package modules
 
 import (
 	"context"
+	"os"
 	"os/exec"
 
 	"github.com/julian7/goshipdone/ctx"
 	"github.com/julian7/goshipdone/modules"
-	"github.com/magefile/mage/sh"
 )
 
 // UPX is a module for compressing executable binaries in a self-extracting
@@ -25,13 +25,12 @@ func NewUPX() modules.Pluggable {
 
 // Run calls upx on built artifacts, changing their artifact types
 func (archive *UPX) Run(cx context.Context) error {
-	upxCmd, err := exec.LookPath("upx")
-
+	context, err := ctx.GetShipContext(cx)
 	if err != nil {
 		return err
 	}
 
-	context, err := ctx.GetShipContext(cx)
+	upxCmd, err := exec.LookPath(ctx.LookPath(context.Env, "upx"))
 	if err != nil {
 		return err
 	}
@@ -49,7 +48,13 @@ func (archive *UPX) Run(cx context.Context) error {
 		}
 	}
 
-	if err := sh.RunV(upxCmd, args...); err != nil {
+	cmd := exec.CommandContext(cx, upxCmd, args...)
+	cmd.Dir = context.Dir
+	cmd.Env = context.Env.Environ()
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
 		return err
 	}
 
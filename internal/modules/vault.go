@@ -0,0 +1,232 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+type (
+	// Vault is a setup module fetching secrets from HashiCorp Vault KV
+	// secrets engines (version 1, or 2) into the context's environment,
+	// for later modules
+	Vault struct {
+		// Address is Vault's URL. Default: $VAULT_ADDR.
+		Address string
+		// Auth is the authentication method: token (from $VAULT_TOKEN),
+		// approle (role ID, and secret ID from RoleIDEnv, and
+		// SecretIDEnv), or kubernetes (service account token of Role).
+		// Default: "token".
+		Auth string
+		// AuthMount is the mount path of the authentication method.
+		// Default: the method's name.
+		AuthMount string `yaml:"auth_mount"`
+		// JWTFile is the service account token file of kubernetes
+		// authentication. Default:
+		// "/var/run/secrets/kubernetes.io/serviceaccount/token".
+		JWTFile string `yaml:"jwt_file"`
+		// Namespace is the Vault Enterprise namespace. Default:
+		// $VAULT_NAMESPACE.
+		Namespace string
+		// Role is the role of kubernetes authentication.
+		Role string
+		// RoleIDEnv specifies the environment variable of approle role ID.
+		// Default: "VAULT_ROLE_ID".
+		RoleIDEnv string `yaml:"role_id_env"`
+		// SecretIDEnv specifies the environment variable of approle secret
+		// ID. Default: "VAULT_SECRET_ID".
+		SecretIDEnv string `yaml:"secret_id_env"`
+		// Secrets are the secrets to be fetched. Required.
+		Secrets []*VaultSecret
+	}
+
+	// VaultSecret is a secret of a KV secrets engine
+	VaultSecret struct {
+		// Env maps environment variable names to the secret's keys, like
+		// {"GITHUB_TOKEN": "token"}. Default: all keys, upper-cased.
+		Env map[string]string
+		// Path is the secret's API path, like "secret/data/ci" (KV version
+		// 2), or "kv/ci" (KV version 1). Required.
+		Path string
+	}
+)
+
+// NewVault is a factory function for Vault module
+func NewVault() modules.Pluggable {
+	return &Vault{
+		Auth:        "token",
+		JWTFile:     "/var/run/secrets/kubernetes.io/serviceaccount/token",
+		RoleIDEnv:   "VAULT_ROLE_ID",
+		SecretIDEnv: "VAULT_SECRET_ID",
+	}
+}
+
+// Run fetches secrets, and sets them as environment variables
+func (mod *Vault) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	address := mod.Address
+	if address == "" {
+		address = context.Getenv("VAULT_ADDR", "")
+	}
+
+	if address == "" {
+		return errors.New("vault address is not specified")
+	}
+
+	if len(mod.Secrets) == 0 {
+		return errors.New("no secrets specified")
+	}
+
+	api := strings.TrimSuffix(address, "/") + "/v1/"
+	headers := map[string]string{}
+
+	namespace := mod.Namespace
+	if namespace == "" {
+		namespace = context.Getenv("VAULT_NAMESPACE", "")
+	}
+
+	if namespace != "" {
+		headers["X-Vault-Namespace"] = namespace
+	}
+
+	token, err := mod.login(cx, context, api, headers)
+	if err != nil {
+		return err
+	}
+
+	headers["X-Vault-Token"] = token
+	names := []string{}
+
+	for _, secret := range mod.Secrets {
+		vars, err := secret.fetch(cx, api, headers)
+		if err != nil {
+			return err
+		}
+
+		// Setenv stores secrets as fetched, without expanding "$"
+		for name, val := range vars {
+			context.Setenv(name, val)
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	log.Printf("      loaded secrets: %s", strings.Join(names, ", "))
+
+	return nil
+}
+
+// login returns a Vault token of the authentication method
+func (mod *Vault) login(cx context.Context, context *ctx.Context, api string, headers map[string]string) (string, error) {
+	var body map[string]string
+
+	switch mod.Auth {
+	case "token":
+		return context.RequireEnv("VAULT_TOKEN", "vault token")
+	case "approle":
+		roleID, err := context.RequireEnv(mod.RoleIDEnv, "approle role ID")
+		if err != nil {
+			return "", err
+		}
+
+		secretID, err := context.RequireEnv(mod.SecretIDEnv, "approle secret ID")
+		if err != nil {
+			return "", err
+		}
+
+		body = map[string]string{"role_id": roleID, "secret_id": secretID}
+	case "kubernetes":
+		if mod.Role == "" {
+			return "", errors.New("role of kubernetes authentication is not specified")
+		}
+
+		jwt, err := os.ReadFile(context.Path(mod.JWTFile))
+		if err != nil {
+			return "", fmt.Errorf("reading service account token: %w", err)
+		}
+
+		body = map[string]string{"jwt": strings.TrimSpace(string(jwt)), "role": mod.Role}
+	default:
+		return "", fmt.Errorf("unknown vault authentication method %q", mod.Auth)
+	}
+
+	mount := mod.AuthMount
+	if mount == "" {
+		mount = mod.Auth
+	}
+
+	var resp struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+
+	context.Progress.SetState(fmt.Sprintf("logging in to vault with %s", mod.Auth))
+
+	if err := requestJSON(cx, http.MethodPost, api+"auth/"+strings.Trim(mount, "/")+"/login", headers, body, &resp); err != nil {
+		return "", fmt.Errorf("logging in to vault with %s: %w", mod.Auth, err)
+	}
+
+	if resp.Auth.ClientToken == "" {
+		return "", fmt.Errorf("logging in to vault with %s: no token returned", mod.Auth)
+	}
+
+	return resp.Auth.ClientToken, nil
+}
+
+// fetch returns environment variables of the secret
+func (secret *VaultSecret) fetch(cx context.Context, api string, headers map[string]string) (map[string]string, error) {
+	if secret.Path == "" {
+		return nil, errors.New("secret path is not specified")
+	}
+
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+
+	if err := requestJSON(cx, http.MethodGet, api+strings.Trim(secret.Path, "/"), headers, nil, &resp); err != nil {
+		return nil, fmt.Errorf("reading secret %s: %w", secret.Path, err)
+	}
+
+	data := resp.Data
+
+	// KV version 2 wraps data with metadata
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = nested
+		}
+	}
+
+	vars := map[string]string{}
+
+	if len(secret.Env) == 0 {
+		for key, val := range data {
+			vars[secretEnvName([]string{key})] = fmt.Sprint(val)
+		}
+
+		return vars, nil
+	}
+
+	for name, key := range secret.Env {
+		val, ok := data[key]
+		if !ok {
+			return nil, fmt.Errorf("key %q not found in secret %s", key, secret.Path)
+		}
+
+		vars[name] = fmt.Sprint(val)
+	}
+
+	return vars, nil
+}
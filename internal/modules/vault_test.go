@@ -0,0 +1,82 @@
+package modules
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+func TestVault_Run(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/approle/login":
+			var body map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body["role_id"] != "role" || body["secret_id"] != "secret" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			_, _ = w.Write([]byte(`{"auth": {"client_token": "s.token"}}`))
+
+			return
+		}
+
+		if r.Header.Get("X-Vault-Token") != "s.token" || r.Header.Get("X-Vault-Namespace") != "ci" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/secret/data/release":
+			_, _ = w.Write([]byte(`{"data": {"data": {"token": "gh", "password": "pw"}, "metadata": {"version": 3}}}`))
+		case "/v1/kv/registry":
+			_, _ = w.Write([]byte(`{"data": {"user": "bot", "pass": "x$word${USER}"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	cx := ctx.New(context.Background())
+
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	context.Env.Set("VAULT_ROLE_ID", "role")
+	context.Env.Set("VAULT_SECRET_ID", "secret")
+	context.Env.Set("VAULT_NAMESPACE", "ci")
+
+	mod := NewVault().(*Vault)
+	mod.Address = srv.URL
+	mod.Auth = "approle"
+	mod.Secrets = []*VaultSecret{
+		{Path: "secret/data/release", Env: map[string]string{"GITHUB_TOKEN": "token"}},
+		{Path: "kv/registry"},
+	}
+
+	if err := mod.Run(cx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// secrets are stored as fetched, without expanding variables
+	for name, want := range map[string]string{"GITHUB_TOKEN": "gh", "USER": "bot", "PASS": "x$word${USER}"} {
+		if got, _ := context.Env.Get(name); got != want {
+			t.Errorf("$%s = %q, want %q", name, got, want)
+		}
+	}
+
+	if _, ok := context.Env.Get("PASSWORD"); ok {
+		t.Error("unmapped key of secret/data/release has been loaded")
+	}
+
+	mod.Secrets = []*VaultSecret{{Path: "secret/data/release", Env: map[string]string{"X": "missing"}}}
+	if err := mod.Run(cx); err == nil {
+		t.Error("Run() with missing key succeeded")
+	}
+}
@@ -0,0 +1,250 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+type (
+	// VerifySignatures is a module for validating signature artifacts
+	// against public keys, catching key mismatches before publishing.
+	//
+	// Signatures are paired with signed artifacts by file name: the
+	// signature's file name has to start with the signed artifact's
+	// file name (eg. `app.tar.gz` and `app.tar.gz.sig`).
+	VerifySignatures struct {
+		// Builds specifies which build names have been signed.
+		Builds []string
+		// Cosign contains cosign verification settings.
+		Cosign *CosignVerification
+		// GPG contains GnuPG verification settings.
+		GPG *GPGVerification
+		// Minisign contains minisign verification settings.
+		Minisign *MinisignVerification
+		// Skip specifies GOOS-GOArch combinations to be skipped.
+		Skip []string
+	}
+
+	// CosignVerification specifies how cosign signatures are verified
+	CosignVerification struct {
+		// Certificates is the artifact name of signing certificates
+		// (keyless mode). Default: "certificate".
+		Certificates string
+		// CertificateIdentity is the expected identity of the signing
+		// certificate (keyless mode).
+		CertificateIdentity string `yaml:"certificate_identity"`
+		// CertificateOIDCIssuer is the expected OIDC issuer of the
+		// signing certificate (keyless mode).
+		CertificateOIDCIssuer string `yaml:"certificate_oidc_issuer"`
+		// Key is a cosign public key reference. Keyless verification is
+		// used if empty.
+		Key string
+		// Signatures is the artifact name of signatures. Default: "signature".
+		Signatures string
+	}
+
+	// GPGVerification specifies how GnuPG signatures are verified
+	GPGVerification struct {
+		// Keyring is a keyring file with public keys. Default keyring is
+		// used if empty.
+		Keyring string
+		// Signatures is the artifact name of signatures. Default: "gpgsig".
+		Signatures string
+	}
+
+	// MinisignVerification specifies how minisign signatures are verified
+	MinisignVerification struct {
+		// PublicKey is the base64-encoded public key.
+		PublicKey string `yaml:"public_key"`
+		// PublicKeyFile is the public key file, used if PublicKey is empty.
+		PublicKeyFile string `yaml:"public_key_file"`
+		// Signatures is the artifact name of signatures. Default: "minisig".
+		Signatures string
+	}
+
+	signaturePair struct {
+		signed    *ctx.Artifact
+		signature *ctx.Artifact
+	}
+)
+
+// NewVerifySignatures is a factory function for VerifySignatures module
+func NewVerifySignatures() modules.Pluggable {
+	return &VerifySignatures{}
+}
+
+// Run verifies all configured signature kinds
+func (mod *VerifySignatures) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	if mod.Cosign == nil && mod.GPG == nil && mod.Minisign == nil {
+		return errors.New("no signature verification configured")
+	}
+
+	if mod.Cosign != nil {
+		if err := mod.verifyCosign(cx, context); err != nil {
+			return err
+		}
+	}
+
+	if mod.GPG != nil {
+		if err := mod.verifyGPG(cx, context); err != nil {
+			return err
+		}
+	}
+
+	if mod.Minisign != nil {
+		if err := mod.verifyMinisign(cx, context); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (mod *VerifySignatures) verifyCosign(cx context.Context, context *ctx.Context) error {
+	pairs, err := mod.pairs(context, defaultString(mod.Cosign.Signatures, "signature"))
+	if err != nil {
+		return fmt.Errorf("cosign: %w", err)
+	}
+
+	certs := context.Artifacts.ByID(defaultString(mod.Cosign.Certificates, "certificate"))
+
+	for _, pair := range pairs {
+		args := []string{"verify-blob", "--signature", pair.signature.Location}
+
+		if mod.Cosign.Key != "" {
+			args = append(args, "--key", mod.Cosign.Key)
+		} else {
+			cert := findSignature(certs, pair.signed)
+			if cert == nil {
+				return fmt.Errorf("cosign: no certificate found for %s", pair.signed.Filename)
+			}
+
+			args = append(
+				args,
+				"--certificate", cert.Location,
+				"--certificate-identity", mod.Cosign.CertificateIdentity,
+				"--certificate-oidc-issuer", mod.Cosign.CertificateOIDCIssuer,
+			)
+		}
+
+		if err := ctx.RunCommand(cx, context.Env, "cosign", append(args, pair.signed.Location)...); err != nil {
+			return fmt.Errorf("cosign: verifying %s: %w", pair.signature.Filename, err)
+		}
+	}
+
+	return nil
+}
+
+func (mod *VerifySignatures) verifyGPG(cx context.Context, context *ctx.Context) error {
+	pairs, err := mod.pairs(context, defaultString(mod.GPG.Signatures, "gpgsig"))
+	if err != nil {
+		return fmt.Errorf("gpg: %w", err)
+	}
+
+	for _, pair := range pairs {
+		args := []string{"--batch"}
+
+		if mod.GPG.Keyring != "" {
+			args = append(args, "--no-default-keyring", "--keyring", mod.GPG.Keyring)
+		}
+
+		args = append(args, "--verify", pair.signature.Location, pair.signed.Location)
+
+		if err := ctx.RunCommand(cx, context.Env, "gpg", args...); err != nil {
+			return fmt.Errorf("gpg: verifying %s: %w", pair.signature.Filename, err)
+		}
+	}
+
+	return nil
+}
+
+func (mod *VerifySignatures) verifyMinisign(cx context.Context, context *ctx.Context) error {
+	pairs, err := mod.pairs(context, defaultString(mod.Minisign.Signatures, "minisig"))
+	if err != nil {
+		return fmt.Errorf("minisign: %w", err)
+	}
+
+	var keyArgs []string
+
+	switch {
+	case mod.Minisign.PublicKey != "":
+		keyArgs = []string{"-P", mod.Minisign.PublicKey}
+	case mod.Minisign.PublicKeyFile != "":
+		keyArgs = []string{"-p", mod.Minisign.PublicKeyFile}
+	default:
+		return errors.New("minisign: no public key provided")
+	}
+
+	for _, pair := range pairs {
+		args := append([]string{"-V", "-m", pair.signed.Location, "-x", pair.signature.Location}, keyArgs...)
+
+		if err := ctx.RunCommand(cx, context.Env, "minisign", args...); err != nil {
+			return fmt.Errorf("minisign: verifying %s: %w", pair.signature.Filename, err)
+		}
+	}
+
+	return nil
+}
+
+// pairs finds a signature for each signed artifact
+func (mod *VerifySignatures) pairs(context *ctx.Context, signatureID string) ([]signaturePair, error) {
+	signatures := context.Artifacts.ByID(signatureID)
+	pairs := []signaturePair{}
+
+	for _, build := range context.Artifacts.OsArchByIDs(mod.Builds, mod.Skip) {
+		for _, signed := range *build {
+			signature := findSignature(signatures, signed)
+			if signature == nil {
+				return nil, fmt.Errorf("no signature found for %s", signed.Filename)
+			}
+
+			pairs = append(pairs, signaturePair{signed: signed, signature: signature})
+		}
+	}
+
+	if len(pairs) == 0 {
+		return nil, fmt.Errorf("no signed artifacts found in %s", strings.Join(mod.Builds, ", "))
+	}
+
+	return pairs, nil
+}
+
+// findSignature returns the signature with the shortest file name, which
+// starts with the signed artifact's file name
+func findSignature(signatures *ctx.Artifacts, signed *ctx.Artifact) *ctx.Artifact {
+	var found *ctx.Artifact
+
+	for _, signature := range *signatures {
+		if signature.OsArch.String() != signed.OsArch.String() {
+			continue
+		}
+
+		if !strings.HasPrefix(signature.Filename, signed.Filename) || signature.Filename == signed.Filename {
+			continue
+		}
+
+		if found == nil || len(signature.Filename) < len(found.Filename) {
+			found = signature
+		}
+	}
+
+	return found
+}
+
+func defaultString(val, deflt string) string {
+	if val == "" {
+		return deflt
+	}
+
+	return val
+}
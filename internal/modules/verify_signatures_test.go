@@ -0,0 +1,45 @@
+package modules
+
+import (
+	"testing"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+func Test_findSignature(t *testing.T) {
+	linux := &ctx.OsArch{OS: "linux", Arch: "amd64"}
+	windows := &ctx.OsArch{OS: "windows", Arch: "amd64"}
+	signatures := &ctx.Artifacts{
+		{Filename: "app.tar.gz.sig", OsArch: linux},
+		{Filename: "app.tar.sig", OsArch: linux},
+		{Filename: "app.zip.sig", OsArch: windows},
+	}
+
+	tests := []struct {
+		name   string
+		signed *ctx.Artifact
+		want   string
+	}{
+		{name: "exact prefix", signed: &ctx.Artifact{Filename: "app.tar.gz", OsArch: linux}, want: "app.tar.gz.sig"},
+		{name: "shortest match", signed: &ctx.Artifact{Filename: "app.tar", OsArch: linux}, want: "app.tar.sig"},
+		{name: "other os-arch", signed: &ctx.Artifact{Filename: "app.zip", OsArch: linux}, want: ""},
+		{name: "not found", signed: &ctx.Artifact{Filename: "other", OsArch: linux}, want: ""},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got := findSignature(signatures, tt.signed)
+
+			var gotName string
+			if got != nil {
+				gotName = got.Filename
+			}
+
+			if gotName != tt.want {
+				t.Errorf("findSignature() = %q, want %q", gotName, tt.want)
+			}
+		})
+	}
+}
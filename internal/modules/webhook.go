@@ -0,0 +1,362 @@
+package modules
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	neturl "net/url"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+type (
+	// Webhook is a publish module sending release information to HTTP
+	// endpoints, like deployment triggers.
+	Webhook struct {
+		// Artifacts is a template expression selecting artifacts of
+		// Builds (or all artifacts, if Builds is empty), evaluated for
+		// each artifact. See modules.SelectArtifacts.
+		Artifacts string
+		// Builds specifies which build names are listed in the payload.
+		// Default: ["archive"].
+		Builds []string
+		// Changelog is the artifact ID of the changelog excerpt. It is
+		// optional. Default: "changelog".
+		Changelog string
+		// Endpoints lists the webhooks to be called. Required.
+		Endpoints []*WebhookEndpoint
+		// Payload is the request body (Go template of WebhookPayload, with
+		// `json`, and `highlights` functions). Default: WebhookPayload in
+		// JSON.
+		Payload string
+		// Skip specifies GOOS-GOArch combinations to be skipped.
+		// They are in `{{.Os}}-{{.Arch}}` format.
+		Skip []string
+	}
+
+	// WebhookEndpoint is an HTTP endpoint of a webhook
+	WebhookEndpoint struct {
+		// Headers are additional request headers (templates).
+		Headers map[string]string
+		// Method is the request method. Default: "POST".
+		Method string
+		// SecretEnv specifies the environment variable of the HMAC-SHA256
+		// secret signing request bodies. Requests are not signed if empty.
+		SecretEnv string `yaml:"secret_env"`
+		// SignatureHeader is the header of the `sha256=<hex>` signature.
+		// Default: "X-Hub-Signature-256".
+		SignatureHeader string `yaml:"signature_header"`
+		// URL is the endpoint's URL (template). Required.
+		URL string
+	}
+
+	// WebhookPayload is the default payload of webhooks, and the data of
+	// payload templates
+	WebhookPayload struct {
+		Artifacts   []*WebhookArtifact `json:"artifacts"`
+		Changelog   string             `json:"changelog,omitempty"`
+		Commit      string             `json:"commit,omitempty"`
+		Images      []string           `json:"images,omitempty"`
+		Links       []ctx.Link         `json:"links,omitempty"`
+		ProjectName string             `json:"project_name"`
+		Tag         string             `json:"tag,omitempty"`
+		Version     string             `json:"version"`
+	}
+
+	// WebhookArtifact is an artifact in webhook payloads
+	WebhookArtifact struct {
+		Filename string `json:"filename"`
+		OsArch   string `json:"osarch"`
+		SHA256   string `json:"sha256"`
+		URL      string `json:"url,omitempty"`
+	}
+)
+
+// NewWebhook is a factory function for Webhook module
+func NewWebhook() modules.Pluggable {
+	return &Webhook{
+		Builds:    []string{"archive"},
+		Changelog: "changelog",
+		Skip:      []string{},
+	}
+}
+
+// Run sends the payload to all endpoints
+func (mod *Webhook) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	if len(mod.Endpoints) == 0 {
+		return errors.New("no endpoints specified")
+	}
+
+	payload, err := releasePayload(cx, context, mod.Builds, mod.Skip, mod.Artifacts, mod.Changelog)
+	if err != nil {
+		return err
+	}
+
+	body, err := mod.render(payload)
+	if err != nil {
+		return err
+	}
+
+	for _, endpoint := range mod.Endpoints {
+		if err := endpoint.send(cx, context, body); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidateTemplates implements modules.TemplateValidator
+func (mod *Webhook) ValidateTemplates(context.Context) ([]string, error) {
+	if mod.Payload == "" {
+		return []string{"Payload"}, nil
+	}
+
+	_, err := renderPayload("webhook-payload", mod.Payload, sampleWebhookPayload())
+
+	return []string{"Payload"}, err
+}
+
+// render renders the request body from the payload
+func (mod *Webhook) render(payload *WebhookPayload) ([]byte, error) {
+	if mod.Payload == "" {
+		return json.Marshal(payload)
+	}
+
+	out, err := renderPayload("webhook-payload", mod.Payload, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(out), nil
+}
+
+// releasePayload collects release information: selected artifacts with
+// their checksums and download URLs, the changelog excerpt (if there is
+// an artifact of changelogID), and links and images of publishers
+func releasePayload(cx context.Context, context *ctx.Context, builds, skips []string, expr, changelogID string) (*WebhookPayload, error) {
+	payload := &WebhookPayload{
+		Artifacts:   []*WebhookArtifact{},
+		Commit:      context.Git.Ref,
+		ProjectName: context.ProjectName,
+		Tag:         context.Git.Tag,
+		Version:     context.Version,
+	}
+
+	if release := context.Published.Release(); release != nil {
+		payload.Images = release.Images
+		payload.Links = release.Links
+	}
+
+	if changelogID != "" {
+		if changelogs := *context.Artifacts.ByID(changelogID); len(changelogs) > 0 {
+			content, err := ioutil.ReadFile(changelogs[0].Location)
+			if err != nil {
+				return nil, fmt.Errorf("reading changelog: %w", err)
+			}
+
+			payload.Changelog = string(content)
+		}
+	}
+
+	selected, err := modules.SelectArtifacts(cx, builds, skips, expr)
+	if err != nil {
+		return nil, err
+	}
+
+	algo, err := modules.NewHashAlgorithm(modules.DefaultDigest)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, build := range selected {
+		for _, artifact := range *build {
+			sum, err := algo.SumArtifact(artifact)
+			if err != nil {
+				return nil, fmt.Errorf("calculating checksum of %s: %w", artifact.Filename, err)
+			}
+
+			url, _ := context.Published.DownloadURL(artifact.Filename)
+
+			payload.Artifacts = append(payload.Artifacts, &WebhookArtifact{
+				Filename: artifact.Filename,
+				OsArch:   artifact.OsArch.String(),
+				SHA256:   sum,
+				URL:      url,
+			})
+		}
+	}
+
+	sort.Slice(payload.Artifacts, func(i, j int) bool {
+		return payload.Artifacts[i].Filename < payload.Artifacts[j].Filename
+	})
+
+	return payload, nil
+}
+
+// renderPayload renders a Go template of release information, with
+// `json` function encoding values, `highlights` function returning the
+// first n list items of a changelog, and registered template functions
+// (see modules.RegisterTemplateFunc)
+func renderPayload(name, text string, payload interface{}) (string, error) {
+	funcs := modules.TemplateFuncs()
+	funcs["highlights"] = changelogHighlights
+	funcs["json"] = func(value interface{}) (string, error) {
+		out, err := json.Marshal(value)
+		return string(out), err
+	}
+
+	tmpl, err := template.New(name).Funcs(funcs).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("parsing %s: %w", name, err)
+	}
+
+	out := &bytes.Buffer{}
+	if err := tmpl.Execute(out, payload); err != nil {
+		return "", fmt.Errorf("rendering %s: %w", name, err)
+	}
+
+	return out.String(), nil
+}
+
+// sampleWebhookPayload returns a WebhookPayload with sample values, for
+// checking templates
+func sampleWebhookPayload() *WebhookPayload {
+	return &WebhookPayload{
+		Artifacts: []*WebhookArtifact{{
+			Filename: "project-v0.0.0-linux-amd64.tar.gz",
+			OsArch:   "linux-amd64",
+			SHA256:   strings.Repeat("0", 64),
+			URL:      "https://example.com/project-v0.0.0-linux-amd64.tar.gz",
+		}},
+		Changelog:   "- change",
+		Commit:      strings.Repeat("0", 40),
+		Links:       []ctx.Link{{Name: "Release", URL: "https://example.com/releases/v0.0.0"}},
+		ProjectName: "project",
+		Tag:         "v0.0.0",
+		Version:     "v0.0.0",
+	}
+}
+
+// changelogHighlights returns the first n list items of a changelog,
+// without list markers
+func changelogHighlights(n int, changelog string) []string {
+	items := []string{}
+
+	for _, line := range strings.Split(changelog, "\n") {
+		if len(items) >= n {
+			break
+		}
+
+		line = strings.TrimSpace(line)
+
+		for _, marker := range []string{"- ", "* "} {
+			if strings.HasPrefix(line, marker) {
+				items = append(items, strings.TrimSpace(strings.TrimPrefix(line, marker)))
+				break
+			}
+		}
+	}
+
+	return items
+}
+
+// send sends the body to the endpoint, signing it if configured
+func (endpoint *WebhookEndpoint) send(cx context.Context, context *ctx.Context, body []byte) error {
+	td, err := modules.NewTemplate(cx)
+	if err != nil {
+		return err
+	}
+
+	if endpoint.URL == "" {
+		return errors.New("endpoint URL is not specified")
+	}
+
+	url, err := td.Parse("webhook-url", endpoint.URL)
+	if err != nil {
+		return fmt.Errorf("rendering URL: %w", err)
+	}
+
+	method := endpoint.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	// URLs may contain credentials: only the host is reported, and errors
+	// of net/url, containing the whole URL, are unwrapped
+	var urlErr *neturl.Error
+
+	req, err := http.NewRequestWithContext(cx, method, url, bytes.NewReader(body))
+	if err != nil {
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+
+		return fmt.Errorf("invalid webhook URL: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "goshipdone")
+
+	for name, value := range endpoint.Headers {
+		rendered, err := td.Parse("webhook-header", value)
+		if err != nil {
+			return fmt.Errorf("rendering header %s: %w", name, err)
+		}
+
+		req.Header.Set(name, rendered)
+	}
+
+	if endpoint.SecretEnv != "" {
+		secret, err := context.RequireEnv(endpoint.SecretEnv, "webhook secret")
+		if err != nil {
+			return err
+		}
+
+		header := endpoint.SignatureHeader
+		if header == "" {
+			header = "X-Hub-Signature-256"
+		}
+
+		mac := hmac.New(sha256.New, []byte(secret))
+		_, _ = mac.Write(body)
+
+		req.Header.Set(header, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	context.Progress.SetState(fmt.Sprintf("calling webhook at %s", req.URL.Host))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+
+		return fmt.Errorf("calling webhook at %s: %w", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("calling webhook at %s: %s: %s", req.URL.Host, resp.Status, bytes.TrimSpace(msg))
+	}
+
+	return nil
+}
@@ -0,0 +1,323 @@
+package modules
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+	"gopkg.in/yaml.v3"
+)
+
+const wingetManifestVersion = "1.6.0"
+
+type (
+	// Winget is a publish module generating winget-pkgs manifests for
+	// windows artifacts, optionally submitting them as a pull request
+	// against microsoft/winget-pkgs.
+	Winget struct {
+		// Artifacts is a template expression selecting artifacts of
+		// Builds (or all artifacts, if Builds is empty), evaluated for
+		// each artifact. See modules.SelectArtifacts.
+		Artifacts string
+		// Binary is the executable's path in zip archives (template).
+		// Default: "{{.ProjectName}}.exe".
+		Binary string
+		// Builds specifies windows artifacts to be installed: zip
+		// archives, or executables. Default: ["archive"].
+		Builds []string
+		// CommitAuthor is the pull request commit's author in
+		// `Name <email>` format. Git config is used if empty.
+		CommitAuthor string `yaml:"commit_author"`
+		// ID is the artifact ID of generated manifests. Default: "winget".
+		ID string
+		// License is the package's license. Required.
+		License string
+		// Moniker is the package's short name. Default: empty.
+		Moniker string
+		// PackageIdentifier is the package's winget ID, like
+		// "Publisher.App". Required.
+		PackageIdentifier string `yaml:"package_identifier"`
+		// PackageName is the package's name (template).
+		// Default: "{{.ProjectName}}".
+		PackageName string `yaml:"package_name"`
+		// PackageURL is the package's home page. Default: empty.
+		PackageURL string `yaml:"package_url"`
+		// Publisher is the package's publisher. Required.
+		Publisher string
+		// PullRequest submits manifests to winget-pkgs, if set.
+		// Default repository: microsoft/winget-pkgs.
+		PullRequest *GitHubPullRequest `yaml:"pull_request"`
+		// ShortDescription is the package's description. Required.
+		ShortDescription string `yaml:"short_description"`
+		// Skip specifies GOOS-GOArch combinations to be skipped.
+		// They are in `{{.Os}}-{{.Arch}}` format.
+		Skip []string
+		// URLTemplate renders download URLs of artifacts. Download links
+		// of previous publishers are used if empty.
+		URLTemplate string `yaml:"url_template"`
+	}
+
+	wingetVersion struct {
+		PackageIdentifier string `yaml:"PackageIdentifier"`
+		PackageVersion    string `yaml:"PackageVersion"`
+		DefaultLocale     string `yaml:"DefaultLocale"`
+		ManifestType      string `yaml:"ManifestType"`
+		ManifestVersion   string `yaml:"ManifestVersion"`
+	}
+
+	wingetInstallers struct {
+		PackageIdentifier string             `yaml:"PackageIdentifier"`
+		PackageVersion    string             `yaml:"PackageVersion"`
+		ReleaseDate       string             `yaml:"ReleaseDate,omitempty"`
+		Installers        []*wingetInstaller `yaml:"Installers"`
+		ManifestType      string             `yaml:"ManifestType"`
+		ManifestVersion   string             `yaml:"ManifestVersion"`
+	}
+
+	wingetInstaller struct {
+		Architecture         string              `yaml:"Architecture"`
+		InstallerType        string              `yaml:"InstallerType"`
+		NestedInstallerType  string              `yaml:"NestedInstallerType,omitempty"`
+		NestedInstallerFiles []*wingetNestedFile `yaml:"NestedInstallerFiles,omitempty"`
+		InstallerURL         string              `yaml:"InstallerUrl"`
+		InstallerSha256      string              `yaml:"InstallerSha256"`
+	}
+
+	wingetNestedFile struct {
+		RelativeFilePath     string `yaml:"RelativeFilePath"`
+		PortableCommandAlias string `yaml:"PortableCommandAlias,omitempty"`
+	}
+
+	wingetLocale struct {
+		PackageIdentifier string `yaml:"PackageIdentifier"`
+		PackageVersion    string `yaml:"PackageVersion"`
+		PackageLocale     string `yaml:"PackageLocale"`
+		Publisher         string `yaml:"Publisher"`
+		PackageName       string `yaml:"PackageName"`
+		PackageURL        string `yaml:"PackageUrl,omitempty"`
+		License           string `yaml:"License"`
+		ShortDescription  string `yaml:"ShortDescription"`
+		Moniker           string `yaml:"Moniker,omitempty"`
+		ManifestType      string `yaml:"ManifestType"`
+		ManifestVersion   string `yaml:"ManifestVersion"`
+	}
+)
+
+// wingetArchs maps GOARCH values to winget architectures
+// nolint: gochecknoglobals
+var wingetArchs = map[string]string{
+	"386":   "x86",
+	"amd64": "x64",
+	"arm":   "arm",
+	"arm64": "arm64",
+}
+
+// NewWinget is a factory function for Winget module
+func NewWinget() modules.Pluggable {
+	return &Winget{
+		Binary:      "{{.ProjectName}}.exe",
+		Builds:      []string{"archive"},
+		ID:          "winget",
+		PackageName: "{{.ProjectName}}",
+		Skip:        []string{},
+	}
+}
+
+// Run generates winget manifests, registers them as artifacts, and submits
+// them as a pull request if configured
+func (mod *Winget) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	files, err := mod.manifests(cx, context)
+	if err != nil {
+		return err
+	}
+
+	for name, content := range files {
+		location := filepath.Join(context.TargetDir, "winget", filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(location), 0o755); err != nil {
+			return fmt.Errorf("creating manifest directory: %w", err)
+		}
+
+		if err := os.WriteFile(location, content, 0o644); err != nil { // nolint: gosec
+			return fmt.Errorf("writing manifest %s: %w", name, err)
+		}
+
+		context.Artifacts.Add(&ctx.Artifact{
+			Filename: path.Base(name),
+			ID:       mod.ID,
+			Location: location,
+		})
+	}
+
+	if mod.PullRequest == nil {
+		return nil
+	}
+
+	mod.PullRequest.defaults("microsoft/winget-pkgs")
+
+	version := strings.TrimPrefix(context.Version, "v")
+	branch := fmt.Sprintf("%s-%s", mod.PackageIdentifier, version)
+	title := fmt.Sprintf("New version: %s version %s", mod.PackageIdentifier, version)
+
+	_, err = mod.PullRequest.submit(cx, context, branch, title, mod.CommitAuthor, files)
+
+	return err
+}
+
+// manifests renders multi-file manifests, keyed by their paths in
+// winget-pkgs repository
+func (mod *Winget) manifests(cx context.Context, context *ctx.Context) (map[string][]byte, error) {
+	for name, value := range map[string]string{
+		"license":            mod.License,
+		"package_identifier": mod.PackageIdentifier,
+		"publisher":          mod.Publisher,
+		"short_description":  mod.ShortDescription,
+	} {
+		if value == "" {
+			return nil, fmt.Errorf("%s is required", name)
+		}
+	}
+
+	installers, err := mod.installers(cx)
+	if err != nil {
+		return nil, err
+	}
+
+	td, err := modules.NewTemplate(cx)
+	if err != nil {
+		return nil, err
+	}
+
+	packageName, err := td.Parse("winget-package-name", mod.PackageName)
+	if err != nil {
+		return nil, fmt.Errorf("rendering package name: %w", err)
+	}
+
+	version := strings.TrimPrefix(context.Version, "v")
+	id := mod.PackageIdentifier
+	dir := path.Join(
+		"manifests",
+		strings.ToLower(id[:1]),
+		strings.ReplaceAll(id, ".", "/"),
+		version,
+	)
+
+	manifests := map[string]interface{}{
+		id + ".yaml": &wingetVersion{
+			PackageIdentifier: id,
+			PackageVersion:    version,
+			DefaultLocale:     "en-US",
+			ManifestType:      "version",
+			ManifestVersion:   wingetManifestVersion,
+		},
+		id + ".installer.yaml": &wingetInstallers{
+			PackageIdentifier: id,
+			PackageVersion:    version,
+			ReleaseDate:       context.StartedAt.Format("2006-01-02"),
+			Installers:        installers,
+			ManifestType:      "installer",
+			ManifestVersion:   wingetManifestVersion,
+		},
+		id + ".locale.en-US.yaml": &wingetLocale{
+			PackageIdentifier: id,
+			PackageVersion:    version,
+			PackageLocale:     "en-US",
+			Publisher:         mod.Publisher,
+			PackageName:       packageName,
+			PackageURL:        mod.PackageURL,
+			License:           mod.License,
+			ShortDescription:  mod.ShortDescription,
+			Moniker:           mod.Moniker,
+			ManifestType:      "defaultLocale",
+			ManifestVersion:   wingetManifestVersion,
+		},
+	}
+
+	files := make(map[string][]byte, len(manifests))
+
+	for name, manifest := range manifests {
+		content := &bytes.Buffer{}
+		fmt.Fprintf(content, "# Created with goshipdone\n")
+
+		enc := yaml.NewEncoder(content)
+		enc.SetIndent(2)
+
+		if err := enc.Encode(manifest); err != nil {
+			return nil, fmt.Errorf("encoding %s: %w", name, err)
+		}
+
+		files[path.Join(dir, name)] = content.Bytes()
+	}
+
+	return files, nil
+}
+
+func (mod *Winget) installers(cx context.Context) ([]*wingetInstaller, error) {
+	artifacts, err := packageArtifacts(cx, mod.Builds, mod.Skip, mod.Artifacts, mod.URLTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	td, err := modules.NewTemplate(cx)
+	if err != nil {
+		return nil, err
+	}
+
+	binary, err := td.Parse("winget-binary", mod.Binary)
+	if err != nil {
+		return nil, fmt.Errorf("rendering binary: %w", err)
+	}
+
+	installers := []*wingetInstaller{}
+
+	for _, artifact := range artifacts {
+		if artifact.OsArch == nil || artifact.OS != "windows" {
+			continue
+		}
+
+		arch, ok := wingetArchs[artifact.Arch]
+		if !ok {
+			return nil, fmt.Errorf("%s: architecture %s is not supported by winget", artifact.Filename, artifact.Arch)
+		}
+
+		installer := &wingetInstaller{
+			Architecture:    arch,
+			InstallerURL:    artifact.URL,
+			InstallerSha256: strings.ToUpper(artifact.SHA256),
+		}
+
+		switch strings.ToLower(path.Ext(artifact.Filename)) {
+		case ".zip":
+			installer.InstallerType = "zip"
+			installer.NestedInstallerType = "portable"
+			installer.NestedInstallerFiles = []*wingetNestedFile{{
+				RelativeFilePath:     binary,
+				PortableCommandAlias: strings.TrimSuffix(path.Base(binary), ".exe"),
+			}}
+		case ".exe":
+			installer.InstallerType = "portable"
+		default:
+			return nil, fmt.Errorf("%s: only zip archives and executables are supported by winget", artifact.Filename)
+		}
+
+		installers = append(installers, installer)
+	}
+
+	if len(installers) == 0 {
+		return nil, errors.New("no windows artifacts found")
+	}
+
+	return installers, nil
+}
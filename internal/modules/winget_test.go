@@ -0,0 +1,86 @@
+package modules
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+func TestWinget_Run(t *testing.T) {
+	dir := t.TempDir()
+	cx := ctx.New(context.Background())
+
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	context.ProjectName = "app"
+	context.StartedAt = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	context.TargetDir = dir
+	context.Version = "v1.2.3"
+
+	for _, art := range []*ctx.Artifact{
+		{Filename: "app-windows-amd64.zip", OsArch: &ctx.OsArch{OS: "windows", Arch: "amd64"}},
+		{Filename: "app-linux-amd64.zip", OsArch: &ctx.OsArch{OS: "linux", Arch: "amd64"}},
+	} {
+		art.ID = "archive"
+		art.Location = filepath.Join(dir, art.Filename)
+
+		if err := os.WriteFile(art.Location, []byte("content"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		context.Artifacts.Add(art)
+	}
+
+	mod := NewWinget().(*Winget)
+	mod.License = "MIT"
+	mod.PackageIdentifier = "Example.App"
+	mod.Publisher = "Example"
+	mod.ShortDescription = "An example app"
+	mod.URLTemplate = "https://example.com/{{.Version}}/{{.Filename}}"
+
+	if err := mod.Run(cx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	installer, err := os.ReadFile(filepath.Join(dir, "winget", "manifests", "e", "Example", "App", "1.2.3", "Example.App.installer.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# Created with goshipdone
+PackageIdentifier: Example.App
+PackageVersion: 1.2.3
+ReleaseDate: "2026-01-02"
+Installers:
+  - Architecture: x64
+    InstallerType: zip
+    NestedInstallerType: portable
+    NestedInstallerFiles:
+      - RelativeFilePath: app.exe
+        PortableCommandAlias: app
+    InstallerUrl: https://example.com/v1.2.3/app-windows-amd64.zip
+    InstallerSha256: ED7002B439E9AC845F22357D822BAC1444730FBDB6016D3EC9432297B9EC9F73
+ManifestType: installer
+ManifestVersion: 1.6.0
+`
+	if string(installer) != want {
+		t.Errorf("installer manifest:\n%s\nwant:\n%s", installer, want)
+	}
+
+	if got := len(*context.Artifacts.ByID("winget")); got != 3 {
+		t.Errorf("registered %d manifests, want 3", got)
+	}
+
+	mod.Builds = []string{"nonexisting"}
+	if err := mod.Run(cx); err == nil || !strings.Contains(err.Error(), "no windows artifacts") {
+		t.Errorf("Run() error = %v, want missing windows artifacts", err)
+	}
+}
@@ -0,0 +1,206 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/internal/rpm"
+	"github.com/julian7/goshipdone/modules"
+)
+
+// YUM is a publish module maintaining a YUM/DNF repository of RPM packages
+// in a local directory. It copies packages into the repository, and
+// regenerates createrepo compatible metadata of all packages found there,
+// optionally signing it with GnuPG. Repository files changed are
+// registered as artifacts, to be uploaded by other publishers (like
+// publish:s3), or the directory can be synced elsewhere.
+type YUM struct {
+	// Artifacts is a template expression selecting artifacts of Builds
+	// (or all artifacts, if Builds is empty), evaluated for each artifact.
+	// See modules.SelectArtifacts.
+	Artifacts string
+	// Builds specifies RPM packages to be published. Default: ["rpm"].
+	Builds []string
+	// Directory is the repository's local directory. Keep it (or sync it
+	// back) between releases to retain packages of previous releases.
+	// Default: "<target>/yum".
+	Directory string
+	// ID is the artifact ID of repository files. Default: "yum".
+	ID string
+	// PackageDir is the directory of packages in the repository.
+	// Default: "Packages".
+	PackageDir string `yaml:"package_dir"`
+	// PassphraseEnv specifies the environment variable of the signing
+	// key's passphrase. Default: empty (no passphrase).
+	PassphraseEnv string `yaml:"passphrase_env"`
+	// SigningKey is the GnuPG key ID signing repomd.xml. Default: the key
+	// imported by setup:gpg_import. Metadata is not signed if empty.
+	SigningKey string `yaml:"signing_key"`
+	// Skip specifies GOOS-GOArch combinations to be skipped.
+	// They are in `{{.Os}}-{{.Arch}}` format.
+	Skip []string
+}
+
+// NewYUM is a factory function for YUM module
+func NewYUM() modules.Pluggable {
+	return &YUM{
+		Builds:     []string{"rpm"},
+		ID:         "yum",
+		PackageDir: "Packages",
+		Skip:       []string{},
+	}
+}
+
+// Run adds packages into the repository, and regenerates its metadata
+func (mod *YUM) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	dir := context.Path(mod.Directory)
+	if dir == "" {
+		dir = filepath.Join(context.TargetDir, "yum")
+	}
+
+	added, err := mod.addPackages(cx, dir)
+	if err != nil {
+		return err
+	}
+
+	pkgs, err := mod.scan(dir)
+	if err != nil {
+		return err
+	}
+
+	metadata, err := rpm.Metadata(pkgs, context.StartedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := removeStaleMetadata(dir, metadata); err != nil {
+		return err
+	}
+
+	for _, name := range repoFileNames(metadata) {
+		if err := writeRepoFile(dir, name, metadata[name]); err != nil {
+			return err
+		}
+	}
+
+	files := append(added, repoFileNames(metadata)...)
+
+	if key := defaultString(mod.SigningKey, context.GPGFingerprint); key != "" {
+		repomd := filepath.Join(dir, "repodata", "repomd.xml")
+
+		if err := gpgSign(cx, context, key, mod.PassphraseEnv, repomd, repomd+".asc", "--armor", "--detach-sign"); err != nil {
+			return err
+		}
+
+		files = append(files, "repodata/repomd.xml.asc")
+	}
+
+	for _, name := range files {
+		context.Artifacts.Add(&ctx.Artifact{
+			Filename: name,
+			ID:       mod.ID,
+			Location: filepath.Join(dir, filepath.FromSlash(name)),
+		})
+	}
+
+	return nil
+}
+
+// addPackages copies selected packages into the repository, returning
+// their paths relative to the repository root
+func (mod *YUM) addPackages(cx context.Context, dir string) ([]string, error) {
+	builds, err := modules.SelectArtifacts(cx, mod.Builds, mod.Skip, mod.Artifacts)
+	if err != nil {
+		return nil, err
+	}
+
+	added := []string{}
+
+	for _, build := range builds {
+		for _, artifact := range *build {
+			if !strings.HasSuffix(artifact.Filename, ".rpm") {
+				continue
+			}
+
+			name := path.Join(mod.PackageDir, artifact.Filename)
+
+			if err := copyRepoFile(artifact.Location, filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+				return nil, err
+			}
+
+			added = append(added, name)
+		}
+	}
+
+	if len(added) == 0 {
+		return nil, errors.New("no RPM packages found")
+	}
+
+	return added, nil
+}
+
+// scan reads all packages of the repository
+func (mod *YUM) scan(dir string) ([]*rpm.RepoPackage, error) {
+	pkgs := []*rpm.RepoPackage{}
+
+	err := filepath.Walk(dir, func(location string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".rpm") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, location)
+		if err != nil {
+			return err
+		}
+
+		pkg, err := rpm.ReadRepoPackage(location, filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+
+		pkgs = append(pkgs, pkg)
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning repository: %w", err)
+	}
+
+	return pkgs, nil
+}
+
+// removeStaleMetadata removes metadata files of previous runs, as their
+// names contain their checksums
+func removeStaleMetadata(dir string, metadata map[string][]byte) error {
+	stale, err := filepath.Glob(filepath.Join(dir, "repodata", "*.xml.gz"))
+	if err != nil {
+		return err
+	}
+
+	for _, location := range stale {
+		if _, ok := metadata["repodata/"+filepath.Base(location)]; ok {
+			continue
+		}
+
+		if err := os.Remove(location); err != nil {
+			return fmt.Errorf("removing stale metadata: %w", err)
+		}
+	}
+
+	return nil
+}
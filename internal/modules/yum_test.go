@@ -0,0 +1,249 @@
+package modules
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/internal/rpm/rpmtest"
+)
+
+// fakeGPG writes a signature into the --output file
+const fakeGPG = `while [ $# -gt 0 ]; do
+	[ "$1" = --output ] && echo signature > "$2"
+	shift
+done`
+
+// nolint: funlen
+func TestYUM_Run(t *testing.T) {
+	linux := &ctx.OsArch{OS: "linux", Arch: "amd64"}
+	checksum := regexp.MustCompile(`[0-9a-f]{64}-`)
+	repoFiles := []string{
+		"repodata/filelists.xml.gz",
+		"repodata/other.xml.gz",
+		"repodata/primary.xml.gz",
+		"repodata/repomd.xml",
+	}
+
+	tests := []struct {
+		name string
+		mod  *YUM
+		// existing are files in the repository before the run. Files
+		// other than packages are expected to be removed as stale
+		// metadata.
+		existing    []string
+		fingerprint string
+		env         map[string]string
+		script      string
+		// wantCalls are gpg invocations, REPO standing for the
+		// repository's directory
+		wantCalls []string
+		// wantArtifacts are registered repository files, without
+		// checksums in metadata file names
+		wantArtifacts []string
+		// wantPackages are package locations listed in primary metadata
+		wantPackages []string
+		wantErr      string
+	}{
+		{
+			name:          "new repository",
+			mod:           &YUM{},
+			wantArtifacts: append([]string{"Packages/hello-1.0.0-1.x86_64.rpm"}, repoFiles...),
+			wantPackages:  []string{"Packages/hello-1.0.0-1.x86_64.rpm"},
+		},
+		{
+			name:          "existing packages",
+			mod:           &YUM{PackageDir: "x86_64"},
+			existing:      []string{"Packages/hello-0.9.0-1.x86_64.rpm", "repodata/0123-primary.xml.gz"},
+			wantArtifacts: append([]string{"x86_64/hello-1.0.0-1.x86_64.rpm"}, repoFiles...),
+			wantPackages:  []string{"Packages/hello-0.9.0-1.x86_64.rpm", "x86_64/hello-1.0.0-1.x86_64.rpm"},
+		},
+		{
+			name:          "signed",
+			mod:           &YUM{SigningKey: "ABCD1234"},
+			script:        fakeGPG,
+			wantCalls:     []string{"--batch --yes --local-user ABCD1234 --armor --detach-sign --output REPO/repodata/repomd.xml.asc REPO/repodata/repomd.xml"},
+			wantArtifacts: append([]string{"Packages/hello-1.0.0-1.x86_64.rpm", "repodata/repomd.xml.asc"}, repoFiles...),
+			wantPackages:  []string{"Packages/hello-1.0.0-1.x86_64.rpm"},
+		},
+		{
+			name:        "imported key with passphrase",
+			mod:         &YUM{PassphraseEnv: "GPG_PASSPHRASE"},
+			fingerprint: "FEDC4321",
+			env:         map[string]string{"GPG_PASSPHRASE": "secret"},
+			script:      fakeGPG,
+			wantCalls: []string{
+				"--batch --yes --local-user FEDC4321 --pinentry-mode loopback --passphrase-fd 0 --armor --detach-sign " +
+					"--output REPO/repodata/repomd.xml.asc REPO/repodata/repomd.xml",
+			},
+			wantArtifacts: append([]string{"Packages/hello-1.0.0-1.x86_64.rpm", "repodata/repomd.xml.asc"}, repoFiles...),
+			wantPackages:  []string{"Packages/hello-1.0.0-1.x86_64.rpm"},
+		},
+		{
+			name:    "no packages",
+			mod:     &YUM{Builds: []string{"archive"}},
+			wantErr: "no RPM packages found",
+		},
+		{
+			name:     "broken package in repository",
+			mod:      &YUM{},
+			existing: []string{"Packages/broken.rpm"},
+			wantErr:  "scanning repository",
+		},
+		{
+			name:    "missing passphrase",
+			mod:     &YUM{SigningKey: "ABCD1234", PassphraseEnv: "GPG_PASSPHRASE"},
+			script:  fakeGPG,
+			wantErr: "signing key passphrase not found in $GPG_PASSPHRASE",
+		},
+		{
+			name:    "failing gpg",
+			mod:     &YUM{SigningKey: "ABCD1234"},
+			script:  "exit 1",
+			wantErr: "gpg: signing repomd.xml",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			calls := fakeCommand(t, "gpg", tt.script)
+			cx, shipContext := testShipContext(t)
+			shipContext.GPGFingerprint = tt.fingerprint
+
+			for key, value := range tt.env {
+				shipContext.Env.Set(key, value)
+			}
+
+			addTestArtifact(t, shipContext, "archive", "app.tar.gz", linux)
+			pkg := addTestArtifact(t, shipContext, "rpm", "hello-1.0.0-1.x86_64.rpm", linux)
+			rpmtest.WritePackage(t, pkg.Location, 0, rpmtest.Package("hello", "1.0.0", "x86_64"))
+
+			repo := filepath.Join(t.TempDir(), "repo")
+
+			for _, name := range tt.existing {
+				location := filepath.Join(repo, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(location), 0o755); err != nil {
+					t.Fatal(err)
+				}
+
+				if !strings.HasSuffix(name, ".rpm") || strings.Contains(name, "broken") {
+					if err := os.WriteFile(location, []byte(name), 0o600); err != nil {
+						t.Fatal(err)
+					}
+
+					continue
+				}
+
+				rpmtest.WritePackage(t, location, 0, rpmtest.Package("hello", "0.9.0", "x86_64"))
+			}
+
+			mod := NewYUM().(*YUM)
+			mod.Directory = repo
+			mod.PassphraseEnv = tt.mod.PassphraseEnv
+			mod.SigningKey = tt.mod.SigningKey
+
+			if tt.mod.Builds != nil {
+				mod.Builds = tt.mod.Builds
+			}
+
+			if tt.mod.PackageDir != "" {
+				mod.PackageDir = tt.mod.PackageDir
+			}
+
+			err := mod.Run(cx)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Run() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			for _, name := range tt.existing {
+				if _, err := os.Stat(filepath.Join(repo, filepath.FromSlash(name))); !strings.HasSuffix(name, ".rpm") && err == nil {
+					t.Errorf("stale %s kept", name)
+				}
+			}
+
+			var wantCalls []string
+			for _, call := range tt.wantCalls {
+				wantCalls = append(wantCalls, strings.ReplaceAll(call, "REPO", repo))
+			}
+
+			if diff := deep.Equal(fakeCalls(t, calls), wantCalls); diff != nil {
+				t.Errorf("gpg calls %v", diff)
+			}
+
+			got := []string{}
+			primary := ""
+
+			for _, art := range *shipContext.Artifacts.ByID("yum") {
+				got = append(got, checksum.ReplaceAllString(art.Filename, ""))
+
+				if _, err := os.Stat(art.Location); err != nil {
+					t.Errorf("artifact %s: %v", art.Filename, err)
+				}
+
+				if strings.HasSuffix(art.Filename, "-primary.xml.gz") {
+					primary = readGzip(t, art.Location)
+				}
+			}
+
+			sort.Strings(got)
+
+			want := append([]string{}, tt.wantArtifacts...)
+			sort.Strings(want)
+
+			if diff := deep.Equal(got, want); diff != nil {
+				t.Errorf("Run() artifacts %v", diff)
+			}
+
+			if count := strings.Count(primary, "<location href="); count != len(tt.wantPackages) {
+				t.Errorf("primary.xml lists %d packages, want %d", count, len(tt.wantPackages))
+			}
+
+			for _, location := range tt.wantPackages {
+				if !strings.Contains(primary, `<location href="`+location+`">`) {
+					t.Errorf("primary.xml does not list %s:\n%s", location, primary)
+				}
+			}
+		})
+	}
+}
+
+// readGzip returns the decompressed contents of a gzip file
+func readGzip(t *testing.T, location string) string {
+	t.Helper()
+
+	content, err := os.ReadFile(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
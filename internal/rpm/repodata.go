@@ -0,0 +1,527 @@
+package rpm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	nsCommon    = "http://linux.duke.edu/metadata/common"
+	nsFilelists = "http://linux.duke.edu/metadata/filelists"
+	nsOther     = "http://linux.duke.edu/metadata/other"
+	nsRepo      = "http://linux.duke.edu/metadata/repo"
+	nsRPM       = "http://linux.duke.edu/metadata/rpm"
+
+	senseLess    = 1 << 1
+	senseGreater = 1 << 2
+	senseEqual   = 1 << 3
+	// prerequisites: RPMSENSE_PREREQ, RPMSENSE_SCRIPT_PRE, and
+	// RPMSENSE_SCRIPT_POST
+	sensePre = 1<<6 | 1<<9 | 1<<10
+
+	fileFlagGhost = 1 << 6
+	fileModeDir   = 0o040000
+	fileModeType  = 0o170000
+)
+
+type (
+	// RepoPackage is a package in a repository
+	RepoPackage struct {
+		*Package
+		// Location is the package's path relative to the repository root
+		Location string
+		// ModTime is the package file's modification time
+		ModTime time.Time
+		// SHA256 is the hex-encoded SHA256 checksum of the package file
+		SHA256 string
+		// Size is the package file's size in bytes
+		Size int64
+	}
+
+	xmlVersion struct {
+		Epoch string `xml:"epoch,attr"`
+		Ver   string `xml:"ver,attr"`
+		Rel   string `xml:"rel,attr"`
+	}
+
+	xmlEntry struct {
+		Name  string `xml:"name,attr"`
+		Flags string `xml:"flags,attr,omitempty"`
+		Epoch string `xml:"epoch,attr,omitempty"`
+		Ver   string `xml:"ver,attr,omitempty"`
+		Rel   string `xml:"rel,attr,omitempty"`
+		Pre   string `xml:"pre,attr,omitempty"`
+	}
+
+	xmlEntries struct {
+		Entries []*xmlEntry `xml:"rpm:entry"`
+	}
+
+	xmlFile struct {
+		Type string `xml:"type,attr,omitempty"`
+		Path string `xml:",chardata"`
+	}
+
+	xmlChecksum struct {
+		Type  string `xml:"type,attr"`
+		PkgID string `xml:"pkgid,attr,omitempty"`
+		Value string `xml:",chardata"`
+	}
+
+	xmlPrimary struct {
+		XMLName  xml.Name          `xml:"metadata"`
+		NS       string            `xml:"xmlns,attr"`
+		NSRPM    string            `xml:"xmlns:rpm,attr"`
+		Count    int               `xml:"packages,attr"`
+		Packages []*xmlPrimaryItem `xml:"package"`
+	}
+
+	xmlPrimaryItem struct {
+		Type        string      `xml:"type,attr"`
+		Name        string      `xml:"name"`
+		Arch        string      `xml:"arch"`
+		Version     xmlVersion  `xml:"version"`
+		Checksum    xmlChecksum `xml:"checksum"`
+		Summary     string      `xml:"summary"`
+		Description string      `xml:"description"`
+		Packager    string      `xml:"packager"`
+		URL         string      `xml:"url"`
+		Time        struct {
+			File  int64 `xml:"file,attr"`
+			Build int64 `xml:"build,attr"`
+		} `xml:"time"`
+		Size struct {
+			Package   int64 `xml:"package,attr"`
+			Installed int64 `xml:"installed,attr"`
+			Archive   int64 `xml:"archive,attr"`
+		} `xml:"size"`
+		Location struct {
+			Href string `xml:"href,attr"`
+		} `xml:"location"`
+		Format struct {
+			License     string `xml:"rpm:license"`
+			Vendor      string `xml:"rpm:vendor"`
+			Group       string `xml:"rpm:group"`
+			BuildHost   string `xml:"rpm:buildhost"`
+			SourceRPM   string `xml:"rpm:sourcerpm"`
+			HeaderRange struct {
+				Start int64 `xml:"start,attr"`
+				End   int64 `xml:"end,attr"`
+			} `xml:"rpm:header-range"`
+			Provides  *xmlEntries `xml:"rpm:provides,omitempty"`
+			Requires  *xmlEntries `xml:"rpm:requires,omitempty"`
+			Conflicts *xmlEntries `xml:"rpm:conflicts,omitempty"`
+			Obsoletes *xmlEntries `xml:"rpm:obsoletes,omitempty"`
+			Files     []*xmlFile  `xml:"file"`
+		} `xml:"format"`
+	}
+
+	xmlFilelists struct {
+		XMLName  xml.Name       `xml:"filelists"`
+		NS       string         `xml:"xmlns,attr"`
+		Count    int            `xml:"packages,attr"`
+		Packages []*xmlFileList `xml:"package"`
+	}
+
+	xmlFileList struct {
+		PkgID   string     `xml:"pkgid,attr"`
+		Name    string     `xml:"name,attr"`
+		Arch    string     `xml:"arch,attr"`
+		Version xmlVersion `xml:"version"`
+		Files   []*xmlFile `xml:"file"`
+	}
+
+	xmlOther struct {
+		XMLName  xml.Name        `xml:"otherdata"`
+		NS       string          `xml:"xmlns,attr"`
+		Count    int             `xml:"packages,attr"`
+		Packages []*xmlOtherItem `xml:"package"`
+	}
+
+	xmlOtherItem struct {
+		PkgID     string          `xml:"pkgid,attr"`
+		Name      string          `xml:"name,attr"`
+		Arch      string          `xml:"arch,attr"`
+		Version   xmlVersion      `xml:"version"`
+		Changelog []*xmlChangelog `xml:"changelog"`
+	}
+
+	xmlChangelog struct {
+		Author string `xml:"author,attr"`
+		Date   int64  `xml:"date,attr"`
+		Text   string `xml:",chardata"`
+	}
+
+	xmlRepomd struct {
+		XMLName  xml.Name   `xml:"repomd"`
+		NS       string     `xml:"xmlns,attr"`
+		NSRPM    string     `xml:"xmlns:rpm,attr"`
+		Revision int64      `xml:"revision"`
+		Data     []*xmlData `xml:"data"`
+	}
+
+	xmlData struct {
+		Type         string      `xml:"type,attr"`
+		Checksum     xmlChecksum `xml:"checksum"`
+		OpenChecksum xmlChecksum `xml:"open-checksum"`
+		Location     struct {
+			Href string `xml:"href,attr"`
+		} `xml:"location"`
+		Timestamp int64 `xml:"timestamp"`
+		Size      int64 `xml:"size"`
+		OpenSize  int64 `xml:"open-size"`
+	}
+)
+
+// ReadRepoPackage reads an RPM package's headers and checksum. Location
+// is the package's path in the repository.
+func ReadRepoPackage(filename, location string) (*RepoPackage, error) {
+	pkg, err := ReadPackage(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tag := range []int{TagName, TagVersion, TagArch} {
+		if pkg.Header.String(tag) == "" {
+			return nil, fmt.Errorf("%s: required tag %d is missing", filename, tag)
+		}
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	st, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.New()
+	if _, err := io.Copy(sum, file); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	return &RepoPackage{
+		Package:  pkg,
+		Location: location,
+		ModTime:  st.ModTime(),
+		SHA256:   hex.EncodeToString(sum.Sum(nil)),
+		Size:     st.Size(),
+	}, nil
+}
+
+// Name returns the package's name
+func (pkg *RepoPackage) Name() string {
+	return pkg.Header.String(TagName)
+}
+
+// Arch returns the package's architecture, like "x86_64", or "noarch"
+func (pkg *RepoPackage) Arch() string {
+	if pkg.Header.String(TagSourceRPM) == "" {
+		return "src"
+	}
+
+	return pkg.Header.String(TagArch)
+}
+
+func (pkg *RepoPackage) version() xmlVersion {
+	epoch, _ := pkg.Header.Int(TagEpoch)
+
+	return xmlVersion{
+		Epoch: fmt.Sprint(epoch),
+		Ver:   pkg.Header.String(TagVersion),
+		Rel:   pkg.Header.String(TagRelease),
+	}
+}
+
+// files returns the package's files, with their types
+func (pkg *RepoPackage) files() []*xmlFile {
+	dirs := pkg.Header.Strings(TagDirNames)
+	indexes := pkg.Header.Ints(TagDirIndexes)
+	modes := pkg.Header.Ints(TagFileModes)
+	flags := pkg.Header.Ints(TagFileFlags)
+
+	files := []*xmlFile{}
+
+	for i, base := range pkg.Header.Strings(TagBaseNames) {
+		if i >= len(indexes) || indexes[i] >= int64(len(dirs)) {
+			break
+		}
+
+		file := &xmlFile{Path: dirs[indexes[i]] + base}
+
+		if i < len(modes) && modes[i]&fileModeType == fileModeDir {
+			file.Type = "dir"
+		} else if i < len(flags) && flags[i]&fileFlagGhost != 0 {
+			file.Type = "ghost"
+		}
+
+		files = append(files, file)
+	}
+
+	return files
+}
+
+// primaryFile tells whether a file is listed in primary metadata, like
+// createrepo does
+func primaryFile(name string) bool {
+	return strings.HasPrefix(name, "/etc/") ||
+		strings.Contains(name, "bin/") ||
+		name == "/usr/lib/sendmail"
+}
+
+// entries returns dependency entries of a dependency type
+func (pkg *RepoPackage) entries(nameTag, flagsTag, versionTag int) *xmlEntries {
+	names := pkg.Header.Strings(nameTag)
+	flags := pkg.Header.Ints(flagsTag)
+	versions := pkg.Header.Strings(versionTag)
+
+	entries := []*xmlEntry{}
+	seen := map[string]bool{}
+
+	for i, name := range names {
+		// rpmlib dependencies are satisfied by rpm itself
+		if strings.HasPrefix(name, "rpmlib(") {
+			continue
+		}
+
+		entry := &xmlEntry{Name: name}
+
+		var flag int64
+		if i < len(flags) {
+			flag = flags[i]
+		}
+
+		if i < len(versions) && versions[i] != "" {
+			entry.Flags = senseFlags(flag)
+			entry.Epoch, entry.Ver, entry.Rel = splitEVR(versions[i])
+		}
+
+		if nameTag == TagRequireName && flag&sensePre != 0 {
+			entry.Pre = "1"
+		}
+
+		key := fmt.Sprintf("%+v", *entry)
+		if seen[key] {
+			continue
+		}
+
+		seen[key] = true
+
+		entries = append(entries, entry)
+	}
+
+	if len(entries) == 0 {
+		return nil
+	}
+
+	return &xmlEntries{Entries: entries}
+}
+
+// senseFlags converts comparison flags into repodata format
+func senseFlags(flags int64) string {
+	switch flags & (senseLess | senseGreater | senseEqual) {
+	case senseLess:
+		return "LT"
+	case senseGreater:
+		return "GT"
+	case senseEqual:
+		return "EQ"
+	case senseLess | senseEqual:
+		return "LE"
+	case senseGreater | senseEqual:
+		return "GE"
+	}
+
+	return ""
+}
+
+// splitEVR splits an `[epoch:]version[-release]` string. Epoch defaults to
+// "0".
+func splitEVR(evr string) (string, string, string) {
+	epoch := "0"
+
+	if i := strings.Index(evr, ":"); i >= 0 {
+		epoch, evr = evr[:i], evr[i+1:]
+	}
+
+	var release string
+
+	if i := strings.LastIndex(evr, "-"); i >= 0 {
+		evr, release = evr[:i], evr[i+1:]
+	}
+
+	return epoch, evr, release
+}
+
+func (pkg *RepoPackage) primary() *xmlPrimaryItem {
+	item := &xmlPrimaryItem{
+		Type:        "rpm",
+		Name:        pkg.Name(),
+		Arch:        pkg.Arch(),
+		Version:     pkg.version(),
+		Checksum:    xmlChecksum{Type: "sha256", PkgID: "YES", Value: pkg.SHA256},
+		Summary:     pkg.Header.String(TagSummary),
+		Description: pkg.Header.String(TagDescription),
+		Packager:    pkg.Header.String(TagPackager),
+		URL:         pkg.Header.String(TagURL),
+	}
+
+	item.Time.File = pkg.ModTime.Unix()
+	item.Time.Build, _ = pkg.Header.Int(TagBuildTime)
+
+	item.Size.Package = pkg.Size
+	item.Size.Archive, _ = pkg.Signature.Int(SigTagPayloadSize)
+
+	var ok bool
+	if item.Size.Installed, ok = pkg.Header.Int(TagLongSize); !ok {
+		item.Size.Installed, _ = pkg.Header.Int(TagSize)
+	}
+
+	item.Location.Href = pkg.Location
+
+	format := &item.Format
+	format.License = pkg.Header.String(TagLicense)
+	format.Vendor = pkg.Header.String(TagVendor)
+	format.Group = pkg.Header.String(TagGroup)
+	format.BuildHost = pkg.Header.String(TagBuildHost)
+	format.SourceRPM = pkg.Header.String(TagSourceRPM)
+	format.HeaderRange.Start = pkg.HeaderStart
+	format.HeaderRange.End = pkg.HeaderEnd
+	format.Provides = pkg.entries(TagProvideName, TagProvideFlags, TagProvideVersion)
+	format.Requires = pkg.entries(TagRequireName, TagRequireFlags, TagRequireVersion)
+	format.Conflicts = pkg.entries(TagConflictName, TagConflictFlags, TagConflictVersion)
+	format.Obsoletes = pkg.entries(TagObsoleteName, TagObsoleteFlags, TagObsoleteVersion)
+
+	for _, file := range pkg.files() {
+		if primaryFile(file.Path) {
+			format.Files = append(format.Files, file)
+		}
+	}
+
+	return item
+}
+
+func (pkg *RepoPackage) other() *xmlOtherItem {
+	item := &xmlOtherItem{
+		PkgID:   pkg.SHA256,
+		Name:    pkg.Name(),
+		Arch:    pkg.Arch(),
+		Version: pkg.version(),
+	}
+
+	times := pkg.Header.Ints(TagChangelogTime)
+	names := pkg.Header.Strings(TagChangelogName)
+	texts := pkg.Header.Strings(TagChangelogText)
+
+	for i := range times {
+		if i >= len(names) || i >= len(texts) {
+			break
+		}
+
+		item.Changelog = append(item.Changelog, &xmlChangelog{Author: names[i], Date: times[i], Text: texts[i]})
+	}
+
+	return item
+}
+
+// Metadata renders repository metadata of packages, keyed by paths
+// relative to the repository root. Metadata file names are prefixed by
+// their checksums, like createrepo's unique metadata file names.
+func Metadata(pkgs []*RepoPackage, revision time.Time) (map[string][]byte, error) {
+	sorted := append([]*RepoPackage{}, pkgs...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Location < sorted[j].Location
+	})
+
+	primary := &xmlPrimary{NS: nsCommon, NSRPM: nsRPM, Count: len(sorted)}
+	filelists := &xmlFilelists{NS: nsFilelists, Count: len(sorted)}
+	other := &xmlOther{NS: nsOther, Count: len(sorted)}
+
+	for _, pkg := range sorted {
+		primary.Packages = append(primary.Packages, pkg.primary())
+		filelists.Packages = append(filelists.Packages, &xmlFileList{
+			PkgID:   pkg.SHA256,
+			Name:    pkg.Name(),
+			Arch:    pkg.Arch(),
+			Version: pkg.version(),
+			Files:   pkg.files(),
+		})
+		other.Packages = append(other.Packages, pkg.other())
+	}
+
+	repomd := &xmlRepomd{NS: nsRepo, NSRPM: nsRPM, Revision: revision.Unix()}
+	files := map[string][]byte{}
+
+	for _, item := range []struct {
+		name string
+		doc  interface{}
+	}{
+		{"primary", primary},
+		{"filelists", filelists},
+		{"other", other},
+	} {
+		content, err := marshalXML(item.doc)
+		if err != nil {
+			return nil, fmt.Errorf("rendering %s metadata: %w", item.name, err)
+		}
+
+		compressed := &bytes.Buffer{}
+		gz := gzip.NewWriter(compressed)
+
+		if _, err := gz.Write(content); err != nil {
+			return nil, err
+		}
+
+		if err := gz.Close(); err != nil {
+			return nil, err
+		}
+
+		sum := sha256.Sum256(compressed.Bytes())
+		openSum := sha256.Sum256(content)
+		location := path.Join("repodata", hex.EncodeToString(sum[:])+"-"+item.name+".xml.gz")
+
+		data := &xmlData{
+			Type:         item.name,
+			Checksum:     xmlChecksum{Type: "sha256", Value: hex.EncodeToString(sum[:])},
+			OpenChecksum: xmlChecksum{Type: "sha256", Value: hex.EncodeToString(openSum[:])},
+			Timestamp:    revision.Unix(),
+			Size:         int64(compressed.Len()),
+			OpenSize:     int64(len(content)),
+		}
+		data.Location.Href = location
+
+		repomd.Data = append(repomd.Data, data)
+		files[location] = compressed.Bytes()
+	}
+
+	content, err := marshalXML(repomd)
+	if err != nil {
+		return nil, fmt.Errorf("rendering repomd: %w", err)
+	}
+
+	files["repodata/repomd.xml"] = content
+
+	return files, nil
+}
+
+func marshalXML(doc interface{}) ([]byte, error) {
+	content, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), append(content, '\n')...), nil
+}
@@ -0,0 +1,274 @@
+// Package rpm reads headers of RPM packages, and renders createrepo
+// compatible YUM/DNF repository metadata.
+package rpm
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Header tags used for repository metadata
+const (
+	TagName            = 1000
+	TagVersion         = 1001
+	TagRelease         = 1002
+	TagEpoch           = 1003
+	TagSummary         = 1004
+	TagDescription     = 1005
+	TagBuildTime       = 1006
+	TagBuildHost       = 1007
+	TagSize            = 1009
+	TagVendor          = 1011
+	TagLicense         = 1014
+	TagPackager        = 1015
+	TagGroup           = 1016
+	TagURL             = 1020
+	TagArch            = 1022
+	TagFileModes       = 1030
+	TagFileFlags       = 1037
+	TagSourceRPM       = 1044
+	TagProvideName     = 1047
+	TagRequireFlags    = 1048
+	TagRequireName     = 1049
+	TagRequireVersion  = 1050
+	TagConflictFlags   = 1053
+	TagConflictName    = 1054
+	TagConflictVersion = 1055
+	TagChangelogTime   = 1080
+	TagChangelogName   = 1081
+	TagChangelogText   = 1082
+	TagObsoleteName    = 1090
+	TagProvideFlags    = 1112
+	TagProvideVersion  = 1113
+	TagObsoleteFlags   = 1114
+	TagObsoleteVersion = 1115
+	TagDirIndexes      = 1116
+	TagBaseNames       = 1117
+	TagDirNames        = 1118
+	TagLongSize        = 5009
+
+	// SigTagPayloadSize is the signature header tag of the payload's
+	// uncompressed size
+	SigTagPayloadSize = 1007
+)
+
+const (
+	leadSize = 96
+
+	typeInt8        = 2
+	typeInt16       = 3
+	typeInt32       = 4
+	typeInt64       = 5
+	typeString      = 6
+	typeStringArray = 8
+	typeI18NString  = 9
+)
+
+// nolint: gochecknoglobals
+var (
+	leadMagic   = []byte{0xed, 0xab, 0xee, 0xdb}
+	headerMagic = []byte{0x8e, 0xad, 0xe8, 0x01}
+)
+
+type (
+	// Header is a parsed RPM header structure
+	Header struct {
+		entries map[int]*entry
+	}
+
+	entry struct {
+		typ   uint32
+		count uint32
+		data  []byte
+	}
+
+	// Package is an RPM package's signature and main headers
+	Package struct {
+		// Signature is the signature header
+		Signature *Header
+		// Header is the main header
+		Header *Header
+		// HeaderStart is the byte offset of the main header in the file
+		HeaderStart int64
+		// HeaderEnd is the byte offset of the end of the main header
+		HeaderEnd int64
+	}
+)
+
+// ReadPackage reads the headers of an RPM package file
+func ReadPackage(filename string) (*Package, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+
+	lead := make([]byte, leadSize)
+	if _, err := io.ReadFull(reader, lead); err != nil || !bytes.Equal(lead[:4], leadMagic) {
+		return nil, fmt.Errorf("%s: not an RPM package", filename)
+	}
+
+	sig, sigSize, err := readHeader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("%s: reading signature header: %w", filename, err)
+	}
+
+	// signature header is padded to 8 bytes
+	padding := (8 - sigSize%8) % 8
+	if _, err := reader.Discard(int(padding)); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	header, size, err := readHeader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("%s: reading header: %w", filename, err)
+	}
+
+	start := leadSize + sigSize + padding
+
+	return &Package{
+		Signature:   sig,
+		Header:      header,
+		HeaderStart: start,
+		HeaderEnd:   start + size,
+	}, nil
+}
+
+// readHeader reads a header structure, returning its size too
+func readHeader(reader io.Reader) (*Header, int64, error) {
+	intro := make([]byte, 16)
+	if _, err := io.ReadFull(reader, intro); err != nil {
+		return nil, 0, err
+	}
+
+	if !bytes.Equal(intro[:4], headerMagic) {
+		return nil, 0, errors.New("invalid header magic")
+	}
+
+	nindex := binary.BigEndian.Uint32(intro[8:12])
+	hsize := binary.BigEndian.Uint32(intro[12:16])
+
+	index := make([]byte, 16*int64(nindex))
+	if _, err := io.ReadFull(reader, index); err != nil {
+		return nil, 0, err
+	}
+
+	store := make([]byte, hsize)
+	if _, err := io.ReadFull(reader, store); err != nil {
+		return nil, 0, err
+	}
+
+	header := &Header{entries: make(map[int]*entry, nindex)}
+
+	for i := uint32(0); i < nindex; i++ {
+		rec := index[16*i : 16*i+16]
+		offset := binary.BigEndian.Uint32(rec[8:12])
+
+		if offset > hsize {
+			return nil, 0, fmt.Errorf("invalid offset of tag %d", binary.BigEndian.Uint32(rec[0:4]))
+		}
+
+		header.entries[int(binary.BigEndian.Uint32(rec[0:4]))] = &entry{
+			typ:   binary.BigEndian.Uint32(rec[4:8]),
+			count: binary.BigEndian.Uint32(rec[12:16]),
+			data:  store[offset:],
+		}
+	}
+
+	return header, 16 + int64(len(index)) + int64(hsize), nil
+}
+
+// String returns a string tag's value, or the first value of string
+// arrays
+func (h *Header) String(tag int) string {
+	if values := h.Strings(tag); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+// Strings returns a string array tag's values
+func (h *Header) Strings(tag int) []string {
+	e, ok := h.entries[tag]
+	if !ok {
+		return nil
+	}
+
+	switch e.typ {
+	case typeString, typeStringArray, typeI18NString:
+	default:
+		return nil
+	}
+
+	count := e.count
+	if e.typ == typeString {
+		count = 1
+	}
+
+	values := make([]string, 0, count)
+	data := e.data
+
+	for i := uint32(0); i < count; i++ {
+		end := bytes.IndexByte(data, 0)
+		if end < 0 {
+			break
+		}
+
+		values = append(values, string(data[:end]))
+		data = data[end+1:]
+	}
+
+	return values
+}
+
+// Int returns an integer tag's value, or the first value of integer
+// arrays. ok is false if the tag is missing.
+func (h *Header) Int(tag int) (value int64, ok bool) {
+	if values := h.Ints(tag); len(values) > 0 {
+		return values[0], true
+	}
+
+	return 0, false
+}
+
+// Ints returns an integer array tag's values
+func (h *Header) Ints(tag int) []int64 {
+	e, ok := h.entries[tag]
+	if !ok {
+		return nil
+	}
+
+	sizes := map[uint32]uint32{typeInt8: 1, typeInt16: 2, typeInt32: 4, typeInt64: 8}
+
+	size, ok := sizes[e.typ]
+	if !ok || uint64(len(e.data)) < uint64(size)*uint64(e.count) {
+		return nil
+	}
+
+	values := make([]int64, e.count)
+
+	for i := range values {
+		item := e.data[uint32(i)*size:]
+
+		switch e.typ {
+		case typeInt8:
+			values[i] = int64(item[0])
+		case typeInt16:
+			values[i] = int64(binary.BigEndian.Uint16(item))
+		case typeInt32:
+			values[i] = int64(binary.BigEndian.Uint32(item))
+		case typeInt64:
+			values[i] = int64(binary.BigEndian.Uint64(item))
+		}
+	}
+
+	return values
+}
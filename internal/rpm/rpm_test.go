@@ -0,0 +1,107 @@
+package rpm_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julian7/goshipdone/internal/rpm"
+	"github.com/julian7/goshipdone/internal/rpm/rpmtest"
+)
+
+func TestMetadata(t *testing.T) {
+	location := filepath.Join(t.TempDir(), "hello-1.0.0-1.x86_64.rpm")
+	rpmtest.WritePackage(t, location, 1234, []rpmtest.Tag{
+		rpmtest.StringTag(rpm.TagName, "hello"),
+		rpmtest.StringTag(rpm.TagVersion, "1.0.0"),
+		rpmtest.StringTag(rpm.TagRelease, "1"),
+		rpmtest.StringTag(rpm.TagSummary, "Says hello"),
+		rpmtest.Int32Tag(rpm.TagBuildTime, 1700000000),
+		rpmtest.Int32Tag(rpm.TagSize, 4096),
+		rpmtest.StringTag(rpm.TagLicense, "MIT"),
+		rpmtest.StringTag(rpm.TagArch, "x86_64"),
+		rpmtest.StringTag(rpm.TagSourceRPM, "hello-1.0.0-1.src.rpm"),
+		rpmtest.StringTag(rpm.TagProvideName, "hello", "hello(x86-64)"),
+		rpmtest.Int32Tag(rpm.TagRequireFlags, 0, 1<<24, 8|1<<9),
+		rpmtest.StringTag(rpm.TagRequireName, "glibc", "rpmlib(CompressedFileNames)", "bash"),
+		rpmtest.StringTag(rpm.TagRequireVersion, "", "3.0.4-1", "5.0"),
+		rpmtest.Int32Tag(rpm.TagProvideFlags, 8, 8),
+		rpmtest.StringTag(rpm.TagProvideVersion, "1.0.0-1", "1.0.0-1"),
+		rpmtest.Int32Tag(rpm.TagDirIndexes, 0, 1),
+		rpmtest.StringTag(rpm.TagBaseNames, "hello", "README.md"),
+		rpmtest.StringTag(rpm.TagDirNames, "/usr/bin/", "/usr/share/doc/hello/"),
+	})
+
+	pkg, err := rpm.ReadRepoPackage(location, "Packages/hello-1.0.0-1.x86_64.rpm")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// lead, and signature header with one int32 tag, padded to 8 bytes
+	if pkg.HeaderStart != 136 || pkg.HeaderEnd <= pkg.HeaderStart {
+		t.Errorf("header range = %d-%d", pkg.HeaderStart, pkg.HeaderEnd)
+	}
+
+	files, err := rpm.Metadata([]*rpm.RepoPackage{pkg}, time.Unix(1700000001, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != 4 {
+		t.Fatalf("got %d metadata files, want 4", len(files))
+	}
+
+	repomd := string(files["repodata/repomd.xml"])
+	if !strings.Contains(repomd, "<revision>1700000001</revision>") {
+		t.Errorf("repomd.xml has no revision:\n%s", repomd)
+	}
+
+	var primary string
+
+	for name, content := range files {
+		if !strings.HasSuffix(name, "-primary.xml.gz") {
+			continue
+		}
+
+		if !strings.Contains(repomd, `href="`+name+`"`) {
+			t.Errorf("repomd.xml does not reference %s", name)
+		}
+
+		gz, err := gzip.NewReader(bytes.NewReader(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := io.ReadAll(gz)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		primary = string(data)
+	}
+
+	for _, want := range []string{
+		`<version epoch="0" ver="1.0.0" rel="1"></version>`,
+		`<size package="`,
+		`installed="4096" archive="1234"></size>`,
+		`<location href="Packages/hello-1.0.0-1.x86_64.rpm"></location>`,
+		`<rpm:entry name="hello(x86-64)" flags="EQ" epoch="0" ver="1.0.0" rel="1"></rpm:entry>`,
+		`<rpm:entry name="glibc"></rpm:entry>`,
+		`<rpm:entry name="bash" flags="EQ" epoch="0" ver="5.0" pre="1"></rpm:entry>`,
+		`<file>/usr/bin/hello</file>`,
+	} {
+		if !strings.Contains(primary, want) {
+			t.Errorf("primary.xml does not contain %q:\n%s", want, primary)
+		}
+	}
+
+	for _, unwanted := range []string{"rpmlib(", "README.md"} {
+		if strings.Contains(primary, unwanted) {
+			t.Errorf("primary.xml contains %q", unwanted)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// rpmtest provides minimal RPM packages for tests of RPM reading, and
+// repository modules. Packages contain a lead, a signature header, and a
+// main header, but no payload.
+package rpmtest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/julian7/goshipdone/internal/rpm"
+)
+
+const (
+	typeInt32       = 4
+	typeString      = 6
+	typeStringArray = 8
+)
+
+// Tag is an entry of a package header
+type Tag struct {
+	tag   int
+	typ   uint32
+	count int
+	data  []byte
+}
+
+// StringTag returns a string tag. Multiple values, and tags of dependency
+// lists are written as string arrays.
+func StringTag(tag int, values ...string) Tag {
+	data := []byte(strings.Join(values, "\x00") + "\x00")
+	typ := uint32(typeStringArray)
+
+	if len(values) == 1 && tag < rpm.TagProvideName {
+		typ = typeString
+	}
+
+	return Tag{tag: tag, typ: typ, count: len(values), data: data}
+}
+
+// Int32Tag returns an int32 array tag
+func Int32Tag(tag int, values ...int32) Tag {
+	data := &bytes.Buffer{}
+	_ = binary.Write(data, binary.BigEndian, values)
+
+	return Tag{tag: tag, typ: typeInt32, count: len(values), data: data.Bytes()}
+}
+
+// Package returns the tags of a package with name, version (release 1),
+// and arch
+func Package(name, version, arch string) []Tag {
+	return []Tag{
+		StringTag(rpm.TagName, name),
+		StringTag(rpm.TagVersion, version),
+		StringTag(rpm.TagRelease, "1"),
+		StringTag(rpm.TagArch, arch),
+		StringTag(rpm.TagSourceRPM, name+"-"+version+"-1.src.rpm"),
+	}
+}
+
+// WritePackage writes a package file with a signature header containing
+// payloadSize, and a main header of tags
+func WritePackage(t testing.TB, location string, payloadSize int32, tags []Tag) {
+	t.Helper()
+
+	out := &bytes.Buffer{}
+	lead := make([]byte, 96)
+	copy(lead, []byte{0xed, 0xab, 0xee, 0xdb})
+	out.Write(lead)
+
+	writeHeader(out, []Tag{Int32Tag(rpm.SigTagPayloadSize, payloadSize)})
+
+	for out.Len()%8 != 0 {
+		out.WriteByte(0)
+	}
+
+	writeHeader(out, tags)
+
+	if err := os.WriteFile(location, out.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeHeader(out *bytes.Buffer, tags []Tag) {
+	store := &bytes.Buffer{}
+	index := &bytes.Buffer{}
+
+	for _, tag := range tags {
+		if tag.typ == typeInt32 {
+			for store.Len()%4 != 0 {
+				store.WriteByte(0)
+			}
+		}
+
+		_ = binary.Write(index, binary.BigEndian, []uint32{uint32(tag.tag), tag.typ, uint32(store.Len()), uint32(tag.count)})
+		store.Write(tag.data)
+	}
+
+	out.Write([]byte{0x8e, 0xad, 0xe8, 0x01, 0, 0, 0, 0})
+	_ = binary.Write(out, binary.BigEndian, []uint32{uint32(len(tags)), uint32(store.Len())})
+	out.Write(index.Bytes())
+	out.Write(store.Bytes())
+}
@@ -0,0 +1,184 @@
+package modules
+
+import (
+	//nolint: gosec
+	"crypto/md5"
+	//nolint: gosec
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/julian7/goshipdone/ctx"
+	"golang.org/x/crypto/blake2b"
+	"gopkg.in/yaml.v3"
+	"lukechampine.com/blake3"
+)
+
+// DefaultDigest is the hash algorithm of digests recorded by modules
+// producing artifacts (see RecordDigest)
+const DefaultDigest = "sha256"
+
+type (
+	// HashFactory is a method, which yields a new hash.Hash
+	HashFactory func() hash.Hash
+
+	// HashAlgorithm is a YAML representation of a registered hash
+	// algorithm.
+	HashAlgorithm struct {
+		Algo    string
+		Factory HashFactory
+	}
+)
+
+// nolint: gochecknoglobals
+var (
+	hashRegistry = map[string]HashFactory{
+		"md5":         md5.New,
+		"sha1":        sha1.New,
+		"sha256":      sha256.New,
+		"sha512":      sha512.New,
+		"blake2b":     func() hash.Hash { h, _ := blake2b.New512(nil); return h },
+		"blake2b-256": func() hash.Hash { h, _ := blake2b.New256(nil); return h },
+		"blake3":      func() hash.Hash { return blake3.New(32, nil) },
+		"crc32c":      func() hash.Hash { return crc32.New(crc32.MakeTable(crc32.Castagnoli)) },
+	}
+	hashRegistryLock sync.RWMutex
+)
+
+// RegisterHashAlgorithm allows registering additional hash algorithms,
+// which then can be used by all modules calculating checksums. It
+// overrides earlier registrations with the same name.
+func RegisterHashAlgorithm(name string, factory HashFactory) {
+	hashRegistryLock.Lock()
+	defer hashRegistryLock.Unlock()
+
+	hashRegistry[name] = factory
+}
+
+// LookupHashAlgorithm returns a HashFactory by its name
+func LookupHashAlgorithm(name string) (HashFactory, bool) {
+	hashRegistryLock.RLock()
+	defer hashRegistryLock.RUnlock()
+
+	factory, ok := hashRegistry[name]
+
+	return factory, ok
+}
+
+// HashAlgorithms returns names of all registered hash algorithms
+func HashAlgorithms() []string {
+	hashRegistryLock.RLock()
+	defer hashRegistryLock.RUnlock()
+
+	names := make([]string, 0, len(hashRegistry))
+	for name := range hashRegistry {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// NewHashAlgorithm returns a registered hash algorithm by its name
+func NewHashAlgorithm(hasher string) (*HashAlgorithm, error) {
+	factory, ok := LookupHashAlgorithm(hasher)
+	if !ok {
+		return nil, fmt.Errorf("algorithm `%s` not registered", hasher)
+	}
+
+	return &HashAlgorithm{Algo: hasher, Factory: factory}, nil
+}
+
+func (algo HashAlgorithm) MarshalYAML() (interface{}, error) {
+	return algo.Algo, nil
+}
+
+func (algo *HashAlgorithm) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode {
+		return fmt.Errorf("algorithm is `%v`, not a scalar", node.Kind)
+	}
+
+	var hasherString string
+	if err := node.Decode(&hasherString); err != nil {
+		return fmt.Errorf("algorithm cannot be decoded: %w", err)
+	}
+
+	newAlgo, err := NewHashAlgorithm(hasherString)
+	if err != nil {
+		return err
+	}
+
+	*algo = *newAlgo
+
+	return nil
+}
+
+func (algo *HashAlgorithm) String() string {
+	return algo.Algo
+}
+
+// SumFile calculates the checksum of a file, returning it in
+// hexadecimal format
+func (algo *HashAlgorithm) SumFile(filename string) (string, error) {
+	hasher := algo.Factory()
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", fmt.Errorf("checksumming %s: %w", filename, err)
+	}
+
+	defer f.Close()
+
+	if _, err := io.Copy(hasher, f); err != nil {
+		return "", fmt.Errorf("reading %s for checksumming: %w", filename, err)
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// SumArtifact returns the checksum of an artifact's file in hexadecimal
+// format. It is taken from digests recorded in the artifact, or
+// calculated, and recorded for later modules. Recorded digests are
+// dropped, if the file has been changed since its registration (see
+// ctx.Artifact.Changed).
+func (algo *HashAlgorithm) SumArtifact(art *ctx.Artifact) (string, error) {
+	if art.Changed() {
+		art.ResetDigests()
+		_ = art.Stat()
+	}
+
+	if sum := art.Digest(algo.Algo); sum != "" {
+		return sum, nil
+	}
+
+	sum, err := algo.SumFile(art.Location)
+	if err != nil {
+		return "", err
+	}
+
+	art.SetDigest(algo.Algo, sum)
+
+	return sum, nil
+}
+
+// RecordDigest calculates, and records the DefaultDigest of an artifact's
+// file, so signing, checksum, and publish modules don't read it again
+func RecordDigest(art *ctx.Artifact) error {
+	algo, err := NewHashAlgorithm(DefaultDigest)
+	if err != nil {
+		return err
+	}
+
+	_, err = algo.SumArtifact(art)
+
+	return err
+}
@@ -0,0 +1,134 @@
+package modules_test
+
+import (
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+func TestHashAlgorithm_SumFile(t *testing.T) {
+	modules.RegisterHashAlgorithm("test-sha224", sha256.New224)
+
+	filename := filepath.Join(t.TempDir(), "abc")
+	if err := os.WriteFile(filename, []byte("abc"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		algo string
+		want string
+	}{
+		{"md5", "900150983cd24fb0d6963f7d28e17f72"},
+		{"sha256", "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{"crc32c", "364b3fb7"},
+		{"blake3", "6437b3ac38465133ffb63b75273a8db548c558465d79db03fd359c6cd5bd9d85"},
+		{"test-sha224", "23097d223405d8228642a477bda255b32aadbce4bda0b3f7e36c9da7"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.algo, func(t *testing.T) {
+			algo, err := modules.NewHashAlgorithm(tt.algo)
+			if err != nil {
+				t.Errorf("NewHashAlgorithm() error = %v", err)
+				return
+			}
+
+			got, err := algo.SumFile(filename)
+			if err != nil {
+				t.Errorf("HashAlgorithm.SumFile() error = %v", err)
+				return
+			}
+
+			if got != tt.want {
+				t.Errorf("HashAlgorithm.SumFile() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashAlgorithm_SumArtifact(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "abc")
+	if err := os.WriteFile(filename, []byte("abc"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	art := &ctx.Artifact{Filename: "abc", Location: filename}
+	want := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+
+	if err := modules.RecordDigest(art); err != nil {
+		t.Fatalf("RecordDigest() error = %v", err)
+	}
+
+	if got := art.Digest("sha256"); got != want {
+		t.Errorf("RecordDigest() recorded %s, want %s", got, want)
+	}
+
+	if err := os.Remove(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	algo, _ := modules.NewHashAlgorithm("sha256")
+
+	got, err := algo.SumArtifact(art)
+	if err != nil {
+		t.Fatalf("HashAlgorithm.SumArtifact() didn't use recorded digest: %v", err)
+	}
+
+	if got != want {
+		t.Errorf("HashAlgorithm.SumArtifact() = %s, want %s", got, want)
+	}
+
+	art.ResetDigests()
+
+	if _, err := algo.SumArtifact(art); err == nil {
+		t.Error("HashAlgorithm.SumArtifact() used digest after ResetDigests()")
+	}
+}
+
+func TestHashAlgorithm_SumArtifact_changed(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "abc")
+	if err := os.WriteFile(filename, []byte("abc"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	art := &ctx.Artifact{Filename: "abc", Location: filename}
+	if err := art.Stat(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := modules.RecordDigest(art); err != nil {
+		t.Fatalf("RecordDigest() error = %v", err)
+	}
+
+	if err := os.WriteFile(filename, []byte("abcd"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	algo, _ := modules.NewHashAlgorithm("sha256")
+
+	got, err := algo.SumArtifact(art)
+	if err != nil {
+		t.Fatalf("HashAlgorithm.SumArtifact() error = %v", err)
+	}
+
+	want := "88d4266fd4e6338d13b845fcf289579d209c897823b9217da3e161936f031589"
+	if got != want {
+		t.Errorf("HashAlgorithm.SumArtifact() = %s of a changed file, want %s", got, want)
+	}
+
+	if art.Size != 4 {
+		t.Errorf("HashAlgorithm.SumArtifact() recorded size %d, want 4", art.Size)
+	}
+}
+
+func TestNewHashAlgorithm_unknown(t *testing.T) {
+	if _, err := modules.NewHashAlgorithm("unknown"); err == nil {
+		t.Error("NewHashAlgorithm() expected error for unknown algorithm")
+	}
+}
@@ -0,0 +1,54 @@
+package modules
+
+import (
+	"fmt"
+
+	"github.com/julian7/goshipdone/archive"
+	"gopkg.in/yaml.v3"
+)
+
+// Compression is a YAML representation of a compression format. See
+// archive.RegisterCompression for registering new ones.
+type Compression struct {
+	*archive.Compression
+}
+
+// NewCompression returns a registered compression format by name
+func NewCompression(name string) (Compression, error) {
+	comp, ok := archive.LookupCompression(name)
+	if !ok {
+		return Compression{}, fmt.Errorf("invalid compression format: `%s`", name)
+	}
+
+	return Compression{comp}, nil
+}
+
+// MarshalYAML returns compression format's name
+func (c Compression) MarshalYAML() (interface{}, error) {
+	if c.Compression == nil {
+		return "", nil
+	}
+
+	return c.Name, nil
+}
+
+// UnmarshalYAML detects compression format
+func (c *Compression) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode {
+		return fmt.Errorf("compression is `%v`, not scalar", node.Kind)
+	}
+
+	var compressString string
+	if err := node.Decode(&compressString); err != nil {
+		return fmt.Errorf("compression cannot be decoded: %w", err)
+	}
+
+	comp, err := NewCompression(compressString)
+	if err != nil {
+		return err
+	}
+
+	*c = comp
+
+	return nil
+}
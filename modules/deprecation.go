@@ -0,0 +1,109 @@
+package modules
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// Deprecation is a renamed option of a module's configuration
+	Deprecation struct {
+		// Old is the deprecated YAML key
+		Old string
+		// New is the YAML key replacing Old
+		New string
+		// Since is the version deprecating Old, if known
+		Since string
+	}
+
+	// Deprecated is implemented by module configurations with renamed
+	// options. Deprecated options are renamed before decoding the
+	// configuration (see MigrateOptions), therefore the module only takes
+	// their new names.
+	Deprecated interface {
+		Deprecations() []Deprecation
+	}
+
+	// DeprecationWarning is a deprecated option found in a configuration
+	DeprecationWarning struct {
+		Module string `json:"module"`
+		Old    string `json:"old"`
+		New    string `json:"new"`
+		Since  string `json:"since,omitempty"`
+		Line   int    `json:"line"`
+		Column int    `json:"column"`
+	}
+)
+
+// MigrateOptions renames deprecated options of a module's configuration
+// node in place, returning a warning of each of them. Setting both an
+// option, and its deprecated name is an error.
+func MigrateOptions(module string, mod Pluggable, node *yaml.Node) ([]DeprecationWarning, error) {
+	deprecated, ok := Config(mod).(Deprecated)
+	if !ok || node == nil || node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	warnings := []DeprecationWarning{}
+
+	for _, deprecation := range deprecated.Deprecations() {
+		for idx := 0; idx+1 < len(node.Content); idx += 2 {
+			key := node.Content[idx]
+			if key.Value != deprecation.Old {
+				continue
+			}
+
+			if hasKey(node, deprecation.New) {
+				return nil, &SchemaError{
+					Column:  key.Column,
+					Line:    key.Line,
+					Message: fmt.Sprintf("both `%s`, and its deprecated name `%s` are set", deprecation.New, deprecation.Old),
+					Module:  module,
+				}
+			}
+
+			warnings = append(warnings, DeprecationWarning{
+				Module: module,
+				Old:    deprecation.Old,
+				New:    deprecation.New,
+				Since:  deprecation.Since,
+				Line:   key.Line,
+				Column: key.Column,
+			})
+
+			key.Value = deprecation.New
+		}
+	}
+
+	return warnings, nil
+}
+
+// hasKey returns true if a mapping node has a key
+func hasKey(node *yaml.Node, name string) bool {
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		if node.Content[idx].Value == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// String implements fmt.Stringer
+func (warning DeprecationWarning) String() string {
+	since := ""
+	if warning.Since != "" {
+		since = " since " + warning.Since
+	}
+
+	return fmt.Sprintf(
+		"`%s` is deprecated%s, use `%s` instead in %s at line %d, column %d",
+		warning.Old,
+		since,
+		warning.New,
+		warning.Module,
+		warning.Line,
+		warning.Column,
+	)
+}
@@ -0,0 +1,87 @@
+package modules_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/julian7/goshipdone/modules"
+	"gopkg.in/yaml.v3"
+)
+
+type testDeprecatedConfig struct {
+	SigningKey string `yaml:"signing_key"`
+}
+
+func (*testDeprecatedConfig) Run(context.Context) error {
+	return nil
+}
+
+func (*testDeprecatedConfig) Deprecations() []modules.Deprecation {
+	return []modules.Deprecation{{Old: "key", New: "signing_key", Since: "v1.2.0"}}
+}
+
+func TestMigrateOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		yml     string
+		want    []string
+		wantKey string
+		wantErr string
+	}{
+		{name: "current", yml: "signing_key: a\n", want: []string{}, wantKey: "a"},
+		{
+			name:    "deprecated",
+			yml:     "key: a\n",
+			want:    []string{"`key` is deprecated since v1.2.0, use `signing_key` instead in build:sign at line 1, column 1"},
+			wantKey: "a",
+		},
+		{
+			name:    "both",
+			yml:     "signing_key: a\nkey: b\n",
+			wantErr: "both `signing_key`, and its deprecated name `key` are set in build:sign at line 2, column 1",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			node := &yaml.Node{}
+			if err := yaml.Unmarshal([]byte(tt.yml), node); err != nil {
+				t.Fatal(err)
+			}
+
+			mod := &testDeprecatedConfig{}
+
+			warnings, err := modules.MigrateOptions("build:sign", mod, node.Content[0])
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("MigrateOptions() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("MigrateOptions() error = %v", err)
+			}
+
+			got := []string{}
+			for _, warning := range warnings {
+				got = append(got, warning.String())
+			}
+
+			if diff := deep.Equal(got, tt.want); diff != nil {
+				t.Errorf("MigrateOptions() %v", diff)
+			}
+
+			if err := node.Decode(mod); err != nil {
+				t.Fatal(err)
+			}
+
+			if mod.SigningKey != tt.wantKey {
+				t.Errorf("MigrateOptions() decoded signing_key %q, want %q", mod.SigningKey, tt.wantKey)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package modules_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+// Notarize is a custom build module
+type Notarize struct {
+	// Output is a template of the notarized file's name
+	Output string
+	// Compression of the notarized file
+	Compression modules.Compression
+}
+
+func NewNotarize() modules.PluggableV2 {
+	none, _ := modules.NewCompression("none")
+
+	return &Notarize{Output: "{{.ProjectName}}-{{.Version}}.notarized", Compression: none}
+}
+
+func (mod *Notarize) RunResult(cx context.Context) (*modules.Result, error) {
+	shipContext, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return nil, err
+	}
+
+	td, err := modules.NewTemplate(cx)
+	if err != nil {
+		return nil, err
+	}
+
+	filename, err := td.Parse("notarize", mod.Output+mod.Compression.Extension)
+	if err != nil {
+		return nil, err
+	}
+
+	// ... notarize the file ...
+
+	return &modules.Result{Artifacts: []*ctx.Artifact{{
+		ID:       "notarized",
+		Filename: filename,
+		Location: filepath.Join(shipContext.TargetDir, filename),
+		OsArch:   ctx.ParseOsArch("darwin", "arm64"),
+	}}}, nil
+}
+
+func ExampleRegisterModule() {
+	modules.RegisterModule(&modules.ModuleRegistration{
+		Stage:   "build",
+		Type:    "notarize",
+		Factory: modules.V2Factory(NewNotarize),
+	})
+
+	cx := ctx.New(context.Background())
+	shipContext, _ := ctx.GetShipContext(cx)
+	shipContext.ProjectName = "app"
+	shipContext.Version = "1.2.3"
+	shipContext.TargetDir = "dist"
+
+	factory, _ := modules.LookupModule("build:notarize")
+	result, _ := modules.AsV2(factory()).RunResult(cx)
+
+	for _, artifact := range result.Artifacts {
+		fmt.Println(artifact.Location, artifact.OsArch)
+	}
+	// Output: dist/app-1.2.3.notarized darwin-arm64
+}
@@ -1,12 +1,40 @@
 // modules provides an extendable interface for executable components in the
 // build pipeline.
+//
+// Downstream programs can register their own modules before running a
+// pipeline, without touching internal packages. A module implements
+// Pluggable, or PluggableV2 (wrapped by V2Factory), and it is registered
+// with RegisterModule for a stage (or "*" for every stage). Its YAML
+// configuration is decoded into the value its factory returns, therefore
+// factories set default values.
+//
+// Modules get the ship context (see ctx.GetShipContext), which holds the
+// project's settings, and registered artifacts. Helpers of this package
+// select artifacts (SelectArtifacts), render templates (NewTemplate, and
+// TemplateData), calculate checksums (NewHashAlgorithm), and decode
+// compression formats (Compression). Platforms of artifacts can be parsed
+// with ctx.ParseOsArch, and ctx.OsArchFromFilename.
 package modules
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+const (
+	// DefaultRetryBackoff is the default delay before the first retry of
+	// a failed module
+	DefaultRetryBackoff = time.Second
+	// OnErrorFail stops the pipeline at the module's failure
+	OnErrorFail = "fail"
+	// OnErrorContinue lets the pipeline go on after the module's failure,
+	// failing it at the end
+	OnErrorContinue = "continue"
 )
 
 type (
@@ -18,17 +46,94 @@ type (
 	// Module is a single module, specifying its type and its Pluggable
 	Module struct {
 		Type string
+		// ID is the module's `id` option, which other modules of the stage
+		// can refer to in Needs. Artifacts produced by the module are named
+		// after it, unless the module takes `id` as its own option.
+		ID string
+		// Meta annotates artifacts produced by the module (see
+		// ctx.Artifact.Meta)
+		Meta map[string]string
 		Pluggable
+		// Needs lists IDs, or types of modules in the same stage, which
+		// must finish before the module starts. Nil means the previous
+		// module, running modules strictly in order. Modules with
+		// dependencies finished run concurrently.
+		Needs []string
+		// OnError is OnErrorFail, or OnErrorContinue. Default: OnErrorFail.
+		OnError string
+		// Replacements override DefaultReplacements in templates of the
+		// module (see TemplateData.Alias)
+		Replacements map[string]string
+		// Result is the result of the module's last run
+		Result *Result
+		// Retries is the number of times a failed module is run again
+		Retries int
+		// RetryBackoff is the delay before the first retry, doubled for
+		// each further retry. Default: DefaultRetryBackoff.
+		RetryBackoff time.Duration
+		// Timeout is the maximum run time of the module (of each attempt,
+		// if retried). Its context is canceled after the timeout. Zero
+		// means no timeout.
+		Timeout time.Duration
+		// When is a TemplateData template; the module is skipped unless it
+		// renders to a truthy value. Empty runs the module.
+		When string
 	}
 )
 
+// Enabled evaluates the module's When expression
+func (mod *Module) Enabled(cx context.Context) (bool, error) {
+	if mod.When == "" {
+		return true, nil
+	}
+
+	td, err := NewTemplate(mod.runContext(cx))
+	if err != nil {
+		return false, err
+	}
+
+	enabled, err := td.ParseBool(mod.Type+":when", mod.When)
+	if err != nil {
+		return false, fmt.Errorf("%s: rendering when: %w", mod.Type, err)
+	}
+
+	return enabled, nil
+}
+
 // Run executes a module, and measures its wallclock time spent
-func (mod *Module) Run(ctx context.Context) error {
+func (mod *Module) Run(cx context.Context) error {
 	log.Printf("----> %s", mod.Type)
 
+	shipContext, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", mod.Type, err)
+	}
+
+	if shipContext.Verbose {
+		mod.logOptions(cx)
+	}
+
 	start := time.Now()
+	progress := new(ctx.Progress)
+	shipContext.Progress = progress
+	done := make(chan struct{})
+
+	go mod.heartbeat(progress, shipContext.Heartbeat, start, done)
+
+	mod.Result, err = mod.runAttempts(mod.runContext(cx), shipContext)
+
+	close(done)
+
+	for _, warning := range mod.Result.Warnings {
+		log.Printf("      warning: %s", warning)
+	}
+
+	// temp dirs are kept for debugging failures, but not interruptions
+	if cleanupErr := shipContext.ReleaseTempDirs(err != nil && cx.Err() == nil); cleanupErr != nil && err == nil {
+		err = cleanupErr
+	}
 
-	if err := mod.Pluggable.Run(ctx); err != nil {
+	if err != nil {
 		return fmt.Errorf("%s: %w", mod.Type, err)
 	}
 
@@ -36,3 +141,102 @@ func (mod *Module) Run(ctx context.Context) error {
 
 	return nil
 }
+
+// runContext returns the context of running the module, carrying its
+// replacements for templates
+func (mod *Module) runContext(cx context.Context) context.Context {
+	if len(mod.Replacements) == 0 {
+		return cx
+	}
+
+	return context.WithValue(cx, replacementsKey{}, mod.Replacements)
+}
+
+// runAttempts runs the module, running it again after failures at most
+// Retries times, with exponential backoff. Artifacts registered, and
+// temporary directories created by a failed attempt are discarded before
+// the next one.
+func (mod *Module) runAttempts(cx context.Context, shipContext *ctx.Context) (*Result, error) {
+	backoff := mod.RetryBackoff
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
+
+	for attempt := 1; ; attempt++ {
+		artifacts := len(shipContext.Artifacts)
+
+		result, err := mod.runAttempt(cx)
+		if err == nil || cx.Err() != nil {
+			return result, err
+		}
+
+		if attempt > mod.Retries {
+			if mod.Retries > 0 {
+				err = fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+			}
+
+			return result, err
+		}
+
+		log.Printf("      attempt %d of %d failed: %v; retrying in %s", attempt, mod.Retries+1, err, backoff)
+
+		shipContext.Artifacts = shipContext.Artifacts[:artifacts]
+
+		if cleanupErr := shipContext.ReleaseTempDirs(false); cleanupErr != nil {
+			return result, cleanupErr
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-cx.Done():
+			return result, err
+		}
+
+		backoff *= 2
+	}
+}
+
+// runAttempt runs the module once, canceling it after Timeout
+func (mod *Module) runAttempt(cx context.Context) (*Result, error) {
+	runCx := cx
+
+	if mod.Timeout > 0 {
+		var cancel func()
+
+		runCx, cancel = context.WithTimeout(cx, mod.Timeout)
+		defer cancel()
+	}
+
+	result, err := mod.runResult(runCx)
+
+	if err != nil && cx.Err() == nil && errors.Is(runCx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s: %w", mod.Timeout, err)
+	}
+
+	return result, err
+}
+
+// heartbeat reports progress periodically, until done is closed
+func (mod *Module) heartbeat(progress *ctx.Progress, interval time.Duration, start time.Time, done <-chan struct{}) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			report := progress.String()
+			if report != "" {
+				report = ": " + report
+			}
+
+			log.Printf("      %s still running (%s elapsed)%s", mod.Type, time.Since(start).Round(time.Second), report)
+		}
+	}
+}
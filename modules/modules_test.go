@@ -0,0 +1,167 @@
+package modules_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+type testHangingModule struct{}
+
+func (*testHangingModule) Run(cx context.Context) error {
+	<-cx.Done()
+
+	return cx.Err()
+}
+
+func TestModule_Run_timeout(t *testing.T) {
+	mod := &modules.Module{Type: "hang", Pluggable: &testHangingModule{}, Timeout: 10 * time.Millisecond}
+
+	err := mod.Run(ctx.New(context.Background()))
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "timed out after 10ms") {
+		t.Errorf("Run() error = %v, want timeout", err)
+	}
+}
+
+type testFlakyModule struct {
+	attempts int
+	failures int
+}
+
+func (mod *testFlakyModule) Run(context.Context) error {
+	mod.attempts++
+	if mod.attempts <= mod.failures {
+		return errors.New("connection reset")
+	}
+
+	return nil
+}
+
+func TestModule_Run_retries(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		retries      int
+		wantAttempts int
+		wantErr      string
+	}{
+		{name: "recovers", failures: 2, retries: 2, wantAttempts: 3},
+		{name: "gives up", failures: 3, retries: 2, wantAttempts: 3, wantErr: "giving up after 3 attempts: connection reset"},
+		{name: "no retries", failures: 1, retries: 0, wantAttempts: 1, wantErr: "flaky: connection reset"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			pluggable := &testFlakyModule{failures: tt.failures}
+			mod := &modules.Module{Type: "flaky", Pluggable: pluggable, Retries: tt.retries, RetryBackoff: time.Millisecond}
+
+			err := mod.Run(ctx.New(context.Background()))
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.HasSuffix(err.Error(), tt.wantErr)) {
+				t.Errorf("Run() error = %v, want %q", err, tt.wantErr)
+			}
+
+			if pluggable.attempts != tt.wantAttempts {
+				t.Errorf("Run() attempts = %d, want %d", pluggable.attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+type testPartialModule struct {
+	attempts int
+	tempDirs []string
+}
+
+func (mod *testPartialModule) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	mod.attempts++
+
+	dir, err := context.TempDir("partial")
+	if err != nil {
+		return err
+	}
+
+	mod.tempDirs = append(mod.tempDirs, dir)
+	context.Artifacts.Add(&ctx.Artifact{ID: "partial", Filename: fmt.Sprintf("attempt-%d", mod.attempts)})
+
+	if mod.attempts == 1 {
+		return errors.New("connection reset")
+	}
+
+	return nil
+}
+
+func TestModule_Run_retryDiscardsAttempt(t *testing.T) {
+	cx := ctx.New(context.Background())
+
+	shipContext, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shipContext.TargetDir = t.TempDir()
+	shipContext.Artifacts.Add(&ctx.Artifact{ID: "earlier", Filename: "earlier"})
+
+	pluggable := &testPartialModule{}
+	mod := &modules.Module{Type: "partial", Pluggable: pluggable, Retries: 1, RetryBackoff: time.Millisecond}
+
+	if err := mod.Run(cx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if got, want := shipContext.Artifacts.Filenames(), []string{"earlier", "attempt-2"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Run() registered artifacts %v, want %v", got, want)
+	}
+
+	for _, dir := range pluggable.tempDirs {
+		if _, err := os.Stat(dir); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Run() kept temp directory %s: %v", dir, err)
+		}
+	}
+}
+
+type testAliasModule struct {
+	got string
+}
+
+func (mod *testAliasModule) Run(cx context.Context) error {
+	td, err := modules.NewTemplate(cx)
+	if err != nil {
+		return err
+	}
+
+	td.OSArch = &ctx.OsArch{OS: "darwin", Arch: "amd64"}
+	mod.got, err = td.Parse("output", "{{ OSAlias }}-{{ ArchAlias }}-{{ Alias \"linux\" }}")
+
+	return err
+}
+
+func TestModule_Run_replacements(t *testing.T) {
+	pluggable := &testAliasModule{}
+	mod := &modules.Module{
+		Type:         "alias",
+		Pluggable:    pluggable,
+		Replacements: map[string]string{"darwin": "Darwin", "linux": "Linux"},
+	}
+
+	if err := mod.Run(ctx.New(context.Background())); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if want := "Darwin-x86_64-Linux"; pluggable.got != want {
+		t.Errorf("Run() rendered %q, want %q", pluggable.got, want)
+	}
+}
@@ -0,0 +1,286 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "[redacted]"
+
+// nolint: gochecknoglobals
+var (
+	reSecretKey    = regexp.MustCompile(`(?i)(^|_)(api_key|auth|authorization|cookie|credentials|passphrase|password|private_key|secret|token)$`)
+	reHeadersKey   = regexp.MustCompile(`(?i)(^|_)headers?$`)
+	reSecretHeader = regexp.MustCompile(`(?i)(auth|cookie|key|password|secret|session|signature|token)`)
+	reSecretParam  = regexp.MustCompile(`(?i)(auth|key|password|secret|sig|token)`)
+)
+
+// Options returns the module's configuration in YAML format, as it is
+// going to be executed (that is, defaults and configured values are both
+// applied). Values of secret-looking keys (eg. "password", "token"), of
+// secret-looking headers (eg. "Authorization"), and credentials of URLs
+// are redacted.
+func (mod *Module) Options() (string, error) {
+	node, err := mod.OptionsNode()
+	if err != nil {
+		return "", err
+	}
+
+	return marshalOptions(node)
+}
+
+// ResolvedOptions returns the module's redacted configuration like Options,
+// with templates rendered, and environment variables expanded with the
+// run's template data. Original values of resolved options are kept as
+// comments. Values depending on the artifact being processed (like
+// "{{.Filename}}.sig"), values failing to render, and values of fields
+// rendered with other data (see TemplateValidator) are not resolved.
+func (mod *Module) ResolvedOptions(cx context.Context) (string, error) {
+	node, err := mod.encodeOptions()
+	if err != nil {
+		return "", err
+	}
+
+	td, err := NewTemplate(mod.runContext(cx))
+	if err != nil {
+		return "", err
+	}
+
+	sample, err := SampleTemplate(mod.runContext(cx))
+	if err != nil {
+		return "", err
+	}
+
+	if skips, err := templateSkips(cx, Config(mod.Pluggable)); err == nil && node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if !skips[node.Content[i].Value] {
+				resolveOptions(td, sample, node.Content[i+1])
+			}
+		}
+	}
+
+	redactSecrets(node)
+
+	return marshalOptions(node)
+}
+
+// OptionsNode returns the module's redacted configuration as a YAML node
+// (see Options).
+func (mod *Module) OptionsNode() (*yaml.Node, error) {
+	node, err := mod.encodeOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	redactSecrets(node)
+
+	return node, nil
+}
+
+// encodeOptions returns the module's configuration as a YAML node
+func (mod *Module) encodeOptions() (node *yaml.Node, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("cannot marshal options: %v", r)
+		}
+	}()
+
+	node = &yaml.Node{}
+
+	if err := node.Encode(mod.Pluggable); err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+func marshalOptions(node *yaml.Node) (string, error) {
+	data, err := yaml.Marshal(node)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+func (mod *Module) logOptions(cx context.Context) {
+	options, err := mod.ResolvedOptions(cx)
+	if err != nil {
+		log.Printf("      cannot report options: %v", err)
+		return
+	}
+
+	for _, line := range strings.Split(strings.TrimRight(options, "\n"), "\n") {
+		log.Printf("      %s", line)
+	}
+}
+
+// templateSkips returns YAML keys of fields a TemplateValidator renders
+// with other data
+func templateSkips(cx context.Context, config interface{}) (map[string]bool, error) {
+	skips := map[string]bool{}
+
+	validator, ok := config.(TemplateValidator)
+	if !ok {
+		return skips, nil
+	}
+
+	fields, err := validator.ValidateTemplates(cx)
+	if err != nil {
+		return nil, err
+	}
+
+	typ := reflect.TypeOf(config)
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if typ.Kind() != reflect.Struct {
+		return skips, nil
+	}
+
+	for _, name := range fields {
+		if field, ok := typ.FieldByName(name); ok {
+			key, _ := yamlKey(field)
+			skips[key] = true
+		}
+	}
+
+	return skips, nil
+}
+
+// resolveOptions renders templates, and expands environment variables in
+// string values of a node with td. Values rendering differently with
+// sample artifact data depend on the artifact being processed, and they
+// are kept.
+func resolveOptions(td, sample *TemplateData, node *yaml.Node) {
+	if node.Kind != yaml.ScalarNode {
+		for _, child := range node.Content {
+			resolveOptions(td, sample, child)
+		}
+
+		return
+	}
+
+	if node.Tag != "!!str" || !strings.ContainsAny(node.Value, "{$") {
+		return
+	}
+
+	out, err := td.Parse("option", node.Value)
+	if err != nil || out == node.Value {
+		return
+	}
+
+	if sampled, err := sample.Parse("option", node.Value); err != nil || sampled != out {
+		return
+	}
+
+	original := node.Value
+	node.Style = 0
+	node.SetString(out)
+	node.LineComment = original
+}
+
+func redactSecrets(node *yaml.Node) {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			redactSecrets(child)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, val := node.Content[i], node.Content[i+1]
+
+			switch {
+			case val.Kind == yaml.ScalarNode && val.Value != "" && reSecretKey.MatchString(key.Value):
+				redact(val, redacted)
+			case val.Kind == yaml.MappingNode && reHeadersKey.MatchString(key.Value):
+				redactHeaders(val)
+			default:
+				redactSecrets(val)
+			}
+		}
+	case yaml.ScalarNode:
+		if value, ok := redactURL(node.Value); ok {
+			redact(node, value)
+		}
+	}
+}
+
+// redactHeaders redacts values of secret-looking headers (eg.
+// "Authorization", or "X-Api-Key")
+func redactHeaders(node *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		name, val := node.Content[i], node.Content[i+1]
+
+		if val.Kind == yaml.ScalarNode && val.Value != "" && reSecretHeader.MatchString(name.Value) {
+			redact(val, redacted)
+
+			continue
+		}
+
+		redactSecrets(val)
+	}
+}
+
+// redactURL redacts user info, and values of secret-looking query
+// parameters of URLs. It returns false, if value is not a URL, or it
+// contains no secrets.
+func redactURL(value string) (string, bool) {
+	if !strings.Contains(value, "://") {
+		return value, false
+	}
+
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		return value, false
+	}
+
+	user := u.User != nil
+	changed := user
+	u.User = nil
+
+	params := strings.Split(u.RawQuery, "&")
+	for i, param := range params {
+		if key := strings.SplitN(param, "=", 2)[0]; key != param && reSecretParam.MatchString(key) {
+			params[i] = key + "=" + redacted
+			changed = true
+		}
+	}
+
+	if !changed {
+		return value, false
+	}
+
+	u.RawQuery = ""
+	out := u.String()
+
+	if user {
+		out = strings.Replace(out, "://", "://"+redacted+"@", 1)
+	}
+
+	if len(params) > 0 && params[0] != "" {
+		fragment := ""
+		if idx := strings.Index(out, "#"); idx >= 0 {
+			out, fragment = out[:idx], out[idx:]
+		}
+
+		out += "?" + strings.Join(params, "&") + fragment
+	}
+
+	return out, true
+}
+
+// redact replaces a scalar node's value, dropping its comment, which may
+// contain the original value
+func redact(node *yaml.Node, value string) {
+	node.SetString(value)
+	node.LineComment = ""
+}
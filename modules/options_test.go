@@ -0,0 +1,100 @@
+package modules_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+type testOptionsModule struct {
+	Name     string
+	Token    string
+	TokenEnv string `yaml:"token_env"`
+	Auth     struct {
+		Password string
+	}
+}
+
+func (*testOptionsModule) Run(context.Context) error {
+	return nil
+}
+
+func TestModule_Options(t *testing.T) {
+	pluggable := &testOptionsModule{Name: "name", Token: "s3cr3t", TokenEnv: "TOKEN"}
+	pluggable.Auth.Password = "pa55"
+
+	mod := &modules.Module{Type: "test", Pluggable: pluggable}
+
+	got, err := mod.Options()
+	if err != nil {
+		t.Errorf("Module.Options() error = %v", err)
+		return
+	}
+
+	want := "name: name\ntoken: '[redacted]'\ntoken_env: TOKEN\nauth:\n    password: '[redacted]'\n"
+	if got != want {
+		t.Errorf("Module.Options() = %q, want %q", got, want)
+	}
+}
+
+type testResolvedOptionsModule struct {
+	Auth     string
+	Endpoint string
+	Headers  map[string]string
+	Output   string
+	Prefix   string
+	URL      string
+}
+
+func (*testResolvedOptionsModule) Run(context.Context) error {
+	return nil
+}
+
+func TestModule_ResolvedOptions(t *testing.T) {
+	cx := ctx.New(context.Background())
+
+	shipContext, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shipContext.ProjectName = "app"
+	shipContext.Version = "v1.2.3"
+	shipContext.Env.Set("HOST", "example.com")
+	shipContext.Env.Set("TOKEN", "s3cr3t")
+
+	mod := &modules.Module{Type: "test", Pluggable: &testResolvedOptionsModule{
+		Auth:     "s3cr3t",
+		Endpoint: "https://bot:{{ env \"TOKEN\" }}@$HOST/api?sig=s3cr3t&page=1",
+		Headers:  map[string]string{"Accept": "text/plain", "Authorization": "Bearer {{ env \"TOKEN\" }}", "X-Api-Key": "s3cr3t"},
+		Output:   "{{.Filename}}.sig",
+		Prefix:   "{{.ProjectName}}/{{.Version}}/",
+		URL:      "https://$HOST/upload",
+	}}
+
+	got, err := mod.ResolvedOptions(cx)
+	if err != nil {
+		t.Fatalf("Module.ResolvedOptions() error = %v", err)
+	}
+
+	want := `auth: '[redacted]'
+endpoint: https://[redacted]@example.com/api?sig=[redacted]&page=1
+headers:
+    Accept: text/plain
+    Authorization: '[redacted]'
+    X-Api-Key: '[redacted]'
+output: '{{.Filename}}.sig'
+prefix: app/v1.2.3/ # {{.ProjectName}}/{{.Version}}/
+url: https://example.com/upload # https://$HOST/upload
+`
+	if got != want {
+		t.Errorf("Module.ResolvedOptions() = %s, want %s", got, want)
+	}
+
+	if strings.Contains(got, "s3cr3t") {
+		t.Errorf("Module.ResolvedOptions() contains a secret: %s", got)
+	}
+}
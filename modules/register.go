@@ -1,6 +1,9 @@
 package modules
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+)
 
 // nolint: gochecknoglobals
 var modRegistry map[string]PluggableFactory
@@ -48,3 +51,16 @@ func LookupModule(kind string) (PluggableFactory, bool) {
 
 	return nil, false
 }
+
+// ModuleKinds returns kinds of all registered modules (see
+// ModuleRegistration.Kind), sorted
+func ModuleKinds() []string {
+	kinds := make([]string, 0, len(modRegistry))
+	for kind := range modRegistry {
+		kinds = append(kinds, kind)
+	}
+
+	sort.Strings(kinds)
+
+	return kinds
+}
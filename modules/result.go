@@ -0,0 +1,167 @@
+package modules
+
+import (
+	"context"
+	"log"
+
+	"github.com/julian7/goshipdone/ctx"
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// Result is the structured outcome of a module's run
+	Result struct {
+		// Artifacts lists artifacts produced by the module. Artifacts
+		// returned by PluggableV2 modules are registered in ctx.Context by
+		// the pipeline, if the module succeeded.
+		Artifacts []*ctx.Artifact
+		// Warnings lists non-fatal problems, which are logged, and
+		// reported.
+		Warnings []string
+		// Metrics contains arbitrary numeric measurements (eg. sizes,
+		// counts), which are reported.
+		Metrics map[string]float64
+	}
+
+	// PluggableV2 is a module returning a structured Result. Register it
+	// with a factory created by V2Factory.
+	PluggableV2 interface {
+		RunResult(context.Context) (*Result, error)
+	}
+
+	// v2Adapter makes a PluggableV2 usable as a Pluggable
+	v2Adapter struct {
+		PluggableV2
+	}
+
+	// legacyAdapter makes a Pluggable usable as a PluggableV2, collecting
+	// artifacts registered during its run
+	legacyAdapter struct {
+		Pluggable
+	}
+)
+
+// V2Factory wraps a factory of PluggableV2 modules into a PluggableFactory,
+// for module registration.
+func V2Factory(factory func() PluggableV2) PluggableFactory {
+	return func() Pluggable {
+		return &v2Adapter{PluggableV2: factory()}
+	}
+}
+
+// Run implements Pluggable, dropping the result. Produced artifacts are
+// still registered.
+func (adapter *v2Adapter) Run(cx context.Context) error {
+	_, err := (&Module{Pluggable: adapter}).runResult(cx)
+
+	return err
+}
+
+// UnmarshalYAML decodes configuration into the wrapped module
+func (adapter *v2Adapter) UnmarshalYAML(node *yaml.Node) error {
+	return node.Decode(adapter.PluggableV2)
+}
+
+// MarshalYAML encodes the wrapped module's configuration
+func (adapter *v2Adapter) MarshalYAML() (interface{}, error) {
+	return adapter.PluggableV2, nil
+}
+
+// RunResult implements PluggableV2
+func (adapter *legacyAdapter) RunResult(cx context.Context) (*Result, error) {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return nil, err
+	}
+
+	before := len(context.Artifacts)
+	err = adapter.Pluggable.Run(cx)
+	result := &Result{}
+
+	if len(context.Artifacts) > before {
+		result.Artifacts = append(result.Artifacts, context.Artifacts[before:]...)
+	}
+
+	return result, err
+}
+
+// AsV2 returns a Pluggable as a PluggableV2. Legacy modules are adapted.
+func AsV2(mod Pluggable) PluggableV2 {
+	switch m := mod.(type) {
+	case *v2Adapter:
+		return m.PluggableV2
+	case PluggableV2:
+		return m
+	default:
+		return &legacyAdapter{Pluggable: mod}
+	}
+}
+
+// Config returns the value a module's configuration is decoded into.
+// PluggableV2 modules registered with V2Factory are unwrapped.
+func Config(mod Pluggable) interface{} {
+	if adapter, ok := mod.(*v2Adapter); ok {
+		return adapter.PluggableV2
+	}
+
+	return mod
+}
+
+// runResult runs the module as a PluggableV2, and registers artifacts
+// returned by native PluggableV2 modules, unless they failed. Partial
+// artifacts of failed modules are not left behind for later modules.
+func (mod *Module) runResult(cx context.Context) (*Result, error) {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return &Result{}, err
+	}
+
+	v2 := AsV2(mod.Pluggable)
+
+	result, err := v2.RunResult(cx)
+	if result == nil {
+		result = &Result{}
+	}
+
+	mod.nameArtifacts(result.Artifacts)
+	mod.annotateArtifacts(result.Artifacts)
+
+	if _, legacy := v2.(*legacyAdapter); !legacy && err == nil {
+		for _, art := range result.Artifacts {
+			context.Artifacts.Add(art)
+		}
+	}
+
+	return result, err
+}
+
+// nameArtifacts names artifacts produced by the module after its ID, if
+// set, unless the module takes `id` as its own option (naming its
+// artifacts itself). Later modules can refer to the module's artifacts by
+// its ID.
+func (mod *Module) nameArtifacts(arts []*ctx.Artifact) {
+	if mod.ID == "" {
+		return
+	}
+
+	if _, ok := SchemaOf(Config(mod.Pluggable)).Properties["id"]; ok {
+		return
+	}
+
+	for _, art := range arts {
+		if art.ID != mod.ID {
+			log.Printf("      naming artifact %s as %s", art.Filename, mod.ID)
+
+			art.ID = mod.ID
+		}
+	}
+}
+
+// annotateArtifacts sets the module's Meta on artifacts produced by it
+func (mod *Module) annotateArtifacts(arts []*ctx.Artifact) {
+	for _, art := range arts {
+		for key, value := range mod.Meta {
+			art.SetMeta(key, value)
+		}
+	}
+}
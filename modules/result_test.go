@@ -0,0 +1,207 @@
+package modules_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+	"gopkg.in/yaml.v3"
+)
+
+type testV2Module struct {
+	Name string
+}
+
+func (mod *testV2Module) RunResult(context.Context) (*modules.Result, error) {
+	return &modules.Result{
+		Artifacts: []*ctx.Artifact{{Filename: mod.Name, ID: "v2", OsArch: &ctx.OsArch{}}},
+		Warnings:  []string{"careful"},
+		Metrics:   map[string]float64{"count": 1},
+	}, nil
+}
+
+type testFailingV2Module struct{}
+
+func (*testFailingV2Module) RunResult(context.Context) (*modules.Result, error) {
+	return &modules.Result{
+		Artifacts: []*ctx.Artifact{{Filename: "partial", ID: "v2", OsArch: &ctx.OsArch{}}},
+	}, errors.New("failed")
+}
+
+type testLegacyModule struct{}
+
+func (*testLegacyModule) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	context.Artifacts.Add(&ctx.Artifact{Filename: "legacy", ID: "legacy", OsArch: &ctx.OsArch{}})
+
+	return nil
+}
+
+// testNamedModule names its artifacts with its own `id` option
+type testNamedModule struct {
+	ID string
+}
+
+func (mod *testNamedModule) RunResult(context.Context) (*modules.Result, error) {
+	return &modules.Result{
+		Artifacts: []*ctx.Artifact{{Filename: "named", ID: mod.ID, OsArch: &ctx.OsArch{}}},
+	}, nil
+}
+
+func TestModule_RunResult(t *testing.T) {
+	pluggable := modules.V2Factory(func() modules.PluggableV2 { return &testV2Module{} })()
+
+	if err := yaml.Unmarshal([]byte("name: from-config\n"), pluggable); err != nil {
+		t.Fatalf("decoding v2 module: %v", err)
+	}
+
+	cx := ctx.New(context.Background())
+
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	context.Heartbeat = 0
+
+	v2 := &modules.Module{Type: "v2", Pluggable: pluggable}
+	if err := v2.Run(cx); err != nil {
+		t.Fatalf("running v2 module: %v", err)
+	}
+
+	legacy := &modules.Module{Type: "legacy", Pluggable: &testLegacyModule{}}
+	if err := legacy.Run(cx); err != nil {
+		t.Fatalf("running legacy module: %v", err)
+	}
+
+	if diff := deep.Equal(v2.Result.Warnings, []string{"careful"}); diff != nil {
+		t.Errorf("v2 warnings %v", diff)
+	}
+
+	if diff := deep.Equal(v2.Result.Metrics, map[string]float64{"count": 1}); diff != nil {
+		t.Errorf("v2 metrics %v", diff)
+	}
+
+	got := []string{}
+	for _, art := range context.Artifacts {
+		got = append(got, art.Filename)
+	}
+
+	if diff := deep.Equal(got, []string{"from-config", "legacy"}); diff != nil {
+		t.Errorf("registered artifacts %v", diff)
+	}
+
+	if len(legacy.Result.Artifacts) != 1 || legacy.Result.Artifacts[0].Filename != "legacy" {
+		t.Errorf("legacy result artifacts = %v, want [legacy]", legacy.Result.Artifacts)
+	}
+}
+
+func TestModule_RunResult_failed(t *testing.T) {
+	cx := ctx.New(context.Background())
+
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	context.Heartbeat = 0
+
+	pluggable := modules.V2Factory(func() modules.PluggableV2 { return &testFailingV2Module{} })()
+
+	mod := &modules.Module{Type: "failing", Pluggable: pluggable}
+	if err := mod.Run(cx); err == nil {
+		t.Fatal("running failing v2 module succeeded")
+	}
+
+	if len(context.Artifacts) != 0 {
+		t.Errorf("failing v2 module registered artifacts %v", context.Artifacts)
+	}
+}
+
+func TestModule_RunResult_id(t *testing.T) {
+	tests := []struct {
+		name      string
+		id        string
+		pluggable modules.Pluggable
+		want      []string
+	}{
+		{
+			name:      "v2 without id",
+			pluggable: modules.V2Factory(func() modules.PluggableV2 { return &testV2Module{} })(),
+			want:      []string{"v2"},
+		},
+		{
+			name:      "v2 with id",
+			id:        "linux-tarball",
+			pluggable: modules.V2Factory(func() modules.PluggableV2 { return &testV2Module{} })(),
+			want:      []string{"linux-tarball"},
+		},
+		{
+			name:      "legacy with id",
+			id:        "signatures",
+			pluggable: &testLegacyModule{},
+			want:      []string{"signatures"},
+		},
+		{
+			name:      "own id option",
+			id:        "windows-zip",
+			pluggable: modules.V2Factory(func() modules.PluggableV2 { return &testNamedModule{ID: "zip"} })(),
+			want:      []string{"zip"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			cx := ctx.New(context.Background())
+
+			context, err := ctx.GetShipContext(cx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			context.Heartbeat = 0
+
+			mod := &modules.Module{Type: "test", ID: tt.id, Pluggable: tt.pluggable}
+			if err := mod.Run(cx); err != nil {
+				t.Fatalf("running module: %v", err)
+			}
+
+			got := []string{}
+			for _, art := range context.Artifacts {
+				got = append(got, art.ID)
+			}
+
+			if diff := deep.Equal(got, tt.want); diff != nil {
+				t.Errorf("artifact IDs %v", diff)
+			}
+		})
+	}
+}
+
+func TestModule_RunResult_meta(t *testing.T) {
+	cx := ctx.New(context.Background())
+
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	context.Heartbeat = 0
+
+	mod := &modules.Module{Type: "legacy", Meta: map[string]string{"channel": "stable"}, Pluggable: &testLegacyModule{}}
+	if err := mod.Run(cx); err != nil {
+		t.Fatalf("running module: %v", err)
+	}
+
+	if got := context.Artifacts.ByMeta("channel", "stable"); len(*got) != 1 {
+		t.Errorf("artifacts annotated with channel=stable: %v, want 1", got)
+	}
+}
@@ -0,0 +1,311 @@
+package modules
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaVersion is the JSON Schema dialect of generated schemas
+const SchemaVersion = "https://json-schema.org/draft/2020-12/schema"
+
+type (
+	// Schema is a JSON Schema of a configuration. It covers the subset of
+	// JSON Schema generated from configuration structs (see SchemaOf).
+	Schema struct {
+		Schema string `json:"$schema,omitempty"`
+		Type   string `json:"type,omitempty"`
+		Const  string `json:"const,omitempty"`
+		// AdditionalProperties is false for structs, and the value's
+		// Schema for maps
+		AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+		Items                *Schema            `json:"items,omitempty"`
+		OneOf                []*Schema          `json:"oneOf,omitempty"`
+		Properties           map[string]*Schema `json:"properties,omitempty"`
+		Required             []string           `json:"required,omitempty"`
+	}
+
+	// SchemaError is a configuration error found by Schema.Validate
+	SchemaError struct {
+		Column  int
+		Line    int
+		Message string
+		// Module is the kind of the module configured, if any
+		Module string
+		// Unknown is true for unknown keys
+		Unknown bool
+	}
+
+	// SchemaErrors are all configuration errors found by Schema.Validate
+	SchemaErrors []*SchemaError
+)
+
+// nolint: gochecknoglobals
+var (
+	durationType    = reflect.TypeOf(time.Duration(0))
+	unmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+)
+
+// SchemaOf generates a Schema of a configuration value (usually a pointer
+// to a module), following YAML decoding rules: keys are `yaml` tags, or
+// lowercase field names, and `inline` fields are merged. Values decoding
+// themselves (implementing yaml.Unmarshaler) accept anything.
+func SchemaOf(value interface{}) *Schema {
+	return schemaOf(reflect.TypeOf(value), map[reflect.Type]bool{})
+}
+
+func schemaOf(typ reflect.Type, visiting map[reflect.Type]bool) *Schema {
+	if typ == nil || visiting[typ] {
+		return &Schema{}
+	}
+
+	if typ.Implements(unmarshalerType) || reflect.PtrTo(typ).Implements(unmarshalerType) {
+		return &Schema{}
+	}
+
+	if typ == durationType {
+		return &Schema{Type: "string"}
+	}
+
+	switch typ.Kind() {
+	case reflect.Ptr:
+		return schemaOf(typ.Elem(), visiting)
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: schemaOf(typ.Elem(), visiting)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaOf(typ.Elem(), visiting)}
+	case reflect.Struct:
+		visiting[typ] = true
+		defer delete(visiting, typ)
+
+		schema := &Schema{Type: "object", Properties: map[string]*Schema{}, AdditionalProperties: false}
+		schema.addFields(typ, visiting)
+
+		return schema
+	default:
+		return &Schema{}
+	}
+}
+
+// addFields adds properties of a struct's fields
+func (schema *Schema) addFields(typ reflect.Type, visiting map[reflect.Type]bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		name, inline := yamlKey(field)
+		if name == "-" {
+			continue
+		}
+
+		if inline {
+			inline := schemaOf(field.Type, visiting)
+			for key, prop := range inline.Properties {
+				schema.Properties[key] = prop
+			}
+
+			if inline.Type == "object" && inline.Properties == nil {
+				schema.AdditionalProperties = inline.AdditionalProperties
+			}
+
+			continue
+		}
+
+		if field.PkgPath != "" {
+			continue
+		}
+
+		schema.Properties[name] = schemaOf(field.Type, visiting)
+	}
+}
+
+// yamlKey returns the YAML key of a struct field ("-" for ignored fields),
+// and whether it is inlined
+func yamlKey(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("yaml")
+	if tag == "-" {
+		return tag, false
+	}
+
+	name, flags := tag, ""
+	if idx := strings.Index(tag, ","); idx >= 0 {
+		name, flags = tag[:idx], tag[idx+1:]
+	}
+
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+
+	return name, strings.Contains(","+flags+",", ",inline,")
+}
+
+// Merge adds properties of another object schema
+func (schema *Schema) Merge(other *Schema) {
+	if schema.Properties == nil {
+		schema.Properties = map[string]*Schema{}
+	}
+
+	for key, prop := range other.Properties {
+		if _, ok := schema.Properties[key]; !ok {
+			schema.Properties[key] = prop
+		}
+	}
+}
+
+// Validate checks a YAML node against the schema. It reports unknown keys,
+// and values of unexpected types.
+func (schema *Schema) Validate(node *yaml.Node) SchemaErrors {
+	errs := SchemaErrors{}
+	schema.validate(node, "", &errs)
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
+
+func (schema *Schema) validate(node *yaml.Node, path string, errs *SchemaErrors) {
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+
+	if msg := schema.typeMismatch(node); msg != "" {
+		*errs = append(*errs, &SchemaError{
+			Column:  node.Column,
+			Line:    node.Line,
+			Message: fmt.Sprintf("`%s` %s", strings.TrimPrefix(path, "."), msg),
+		})
+
+		return
+	}
+
+	switch schema.Type {
+	case "array":
+		for _, item := range node.Content {
+			schema.Items.validate(item, path, errs)
+		}
+	case "object":
+		schema.validateKeys(node, path, errs)
+	}
+}
+
+// validateKeys checks keys of a mapping node
+func (schema *Schema) validateKeys(node *yaml.Node, path string, errs *SchemaErrors) {
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		key, value := node.Content[idx], node.Content[idx+1]
+		if key.Value == "<<" {
+			continue
+		}
+
+		keyPath := path + "." + key.Value
+
+		if prop, ok := schema.Properties[key.Value]; ok {
+			prop.validate(value, keyPath, errs)
+
+			continue
+		}
+
+		switch additional := schema.AdditionalProperties.(type) {
+		case *Schema:
+			additional.validate(value, keyPath, errs)
+		case bool:
+			if !additional {
+				*errs = append(*errs, &SchemaError{
+					Column:  key.Column,
+					Line:    key.Line,
+					Message: fmt.Sprintf("unknown key `%s`", strings.TrimPrefix(keyPath, ".")),
+					Unknown: true,
+				})
+			}
+		}
+	}
+}
+
+// typeMismatch returns a message if node is not of the schema's type
+func (schema *Schema) typeMismatch(node *yaml.Node) string {
+	switch schema.Type {
+	case "array":
+		if node.Kind != yaml.SequenceNode {
+			return "must be a list"
+		}
+	case "object":
+		if node.Kind != yaml.MappingNode {
+			return "must be a map"
+		}
+	case "string":
+		if node.Kind != yaml.ScalarNode {
+			return "must be a string"
+		}
+	case "boolean":
+		if node.Kind != yaml.ScalarNode || (node.Tag != "!!bool" && !isLegacyBool(node)) {
+			return "must be a boolean"
+		}
+	case "integer":
+		if node.Kind != yaml.ScalarNode || (node.Tag != "!!int" && node.Tag != "!!float") {
+			return "must be an integer"
+		}
+	case "number":
+		if node.Kind != yaml.ScalarNode || (node.Tag != "!!int" && node.Tag != "!!float") {
+			return "must be a number"
+		}
+	}
+
+	return ""
+}
+
+// isLegacyBool returns true for unquoted YAML 1.1 booleans (eg. "yes"),
+// which are still decoded into booleans
+func isLegacyBool(node *yaml.Node) bool {
+	if node.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle) != 0 {
+		return false
+	}
+
+	switch strings.ToLower(node.Value) {
+	case "y", "yes", "on", "n", "no", "off":
+		return true
+	}
+
+	return false
+}
+
+// Error implements error
+func (err *SchemaError) Error() string {
+	if err.Module != "" {
+		return fmt.Sprintf("%s in %s at line %d, column %d", err.Message, err.Module, err.Line, err.Column)
+	}
+
+	return fmt.Sprintf("%s at line %d, column %d", err.Message, err.Line, err.Column)
+}
+
+// Error implements error
+func (errs SchemaErrors) Error() string {
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+
+	return strings.Join(messages, "; ")
+}
@@ -0,0 +1,81 @@
+package modules_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/julian7/goshipdone/modules"
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	testSchemaCommon struct {
+		Name string
+	}
+
+	testSchemaConfig struct {
+		testSchemaCommon `yaml:",inline"`
+		Compression      modules.Compression
+		Count            int
+		Enabled          bool
+		Labels           map[string]string
+		SigningKey       string `yaml:"signing_key"`
+		Targets          []struct{ OS string }
+		Timeout          time.Duration
+		internal         string // nolint: structcheck, unused
+	}
+)
+
+func TestSchemaOf(t *testing.T) {
+	data, err := json.Marshal(modules.SchemaOf(&testSchemaConfig{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"type":"object","additionalProperties":false,"properties":{` +
+		`"compression":{},"count":{"type":"integer"},"enabled":{"type":"boolean"},` +
+		`"labels":{"type":"object","additionalProperties":{"type":"string"}},"name":{"type":"string"},` +
+		`"signing_key":{"type":"string"},` +
+		`"targets":{"type":"array","items":{"type":"object","additionalProperties":false,"properties":{"os":{"type":"string"}}}},` +
+		`"timeout":{"type":"string"}}}`
+
+	if string(data) != want {
+		t.Errorf("SchemaOf() = %s, want %s", data, want)
+	}
+}
+
+func TestSchema_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		yml  string
+		want string
+	}{
+		{name: "valid", yml: "name: a\ncount: 2\nenabled: yes\nlabels: {a: b}\ntargets: [{os: linux}]\ntimeout: 1m\ncompression: gz\n"},
+		{name: "unknown key", yml: "name: a\ncompresion: gz\n", want: "unknown key `compresion` at line 2, column 1"},
+		{name: "nested unknown key", yml: "targets:\n- os: linux\n  arch: amd64\n", want: "unknown key `targets.arch` at line 3, column 3"},
+		{name: "type mismatch", yml: "count: many\nenabled: \"true\"\n", want: "`count` must be an integer at line 1, column 8; `enabled` must be a boolean at line 2, column 10"},
+		{name: "not a list", yml: "targets: linux\n", want: "`targets` must be a list at line 1, column 10"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			node := &yaml.Node{}
+			if err := yaml.Unmarshal([]byte(tt.yml), node); err != nil {
+				t.Fatal(err)
+			}
+
+			errs := modules.SchemaOf(&testSchemaConfig{}).Validate(node)
+
+			got := ""
+			if errs != nil {
+				got = errs.Error()
+			}
+
+			if got != tt.want {
+				t.Errorf("Validate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
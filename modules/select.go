@@ -0,0 +1,77 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+// SelectArtifacts returns artifacts of builds mapped by OS-Arch (see
+// ctx.Artifacts.OsArchByIDs), filtered by a template expression. The
+// expression is evaluated for each artifact (see TemplateData.ParseBool),
+// having OSArch, Filename, and Artifact set. If builds is empty, but
+// expression is set, all artifacts are evaluated.
+//
+// Example: `{{and (eq OS "linux") (Match "*.tar.gz" .Filename)}}`.
+func SelectArtifacts(cx context.Context, builds, skips []string, expr string) (map[string]*ctx.Artifacts, error) {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return nil, err
+	}
+
+	if expr == "" {
+		return context.Artifacts.OsArchByIDs(builds, skips), nil
+	}
+
+	if len(builds) == 0 {
+		builds = artifactIDs(context.Artifacts)
+	}
+
+	td, err := NewTemplate(cx)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := map[string]*ctx.Artifacts{}
+
+	for osarch, arts := range context.Artifacts.OsArchByIDs(builds, skips) {
+		for _, art := range *arts {
+			td.Artifact = art
+			td.Filename = art.Filename
+			td.OSArch = art.OsArch
+
+			ok, err := td.ParseBool("artifacts", expr)
+			if err != nil {
+				return nil, fmt.Errorf("selecting artifact %s: %w", art.Filename, err)
+			}
+
+			if !ok {
+				continue
+			}
+
+			if _, found := selected[osarch]; !found {
+				selected[osarch] = &ctx.Artifacts{}
+			}
+
+			*selected[osarch] = append(*selected[osarch], art)
+		}
+	}
+
+	return selected, nil
+}
+
+// artifactIDs returns unique artifact IDs in order of appearance
+func artifactIDs(arts ctx.Artifacts) []string {
+	seen := map[string]bool{}
+	ids := []string{}
+
+	for _, art := range arts {
+		if !seen[art.ID] {
+			seen[art.ID] = true
+			ids = append(ids, art.ID)
+		}
+	}
+
+	return ids
+}
@@ -0,0 +1,74 @@
+package modules_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+func TestSelectArtifacts(t *testing.T) {
+	cx := ctx.New(context.Background())
+
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	linux := &ctx.OsArch{OS: "linux", Arch: "amd64"}
+	windows := &ctx.OsArch{OS: "windows", Arch: "amd64"}
+	context.Artifacts = ctx.Artifacts{
+		{Filename: "app", ID: "default", OsArch: linux},
+		{Filename: "app.exe", ID: "default", OsArch: windows},
+		{Filename: "app-linux.tar.gz", ID: "archive", OsArch: linux, Meta: map[string]string{"channel": "stable"}},
+		{Filename: "app-windows.zip", ID: "archive", OsArch: windows},
+	}
+
+	tests := []struct {
+		name    string
+		builds  []string
+		expr    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "builds only", builds: []string{"archive"}, want: []string{"app-linux.tar.gz", "app-windows.zip"}},
+		{name: "os filter", builds: []string{"default"}, expr: `{{eq OS "windows"}}`, want: []string{"app.exe"}},
+		{name: "all artifacts", expr: `{{Match "*.tar.gz" .Filename}}`, want: []string{"app-linux.tar.gz"}},
+		{name: "artifact fields", expr: `{{and (eq .Artifact.ID "default") (eq OS "linux")}}`, want: []string{"app"}},
+		{name: "meta", expr: `{{HasMeta "channel" "stable"}}`, want: []string{"app-linux.tar.gz"}},
+		{name: "nothing", builds: []string{"archive"}, expr: `{{eq OS "darwin"}}`, want: []string{}},
+		{name: "bad pattern", expr: `{{Match "[*.tar.gz" .Filename}}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			selected, err := modules.SelectArtifacts(cx, tt.builds, nil, tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SelectArtifacts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			got := []string{}
+
+			for _, arts := range selected {
+				for _, art := range *arts {
+					got = append(got, art.Filename)
+				}
+			}
+
+			sort.Strings(got)
+
+			if diff := deep.Equal(got, tt.want); diff != nil {
+				t.Errorf("SelectArtifacts() %v", diff)
+			}
+		})
+	}
+}
@@ -3,12 +3,96 @@ package modules
 import (
 	"bytes"
 	"context"
+	"fmt"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 	"text/template"
+	"time"
 
+	"github.com/Masterminds/sprig/v3"
 	"github.com/julian7/goshipdone/ctx"
 	"github.com/julian7/withenv"
 )
 
+// replacementsKey is the context key of the running module's replacements
+type replacementsKey struct{}
+
+// nolint: gochecknoglobals
+var (
+	// DefaultReplacements map Go OS, and architecture names to names of
+	// other ecosystems in templates (see TemplateData.Alias). Modules
+	// override them with their `replacements` option.
+	DefaultReplacements = map[string]string{
+		"386":    "i686",
+		"amd64":  "x86_64",
+		"arm64":  "aarch64",
+		"darwin": "macOS",
+	}
+	// DefaultExeExtensions map OS names, architecture names, or OS-arch
+	// combinations (like "js-wasm") to file name extensions of
+	// executables in templates (see TemplateData.Exe). setup:project
+	// overrides them with its `exe_extensions` option.
+	DefaultExeExtensions = map[string]string{
+		"wasm":    ".wasm",
+		"windows": ".exe",
+	}
+	templateFuncs     = template.FuncMap{}
+	templateFuncsLock sync.RWMutex
+	reTemplateFunc    = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	errorType         = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// RegisterTemplateFunc allows registering additional template functions
+// (like organization-specific naming conventions), which then can be used
+// by all templated fields, including payload templates of notifiers. Fn
+// must be a function returning a value, and optionally an error, like
+// functions of text/template. It overrides earlier registrations, and
+// sprig functions with the same name, but not functions of TemplateData
+// (like OS, or Arch).
+func RegisterTemplateFunc(name string, fn interface{}) error {
+	if !reTemplateFunc.MatchString(name) {
+		return fmt.Errorf("invalid template function name %q", name)
+	}
+
+	typ := reflect.TypeOf(fn)
+	if typ == nil || typ.Kind() != reflect.Func {
+		return fmt.Errorf("template function %s is not a function", name)
+	}
+
+	switch {
+	case typ.NumOut() == 1:
+	case typ.NumOut() == 2 && typ.Out(1) == errorType:
+	default:
+		return fmt.Errorf("template function %s must return a value, and optionally an error", name)
+	}
+
+	templateFuncsLock.Lock()
+	defer templateFuncsLock.Unlock()
+
+	templateFuncs[name] = fn
+
+	return nil
+}
+
+// TemplateFuncs returns template functions registered by
+// RegisterTemplateFunc, for modules rendering templates with data other
+// than TemplateData
+func TemplateFuncs() template.FuncMap {
+	templateFuncsLock.RLock()
+	defer templateFuncsLock.RUnlock()
+
+	funcs := make(template.FuncMap, len(templateFuncs))
+	for name, fn := range templateFuncs {
+		funcs[name] = fn
+	}
+
+	return funcs
+}
+
 // TemplateData is the data all template-based text replacement takes place.
 // Modules are responsible of filling in the appropriate fields, and handle
 // dependencies (for example, ArchiveName is often depends on ProjectName, therefore
@@ -16,50 +100,123 @@ import (
 type TemplateData struct {
 	// Algo represents algorithm. Hashing and signing modules use them.
 	Algo string
+	// Artifact is the artifact being selected (see SelectArtifacts)
+	Artifact *ctx.Artifact
 	// ArchiveName defines a URL where the resource will be remotely available
 	ArchiveName string
+	// Branch is the current branch (see ctx.GitData)
+	Branch string
+	// BuildDate is the time the pipeline has been started
+	BuildDate time.Time
+	// CI is a copy of CI environment info from ctx.Context
+	CI *ctx.CIData
+	// Commit is the full SHA1 checksum of the current commit
+	Commit string
+	// CommitDate is the committer date of the current commit
+	CommitDate time.Time
 	// Env is a copy of environment variables set in ctx.Context
 	Env *withenv.Env
+	// Filename is the file name of the artifact being processed, for
+	// modules creating a new file for each artifact (eg. signatures)
+	Filename string
 	// Git is a copy of git-related info from ctx.Context
 	Git *ctx.GitData
+	// Major is the major version of Version, if it's a semantic version
+	Major uint64
+	// Minor is the minor version of Version, if it's a semantic version
+	Minor uint64
 	// OSArch defines target operating system and architecture
 	OSArch *ctx.OsArch
+	// OSes are operating systems of artifacts registered so far
+	OSes []string
+	// Patch is the patch version of Version, if it's a semantic version
+	Patch uint64
+	// Prerelease is the pre-release part of Version, like "rc.1", if it's
+	// a semantic version
+	Prerelease string
 	// ProjectName defines local filename of the resource
 	ProjectName string
+	// ShortCommit is the abbreviated (7 characters long) SHA1 checksum of
+	// the current commit
+	ShortCommit string
+	// ShortVersion is the major, and minor version of Version, like "1.2",
+	// if it's a semantic version
+	ShortVersion string
+	// Snapshot is true for builds of untagged commits (see setup:snapshot)
+	Snapshot bool
+	// Tag is the current tag, if the current commit is tagged
+	Tag string
 	// Version defines artifact's version
 	Version string
-	// Ext contains executable extension
+	// Ext contains a module-specific file name extension, like the
+	// compression's extension of archives (see Exe for executables)
 	Ext string
+	// exeExtensions override DefaultExeExtensions (see Exe)
+	exeExtensions map[string]string
+	// replacements override DefaultReplacements (see Alias)
+	replacements map[string]string
+	// lenient renders missing map keys as "<no value>" instead of failing
+	// (see ctx.Context.LenientTemplates)
+	lenient bool
 }
 
+// NewTemplate creates template data from the ship context. Modules fill in
+// further fields (eg. OSArch, or Filename) before rendering.
 func NewTemplate(cx context.Context) (*TemplateData, error) {
 	context, err := ctx.GetShipContext(cx)
 	if err != nil {
 		return nil, err
 	}
 
-	return &TemplateData{
-		Env:         context.Env,
-		Git:         context.Git,
-		ProjectName: context.ProjectName,
-		Version:     context.Version,
-	}, nil
+	shortCommit := context.Git.Ref
+	if len(shortCommit) > 7 {
+		shortCommit = shortCommit[:7]
+	}
+
+	td := &TemplateData{
+		Branch:        context.Git.Branch,
+		BuildDate:     context.StartedAt,
+		CI:            context.CI,
+		Commit:        context.Git.Ref,
+		CommitDate:    context.Git.CommitDate,
+		Env:           context.Env,
+		exeExtensions: context.ExeExtensions,
+		Git:           context.Git,
+		OSes:          context.Artifacts.OSes(),
+		ProjectName:   context.ProjectName,
+		ShortCommit:   shortCommit,
+		Snapshot:      context.Snapshot,
+		Tag:           context.Git.Tag,
+		Version:       context.Version,
+		lenient:       context.LenientTemplates,
+	}
+
+	if replacements, ok := cx.Value(replacementsKey{}).(map[string]string); ok {
+		td.replacements = replacements
+	}
+
+	if semver, err := ctx.ParseSemver(context.Version); err == nil {
+		td.Major = semver.Major
+		td.Minor = semver.Minor
+		td.Patch = semver.Patch
+		td.Prerelease = semver.Prerelease
+		td.ShortVersion = fmt.Sprintf("%d.%d", semver.Major, semver.Minor)
+	}
+
+	return td, nil
 }
 
-// Parse parses a string based on TemplateData, and returns output in string format
+// Parse parses a string based on TemplateData, and returns output in string
+// format. Name is the template's name in errors, usually the module's
+// field. Unknown fields, and missing map keys (like `{{.Verzion}}`) are
+// errors, unless templates are lenient (see ctx.Context.LenientTemplates).
 func (td *TemplateData) Parse(name, text string) (string, error) {
-	tmpl := template.New(name).Funcs(template.FuncMap{
-		"Arch":     func() string { return td.OSArch.Arch },
-		"ArchName": func() string { return td.OSArch.ArchName() },
-		"OS":       func() string { return td.OSArch.OS },
-		"OSExt": func() string {
-			if td.OSArch.OS == "windows" {
-				return ".exe"
-			}
+	missingKey := "missingkey=error"
+	if td.lenient {
+		missingKey = "missingkey=default"
+	}
 
-			return ""
-		},
-	})
+	tmpl := template.New(name).Funcs(td.funcs()).Option(missingKey)
 	_, err := tmpl.Parse(text)
 
 	if err != nil {
@@ -73,3 +230,132 @@ func (td *TemplateData) Parse(name, text string) (string, error) {
 
 	return td.Env.Expand(out.String()), nil
 }
+
+// funcs returns template functions: the sprig library (see
+// https://masterminds.github.io/sprig/), registered functions (see
+// RegisterTemplateFunc), and functions of TemplateData. Sprig's
+// environment functions read the run's environment (see Env) instead of
+// the process's.
+func (td *TemplateData) funcs() template.FuncMap {
+	funcs := sprig.TxtFuncMap()
+
+	for name, fn := range TemplateFuncs() {
+		funcs[name] = fn
+	}
+
+	own := template.FuncMap{
+		"Alias":     td.Alias,
+		"Arch":      func() string { return td.OSArch.Arch },
+		"ArchAlias": func() string { return td.Alias(td.OSArch.ArchName()) },
+		"ArchName":  func() string { return td.OSArch.ArchName() },
+		"Has": func(list []string, item string) bool {
+			for _, elem := range list {
+				if elem == item {
+					return true
+				}
+			}
+
+			return false
+		},
+		"HasMeta": func(key, value string) bool {
+			return td.Artifact != nil && td.Artifact.HasMeta(key, value)
+		},
+		"Match":     path.Match,
+		"OS":        func() string { return td.OSArch.OS },
+		"OSAlias":   func() string { return td.Alias(td.OSArch.OS) },
+		"OSExt":     td.Exe,
+		"env":       func(name string) string { return td.Env.GetOrDefault(name, "") },
+		"expandenv": func(text string) string { return td.Env.Expand(text) },
+		"incMajor":  incVersion((*ctx.Semver).IncMajor),
+		"incMinor":  incVersion((*ctx.Semver).IncMinor),
+		"incPatch":  incVersion((*ctx.Semver).IncPatch),
+	}
+
+	for name, fn := range own {
+		funcs[name] = fn
+	}
+
+	return funcs
+}
+
+// Alias returns a name (like an OS, or an architecture) replaced by the
+// module's `replacements` option, or DefaultReplacements, like "x86_64"
+// for "amd64". Names without replacements are returned as is.
+func (td *TemplateData) Alias(name string) string {
+	if alias, ok := td.replacements[name]; ok {
+		return alias
+	}
+
+	if alias, ok := DefaultReplacements[name]; ok {
+		return alias
+	}
+
+	return name
+}
+
+// Exe returns the file name extension of executables of OSArch, like
+// ".exe" on windows, ".wasm" for wasm, or empty elsewhere. OS-arch
+// combinations take precedence over architectures, and architectures
+// take precedence over OSes. The project's `exe_extensions` option
+// overrides DefaultExeExtensions.
+func (td *TemplateData) Exe() string {
+	if td.OSArch == nil {
+		return ""
+	}
+
+	for _, name := range []string{td.OSArch.String(), td.OSArch.ArchName(), td.OSArch.OS} {
+		if ext, ok := td.exeExtensions[name]; ok {
+			return ext
+		}
+
+		if ext, ok := DefaultExeExtensions[name]; ok {
+			return ext
+		}
+	}
+
+	return ""
+}
+
+// incVersion returns a template function bumping a semantic version, and
+// keeping its "v" prefix
+func incVersion(inc func(*ctx.Semver) *ctx.Semver) func(string) (string, error) {
+	return func(version string) (string, error) {
+		semver, err := ctx.ParseSemver(version)
+		if err != nil {
+			return "", err
+		}
+
+		prefix := ""
+		if strings.HasPrefix(version, "v") {
+			prefix = "v"
+		}
+
+		return prefix + inc(semver).String(), nil
+	}
+}
+
+// ParseBool renders a template expression, and interprets its output as a
+// boolean value. An empty expression, or an expression rendering to an
+// empty string is false.
+func (td *TemplateData) ParseBool(name, text string) (bool, error) {
+	if text == "" {
+		return false, nil
+	}
+
+	out, err := td.Parse(name, text)
+	if err != nil {
+		return false, err
+	}
+
+	out = strings.TrimSpace(out)
+	if out == "" {
+		return false, nil
+	}
+
+	val, err := strconv.ParseBool(out)
+	if err != nil {
+		return false, fmt.Errorf("expression %q is not a boolean: %q", text, out)
+	}
+
+	return val, nil
+}
@@ -0,0 +1,301 @@
+package modules_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+	"github.com/julian7/withenv"
+)
+
+func TestTemplateData_ParseBool(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    bool
+		wantErr bool
+	}{
+		{name: "empty", text: "", want: false},
+		{name: "literal true", text: "true", want: true},
+		{name: "matching expression", text: `{{ and (eq OS "windows") (eq Arch "arm") }}`, want: true},
+		{name: "non-matching expression", text: `{{ eq OS "linux" }}`, want: false},
+		{name: "list membership", text: `{{ and (Has .OSes "darwin") (not (Has .OSes "linux")) }}`, want: true},
+		{name: "empty output", text: `{{ if eq OS "linux" }}true{{ end }}`, want: false},
+		{name: "not a boolean", text: "{{ OS }}", wantErr: true},
+		{name: "invalid template", text: "{{ OS ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			td := &modules.TemplateData{
+				Env:    withenv.New(),
+				OSArch: &ctx.OsArch{OS: "windows", Arch: "arm"},
+				OSes:   []string{"darwin", "windows"},
+			}
+
+			got, err := td.ParseBool("test", tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("TemplateData.ParseBool() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if got != tt.want {
+				t.Errorf("TemplateData.ParseBool() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateData_Parse_semver(t *testing.T) {
+	td := &modules.TemplateData{
+		Env: withenv.New(),
+		Git: &ctx.GitData{Semver: ctx.Semver{Major: 1, Minor: 2, Patch: 3, Prerelease: "rc.1"}},
+	}
+
+	got, err := td.Parse("test", "{{.Git.Semver.Major}}.{{.Git.Semver.Minor}} {{.Git.Semver.IsPrerelease}}")
+	if err != nil {
+		t.Fatalf("TemplateData.Parse() error = %v", err)
+	}
+
+	if got != "1.2 true" {
+		t.Errorf("TemplateData.Parse() = %q, want %q", got, "1.2 true")
+	}
+}
+
+func TestTemplateData_Parse_sprig(t *testing.T) {
+	env := withenv.New()
+	env.Set("CHANNEL", "beta")
+
+	td := &modules.TemplateData{
+		BuildDate:   time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC),
+		Env:         env,
+		ProjectName: "my_app",
+		Snapshot:    true,
+		Version:     "v1.2.3",
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "strings", text: `{{ .ProjectName | replace "_" "-" | upper }}`, want: "MY-APP"},
+		{name: "trim prefix", text: `{{ trimPrefix "v" .Version }}`, want: "1.2.3"},
+		{name: "default", text: `{{ .CI | default "local" }}`, want: "local"},
+		{name: "ternary", text: `{{ ternary "nightly" "stable" .Snapshot }}`, want: "nightly"},
+		{name: "date math", text: `{{ dateModify "24h" .BuildDate | date "2006-01-02" }}`, want: "2021-01-03"},
+		{name: "run environment", text: `{{ env "CHANNEL" }}`, want: "beta"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := td.Parse("test", tt.text)
+			if err != nil {
+				t.Fatalf("TemplateData.Parse() error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("TemplateData.Parse() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterTemplateFunc(t *testing.T) {
+	debArch := func(arch string) string {
+		if arch == "386" {
+			return "i386"
+		}
+
+		return arch
+	}
+
+	if err := modules.RegisterTemplateFunc("testDebArch", debArch); err != nil {
+		t.Fatalf("RegisterTemplateFunc() error = %v", err)
+	}
+
+	if err := modules.RegisterTemplateFunc("OS", func() string { return "plan9" }); err != nil {
+		t.Fatalf("RegisterTemplateFunc() error = %v", err)
+	}
+
+	for name, fn := range map[string]interface{}{
+		"not-an-identifier": debArch,
+		"notAFunc":          "i386",
+		"noResult":          func() {},
+		"badError":          func() (string, string) { return "", "" },
+	} {
+		if err := modules.RegisterTemplateFunc(name, fn); err == nil {
+			t.Errorf("RegisterTemplateFunc(%q) succeeded", name)
+		}
+	}
+
+	td := &modules.TemplateData{
+		Env:    withenv.New(),
+		OSArch: &ctx.OsArch{OS: "linux", Arch: "386"},
+	}
+
+	got, err := td.Parse("test", "{{ OS }}-{{ testDebArch Arch }}")
+	if err != nil {
+		t.Fatalf("TemplateData.Parse() error = %v", err)
+	}
+
+	if got != "linux-i386" {
+		t.Errorf("TemplateData.Parse() = %q, want %q", got, "linux-i386")
+	}
+
+	if _, ok := modules.TemplateFuncs()["testDebArch"]; !ok {
+		t.Error("TemplateFuncs() doesn't contain registered function")
+	}
+}
+
+func TestTemplateData_Parse_strict(t *testing.T) {
+	cx := ctx.New(context.Background())
+
+	td, err := modules.NewTemplate(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	td.Artifact = &ctx.Artifact{Meta: map[string]string{"channel": "stable"}}
+
+	for _, text := range []string{"{{.Verzion}}", "{{.Artifact.Meta.chanel}}"} {
+		if _, err := td.Parse("output", text); err == nil || !strings.Contains(err.Error(), "output") {
+			t.Errorf("TemplateData.Parse(%q) error = %v, want error naming the template", text, err)
+		}
+	}
+
+	shipContext, _ := ctx.GetShipContext(cx)
+	shipContext.LenientTemplates = true
+
+	td, err = modules.NewTemplate(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	td.Artifact = &ctx.Artifact{Meta: map[string]string{"channel": "stable"}}
+
+	if got, err := td.Parse("output", "{{.Artifact.Meta.chanel}}"); err != nil || got != "<no value>" {
+		t.Errorf("lenient TemplateData.Parse() = %q, %v, want <no value>", got, err)
+	}
+}
+
+func TestNewTemplate_git(t *testing.T) {
+	cx := ctx.New(context.Background())
+
+	shipContext, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shipContext.Git.Branch = "main"
+	shipContext.Git.CommitDate = time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	shipContext.Git.Ref = "abcdef0123456789abcdef0123456789abcdef01"
+	shipContext.Git.Tag = "v1.2.3"
+
+	td, err := modules.NewTemplate(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := td.Parse("test", `{{.Branch}} {{.Tag}} {{.ShortCommit}} {{.Commit}} {{.CommitDate.Format "2006-01-02"}}`)
+	if err != nil {
+		t.Fatalf("TemplateData.Parse() error = %v", err)
+	}
+
+	want := "main v1.2.3 abcdef0 abcdef0123456789abcdef0123456789abcdef01 2021-01-02"
+	if got != want {
+		t.Errorf("TemplateData.Parse() = %q, want %q", got, want)
+	}
+}
+
+func TestNewTemplate_semver(t *testing.T) {
+	cx := ctx.New(context.Background())
+
+	shipContext, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shipContext.Version = "v1.2.3-rc.1"
+
+	td, err := modules.NewTemplate(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "{{.Major}}.{{.Minor}}.{{.Patch}} {{.Prerelease}}", want: "1.2.3 rc.1"},
+		{text: "{{.ShortVersion}}", want: "1.2"},
+		{text: "{{ incMajor .Version }}", want: "v2.0.0"},
+		{text: "{{ incMinor .Version }}", want: "v1.3.0"},
+		{text: "{{ incPatch .Version }}", want: "v1.2.3"},
+		{text: `{{ incPatch "1.2.3" }}`, want: "1.2.4"},
+	}
+
+	for _, tt := range tests {
+		got, err := td.Parse("test", tt.text)
+		if err != nil {
+			t.Errorf("TemplateData.Parse(%q) error = %v", tt.text, err)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("TemplateData.Parse(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+
+	if _, err := td.Parse("test", `{{ incMinor "latest" }}`); err == nil {
+		t.Error("TemplateData.Parse() succeeded bumping a non-semantic version")
+	}
+}
+
+func TestTemplateData_Exe(t *testing.T) {
+	cx := ctx.New(context.Background())
+
+	shipContext, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shipContext.ExeExtensions = map[string]string{"js-wasm": ".js"}
+
+	td, err := modules.NewTemplate(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		osarch *ctx.OsArch
+		want   string
+	}{
+		{osarch: nil, want: ""},
+		{osarch: &ctx.OsArch{OS: "linux", Arch: "amd64"}, want: ""},
+		{osarch: &ctx.OsArch{OS: "windows", Arch: "arm64"}, want: ".exe"},
+		{osarch: &ctx.OsArch{OS: "wasip1", Arch: "wasm"}, want: ".wasm"},
+		{osarch: &ctx.OsArch{OS: "js", Arch: "wasm"}, want: ".js"},
+	}
+
+	for _, tt := range tests {
+		td.OSArch = tt.osarch
+
+		got, err := td.Parse("test", "{{.Exe}}")
+		if err != nil {
+			t.Errorf("TemplateData.Parse() for %s error = %v", tt.osarch, err)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("TemplateData.Parse() for %s = %q, want %q", tt.osarch, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+// DynamicArtifacts is implemented by modules producing artifacts of IDs
+// known only when running (eg. imported from another run's manifest).
+// Validation doesn't report artifact IDs taken by later modules as
+// unknown, if DynamicArtifacts returns true.
+type DynamicArtifacts interface {
+	DynamicArtifacts() bool
+}
+
+// TemplateValidator is implemented by modules rendering some of their
+// templates with data other than TemplateData (eg. notification
+// payloads), or taking values CheckTemplates must not render (eg.
+// configuration passed to plugins). ValidateTemplates checks these
+// templates, and returns Go field names CheckTemplates skips.
+type TemplateValidator interface {
+	ValidateTemplates(context.Context) ([]string, error)
+}
+
+// SampleTemplate returns template data of the ship context, completed with
+// sample values of an artifact being processed, for checking templates
+// before running the pipeline
+func SampleTemplate(cx context.Context) (*TemplateData, error) {
+	td, err := NewTemplate(cx)
+	if err != nil {
+		return nil, err
+	}
+
+	osarch := &ctx.OsArch{OS: "linux", Arch: "amd64"}
+
+	td.Algo = "sha256"
+	td.ArchiveName = "project-v0.0.0-linux-amd64.tar.gz"
+	td.Artifact = &ctx.Artifact{
+		Filename: "project",
+		ID:       "default",
+		Location: "dist/linux-amd64/project",
+		OsArch:   osarch,
+	}
+	td.Ext = ".tar.gz"
+	td.Filename = td.Artifact.Filename
+	td.OSArch = osarch
+
+	if td.ProjectName == "" {
+		td.ProjectName = "project"
+	}
+
+	if td.Version == "" {
+		td.Version = "v0.0.0"
+	}
+
+	return td, nil
+}
+
+// CheckTemplates renders templates (string values containing "{{") of a
+// module's configuration with SampleTemplate, returning the first error.
+// Modules implementing TemplateValidator check their other templates
+// themselves.
+func CheckTemplates(cx context.Context, mod Pluggable) error {
+	config := Config(mod)
+	skips := map[string]bool{}
+
+	if validator, ok := config.(TemplateValidator); ok {
+		fields, err := validator.ValidateTemplates(cx)
+		if err != nil {
+			return err
+		}
+
+		for _, field := range fields {
+			skips[field] = true
+		}
+	}
+
+	td, err := SampleTemplate(cx)
+	if err != nil {
+		return err
+	}
+
+	return checkTemplates(td, reflect.ValueOf(config), "", skips)
+}
+
+// checkTemplates renders templates of a configuration value, named by its
+// YAML path
+func checkTemplates(td *TemplateData, value reflect.Value, path string, skips map[string]bool) error {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() {
+			return nil
+		}
+
+		return checkTemplates(td, value.Elem(), path, skips)
+	case reflect.String:
+		if !strings.Contains(value.String(), "{{") {
+			return nil
+		}
+
+		_, err := td.Parse(path, value.String())
+
+		return err
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := checkTemplates(td, value.Index(i), fmt.Sprintf("%s[%d]", path, i), skips); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		keys := value.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+
+		for _, key := range keys {
+			if err := checkTemplates(td, value.MapIndex(key), strings.TrimPrefix(fmt.Sprintf("%s.%v", path, key), "."), skips); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		return checkFieldTemplates(td, value, path, skips)
+	}
+
+	return nil
+}
+
+// checkFieldTemplates renders templates of a struct's fields
+func checkFieldTemplates(td *TemplateData, value reflect.Value, path string, skips map[string]bool) error {
+	typ := value.Type()
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if (field.PkgPath != "" && !field.Anonymous) || skips[field.Name] {
+			continue
+		}
+
+		name, inline := yamlKey(field)
+		if name == "-" {
+			continue
+		}
+
+		fieldPath := strings.TrimPrefix(path+"."+name, ".")
+		if inline {
+			fieldPath = path
+		}
+
+		if err := checkTemplates(td, value.Field(i), fieldPath, skips); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
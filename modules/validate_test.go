@@ -0,0 +1,79 @@
+package modules_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+type testTemplateCommon struct {
+	Output string
+}
+
+type testTemplateModule struct {
+	testTemplateCommon `yaml:",inline"`
+	Env                map[string]string
+	Payload            string
+	Targets            []*testTemplateCommon
+}
+
+func (*testTemplateModule) Run(context.Context) error {
+	return nil
+}
+
+func (mod *testTemplateModule) ValidateTemplates(context.Context) ([]string, error) {
+	return []string{"Payload"}, nil
+}
+
+func TestCheckTemplates(t *testing.T) {
+	tests := []struct {
+		name    string
+		mod     *testTemplateModule
+		wantErr string
+	}{
+		{
+			name: "valid",
+			mod: &testTemplateModule{
+				testTemplateCommon: testTemplateCommon{Output: "{{.ProjectName}}-{{OS}}-{{ArchName}}{{.Ext}}"},
+				Env:                map[string]string{"NAME": "{{.Artifact.Filename}}"},
+				Payload:            "{{ json .Payload }}",
+			},
+		},
+		{
+			name:    "inline field",
+			mod:     &testTemplateModule{testTemplateCommon: testTemplateCommon{Output: "{{.Nope}}"}},
+			wantErr: `template: output:1:2: executing "output" at <.Nope>`,
+		},
+		{
+			name:    "map value",
+			mod:     &testTemplateModule{Env: map[string]string{"NAME": "{{ end }}"}},
+			wantErr: "template: env.NAME:1: unexpected {{end}}",
+		},
+		{
+			name:    "list item",
+			mod:     &testTemplateModule{Targets: []*testTemplateCommon{{}, {Output: "{{ Nope }}"}}},
+			wantErr: `template: targets[1].output:1: function "Nope" not defined`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := modules.CheckTemplates(ctx.New(context.Background()), tt.mod)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("CheckTemplates() error = %v", err)
+				}
+
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CheckTemplates() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
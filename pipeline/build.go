@@ -12,12 +12,24 @@ import (
 // contents of a byte slice. Then, it makes sure default modules
 // are loaded, providing safe defaults.
 func LoadBuildPipeline(ymlcontent []byte) (*Pipeline, error) {
+	node := &yaml.Node{}
+	if err := yaml.Unmarshal(ymlcontent, node); err != nil {
+		return nil, err
+	}
+
+	return LoadBuildPipelineNode(node)
+}
+
+// LoadBuildPipelineNode creates a new BuildPipeline from a parsed YAML
+// document (eg. merged from multiple files), like LoadBuildPipeline.
+func LoadBuildPipelineNode(node *yaml.Node) (*Pipeline, error) {
 	modules.Register()
 
 	pipeline := New([]*Stage{
 		{
-			Name:   "setup",
-			Plural: "setups",
+			Name:       "setup",
+			Plural:     "setups",
+			Sequential: true,
 		},
 		{
 			Name:   "build",
@@ -26,19 +38,23 @@ func LoadBuildPipeline(ymlcontent []byte) (*Pipeline, error) {
 		{
 			Name:   "publish",
 			Plural: "publishes",
-			SkipFN: func(cx context.Context) bool {
-				context, err := ctx.GetShipContext(cx)
-				if err != nil {
-					return true
-				}
-				return !context.Publish
-			},
+			SkipFN: skipUnlessPublishing,
+		},
+		{
+			Name:   "notify",
+			Plural: "notifications",
+			SkipFN: skipUnlessPublishing,
 		},
 	})
 
-	err := yaml.Unmarshal(ymlcontent, pipeline)
-	if err != nil {
-		return nil, err
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	if node.Kind != 0 && node.Kind != yaml.DocumentNode {
+		if err := node.Decode(pipeline); err != nil {
+			return nil, err
+		}
 	}
 
 	for _, kind := range []string{
@@ -52,3 +68,13 @@ func LoadBuildPipeline(ymlcontent []byte) (*Pipeline, error) {
 
 	return pipeline, nil
 }
+
+// skipUnlessPublishing skips stages if publishing is turned off
+func skipUnlessPublishing(cx context.Context) bool {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return true
+	}
+
+	return !context.Publish
+}
@@ -4,10 +4,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/go-test/deep"
+	"github.com/julian7/goshipdone/ctx"
 	intmod "github.com/julian7/goshipdone/internal/modules"
 	"github.com/julian7/goshipdone/modules"
 	"github.com/julian7/goshipdone/pipeline"
@@ -59,8 +64,9 @@ func TestLoadBuildPipeline(t *testing.T) {
 			&pipeline.Pipeline{
 				Stages: []*pipeline.Stage{
 					{
-						Name:   "setup",
-						Plural: "setups",
+						Name:       "setup",
+						Plural:     "setups",
+						Sequential: true,
 						Modules: []*modules.Module{
 							{Type: "env", Pluggable: intmod.NewEnv()},
 							{Type: "project", Pluggable: intmod.NewProject()},
@@ -70,6 +76,7 @@ func TestLoadBuildPipeline(t *testing.T) {
 					},
 					{Name: "build", Plural: "builds"},
 					{Name: "publish", Plural: "publishes"},
+					{Name: "notify", Plural: "notifications"},
 				},
 			},
 			false,
@@ -80,8 +87,9 @@ func TestLoadBuildPipeline(t *testing.T) {
 			&pipeline.Pipeline{
 				Stages: []*pipeline.Stage{
 					{
-						Name:   "setup",
-						Plural: "setups",
+						Name:       "setup",
+						Plural:     "setups",
+						Sequential: true,
 						Modules: []*modules.Module{
 							{Type: "env", Pluggable: intmod.NewEnv()},
 							{Type: "project", Pluggable: intmod.NewProject()},
@@ -97,6 +105,7 @@ func TestLoadBuildPipeline(t *testing.T) {
 						},
 					},
 					{Name: "publish", Plural: "publishes"},
+					{Name: "notify", Plural: "notifications"},
 				},
 			},
 			false,
@@ -230,3 +239,917 @@ func TestBuildPipeline_Run(t *testing.T) {
 		})
 	}
 }
+
+type testInterruptingModule struct {
+	cancel   func()
+	aborted  *bool
+	failed   *bool
+	finished *bool
+}
+
+func (mod *testInterruptingModule) Run(cx context.Context) error {
+	shipContext, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	shipContext.OnAbort("test", func() error {
+		*mod.aborted = true
+		return nil
+	})
+
+	shipContext.OnFailure("test", func() error {
+		*mod.failed = true
+		return nil
+	})
+
+	shipContext.OnFinish("test", func() error {
+		*mod.finished = true
+		return nil
+	})
+
+	if mod.cancel == nil {
+		return errors.New("failed")
+	}
+
+	mod.cancel()
+
+	return cx.Err()
+}
+
+func TestPipeline_RunContextInterrupted(t *testing.T) {
+	cx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var aborted, failed, finished bool
+
+	modules.RegisterModule(&modules.ModuleRegistration{
+		Stage: "build",
+		Type:  "interrupt",
+		Factory: func() modules.Pluggable {
+			return &testInterruptingModule{cancel: cancel, aborted: &aborted, failed: &failed, finished: &finished}
+		},
+	})
+
+	pip, err := pipeline.LoadBuildPipeline([]byte(fmt.Sprintf(
+		"---\nsetups:\n- type: project\n  target: %s\nbuilds:\n- type: interrupt\n- type: test\n",
+		t.TempDir(),
+	)))
+	if err != nil {
+		t.Fatalf("loading pipeline: %v", err)
+	}
+
+	if err := pip.RunContext(ctx.New(cx)); !errors.Is(err, context.Canceled) {
+		t.Errorf("RunContext() error = %v, want %v", err, context.Canceled)
+	}
+
+	if !aborted {
+		t.Error("RunContext() didn't run abort handlers")
+	}
+
+	if !failed {
+		t.Error("RunContext() didn't run failure handlers")
+	}
+
+	if !finished {
+		t.Error("RunContext() didn't run cleanup handlers")
+	}
+
+	got := []string{}
+	for _, res := range pip.Results() {
+		got = append(got, fmt.Sprintf("%s:%s %s", res.Stage, res.Module, res.Status))
+	}
+
+	want := []string{
+		"setup:project completed",
+		"setup:env completed",
+		"setup:git completed",
+		"setup:skip_publish completed",
+		"build:interrupt aborted",
+		"build:test not started",
+	}
+
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Errorf("Pipeline.Results() %v", diff)
+	}
+}
+
+func TestPipeline_RunContextFailed(t *testing.T) {
+	var aborted, failed, finished bool
+
+	modules.RegisterModule(&modules.ModuleRegistration{
+		Stage: "build",
+		Type:  "fail_cleanup",
+		Factory: func() modules.Pluggable {
+			return &testInterruptingModule{aborted: &aborted, failed: &failed, finished: &finished}
+		},
+	})
+
+	pip, err := pipeline.LoadBuildPipeline([]byte(fmt.Sprintf(
+		"---\nsetups:\n- type: project\n  target: %s\nbuilds:\n- type: fail_cleanup\n",
+		t.TempDir(),
+	)))
+	if err != nil {
+		t.Fatalf("loading pipeline: %v", err)
+	}
+
+	if err := pip.RunContext(ctx.New(context.Background())); err == nil {
+		t.Error("RunContext() didn't fail")
+	}
+
+	if aborted {
+		t.Error("RunContext() ran abort handlers without interruption")
+	}
+
+	if !failed {
+		t.Error("RunContext() didn't run failure handlers")
+	}
+
+	if !finished {
+		t.Error("RunContext() didn't run cleanup handlers")
+	}
+}
+
+func TestPipeline_RunContextWhen(t *testing.T) {
+	pip, err := pipeline.LoadBuildPipeline([]byte(fmt.Sprintf(
+		"---\nsetups:\n- type: project\n  target: %s\nbuilds:\n- type: test\n  when: '{{ eq (.Env.GetOrDefault \"CHANNEL\" \"\") \"beta\" }}'\n- type: test\n  when: '{{ not .Snapshot }}'\n",
+		t.TempDir(),
+	)))
+	if err != nil {
+		t.Fatalf("loading pipeline: %v", err)
+	}
+
+	if err := pip.RunContext(ctx.New(context.Background())); err != nil {
+		t.Fatalf("RunContext() error = %v", err)
+	}
+
+	got := []string{}
+	for _, res := range pip.Results() {
+		if res.Stage == "build" {
+			got = append(got, fmt.Sprintf("%s:%s %s", res.Stage, res.Module, res.Status))
+		}
+	}
+
+	want := []string{
+		"build:test skipped",
+		"build:test completed",
+	}
+
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Errorf("Pipeline.Results() %v", diff)
+	}
+}
+
+func TestPipeline_RunContextSeed(t *testing.T) {
+	t.Setenv(ctx.SeedEnv, "42")
+
+	pip, err := pipeline.LoadBuildPipeline([]byte(fmt.Sprintf(
+		"---\nsetups:\n- type: project\n  target: %s\n",
+		t.TempDir(),
+	)))
+	if err != nil {
+		t.Fatalf("loading pipeline: %v", err)
+	}
+
+	cx := ctx.New(context.Background())
+	shipContext, _ := ctx.GetShipContext(cx)
+
+	if err := pip.RunContext(cx); err != nil {
+		t.Fatalf("RunContext() error = %v", err)
+	}
+
+	if seed := shipContext.Random.Seed(); seed != 42 {
+		t.Errorf("RunContext() seeded with %d, want 42", seed)
+	}
+}
+
+func TestPipeline_Subscribe(t *testing.T) {
+	pip, err := pipeline.LoadBuildPipeline([]byte(fmt.Sprintf(
+		"---\nsetups:\n- type: project\n  target: %s\nbuilds:\n- type: test\n  when: '{{ eq (.Env.GetOrDefault \"CHANNEL\" \"\") \"beta\" }}'\n- type: test\n",
+		t.TempDir(),
+	)))
+	if err != nil {
+		t.Fatalf("loading pipeline: %v", err)
+	}
+
+	var mu sync.Mutex
+
+	got := []string{}
+
+	pip.Subscribe(func(event ctx.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if event.Stage != "build" && event.Stage != "publish" {
+			return
+		}
+
+		got = append(got, strings.TrimSpace(fmt.Sprintf("%s %s:%s %s", event.Type, event.Stage, event.Module, event.Status)))
+	})
+
+	if err := pip.RunContext(ctx.New(context.Background())); err != nil {
+		t.Fatalf("RunContext() error = %v", err)
+	}
+
+	want := []string{
+		"stage_started build:",
+		"module_finished build:test skipped",
+		"module_started build:test",
+		"module_finished build:test completed",
+		"stage_finished build: completed",
+		"stage_started publish:",
+		"stage_finished publish: skipped",
+	}
+
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Errorf("Pipeline.Subscribe() events %v", diff)
+	}
+}
+
+type testRendezvousModule struct {
+	arrived *sync.WaitGroup
+	order   *[]string
+	mu      *sync.Mutex
+	ID      string
+}
+
+func (mod *testRendezvousModule) Run(cx context.Context) error {
+	shipContext, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	if mod.ID != "last" {
+		// both modules must be running at the same time
+		mod.arrived.Done()
+
+		waited := make(chan struct{})
+		go func() {
+			mod.arrived.Wait()
+			close(waited)
+		}()
+
+		select {
+		case <-waited:
+		case <-time.After(5 * time.Second):
+			return errors.New("modules are not running concurrently")
+		}
+	}
+
+	shipContext.Artifacts.Add(&ctx.Artifact{ID: mod.ID, Filename: mod.ID})
+
+	mod.mu.Lock()
+	*mod.order = append(*mod.order, mod.ID)
+	mod.mu.Unlock()
+
+	return nil
+}
+
+func TestPipeline_RunContextNeeds(t *testing.T) {
+	var (
+		arrived sync.WaitGroup
+		mu      sync.Mutex
+		order   []string
+	)
+
+	arrived.Add(2)
+
+	modules.RegisterModule(&modules.ModuleRegistration{
+		Stage: "build",
+		Type:  "rendezvous",
+		Factory: func() modules.Pluggable {
+			return &testRendezvousModule{arrived: &arrived, order: &order, mu: &mu}
+		},
+	})
+
+	pip, err := pipeline.LoadBuildPipeline([]byte(fmt.Sprintf(
+		"---\nsetups:\n- type: project\n  target: %s\n  parallelism: 2\nbuilds:\n"+
+			"- {type: rendezvous, id: last, needs: [first, second]}\n"+
+			"- {type: rendezvous, id: first, needs: []}\n"+
+			"- {type: rendezvous, id: second, needs: []}\n",
+		t.TempDir(),
+	)))
+	if err != nil {
+		t.Fatalf("loading pipeline: %v", err)
+	}
+
+	cx := ctx.New(context.Background())
+
+	if err := pip.RunContext(cx); err != nil {
+		t.Fatalf("RunContext() error = %v", err)
+	}
+
+	if len(order) != 3 || order[2] != "last" {
+		t.Errorf("RunContext() ran modules in %v order", order)
+	}
+
+	shipContext, _ := ctx.GetShipContext(cx)
+	if len(shipContext.Artifacts) != 3 {
+		t.Errorf("RunContext() registered %d artifacts, want 3", len(shipContext.Artifacts))
+	}
+
+	got := []string{}
+	for _, res := range pip.Results() {
+		if res.Stage == "build" {
+			got = append(got, fmt.Sprintf("%s:%s %s", res.Stage, res.Module, res.Status))
+		}
+	}
+
+	want := []string{
+		"build:rendezvous completed",
+		"build:rendezvous completed",
+		"build:rendezvous completed",
+	}
+
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Errorf("Pipeline.Results() %v", diff)
+	}
+}
+
+func TestPipeline_RunContextNeedsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{
+			name:    "unknown",
+			yaml:    "builds:\n- {type: test, needs: [missing]}\n",
+			wantErr: "module test needs unknown module missing",
+		},
+		{
+			name:    "cycle",
+			yaml:    "builds:\n- {type: test, id: a, needs: [b]}\n- {type: test, id: b, needs: [a]}\n",
+			wantErr: "dependency cycle: test -> test -> test",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			pip, err := pipeline.LoadBuildPipeline([]byte(fmt.Sprintf(
+				"---\nsetups:\n- type: project\n  target: %s\n%s",
+				t.TempDir(),
+				tt.yaml,
+			)))
+			if err != nil {
+				t.Fatalf("loading pipeline: %v", err)
+			}
+
+			err = pip.RunContext(ctx.New(context.Background()))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("RunContext() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := pipeline.LoadBuildPipeline([]byte("---\nsetups:\n- {type: project, needs: []}\n")); err == nil {
+		t.Error("LoadBuildPipeline() accepted needs in setups")
+	}
+}
+
+type testArtifactModule struct {
+	runs *int
+}
+
+func (mod *testArtifactModule) Run(cx context.Context) error {
+	shipContext, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	*mod.runs++
+
+	location := filepath.Join(shipContext.TargetDir, "app")
+	if err := os.WriteFile(location, []byte("app"), 0o644); err != nil { // nolint: gosec
+		return err
+	}
+
+	shipContext.Artifacts.Add(&ctx.Artifact{
+		ID:       "default",
+		Filename: "app",
+		Location: location,
+		OsArch:   &ctx.OsArch{OS: "linux", Arch: "arm", ArmVersion: 7},
+	})
+
+	return nil
+}
+
+func TestPipeline_RunContextResume(t *testing.T) {
+	runs := 0
+
+	modules.RegisterModule(&modules.ModuleRegistration{
+		Stage: "build",
+		Type:  "artifact",
+		Factory: func() modules.Pluggable {
+			return &testArtifactModule{runs: &runs}
+		},
+	})
+
+	config := []byte(fmt.Sprintf(
+		"---\nsetups:\n- type: project\n  target: %s\nbuilds:\n- type: artifact\n",
+		t.TempDir(),
+	))
+
+	run := func(from string, only ...string) (*pipeline.Pipeline, *ctx.Context, error) {
+		pip, err := pipeline.LoadBuildPipeline(config)
+		if err != nil {
+			t.Fatalf("loading pipeline: %v", err)
+		}
+
+		t.Setenv(pipeline.FromEnv, from)
+		t.Setenv(pipeline.StagesEnv, strings.Join(only, ","))
+
+		cx := ctx.New(context.Background())
+		shipContext, _ := ctx.GetShipContext(cx)
+
+		return pip, shipContext, pip.RunContext(cx)
+	}
+
+	if _, _, err := run("publish"); err == nil || !strings.Contains(err.Error(), "no previous run to resume") {
+		t.Errorf("RunContext() without previous run error = %v", err)
+	}
+
+	if _, _, err := run("deploy"); err == nil || !strings.Contains(err.Error(), "unknown stage") {
+		t.Errorf("RunContext() of unknown stage error = %v", err)
+	}
+
+	if _, _, err := run(""); err != nil {
+		t.Fatalf("RunContext() error = %v", err)
+	}
+
+	for _, tt := range []struct {
+		from string
+		only []string
+	}{
+		{from: "publish"},
+		{only: []string{"notify"}},
+	} {
+		pip, shipContext, err := run(tt.from, tt.only...)
+		if err != nil {
+			t.Fatalf("RunContext() resuming error = %v", err)
+		}
+
+		if runs != 1 {
+			t.Errorf("RunContext() resuming ran build %d times", runs)
+		}
+
+		if len(shipContext.Artifacts) != 1 || shipContext.Artifacts[0].OsArch.String() != "linux-armv7" {
+			t.Errorf("RunContext() resuming loaded %v artifacts", shipContext.Artifacts)
+		}
+
+		for _, res := range pip.Results() {
+			if res.Stage == "build" && res.Status != pipeline.StatusSkipped {
+				t.Errorf("RunContext() resuming has build:%s %s", res.Module, res.Status)
+			}
+		}
+	}
+}
+
+func TestLoadBuildPipeline_customStages(t *testing.T) {
+	modules.RegisterModule(&modules.ModuleRegistration{
+		Stage:   "verify",
+		Type:    "test",
+		Factory: testModuleRegistrationFactory,
+	})
+
+	pip, err := pipeline.LoadBuildPipeline([]byte(
+		"---\nverifies:\n- type: test\n- type: show\nstages:\n- name: verify\n  plural: verifies\n  after: build\n- name: sign\n",
+	))
+	if err != nil {
+		t.Fatalf("LoadBuildPipeline() error = %v", err)
+	}
+
+	got := []string{}
+	for _, stg := range pip.Stages {
+		got = append(got, fmt.Sprintf("%s:%d", stg.Plural, len(stg.Modules)))
+	}
+
+	want := []string{"setups:4", "builds:0", "verifies:2", "publishes:0", "notifications:0", "sign:0"}
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Errorf("LoadBuildPipeline() stages %v", diff)
+	}
+
+	for _, yml := range []string{
+		"---\nstages:\n- name: verify\n  after: deploy\n",
+		"---\nstages:\n- name: build\n",
+		"---\nstages:\n- plural: verifies\n",
+		"---\nstages:\n- name: sign\nsign:\n- type: unknown\n",
+	} {
+		if _, err := pipeline.LoadBuildPipeline([]byte(yml)); err == nil {
+			t.Errorf("LoadBuildPipeline(%q) succeeded", yml)
+		}
+	}
+}
+
+func TestLoadBuildPipeline_signingStages(t *testing.T) {
+	pip, err := pipeline.LoadBuildPipeline([]byte(
+		"---\nstages:\n- name: sign\n  plural: signs\n  after: build\n- name: verify\n  plural: verifies\n  after: sign\n" +
+			"signs:\n- type: cosign\n- type: fake\n- type: minisign\nverifies:\n- type: signatures\n",
+	))
+	if err != nil {
+		t.Fatalf("LoadBuildPipeline() error = %v", err)
+	}
+
+	got := []string{}
+	for _, name := range []string{"sign", "verify"} {
+		for _, mod := range pip.StageByName(name).Modules {
+			got = append(got, name+":"+mod.Type)
+		}
+	}
+
+	want := []string{"sign:cosign", "sign:fake", "sign:minisign", "verify:signatures"}
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Errorf("LoadBuildPipeline() modules %v", diff)
+	}
+}
+
+func TestLoadBuildPipeline_plugins(t *testing.T) {
+	pip, err := pipeline.LoadBuildPipeline([]byte(
+		"---\nbuilds:\n- type: notarize\n  config:\n    team: ABC\nplugins:\n- name: notarize\n  command: ./notarize\n  version: \">=1.0.0\"\n",
+	))
+	if err != nil {
+		t.Fatalf("LoadBuildPipeline() error = %v", err)
+	}
+
+	if mods := pip.StageByName("build").Modules; len(mods) != 1 || mods[0].Type != "notarize" {
+		t.Errorf("LoadBuildPipeline() builds %v, want notarize plugin", mods)
+	}
+
+	for _, yml := range []string{
+		"---\nplugins:\n- name: notarize\n",
+		"---\nplugins:\n- name: notarize\n  command: ./notarize\n  checksum: sha256\n",
+		"---\nstages:\n- name: plugins\n",
+	} {
+		if _, err := pipeline.LoadBuildPipeline([]byte(yml)); err == nil {
+			t.Errorf("LoadBuildPipeline(%q) succeeded", yml)
+		}
+	}
+}
+
+type testDeprecatedModule struct {
+	Output string
+}
+
+func (*testDeprecatedModule) Run(context.Context) error {
+	return nil
+}
+
+func (*testDeprecatedModule) Deprecations() []modules.Deprecation {
+	return []modules.Deprecation{{Old: "target", New: "output"}}
+}
+
+func TestLoadBuildPipeline_deprecations(t *testing.T) {
+	modules.RegisterModule(&modules.ModuleRegistration{
+		Stage:   "build",
+		Type:    "deprecated",
+		Factory: func() modules.Pluggable { return &testDeprecatedModule{} },
+	})
+
+	pip, err := pipeline.LoadBuildPipeline([]byte("---\nbuilds:\n- type: deprecated\n  target: out\n"))
+	if err != nil {
+		t.Fatalf("LoadBuildPipeline() error = %v", err)
+	}
+
+	if mod := pip.StageByName("build").Modules[0].Pluggable.(*testDeprecatedModule); mod.Output != "out" {
+		t.Errorf("LoadBuildPipeline() output = %q, want %q", mod.Output, "out")
+	}
+
+	want := []modules.DeprecationWarning{{Module: "build:deprecated", Old: "target", New: "output", Line: 4, Column: 3}}
+
+	if diff := deep.Equal(pip.Deprecations(), want); diff != nil {
+		t.Errorf("Pipeline.Deprecations() %v", diff)
+	}
+}
+
+func TestLoadBuildPipeline_schema(t *testing.T) {
+	tests := []struct {
+		name string
+		yml  string
+		want string
+	}{
+		{
+			name: "unknown module key",
+			yml:  "---\nbuilds:\n- type: tar\n  compresion: gz\n",
+			want: "decoding build stage: unknown key `compresion` in build:tar at line 4, column 3",
+		},
+		{
+			name: "invalid common option",
+			yml:  "---\npublishes:\n- type: show\n  retries: often\n",
+			want: "decoding publish stage: `retries` must be an integer in publish:show at line 4, column 12",
+		},
+		{
+			name: "strict module",
+			yml:  "---\npublishes:\n- type: show\n  strict: true\n  color: red\n",
+			want: "decoding publish stage: unknown key `color` in publish:show at line 5, column 3",
+		},
+		{
+			name: "lenient module",
+			yml:  "---\npublishes:\n- type: show\n  strict: false\n  color: red\n",
+		},
+		{
+			name: "lenient module with invalid value",
+			yml:  "---\npublishes:\n- type: show\n  strict: false\n  color: red\n  timeout: [1m]\n",
+			want: "decoding publish stage: `timeout` must be a string in publish:show at line 6, column 12",
+		},
+		{
+			name: "unknown stage",
+			yml:  "---\nbulds:\n- type: tar\n",
+			want: "unknown key `bulds` at line 2, column 1",
+		},
+		{
+			name: "unknown plugin key",
+			yml:  "---\nplugins:\n- name: notarize\n  cmd: ./notarize\n",
+			want: "unknown key `cmd` at line 4, column 3",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := pipeline.LoadBuildPipeline([]byte(tt.yml))
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("LoadBuildPipeline() error = %v", err)
+				}
+
+				return
+			}
+
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("LoadBuildPipeline() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPipeline_Schema(t *testing.T) {
+	pip, err := pipeline.LoadBuildPipeline(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema := pip.Schema()
+
+	builds, ok := schema.Properties["builds"]
+	if !ok {
+		t.Fatalf("Schema() has no builds")
+	}
+
+	for _, mod := range builds.Items.OneOf {
+		if mod.Properties["type"].Const != "tar" {
+			continue
+		}
+
+		for _, key := range []string{"compression", "needs", "type", "when"} {
+			if _, ok := mod.Properties[key]; !ok {
+				t.Errorf("Schema() of build:tar has no %s", key)
+			}
+		}
+
+		return
+	}
+
+	t.Errorf("Schema() has no build:tar")
+}
+
+func TestPipeline_RunContextOnError(t *testing.T) {
+	modules.RegisterModule(&modules.ModuleRegistration{
+		Stage:   "build",
+		Type:    "failure",
+		Factory: testFailingModuleRegistrationFactory,
+	})
+
+	pip, err := pipeline.LoadBuildPipeline([]byte(fmt.Sprintf(
+		"---\nsetups:\n- type: project\n  target: %s\nbuilds:\n- {type: failure, on_error: continue}\n- type: test\n- {type: failure, on_error: continue}\n",
+		t.TempDir(),
+	)))
+	if err != nil {
+		t.Fatalf("loading pipeline: %v", err)
+	}
+
+	err = pip.RunContext(ctx.New(context.Background()))
+
+	var errs pipeline.ModuleErrors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("RunContext() error = %v, want 2 module errors", err)
+	}
+
+	got := []string{}
+	for _, res := range pip.Results() {
+		if res.Stage == "build" {
+			got = append(got, fmt.Sprintf("%s:%s %s", res.Stage, res.Module, res.Status))
+		}
+	}
+
+	want := []string{
+		"build:failure failed",
+		"build:test completed",
+		"build:failure failed",
+	}
+
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Errorf("Pipeline.Results() %v", diff)
+	}
+
+	if _, err := pipeline.LoadBuildPipeline([]byte("---\nbuilds:\n- {type: test, on_error: ignore}\n")); err == nil {
+		t.Error("LoadBuildPipeline() accepted invalid on_error")
+	}
+}
+
+func TestPipeline_RunContextOnErrorNeeds(t *testing.T) {
+	for _, typ := range []string{"test", "failure"} {
+		factory := testModuleRegistrationFactory
+		if typ == "failure" {
+			factory = testFailingModuleRegistrationFactory
+		}
+
+		modules.RegisterModule(&modules.ModuleRegistration{Stage: "build", Type: typ, Factory: factory})
+	}
+
+	pip, err := pipeline.LoadBuildPipeline([]byte(fmt.Sprintf(
+		"---\nsetups:\n- type: project\n  target: %s\nbuilds:\n"+
+			"- {type: test, id: transitive, needs: [dependent]}\n"+
+			"- {type: test, id: dependent, needs: [broken]}\n"+
+			"- {type: failure, id: broken, needs: [], on_error: continue}\n"+
+			"- {type: test, id: independent, needs: []}\n",
+		t.TempDir(),
+	)))
+	if err != nil {
+		t.Fatalf("loading pipeline: %v", err)
+	}
+
+	err = pip.RunContext(ctx.New(context.Background()))
+
+	var errs pipeline.ModuleErrors
+	if !errors.As(err, &errs) || len(errs) != 1 {
+		t.Fatalf("RunContext() error = %v, want 1 module error", err)
+	}
+
+	got := []string{}
+	for _, res := range pip.Results() {
+		if res.Stage == "build" {
+			got = append(got, fmt.Sprintf("%s:%s %s", res.Stage, res.Module, res.Status))
+		}
+	}
+
+	want := []string{
+		"build:test skipped",
+		"build:test skipped",
+		"build:failure failed",
+		"build:test completed",
+	}
+
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Errorf("Pipeline.Results() %v", diff)
+	}
+}
+
+func TestPipeline_Graph(t *testing.T) {
+	pip, err := pipeline.LoadBuildPipeline([]byte(`builds:
+- type: go
+- type: tar
+  id: linux-tarball
+- type: checksum
+  builds: [linux-tarball]
+  needs: [tar]
+`))
+	if err != nil {
+		t.Fatalf("LoadBuildPipeline() error = %v", err)
+	}
+
+	tests := []struct {
+		format  string
+		want    []string
+		wantErr string
+	}{
+		{
+			format: pipeline.GraphDOT,
+			want: []string{
+				`m1_1 [label="build:tar (linux-tarball)"];`,
+				`m0_3 -> m1_0 [ltail=cluster_0, lhead=cluster_1, style=bold];`,
+				"m1_1 -> m1_2;",
+				`m1_1 -> m1_2 [label="linux-tarball", style=dashed];`,
+			},
+		},
+		{
+			format: pipeline.GraphMermaid,
+			want: []string{
+				`m1_1["build:tar (linux-tarball)"]`,
+				"s0 ==> s1",
+				"m1_1 --> m1_2",
+				"m1_1 -.->|linux-tarball| m1_2",
+			},
+		},
+		{format: "svg", wantErr: `unknown graph format: "svg"`},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.format, func(t *testing.T) {
+			got, err := pip.Graph(tt.format)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Graph() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Graph() error = %v", err)
+			}
+
+			for _, line := range tt.want {
+				if !strings.Contains(got, line) {
+					t.Errorf("Graph() doesn't contain %q:\n%s", line, got)
+				}
+			}
+		})
+	}
+}
+
+func TestPipeline_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr []string
+	}{
+		{
+			name: "valid",
+			config: `builds:
+- type: go
+  ldflags: -X main.version={{.Version}}
+- type: tar
+  id: linux-tarball
+- type: checksum
+  builds: [linux-tarball]
+  when: '{{ not .Snapshot }}'
+`,
+		},
+		{
+			name: "broken templates",
+			config: `builds:
+- type: go
+  ldflags: -X main.version={{.Versoin}}
+- type: tar
+  output: '{{ Unknown }}'
+  when: '{{ if }}'
+`,
+			wantErr: []string{
+				"3 problem(s) found",
+				`build:go: template: ldflags:1:18: executing "ldflags" at <.Versoin>: can't evaluate field Versoin`,
+				`build:tar: template: output:1: function "Unknown" not defined`,
+				"build:tar: template: when:1: missing value for if",
+			},
+		},
+		{
+			name: "unknown builds",
+			config: `builds:
+- type: checksum
+  builds: [linux-tarball]
+- type: tar
+  id: linux-tarball
+`,
+			wantErr: []string{`build:checksum: build "linux-tarball" is not produced by earlier modules`},
+		},
+		{
+			name: "unknown needs",
+			config: `publishes:
+- type: show
+  needs: [upload]
+`,
+			wantErr: []string{"stage publish: module show needs unknown module upload"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			pip, err := pipeline.LoadBuildPipeline([]byte(tt.config))
+			if err != nil {
+				t.Fatalf("LoadBuildPipeline() error = %v", err)
+			}
+
+			err = pip.Validate()
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+
+				return
+			}
+
+			if err == nil {
+				t.Fatal("Validate() returned no error")
+			}
+
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() error = %v, want %q", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// stagesKey is the YAML key of custom stage declarations
+	stagesKey = "stages"
+	// pluginsKey is the YAML key of gRPC plugin declarations
+	pluginsKey = "plugins"
+)
+
+// StageDefinition declares a custom stage (like `sign`, or `verify`).
+// Modules can be registered for its name, like for built-in stages.
+type StageDefinition struct {
+	// After is the name of the stage the custom stage runs after.
+	// Default: the last stage.
+	After string
+	// Name is the stage's name. Required.
+	Name string
+	// Plural is the YAML key listing the stage's modules. Default: Name.
+	Plural string
+	// PublishOnly stages are skipped unless publishing, like publish.
+	PublishOnly bool `yaml:"publish_only"`
+}
+
+// AddStage adds a custom stage into the pipeline
+func (pip *Pipeline) AddStage(def *StageDefinition) error {
+	if def.Name == "" {
+		return errors.New("stage name is not specified")
+	}
+
+	stage := NewStage(def.Name, def.Plural)
+	if stage.Plural == "" {
+		stage.Plural = def.Name
+	}
+
+	if stage.Plural == stagesKey || stage.Plural == pluginsKey {
+		return fmt.Errorf("stage %s: %s is reserved", def.Name, stage.Plural)
+	}
+
+	if def.PublishOnly {
+		stage.SkipFN = skipUnlessPublishing
+	}
+
+	pos := -1
+
+	for idx, stg := range pip.Stages {
+		if stg.Name == stage.Name || stg.Plural == stage.Plural {
+			return fmt.Errorf("stage %s (%s) is already defined", stage.Name, stage.Plural)
+		}
+
+		if stg.Name == def.After {
+			pos = idx + 1
+		}
+	}
+
+	if def.After == "" {
+		pos = len(pip.Stages)
+	} else if pos < 0 {
+		return fmt.Errorf("stage %s: unknown stage %s to run after", def.Name, def.After)
+	}
+
+	pip.Stages = append(pip.Stages[:pos], append([]*Stage{stage}, pip.Stages[pos:]...)...)
+
+	return nil
+}
@@ -0,0 +1,31 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ModuleErrors are errors of modules continuing on error (see
+// modules.OnErrorContinue), failing the pipeline at the end
+type ModuleErrors []error
+
+func (errs ModuleErrors) Error() string {
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+
+	return fmt.Sprintf("%d module(s) failed: %s", len(errs), strings.Join(msgs, "; "))
+}
+
+// ValidationErrors are problems of a pipeline found by Pipeline.Validate
+type ValidationErrors []error
+
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+
+	return fmt.Sprintf("%d problem(s) found: %s", len(errs), strings.Join(msgs, "; "))
+}
@@ -0,0 +1,221 @@
+package pipeline
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/julian7/goshipdone/modules"
+)
+
+const (
+	// GraphDOT is the Graphviz DOT format of Graph
+	GraphDOT = "dot"
+	// GraphMermaid is the Mermaid flowchart format of Graph
+	GraphMermaid = "mermaid"
+)
+
+type (
+	// graphNode is a module in the graph
+	graphNode struct {
+		name  string
+		label string
+	}
+
+	// graphEdge is a dependency, or an artifact flow between modules
+	graphEdge struct {
+		from, to string
+		// artifact is the ID of artifacts flowing between modules, or
+		// empty for dependencies
+		artifact string
+	}
+
+	// graphStage is a stage with its modules
+	graphStage struct {
+		name  string
+		nodes []graphNode
+	}
+)
+
+// Graph renders the loaded pipeline as a graph in GraphDOT, or
+// GraphMermaid format, without running it. Stages are drawn as clusters
+// of their modules in order, dependencies between modules (see `needs`)
+// as solid, and artifacts flowing from modules to later ones taking them
+// in `builds` as dashed arrows, labeled by artifact IDs.
+func (pip *Pipeline) Graph(format string) (string, error) {
+	stages := make([]graphStage, 0, len(pip.Stages))
+	edges := []graphEdge{}
+	producers := map[string][]string{}
+
+	for sIdx, stg := range pip.Stages {
+		deps, err := stg.dependencies()
+		if err != nil {
+			return "", fmt.Errorf("stage %s: %w", stg.Name, err)
+		}
+
+		stage := graphStage{name: stg.Name}
+
+		for mIdx, module := range stg.Modules {
+			name := fmt.Sprintf("m%d_%d", sIdx, mIdx)
+			label := stg.Name + ":" + module.Type
+
+			if module.ID != "" {
+				label += " (" + module.ID + ")"
+			}
+
+			stage.nodes = append(stage.nodes, graphNode{name: name, label: label})
+
+			for _, dep := range deps[mIdx] {
+				edges = append(edges, graphEdge{from: fmt.Sprintf("m%d_%d", sIdx, dep), to: name})
+			}
+
+			produces, consumes := artifactFlow(module)
+
+			for _, id := range consumes {
+				for _, producer := range producers[id] {
+					edges = append(edges, graphEdge{from: producer, to: name, artifact: id})
+				}
+			}
+
+			for _, id := range produces {
+				producers[id] = append(producers[id], name)
+			}
+		}
+
+		stages = append(stages, stage)
+	}
+
+	switch format {
+	case GraphDOT:
+		return renderDOT(stages, edges), nil
+	case GraphMermaid:
+		return renderMermaid(stages, edges), nil
+	default:
+		return "", fmt.Errorf("unknown graph format: %q", format)
+	}
+}
+
+// artifactFlow returns artifact IDs a module produces (its `id` option,
+// and other options ending with ID, like `certificate_id`), and artifact
+// IDs it takes (its `builds` option)
+func artifactFlow(module *modules.Module) ([]string, []string) {
+	produces := []string{}
+	consumes := []string{}
+
+	value := reflect.Indirect(reflect.ValueOf(modules.Config(module.Pluggable)))
+	if value.Kind() == reflect.Struct {
+		typ := value.Type()
+
+		for i := 0; i < typ.NumField(); i++ {
+			field := value.Field(i)
+			if !strings.HasSuffix(typ.Field(i).Name, "ID") || field.Kind() != reflect.String || field.String() == "" {
+				continue
+			}
+
+			produces = append(produces, field.String())
+		}
+
+		if field := value.FieldByName("Builds"); field.IsValid() {
+			if builds, ok := field.Interface().([]string); ok {
+				consumes = builds
+			}
+		}
+	}
+
+	if module.ID != "" && len(produces) == 0 {
+		produces = append(produces, module.ID)
+	}
+
+	return produces, consumes
+}
+
+// renderDOT renders a graph in Graphviz DOT format
+func renderDOT(stages []graphStage, edges []graphEdge) string {
+	var out strings.Builder
+
+	out.WriteString("digraph pipeline {\n  compound=true;\n  rankdir=LR;\n  node [shape=box];\n")
+
+	for idx, stage := range stages {
+		fmt.Fprintf(&out, "  subgraph cluster_%d {\n    label=%q;\n", idx, stage.name)
+
+		if len(stage.nodes) == 0 {
+			fmt.Fprintf(&out, "    s%d [label=%q, shape=plaintext];\n", idx, "(empty)")
+		}
+
+		for _, node := range stage.nodes {
+			fmt.Fprintf(&out, "    %s [label=%q];\n", node.name, node.label)
+		}
+
+		out.WriteString("  }\n")
+	}
+
+	for idx := 1; idx < len(stages); idx++ {
+		fmt.Fprintf(
+			&out,
+			"  %s -> %s [ltail=cluster_%d, lhead=cluster_%d, style=bold];\n",
+			dotAnchor(stages, idx-1, false), dotAnchor(stages, idx, true), idx-1, idx,
+		)
+	}
+
+	for _, edge := range edges {
+		if edge.artifact != "" {
+			fmt.Fprintf(&out, "  %s -> %s [label=%q, style=dashed];\n", edge.from, edge.to, edge.artifact)
+
+			continue
+		}
+
+		fmt.Fprintf(&out, "  %s -> %s;\n", edge.from, edge.to)
+	}
+
+	out.WriteString("}\n")
+
+	return out.String()
+}
+
+// dotAnchor returns the node of a stage's cluster, which edges between
+// stages are attached to
+func dotAnchor(stages []graphStage, idx int, first bool) string {
+	nodes := stages[idx].nodes
+	if len(nodes) == 0 {
+		return fmt.Sprintf("s%d", idx)
+	}
+
+	if first {
+		return nodes[0].name
+	}
+
+	return nodes[len(nodes)-1].name
+}
+
+// renderMermaid renders a graph as a Mermaid flowchart
+func renderMermaid(stages []graphStage, edges []graphEdge) string {
+	var out strings.Builder
+
+	out.WriteString("flowchart LR\n")
+
+	for idx, stage := range stages {
+		fmt.Fprintf(&out, "  subgraph s%d [%q]\n", idx, stage.name)
+
+		for _, node := range stage.nodes {
+			fmt.Fprintf(&out, "    %s[%q]\n", node.name, node.label)
+		}
+
+		out.WriteString("  end\n")
+	}
+
+	for idx := 1; idx < len(stages); idx++ {
+		fmt.Fprintf(&out, "  s%d ==> s%d\n", idx-1, idx)
+	}
+
+	for _, edge := range edges {
+		if edge.artifact != "" {
+			fmt.Fprintf(&out, "  %s -.->|%s| %s\n", edge.from, edge.artifact, edge.to)
+
+			continue
+		}
+
+		fmt.Fprintf(&out, "  %s --> %s\n", edge.from, edge.to)
+	}
+
+	return out.String()
+}
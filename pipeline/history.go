@@ -0,0 +1,174 @@
+package pipeline
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/julian7/goshipdone/ctx"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// HistoryDir is the directory under the target directory, where
+	// per-run logs, resolved configuration, and reports are kept
+	HistoryDir = ctx.HistoryDir
+	// HistoryLogFilename is the log file name of a run in history
+	HistoryLogFilename = "run.log"
+	// HistoryConfigFilename is the resolved configuration's file name of a
+	// run in history
+	HistoryConfigFilename = "config.yml"
+)
+
+// Config returns the pipeline's resolved configuration in YAML format:
+// each module's options with defaults applied, and secrets redacted.
+func (pip *Pipeline) Config() ([]byte, error) {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+
+	for _, stg := range pip.Stages {
+		mods := &yaml.Node{Kind: yaml.SequenceNode}
+
+		for _, mod := range stg.Modules {
+			item := &yaml.Node{Kind: yaml.MappingNode}
+			item.Content = append(item.Content, scalarNode("type"), scalarNode(mod.Type))
+
+			options, err := mod.OptionsNode()
+			if err != nil {
+				return nil, fmt.Errorf("%s:%s: %w", stg.Name, mod.Type, err)
+			}
+
+			if options.Kind == yaml.MappingNode {
+				item.Content = append(item.Content, options.Content...)
+			}
+
+			if mod.Needs != nil {
+				needs := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+				for _, name := range mod.Needs {
+					needs.Content = append(needs.Content, scalarNode(name))
+				}
+
+				item.Content = append(item.Content, scalarNode("needs"), needs)
+			}
+
+			if mod.OnError != "" {
+				item.Content = append(item.Content, scalarNode("on_error"), scalarNode(mod.OnError))
+			}
+
+			if mod.Retries > 0 {
+				item.Content = append(item.Content, scalarNode("retries"), scalarNode(strconv.Itoa(mod.Retries)))
+			}
+
+			if mod.RetryBackoff > 0 {
+				item.Content = append(item.Content, scalarNode("retry_backoff"), scalarNode(mod.RetryBackoff.String()))
+			}
+
+			if mod.Timeout > 0 {
+				item.Content = append(item.Content, scalarNode("timeout"), scalarNode(mod.Timeout.String()))
+			}
+
+			if mod.When != "" {
+				item.Content = append(item.Content, scalarNode("when"), scalarNode(mod.When))
+			}
+
+			mods.Content = append(mods.Content, item)
+		}
+
+		root.Content = append(root.Content, scalarNode(stg.Plural), mods)
+	}
+
+	return yaml.Marshal(root)
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+}
+
+// history records log output of a pipeline run
+type history struct {
+	buf  bytes.Buffer
+	prev io.Writer
+}
+
+// startHistory starts recording log output, while keeping the original
+// output
+func startHistory() *history {
+	hist := &history{prev: log.Writer()}
+	log.SetOutput(io.MultiWriter(hist.prev, &hist.buf))
+
+	return hist
+}
+
+// stop stops recording log output
+func (hist *history) stop() {
+	log.SetOutput(hist.prev)
+}
+
+// write saves log output, resolved config, and report of a run under
+// TargetDir/.runs/<timestamp>/, and removes old runs, keeping the last
+// ctx.Context.History runs.
+func (hist *history) write(pip *Pipeline, context *ctx.Context, report *Report) error {
+	if context.TargetDir == "" || context.History <= 0 {
+		return nil
+	}
+
+	base := filepath.Join(context.TargetDir, HistoryDir)
+	dir := filepath.Join(base, context.StartedAt.Format("20060102-150405"))
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating history directory: %w", err)
+	}
+
+	config, err := pip.Config()
+	if err != nil {
+		return fmt.Errorf("resolving config: %w", err)
+	}
+
+	for name, content := range map[string][]byte{
+		HistoryLogFilename:    hist.buf.Bytes(),
+		HistoryConfigFilename: config,
+	} {
+		fn := filepath.Join(dir, name)
+		if err := os.WriteFile(fn, content, 0o644); err != nil { // nolint: gosec
+			return fmt.Errorf("writing %s: %w", fn, err)
+		}
+	}
+
+	if err := report.Write(dir); err != nil {
+		return err
+	}
+
+	return pruneHistory(base, context.History)
+}
+
+// pruneHistory removes the oldest runs from history, keeping keep runs
+func pruneHistory(base string, keep int) error {
+	entries, err := os.ReadDir(base)
+	if err != nil {
+		return fmt.Errorf("reading history: %w", err)
+	}
+
+	runs := []string{}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			runs = append(runs, entry.Name())
+		}
+	}
+
+	sort.Strings(runs)
+
+	for len(runs) > keep {
+		if err := os.RemoveAll(filepath.Join(base, runs[0])); err != nil {
+			return fmt.Errorf("removing old run: %w", err)
+		}
+
+		runs = runs[1:]
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package pipeline_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/pipeline"
+)
+
+func TestPipeline_RunContextHistory(t *testing.T) {
+	target := t.TempDir()
+	yml := fmt.Sprintf("---\nsetups:\n- type: project\n  name: hist\n  target: %s\n  history: 2\n", target)
+	started := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		pip, err := pipeline.LoadBuildPipeline([]byte(yml))
+		if err != nil {
+			t.Fatalf("loading pipeline: %v", err)
+		}
+
+		cx := ctx.New(context.Background())
+
+		shipContext, err := ctx.GetShipContext(cx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		shipContext.StartedAt = started.Add(time.Duration(i) * time.Minute)
+
+		if err := pip.RunContext(cx); err != nil {
+			t.Fatalf("run #%d: %v", i, err)
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Join(target, pipeline.HistoryDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runs := []string{}
+	for _, entry := range entries {
+		runs = append(runs, entry.Name())
+	}
+
+	if diff := deep.Equal(runs, []string{"20200102-030505", "20200102-030605"}); diff != nil {
+		t.Errorf("history runs %v", diff)
+	}
+
+	last := filepath.Join(target, pipeline.HistoryDir, "20200102-030605")
+
+	config, err := os.ReadFile(filepath.Join(last, pipeline.HistoryConfigFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(config), "name: hist") {
+		t.Errorf("resolved config doesn't contain project name:\n%s", config)
+	}
+
+	runLog, err := os.ReadFile(filepath.Join(last, pipeline.HistoryLogFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(runLog), "----> project") {
+		t.Errorf("run log doesn't contain module logs:\n%s", runLog)
+	}
+
+	if _, err := os.Stat(filepath.Join(last, pipeline.ReportFilename)); err != nil {
+		t.Errorf("report not found in history: %v", err)
+	}
+}
@@ -6,15 +6,34 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
 
 	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+	"github.com/julian7/goshipdone/plugin"
+	"github.com/magefile/mage/mg"
 	"gopkg.in/yaml.v3"
 )
 
 // Pipeline is a generic pipeline, with a registry and stages configured.
 type Pipeline struct {
+	// From is the name of the stage the run is resumed from. Earlier
+	// stages (except setups) are skipped, and their artifacts are loaded
+	// from the previous run's state. Default: GOSHIPDONE_FROM environment
+	// variable.
+	From string
+	// Only lists names of stages selected for the run. Other stages
+	// (except setups) are skipped. Default: GOSHIPDONE_STAGES environment
+	// variable (comma-separated), or all stages.
+	Only   []string
 	Stages []*Stage
+	// subscribers are called with events of runs (see Subscribe)
+	subscribers []func(ctx.Event)
 }
 
 func New(stages []*Stage) *Pipeline {
@@ -24,13 +43,50 @@ func New(stages []*Stage) *Pipeline {
 	return pip
 }
 
-// UnmarshalYAML parses YAML node to load its modules
+// UnmarshalYAML parses YAML node to load its modules. Custom stages
+// declared in `stages`, and gRPC plugins declared in `plugins` are added
+// before loading modules.
 func (pip *Pipeline) UnmarshalYAML(node *yaml.Node) error {
 	if node.Kind != yaml.MappingNode {
 		return errors.New("pipeline definition is not a map")
 	}
 
 	l := len(node.Content)
+	for i := 0; i < l; i += 2 {
+		switch node.Content[i].Value {
+		case stagesKey:
+			if errs := modules.SchemaOf([]*StageDefinition{}).Validate(node.Content[i+1]); errs != nil {
+				return errs
+			}
+
+			defs := []*StageDefinition{}
+			if err := node.Content[i+1].Decode(&defs); err != nil {
+				return fmt.Errorf("decoding stages: %w", err)
+			}
+
+			for _, def := range defs {
+				if err := pip.AddStage(def); err != nil {
+					return err
+				}
+			}
+		case pluginsKey:
+			if errs := modules.SchemaOf([]*plugin.Definition{}).Validate(node.Content[i+1]); errs != nil {
+				return errs
+			}
+
+			defs := []*plugin.Definition{}
+			if err := node.Content[i+1].Decode(&defs); err != nil {
+				return fmt.Errorf("decoding plugins: %w", err)
+			}
+
+			for _, def := range defs {
+				if err := def.Register(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
 	for i := 0; i < l; i += 2 {
 		var stage *Stage
 
@@ -43,7 +99,15 @@ func (pip *Pipeline) UnmarshalYAML(node *yaml.Node) error {
 		}
 
 		if stage == nil {
-			continue
+			if stageDefName == stagesKey || stageDefName == pluginsKey {
+				continue
+			}
+
+			return &modules.SchemaError{
+				Column:  node.Content[i].Column,
+				Line:    node.Content[i].Line,
+				Message: fmt.Sprintf("unknown key `%s`", stageDefName),
+			}
 		}
 
 		if err := node.Content[i+1].Decode(stage); err != nil {
@@ -70,6 +134,26 @@ func (pip *Pipeline) LoadDefault(kind string) error {
 	return nil
 }
 
+// Subscribe registers a function called with every event of later runs:
+// stages, and modules starting, and finishing, artifacts produced, and
+// bytes transferred (see ctx.Event). It allows embedders to show progress,
+// or collect metrics without parsing logs.
+func (pip *Pipeline) Subscribe(fn func(ctx.Event)) {
+	pip.subscribers = append(pip.subscribers, fn)
+}
+
+// Deprecations returns deprecated options found in the configuration of
+// all stages
+func (pip *Pipeline) Deprecations() []modules.DeprecationWarning {
+	warnings := []modules.DeprecationWarning{}
+
+	for _, stg := range pip.Stages {
+		warnings = append(warnings, stg.Deprecations...)
+	}
+
+	return warnings
+}
+
 func (pip *Pipeline) StageByName(name string) *Stage {
 	for _, stage := range pip.Stages {
 		if stage.Name == name {
@@ -81,15 +165,193 @@ func (pip *Pipeline) StageByName(name string) *Stage {
 }
 
 // Run executes build pipeline, calling Run on all
-// Modules
+// Modules. Verbose logging is turned on by magefile's verbose
+// flag (MAGEFILE_VERBOSE environment variable).
+//
+// SIGINT and SIGTERM signals interrupt the pipeline: the running module's
+// context is canceled, rollback handlers are called (see
+// ctx.Context.OnAbort), and a summary of completed and aborted modules is
+// logged. A second signal terminates the process immediately. Cleanup
+// handlers of partial results (see ctx.Context.OnFailure) run if the run
+// fails, or it is interrupted, and other cleanup handlers (see
+// ctx.Context.OnFinish) run at the end of every run.
 func (pip *Pipeline) Run() error {
-	ctx := ctx.New(context.Background())
+	cx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-cx.Done()
+		stop()
+	}()
+
+	return pip.RunContext(ctx.New(cx))
+}
+
+// RunContext executes build pipeline with a context already containing
+// ship context (see ctx.New). It allows callers to inspect ship context's
+// results after the run.
+func (pip *Pipeline) RunContext(cx context.Context) error {
+	shipContext, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	shipContext.Verbose = mg.Verbose()
+
+	for _, fn := range pip.subscribers {
+		shipContext.Events.Subscribe(fn)
+	}
+
+	if seed, ok := os.LookupEnv(ctx.SeedEnv); ok {
+		n, err := strconv.ParseInt(seed, 10, 64)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", ctx.SeedEnv, err)
+		}
+
+		shipContext.Random = ctx.NewRandom(n)
+	}
+
+	if pip.From == "" {
+		pip.From = os.Getenv(FromEnv)
+	}
+
+	if stages := os.Getenv(StagesEnv); len(pip.Only) == 0 && stages != "" {
+		pip.Only = strings.Split(stages, ",")
+	}
+
+	for _, stg := range pip.Stages {
+		stg.Results = nil
+	}
+
+	hist := startHistory()
+	defer hist.stop()
+
+	defer func() {
+		if err := shipContext.UnlockTargetDir(); err != nil {
+			log.Printf("cannot release lock: %v", err)
+		}
+	}()
+
+	err = pip.runStages(cx)
+
+	if cx.Err() != nil {
+		log.Printf("interrupted: %v", cx.Err())
+		shipContext.Abort()
+	}
+
+	if err != nil || cx.Err() != nil {
+		shipContext.Fail()
+	}
+
+	shipContext.Finish()
+
+	report := NewReport(shipContext, pip.Results())
+	report.Deprecations = pip.Deprecations()
+
+	if err != nil {
+		report.Error = err.Error()
+	}
+
+	report.Log()
+
+	if reportErr := report.Write(shipContext.TargetDir); reportErr != nil && err == nil {
+		err = reportErr
+	}
+
+	hist.stop()
+
+	if histErr := hist.write(pip, shipContext, report); histErr != nil {
+		log.Printf("cannot save run history: %v", histErr)
+	}
+
+	return err
+}
+
+// runStages runs selected stages, persisting the pipeline's state, and the
+// artifact manifest after each of them. If earlier stages are skipped,
+// their artifacts are loaded from the previous run's state after setups.
+// Errors of modules continuing on error are returned as ModuleErrors at
+// the end.
+func (pip *Pipeline) runStages(cx context.Context) error {
+	shipContext, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	selected, err := pip.selectedStages()
+	if err != nil {
+		return err
+	}
+
+	required := []string{}
+	current := &state{}
+
+	for idx, stg := range pip.Stages {
+		if !selected[stg.Name] && pip.selectedAfter(selected, idx) {
+			required = append(required, stg.Name)
+		}
+	}
+
+	shipContext.Resumed = len(required) > 0
 
 	for _, stg := range pip.Stages {
-		if err := stg.Run(ctx); err != nil {
+		if !selected[stg.Name] {
+			stg.Skip()
+			stg.emit(cx, ctx.Event{Type: ctx.EventStageFinished, Status: string(StatusSkipped)})
+
+			continue
+		}
+
+		if err := stg.Run(cx); err != nil {
+			return err
+		}
+
+		if len(required) > 0 {
+			prev, err := loadState(shipContext, required)
+			if err != nil {
+				return err
+			}
+
+			current.Stages = prev.Stages
+			required = nil
+		}
+
+		current.complete(stg.Name)
+
+		if err := current.write(shipContext); err != nil {
+			return err
+		}
+
+		if err := shipContext.WriteManifest(); err != nil {
 			return err
 		}
 	}
 
+	errs := ModuleErrors{}
+	for _, stg := range pip.Stages {
+		errs = append(errs, stg.Errors...)
+	}
+
+	if len(errs) > 0 {
+		log.Printf("failed modules:")
+
+		for _, err := range errs {
+			log.Printf("- %v", err)
+		}
+
+		return errs
+	}
+
 	return nil
 }
+
+// selectedAfter returns true if any stage after idx is selected
+func (pip *Pipeline) selectedAfter(selected map[string]bool, idx int) bool {
+	for _, stg := range pip.Stages[idx+1:] {
+		if selected[stg.Name] {
+			return true
+		}
+	}
+
+	return false
+}
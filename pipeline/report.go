@@ -0,0 +1,289 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+// ReportFilename is the name of the machine-readable report written into
+// the target directory at the end of the pipeline run.
+const ReportFilename = ctx.ReportFilename
+
+type (
+	// Report is a machine-readable summary of a pipeline run
+	Report struct {
+		// Actions lists intended actions of fake modules
+		Actions []ctx.Action `json:"actions,omitempty"`
+		// Artifacts lists registered artifacts with their sizes, and
+		// digests
+		Artifacts []ArtifactReport `json:"artifacts"`
+		// Deprecations lists deprecated options found in the
+		// configuration
+		Deprecations []modules.DeprecationWarning `json:"deprecations,omitempty"`
+		// Error is the error the pipeline failed with
+		Error string `json:"error,omitempty"`
+		// Modules lists module results in order of configuration
+		Modules []ModuleResult `json:"modules"`
+		// Release contains links, and install instructions of the
+		// published release
+		Release *ctx.Release `json:"release,omitempty"`
+		// Seed is the seed of ctx.Random, to reproduce the run
+		Seed      int64          `json:"seed"`
+		Transfers TransferReport `json:"transfers"`
+	}
+
+	// ArtifactReport is a registered artifact with its file's size, and
+	// SHA-256 digest. Size, and digest are empty for missing files, and
+	// directories.
+	ArtifactReport struct {
+		ID       string `json:"id"`
+		Filename string `json:"filename"`
+		Location string `json:"location"`
+		OsArch   string `json:"os_arch,omitempty"`
+		// Parents lists file names of artifacts the artifact was made of
+		Parents []string `json:"parents,omitempty"`
+		Size    int64    `json:"size,omitempty"`
+		SHA256  string   `json:"sha256,omitempty"`
+	}
+
+	// TransferReport contains transfer statistics of publishers
+	TransferReport struct {
+		Total        ctx.Transfer   `json:"total"`
+		Destinations []ctx.Transfer `json:"destinations"`
+	}
+)
+
+// NewReport collects a Report from ctx.Context and module results
+func NewReport(context *ctx.Context, results []ModuleResult) *Report {
+	artifacts := make([]ArtifactReport, 0, len(context.Artifacts))
+
+	for _, art := range context.Artifacts {
+		artifacts = append(artifacts, newArtifactReport(art))
+	}
+
+	return &Report{
+		Actions:   context.Actions.List(),
+		Artifacts: artifacts,
+		Modules:   results,
+		Release:   context.Published.Release(),
+		Seed:      context.Random.Seed(),
+		Transfers: TransferReport{
+			Total:        context.Transfers.Total(),
+			Destinations: context.Transfers.List(),
+		},
+	}
+}
+
+// newArtifactReport collects the size, and digest of an artifact's file
+func newArtifactReport(art *ctx.Artifact) ArtifactReport {
+	report := ArtifactReport{ID: art.ID, Filename: art.Filename, Location: art.Location, Parents: art.Parents}
+
+	if art.OsArch != nil {
+		report.OsArch = art.OsArch.String()
+	}
+
+	if !art.Recorded() {
+		if err := art.Stat(); err != nil || !art.Recorded() {
+			return report
+		}
+	}
+
+	report.Size = art.Size
+
+	algo, err := modules.NewHashAlgorithm(modules.DefaultDigest)
+	if err != nil {
+		return report
+	}
+
+	digest, err := algo.SumArtifact(art)
+	if err != nil {
+		log.Printf("cannot calculate digest of %s: %v", art.Location, err)
+
+		return report
+	}
+
+	report.SHA256 = digest
+
+	return report
+}
+
+// Log writes a human-readable summary of the report: module results, and
+// produced artifacts as tables, transfers to publish destinations, and
+// deprecated options to migrate
+func (rep *Report) Log() {
+	rep.logModules()
+	rep.logArtifacts()
+	rep.logTransfers()
+
+	if rep.Error == "" && rep.Release != nil {
+		rep.logRelease()
+	}
+
+	logDeprecations(rep.Deprecations)
+}
+
+// logDeprecations writes deprecated options found in the configuration
+func logDeprecations(warnings []modules.DeprecationWarning) {
+	if len(warnings) == 0 {
+		return
+	}
+
+	log.Printf("deprecated options (renamed automatically, please update the configuration):")
+
+	for _, warning := range warnings {
+		log.Printf("- %s", warning)
+	}
+}
+
+// logModules writes a table of module results
+func (rep *Report) logModules() {
+	if len(rep.Modules) == 0 {
+		return
+	}
+
+	rows := make([][]string, 0, len(rep.Modules))
+
+	for _, res := range rep.Modules {
+		duration := "-"
+		if res.Duration > 0 {
+			duration = res.Duration.Round(time.Millisecond).String()
+		}
+
+		rows = append(rows, []string{
+			res.Stage + ":" + res.Module,
+			string(res.Status),
+			duration,
+			fmt.Sprint(len(res.Artifacts)),
+		})
+	}
+
+	log.Printf("summary:")
+	logTable([]string{"MODULE", "STATUS", "DURATION", "ARTIFACTS"}, rows)
+}
+
+// logArtifacts writes a table of registered artifacts
+func (rep *Report) logArtifacts() {
+	if len(rep.Artifacts) == 0 {
+		return
+	}
+
+	rows := make([][]string, 0, len(rep.Artifacts))
+
+	for _, art := range rep.Artifacts {
+		size, digest := "-", "-"
+		if art.SHA256 != "" {
+			size = ctx.FormatBytes(art.Size)
+			digest = art.SHA256[:12]
+		}
+
+		rows = append(rows, []string{art.Filename, art.ID, size, digest})
+	}
+
+	log.Printf("artifacts:")
+	logTable([]string{"FILENAME", "ID", "SIZE", "SHA256"}, rows)
+}
+
+// logTable writes rows aligned in columns, with a header
+func logTable(header []string, rows [][]string) {
+	var out strings.Builder
+
+	table := tabwriter.NewWriter(&out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, strings.Join(header, "\t"))
+
+	for _, row := range rows {
+		fmt.Fprintln(table, strings.Join(row, "\t"))
+	}
+
+	_ = table.Flush()
+
+	for _, line := range strings.Split(strings.TrimRight(out.String(), "\n"), "\n") {
+		log.Printf("  %s", line)
+	}
+}
+
+func (rep *Report) logTransfers() {
+	total := rep.Transfers.Total
+	if total.Uploaded == 0 && total.Downloaded == 0 {
+		return
+	}
+
+	log.Printf(
+		"transferred %s up, %s down",
+		ctx.FormatBytes(total.Uploaded),
+		ctx.FormatBytes(total.Downloaded),
+	)
+
+	for _, item := range rep.Transfers.Destinations {
+		log.Printf(
+			"- %s: %s up, %s down",
+			item.Destination,
+			ctx.FormatBytes(item.Uploaded),
+			ctx.FormatBytes(item.Downloaded),
+		)
+	}
+}
+
+// logRelease writes copy-pasteable links and commands of the release
+func (rep *Report) logRelease() {
+	log.Printf("what's next:")
+
+	for _, link := range rep.Release.Links {
+		log.Printf("- %s: %s", link.Name, link.URL)
+	}
+
+	if len(rep.Release.Downloads) > 0 {
+		log.Printf("downloads:")
+
+		for _, download := range rep.Release.Downloads {
+			log.Printf("- %s (%s): %s", download.Filename, download.OsArch, download.URL)
+		}
+	}
+
+	if len(rep.Release.Images) > 0 {
+		log.Printf("images:")
+
+		for _, image := range rep.Release.Images {
+			log.Printf("- %s", image)
+		}
+	}
+
+	if len(rep.Release.Install) > 0 {
+		log.Printf("install:")
+
+		for _, command := range rep.Release.Install {
+			log.Printf("  %s", command)
+		}
+	}
+}
+
+// Write writes the report in JSON format into the target directory
+func (rep *Report) Write(targetDir string) error {
+	if targetDir == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(rep, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding report: %w", err)
+	}
+
+	if err := os.MkdirAll(targetDir, 0o755); err != nil {
+		return fmt.Errorf("creating target directory: %w", err)
+	}
+
+	fn := filepath.Join(targetDir, ReportFilename)
+	if err := os.WriteFile(fn, append(data, '\n'), 0o644); err != nil { // nolint: gosec
+		return fmt.Errorf("writing report %s: %w", fn, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package pipeline_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/julian7/goshipdone/ctx"
+	intmod "github.com/julian7/goshipdone/internal/modules"
+	"github.com/julian7/goshipdone/pipeline"
+)
+
+func TestNewReport_artifacts(t *testing.T) {
+	dir := t.TempDir()
+	location := filepath.Join(dir, "project")
+
+	if err := os.WriteFile(location, []byte("hello\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	shipContext, err := ctx.GetShipContext(ctx.New(context.Background()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	osarch := &ctx.OsArch{OS: "linux", Arch: "amd64"}
+	shipContext.Artifacts = ctx.Artifacts{
+		{ID: "default", Filename: "project", Location: location, OsArch: osarch},
+		{ID: "missing", Filename: "missing", Location: filepath.Join(dir, "missing")},
+		{ID: "dir", Filename: "dir", Location: dir},
+	}
+
+	want := []pipeline.ArtifactReport{
+		{
+			ID:       "default",
+			Filename: "project",
+			Location: location,
+			OsArch:   "linux-amd64",
+			Size:     6,
+			SHA256:   "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03",
+		},
+		{ID: "missing", Filename: "missing", Location: filepath.Join(dir, "missing")},
+		{ID: "dir", Filename: "dir", Location: dir},
+	}
+
+	report := pipeline.NewReport(shipContext, nil)
+
+	if diff := deep.Equal(report.Artifacts, want); diff != nil {
+		t.Errorf("NewReport() artifacts %v", diff)
+	}
+
+	report.Log()
+}
+
+func TestNewReport_transfers(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("tool binary"))
+	}))
+	defer srv.Close()
+
+	cx := ctx.New(context.Background())
+
+	shipContext, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sum := sha256.Sum256([]byte("tool binary"))
+
+	mod := intmod.NewTools().(*intmod.Tools)
+	mod.CacheDir = t.TempDir()
+	mod.Tools = []*intmod.Tool{
+		{Name: "tool", Version: "1.0.0", URL: srv.URL + "/tool", SHA256: map[string]string{"*": hex.EncodeToString(sum[:])}},
+	}
+
+	if err := mod.Run(cx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	shipContext.Transfers.Upload("scp:host", 5)
+
+	report := pipeline.NewReport(shipContext, nil)
+
+	if got := report.Transfers.Total.Downloaded; got == 0 {
+		t.Errorf("NewReport() downloaded %d bytes, want %d", got, len("tool binary"))
+	}
+
+	want := []ctx.Transfer{
+		{Destination: "scp:host", Uploaded: 5},
+		{Destination: "tools:tool", Downloaded: int64(len("tool binary"))},
+	}
+
+	if diff := deep.Equal(report.Transfers.Destinations, want); diff != nil {
+		t.Errorf("NewReport() transfers %v", diff)
+	}
+
+	report.Log()
+}
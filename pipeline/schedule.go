@@ -0,0 +1,225 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+// finishedModule is the outcome of a module run by runConcurrently
+type finishedModule struct {
+	idx     int
+	err     error
+	result  ModuleResult
+	context *ctx.Context
+}
+
+// concurrent returns true if any of the stage's modules declare their
+// dependencies
+func (stg *Stage) concurrent() bool {
+	for _, module := range stg.Modules {
+		if module.Needs != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dependencies returns indexes of modules each module depends on. Modules
+// without declared dependencies depend on the previous module.
+func (stg *Stage) dependencies() ([][]int, error) {
+	deps := make([][]int, len(stg.Modules))
+
+	for idx, module := range stg.Modules {
+		if module.Needs == nil {
+			if idx > 0 {
+				deps[idx] = []int{idx - 1}
+			}
+
+			continue
+		}
+
+		for _, name := range module.Needs {
+			found := false
+
+			for dep, other := range stg.Modules {
+				if dep != idx && (other.ID == name || other.Type == name) {
+					deps[idx] = append(deps[idx], dep)
+					found = true
+				}
+			}
+
+			if !found {
+				return nil, fmt.Errorf("module %s needs unknown module %s", module.Type, name)
+			}
+		}
+	}
+
+	for idx := range deps {
+		if path := stg.findCycle(deps, idx, []int{}); path != nil {
+			return nil, fmt.Errorf("dependency cycle: %s", strings.Join(path, " -> "))
+		}
+	}
+
+	return deps, nil
+}
+
+// findCycle returns module types of a dependency cycle reachable from idx,
+// or nil if there is none
+func (stg *Stage) findCycle(deps [][]int, idx int, path []int) []string {
+	for pos, visited := range path {
+		if visited == idx {
+			cycle := []string{}
+			for _, item := range append(path[pos:], idx) {
+				cycle = append(cycle, stg.Modules[item].Type)
+			}
+
+			return cycle
+		}
+	}
+
+	for _, dep := range deps[idx] {
+		if cycle := stg.findCycle(deps, dep, append(path, idx)); cycle != nil {
+			return cycle
+		}
+	}
+
+	return nil
+}
+
+// runConcurrently runs modules as soon as their dependencies finished,
+// running at most ctx.Context.Parallelism modules at a time. Each module
+// works on a fork of the ship context, and artifacts registered by the
+// module are merged back after it finished. After the first failure (of
+// modules not continuing on error), no new modules are started, but
+// running ones are waited for. Modules needing a module, which failed
+// while continuing on error, are skipped (transitively). Modules without
+// declared dependencies run after such a failure, like in sequential
+// stages.
+func (stg *Stage) runConcurrently(cx context.Context) error {
+	shipContext, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	deps, err := stg.dependencies()
+	if err != nil {
+		return err
+	}
+
+	parallelism := shipContext.Parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	stg.Results = make([]ModuleResult, len(stg.Modules))
+	for idx, module := range stg.Modules {
+		stg.Results[idx] = ModuleResult{Stage: stg.Name, Module: module.Type, Status: StatusNotStarted}
+	}
+
+	started := make([]bool, len(stg.Modules))
+	finished := make([]bool, len(stg.Modules))
+	failed := make([]bool, len(stg.Modules))
+	done := make(chan finishedModule)
+	running := 0
+
+	var firstErr error
+
+	for {
+		skipped := false
+
+		for idx, module := range stg.Modules {
+			if firstErr != nil || cx.Err() != nil || running >= parallelism {
+				break
+			}
+
+			if started[idx] || !allFinished(finished, failed, deps[idx]) {
+				continue
+			}
+
+			started[idx] = true
+
+			if dep := firstFailed(failed, deps[idx]); module.Needs != nil && dep >= 0 {
+				log.Printf("----> %s SKIPPED: needs failed %s", module.Type, stg.Modules[dep].Type)
+
+				stg.Results[idx], _ = stg.moduleFinished(cx, module, ModuleResult{Stage: stg.Name, Module: module.Type, Status: StatusSkipped}, nil)
+				failed[idx] = true
+				skipped = true
+
+				continue
+			}
+
+			running++
+
+			moduleCx, moduleContext := shipContext.Fork(cx)
+
+			go func(idx int, module *modules.Module) {
+				result, err := stg.runModule(moduleCx, module)
+				done <- finishedModule{idx: idx, err: err, result: result, context: moduleContext}
+			}(idx, module)
+		}
+
+		if running == 0 {
+			// skips may make modules listed earlier runnable
+			if skipped {
+				continue
+			}
+
+			break
+		}
+
+		item := <-done
+		running--
+
+		shipContext.Join(item.context)
+		stg.Results[item.idx] = item.result
+
+		if item.err == nil {
+			finished[item.idx] = true
+			continue
+		}
+
+		if stg.tolerated(cx, stg.Modules[item.idx], item.err) {
+			failed[item.idx] = true
+			continue
+		}
+
+		if firstErr == nil {
+			firstErr = item.err
+		}
+	}
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	return cx.Err()
+}
+
+// allFinished returns true if all modules of indexes have been finished,
+// or failed (continuing on error)
+func allFinished(finished, failed []bool, indexes []int) bool {
+	for _, idx := range indexes {
+		if !finished[idx] && !failed[idx] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// firstFailed returns the first of indexes, which failed, or -1
+func firstFailed(failed []bool, indexes []int) int {
+	for _, idx := range indexes {
+		if failed[idx] {
+			return idx
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,125 @@
+package pipeline
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/julian7/goshipdone/modules"
+	"github.com/julian7/goshipdone/plugin"
+	"gopkg.in/yaml.v3"
+)
+
+// moduleOptions are options available in every module (see
+// modules.Module)
+type moduleOptions struct {
+	ID           string
+	Meta         map[string]string
+	Needs        []string
+	OnError      string `yaml:"on_error"`
+	Replacements map[string]string
+	Retries      int
+	RetryBackoff time.Duration `yaml:"retry_backoff"`
+	// Strict rejects unknown keys of the module. Default: true.
+	Strict  *bool
+	Timeout time.Duration
+	Type    string
+	When    string
+}
+
+// Schema returns a JSON Schema of the pipeline's configuration, including
+// modules registered for its stages. Editors can validate configuration
+// files with it.
+func (pip *Pipeline) Schema() *modules.Schema {
+	schema := &modules.Schema{
+		Schema: modules.SchemaVersion,
+		Type:   "object",
+		Properties: map[string]*modules.Schema{
+			pluginsKey: modules.SchemaOf([]*plugin.Definition{}),
+			stagesKey:  modules.SchemaOf([]*StageDefinition{}),
+		},
+		AdditionalProperties: false,
+	}
+
+	for _, stg := range pip.Stages {
+		schema.Properties[stg.Plural] = &modules.Schema{
+			Type:  "array",
+			Items: &modules.Schema{OneOf: stg.moduleSchemas()},
+		}
+	}
+
+	return schema
+}
+
+// moduleSchemas returns schemas of modules available in the stage
+func (stg *Stage) moduleSchemas() []*modules.Schema {
+	schemas := []*modules.Schema{}
+	kinds := modules.ModuleKinds()
+	registered := map[string]bool{}
+
+	for _, kind := range kinds {
+		registered[kind] = true
+	}
+
+	for _, kind := range kinds {
+		items := strings.SplitN(kind, ":", 2)
+		if items[0] != stg.Name && (items[0] != "*" || registered[stg.Name+":"+items[1]]) {
+			continue
+		}
+
+		factory, _ := modules.LookupModule(kind)
+
+		schema := moduleSchema(factory())
+		schema.Properties["type"] = &modules.Schema{Const: items[1]}
+		schema.Required = []string{"type"}
+		schemas = append(schemas, schema)
+	}
+
+	return schemas
+}
+
+// validateModule validates a module's configuration. Unknown keys of
+// modules with `strict: false` are logged, and ignored.
+func validateModule(kind string, pluggable modules.Pluggable, node *yaml.Node) error {
+	errs := moduleSchema(pluggable).Validate(node)
+	if errs == nil {
+		return nil
+	}
+
+	var options struct{ Strict *bool }
+
+	strict := node.Decode(&options) != nil || options.Strict == nil || *options.Strict
+	failed := modules.SchemaErrors{}
+
+	for _, err := range errs {
+		err.Module = kind
+
+		if err.Unknown && !strict {
+			log.Printf("ignoring %v", err)
+
+			continue
+		}
+
+		failed = append(failed, err)
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+
+	return failed
+}
+
+// moduleSchema returns the schema of a module's configuration, including
+// options available in every module
+func moduleSchema(pluggable modules.Pluggable) *modules.Schema {
+	schema := modules.SchemaOf(modules.Config(pluggable))
+	if schema.Type != "object" {
+		// the module decodes its own configuration
+		schema = &modules.Schema{Type: "object"}
+	}
+
+	schema.Merge(modules.SchemaOf(&moduleOptions{}))
+
+	return schema
+}
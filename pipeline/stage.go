@@ -8,17 +8,29 @@ import (
 	"strings"
 	"time"
 
+	"github.com/julian7/goshipdone/ctx"
 	"github.com/julian7/goshipdone/modules"
 	"gopkg.in/yaml.v3"
 )
 
 // Stage is a single stage in the pipeline
 type Stage struct {
-	loaded  map[string]bool
-	Modules []*modules.Module          `yaml:"-"`
-	Name    string                     `yaml:"-"`
-	Plural  string                     `yaml:"-"`
-	SkipFN  func(context.Context) bool `yaml:"-"`
+	loaded map[string]bool
+	// Deprecations lists deprecated options found in configurations of
+	// the stage's modules (see modules.Deprecated)
+	Deprecations []modules.DeprecationWarning `yaml:"-"`
+	// Errors contains errors of modules continuing on error (see
+	// modules.OnErrorContinue) in the last Run
+	Errors  []error           `yaml:"-"`
+	Modules []*modules.Module `yaml:"-"`
+	Name    string            `yaml:"-"`
+	Plural  string            `yaml:"-"`
+	// Results contains results of modules run by the last Run, in order
+	Results []ModuleResult `yaml:"-"`
+	// Sequential stages don't accept `needs` declarations, as their
+	// modules change the ship context for later ones (eg. setups)
+	Sequential bool                       `yaml:"-"`
+	SkipFN     func(context.Context) bool `yaml:"-"`
 }
 
 func NewStage(name, plural string) *Stage {
@@ -65,21 +77,7 @@ func (stg *Stage) UnmarshalYAML(node *yaml.Node) error {
 // "build:dump", a reference to "dump" kind in publishes will fire "*:dump"
 // module, but a similar "dump" kind in builds will fire "build:dump".
 func (stg *Stage) Add(itemType string, node *yaml.Node, once bool) error {
-	var kind string
-
-	var targetModFactory modules.PluggableFactory
-
-	var ok bool
-
-	for _, stage := range []string{stg.Name, "*"} {
-		kind = fmt.Sprintf("%s:%s", stage, itemType)
-
-		targetModFactory, ok = modules.LookupModule(kind)
-		if ok {
-			break
-		}
-	}
-
+	kind, targetModFactory, ok := stg.lookup(itemType)
 	if !ok {
 		return fmt.Errorf("unknown module %s:%s", stg.Name, itemType)
 	}
@@ -88,39 +86,111 @@ func (stg *Stage) Add(itemType string, node *yaml.Node, once bool) error {
 		return fmt.Errorf("module %s already loaded", kind)
 	}
 
-	targetMod := targetModFactory()
+	module := &modules.Module{
+		Type:      itemType,
+		Pluggable: targetModFactory(),
+	}
 
 	if node != nil {
-		if err := node.Decode(targetMod); err != nil {
+		name := fmt.Sprintf("%s:%s", stg.Name, itemType)
+
+		warnings, err := modules.MigrateOptions(name, module.Pluggable, node)
+		if err != nil {
+			return err
+		}
+
+		stg.Deprecations = append(stg.Deprecations, warnings...)
+
+		if err := validateModule(name, module.Pluggable, node); err != nil {
+			return err
+		}
+
+		if err := node.Decode(module.Pluggable); err != nil {
 			return fmt.Errorf("cannot decode module %s: %w", kind, err)
 		}
+
+		if err := decodeModuleOptions(module, node); err != nil {
+			return fmt.Errorf("cannot decode module %s: %w", kind, err)
+		}
+
+		if module.Needs != nil && stg.Sequential {
+			return fmt.Errorf("module %s: needs is not supported in %s", kind, stg.Plural)
+		}
 	}
 
-	stg.Modules = append(stg.Modules, &modules.Module{
-		Type:      itemType,
-		Pluggable: targetMod,
-	})
+	stg.Modules = append(stg.Modules, module)
 
 	stg.flagLoaded(kind)
 
 	return nil
 }
 
+// lookup returns the kind, and the factory of a module type in the stage,
+// preferring modules registered for the stage over ones registered for
+// every stage
+func (stg *Stage) lookup(itemType string) (string, modules.PluggableFactory, bool) {
+	for _, stage := range []string{stg.Name, "*"} {
+		kind := fmt.Sprintf("%s:%s", stage, itemType)
+
+		if factory, ok := modules.LookupModule(kind); ok {
+			return kind, factory, true
+		}
+	}
+
+	return "", nil, false
+}
+
+// Skip marks all modules of the stage skipped, without running them
+func (stg *Stage) Skip() {
+	log.Printf("====> %s SKIPPED", strings.ToUpper(stg.Name))
+
+	stg.Errors = nil
+	stg.Results = make([]ModuleResult, 0, len(stg.Modules))
+
+	for _, module := range stg.Modules {
+		stg.Results = append(stg.Results, ModuleResult{Stage: stg.Name, Module: module.Type, Status: StatusSkipped})
+	}
+}
+
 // Run goes through all internally loaded modules, and run them
-// one by one.
+// one by one. It stops if cx is canceled.
 func (stg *Stage) Run(cx context.Context) error {
 	log.Printf("====> %s", strings.ToUpper(stg.Name))
 
 	startMod := time.Now()
+	stg.Errors = nil
+	stg.Results = make([]ModuleResult, 0, len(stg.Modules))
+	stg.emit(cx, ctx.Event{Type: ctx.EventStageStarted})
 
 	if stg.SkipFN != nil && stg.SkipFN(cx) {
 		log.Printf("SKIPPED")
-	} else {
+
 		for _, module := range stg.Modules {
-			if err := module.Run(cx); err != nil {
-				return fmt.Errorf("stage %s: %w", stg.Name, err)
-			}
+			stg.Results = append(stg.Results, ModuleResult{Stage: stg.Name, Module: module.Type, Status: StatusSkipped})
 		}
+
+		stg.emit(cx, ctx.Event{Type: ctx.EventStageFinished, Status: string(StatusSkipped)})
+	} else {
+		var err error
+
+		if stg.concurrent() {
+			err = stg.runConcurrently(cx)
+		} else {
+			err = stg.runSequentially(cx)
+		}
+
+		if err != nil {
+			stg.emit(cx, ctx.Event{
+				Type:     ctx.EventStageFinished,
+				Status:   string(StatusFailed),
+				Duration: time.Since(startMod),
+				Error:    err.Error(),
+			})
+
+			return fmt.Errorf("stage %s: %w", stg.Name, err)
+		}
+
+		stg.emit(cx, ctx.Event{Type: ctx.EventStageFinished, Status: string(StatusCompleted), Duration: time.Since(startMod)})
 	}
 
 	log.Printf("<==== %s done in %s", strings.ToUpper(stg.Name), time.Since(startMod))
@@ -128,6 +198,103 @@ func (stg *Stage) Run(cx context.Context) error {
 	return nil
 }
 
+// runSequentially runs modules one by one, in order
+func (stg *Stage) runSequentially(cx context.Context) error {
+	for _, module := range stg.Modules {
+		if err := cx.Err(); err != nil {
+			return err
+		}
+
+		result, err := stg.runModule(cx, module)
+		stg.Results = append(stg.Results, result)
+
+		if err != nil && !stg.tolerated(cx, module, err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// tolerated collects the error of a module continuing on error, and
+// returns true, if the stage can go on
+func (stg *Stage) tolerated(cx context.Context, module *modules.Module, err error) bool {
+	if module.OnError != modules.OnErrorContinue || cx.Err() != nil {
+		return false
+	}
+
+	log.Printf("      %s failed, continuing: %v", module.Type, err)
+
+	stg.Errors = append(stg.Errors, fmt.Errorf("stage %s: %w", stg.Name, err))
+
+	return true
+}
+
+// runModule runs a module, unless its `when` expression skips it
+func (stg *Stage) runModule(cx context.Context, module *modules.Module) (ModuleResult, error) {
+	enabled, err := module.Enabled(cx)
+	if err != nil {
+		return stg.moduleFinished(cx, module, ModuleResult{Stage: stg.Name, Module: module.Type, Status: StatusFailed}, err)
+	}
+
+	if !enabled {
+		log.Printf("----> %s SKIPPED", module.Type)
+
+		return stg.moduleFinished(cx, module, ModuleResult{Stage: stg.Name, Module: module.Type, Status: StatusSkipped}, nil)
+	}
+
+	stg.emit(cx, ctx.Event{Type: ctx.EventModuleStarted, Module: module.Type})
+
+	start := time.Now()
+	err = module.Run(cx)
+	status := StatusCompleted
+
+	if err != nil {
+		status = StatusFailed
+		if cx.Err() != nil {
+			status = StatusAborted
+		}
+	}
+
+	return stg.moduleFinished(cx, module, newModuleResult(stg.Name, module, status, time.Since(start)), err)
+}
+
+// moduleFinished emits events of artifacts produced by a module, and its
+// result
+func (stg *Stage) moduleFinished(cx context.Context, module *modules.Module, result ModuleResult, err error) (ModuleResult, error) {
+	if result.Status == StatusCompleted && module.Result != nil {
+		for _, art := range module.Result.Artifacts {
+			stg.emit(cx, ctx.Event{Type: ctx.EventArtifact, Module: module.Type, Artifact: art})
+		}
+	}
+
+	event := ctx.Event{
+		Type:     ctx.EventModuleFinished,
+		Module:   module.Type,
+		Status:   string(result.Status),
+		Duration: result.Duration,
+	}
+
+	if err != nil {
+		event.Error = err.Error()
+	}
+
+	stg.emit(cx, event)
+
+	return result, err
+}
+
+// emit sends an event of the stage to the ship context's event bus
+func (stg *Stage) emit(cx context.Context, event ctx.Event) {
+	shipContext, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return
+	}
+
+	event.Stage = stg.Name
+	shipContext.Events.Emit(event)
+}
+
 func (stg *Stage) isLoaded(kind string) bool {
 	if stg.loaded == nil {
 		return false
@@ -147,17 +314,48 @@ func (stg *Stage) flagLoaded(kind string) {
 }
 
 func getType(node *yaml.Node) (string, error) {
-	var itemType string
+	itemType := getValue(node, "type")
+	if itemType == "" {
+		return "", errors.New("type not defined")
+	}
+
+	return itemType, nil
+}
+
+// decodeModuleOptions decodes options common to all modules
+func decodeModuleOptions(module *modules.Module, node *yaml.Node) error {
+	var options moduleOptions
 
-	for idx := 0; idx < len(node.Content); idx += 2 {
-		key := node.Content[idx]
-		val := node.Content[idx+1]
+	if err := node.Decode(&options); err != nil {
+		return err
+	}
+
+	module.ID = options.ID
+	module.Meta = options.Meta
+	module.Needs = options.Needs
+	module.OnError = options.OnError
+	module.Replacements = options.Replacements
+	module.Retries = options.Retries
+	module.RetryBackoff = options.RetryBackoff
+	module.Timeout = options.Timeout
+	module.When = options.When
+
+	switch module.OnError {
+	case "", modules.OnErrorFail, modules.OnErrorContinue:
+	default:
+		return fmt.Errorf("invalid on_error: %q", module.OnError)
+	}
+
+	return nil
+}
 
-		if key.Value == "type" {
-			itemType = val.Value
-			return itemType, nil
+// getValue returns a key's value of a mapping node, or an empty string
+func getValue(node *yaml.Node, name string) string {
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		if node.Content[idx].Value == name {
+			return node.Content[idx+1].Value
 		}
 	}
 
-	return "", errors.New("type not defined")
+	return ""
 }
@@ -0,0 +1,171 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+const (
+	// StateFilename is the name of the pipeline's state file in the target
+	// directory, which resumed runs load artifacts from.
+	StateFilename = ctx.StateFilename
+	// FromEnv is the environment variable of the stage a pipeline run is
+	// resumed from (see Pipeline.From)
+	FromEnv = "GOSHIPDONE_FROM"
+	// StagesEnv is the environment variable of comma-separated stages
+	// selected for a pipeline run (see Pipeline.Only)
+	StagesEnv = "GOSHIPDONE_STAGES"
+)
+
+// state is the pipeline's state persisted after each stage
+type state struct {
+	// Artifacts are the artifacts registered so far
+	Artifacts ctx.Artifacts `json:"artifacts"`
+	// Stages lists stages completed, in order
+	Stages []string `json:"stages"`
+	// Version is the version built
+	Version string `json:"version"`
+}
+
+// selectedStages returns whether stages are selected for the run by From,
+// and Only. Setup stages are always selected, as they build the ship
+// context.
+func (pip *Pipeline) selectedStages() (map[string]bool, error) {
+	selected := map[string]bool{}
+	found := pip.From == ""
+
+	for _, stg := range pip.Stages {
+		if stg.Name == pip.From {
+			found = true
+		}
+
+		selected[stg.Name] = found
+	}
+
+	if !found {
+		return nil, fmt.Errorf("unknown stage to resume from: %s", pip.From)
+	}
+
+	if len(pip.Only) > 0 {
+		only := map[string]bool{}
+
+		for _, name := range pip.Only {
+			if _, ok := selected[name]; !ok {
+				return nil, fmt.Errorf("unknown stage selected: %s", name)
+			}
+
+			only[name] = true
+		}
+
+		for name := range selected {
+			selected[name] = selected[name] && only[name]
+		}
+	}
+
+	selected["setup"] = true
+
+	return selected, nil
+}
+
+// loadState loads the state of a previous run, and registers its
+// artifacts. Stages in required must have been completed in that run.
+func loadState(context *ctx.Context, required []string) (*state, error) {
+	fn := filepath.Join(context.TargetDir, StateFilename)
+
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("no previous run to resume: %s not found", fn)
+		}
+
+		return nil, fmt.Errorf("reading state: %w", err)
+	}
+
+	prev := &state{}
+	if err := json.Unmarshal(data, prev); err != nil {
+		return nil, fmt.Errorf("decoding state %s: %w", fn, err)
+	}
+
+	if prev.Version != context.Version {
+		return nil, fmt.Errorf("previous run has built version %s, not %s", prev.Version, context.Version)
+	}
+
+	completed := map[string]bool{}
+	for _, name := range prev.Stages {
+		completed[name] = true
+	}
+
+	missing := []string{}
+
+	for _, name := range required {
+		if !completed[name] {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("stages not completed by the previous run: %s", strings.Join(missing, ", "))
+	}
+
+	registered := map[string]bool{}
+	for _, artifact := range context.Artifacts {
+		registered[artifact.Location] = true
+	}
+
+	for _, artifact := range prev.Artifacts {
+		if registered[artifact.Location] {
+			continue
+		}
+
+		if _, err := os.Stat(artifact.Location); err != nil {
+			return nil, fmt.Errorf("artifact %s of the previous run: %w", artifact.Filename, err)
+		}
+
+		context.Artifacts.Add(artifact)
+	}
+
+	return prev, nil
+}
+
+// complete records a completed stage
+func (st *state) complete(name string) {
+	for _, stage := range st.Stages {
+		if stage == name {
+			return
+		}
+	}
+
+	st.Stages = append(st.Stages, name)
+}
+
+// write writes the state into the target directory
+func (st *state) write(context *ctx.Context) error {
+	if context.TargetDir == "" {
+		return nil
+	}
+
+	st.Artifacts = context.Artifacts
+	st.Version = context.Version
+
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding state: %w", err)
+	}
+
+	if err := os.MkdirAll(context.TargetDir, 0o755); err != nil {
+		return fmt.Errorf("creating target directory: %w", err)
+	}
+
+	fn := filepath.Join(context.TargetDir, StateFilename)
+	if err := os.WriteFile(fn, append(data, '\n'), 0o644); err != nil { // nolint: gosec
+		return fmt.Errorf("writing state %s: %w", fn, err)
+	}
+
+	return nil
+}
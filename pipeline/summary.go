@@ -0,0 +1,81 @@
+package pipeline
+
+import (
+	"time"
+
+	"github.com/julian7/goshipdone/modules"
+)
+
+// ModuleStatus is the outcome of a module's run
+type ModuleStatus string
+
+const (
+	// StatusCompleted is the status of successfully finished modules
+	StatusCompleted ModuleStatus = "completed"
+	// StatusFailed is the status of modules returning an error
+	StatusFailed ModuleStatus = "failed"
+	// StatusAborted is the status of modules interrupted while running
+	StatusAborted ModuleStatus = "aborted"
+	// StatusSkipped is the status of modules in skipped stages, or
+	// modules skipped by their `when` expressions
+	StatusSkipped ModuleStatus = "skipped"
+	// StatusNotStarted is the status of modules not reached
+	StatusNotStarted ModuleStatus = "not started"
+)
+
+// ModuleResult is the outcome of a single module's run
+type ModuleResult struct {
+	Stage  string       `json:"stage"`
+	Module string       `json:"module"`
+	Status ModuleStatus `json:"status"`
+	// Duration is the module's wallclock run time in nanoseconds
+	Duration time.Duration `json:"duration"`
+	// Artifacts lists file names of artifacts produced by the module
+	Artifacts []string           `json:"artifacts,omitempty"`
+	Warnings  []string           `json:"warnings,omitempty"`
+	Metrics   map[string]float64 `json:"metrics,omitempty"`
+}
+
+// newModuleResult creates a ModuleResult from a finished module
+func newModuleResult(stage string, mod *modules.Module, status ModuleStatus, duration time.Duration) ModuleResult {
+	result := ModuleResult{
+		Stage:    stage,
+		Module:   mod.Type,
+		Status:   status,
+		Duration: duration,
+	}
+
+	if mod.Result != nil {
+		for _, art := range mod.Result.Artifacts {
+			result.Artifacts = append(result.Artifacts, art.Filename)
+		}
+
+		result.Warnings = mod.Result.Warnings
+		result.Metrics = mod.Result.Metrics
+	}
+
+	return result
+}
+
+// Results returns module results of all stages, including modules not
+// started
+func (pip *Pipeline) Results() []ModuleResult {
+	results := []ModuleResult{}
+
+	for _, stg := range pip.Stages {
+		for idx, mod := range stg.Modules {
+			if idx < len(stg.Results) {
+				results = append(results, stg.Results[idx])
+				continue
+			}
+
+			results = append(results, ModuleResult{
+				Stage:  stg.Name,
+				Module: mod.Type,
+				Status: StatusNotStarted,
+			})
+		}
+	}
+
+	return results
+}
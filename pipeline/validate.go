@@ -0,0 +1,87 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+// Validate checks the loaded pipeline without running it: dependencies of
+// modules (see `needs`), templates of their configurations, and `when`
+// expressions rendered with sample data (see modules.CheckTemplates), and
+// artifact IDs configured in `builds`, which must be produced by earlier
+// modules. All problems found are returned as ValidationErrors.
+// Deprecated options are logged.
+func (pip *Pipeline) Validate() error {
+	logDeprecations(pip.Deprecations())
+
+	cx := ctx.New(context.Background())
+	errs := ValidationErrors{}
+	produced := map[string]bool{}
+	dynamic := false
+
+	td, err := modules.SampleTemplate(cx)
+	if err != nil {
+		return err
+	}
+
+	for _, stg := range pip.Stages {
+		if _, err := stg.dependencies(); err != nil {
+			errs = append(errs, fmt.Errorf("stage %s: %w", stg.Name, err))
+		}
+
+		for _, module := range stg.Modules {
+			kind := stg.Name + ":" + module.Type
+
+			if err := modules.CheckTemplates(cx, module.Pluggable); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", kind, err))
+			}
+
+			if module.When != "" {
+				if _, err := td.Parse("when", module.When); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", kind, err))
+				}
+			}
+
+			produces, consumes := artifactFlow(module)
+
+			if !dynamic && !stg.defaultBuilds(module, consumes) {
+				for _, id := range consumes {
+					if !produced[id] {
+						errs = append(errs, fmt.Errorf("%s: build %q is not produced by earlier modules", kind, id))
+					}
+				}
+			}
+
+			for _, id := range produces {
+				produced[id] = true
+			}
+
+			if source, ok := modules.Config(module.Pluggable).(modules.DynamicArtifacts); ok && module.ID == "" {
+				dynamic = dynamic || source.DynamicArtifacts()
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
+
+// defaultBuilds returns true if a module takes the default artifact IDs of
+// its type, which are not reported, if missing
+func (stg *Stage) defaultBuilds(module *modules.Module, consumes []string) bool {
+	_, factory, ok := stg.lookup(module.Type)
+	if !ok {
+		return false
+	}
+
+	_, defaults := artifactFlow(&modules.Module{Pluggable: factory()})
+
+	return reflect.DeepEqual(consumes, defaults)
+}
@@ -0,0 +1,273 @@
+package pipelinetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+type (
+	// FakeGitHub is a fake GitHub Enterprise server, implementing the
+	// release API endpoints used by publish:artifact. Use its URL as
+	// `url` setting of the module.
+	FakeGitHub struct {
+		*httptest.Server
+		assets      map[int64]fakeAsset
+		mu          sync.Mutex
+		releases    map[int64]*FakeRelease
+		nextAssetID int64
+		nextID      int64
+	}
+
+	fakeAsset struct {
+		name    string
+		release int64
+	}
+
+	// FakeRelease is a release stored in FakeGitHub
+	FakeRelease struct {
+		ID      int64            `json:"id"`
+		Name    string           `json:"name"`
+		TagName string           `json:"tag_name"`
+		Body    string           `json:"body"`
+		Draft   bool             `json:"draft"`
+		HTMLURL string           `json:"html_url"`
+		Assets  map[string]int64 `json:"-"`
+	}
+)
+
+// nolint: gochecknoglobals
+var (
+	reGitHubLatest       = regexp.MustCompile(`^/api/v3/repos/[^/]+/[^/]+/releases/latest$`)
+	reGitHubReleaseByTag = regexp.MustCompile(`^/api/v3/repos/[^/]+/[^/]+/releases/tags/(.+)$`)
+	reGitHubReleases     = regexp.MustCompile(`^/api/v3/repos/[^/]+/[^/]+/releases$`)
+	reGitHubRelease      = regexp.MustCompile(`^/api/v3/repos/[^/]+/[^/]+/releases/(\d+)$`)
+	reGitHubAssets       = regexp.MustCompile(`^/api/uploads/repos/[^/]+/[^/]+/releases/(\d+)/assets$`)
+	reGitHubAssetList    = regexp.MustCompile(`^/api/v3/repos/[^/]+/[^/]+/releases/(\d+)/assets$`)
+	reGitHubAsset        = regexp.MustCompile(`^/api/v3/repos/[^/]+/[^/]+/releases/assets/(\d+)$`)
+)
+
+// NewFakeGitHub starts a new FakeGitHub server, which is closed at the end
+// of the test.
+func NewFakeGitHub(t testing.TB) *FakeGitHub {
+	srv := &FakeGitHub{assets: map[int64]fakeAsset{}, releases: map[int64]*FakeRelease{}}
+	srv.Server = httptest.NewServer(http.HandlerFunc(srv.handle))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// Releases returns all releases by tag name
+func (srv *FakeGitHub) Releases() map[string]*FakeRelease {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	releases := make(map[string]*FakeRelease, len(srv.releases))
+	for _, rel := range srv.releases {
+		releases[rel.TagName] = rel
+	}
+
+	return releases
+}
+
+// AddRelease adds a published release, returning its ID
+func (srv *FakeGitHub) AddRelease(tag string) int64 {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	srv.nextID++
+	srv.releases[srv.nextID] = &FakeRelease{
+		ID:      srv.nextID,
+		Name:    tag,
+		TagName: tag,
+		Assets:  map[string]int64{},
+	}
+
+	return srv.nextID
+}
+
+// Uploads returns sorted names of all uploaded assets
+func (srv *FakeGitHub) Uploads() []string {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	names := []string{}
+
+	for _, rel := range srv.releases {
+		for name := range rel.Assets {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func (srv *FakeGitHub) handle(w http.ResponseWriter, req *http.Request) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	path := req.URL.Path
+
+	switch {
+	case req.Method == http.MethodGet && reGitHubLatest.MatchString(path):
+		var latest *FakeRelease
+
+		for _, rel := range srv.releases {
+			if !rel.Draft && (latest == nil || rel.ID > latest.ID) {
+				latest = rel
+			}
+		}
+
+		if latest == nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, latest)
+	case req.Method == http.MethodGet && reGitHubReleaseByTag.MatchString(path):
+		tag := reGitHubReleaseByTag.FindStringSubmatch(path)[1]
+
+		// like GitHub, drafts are not found by tag
+		for _, rel := range srv.releases {
+			if rel.TagName == tag && !rel.Draft {
+				writeJSON(w, http.StatusOK, rel)
+				return
+			}
+		}
+
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+	case req.Method == http.MethodGet && reGitHubReleases.MatchString(path):
+		list := make([]*FakeRelease, 0, len(srv.releases))
+		for _, rel := range srv.releases {
+			list = append(list, rel)
+		}
+
+		sort.Slice(list, func(i, j int) bool { return list[i].ID > list[j].ID })
+
+		writeJSON(w, http.StatusOK, list)
+	case req.Method == http.MethodPost && reGitHubReleases.MatchString(path):
+		rel := &FakeRelease{}
+		if err := json.NewDecoder(req.Body).Decode(rel); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+			return
+		}
+
+		srv.nextID++
+		rel.ID = srv.nextID
+		rel.HTMLURL = fmt.Sprintf("%s/releases/tag/%s", srv.URL, rel.TagName)
+		rel.Assets = map[string]int64{}
+		srv.releases[rel.ID] = rel
+
+		writeJSON(w, http.StatusCreated, rel)
+	case req.Method == http.MethodPatch && reGitHubRelease.MatchString(path):
+		rel := srv.releaseByPath(reGitHubRelease, path)
+		if rel == nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+			return
+		}
+
+		if err := json.NewDecoder(req.Body).Decode(rel); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, rel)
+	case req.Method == http.MethodPost && reGitHubAssets.MatchString(path):
+		rel := srv.releaseByPath(reGitHubAssets, path)
+		if rel == nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+			return
+		}
+
+		size, err := io.Copy(io.Discard, req.Body)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+			return
+		}
+
+		name := req.URL.Query().Get("name")
+		if _, ok := rel.Assets[name]; ok {
+			writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "Validation Failed: already_exists"})
+			return
+		}
+
+		rel.Assets[name] = size
+		srv.nextAssetID++
+		srv.assets[srv.nextAssetID] = fakeAsset{name: name, release: rel.ID}
+
+		writeJSON(w, http.StatusCreated, srv.assetData(rel, srv.nextAssetID))
+	case req.Method == http.MethodGet && reGitHubAssetList.MatchString(path):
+		rel := srv.releaseByPath(reGitHubAssetList, path)
+		if rel == nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+			return
+		}
+
+		ids := []int64{}
+
+		for id, asset := range srv.assets {
+			if asset.release == rel.ID {
+				ids = append(ids, id)
+			}
+		}
+
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+		list := make([]map[string]interface{}, 0, len(ids))
+		for _, id := range ids {
+			list = append(list, srv.assetData(rel, id))
+		}
+
+		writeJSON(w, http.StatusOK, list)
+	case req.Method == http.MethodDelete && reGitHubAsset.MatchString(path):
+		id, _ := strconv.ParseInt(reGitHubAsset.FindStringSubmatch(path)[1], 10, 64)
+
+		asset, ok := srv.assets[id]
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+			return
+		}
+
+		delete(srv.releases[asset.release].Assets, asset.name)
+		delete(srv.assets, id)
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": fmt.Sprintf("%s %s not implemented", req.Method, path)})
+	}
+}
+
+func (srv *FakeGitHub) assetData(rel *FakeRelease, id int64) map[string]interface{} {
+	name := srv.assets[id].name
+
+	return map[string]interface{}{
+		"id":                   id,
+		"name":                 name,
+		"size":                 rel.Assets[name],
+		"browser_download_url": fmt.Sprintf("%s/releases/download/%s/%s", srv.URL, rel.TagName, name),
+	}
+}
+
+func (srv *FakeGitHub) releaseByPath(re *regexp.Regexp, path string) *FakeRelease {
+	id, err := strconv.ParseInt(re.FindStringSubmatch(path)[1], 10, 64)
+	if err != nil {
+		return nil
+	}
+
+	return srv.releases[id]
+}
+
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(data)
+}
@@ -0,0 +1,163 @@
+// pipelinetest provides utilities for end-to-end testing of pipelines,
+// including custom modules. It runs YAML pipelines in a temporary copy of
+// a fixture directory, against fake remote endpoints.
+//
+// Harness changes the current working directory while running a pipeline,
+// therefore tests using it must not run in parallel.
+package pipelinetest
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/pipeline"
+)
+
+// Harness runs pipelines in a temporary copy of a fixture directory
+type Harness struct {
+	testing.TB
+	// Dir is the temporary working directory of the pipeline
+	Dir string
+	// Seed seeds ctx.Random of pipeline runs, for stable outputs.
+	// Default: 1.
+	Seed int64
+}
+
+// New creates a new Harness, copying fixture directory's contents into
+// a temporary directory. An empty fixture starts with an empty directory.
+func New(t testing.TB, fixture string) *Harness {
+	t.Helper()
+
+	h := &Harness{TB: t, Dir: t.TempDir(), Seed: 1}
+
+	if fixture != "" {
+		if err := copyDir(fixture, h.Dir); err != nil {
+			t.Fatalf("copying fixture %s: %v", fixture, err)
+		}
+	}
+
+	return h
+}
+
+// GitInit initializes a git repository in the working directory, commits
+// all files, and tags the commit if tag is not empty.
+func (h *Harness) GitInit(tag string) {
+	h.Helper()
+
+	commands := [][]string{
+		{"init", "-q"},
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "fixture"},
+	}
+
+	if tag != "" {
+		commands = append(commands, []string{"tag", tag})
+	}
+
+	for _, args := range commands {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = h.Dir
+
+		if out, err := cmd.CombinedOutput(); err != nil {
+			h.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+}
+
+// Run loads a YAML pipeline, and runs it in the working directory. It
+// returns ship context for inspecting results, even if the pipeline failed.
+func (h *Harness) Run(yml string) (*ctx.Context, error) {
+	h.Helper()
+
+	pip, err := pipeline.LoadBuildPipeline([]byte(yml))
+	if err != nil {
+		return nil, err
+	}
+
+	origDir, err := os.Getwd()
+	if err != nil {
+		h.Fatalf("getting working directory: %v", err)
+	}
+
+	if err := os.Chdir(h.Dir); err != nil {
+		h.Fatalf("changing to working directory: %v", err)
+	}
+
+	defer func() {
+		if err := os.Chdir(origDir); err != nil {
+			h.Fatalf("changing back to original directory: %v", err)
+		}
+	}()
+
+	cx := ctx.New(context.Background())
+
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return nil, err
+	}
+
+	context.Random = ctx.NewRandom(h.Seed)
+
+	return context, pip.RunContext(cx)
+}
+
+// Filenames returns sorted file names of artifacts
+func Filenames(arts ctx.Artifacts) []string {
+	names := make([]string, 0, len(arts))
+
+	for _, art := range arts {
+		names = append(names, art.Filename)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(fn string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, fn)
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dst, rel)
+
+		if info.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm()|0o700)
+		}
+
+		return copyFile(fn, target, info.Mode().Perm())
+	})
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
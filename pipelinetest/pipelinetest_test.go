@@ -0,0 +1,216 @@
+package pipelinetest_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/julian7/goshipdone/archive"
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/pipelinetest"
+)
+
+const helloPipeline = `---
+setups:
+- type: project
+  name: hello
+builds:
+- type: go
+  ldflags: "-s -w -X main.version={{.Version}}"
+  goos:
+  - linux
+  goarch:
+  - amd64
+- type: changelog
+- type: tar
+  builds:
+  - default
+  id: archive
+publishes:
+- type: artifact
+  url: %s/
+  owner: example
+  name: hello
+  builds:
+  - archive
+  release_notes: changelog
+`
+
+func TestHarness_Run(t *testing.T) {
+	gh := pipelinetest.NewFakeGitHub(t)
+
+	t.Setenv("GITHUB_TOKEN", "test-token")
+	t.Setenv("SKIP_PUBLISH", "false")
+
+	h := pipelinetest.New(t, "testdata/hello")
+	h.GitInit("v1.0.0")
+
+	context, err := h.Run(fmt.Sprintf(helloPipeline, gh.URL))
+	if err != nil {
+		t.Fatalf("running pipeline: %v", err)
+	}
+
+	wantArtifacts := []string{"CHANGELOG.md", "hello", "hello-v1.0.0-linux-amd64.tar"}
+	if got := pipelinetest.Filenames(context.Artifacts); !reflect.DeepEqual(got, wantArtifacts) {
+		t.Errorf("artifacts = %v, want %v", got, wantArtifacts)
+	}
+
+	entries, err := archive.List(filepath.Join(h.Dir, "dist", "hello-v1.0.0-linux-amd64.tar"))
+	if err != nil {
+		t.Fatalf("listing archive: %v", err)
+	}
+
+	if len(entries) == 0 || entries[len(entries)-1].Name != "hello-v1.0.0-linux-amd64/hello" {
+		t.Errorf("archive entries = %v, want hello binary", entries)
+	}
+
+	wantUploads := []string{"hello-v1.0.0-linux-amd64.tar"}
+	if got := gh.Uploads(); !reflect.DeepEqual(got, wantUploads) {
+		t.Errorf("uploads = %v, want %v", got, wantUploads)
+	}
+
+	rel, ok := gh.Releases()["v1.0.0"]
+	if !ok {
+		t.Fatalf("release v1.0.0 not created")
+	}
+
+	if !strings.Contains(rel.Body, "greeting the world") {
+		t.Errorf("release notes = %q, missing changelog entry", rel.Body)
+	}
+
+	published := context.Published.Release()
+	if published == nil || len(published.Links) != 1 || published.Links[0].URL != gh.URL+"/releases/tag/v1.0.0" {
+		t.Fatalf("published release = %+v, want release link", published)
+	}
+
+	wantDownload := ctx.Download{
+		Filename: "hello-v1.0.0-linux-amd64.tar",
+		OsArch:   "linux-amd64",
+		URL:      gh.URL + "/releases/download/v1.0.0/hello-v1.0.0-linux-amd64.tar",
+	}
+	if !reflect.DeepEqual(published.Downloads, []ctx.Download{wantDownload}) {
+		t.Errorf("downloads = %+v, want %+v", published.Downloads, wantDownload)
+	}
+}
+
+const fakePipeline = `---
+setups:
+- type: project
+  name: hello
+builds:
+- type: go
+  goos:
+  - linux
+  goarch:
+  - amd64
+- type: fake
+  builds:
+  - default
+publishes:
+- type: fake
+  builds:
+  - default
+  - fakesig
+  target: github:example/hello
+`
+
+func TestHarness_Fake(t *testing.T) {
+	t.Setenv("SKIP_PUBLISH", "false")
+
+	h := pipelinetest.New(t, "testdata/hello")
+	h.GitInit("v1.0.0")
+
+	context, err := h.Run(fakePipeline)
+	if err != nil {
+		t.Fatalf("running pipeline: %v", err)
+	}
+
+	want := []string{
+		"sign hello to fake",
+		"publish hello to github:example/hello",
+		"publish hello.sig to github:example/hello",
+	}
+
+	got := []string{}
+	for _, action := range context.Actions.List() {
+		got = append(got, fmt.Sprintf("%s %s to %s", action.Action, action.Artifact, action.Target))
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("actions = %v, want %v", got, want)
+	}
+}
+
+func TestHarness_RunDowngrade(t *testing.T) {
+	gh := pipelinetest.NewFakeGitHub(t)
+	gh.AddRelease("v2.0.0")
+
+	t.Setenv("GITHUB_TOKEN", "test-token")
+	t.Setenv("SKIP_PUBLISH", "false")
+
+	h := pipelinetest.New(t, "testdata/hello")
+	h.GitInit("v1.0.0")
+
+	_, err := h.Run(fmt.Sprintf(helloPipeline, gh.URL))
+	if err == nil || !strings.Contains(err.Error(), "older than latest release v2.0.0") {
+		t.Errorf("running pipeline: error = %v, want downgrade error", err)
+	}
+
+	if uploads := gh.Uploads(); len(uploads) > 0 {
+		t.Errorf("uploads = %v, want none", uploads)
+	}
+}
+
+const promotePipeline = `---
+setups:
+- type: project
+  name: hello
+publishes:
+- type: promote
+  url: %s/
+  owner: example
+  name: hello
+`
+
+func TestHarness_RunDraft(t *testing.T) {
+	gh := pipelinetest.NewFakeGitHub(t)
+
+	t.Setenv("GITHUB_TOKEN", "test-token")
+	t.Setenv("SKIP_PUBLISH", "false")
+
+	h := pipelinetest.New(t, "testdata/hello")
+	h.GitInit("v1.0.0")
+
+	if _, err := h.Run(fmt.Sprintf(helloPipeline, gh.URL) + "  draft: true\n"); err != nil {
+		t.Fatalf("running pipeline: %v", err)
+	}
+
+	if rel, ok := gh.Releases()["v1.0.0"]; !ok || !rel.Draft {
+		t.Fatalf("release v1.0.0 = %+v, want draft", rel)
+	}
+
+	// re-running finds the draft, instead of creating a new one
+	if _, err := h.Run(fmt.Sprintf(helloPipeline, gh.URL) + "  draft: true\n  if_exists: replace\n"); err != nil {
+		t.Fatalf("re-running pipeline: %v", err)
+	}
+
+	if releases := gh.Releases(); len(releases) != 1 || !releases["v1.0.0"].Draft {
+		t.Fatalf("releases = %+v, want a single draft", releases)
+	}
+
+	context, err := h.Run(fmt.Sprintf(promotePipeline, gh.URL))
+	if err != nil {
+		t.Fatalf("running promote pipeline: %v", err)
+	}
+
+	if rel := gh.Releases()["v1.0.0"]; rel.Draft {
+		t.Errorf("release v1.0.0 is still a draft")
+	}
+
+	published := context.Published.Release()
+	if published == nil || len(published.Links) != 1 || published.Links[0].URL != gh.URL+"/releases/tag/v1.0.0" {
+		t.Errorf("published release = %+v, want release link", published)
+	}
+}
@@ -0,0 +1,206 @@
+package pipelinetest
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// FakeStorage is a fake object storage server, accepting S3-style path
+// based PUT, GET, HEAD, and DELETE requests, and multipart uploads
+// without authentication.
+type FakeStorage struct {
+	*httptest.Server
+	mu      sync.Mutex
+	headers map[string]http.Header
+	objects map[string][]byte
+	uploads map[string]map[int][]byte
+	nextID  int
+}
+
+// NewFakeStorage starts a new FakeStorage server, which is closed at the
+// end of the test.
+func NewFakeStorage(t testing.TB) *FakeStorage {
+	srv := &FakeStorage{
+		headers: map[string]http.Header{},
+		objects: map[string][]byte{},
+		uploads: map[string]map[int][]byte{},
+	}
+	srv.Server = httptest.NewServer(http.HandlerFunc(srv.handle))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// Objects returns sorted keys of stored objects, in `bucket/key` format
+func (srv *FakeStorage) Objects() []string {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	keys := make([]string, 0, len(srv.objects))
+	for key := range srv.objects {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Object returns a stored object's contents
+func (srv *FakeStorage) Object(key string) ([]byte, bool) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	data, ok := srv.objects[key]
+
+	return data, ok
+}
+
+// Header returns request headers an object has been created with
+func (srv *FakeStorage) Header(key string) http.Header {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	return srv.headers[key]
+}
+
+// Parts returns the number of parts of the last multipart upload of an
+// object, or 0 for simple uploads.
+func (srv *FakeStorage) Parts(key string) int {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	parts, _ := strconv.Atoi(srv.headers[key].Get("X-Fake-Parts"))
+
+	return parts
+}
+
+func (srv *FakeStorage) handle(w http.ResponseWriter, req *http.Request) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	key := strings.TrimPrefix(req.URL.Path, "/")
+	query := req.URL.Query()
+
+	if _, ok := query["uploads"]; ok || query.Get("uploadId") != "" {
+		srv.handleMultipart(w, req, key)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		srv.objects[key] = data
+		srv.headers[key] = req.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet, http.MethodHead:
+		data, ok := srv.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+
+		if req.Method == http.MethodGet {
+			_, _ = w.Write(data)
+		}
+	case http.MethodDelete:
+		delete(srv.objects, key)
+		delete(srv.headers, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (srv *FakeStorage) handleMultipart(w http.ResponseWriter, req *http.Request, key string) {
+	query := req.URL.Query()
+	uploadID := query.Get("uploadId")
+
+	if uploadID == "" {
+		srv.nextID++
+		uploadID = fmt.Sprintf("upload-%d", srv.nextID)
+		srv.uploads[uploadID] = map[int][]byte{}
+		srv.headers[uploadID] = req.Header.Clone()
+
+		_ = xml.NewEncoder(w).Encode(struct {
+			XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+			Key      string
+			UploadID string `xml:"UploadId"`
+		}{Key: key, UploadID: uploadID})
+
+		return
+	}
+
+	parts, ok := srv.uploads[uploadID]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodPut:
+		num, err := strconv.Atoi(query.Get("partNumber"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		parts[num] = data
+		w.Header().Set("ETag", fmt.Sprintf("%q", fmt.Sprintf("etag-%d", num)))
+		w.WriteHeader(http.StatusOK)
+	case http.MethodPost:
+		var complete struct {
+			Parts []struct{ PartNumber int } `xml:"Part"`
+		}
+
+		if err := xml.NewDecoder(req.Body).Decode(&complete); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		data := &bytes.Buffer{}
+		for _, part := range complete.Parts {
+			data.Write(parts[part.PartNumber])
+		}
+
+		srv.objects[key] = data.Bytes()
+		srv.headers[key] = srv.headers[uploadID]
+		srv.headers[key].Set("X-Fake-Parts", strconv.Itoa(len(complete.Parts)))
+
+		delete(srv.headers, uploadID)
+		delete(srv.uploads, uploadID)
+
+		_ = xml.NewEncoder(w).Encode(struct {
+			XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+			Key     string
+		}{Key: key})
+	case http.MethodDelete:
+		delete(srv.headers, uploadID)
+		delete(srv.uploads, uploadID)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
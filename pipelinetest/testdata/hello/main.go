@@ -0,0 +1,10 @@
+package main
+
+import "fmt"
+
+// nolint: gochecknoglobals
+var version = "dev"
+
+func main() {
+	fmt.Printf("hello, world (%s)\n", version)
+}
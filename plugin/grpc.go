@@ -0,0 +1,199 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/hashicorp/go-hclog"
+	goplugin "github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	// moduleName is the name of the module served by gRPC plugins
+	moduleName = "module"
+	// serviceName is the gRPC service of modules
+	serviceName = "goshipdone.plugin.Module"
+)
+
+// Handshake is the handshake of gRPC plugins. It prevents running plugins
+// directly, and it rejects plugins of other protocol versions.
+// nolint: gochecknoglobals
+var Handshake = goplugin.HandshakeConfig{
+	ProtocolVersion:  Protocol,
+	MagicCookieKey:   "GOSHIPDONE_PLUGIN",
+	MagicCookieValue: "7c9e2f4a0d8b4c1e9f3a6b5d2e8c0f17",
+}
+
+type (
+	// Module is a module served by a gRPC plugin. It receives the same
+	// Request, and returns the same Response as `*:plugin` programs.
+	Module interface {
+		Run(context.Context, *Request) (*Response, error)
+	}
+
+	// Info is information about a gRPC plugin
+	Info struct {
+		// Version is the plugin's semantic version
+		Version string `json:"version"`
+	}
+
+	// GRPCPlugin is the go-plugin plugin of modules. Impl is only set by
+	// plugin servers.
+	GRPCPlugin struct {
+		goplugin.NetRPCUnsupportedPlugin
+		Impl    Module
+		Version string
+	}
+
+	// grpcServer serves a Module
+	grpcServer struct {
+		impl    Module
+		version string
+	}
+
+	// grpcClient is a Module running remotely
+	grpcClient struct {
+		conn *grpc.ClientConn
+	}
+
+	// moduleServer is the handler type of the gRPC service
+	moduleServer interface {
+		info(context.Context, []byte) ([]byte, error)
+		run(context.Context, []byte) ([]byte, error)
+	}
+)
+
+// nolint: gochecknoglobals
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*moduleServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Info", Handler: unaryHandler("Info", moduleServer.info)},
+		{MethodName: "Run", Handler: unaryHandler("Run", moduleServer.run)},
+	},
+	Metadata: "goshipdone/plugin",
+}
+
+// Serve serves a module as a gRPC plugin. It has to be called from the
+// plugin program's main function, and it doesn't return.
+func Serve(version string, module Module) {
+	goplugin.Serve(&goplugin.ServeConfig{
+		HandshakeConfig: Handshake,
+		Plugins: goplugin.PluginSet{
+			moduleName: &GRPCPlugin{Impl: module, Version: version},
+		},
+		GRPCServer: goplugin.DefaultGRPCServer,
+		Logger: hclog.New(&hclog.LoggerOptions{
+			Level:      hclog.Warn,
+			Output:     os.Stderr,
+			JSONFormat: true,
+		}),
+	})
+}
+
+// GRPCServer implements goplugin.GRPCPlugin
+func (p *GRPCPlugin) GRPCServer(_ *goplugin.GRPCBroker, server *grpc.Server) error {
+	server.RegisterService(&serviceDesc, &grpcServer{impl: p.Impl, version: p.Version})
+
+	return nil
+}
+
+// GRPCClient implements goplugin.GRPCPlugin
+func (p *GRPCPlugin) GRPCClient(_ context.Context, _ *goplugin.GRPCBroker, conn *grpc.ClientConn) (interface{}, error) {
+	return &grpcClient{conn: conn}, nil
+}
+
+func (srv *grpcServer) info(_ context.Context, _ []byte) ([]byte, error) {
+	return json.Marshal(&Info{Version: srv.version})
+}
+
+func (srv *grpcServer) run(cx context.Context, in []byte) ([]byte, error) {
+	request := &Request{}
+	if err := json.Unmarshal(in, request); err != nil {
+		return nil, fmt.Errorf("decoding request: %w", err)
+	}
+
+	response, err := srv.impl.Run(cx, request)
+	if err != nil {
+		response = &Response{Error: err.Error()}
+	}
+
+	if response == nil {
+		response = &Response{}
+	}
+
+	return json.Marshal(response)
+}
+
+// Info returns information about the plugin
+func (c *grpcClient) Info(cx context.Context) (*Info, error) {
+	info := &Info{}
+	if err := c.invoke(cx, "Info", nil, info); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// Run implements Module
+func (c *grpcClient) Run(cx context.Context, request *Request) (*Response, error) {
+	response := &Response{}
+	if err := c.invoke(cx, "Run", request, response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// invoke calls a method of the service with JSON encoded input and output
+func (c *grpcClient) invoke(cx context.Context, method string, in, out interface{}) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("encoding %s request: %w", method, err)
+	}
+
+	reply := &wrapperspb.BytesValue{}
+	if err := c.conn.Invoke(cx, "/"+serviceName+"/"+method, wrapperspb.Bytes(data), reply); err != nil {
+		return fmt.Errorf("calling %s: %w", method, err)
+	}
+
+	if err := json.Unmarshal(reply.Value, out); err != nil {
+		return fmt.Errorf("decoding %s response: %w", method, err)
+	}
+
+	return nil
+}
+
+// unaryHandler creates a gRPC method handler of JSON payloads wrapped into
+// bytes values
+func unaryHandler(method string, fn func(moduleServer, context.Context, []byte) ([]byte, error)) func(
+	interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor,
+) (interface{}, error) {
+	return func(srv interface{}, cx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		in := &wrapperspb.BytesValue{}
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+
+		handler := func(cx context.Context, req interface{}) (interface{}, error) {
+			out, err := fn(srv.(moduleServer), cx, req.(*wrapperspb.BytesValue).Value)
+			if err != nil {
+				return nil, err
+			}
+
+			return wrapperspb.Bytes(out), nil
+		}
+
+		if interceptor == nil {
+			return handler(cx, in)
+		}
+
+		info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + serviceName + "/" + method}
+
+		return interceptor(cx, in, info, handler)
+	}
+}
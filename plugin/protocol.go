@@ -0,0 +1,99 @@
+// plugin provides the protocol of external plugin modules. Plugins can be
+// programs exchanging JSON documents on their standard input and output
+// (see `*:plugin` module), or gRPC plugins built with Serve, and declared
+// in the `plugins` section of the pipeline.
+package plugin
+
+import (
+	"path/filepath"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+// Protocol is the version of the plugin protocol
+const Protocol = 1
+
+type (
+	// Request is the input of plugins
+	Request struct {
+		Config   map[string]interface{} `json:"config"`
+		Context  *Context               `json:"context"`
+		Protocol int                    `json:"protocol"`
+	}
+
+	// Context is a snapshot of the ship context for plugins
+	Context struct {
+		Artifacts   ctx.Artifacts `json:"artifacts"`
+		Git         Git           `json:"git"`
+		ProjectName string        `json:"project_name"`
+		Publish     bool          `json:"publish"`
+		Snapshot    bool          `json:"snapshot"`
+		TargetDir   string        `json:"target_dir"`
+		Version     string        `json:"version"`
+	}
+
+	// Git is git information of Context
+	Git struct {
+		Dirty       bool   `json:"dirty"`
+		PreviousTag string `json:"previous_tag"`
+		Ref         string `json:"ref"`
+		Tag         string `json:"tag"`
+		URL         string `json:"url"`
+	}
+
+	// Response is the output of plugins. Artifacts are registered in the
+	// ship context. Relative artifact locations are relative to the
+	// working directory.
+	Response struct {
+		Artifacts []*ctx.Artifact    `json:"artifacts"`
+		Error     string             `json:"error"`
+		Metrics   map[string]float64 `json:"metrics"`
+		Warnings  []string           `json:"warnings"`
+	}
+)
+
+// NewRequest creates a request of a plugin run
+func NewRequest(context *ctx.Context, config map[string]interface{}) *Request {
+	if config == nil {
+		config = map[string]interface{}{}
+	}
+
+	return &Request{
+		Config:   config,
+		Context:  NewContext(context),
+		Protocol: Protocol,
+	}
+}
+
+// NewContext creates a snapshot of the ship context
+func NewContext(context *ctx.Context) *Context {
+	artifacts := context.Artifacts
+	if artifacts == nil {
+		artifacts = ctx.Artifacts{}
+	}
+
+	return &Context{
+		Artifacts: artifacts,
+		Git: Git{
+			Dirty:       context.Git.Dirty,
+			PreviousTag: context.Git.PreviousTag,
+			Ref:         context.Git.Ref,
+			Tag:         context.Git.Tag,
+			URL:         context.Git.URL,
+		},
+		ProjectName: context.ProjectName,
+		Publish:     context.Publish,
+		Snapshot:    context.Snapshot,
+		TargetDir:   context.TargetDir,
+		Version:     context.Version,
+	}
+}
+
+// Fill fills in missing artifact file names from their locations
+func (resp *Response) Fill() {
+	for _, artifact := range resp.Artifacts {
+		if artifact.Filename == "" {
+			artifact.Filename = filepath.Base(artifact.Location)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package plugin
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/hashicorp/go-hclog"
+	goplugin "github.com/hashicorp/go-plugin"
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+type (
+	// Definition declares a gRPC plugin in the `plugins` section of the
+	// pipeline. Its module can be loaded by the plugin's name in every
+	// stage.
+	Definition struct {
+		// Args are the program's arguments. Environment variables are
+		// expanded.
+		Args []string
+		// Checksum pins the program's checksum in "algorithm:hex" format
+		// (eg. "sha256:..."). The program is not started if it doesn't
+		// match.
+		Checksum string
+		// Command is the plugin program. Environment variables are
+		// expanded. Required.
+		Command string
+		// Name is the module type of the plugin. Required.
+		Name string
+		// Version is a semantic version range the plugin's version must
+		// satisfy (eg. ">=1.2.0 <2.0.0").
+		Version string
+
+		checksum []byte
+		hash     modules.HashFactory
+		versions semver.Range
+	}
+
+	// Remote is a module running a gRPC plugin
+	Remote struct {
+		// Config is passed to the plugin as is.
+		Config map[string]interface{}
+
+		def *Definition
+	}
+)
+
+// Register validates the definition, and registers its module into every
+// stage
+func (def *Definition) Register() error {
+	if def.Name == "" {
+		return errors.New("plugin name is not specified")
+	}
+
+	if def.Command == "" {
+		return fmt.Errorf("plugin %s: no command specified", def.Name)
+	}
+
+	if def.Checksum != "" {
+		items := strings.SplitN(def.Checksum, ":", 2)
+		if len(items) != 2 {
+			return fmt.Errorf("plugin %s: invalid checksum %q", def.Name, def.Checksum)
+		}
+
+		factory, ok := modules.LookupHashAlgorithm(items[0])
+		if !ok {
+			return fmt.Errorf("plugin %s: unknown hash algorithm %s", def.Name, items[0])
+		}
+
+		sum, err := hex.DecodeString(items[1])
+		if err != nil {
+			return fmt.Errorf("plugin %s: invalid checksum: %w", def.Name, err)
+		}
+
+		def.hash = factory
+		def.checksum = sum
+	}
+
+	if def.Version != "" {
+		versions, err := semver.ParseRange(def.Version)
+		if err != nil {
+			return fmt.Errorf("plugin %s: invalid version range: %w", def.Name, err)
+		}
+
+		def.versions = versions
+	}
+
+	modules.RegisterModule(&modules.ModuleRegistration{
+		Stage: "*",
+		Type:  def.Name,
+		Factory: modules.V2Factory(func() modules.PluggableV2 {
+			return &Remote{Config: map[string]interface{}{}, def: def}
+		}),
+	})
+
+	return nil
+}
+
+// DynamicArtifacts implements modules.DynamicArtifacts
+func (mod *Remote) DynamicArtifacts() bool {
+	return true
+}
+
+// ValidateTemplates implements modules.TemplateValidator. Config is not a
+// template.
+func (mod *Remote) ValidateTemplates(context.Context) ([]string, error) {
+	return []string{"Config"}, nil
+}
+
+// RunResult starts the plugin, and runs its module
+func (mod *Remote) RunResult(cx context.Context) (*modules.Result, error) {
+	shipContext, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return nil, err
+	}
+
+	client := mod.def.client(shipContext)
+	defer client.Kill()
+
+	shipContext.Progress.SetState(fmt.Sprintf("starting plugin %s", mod.def.Name))
+
+	remote, err := mod.def.dispense(cx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	shipContext.Progress.SetState(fmt.Sprintf("running plugin %s", mod.def.Name))
+
+	response, err := remote.Run(cx, NewRequest(shipContext, mod.Config))
+	if err != nil {
+		return nil, fmt.Errorf("running plugin %s: %w", mod.def.Name, err)
+	}
+
+	if response.Error != "" {
+		return nil, fmt.Errorf("plugin %s: %s", mod.def.Name, response.Error)
+	}
+
+	response.Fill()
+
+	return &modules.Result{
+		Artifacts: response.Artifacts,
+		Metrics:   response.Metrics,
+		Warnings:  response.Warnings,
+	}, nil
+}
+
+// client creates a go-plugin client of the plugin program
+func (def *Definition) client(context *ctx.Context) *goplugin.Client {
+	args := make([]string, 0, len(def.Args))
+	for _, arg := range def.Args {
+		args = append(args, context.Env.Expand(arg))
+	}
+
+	cmd := exec.Command(context.Env.Expand(def.Command), args...) // nolint: gosec
+	cmd.Env = context.Env.Environ()
+
+	config := &goplugin.ClientConfig{
+		HandshakeConfig:  Handshake,
+		Plugins:          goplugin.PluginSet{moduleName: &GRPCPlugin{}},
+		Cmd:              cmd,
+		AllowedProtocols: []goplugin.Protocol{goplugin.ProtocolGRPC},
+		Stderr:           os.Stderr,
+		Logger: hclog.New(&hclog.LoggerOptions{
+			Name:   "plugin",
+			Level:  hclog.Error,
+			Output: os.Stderr,
+		}),
+	}
+
+	if def.hash != nil {
+		config.SecureConfig = &goplugin.SecureConfig{Checksum: def.checksum, Hash: def.hash()}
+	}
+
+	return goplugin.NewClient(config)
+}
+
+// dispense connects to the plugin, and checks its version
+func (def *Definition) dispense(cx context.Context, client *goplugin.Client) (*grpcClient, error) {
+	protocol, err := client.Client()
+	if err != nil {
+		return nil, fmt.Errorf("starting plugin %s: %w", def.Name, err)
+	}
+
+	raw, err := protocol.Dispense(moduleName)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to plugin %s: %w", def.Name, err)
+	}
+
+	remote, ok := raw.(*grpcClient)
+	if !ok {
+		return nil, fmt.Errorf("plugin %s is not a module", def.Name)
+	}
+
+	if def.versions == nil {
+		return remote, nil
+	}
+
+	info, err := remote.Info(cx)
+	if err != nil {
+		return nil, fmt.Errorf("querying plugin %s: %w", def.Name, err)
+	}
+
+	version, err := semver.ParseTolerant(info.Version)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: invalid version %q: %w", def.Name, info.Version, err)
+	}
+
+	if !def.versions(version) {
+		return nil, fmt.Errorf("plugin %s: version %s doesn't satisfy %s", def.Name, info.Version, def.Version)
+	}
+
+	return remote, nil
+}
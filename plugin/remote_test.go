@@ -0,0 +1,158 @@
+package plugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+// testPluginEnv makes the test binary serve testModule
+const testPluginEnv = "GOSHIPDONE_TEST_PLUGIN"
+
+type testModule struct{}
+
+func (testModule) Run(_ context.Context, request *Request) (*Response, error) {
+	if request.Config["fail"] == true {
+		return nil, errors.New("failed on request")
+	}
+
+	return &Response{
+		Artifacts: []*ctx.Artifact{{ID: "sig", Location: "dist/" + request.Context.ProjectName + ".sig"}},
+		Metrics:   map[string]float64{"artifacts": float64(len(request.Context.Artifacts))},
+	}, nil
+}
+
+func TestMain(m *testing.M) {
+	if os.Getenv(testPluginEnv) == "1" {
+		Serve("1.2.0", testModule{})
+
+		return
+	}
+
+	os.Exit(m.Run())
+}
+
+func testChecksum(t *testing.T) string {
+	t.Helper()
+
+	f, err := os.Open(os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		t.Fatal(err)
+	}
+
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+func TestRemote_RunResult(t *testing.T) {
+	tests := []struct {
+		name    string
+		def     *Definition
+		config  map[string]interface{}
+		want    *modules.Result
+		wantErr string
+	}{
+		{
+			name: "runs",
+			def:  &Definition{Version: ">=1.0.0 <2.0.0", Checksum: testChecksum(t)},
+			want: &modules.Result{
+				Artifacts: []*ctx.Artifact{{ID: "sig", Filename: "app.sig", Location: "dist/app.sig"}},
+				Metrics:   map[string]float64{"artifacts": 1},
+			},
+		},
+		{
+			name:    "plugin error",
+			def:     &Definition{},
+			config:  map[string]interface{}{"fail": true},
+			wantErr: "plugin test: failed on request",
+		},
+		{
+			name:    "version mismatch",
+			def:     &Definition{Version: ">=2.0.0"},
+			wantErr: "plugin test: version 1.2.0 doesn't satisfy >=2.0.0",
+		},
+		{
+			name:    "checksum mismatch",
+			def:     &Definition{Checksum: "sha256:00"},
+			wantErr: "starting plugin test: checksums did not match",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.def.Name = "test"
+			tt.def.Command = os.Args[0]
+
+			if err := tt.def.Register(); err != nil {
+				t.Fatalf("Register() error = %v", err)
+			}
+
+			cx := ctx.New(context.Background())
+
+			context, err := ctx.GetShipContext(cx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			context.Env.Set(testPluginEnv, "1")
+			context.ProjectName = "app"
+			context.Artifacts.Add(&ctx.Artifact{ID: "default", Filename: "app", Location: "dist/app"})
+
+			mod := &Remote{Config: tt.config, def: tt.def}
+
+			result, err := mod.RunResult(cx)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("RunResult() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("RunResult() error = %v", err)
+			}
+
+			if diff := deep.Equal(result, tt.want); diff != nil {
+				t.Errorf("RunResult() %v", diff)
+			}
+		})
+	}
+}
+
+func TestDefinition_Register(t *testing.T) {
+	tests := []struct {
+		name    string
+		def     *Definition
+		wantErr string
+	}{
+		{name: "no name", def: &Definition{Command: "x"}, wantErr: "plugin name is not specified"},
+		{name: "no command", def: &Definition{Name: "x"}, wantErr: "plugin x: no command specified"},
+		{name: "unknown hash", def: &Definition{Name: "x", Command: "x", Checksum: "foo:00"}, wantErr: "plugin x: unknown hash algorithm foo"},
+		{name: "invalid range", def: &Definition{Name: "x", Command: "x", Version: "latest"}, wantErr: "plugin x: invalid version range"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.def.Register(); err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("Register() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package goshipdone
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	profileEnv = "GOSHIPDONE_PROFILE"
+	// profilesKey is the YAML key of profiles
+	profilesKey = "profiles"
+	// releaseProfile is detected for tagged commits
+	releaseProfile = "release"
+	// snapshotProfile is detected for untagged commits
+	snapshotProfile = "snapshot"
+)
+
+// isTagged returns true if the current commit is tagged
+// nolint: gochecknoglobals
+var isTagged = func() bool {
+	return exec.Command("git", "describe", "--exact-match", "--tags", "HEAD").Run() == nil
+}
+
+// applyProfile removes `profiles` from a configuration, and applies the
+// selected profile. The profile is selected by GOSHIPDONE_PROFILE
+// environment variable, or detected: "release" for tagged commits, and
+// "snapshot" otherwise. Only explicitly selected profiles are required to
+// exist.
+func applyProfile(doc *yaml.Node) error {
+	root := doc
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+
+	profiles := takeKey(root, profilesKey)
+	if profiles == nil {
+		return nil
+	}
+
+	if profiles.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s is not a map", profilesKey)
+	}
+
+	name, explicit := os.LookupEnv(profileEnv)
+	if !explicit {
+		name = snapshotProfile
+		if isTagged() {
+			name = releaseProfile
+		}
+	}
+
+	profile := getNode(profiles, name)
+	if profile == nil {
+		if explicit {
+			return fmt.Errorf("profile %s is not defined", name)
+		}
+
+		return nil
+	}
+
+	if profile.Kind != yaml.MappingNode {
+		return fmt.Errorf("profile %s is not a map", name)
+	}
+
+	log.Printf("using profile %s", name)
+
+	overlayNodes(root, profile)
+
+	return nil
+}
+
+// overlayNodes overrides a configuration with a profile. Modules of a
+// stage are matched by `id`, or by `type` if the profile's module has no
+// `id`, and their keys are overridden. Unmatched modules are added.
+// Other values are merged like in mergeNodes.
+func overlayNodes(dst, src *yaml.Node) {
+	for idx := 0; idx+1 < len(src.Content); idx += 2 {
+		key, value := src.Content[idx], src.Content[idx+1]
+
+		existing := getNode(dst, key.Value)
+		if existing == nil || existing.Kind != yaml.SequenceNode || value.Kind != yaml.SequenceNode {
+			mergeNodes(dst, &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{key, value}})
+
+			continue
+		}
+
+		for _, item := range value.Content {
+			matches := matchModules(existing, item)
+			if len(matches) == 0 {
+				existing.Content = append(existing.Content, item)
+
+				continue
+			}
+
+			for _, match := range matches {
+				overrideKeys(match, item)
+			}
+		}
+	}
+}
+
+// matchModules returns modules of a stage matching a profile's module
+func matchModules(stage, module *yaml.Node) []*yaml.Node {
+	if module.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	key, value := "id", getValue(module, "id")
+	if value == "" {
+		key, value = "type", getValue(module, "type")
+	}
+
+	if value == "" {
+		return nil
+	}
+
+	matches := []*yaml.Node{}
+
+	for _, item := range stage.Content {
+		if item.Kind == yaml.MappingNode && getValue(item, key) == value {
+			matches = append(matches, item)
+		}
+	}
+
+	return matches
+}
+
+// overrideKeys overrides keys of a module with a profile's module. Maps
+// (eg. `config`) are merged, other values are replaced.
+func overrideKeys(dst, src *yaml.Node) {
+	for idx := 0; idx+1 < len(src.Content); idx += 2 {
+		key, value := src.Content[idx], src.Content[idx+1]
+
+		existing := getNode(dst, key.Value)
+		if existing != nil && existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode {
+			overrideKeys(existing, value)
+
+			continue
+		}
+
+		setNode(dst, key, value)
+	}
+}
+
+// getNode returns a key's value of a mapping node
+func getNode(node *yaml.Node, name string) *yaml.Node {
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		if node.Content[idx].Value == name {
+			return node.Content[idx+1]
+		}
+	}
+
+	return nil
+}
+
+// getValue returns a key's scalar value of a mapping node
+func getValue(node *yaml.Node, name string) string {
+	if value := getNode(node, name); value != nil && value.Kind == yaml.ScalarNode {
+		return value.Value
+	}
+
+	return ""
+}
+
+// setNode sets a key's value of a mapping node
+func setNode(node, key, value *yaml.Node) {
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		if node.Content[idx].Value == key.Value {
+			node.Content[idx+1] = value
+
+			return
+		}
+	}
+
+	node.Content = append(node.Content, key, value)
+}
+
+// takeKey removes a key from a mapping node, returning its value
+func takeKey(node *yaml.Node, name string) *yaml.Node {
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		if node.Content[idx].Value == name {
+			value := node.Content[idx+1]
+			node.Content = append(node.Content[:idx], node.Content[idx+2:]...)
+
+			return value
+		}
+	}
+
+	return nil
+}
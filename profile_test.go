@@ -0,0 +1,139 @@
+package goshipdone
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func Test_applyProfile(t *testing.T) {
+	config := `builds:
+- type: go
+  id: cli
+  ldflags: -s -w
+  goos: [linux]
+- type: go
+  id: server
+publishes:
+- type: artifact
+profiles:
+  snapshot:
+    builds:
+    - id: cli
+      goos: [linux, darwin]
+    publishes:
+    - type: artifact
+      when: "false"
+  release:
+    builds:
+    - type: go
+      timeout: 10m
+    - type: upx
+`
+
+	tests := []struct {
+		name    string
+		env     string
+		tagged  bool
+		want    string
+		wantErr string
+	}{
+		{
+			name: "detected snapshot",
+			want: `builds:
+    - type: go
+      id: cli
+      ldflags: -s -w
+      goos: [linux, darwin]
+    - type: go
+      id: server
+publishes:
+    - type: artifact
+      when: "false"
+`,
+		},
+		{
+			name:   "detected release",
+			tagged: true,
+			want: `builds:
+    - type: go
+      id: cli
+      ldflags: -s -w
+      goos: [linux]
+      timeout: 10m
+    - type: go
+      id: server
+      timeout: 10m
+    - type: upx
+publishes:
+    - type: artifact
+`,
+		},
+		{
+			name:   "explicit profile",
+			env:    "snapshot",
+			tagged: true,
+			want: `builds:
+    - type: go
+      id: cli
+      ldflags: -s -w
+      goos: [linux, darwin]
+    - type: go
+      id: server
+publishes:
+    - type: artifact
+      when: "false"
+`,
+		},
+		{
+			name:    "undefined profile",
+			env:     "nightly",
+			wantErr: "profile nightly is not defined",
+		},
+	}
+
+	origTagged := isTagged
+
+	defer func() { isTagged = origTagged }()
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.env != "" {
+				os.Setenv(profileEnv, tt.env)
+				defer os.Unsetenv(profileEnv)
+			}
+
+			isTagged = func() bool { return tt.tagged }
+
+			node := &yaml.Node{}
+			if err := yaml.Unmarshal([]byte(config), node); err != nil {
+				t.Fatal(err)
+			}
+
+			err := applyProfile(node)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("applyProfile() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("applyProfile() error = %v", err)
+			}
+
+			data, err := yaml.Marshal(node)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(data) != tt.want {
+				t.Errorf("applyProfile() = %q, want %q", data, tt.want)
+			}
+		})
+	}
+}